@@ -0,0 +1,173 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+// controlTimeout bounds each round trip to a running doit's socket.
+const controlTimeout = 5 * time.Second
+
+// runPending handles `doit --pending`: the escalations still awaiting a
+// human decision across every running doit.
+func runPending(configPath string, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "doit: --pending: unexpected argument %q\n", args[0])
+		return engine.ExitValidation
+	}
+	dir, code := eventsDir(configPath, "--pending")
+	if code != 0 {
+		return code
+	}
+	pending := collectPending(dir)
+	if len(pending) == 0 {
+		fmt.Println("no pending escalations")
+		return 0
+	}
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAGE\tEXPIRES\tCOMMAND\tREASON")
+	for _, p := range pending {
+		expires := "-"
+		if !p.ExpiresAt.IsZero() {
+			expires = p.ExpiresAt.Sub(now).Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%d/%d\t%s\t%s\t%s\t%s\n", p.PID, p.Request,
+			now.Sub(p.IssuedAt).Round(time.Second), expires, oneLine(p.Command), oneLine(p.Reason))
+	}
+	tw.Flush()
+	return 0
+}
+
+// runResolve handles `doit --approve <id> [--always]` and
+// `doit --deny <id> [--always]`. The id is <pid>/<request> as listed by
+// --pending, or just <request> when only one running doit has it.
+func runResolve(configPath string, args []string, approve bool) int {
+	flag := "--deny"
+	if approve {
+		flag = "--approve"
+	}
+	var id string
+	always := false
+	for _, a := range args {
+		switch {
+		case a == "--always":
+			always = true
+		case id == "" && !strings.HasPrefix(a, "-"):
+			id = a
+		default:
+			fmt.Fprintf(os.Stderr, "doit: %s: unexpected argument %q\n", flag, a)
+			return engine.ExitValidation
+		}
+	}
+	if id == "" {
+		fmt.Fprintf(os.Stderr, "doit: usage: %s <id> [--always]\n", flag)
+		return engine.ExitValidation
+	}
+	pid, request, err := parseEscalationID(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %s: %v\n", flag, err)
+		return engine.ExitValidation
+	}
+	dir, code := eventsDir(configPath, flag)
+	if code != 0 {
+		return code
+	}
+
+	if pid == 0 {
+		var owners []int
+		for _, p := range collectPending(dir) {
+			if p.Request == request {
+				owners = append(owners, p.PID)
+			}
+		}
+		switch len(owners) {
+		case 0:
+			fmt.Fprintf(os.Stderr, "doit: %s: no pending escalation %d\n", flag, request)
+			return engine.ExitValidation
+		case 1:
+			pid = owners[0]
+		default:
+			fmt.Fprintf(os.Stderr, "doit: %s: escalation %d is pending in several doits; use <pid>/%d\n", flag, request, request)
+			return engine.ExitValidation
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	if err := events.Resolve(ctx, events.SocketPath(dir, pid), request, approve, always); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %s %d/%d: %v\n", flag, pid, request, err)
+		return engine.ExitUnavailable
+	}
+	verb := "denied"
+	if approve {
+		verb = "approved"
+	}
+	if always {
+		verb += " (remembered)"
+	}
+	fmt.Printf("%d/%d %s\n", pid, request, verb)
+	return 0
+}
+
+// eventsDir returns the configured event socket directory, or a non-zero
+// exit code if the sockets are disabled.
+func eventsDir(configPath, flag string) (string, int) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return "", engine.ExitValidation
+	}
+	if !cfg.Events.Socket {
+		fmt.Fprintf(os.Stderr, "doit: %s: events.socket is disabled in the config\n", flag)
+		return "", engine.ExitUnavailable
+	}
+	return cfg.Events.Dir, 0
+}
+
+// collectPending gathers the pending escalations of every running doit,
+// skipping sockets that do not answer.
+func collectPending(dir string) []events.Pending {
+	socks, _ := events.Sockets(dir)
+	var out []events.Pending
+	for _, path := range socks {
+		ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+		p, err := events.ListPending(ctx, path)
+		cancel()
+		if err != nil {
+			continue // stale socket from a process that has exited
+		}
+		out = append(out, p...)
+	}
+	return out
+}
+
+// parseEscalationID parses <pid>/<request> or <request>; pid is 0 when
+// omitted.
+func parseEscalationID(id string) (pid int, request uint64, err error) {
+	reqPart := id
+	if p, r, ok := strings.Cut(id, "/"); ok {
+		pid, err = strconv.Atoi(p)
+		if err != nil || pid <= 0 {
+			return 0, 0, fmt.Errorf("invalid escalation id %q", id)
+		}
+		reqPart = r
+	}
+	request, err = strconv.ParseUint(reqPart, 10, 64)
+	if err != nil || request == 0 {
+		return 0, 0, fmt.Errorf("invalid escalation id %q", id)
+	}
+	return pid, request, nil
+}
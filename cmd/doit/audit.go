@@ -0,0 +1,387 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/config"
+)
+
+// loadConfig loads the config from configPath, or the default location
+// when it is empty.
+func loadConfig(configPath string) (*config.Config, error) {
+	if configPath != "" {
+		return config.LoadFrom(configPath)
+	}
+	return config.Load()
+}
+
+// runAudit handles `doit --audit <subcommand>`.
+func runAudit(configPath string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "doit: --audit requires a subcommand (verify, tail, export, diff, llm)\n")
+		return engine.ExitValidation
+	}
+	migratePaths(configPath)
+	switch args[0] {
+	case "verify":
+		return runAuditVerify(configPath, args[1:])
+	case "tail":
+		return runAuditTail(configPath, args[1:])
+	case "export":
+		return runAuditExport(configPath, args[1:])
+	case "diff":
+		return runAuditDiff(configPath, args[1:])
+	case "llm":
+		return runAuditLLM(configPath, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "doit: unknown --audit subcommand %q\n", args[0])
+		return engine.ExitValidation
+	}
+}
+
+// runAuditVerify checks the audit hash chain, resuming from the last
+// verified watermark unless --full is given.
+func runAuditVerify(configPath string, args []string) int {
+	full := false
+	for _, a := range args {
+		switch a {
+		case "--full":
+			full = true
+		default:
+			fmt.Fprintf(os.Stderr, "doit: --audit verify: unknown flag %q\n", a)
+			return engine.ExitValidation
+		}
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+
+	path := cfg.Audit.Path
+	report, err := audit.VerifyWith(path, audit.VerifyOptions{
+		Checkpoint: audit.CheckpointPath(path),
+		Full:       full,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: audit chain violation: %v\n", err)
+		return 1
+	}
+	scope := "full chain"
+	if report.Incremental {
+		scope = "since last verified watermark"
+	}
+	fmt.Printf("audit log OK: %d entries checked (%s), last seq %d\n", report.Checked, scope, report.LastSeq)
+	return 0
+}
+
+// runAuditTail prints the last -n entries (default 10) and, with -f,
+// keeps printing entries as they are appended until interrupted.
+func runAuditTail(configPath string, args []string) int {
+	n := 10
+	follow := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-f", "--follow":
+			follow = true
+		case "-n":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "doit: --audit tail: -n requires a count\n")
+				return engine.ExitValidation
+			}
+			v, err := strconv.Atoi(args[i+1])
+			if err != nil || v < 0 {
+				fmt.Fprintf(os.Stderr, "doit: --audit tail: invalid count %q\n", args[i+1])
+				return engine.ExitValidation
+			}
+			n = v
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "doit: --audit tail: unknown flag %q\n", args[i])
+			return engine.ExitValidation
+		}
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	path := cfg.Audit.Path
+
+	if !follow {
+		entries, err := audit.Tail(path, n)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "doit: warning: %v\n", err)
+		}
+		for _, e := range entries {
+			fmt.Println(formatEntryLine(e))
+		}
+		return 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = audit.Follow(ctx, path, audit.FollowOptions{Backlog: n}, func(e audit.Entry) error {
+		fmt.Println(formatEntryLine(e))
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	return 0
+}
+
+// formatEntryLine renders an audit entry as one line for streaming output.
+func formatEntryLine(e audit.Entry) string {
+	policy := "-"
+	if e.PolicyResult != "" {
+		policy = fmt.Sprintf("L%d/%s", e.PolicyLevel, e.PolicyResult)
+	}
+	if e.Grant != "" {
+		policy += "(grant)"
+	}
+	return fmt.Sprintf("#%d %s exit=%s policy=%s cwd=%s %s",
+		e.Seq, e.Time.Local().Format("15:04:05"), exitStatus(e), policy, e.Cwd, oneLine(e.Pipeline))
+}
+
+// exitStatus renders an entry's exit code, naming the signal that killed
+// the command if there was one: "137(SIGKILL)".
+func exitStatus(e audit.Entry) string {
+	if e.Signal != "" {
+		return fmt.Sprintf("%d(%s)", e.ExitCode, e.Signal)
+	}
+	return strconv.Itoa(e.ExitCode)
+}
+
+// runAuditExport writes a human-readable transcript of the audit log,
+// optionally restricted to one work session.
+func runAuditExport(configPath string, args []string) int {
+	var session string
+	format := audit.FormatMarkdown
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--session", "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "doit: --audit export: %s requires an argument\n", args[i])
+				return engine.ExitValidation
+			}
+			if args[i] == "--session" {
+				session = args[i+1]
+			} else {
+				format = args[i+1]
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "doit: --audit export: unknown flag %q\n", args[i])
+			return engine.ExitValidation
+		}
+	}
+	if format != audit.FormatMarkdown && format != audit.FormatHTML {
+		fmt.Fprintf(os.Stderr, "doit: --audit export: unknown format %q (want md or html)\n", format)
+		return engine.ExitValidation
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	entries, err := audit.Query(cfg.Audit.Path, &audit.Filter{Session: session})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	if session != "" && len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "doit: --audit export: no audit entries for session %q\n", session)
+		return engine.ExitValidation
+	}
+
+	title := "doit audit transcript"
+	if session != "" {
+		title = "doit session " + session
+	}
+	if err := audit.WriteTranscript(os.Stdout, title, entries, format); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	return 0
+}
+
+// runAuditLLM prints the prompts the Level 3 gatekeeper was given for the
+// audit entry with sequence number seq, and what it answered, checking
+// each against the hash the entry records.
+func runAuditLLM(configPath string, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --audit llm <seq>\n")
+		return engine.ExitValidation
+	}
+	seq, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || seq == 0 {
+		fmt.Fprintf(os.Stderr, "doit: --audit llm: invalid sequence number %q\n", args[0])
+		return engine.ExitValidation
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	entry, err := audit.Lookup(cfg.Audit.Path, seq)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --audit llm: %v\n", err)
+		return engine.ExitValidation
+	}
+	if len(entry.LLM) == 0 {
+		fmt.Fprintf(os.Stderr, "doit: --audit llm: entry %d records no LLM calls\n", seq)
+		return engine.ExitValidation
+	}
+
+	dir := audit.SpillDir(cfg.Audit.Path)
+	code := 0
+	show := func(what, sum string) {
+		text, err := audit.Unspill(dir, sum)
+		if err != nil {
+			fmt.Printf("--- %s: %v\n", what, err)
+			code = engine.ExitInternal
+			return
+		}
+		fmt.Printf("--- %s (sha256 %.12s)\n%s\n", what, sum, strings.TrimRight(text, "\n"))
+	}
+	fmt.Printf("#%d %s\n", entry.Seq, oneLine(entry.Pipeline))
+	for _, call := range entry.LLM {
+		fmt.Printf("\n=== %s\n", call.Stage)
+		show("prompt", call.Prompt)
+		if call.Error != "" {
+			fmt.Printf("--- error\n%s\n", call.Error)
+			continue
+		}
+		show("response", call.Response)
+	}
+	return code
+}
+
+// runAuditDiff summarizes the filesystem-affecting commands (writes,
+// deletes, git mutations) that ran between --since and --until.
+func runAuditDiff(configPath string, args []string) int {
+	now := time.Now()
+	var since, until time.Time
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since", "--until":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "doit: --audit diff: %s requires a time\n", args[i])
+				return engine.ExitValidation
+			}
+			t, err := parseTimeArg(args[i+1], now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "doit: --audit diff: %s: %v\n", args[i], err)
+				return engine.ExitValidation
+			}
+			if args[i] == "--since" {
+				since = t
+			} else {
+				until = t
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "doit: --audit diff: unknown flag %q\n", args[i])
+			return engine.ExitValidation
+		}
+	}
+	if !since.IsZero() && !until.IsZero() && !until.After(since) {
+		fmt.Fprintf(os.Stderr, "doit: --audit diff: --until must be after --since\n")
+		return engine.ExitValidation
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	entries, err := audit.Query(cfg.Audit.Path, &audit.Filter{After: since, Before: until})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+
+	// Denied commands and unapproved escalations never ran, and group
+	// summaries are not commands.
+	ran := entries[:0]
+	for _, e := range entries {
+		if e.Summary != nil {
+			continue
+		}
+		if e.ExitCode == engine.ExitPolicyDeny && e.PolicyResult == "deny" ||
+			e.ExitCode == engine.ExitEscalationPending && e.PolicyResult == "escalate" {
+			continue
+		}
+		ran = append(ran, e)
+	}
+	changes := audit.Changes(ran)
+
+	counts := map[audit.ChangeKind]int{}
+	for _, c := range changes {
+		for _, k := range c.Kinds {
+			counts[k]++
+		}
+	}
+	fmt.Printf("%d of %d commands changed files (%d delete, %d git, %d write)\n",
+		len(changes), len(ran), counts[audit.ChangeDelete], counts[audit.ChangeGit], counts[audit.ChangeWrite])
+	if len(changes) == 0 {
+		return 0
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEQ\tTIME\tKIND\tEXIT\tCWD\tCOMMAND")
+	for _, c := range changes {
+		kinds := make([]string, len(c.Kinds))
+		for i, k := range c.Kinds {
+			kinds[i] = string(k)
+		}
+		e := c.Entry
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), strings.Join(kinds, ","), e.ExitCode, e.Cwd, oneLine(e.Pipeline))
+	}
+	tw.Flush()
+	return 0
+}
+
+// parseTimeArg parses a --since/--until value: a duration ago ("90m",
+// "2h"), an RFC 3339 timestamp, a local date and time ("2006-01-02 15:04"
+// or with a T), a local date, or a local time of day today ("15:04").
+func parseTimeArg(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want a duration like 2h, RFC 3339, YYYY-MM-DD [HH:MM], or HH:MM)", s)
+}
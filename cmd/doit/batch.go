@@ -0,0 +1,73 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/marcelocantos/doit/engine"
+)
+
+// runBatch handles `doit --batch <file.doit> [--keep-going]`: running
+// the command lines of a file in order from the current directory, each
+// judged and audited on its own. A line policy denies stops the batch
+// unless --keep-going is given. While a line awaits approval, it is
+// listed by doit --pending and doit --top.
+func runBatch(configPath string, args []string, verbosity engine.Verbosity, offline bool) int {
+	keepGoing := false
+	var files []string
+	for _, a := range args {
+		if a == "--keep-going" {
+			keepGoing = true
+			continue
+		}
+		files = append(files, a)
+	}
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --batch <file.doit> [--keep-going]\n")
+		return engine.ExitValidation
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	batch, err := engine.LoadBatch(files[0], cwd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+
+	migratePaths(configPath)
+	eng, err := engine.New(engine.Options{ConfigPath: configPath, Verbosity: verbosity, Offline: offline})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	defer eng.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := eng.ServeEvents(ctx); err != nil {
+			log.Printf("doit: %v", err)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	// An interrupt stops the running line (SIGTERM, then SIGKILL after
+	// exec.kill_grace) and the batch.
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	return eng.RunBatch(runCtx, batch, keepGoing, os.Stdout, os.Stderr)
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/marcelocantos/doit/engine"
+)
+
+// runCommandLine handles `doit -c <command> [--approved <token>]`: it
+// runs a single command line from the current directory, judged and
+// audited as an agent's would be. The command is one argument, quoted as
+// the shell would quote it (doit -c "grep 'hello world' src | head -5");
+// the engine splits it into words honouring those quotes, and sh runs it
+// as written. Piped input becomes the command's stdin.
+func runCommandLine(configPath string, args []string, verbosity engine.Verbosity, offline bool) int {
+	approved := ""
+	if len(args) == 3 && args[1] == "--approved" && args[2] != "" {
+		approved, args = args[2], args[:1]
+	}
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		fmt.Fprintf(os.Stderr, "doit: usage: -c <command> [--approved <token>]\n")
+		return engine.ExitValidation
+	}
+	command := args[0]
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	stdin := ""
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "doit: reading stdin: %v\n", err)
+			return engine.ExitInternal
+		}
+		stdin = string(data)
+	}
+
+	migratePaths(configPath)
+	eng, err := engine.New(engine.Options{
+		ConfigPath:   configPath,
+		Verbosity:    verbosity,
+		Offline:      offline,
+		TokenSession: cliTokenSession,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	defer eng.Close()
+
+	// Signals to doit go to the command; before it starts, they abandon
+	// it.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigs)
+
+	res := eng.ExecuteStreaming(context.Background(), engine.Request{
+		Command:  command,
+		Cwd:      cwd,
+		Stdin:    stdin,
+		Approved: approved,
+		Signals:  sigs,
+	}, os.Stdout, os.Stderr)
+	if res.ExitCode == engine.ExitEscalationPending && res.EscalateToken != "" {
+		fmt.Fprintf(os.Stderr, "doit: once approved, run: doit -c %s --approved %s\n", shellQuote(command), res.EscalateToken)
+	}
+	return res.ExitCode
+}
+
+// shellQuote quotes s as a single sh word, for commands doit suggests
+// running.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
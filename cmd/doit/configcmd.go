@@ -0,0 +1,137 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/config"
+)
+
+// isConfigSubcommand reports whether the argument after --config names a
+// subcommand rather than a config path.
+func isConfigSubcommand(arg string) bool {
+	switch arg {
+	case "check", "get", "set", "unset":
+		return true
+	}
+	return false
+}
+
+// runConfig handles `doit [--config <path>] --config <subcommand>`.
+func runConfig(configPath string, args []string) int {
+	switch args[0] {
+	case "check":
+		return runConfigCheck(configPath, args[1:])
+	case "get":
+		return runConfigGet(configPath, args[1:])
+	case "set":
+		return runConfigEdit(configPath, "set", args[1:], 2, func(data []byte) ([]byte, error) {
+			return config.SetKey(data, args[1], args[2])
+		})
+	case "unset":
+		return runConfigEdit(configPath, "unset", args[1:], 1, func(data []byte) ([]byte, error) {
+			return config.UnsetKey(data, args[1])
+		})
+	default:
+		fmt.Fprintf(os.Stderr, "doit: unknown --config subcommand %q\n", args[0])
+		return engine.ExitValidation
+	}
+}
+
+// runConfigCheck strictly validates the config file and prints each
+// problem as path:line: message.
+func runConfigCheck(configPath string, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "doit: --config check: unexpected argument %q\n", args[0])
+		return engine.ExitValidation
+	}
+	migratePaths(configPath)
+	if configPath == "" {
+		configPath = config.ConfigPath()
+	}
+
+	problems, err := config.Check(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	for _, p := range problems {
+		if p.Line > 0 {
+			fmt.Printf("%s:%d: %s\n", configPath, p.Line, p.Message)
+		} else {
+			fmt.Printf("%s: %s\n", configPath, p.Message)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "doit: %d problem(s) in %s\n", len(problems), configPath)
+		return engine.ExitValidation
+	}
+	fmt.Printf("%s: OK\n", configPath)
+	return 0
+}
+
+// readConfigFile returns the config path to edit and its current content
+// (nil if the file does not exist yet).
+func readConfigFile(configPath string) (string, []byte, error) {
+	migratePaths(configPath)
+	if configPath == "" {
+		configPath = config.ConfigPath()
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", nil, fmt.Errorf("read config: %w", err)
+	}
+	return configPath, data, nil
+}
+
+// runConfigGet prints the value of a dotted key, falling back to the
+// built-in default when the file does not set it.
+func runConfigGet(configPath string, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --config get <key>\n")
+		return engine.ExitValidation
+	}
+	_, data, err := readConfigFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	value, _, err := config.GetKey(data, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	fmt.Println(value)
+	return 0
+}
+
+// runConfigEdit applies edit to the config file and writes it back.
+func runConfigEdit(configPath, name string, args []string, nargs int, edit func([]byte) ([]byte, error)) int {
+	if len(args) != nargs {
+		usage := "<key>"
+		if nargs == 2 {
+			usage = "<key> <value>"
+		}
+		fmt.Fprintf(os.Stderr, "doit: usage: --config %s %s\n", name, usage)
+		return engine.ExitValidation
+	}
+	path, data, err := readConfigFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	out, err := edit(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %s %s: %v\n", name, args[0], err)
+		return engine.ExitValidation
+	}
+	if err := config.WriteFileAtomic(path, out); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// runDoctor handles `doit --doctor`: checking that the shell and every
+// capability's programs are installed and new enough, with a hint for
+// each problem. A capability that cannot run is reported, since it fails
+// only if used; a missing shell, which every command needs, makes doit
+// exit ExitUnavailable.
+func runDoctor(configPath string) int {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	code := 0
+	if _, err := exec.LookPath("sh"); err != nil {
+		fmt.Printf("missing  sh: commands cannot run without a shell\n")
+		code = engine.ExitUnavailable
+	}
+
+	reg := newRegistry(cfg)
+	unavailable := 0
+	for _, c := range reg.All() {
+		var uerr *cap.UnavailableError
+		if !errors.As(reg.Available(c.Name()), &uerr) {
+			continue
+		}
+		note := ""
+		if reg.CheckTier(c.Tier()) != nil {
+			note = " (tier disabled)"
+		}
+		fmt.Printf("missing  %s: %s%s\n         hint: %s\n", c.Name(), uerr.Reason, note, uerr.Hint)
+		unavailable++
+	}
+	if unavailable == 0 {
+		fmt.Printf("all %d capabilities available\n", len(reg.All()))
+	} else {
+		fmt.Printf("%d of %d capabilities unavailable\n", unavailable, len(reg.All()))
+	}
+	return code
+}
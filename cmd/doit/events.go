@@ -0,0 +1,131 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+// socketScanInterval is how often --events looks for newly started doits.
+const socketScanInterval = time.Second
+
+// runEvents handles `doit --events [<type>,...]`: it subscribes to every
+// running doit's event socket, picking up new ones as they start, and
+// prints each event as a JSON line until interrupted.
+func runEvents(configPath string, args []string) int {
+	if len(args) == 1 && args[0] == "clean" {
+		return runEventsClean(configPath)
+	}
+	var types []events.Type
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "doit: --events: unexpected argument %q\n", args[1])
+		return engine.ExitValidation
+	}
+	if len(args) == 1 {
+		for _, name := range strings.Split(args[0], ",") {
+			t, err := events.ParseType(strings.TrimSpace(name))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "doit: --events: %v\n", err)
+				return engine.ExitValidation
+			}
+			types = append(types, t)
+		}
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	if !cfg.Events.Socket {
+		fmt.Fprintf(os.Stderr, "doit: --events: events.socket is disabled in the config\n")
+		return engine.ExitUnavailable
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(os.Stdout)
+	watchEventSockets(ctx, cfg.Events.Dir, types, func(e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(e)
+	})
+	return 0
+}
+
+// runEventsClean handles `doit --events clean`: it removes the event
+// sockets left behind by doit processes that crashed or were killed.
+func runEventsClean(configPath string) int {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	removed, err := events.CleanStale(cfg.Events.Dir)
+	for _, path := range removed {
+		fmt.Printf("removed %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --events clean: %v\n", err)
+		return engine.ExitInternal
+	}
+	fmt.Printf("%d stale sockets removed\n", len(removed))
+	return 0
+}
+
+// watchEventSockets subscribes to every socket in dir, rescanning for new
+// ones, and calls fn (from multiple goroutines) for each event until ctx
+// is done.
+func watchEventSockets(ctx context.Context, dir string, types []events.Type, fn func(events.Event)) {
+	var (
+		mu     sync.Mutex
+		active = map[string]bool{}
+		wg     sync.WaitGroup
+	)
+	ticker := time.NewTicker(socketScanInterval)
+	defer ticker.Stop()
+	for {
+		socks, _ := events.Sockets(dir)
+		for _, path := range socks {
+			mu.Lock()
+			if active[path] {
+				mu.Unlock()
+				continue
+			}
+			active[path] = true
+			mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Stale sockets from crashed processes fail to connect;
+				// they are retried on the next scan in case one restarts.
+				_ = events.Subscribe(ctx, path, types, func(e events.Event) error {
+					fn(e)
+					return nil
+				})
+				mu.Lock()
+				delete(active, path)
+				mu.Unlock()
+			}()
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
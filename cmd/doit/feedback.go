@@ -0,0 +1,81 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// runFeedback handles `doit --feedback <seq> good|bad [--note <text>]`:
+// recording a human's judgment of a past Level 3 decision, which later
+// prompts show the gatekeeper as an example.
+func runFeedback(configPath string, args []string) int {
+	var note string
+	var pos []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--note":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "doit: --feedback: --note requires text\n")
+				return engine.ExitValidation
+			}
+			note = args[i+1]
+			i++
+		default:
+			pos = append(pos, args[i])
+		}
+	}
+	if len(pos) != 2 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --feedback <seq> good|bad [--note <text>]\n")
+		return engine.ExitValidation
+	}
+	seq, err := strconv.ParseUint(pos[0], 10, 64)
+	if err != nil || seq == 0 {
+		fmt.Fprintf(os.Stderr, "doit: --feedback: invalid sequence number %q\n", pos[0])
+		return engine.ExitValidation
+	}
+	verdict, err := policy.ParseVerdict(pos[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --feedback: %v\n", err)
+		return engine.ExitValidation
+	}
+
+	migratePaths(configPath)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	entry, err := audit.Lookup(cfg.Audit.Path, seq)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --feedback: %v\n", err)
+		return engine.ExitValidation
+	}
+	if entry.PolicyLevel != 3 {
+		fmt.Fprintf(os.Stderr, "doit: --feedback: entry %d was not decided by the Level 3 gatekeeper\n", seq)
+		return engine.ExitValidation
+	}
+
+	fb := policy.Feedback{
+		Seq:      seq,
+		Time:     time.Now().UTC(),
+		Command:  entry.Pipeline,
+		Decision: entry.PolicyResult,
+		Verdict:  verdict,
+		Note:     note,
+	}
+	if err := policy.AppendFeedback(policy.DefaultFeedbackPath(), fb); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	fmt.Printf("recorded: %s\n", fb)
+	return 0
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// runGrants handles `doit --grants list|add|revoke`: temporary blanket
+// approvals stored as auto-expiring learned (L2) policy entries.
+func runGrants(configPath string, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --grants list | add (--for <duration> | --until <time>) <pattern> | revoke <id>\n")
+		return engine.ExitValidation
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	storePath := cfg.Policy.Level2Path
+	if storePath == "" {
+		storePath = policy.DefaultStorePath()
+	}
+	now := time.Now()
+
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "doit: --grants list: unexpected argument %q\n", args[1])
+			return engine.ExitValidation
+		}
+		return grantsList(storePath, now)
+
+	case "add":
+		var expires time.Time
+		var pattern []string
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--for", "--until":
+				if i+1 >= len(args) {
+					fmt.Fprintf(os.Stderr, "doit: --grants add: %s requires an argument\n", args[i])
+					return engine.ExitValidation
+				}
+				t, err := grantExpiry(args[i], args[i+1], now)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "doit: --grants add: %v\n", err)
+					return engine.ExitValidation
+				}
+				expires = t
+				i++
+			default:
+				pattern = append(pattern, args[i])
+			}
+		}
+		if expires.IsZero() || len(pattern) == 0 {
+			fmt.Fprintf(os.Stderr, "doit: usage: --grants add (--for <duration> | --until <time>) <pattern>\n")
+			return engine.ExitValidation
+		}
+		entry, err := policy.NewGrant(strings.Join(pattern, " "), expires, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --grants add: %v\n", err)
+			return engine.ExitValidation
+		}
+		if _, err := policy.PruneExpired(storePath, now); err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --grants add: %v\n", err)
+			return engine.ExitInternal
+		}
+		if _, err := policy.AppendEntries(storePath, []policy.PolicyEntry{entry}); err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --grants add: %v\n", err)
+			return engine.ExitInternal
+		}
+		fmt.Printf("%s: allow %s until %s\n", entry.ID, strings.Join(pattern, " "), expires.Local().Format("2006-01-02 15:04"))
+		if !cfg.Policy.Level2Enabled {
+			fmt.Fprintf(os.Stderr, "doit: warning: policy.level2_enabled is off, so grants have no effect\n")
+		}
+		return 0
+
+	case "revoke":
+		if len(args) != 2 || !policy.IsGrant(args[1]) {
+			fmt.Fprintf(os.Stderr, "doit: usage: --grants revoke <%s...id>\n", policy.GrantIDPrefix)
+			return engine.ExitValidation
+		}
+		if err := policy.DeleteEntry(storePath, args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --grants revoke: %v\n", err)
+			return engine.ExitValidation
+		}
+		fmt.Printf("revoked %s\n", args[1])
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "doit: --grants: unknown subcommand %q (want list, add, or revoke)\n", args[0])
+		return engine.ExitValidation
+	}
+}
+
+func grantsList(storePath string, now time.Time) int {
+	entries, err := policy.LoadStore(storePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --grants list: %v\n", err)
+		return engine.ExitInternal
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	n := 0
+	for _, e := range entries {
+		if !policy.IsGrant(e.ID) || e.Expired(now) {
+			continue
+		}
+		if n == 0 {
+			fmt.Fprintln(tw, "ID\tEXPIRES\tPATTERN")
+		}
+		n++
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.ID, e.ExpiresAt.Local().Format("2006-01-02 15:04"), grantPattern(e.Match))
+	}
+	tw.Flush()
+	if n == 0 {
+		fmt.Println("no active grants")
+	}
+	return 0
+}
+
+// grantPattern renders match criteria back into the pattern syntax.
+func grantPattern(m policy.MatchCriteria) string {
+	words := []string{m.Cap}
+	if m.Subcmd != "" {
+		words = append(words, m.Subcmd)
+	}
+	return strings.Join(append(words, m.ArgsGlob...), " ")
+}
+
+// grantExpiry parses --for <duration> or --until <time>; "today" means
+// until midnight.
+func grantExpiry(flag, value string, now time.Time) (time.Time, error) {
+	if flag == "--for" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("--for: invalid duration %q", value)
+		}
+		return now.Add(d), nil
+	}
+	if value == "today" {
+		y, m, d := now.Date()
+		return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()), nil
+	}
+	if _, err := time.ParseDuration(value); err == nil {
+		return time.Time{}, fmt.Errorf("--until takes a time; use --for %s", value)
+	}
+	return parseTimeArg(value, now)
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/cap/builtin"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/manifest"
+)
+
+// newRegistry builds the capability registry as the engine would, with
+// tiers and rules applied from cfg.
+func newRegistry(cfg *config.Config) *cap.Registry {
+	reg := cap.NewRegistry()
+	builtin.RegisterAll(reg)
+	cfg.ApplyTiers(reg)
+	cfg.ApplyRules(reg)
+	return reg
+}
+
+// runHelpCap prints extended help for a single capability.
+func runHelpCap(configPath, name string) int {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	reg := newRegistry(cfg)
+	c, err := reg.Lookup(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	fmt.Print(cap.FormatHelp(c))
+	if err := reg.CheckTier(c.Tier()); err != nil {
+		fmt.Printf("\nNote: %v in the current config.\n", err)
+	}
+	return 0
+}
+
+// runHelpAgent prints the agent guide generated from the live config.
+func runHelpAgent(configPath string) int {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	manifest.Build(version, cfg, newRegistry(cfg)).WriteAgentGuide(os.Stdout)
+	return 0
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/audit"
+)
+
+// defaultHistory is the number of entries --history shows without N.
+const defaultHistory = 20
+
+// runHistory handles `doit --history [N]`: a summary of the last N audit
+// entries, oldest first, with the sequence numbers --rerun accepts.
+func runHistory(configPath string, args []string) int {
+	n := defaultHistory
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			fmt.Fprintf(os.Stderr, "doit: --history: invalid count %q\n", args[0])
+			return engine.ExitValidation
+		}
+		n = v
+	}
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "doit: --history: unexpected argument %q\n", args[1])
+		return engine.ExitValidation
+	}
+
+	migratePaths(configPath)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	entries, err := audit.Tail(cfg.Audit.Path, n)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0
+		}
+		// Malformed entries are skipped; show the rest with a warning.
+		fmt.Fprintf(os.Stderr, "doit: warning: %v\n", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEQ\tTIME\tEXIT\tPOLICY\tCWD\tCOMMAND")
+	for _, e := range entries {
+		policy := "-"
+		if e.PolicyResult != "" {
+			policy = fmt.Sprintf("L%d/%s", e.PolicyLevel, e.PolicyResult)
+		}
+		cmd := e.Pipeline
+		if e.Retry {
+			cmd += " (retry)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), exitStatus(e), policy, e.Cwd, oneLine(cmd))
+	}
+	tw.Flush()
+	return 0
+}
+
+// cliTokenSession scopes the approval tokens the CLI is issued. Each
+// invocation is a new process, so they share a session in order that the
+// invocation told to retry with a token can redeem it.
+const cliTokenSession = "cli"
+
+// runRerun handles `doit --rerun <seq> [--retry | --approved <token>]`: it
+// looks up the audit entry and submits the same command, working
+// directory, and agent justification through the current policy. The
+// entry's own retry is not replayed, lest rerunning it bypass rules
+// again unasked. With --retry, the entry must be a denial, and the rerun
+// retries past the rule that denied it. With --approved, the rerun
+// carries the token of an approved escalation. Output streams to the
+// terminal and the command's exit code becomes doit's. If policy
+// escalates, doit prints the command that retries with the token once a
+// human approves.
+func runRerun(configPath string, args []string, verbosity engine.Verbosity, offline bool) int {
+	retry, approved := false, ""
+	switch {
+	case len(args) == 2 && args[1] == "--retry":
+		retry, args = true, args[:1]
+	case len(args) == 3 && args[1] == "--approved" && args[2] != "":
+		approved, args = args[2], args[:1]
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --rerun <seq> [--retry | --approved <token>]\n")
+		return engine.ExitValidation
+	}
+	seq, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --rerun: invalid sequence number %q\n", args[0])
+		return engine.ExitValidation
+	}
+
+	migratePaths(configPath)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	entry, err := audit.Lookup(cfg.Audit.Path, seq)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --rerun: %v\n", err)
+		return engine.ExitValidation
+	}
+	if entry.Summary != nil {
+		fmt.Fprintf(os.Stderr, "doit: --rerun: entry %d closes a %s; it is not a command\n", seq, entry.Summary.Kind)
+		return engine.ExitValidation
+	}
+
+	eng, err := engine.New(engine.Options{
+		ConfigPath:   configPath,
+		Verbosity:    verbosity,
+		Offline:      offline,
+		TokenSession: cliTokenSession,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	defer eng.Close()
+
+	if verbosity != engine.VerbosityQuiet {
+		fmt.Fprintf(os.Stderr, "doit: re-running #%d: %s\n", entry.Seq, entry.Pipeline)
+	}
+
+	// Signals to doit go to the command; before it starts, they abandon
+	// the rerun.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigs)
+
+	req := engine.Request{
+		Command:       entry.Pipeline,
+		Cwd:           entry.Cwd,
+		Justification: entry.Justification,
+		SafetyArg:     entry.SafetyArg,
+		Approved:      approved,
+		Signals:       sigs,
+	}
+	if retry {
+		req.Retry, req.RetryRef = true, strconv.FormatUint(entry.Seq, 10)
+	}
+	res := eng.ExecuteStreaming(context.Background(), req, os.Stdout, os.Stderr)
+	if res.ExitCode == engine.ExitEscalationPending && res.EscalateToken != "" {
+		fmt.Fprintf(os.Stderr, "doit: once approved, run: doit --rerun %d --approved %s\n", entry.Seq, res.EscalateToken)
+	}
+	return res.ExitCode
+}
+
+// oneLine collapses newlines so multi-line commands fit a table row.
+func oneLine(s string) string {
+	return strings.ReplaceAll(s, "\n", " ⏎ ")
+}
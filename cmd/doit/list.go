@@ -0,0 +1,61 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/manifest"
+)
+
+// runList prints the capability catalogue: a table by default, or the
+// full manifest as JSON with --json.
+func runList(configPath string, args []string) int {
+	asJSON := false
+	for _, a := range args {
+		switch a {
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Fprintf(os.Stderr, "doit: --list: unknown flag %q\n", a)
+			return engine.ExitValidation
+		}
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	m := manifest.Build(version, cfg, newRegistry(cfg))
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(m); err != nil {
+			fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+			return engine.ExitInternal
+		}
+		return 0
+	}
+
+	for _, c := range m.Capabilities {
+		status := ""
+		switch {
+		case !c.Enabled:
+			status = " (tier disabled)"
+		case c.Unavailable != "":
+			status = " (unavailable: " + c.Unavailable + ")"
+		}
+		fmt.Printf("%-12s %-10s %s%s\n", c.Name, c.Tier, c.Description, status)
+	}
+	for _, w := range m.Warnings {
+		fmt.Fprintf(os.Stderr, "doit: warning: %s\n", w)
+	}
+	return 0
+}
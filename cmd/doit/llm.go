@@ -0,0 +1,73 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+// runLLM handles `doit --llm enable|disable|status`: turning the Level 3
+// gatekeeper of every running doit off and on, so that a flaky LLM need
+// not slow every ambiguous command while it is down. Turned off, doit
+// escalates those commands to a human. The switch lasts until the doit
+// exits.
+func runLLM(configPath string, args []string) int {
+	if len(args) != 1 || (args[0] != "enable" && args[0] != "disable" && args[0] != "status") {
+		fmt.Fprintf(os.Stderr, "doit: usage: --llm enable|disable|status\n")
+		return engine.ExitValidation
+	}
+	action := args[0]
+	if action == "status" {
+		action = ""
+	}
+	dir, code := eventsDir(configPath, "--llm")
+	if code != 0 {
+		return code
+	}
+
+	socks, _ := events.Sockets(dir)
+	reached, failed := 0, false
+	for _, path := range socks {
+		ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+		g, err := events.SetGatekeeper(ctx, path, action)
+		cancel()
+		var netErr *net.OpError
+		switch {
+		case err == nil:
+			reached++
+			fmt.Println(gatekeeperLine(g))
+		case errors.As(err, &netErr):
+			// A stale socket from a doit that has exited.
+		default:
+			failed = true
+			fmt.Fprintf(os.Stderr, "doit: --llm %s: %s: %v\n", args[0], path, err)
+		}
+	}
+	if reached == 0 && !failed {
+		fmt.Println("no running doit")
+	}
+	if failed {
+		return engine.ExitUnavailable
+	}
+	return 0
+}
+
+// gatekeeperLine describes one doit's gatekeeper.
+func gatekeeperLine(g *events.Gatekeeper) string {
+	switch {
+	case !g.Enabled:
+		return fmt.Sprintf("%d: disabled", g.PID)
+	case g.Open:
+		return fmt.Sprintf("%d: enabled, breaker open after %d failures until %s", g.PID, g.Failures, g.RetryAt.Format(time.TimeOnly))
+	}
+	return fmt.Sprintf("%d: enabled", g.PID)
+}
@@ -13,6 +13,8 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/mark3labs/mcp-go/server"
 
@@ -28,49 +30,177 @@ func main() {
 
 func run() int {
 	var configPath string
+	verbosity := engine.VerbosityNormal
+	offline := false
 	args := os.Args[1:]
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "--config":
 			if i+1 >= len(args) {
 				fmt.Fprintf(os.Stderr, "doit: --config requires a path argument\n")
-				return 1
+				return engine.ExitValidation
+			}
+			if isConfigSubcommand(args[i+1]) {
+				return runConfig(configPath, args[i+1:])
 			}
 			configPath = args[i+1]
 			i++
+		case "--quiet", "-q":
+			verbosity = engine.VerbosityQuiet
+		case "--verbose", "-v":
+			verbosity = engine.VerbosityVerbose
+		case "--offline":
+			offline = true
+		case "--list":
+			return runList(configPath, args[i+1:])
+		case "--manifest":
+			return runList(configPath, []string{"--json"})
+		case "--paths":
+			return runPaths(configPath)
+		case "--doctor":
+			return runDoctor(configPath)
+		case "--top":
+			return runTop(configPath)
+		case "--events":
+			return runEvents(configPath, args[i+1:])
+		case "--pending":
+			return runPending(configPath, args[i+1:])
+		case "--approve":
+			return runResolve(configPath, args[i+1:], true)
+		case "--deny":
+			return runResolve(configPath, args[i+1:], false)
+		case "--tokens":
+			return runTokens(args[i+1:])
+		case "--grants":
+			return runGrants(configPath, args[i+1:])
+		case "--quota":
+			return runQuota(configPath, args[i+1:])
+		case "--rules":
+			return runRules(configPath, args[i+1:])
+		case "--policy":
+			return runPolicy(configPath, args[i+1:])
+		case "--llm":
+			return runLLM(configPath, args[i+1:])
+		case "--feedback":
+			return runFeedback(configPath, args[i+1:])
+		case "--history":
+			return runHistory(configPath, args[i+1:])
+		case "--plan":
+			return runPlan(configPath, args[i+1:], verbosity, offline)
+		case "--batch":
+			return runBatch(configPath, args[i+1:], verbosity, offline)
+		case "--repl":
+			return runREPL(configPath, args[i+1:], verbosity, offline)
+		case "--serve-http":
+			return runServeHTTP(configPath, args[i+1:], verbosity, offline)
+		case "--rerun":
+			return runRerun(configPath, args[i+1:], verbosity, offline)
+		case "-c":
+			return runCommandLine(configPath, args[i+1:], verbosity, offline)
+		case "--audit":
+			return runAudit(configPath, args[i+1:])
 		case "--version":
 			fmt.Printf("doit %s\n", version)
 			return 0
+		case "--help-agent":
+			return runHelpAgent(configPath)
 		case "--help":
-			fmt.Fprintf(os.Stderr, "Usage: doit [--config <path>] [--version] [--help]\n\n")
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				return runHelpCap(configPath, args[i+1])
+			}
+			fmt.Fprintf(os.Stderr, "Usage: doit [--config <path>] [--quiet | --verbose] [--offline] [--version] [--help [<capability>]] [--help-agent]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --audit verify [--full]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --audit tail [-n N] [-f]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --audit export [--session <id>] [--format md|html]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --audit diff [--since <time>] [--until <time>]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --audit llm <seq>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --config check\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --config get|unset <key>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --config set <key> <value>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --events [<type>,...] | clean\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --top\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --pending\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --approve|--deny <id> [--always]\n")
+			fmt.Fprintf(os.Stderr, "       doit --tokens list | revoke <token> | issue <command>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --grants list | add (--for <duration> | --until <time>) <pattern> | revoke <id>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --quota [list] | reset <agent>[@<session>]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --rules test [--project <dir>] <cases.yaml>...\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --rules audit-coverage [--project <dir>] [--corpus <file.yaml>]...\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --policy review | approve-script|revoke-script <path>...\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --llm enable|disable|status\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --feedback <seq> good|bad [--note <text>]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --history [N]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] [--quiet | --verbose] [--offline] --rerun <seq> [--retry | --approved <token>]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] [--quiet | --verbose] [--offline] -c <command> [--approved <token>]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] [--quiet | --verbose] [--offline] --plan <plan.yaml>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] [--quiet | --verbose] [--offline] --batch <file.doit> [--keep-going]\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] [--quiet | --verbose] [--offline] --repl\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] [--quiet | --verbose] [--offline] --serve-http <host:port>\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --paths\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --doctor\n")
+			fmt.Fprintf(os.Stderr, "       doit [--config <path>] --list [--json] | --manifest\n\n")
 			fmt.Fprintf(os.Stderr, "MCP server for doit's policy engine (stdio transport).\n")
 			return 0
 		default:
 			fmt.Fprintf(os.Stderr, "doit: unknown flag %q\n", args[i])
-			return 1
+			return engine.ExitValidation
 		}
 	}
 
 	// Suppress log output — MCP clients may interpret stderr as errors.
-	log.SetOutput(io.Discard)
+	// --verbose opts back in for diagnosing policy decisions.
+	if verbosity != engine.VerbosityVerbose {
+		log.SetOutput(io.Discard)
+	}
 
-	eng, err := engine.New(engine.Options{ConfigPath: configPath})
+	migratePaths(configPath)
+
+	eng, err := engine.New(engine.Options{ConfigPath: configPath, Verbosity: verbosity, Offline: offline})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
-		return 1
+		return engine.ExitInternal
 	}
 	defer eng.Close()
 
-	srv := server.NewMCPServer("doit", version, server.WithElicitation())
+	srv := server.NewMCPServer("doit", version,
+		server.WithElicitation(),
+		server.WithInstructions(eng.AgentGuide(version)),
+		server.WithToolHandlerMiddleware(mcptools.Recover),
+	)
 	mcptools.Register(srv, eng)
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	// Termination signals cancel in-flight commands, which get SIGTERM
+	// and, after exec.kill_grace, SIGKILL.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	defer stop()
 
+	// Wait for the events socket to close so it is removed before exit.
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := eng.ServeEvents(ctx); err != nil {
+			log.Printf("doit: %v", err)
+		}
+	}()
+	defer func() {
+		stop()
+		<-served
+	}()
+
 	stdio := server.NewStdioServer(srv)
-	if err := stdio.Listen(ctx, os.Stdin, os.Stdout); err != nil {
+	err = stdio.Listen(ctx, os.Stdin, os.Stdout)
+
+	// The client may hang up while a long build is still running; let it
+	// finish and be audited. A further interrupt abandons it.
+	drainCtx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	if eng.Drain(drainCtx) != nil {
+		log.Printf("doit: exiting with %d requests in flight", eng.Active())
+	}
+	cancel()
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
-		return 1
+		return engine.ExitInternal
 	}
 
 	return 0
@@ -0,0 +1,87 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/agent"
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/paths"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// migratePaths relocates files left at doit's pre-XDG default locations.
+// Files whose location the user has set explicitly (via --config,
+// audit.path or policy.level2_path) are left where they are.
+func migratePaths(configPath string) {
+	if configPath == "" {
+		logMoves(paths.Migrate(func(to string) bool { return to == paths.ConfigFile() }))
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return // reported by whoever loads the config next
+	}
+	logMoves(paths.Migrate(func(to string) bool {
+		switch to {
+		case paths.ConfigFile():
+			return false // handled above
+		case paths.AuditLog():
+			return cfg.Audit.Path == to
+		case audit.CheckpointPath(paths.AuditLog()):
+			return cfg.Audit.Path == paths.AuditLog()
+		case paths.LearnedPolicy():
+			return cfg.Policy.Level2Path == "" || cfg.Policy.Level2Path == to
+		}
+		return true
+	}))
+}
+
+func logMoves(moves []paths.Move, err error) {
+	for _, m := range moves {
+		log.Printf("doit: migrated %s -> %s", m.From, m.To)
+	}
+	if err != nil {
+		log.Printf("doit: %v", err)
+	}
+}
+
+// runPaths prints the directories and files doit uses.
+func runPaths(configPath string) int {
+	migratePaths(configPath)
+	if configPath == "" {
+		configPath = config.ConfigPath()
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	storePath := cfg.Policy.Level2Path
+	if storePath == "" {
+		storePath = policy.DefaultStorePath()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "config dir\t%s\n", paths.ConfigDir())
+	fmt.Fprintf(tw, "data dir\t%s\n", paths.DataDir())
+	fmt.Fprintf(tw, "state dir\t%s\n", paths.StateDir())
+	fmt.Fprintf(tw, "config\t%s\n", configPath)
+	fmt.Fprintf(tw, "audit log\t%s\n", cfg.Audit.Path)
+	fmt.Fprintf(tw, "audit watermark\t%s\n", audit.CheckpointPath(cfg.Audit.Path))
+	fmt.Fprintf(tw, "learned policy\t%s\n", storePath)
+	fmt.Fprintf(tw, "approval tokens\t%s\n", policy.DefaultTokenPath())
+	fmt.Fprintf(tw, "llm feedback\t%s\n", policy.DefaultFeedbackPath())
+	fmt.Fprintf(tw, "agent ledger\t%s\n", agent.LedgerPath(cfg.Audit.Path))
+	if cfg.Policy.StarlarkRulesDir != "" {
+		fmt.Fprintf(tw, "starlark rules\t%s\n", cfg.Policy.StarlarkRulesDir)
+	}
+	tw.Flush()
+	return 0
+}
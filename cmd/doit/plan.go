@@ -0,0 +1,69 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/marcelocantos/doit/engine"
+)
+
+// runPlan handles `doit --plan <plan.yaml>`: judging every step of a plan,
+// asking a human once for the steps that escalate, and running the steps
+// in order. While it waits, the plan is listed by doit --pending and
+// doit --top and resolved with doit --approve or --deny.
+func runPlan(configPath string, args []string, verbosity engine.Verbosity, offline bool) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --plan <plan.yaml>\n")
+		return engine.ExitValidation
+	}
+	plan, err := engine.LoadPlan(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	for i := range plan.Steps {
+		if dir := plan.Steps[i].Cwd; !filepath.IsAbs(dir) {
+			plan.Steps[i].Cwd = filepath.Join(cwd, dir)
+		}
+	}
+
+	migratePaths(configPath)
+	eng, err := engine.New(engine.Options{ConfigPath: configPath, Verbosity: verbosity, Offline: offline})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	defer eng.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := eng.ServeEvents(ctx); err != nil {
+			log.Printf("doit: %v", err)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	// An interrupt abandons a plan awaiting approval and stops a running
+	// step (SIGTERM, then SIGKILL after exec.kill_grace).
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	return eng.RunPlan(runCtx, plan, os.Stdout, os.Stderr)
+}
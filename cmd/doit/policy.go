@@ -0,0 +1,164 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// runPolicy handles `doit --policy approve-script|revoke-script <path>...`:
+// blessing repository scripts by content hash in the global config; and
+// `doit --policy review`.
+func runPolicy(configPath string, args []string) int {
+	if len(args) == 1 && args[0] == "review" {
+		return runPolicyReview(configPath)
+	}
+	if len(args) < 2 || (args[0] != "approve-script" && args[0] != "revoke-script") {
+		fmt.Fprintf(os.Stderr, "doit: usage: --policy review | approve-script|revoke-script <path>...\n")
+		return engine.ExitValidation
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	path, data, err := readConfigFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+
+	for _, script := range args[1:] {
+		resolved, sum, err := policy.ScriptDigest(cwd, script)
+		if err != nil && args[0] == "revoke-script" {
+			// A deleted script can still be revoked.
+			resolved, err = filepath.Abs(script)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --policy %s: %v\n", args[0], err)
+			return engine.ExitValidation
+		}
+		if args[0] == "approve-script" {
+			if data, err = config.ApproveScript(data, resolved, sum); err != nil {
+				fmt.Fprintf(os.Stderr, "doit: --policy approve-script: %v\n", err)
+				return engine.ExitValidation
+			}
+			fmt.Printf("approved %s (sha256 %s)\n", resolved, sum)
+			continue
+		}
+		var found bool
+		if data, found, err = config.RevokeScript(data, resolved); err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --policy revoke-script: %v\n", err)
+			return engine.ExitValidation
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "doit: --policy revoke-script: %s is not approved\n", resolved)
+			return engine.ExitValidation
+		}
+		fmt.Printf("revoked %s\n", resolved)
+	}
+
+	if err := config.WriteFileAtomic(path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	return 0
+}
+
+// runPolicyReview lists the learned policy entries that want a human's
+// attention: candidates awaiting approval and approved entries due for
+// review. Under each is the feedback given on commands it matches.
+func runPolicyReview(configPath string) int {
+	migratePaths(configPath)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	storePath := cfg.Policy.Level2Path
+	if storePath == "" {
+		storePath = policy.DefaultStorePath()
+	}
+	entries, err := policy.LoadStore(storePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	feedback, err := policy.LoadFeedback(policy.DefaultFeedbackPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: warning: %v\n", err)
+	}
+
+	now := time.Now()
+	shown := 0
+	for i := range entries {
+		ent := &entries[i]
+		var status string
+		switch {
+		case ent.Expired(now):
+			continue
+		case !ent.Approved:
+			status = "awaiting approval"
+		case !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview, now):
+			status = "due for review"
+		default:
+			continue
+		}
+		if shown > 0 {
+			fmt.Println()
+		}
+		shown++
+		fmt.Printf("%s: %s %s (%s)\n", ent.ID, ent.Decision, matchString(ent.Match), status)
+		if ent.Reasoning != "" {
+			fmt.Printf("  %s\n", ent.Reasoning)
+		}
+		if c := ent.Conditions.String(); c != "" {
+			fmt.Printf("  on conditions: %s\n", c)
+		}
+		for _, fb := range policy.FeedbackFor(ent, feedback) {
+			fmt.Printf("  feedback on #%d: %s\n", fb.Seq, fb)
+		}
+	}
+	if shown == 0 {
+		fmt.Println("no learned policies need review")
+	}
+	return 0
+}
+
+// matchString renders match criteria as the command they describe.
+func matchString(m policy.MatchCriteria) string {
+	if len(m.Pipeline) > 0 {
+		stages := make([]string, len(m.Pipeline))
+		for i, s := range m.Pipeline {
+			stages[i] = matchString(s)
+		}
+		return strings.Join(stages, " | ")
+	}
+	words := []string{m.Cap}
+	if m.Subcmd != "" {
+		words = append(words, m.Subcmd)
+	}
+	words = append(words, m.ArgsGlob...)
+	for _, f := range m.HasFlags {
+		words = append(words, "+"+f)
+	}
+	for _, f := range m.NoFlags {
+		words = append(words, "!"+f)
+	}
+	for _, d := range m.PathsWithin {
+		words = append(words, "within:"+d)
+	}
+	for _, d := range m.PathsOutside {
+		words = append(words, "outside:"+d)
+	}
+	return strings.Join(words, " ")
+}
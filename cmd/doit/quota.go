@@ -0,0 +1,98 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/agent"
+	"github.com/marcelocantos/doit/internal/config"
+)
+
+// runQuota handles `doit --quota [list] | reset <agent>[@<session>]`:
+// what each agent has used of its quotas and whether it has read a
+// secret, and the human's reset of an agent whose quota is exceeded or
+// who is tainted.
+func runQuota(configPath string, args []string) int {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	ledger := agent.NewLedger(agent.LedgerPath(cfg.Audit.Path), nil)
+
+	if len(args) == 0 || args[0] == "list" {
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "doit: --quota list: unexpected argument %q\n", args[1])
+			return engine.ExitValidation
+		}
+		return quotaList(cfg, ledger)
+	}
+	if args[0] != "reset" {
+		fmt.Fprintf(os.Stderr, "doit: --quota: unknown subcommand %q (want list or reset)\n", args[0])
+		return engine.ExitValidation
+	}
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --quota reset <agent>[@<session>]\n")
+		return engine.ExitValidation
+	}
+	found, err := ledger.Reset(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --quota reset: %v\n", err)
+		return engine.ExitInternal
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "doit: --quota reset: nothing counted for %s\n", args[1])
+		return engine.ExitValidation
+	}
+	fmt.Printf("reset %s\n", args[1])
+	return 0
+}
+
+func quotaList(cfg *config.Config, ledger *agent.Ledger) int {
+	usage, err := ledger.Usage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --quota: %v\n", err)
+		return engine.ExitInternal
+	}
+	if len(usage) == 0 {
+		fmt.Println("no quota use recorded")
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tCOMMANDS/H\tWRITES\tWRITTEN MB\tSTATUS")
+	for _, u := range usage {
+		name, _, _ := strings.Cut(u.Key, "@")
+		q := cfg.QuotasFor(name)
+		var status []string
+		if u.Exceeded != "" {
+			status = append(status, "exceeded: "+u.Exceeded)
+		}
+		if u.Tainted != "" {
+			status = append(status, "tainted: read "+u.Tainted)
+		}
+		if status == nil {
+			status = []string{"ok"}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", u.Key,
+			ofLimit(fmt.Sprint(u.Commands), q.CommandsPerHour),
+			ofLimit(fmt.Sprint(u.Writes), q.WriteOps),
+			ofLimit(fmt.Sprintf("%.1f", float64(u.Bytes)/(1<<20)), q.WrittenMB),
+			strings.Join(status, "; "))
+	}
+	tw.Flush()
+	return 0
+}
+
+// ofLimit renders a count against its quota, if it has one.
+func ofLimit(count string, limit int) string {
+	if limit <= 0 {
+		return count
+	}
+	return fmt.Sprintf("%s/%d", count, limit)
+}
@@ -0,0 +1,192 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/marcelocantos/doit/doitclient"
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/wire"
+)
+
+// replRequest and replResult are the lines doit --repl reads and prints:
+// doitclient's requests and results, with an id a request may carry to be
+// echoed in its result.
+type (
+	replRequest struct {
+		ID any `json:"id,omitempty"`
+		doitclient.Request
+	}
+	replResult struct {
+		ID any `json:"id,omitempty"`
+		doitclient.Result
+	}
+)
+
+// maxREPLLine bounds a line of doit --repl input, which carries the
+// command's stdin, as the HTTP API bounds a request body.
+const maxREPLLine = 32 << 20
+
+// runREPL handles `doit --repl`: it reads requests from stdin, one per
+// line, and prints each one's result as a JSON line, so a wrapper pays
+// doit's startup once rather than per command. A line is either a
+// command, run from the current directory, or a JSON request object
+// ({"id": 1, "command": "make", "cwd": "/src"}). Requests run one at a
+// time; while one awaits approval it is listed by doit --pending and doit
+// --top. A line over 32 MiB is refused unread. The session ends at end of input, or on an interrupt, which also
+// stops the running command.
+func runREPL(configPath string, args []string, verbosity engine.Verbosity, offline bool) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "doit: --repl: unexpected argument %q\n", args[0])
+		return engine.ExitValidation
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+
+	migratePaths(configPath)
+	eng, err := engine.New(engine.Options{
+		ConfigPath:   configPath,
+		Verbosity:    verbosity,
+		Offline:      offline,
+		TokenSession: cliTokenSession,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	defer eng.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := eng.ServeEvents(ctx); err != nil {
+			log.Printf("doit: %v", err)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		in := bufio.NewReader(os.Stdin)
+		for {
+			line, err := readLine(in, maxREPLLine)
+			if line != "" {
+				lines <- line
+			}
+			if err != nil {
+				if err != io.EOF {
+					readErr <- err
+				}
+				return
+			}
+		}
+	}()
+
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		var line string
+		var ok bool
+		select {
+		case <-runCtx.Done():
+			return 0
+		case line, ok = <-lines:
+		}
+		if !ok {
+			break
+		}
+		var res *replResult
+		switch line = strings.TrimSpace(line); {
+		case line == "":
+			continue
+		case line == lineTooLong:
+			res = replInvalid(nil, "request line longer than %d bytes", maxREPLLine)
+		default:
+			res = replExecute(runCtx, eng, line, cwd)
+		}
+		if err := enc.Encode(res); err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --repl: %v\n", err)
+			return engine.ExitInternal
+		}
+	}
+	select {
+	case err := <-readErr:
+		fmt.Fprintf(os.Stderr, "doit: --repl: reading stdin: %v\n", err)
+		return engine.ExitInternal
+	default:
+		return 0
+	}
+}
+
+// lineTooLong stands in for a line readLine refused: a NUL cannot
+// otherwise start a line of JSON or a command.
+const lineTooLong = "\x00too long"
+
+// readLine reads one line of at most max bytes from r. A longer line is
+// discarded through its newline and returned as lineTooLong.
+func readLine(r *bufio.Reader, max int) (string, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if tooLong = tooLong || len(line)+len(chunk) > max; !tooLong {
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			if tooLong {
+				return lineTooLong, err
+			}
+			return string(line), err
+		}
+	}
+}
+
+// replInvalid is the result of a request that could not be made.
+func replInvalid(id any, format string, args ...any) *replResult {
+	res := &replResult{ID: id}
+	res.ExitCode = engine.ExitValidation
+	res.Error = &doitclient.Error{Kind: engine.ErrorValidation, Message: fmt.Sprintf(format, args...)}
+	return res
+}
+
+// replExecute runs the request on one line of doit --repl input.
+func replExecute(ctx context.Context, eng *engine.Engine, line, cwd string) *replResult {
+	var r replRequest
+	r.Command = line
+	if strings.HasPrefix(line, "{") {
+		r = replRequest{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return replInvalid(nil, "invalid request: %v", err)
+		}
+		if strings.TrimSpace(r.Command) == "" {
+			return replInvalid(r.ID, "missing required field: command")
+		}
+	}
+	if r.Cwd == "" {
+		r.Cwd = cwd
+	}
+	res := eng.Execute(ctx, wire.Request(r.Request))
+	return &replResult{ID: r.ID, Result: wire.Result(res)}
+}
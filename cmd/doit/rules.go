@@ -0,0 +1,164 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// runRules handles `doit --rules test|audit-coverage`.
+func runRules(configPath string, args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "test":
+			return runRulesTest(configPath, args[1:])
+		case "audit-coverage":
+			return runRulesCoverage(configPath, args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "doit: usage: --rules test [--project <dir>] <cases.yaml>... | audit-coverage [--project <dir>] [--corpus <file.yaml>]...\n")
+	return engine.ExitValidation
+}
+
+// runRulesTest runs policy regression suites against the live L1/L2
+// configuration, printing each mismatch. It exits 1 if any case fails.
+func runRulesTest(configPath string, args []string) int {
+	var projectRoot string
+	var files []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--project":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "doit: --rules test: --project requires a directory\n")
+				return engine.ExitValidation
+			}
+			projectRoot = args[i+1]
+			i++
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --rules test [--project <dir>] <cases.yaml>...\n")
+		return engine.ExitValidation
+	}
+
+	var suites [][]engine.RuleTestCase
+	for _, f := range files {
+		cases, err := engine.LoadRuleTests(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --rules test: %v\n", err)
+			return engine.ExitValidation
+		}
+		suites = append(suites, cases)
+	}
+
+	eng, code := rulesEngine(configPath, projectRoot)
+	if eng == nil {
+		return code
+	}
+	defer eng.Close()
+
+	passed, failed := 0, 0
+	for i, cases := range suites {
+		for _, r := range eng.RunRuleTests(cases) {
+			if r.Pass {
+				passed++
+				continue
+			}
+			failed++
+			want := r.Case.Expect
+			if r.Case.Rule != "" {
+				want += " by " + r.Case.Rule
+			}
+			got := fmt.Sprintf("%s (L%d", r.Decision, r.Level)
+			if r.RuleID != "" {
+				got += ", " + r.RuleID
+			}
+			got += ")"
+			fmt.Printf("FAIL %s: %s\n     got %s, want %s: %s\n", files[i], oneLine(r.Case.Command), got, want, r.Reason)
+		}
+	}
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runRulesCoverage reports which known-dangerous commands the local policy
+// catches at L1 or L2, which are left to L3, and which it allows. It
+// exits 1 if local policy allows any of them.
+func runRulesCoverage(configPath string, args []string) int {
+	var projectRoot string
+	corpus := append([]policy.CorpusEntry(nil), policy.DangerousCorpus...)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--project", "--corpus":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "doit: --rules audit-coverage: %s requires an argument\n", args[i])
+				return engine.ExitValidation
+			}
+			if args[i] == "--project" {
+				projectRoot = args[i+1]
+			} else {
+				extra, err := policy.LoadCorpus(args[i+1])
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "doit: --rules audit-coverage: %v\n", err)
+					return engine.ExitValidation
+				}
+				corpus = append(corpus, extra...)
+			}
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "doit: --rules audit-coverage: unexpected argument %q\n", args[i])
+			return engine.ExitValidation
+		}
+	}
+
+	eng, code := rulesEngine(configPath, projectRoot)
+	if eng == nil {
+		return code
+	}
+	defer eng.Close()
+
+	counts := map[string]int{}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OUTCOME\tCATEGORY\tRULE\tCOMMAND")
+	for _, r := range eng.AuditCoverage(corpus) {
+		counts[r.Outcome]++
+		rule := r.RuleID
+		if rule == "" {
+			rule = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Outcome, r.Entry.Category, rule, oneLine(r.Entry.Command))
+	}
+	tw.Flush()
+	fmt.Printf("\n%d caught at L1, %d caught at L2, %d rely on L3, %d pass\n",
+		counts[engine.CoverageL1], counts[engine.CoverageL2], counts[engine.CoverageL3], counts[engine.CoverageAllows])
+	if counts[engine.CoverageAllows] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// rulesEngine starts an engine for offline policy evaluation. On failure
+// it returns nil and the exit code.
+func rulesEngine(configPath, projectRoot string) (*engine.Engine, int) {
+	log.SetOutput(io.Discard) // engine start-up chatter would bury the report
+	migratePaths(configPath)
+	eng, err := engine.New(engine.Options{ConfigPath: configPath, ProjectRoot: projectRoot})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return nil, engine.ExitInternal
+	}
+	return eng, 0
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/httpapi"
+)
+
+// httpTokenEnv names the variable that sets --serve-http's bearer token.
+const httpTokenEnv = "DOIT_HTTP_TOKEN"
+
+// runServeHTTP handles `doit --serve-http <host:port>`: serving the REST
+// API of package httpapi on a loopback address until interrupted. The
+// bearer token comes from $DOIT_HTTP_TOKEN, or is generated and printed.
+func runServeHTTP(configPath string, args []string, verbosity engine.Verbosity, offline bool) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --serve-http <host:port>\n")
+		return engine.ExitValidation
+	}
+	addr := args[0]
+	if err := checkLoopback(addr); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --serve-http: %v\n", err)
+		return engine.ExitValidation
+	}
+	token := os.Getenv(httpTokenEnv)
+	if token == "" {
+		b := make([]byte, 24)
+		rand.Read(b)
+		token = hex.EncodeToString(b)
+	}
+
+	migratePaths(configPath)
+	eng, err := engine.New(engine.Options{ConfigPath: configPath, Verbosity: verbosity, Offline: offline})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	defer eng.Close()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --serve-http: %v\n", err)
+		return engine.ExitUnavailable
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := eng.ServeEvents(ctx); err != nil {
+			log.Printf("doit: %v", err)
+		}
+	}()
+	defer func() {
+		stop()
+		<-served
+	}()
+
+	srv := &http.Server{
+		Handler:           httpapi.Handler(eng, token, version),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	if os.Getenv(httpTokenEnv) == "" {
+		fmt.Fprintf(os.Stderr, "doit: serving http://%s (bearer token %s)\n", ln.Addr(), token)
+	} else {
+		fmt.Fprintf(os.Stderr, "doit: serving http://%s (bearer token from $%s)\n", ln.Addr(), httpTokenEnv)
+	}
+
+	select {
+	case err := <-errc:
+		fmt.Fprintf(os.Stderr, "doit: --serve-http: %v\n", err)
+		return engine.ExitInternal
+	case <-ctx.Done():
+	}
+
+	// Let running commands finish and be audited; a further interrupt
+	// abandons them.
+	drainCtx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		srv.Close()
+		log.Printf("doit: exiting with %d requests in flight", eng.Active())
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "doit: --serve-http: %v\n", err)
+		return engine.ExitInternal
+	}
+	return 0
+}
+
+// checkLoopback reports an address other than a loopback one: the API
+// runs commands, so it serves only this machine.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%s is not a loopback address; doit serves only this machine", host)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// tokenPrefixLen is how much of each token --tokens list shows; revoke
+// accepts any unique prefix.
+const tokenPrefixLen = 12
+
+// runTokens handles `doit --tokens list|revoke|issue`, operating on the
+// persisted approval token store shared by every running doit.
+func runTokens(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "doit: usage: --tokens list | revoke <token> | issue <command>\n")
+		return engine.ExitValidation
+	}
+	store := policy.OpenTokenStore(policy.DefaultTokenPath(), policy.DefaultTokenTTL)
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			fmt.Fprintf(os.Stderr, "doit: --tokens list: unexpected argument %q\n", args[1])
+			return engine.ExitValidation
+		}
+		return tokensList(store)
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "doit: usage: --tokens revoke <token>\n")
+			return engine.ExitValidation
+		}
+		return tokensRevoke(store, args[1])
+	case "issue":
+		command := strings.Join(args[1:], " ")
+		if len(engine.SplitCommand(command)) == 0 {
+			fmt.Fprintf(os.Stderr, "doit: usage: --tokens issue <command>\n")
+			return engine.ExitValidation
+		}
+		// Tokens are matched against the command line exactly as the
+		// shell will run it, as the engine binds them when it issues them.
+		token, err := store.Issue(command, engine.ApprovalArgs(command))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "doit: --tokens issue: %v\n", err)
+			return engine.ExitInternal
+		}
+		fmt.Println(token)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "doit: --tokens: unknown subcommand %q (want list, revoke, or issue)\n", args[0])
+		return engine.ExitValidation
+	}
+}
+
+func tokensList(store *policy.TokenStore) int {
+	records, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --tokens list: %v\n", err)
+		return engine.ExitInternal
+	}
+	if len(records) == 0 {
+		fmt.Println("no approval tokens")
+		return 0
+	}
+	now := time.Now()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOKEN\tSTATE\tISSUED\tEXPIRES\tCOMMAND")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Token[:min(tokenPrefixLen, len(r.Token))], r.State(now),
+			r.CreatedAt.Local().Format("2006-01-02 15:04:05"), r.ExpiresAt.Local().Format("15:04:05"), oneLine(r.Command))
+	}
+	tw.Flush()
+	return 0
+}
+
+func tokensRevoke(store *policy.TokenStore, prefix string) int {
+	records, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --tokens revoke: %v\n", err)
+		return engine.ExitInternal
+	}
+	var matches []policy.TokenRecord
+	for _, r := range records {
+		if strings.HasPrefix(r.Token, prefix) {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		fmt.Fprintf(os.Stderr, "doit: --tokens revoke: no token %q\n", prefix)
+		return engine.ExitValidation
+	case 1:
+	default:
+		fmt.Fprintf(os.Stderr, "doit: --tokens revoke: %q matches %d tokens\n", prefix, len(matches))
+		return engine.ExitValidation
+	}
+	r := matches[0]
+	if !store.Revoke(r.Token) {
+		fmt.Fprintf(os.Stderr, "doit: --tokens revoke: token %s is %s\n", r.Token[:min(tokenPrefixLen, len(r.Token))], r.State(time.Now()))
+		return engine.ExitValidation
+	}
+	fmt.Printf("revoked %s (%s)\n", r.Token[:min(tokenPrefixLen, len(r.Token))], oneLine(r.Command))
+	return 0
+}
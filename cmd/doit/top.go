@@ -0,0 +1,81 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/top"
+)
+
+// runTop handles `doit --top`: a live dashboard over every running doit.
+func runTop(configPath string) int {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitValidation
+	}
+	if !cfg.Events.Socket {
+		fmt.Fprintf(os.Stderr, "doit: --top: events.socket is disabled in the config\n")
+		return engine.ExitUnavailable
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --top needs a terminal: %v\n", err)
+		return engine.ExitValidation
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		fmt.Fprintf(os.Stderr, "doit: --top: %v\n", err)
+		return engine.ExitInternal
+	}
+	// Alternate screen, hidden cursor; restored on the way out.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		stty(strings.TrimSpace(saved))
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = top.Run(ctx, top.Options{
+		Dir:  cfg.Events.Dir,
+		In:   os.Stdin,
+		Out:  os.Stdout,
+		Size: terminalSize,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "doit: %v\n", err)
+		return engine.ExitInternal
+	}
+	return 0
+}
+
+// stty runs stty against the controlling terminal on stdin.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// terminalSize returns the terminal's columns and rows, or 0, 0 if unknown.
+func terminalSize() (w, h int) {
+	out, err := stty("size")
+	if err != nil {
+		return 0, 0
+	}
+	fmt.Sscan(out, &h, &w)
+	return w, h
+}
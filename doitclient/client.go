@@ -0,0 +1,246 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package doitclient submits commands to doit from other Go programs
+// (IDE plugins, bots, wrappers) without linking doit's engine. A Client
+// keeps one `doit --repl` process running and exchanges JSON requests and
+// results with it, so every command goes through the policy chain, audit
+// log, and approvals of the doit installed on the machine, and pays its
+// startup once.
+//
+//	c, err := doitclient.Connect(ctx, doitclient.Options{})
+//	...
+//	defer c.Close()
+//	res, err := c.Do(ctx, doitclient.Request{Command: "go test ./...", Cwd: dir})
+package doitclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Exit codes doit sets in Result.ExitCode when it, rather than the
+// command, determines the outcome. They match the engine's.
+const (
+	ExitPolicyDeny        = 90 // policy denied the command; it did not run
+	ExitEscalationPending = 91 // the command awaits approval; retry with Result.EscalateToken
+	ExitValidation        = 92 // the request was invalid; it did not run
+	ExitUnavailable       = 93 // something doit needs to run the command was unavailable
+	ExitInternal          = 94 // doit failed internally
+)
+
+// ErrClosed is returned by a Client whose doit has exited.
+var ErrClosed = errors.New("doitclient: client closed")
+
+// Request is a command for doit to judge and run. Its fields are those of
+// the doit_execute MCP tool.
+type Request struct {
+	Command       string            `json:"command"`
+	Cwd           string            `json:"cwd,omitempty"` // absolute; "" for the client's own
+	Env           map[string]string `json:"env,omitempty"` // overlaid on doit's environment
+	Stdin         string            `json:"stdin,omitempty"`
+	Justification string            `json:"justification,omitempty"`
+	SafetyArg     string            `json:"safety_arg,omitempty"`
+	Approved      string            `json:"approved,omitempty"`     // approval token of an escalation
+	Retry         bool              `json:"retry,omitempty"`        // retry past the denial RetryRef names
+	RetryRef      string            `json:"retry_ref,omitempty"`    // audit seq or rule ID of the denial
+	Agent         string            `json:"agent,omitempty"`        // agent making the request; "" for doit's $DOIT_AGENT
+	AgentSecret   string            `json:"agent_secret,omitempty"` // proves Agent's identity
+}
+
+// Policy is the policy decision on a request.
+type Policy struct {
+	Level    int    `json:"level"`
+	Decision string `json:"decision"` // "allow", "deny", or "escalate"
+	Reason   string `json:"reason"`
+	RuleID   string `json:"rule_id"`
+}
+
+// Error explains why doit rather than the command decided a request's
+// outcome.
+type Error struct {
+	Kind       string `json:"kind"` // "validation", "policy", "exec", or "internal"
+	Message    string `json:"message"`
+	Segment    int    `json:"segment,omitempty"`
+	RuleID     string `json:"rule_id,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+	Retryable  bool   `json:"retryable,omitempty"`
+	Approval   bool   `json:"approval,omitempty"`
+}
+
+// Result is the outcome of a request, in the shape of the doit_execute
+// tool's result.
+type Result struct {
+	ExitCode      int     `json:"exit_code"`
+	Signal        string  `json:"signal,omitempty"`
+	Stdout        string  `json:"stdout,omitempty"`
+	Stderr        string  `json:"stderr,omitempty"`
+	Policy        *Policy `json:"policy,omitempty"`
+	EscalateToken string  `json:"escalate_token,omitempty"`
+	Error         *Error  `json:"error,omitempty"`
+}
+
+// Request and result lines carry an id, so each result can be matched
+// with its request.
+type (
+	wireRequest struct {
+		ID int64 `json:"id"`
+		Request
+	}
+	wireResult struct {
+		ID any `json:"id"`
+		Result
+	}
+)
+
+// Options configures Connect.
+type Options struct {
+	Path       string    // the doit binary; "" finds doit on PATH
+	ConfigPath string    // passed as --config if set
+	Offline    bool      // passed as --offline
+	Stderr     io.Writer // doit's diagnostics; nil discards them
+}
+
+// Client is a connection to a doit process. Its methods may be called
+// from several goroutines; requests run one at a time.
+type Client struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	results chan wireResult
+	done    chan struct{} // closed when doit's output ends
+	readErr error         // why it ended, if not cleanly
+	quit    chan struct{} // closed by Close: results are no longer wanted
+
+	mu          sync.Mutex // serialises requests
+	nextID      int64
+	interrupted bool // a cancelled request ended the session
+	closeOnce   sync.Once
+	closeErr    error
+}
+
+// Connect starts doit --repl. The process lives until Close, or until ctx
+// is cancelled.
+func Connect(ctx context.Context, opts Options) (*Client, error) {
+	path := opts.Path
+	if path == "" {
+		path = "doit"
+	}
+	var args []string
+	if opts.ConfigPath != "" {
+		args = append(args, "--config", opts.ConfigPath)
+	}
+	if opts.Offline {
+		args = append(args, "--offline")
+	}
+	args = append(args, "--repl")
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.Stderr = opts.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("doitclient: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("doitclient: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("doitclient: start doit: %w", err)
+	}
+	c := &Client{cmd: cmd, stdin: stdin, results: make(chan wireResult), done: make(chan struct{}), quit: make(chan struct{})}
+	go c.read(stdout)
+	return c, nil
+}
+
+// read delivers the results doit prints until its output ends.
+func (c *Client) read(r io.Reader) {
+	defer close(c.done)
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var res wireResult
+		if err := dec.Decode(&res); err != nil {
+			if err != io.EOF {
+				c.readErr = fmt.Errorf("doitclient: read result: %w", err)
+			}
+			return
+		}
+		select {
+		case c.results <- res:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// Do submits req and waits for its result. A request that escalates
+// waits, as doit does, for a human to resolve it (doit --top); if nobody
+// does, its result carries ExitEscalationPending and the token to resubmit
+// with once approved. Cancelling ctx interrupts doit, which stops the
+// running command and ends the session: the client is then closed.
+func (c *Client) Do(ctx context.Context, req Request) (*Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.interrupted {
+		return nil, ErrClosed
+	}
+
+	c.nextID++
+	line, err := json.Marshal(wireRequest{ID: c.nextID, Request: req})
+	if err != nil {
+		return nil, fmt.Errorf("doitclient: %w", err)
+	}
+	if _, err := c.stdin.Write(append(line, '\n')); err != nil {
+		return nil, c.closedErr()
+	}
+	select {
+	case res := <-c.results:
+		if id, ok := res.ID.(float64); !ok || int64(id) != c.nextID {
+			return nil, fmt.Errorf("doitclient: result for request %v, want %d", res.ID, c.nextID)
+		}
+		return &res.Result, nil
+	case <-c.done:
+		return nil, c.closedErr()
+	case <-ctx.Done():
+		c.interrupted = true
+		c.cmd.Process.Signal(os.Interrupt)
+		return nil, ctx.Err()
+	}
+}
+
+// Run submits command to run from cwd and waits for its result.
+func (c *Client) Run(ctx context.Context, command, cwd string) (*Result, error) {
+	return c.Do(ctx, Request{Command: command, Cwd: cwd})
+}
+
+// closedErr is the error for a request doit did not answer.
+func (c *Client) closedErr() error {
+	select {
+	case <-c.done:
+		if c.readErr != nil {
+			return c.readErr
+		}
+	default:
+	}
+	return ErrClosed
+}
+
+// Close ends the session and waits for doit to exit.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		close(c.quit)
+		<-c.done
+		if err := c.cmd.Wait(); err != nil {
+			c.closeErr = fmt.Errorf("doitclient: doit: %w", err)
+		}
+	})
+	return c.closeErr
+}
@@ -0,0 +1,128 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package doitclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelocantos/doit/engine"
+)
+
+// TestMain lets the test binary stand in for doit --repl: run with
+// DOITCLIENT_FAKE_REPL set, it answers each request by echoing its
+// command, and stops answering at "hang".
+func TestMain(m *testing.M) {
+	if os.Getenv("DOITCLIENT_FAKE_REPL") != "" {
+		fakeREPL()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func fakeREPL() {
+	if os.Args[len(os.Args)-1] != "--repl" {
+		os.Exit(engine.ExitValidation)
+	}
+	in := bufio.NewScanner(os.Stdin)
+	enc := json.NewEncoder(os.Stdout)
+	for in.Scan() {
+		var req wireRequest
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			os.Exit(engine.ExitInternal)
+		}
+		if req.Command == "hang" {
+			select {}
+		}
+		enc.Encode(wireResult{ID: req.ID, Result: Result{
+			ExitCode: len(req.Env),
+			Stdout:   req.Command + " in " + req.Cwd,
+			Policy:   &Policy{Level: 1, Decision: "allow"},
+		}})
+	}
+}
+
+func connect(t *testing.T) *Client {
+	t.Helper()
+	t.Setenv("DOITCLIENT_FAKE_REPL", "1")
+	c, err := Connect(context.Background(), Options{Path: os.Args[0], Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient_Do(t *testing.T) {
+	c := connect(t)
+	ctx := context.Background()
+	for _, cmd := range []string{"echo one", "grep 'a b' c"} {
+		res, err := c.Do(ctx, Request{Command: cmd, Cwd: "/src", Env: map[string]string{"A": "1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Stdout != cmd+" in /src" || res.ExitCode != 1 || res.Policy == nil || res.Policy.Decision != "allow" {
+			t.Errorf("Do(%q) = %+v", cmd, res)
+		}
+	}
+	if res, err := c.Run(ctx, "ls", "/tmp"); err != nil || res.Stdout != "ls in /tmp" {
+		t.Errorf("Run = %+v, %v", res, err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := c.Run(ctx, "ls", ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("Run after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestClient_Cancel(t *testing.T) {
+	c := connect(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Run(ctx, "hang", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want deadline exceeded", err)
+	}
+	if _, err := c.Run(context.Background(), "ls", ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("Run after cancel: %v, want ErrClosed", err)
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	data, err := json.Marshal(wireRequest{ID: 3, Request: Request{Command: "make", Approved: "tok"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != `{"id":3,"command":"make","approved":"tok"}` {
+		t.Errorf("request = %s", got)
+	}
+	var res wireResult
+	line := `{"id":3,"exit_code":91,"escalate_token":"tok","error":{"kind":"policy","message":"m","approval":true}}`
+	if err := json.NewDecoder(strings.NewReader(line)).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != float64(3) || res.ExitCode != ExitEscalationPending || res.EscalateToken != "tok" || res.Error == nil || !res.Error.Approval {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	for _, c := range [][2]int{
+		{ExitPolicyDeny, engine.ExitPolicyDeny},
+		{ExitEscalationPending, engine.ExitEscalationPending},
+		{ExitValidation, engine.ExitValidation},
+		{ExitUnavailable, engine.ExitUnavailable},
+		{ExitInternal, engine.ExitInternal},
+	} {
+		if c[0] != c[1] {
+			t.Errorf("exit code %d, engine's %d", c[0], c[1])
+		}
+	}
+}
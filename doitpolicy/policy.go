@@ -0,0 +1,172 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package doitpolicy judges commands by doit's deterministic policy — the
+// hardcoded and configured rules of Level 1 and the learned entries of
+// Level 2 — without running them, so that other systems (CI gates,
+// chat-ops bots) can pre-screen commands with the rule semantics doit
+// itself applies. It reads the same configuration and policy store as
+// doit, and never calls the Level 3 LLM gatekeeper: a command neither
+// level decides comes back as an escalation.
+//
+//	p, err := doitpolicy.Load(doitpolicy.Options{ProjectRoot: repo})
+//	...
+//	if res := p.Evaluate(&doitpolicy.Request{Command: cmd, Cwd: repo}); res.Decision == doitpolicy.Deny {
+//		return fmt.Errorf("%s: %s", res.RuleID, res.Reason)
+//	}
+package doitpolicy
+
+import (
+	"fmt"
+
+	"github.com/marcelocantos/doit/internal/config"
+	doitctx "github.com/marcelocantos/doit/internal/context"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+type (
+	// Request is a command to judge. Command is the command line as sh
+	// would run it; Cwd, the absolute directory it would run in, against
+	// which rules resolve the paths it names.
+	Request = policy.Request
+
+	// Result is a policy decision: Decision, the Level (1 or 2) that made
+	// it, the Reason, the RuleID that matched, and whether a human may
+	// override it (Bypassable).
+	Result = policy.Result
+
+	// Decision is the outcome of a Result.
+	Decision = policy.Decision
+
+	// Rule is a Level 1 rule.
+	Rule = policy.Rule
+
+	// Entry is a learned (Level 2) policy entry.
+	Entry = policy.PolicyEntry
+)
+
+// ManifestFile is where a repository commits the policy it imposes on
+// agents working in it, relative to its root.
+const ManifestFile = policy.ManifestFile
+
+// Decisions.
+const (
+	Allow    = policy.Allow
+	Deny     = policy.Deny
+	Escalate = policy.Escalate // neither level decided; doit would ask Level 3 or a human
+)
+
+// Options configures Load.
+type Options struct {
+	ConfigPath  string // doit's config file; "" for the default
+	StorePath   string // learned policy store; "" for the config's, else the default
+	ProjectRoot string // project whose .doit/config.yaml and context apply; "" for none
+}
+
+// Policy is doit's deterministic policy as a configuration describes it.
+// It is not refreshed: Load again to pick up changes to the config or
+// the store.
+type Policy struct {
+	l1          *policy.Level1
+	l2          *policy.Level2
+	tripwires   policy.Tripwires
+	projectType string
+}
+
+// Load builds the policy doit would apply with the same configuration:
+// its tripwires, Level 1 and Level 2 as enabled by the config, a
+// project's tightening overlay and safe commands, and the approved
+// entries of the learned policy store.
+func Load(opts Options) (*Policy, error) {
+	var (
+		cfg *config.Config
+		err error
+	)
+	if opts.ConfigPath != "" {
+		cfg, err = config.LoadFrom(opts.ConfigPath)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	p := &Policy{}
+	var safeCommands []string
+	if opts.ProjectRoot != "" {
+		projCfg, err := config.LoadProject(opts.ProjectRoot)
+		if err != nil {
+			return nil, fmt.Errorf("load project config: %w", err)
+		}
+		cfg.MergeProject(projCfg)
+		pctx := doitctx.Discover(opts.ProjectRoot)
+		p.projectType, safeCommands = string(pctx.Type), pctx.SafeCommands
+	}
+
+	p.tripwires = cfg.Tripwires
+	if cfg.Policy.Level1Enabled {
+		p.l1 = cfg.Level1(p.projectType, safeCommands)
+	}
+	if cfg.Policy.Level2Enabled {
+		path := opts.StorePath
+		if path == "" {
+			path = cfg.Policy.Level2Path
+		}
+		if path == "" {
+			path = policy.DefaultStorePath()
+		}
+		entries, err := LoadStore(path)
+		if err != nil {
+			return nil, err
+		}
+		p.l2 = policy.NewLevel2(entries)
+	}
+	return p, nil
+}
+
+// Evaluate judges req as doit's Level 1 and Level 2 would, denying
+// outright a command that touches a tripwire. A Level 1
+// escalation that names a rule calls for review, which learned entries
+// do not get to overturn; otherwise Level 2 decides what Level 1 left
+// open. The committed policy of the repository containing req.Cwd (see
+// ManifestFile) may then restrict, but never loosen, the decision. The
+// result is Escalate if nothing decided. A request without a
+// ProjectType takes the project's.
+func (p *Policy) Evaluate(req *Request) *Result {
+	r := *req
+	if r.ProjectType == "" {
+		r.ProjectType = p.projectType
+	}
+	if denial := p.tripwires.Check(&r); denial != nil {
+		return denial
+	}
+	result := &Result{Decision: Escalate, Level: 1, Reason: "L1 disabled"}
+	if p.l1 != nil {
+		result = p.l1.Evaluate(&r)
+	}
+	if result.Decision == Escalate && result.RuleID == "" && p.l2 != nil {
+		result = p.l2.Evaluate(&r)
+	}
+	return policy.RestrictByManifest(&r, result)
+}
+
+// Rules returns the Level 1 rules, in the order they are checked, or nil
+// if Level 1 is disabled.
+func (p *Policy) Rules() []Rule {
+	if p.l1 == nil {
+		return nil
+	}
+	return p.l1.Rules()
+}
+
+// LoadStore reads the learned policy store at path. A store that does
+// not exist yet has no entries.
+func LoadStore(path string) ([]Entry, error) {
+	return policy.LoadStore(path)
+}
+
+// DefaultStorePath returns where doit keeps its learned policy store by
+// default.
+func DefaultStorePath() string {
+	return policy.DefaultStorePath()
+}
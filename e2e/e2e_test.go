@@ -0,0 +1,360 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package e2e drives the real doit binary: it builds cmd/doit once, then
+// runs it as agents and wrappers would — doit -c, doit --repl through
+// doitclient, and doit --serve-http — against a private configuration
+// and state directory, with a fake `claude` on PATH standing in for the
+// Level 3 gatekeeper. Run with -short to skip it.
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/marcelocantos/doit/doitclient"
+	"github.com/marcelocantos/doit/internal/audit"
+)
+
+// doitBin is the binary under test, built by TestMain.
+var doitBin string
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "doit-e2e-bin")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	doitBin = filepath.Join(dir, "doit")
+	build := exec.Command("go", "build", "-o", doitBin, "github.com/marcelocantos/doit/cmd/doit")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "e2e: build doit: %v\n", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// fakeClaude answers every gatekeeper prompt: it escalates a command
+// containing "escalate-me" and allows anything else.
+const fakeClaude = `#!/bin/sh
+for prompt; do :; done
+case $prompt in
+*escalate-me*) echo '{"decision": "escalate", "reasoning": "needs a human"}' ;;
+*) echo '{"decision": "allow", "confidence": 0.95, "reasoning": "harmless"}' ;;
+esac
+`
+
+// env is a private doit installation: config, state, and a working
+// directory of its own.
+type env struct {
+	t       *testing.T
+	root    string
+	config  string
+	work    string
+	environ []string
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds and runs the doit binary")
+	}
+	// Unix socket paths are length-limited; t.TempDir can be too deep.
+	root, err := os.MkdirTemp("", "e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	e := &env{t: t, root: root, config: filepath.Join(root, "config.yaml"), work: filepath.Join(root, "work")}
+	for _, dir := range []string{"bin", "work", "config", "data", "state"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "bin", "claude"), []byte(fakeClaude), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := "audit:\n  path: " + filepath.Join(root, "audit.jsonl") + "\n" +
+		"policy:\n  level2_path: " + filepath.Join(root, "learned.yaml") + "\n  level3_history: -1\n" +
+		"llm:\n  feedback_examples: -1\n" +
+		"exec:\n  request_env: [GREETING]\n" +
+		"rules:\n  ls:\n    reject_flags: [\"-R\"]\n"
+	if err := os.WriteFile(e.config, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range os.Environ() {
+		switch name, _, _ := strings.Cut(kv, "="); {
+		case strings.HasPrefix(name, "XDG_"), strings.HasPrefix(name, "DOIT_"), strings.HasPrefix(name, "OTEL_"),
+			name == "HOME", name == "PATH":
+		default:
+			e.environ = append(e.environ, kv)
+		}
+	}
+	for k, v := range e.vars() {
+		e.environ = append(e.environ, k+"="+v)
+	}
+	return e
+}
+
+// vars returns the variables that point doit at e.
+func (e *env) vars() map[string]string {
+	return map[string]string{
+		"HOME":            e.root,
+		"PATH":            filepath.Join(e.root, "bin") + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"XDG_CONFIG_HOME": filepath.Join(e.root, "config"),
+		"XDG_DATA_HOME":   filepath.Join(e.root, "data"),
+		"XDG_STATE_HOME":  filepath.Join(e.root, "state"),
+	}
+}
+
+// command returns doit with args, run in the working directory.
+func (e *env) command(args ...string) *exec.Cmd {
+	cmd := exec.Command(doitBin, append([]string{"--config", e.config}, args...)...)
+	cmd.Dir = e.work
+	cmd.Env = e.environ
+	return cmd
+}
+
+// doit runs doit with args to completion.
+func (e *env) doit(args ...string) (stdout, stderr string, code int) {
+	e.t.Helper()
+	var out, errOut bytes.Buffer
+	cmd := e.command(args...)
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		e.t.Fatalf("doit %q: %v", args, err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+// auditEntries returns the audit log.
+func (e *env) auditEntries() []audit.Entry {
+	e.t.Helper()
+	entries, err := audit.Tail(filepath.Join(e.root, "audit.jsonl"), 1000)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return entries
+}
+
+func TestCommandLine(t *testing.T) {
+	e := newEnv(t)
+
+	// A pipeline of real programs runs, and its quoted words stay whole.
+	if out, errOut, code := e.doit("-c", "echo 'hello   world' | cat"); code != 0 || out != "hello   world\n" {
+		t.Errorf("pipeline: exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+	cmd := e.command("-c", "cat")
+	cmd.Stdin = strings.NewReader("piped\n")
+	if out, err := cmd.Output(); err != nil || string(out) != "piped\n" {
+		t.Errorf("stdin: %q, %v", out, err)
+	}
+
+	// Level 1 refuses a catastrophic command without running it.
+	if _, errOut, code := e.doit("-c", "rm -rf /"); code != 90 || !strings.Contains(errOut, "permanently blocked") {
+		t.Errorf("rm -rf /: exit %d, stderr %q", code, errOut)
+	}
+
+	// A command's own failure is its exit code.
+	if _, _, code := e.doit("-c", "exit 3"); code != 3 {
+		t.Errorf("exit 3: exit %d", code)
+	}
+
+	entries := e.auditEntries()
+	if len(entries) != 4 {
+		t.Fatalf("audit has %d entries, want 4", len(entries))
+	}
+	if last := entries[3]; last.Pipeline != "exit 3" || last.ExitCode != 3 {
+		t.Errorf("last audit entry = %+v", last)
+	}
+	if _, errOut, code := e.doit("--audit", "verify"); code != 0 {
+		t.Errorf("audit verify: exit %d, stderr %q", code, errOut)
+	}
+}
+
+func TestEscalationApproval(t *testing.T) {
+	e := newEnv(t)
+	const command = "echo escalate-me"
+	escalate := func() string {
+		t.Helper()
+		_, errOut, code := e.doit("-c", command)
+		if code != 91 {
+			t.Fatalf("escalation: exit %d, stderr %q", code, errOut)
+		}
+		m := regexp.MustCompile(`--approved (\S+)`).FindStringSubmatch(errOut)
+		if m == nil {
+			t.Fatalf("no approval token in %q", errOut)
+		}
+		return m[1]
+	}
+
+	// A token approves its command, in a later process, once.
+	token := escalate()
+	if out, errOut, code := e.doit("-c", command, "--approved", token); code != 0 || out != "escalate-me\n" {
+		t.Errorf("approved: exit %d, stdout %q, stderr %q", code, out, errOut)
+	}
+	if _, _, code := e.doit("-c", command, "--approved", token); code != 92 {
+		t.Errorf("token redeemed twice: exit %d, want 92", code)
+	}
+
+	// Presenting it for another command spends it.
+	token = escalate()
+	if _, _, code := e.doit("-c", "echo something-else", "--approved", token); code != 92 {
+		t.Errorf("token for another command: exit %d, want 92", code)
+	}
+	if _, _, code := e.doit("-c", command, "--approved", token); code != 92 {
+		t.Errorf("spent token: exit %d, want 92", code)
+	}
+
+	out, _, code := e.doit("--tokens", "list")
+	if code != 0 || !strings.Contains(out, command) || !strings.Contains(out, "used") {
+		t.Errorf("tokens list: exit %d, %q", code, out)
+	}
+}
+
+func TestREPLClient(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	for k, v := range e.vars() {
+		t.Setenv(k, v) // doitclient's doit inherits them
+	}
+	c, err := doitclient.Connect(ctx, doitclient.Options{Path: doitBin, ConfigPath: e.config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	res, err := c.Do(ctx, doitclient.Request{Command: "printf '%s' \"$GREETING\" | cat", Cwd: e.work, Env: map[string]string{"GREETING": "hi"}})
+	if err != nil || res.ExitCode != 0 || res.Stdout != "hi" {
+		t.Errorf("env: %+v, %v", res, err)
+	}
+
+	// A config rule's denial can be retried, naming the rule.
+	res, err = c.Run(ctx, "ls -R", e.work)
+	if err != nil || res.ExitCode != doitclient.ExitPolicyDeny || res.Error == nil || !res.Error.Retryable {
+		t.Fatalf("ls -R: %+v, %v", res, err)
+	}
+	res, err = c.Do(ctx, doitclient.Request{Command: "ls -R", Cwd: e.work, Retry: true, RetryRef: res.Error.RuleID})
+	if err != nil || res.ExitCode != 0 {
+		t.Errorf("ls -R retried: %+v, %v", res, err)
+	}
+
+	res, err = c.Run(ctx, "echo escalate-me", e.work)
+	if err != nil || res.ExitCode != doitclient.ExitEscalationPending || res.EscalateToken == "" {
+		t.Fatalf("escalation: %+v, %v", res, err)
+	}
+	res, err = c.Do(ctx, doitclient.Request{Command: "echo escalate-me", Cwd: e.work, Approved: res.EscalateToken})
+	if err != nil || res.ExitCode != 0 || res.Stdout != "escalate-me\n" {
+		t.Errorf("approved: %+v, %v", res, err)
+	}
+
+	// End of input ends the session cleanly.
+	if err := c.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	var retried bool
+	for _, ent := range e.auditEntries() {
+		retried = retried || ent.Pipeline == "ls -R" && ent.Retry && ent.ExitCode == 0
+	}
+	if !retried {
+		t.Error("retried ls -R not audited")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	e := newEnv(t)
+	const token = "e2e-token"
+	cmd := e.command("--serve-http", "127.0.0.1:0")
+	cmd.Env = append(cmd.Env, "DOIT_HTTP_TOKEN="+token)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	// The server announces its address once it is listening.
+	addr := make(chan string, 1)
+	go func() {
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			if m := regexp.MustCompile(`serving (http://\S+)`).FindStringSubmatch(sc.Text()); m != nil {
+				addr <- m[1]
+			}
+		}
+	}()
+	var base string
+	select {
+	case base = <-addr:
+	case <-time.After(10 * time.Second):
+		t.Fatal("doit --serve-http did not start")
+	}
+
+	post := func(body string) doitclient.Result {
+		t.Helper()
+		req, _ := http.NewRequest("POST", base+"/v1/execute", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res doitclient.Result
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if res := post(`{"command": "echo served | cat", "cwd": "` + e.work + `"}`); res.ExitCode != 0 || res.Stdout != "served\n" {
+		t.Errorf("execute: %+v", res)
+	}
+	if res := post(`{"command": "rm -rf /"}`); res.ExitCode != doitclient.ExitPolicyDeny {
+		t.Errorf("rm -rf /: %+v", res)
+	}
+
+	// An interrupt shuts the server down cleanly, removing its events
+	// socket.
+	cmd.Process.Signal(syscall.SIGTERM)
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve-http exit: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("doit --serve-http did not stop on SIGTERM")
+	}
+	if socks, _ := filepath.Glob(filepath.Join(e.root, "state", "doit", "events", "*.sock")); len(socks) != 0 {
+		t.Errorf("sockets left behind: %v", socks)
+	}
+	if n := len(e.auditEntries()); n != 2 {
+		t.Errorf("audit has %d entries, want 2", n)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/agent"
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// Rule IDs of the denials that hold an agent to its limits.
+const (
+	agentTierRuleID = "agent-max-tier"
+	agentRateRuleID = "agent-rate-limit"
+)
+
+// agentIdentity returns the agent req declares and its secret: its Agent
+// and AgentSecret, or else $DOIT_AGENT and $DOIT_AGENT_SECRET.
+func (req *Request) agentIdentity() (name, secret string) {
+	if req.Agent != "" {
+		return req.Agent, req.AgentSecret
+	}
+	return os.Getenv(agent.NameEnv), os.Getenv(agent.SecretEnv)
+}
+
+// agentName returns the agent req declares, or "" if none, for the audit
+// log and monitors.
+func (req *Request) agentName() string {
+	name, _ := req.agentIdentity()
+	return name
+}
+
+// authenticate checks that the agent req declares is configured and that
+// req carries its secret. A request declaring no agent passes.
+func (e *Engine) authenticate(req Request) error {
+	name, secret := req.agentIdentity()
+	if name == "" {
+		return nil
+	}
+	a, ok := e.cfg.Agents[name]
+	if !ok {
+		return fmt.Errorf("unknown agent %q (see agents in the config)", name)
+	}
+	if name == agent.Default && a.SecretSHA256 == "" {
+		return nil
+	}
+	if !agent.Verify(a.SecretSHA256, secret) {
+		return fmt.Errorf("agent %q: wrong or missing secret ($%s)", name, agent.SecretEnv)
+	}
+	return nil
+}
+
+// agentLimits returns the limits of the agent req declares, or of the
+// default agent if it declares none, and that agent's name; nil if there
+// are none. Hooks and assertion checks are the user's, not the agent's,
+// and have none.
+func (e *Engine) agentLimits(req *Request) (string, *config.AgentConfig) {
+	if req.hook != nil {
+		return "", nil
+	}
+	name := req.agentName()
+	if name == "" {
+		name = agent.Default
+	}
+	a, ok := e.cfg.Agents[name]
+	if !ok {
+		return "", nil
+	}
+	return name, &a
+}
+
+// checkAgentTier denies a command above the tier ceiling of its agent.
+func (e *Engine) checkAgentTier(req *Request, cmdStr string) *policy.Result {
+	name, a := e.agentLimits(req)
+	if a == nil || a.MaxTier == "" {
+		return nil
+	}
+	ceiling, err := cap.ParseTier(a.MaxTier)
+	if err != nil {
+		ceiling = cap.TierRead // config.Check reports it; fail closed meanwhile
+	}
+	if tier := e.effectiveTier(cmdStr); tier > ceiling {
+		return &policy.Result{
+			Decision: policy.Deny,
+			Level:    1,
+			Reason:   fmt.Sprintf("agent %s may run %s-tier commands at most; this one is %s", name, ceiling, tier),
+			RuleID:   agentTierRuleID,
+		}
+	}
+	return nil
+}
+
+// admitAgent records a command against its agent's rate limit, returning
+// an error if the agent has reached it.
+func (e *Engine) admitAgent(req Request) error {
+	name, a := e.agentLimits(&req)
+	if a == nil || a.RateLimit == "" {
+		return nil
+	}
+	rate, err := agent.ParseRate(a.RateLimit)
+	if err != nil {
+		return fmt.Errorf("agent %s: %w", name, err)
+	}
+	wait, err := e.agents.Admit(name, rate)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return fmt.Errorf("agent %s has reached its rate limit of %s; try again in %s", name, rate, wait.Round(time.Second))
+	}
+	return nil
+}
+
+// agentLedgerPath is where agents' commands are counted for their rate
+// limits and quotas, and their taints noted: beside the audit log, so
+// every doit process shares it. Without limits to count for or secrets
+// to watch, there is no need to keep it.
+func agentLedgerPath(cfg *config.Config) string {
+	if len(cfg.Agents) == 0 && cfg.Quotas == (config.QuotaConfig{}) && len(cfg.SensitivePaths) == 0 || cfg.Audit.Path == "" {
+		return ""
+	}
+	return agent.LedgerPath(cfg.Audit.Path)
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/config"
+)
+
+// hookCheck is the phase recorded in the audit entry of an assertion's
+// check (see audit.HookRun).
+const hookCheck = "check"
+
+// checkAssertions checks the post-conditions configured for the command
+// of req, whose words are args, once it has run. It notes each violation
+// on w, publishes it to monitors, and returns them all for the command's
+// audit entry.
+func (e *Engine) checkAssertions(ctx context.Context, req Request, args []string, w io.Writer) []string {
+	if req.hook != nil || len(e.cfg.Assertions) == 0 || len(args) == 0 {
+		return nil
+	}
+	var violations []string
+	for _, a := range e.cfg.Assertions {
+		if !e.hookMatches(a.Match, req, args) {
+			continue
+		}
+		why := e.checkAssertion(ctx, a, req)
+		if why == "" {
+			continue
+		}
+		v := a.Name + ": " + why
+		violations = append(violations, v)
+		fmt.Fprint(w, e.commentary(fmt.Sprintf("doit: assertion %s violated: %s\n", a.Name, why)))
+		req.events.violation(v)
+	}
+	return violations
+}
+
+// checkAssertion returns how the command of req violated a, or "" if it
+// did not. A check that cannot run, being invalid or not allowed by
+// policy, counts as a violation: the post-condition went unverified.
+func (e *Engine) checkAssertion(ctx context.Context, a config.AssertionConfig, req Request) string {
+	for _, p := range a.Exists {
+		path := p
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, _ := os.UserHomeDir()
+			path = filepath.Join(home, rest)
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(req.Cwd, path)
+		}
+		if _, err := os.Lstat(path); err != nil {
+			return fmt.Sprintf("%s no longer exists", p)
+		}
+	}
+	if strings.TrimSpace(a.Check) == "" {
+		return ""
+	}
+	hc := &hookCall{
+		hook:    config.HookConfig{Name: a.Name, When: hookCheck, Run: a.Check, Audit: true},
+		trigger: req.shellCommand(),
+		out:     &bytes.Buffer{},
+	}
+	exitCode, err := e.runHookCall(ctx, hc, req)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("check %q did not run: %v", a.Check, err)
+	case exitCode != 0:
+		return fmt.Sprintf("check %q failed with exit code %d", a.Check, exitCode)
+	}
+	if n := strings.Count(ensureNewline(hc.out.String()), "\n"); a.MaxLines > 0 && hc.out.Len() > 0 && n > a.MaxLines {
+		return fmt.Sprintf("check %q printed %d lines, more than %d", a.Check, n, a.MaxLines)
+	}
+	return ""
+}
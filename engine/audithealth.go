@@ -0,0 +1,112 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/clock"
+)
+
+// auditWarnInterval is how often failing audit writes are warned of,
+// however many fail in between.
+const auditWarnInterval = time.Minute
+
+// AuditHealth reports whether the audit log is being written.
+type AuditHealth struct {
+	Failing     bool      `json:"failing"`                // the last audit write failed, or there is no audit log
+	Failures    uint64    `json:"failures"`               // audit writes failed since the engine started
+	LastError   string    `json:"last_error,omitempty"`   // why the last failed write failed
+	LastFailure time.Time `json:"last_failure,omitempty"` // when it did
+	FailClosed  bool      `json:"fail_closed"`            // commands are refused while failing (audit.fail_closed)
+}
+
+// auditMonitor keeps count of failed audit writes and warns of them on
+// doit's log: at once, then at most every auditWarnInterval, with the
+// number since the last warning.
+type auditMonitor struct {
+	clock      clock.Clock // tells the time of failures and warnings
+	mu         sync.Mutex
+	failing    bool
+	failures   uint64
+	lastErr    error
+	lastAt     time.Time
+	warnedAt   time.Time
+	unreported uint64 // failures since the last warning
+}
+
+// failed records a failed audit write.
+func (m *auditMonitor) failed(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	m.failing = true
+	m.failures++
+	m.unreported++
+	m.lastErr, m.lastAt = err, now
+	if !m.warnedAt.IsZero() && now.Sub(m.warnedAt) < auditWarnInterval {
+		return
+	}
+	if m.unreported == 1 {
+		log.Printf("doit: audit: %v", err)
+	} else {
+		log.Printf("doit: audit: %d entries not written since the last warning; the last because: %v", m.unreported, err)
+	}
+	m.warnedAt, m.unreported = now, 0
+}
+
+// wrote records a successful audit write.
+func (m *auditMonitor) wrote() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failing {
+		log.Printf("doit: audit: writing again after %d failures", m.failures)
+		m.failing = false
+	}
+}
+
+// AuditHealth reports whether the audit log is being written.
+func (e *Engine) AuditHealth() AuditHealth {
+	m := e.auditMon
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := AuditHealth{
+		Failing:     m.failing || e.logger == nil,
+		Failures:    m.failures,
+		LastFailure: m.lastAt,
+		FailClosed:  e.cfg.Audit.FailClosed,
+	}
+	if m.lastErr != nil {
+		h.LastError = m.lastErr.Error()
+	}
+	return h
+}
+
+// noteAudit records the outcome of an audit write.
+func (e *Engine) noteAudit(err error) {
+	if err != nil {
+		e.auditMon.failed(err)
+	} else {
+		e.auditMon.wrote()
+	}
+}
+
+// checkAudit reports why a command may not run, if audit.fail_closed is
+// set and audit entries are not being written. The refusal is itself
+// audited, so a log that has recovered lets the next command run.
+func (e *Engine) checkAudit() error {
+	if !e.cfg.Audit.FailClosed {
+		return nil
+	}
+	if h := e.AuditHealth(); h.Failing {
+		if h.LastError == "" {
+			return errors.New("audit.fail_closed is set and there is no audit log")
+		}
+		return errors.New("audit.fail_closed is set and the audit log is not being written: " + h.LastError)
+	}
+	return nil
+}
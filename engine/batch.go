@@ -0,0 +1,100 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/audit"
+)
+
+// Batch is a file of commands run one after another, each judged by
+// policy on its own (see doit --batch).
+type Batch struct {
+	Label    string // names the batch in its audit summary
+	Cwd      string // the working directory of every command
+	Commands []BatchCommand
+}
+
+// BatchCommand is one line of a batch file.
+type BatchCommand struct {
+	Line    int // line number in the file, from 1
+	Command string
+}
+
+// LoadBatch reads a batch file: one command line per line, pipes and
+// other operators included, run from cwd. Blank lines and lines starting
+// with # are skipped. Each line is a whole command, so a here-document or
+// a quote spanning lines does not work, and a cd on one line does not
+// carry over to the next.
+func LoadBatch(path, cwd string) (*Batch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read batch: %w", err)
+	}
+	b := &Batch{Label: filepath.Base(path), Cwd: cwd}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b.Commands = append(b.Commands, BatchCommand{Line: i + 1, Command: line})
+	}
+	if len(b.Commands) == 0 {
+		return nil, fmt.Errorf("batch %s: no commands", path)
+	}
+	return b, nil
+}
+
+// refused reports whether policy kept a command from running: it was
+// denied, or escalated and not approved.
+func refused(res *Result) bool {
+	return res.PolicyDecision == "deny" || res.PolicyDecision == "escalate" && res.ExitCode == ExitEscalationPending
+}
+
+// RunBatch runs the commands of b in order, each through the policy chain
+// and audited, as separate requests would be. A command that fails does
+// not stop the batch, but one that policy denies, or that escalates and
+// is not approved, does unless keepGoing is set. RunBatch returns the
+// exit code of the first refused command, else that of the last command
+// run. Cancelling ctx stops the running command and the batch.
+func (e *Engine) RunBatch(ctx context.Context, b *Batch, keepGoing bool, stdout, stderr io.Writer) int {
+	notify := func(format string, args ...any) {
+		io.WriteString(stderr, e.commentary(fmt.Sprintf("doit: "+format+"\n", args...)))
+	}
+
+	// The batch's entries form one audit group, closed however it ends.
+	group := e.groupID(audit.GroupBatch)
+	defer e.closeGroup(audit.GroupBatch, group, b.Label, e.sessionID())
+
+	code, refusal := 0, 0
+	for _, c := range b.Commands {
+		notify("batch line %d: %s", c.Line, c.Command)
+		res := e.ExecuteStreaming(ctx, Request{Command: c.Command, Cwd: b.Cwd, group: group}, stdout, stderr)
+		code = res.ExitCode
+		if ctx.Err() != nil {
+			notify("batch interrupted at line %d", c.Line)
+			return code
+		}
+		if !refused(res) {
+			continue
+		}
+		if !keepGoing {
+			notify("batch stopped at line %d (exit %d); --keep-going runs the remaining lines", c.Line, code)
+			return code
+		}
+		if refusal == 0 {
+			refusal = code
+		}
+	}
+	if refusal != 0 {
+		return refusal
+	}
+	return code
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// binaryStamp identifies an executable file as it was when recorded. Size
+// and mtime suffice: installs rewrite the file, and hashing a large binary
+// on every start would slow each CLI invocation.
+type binaryStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// stampBinary records the identity of the file at path.
+func stampBinary(path string) (*binaryStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stamp binary: %w", err)
+	}
+	return &binaryStamp{path: path, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// changed reports whether the file at the stamp's path is no longer the
+// one stamped.
+func (b *binaryStamp) changed() bool {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return true // removed, or replaced by something unreadable
+	}
+	return !info.ModTime().Equal(b.modTime) || info.Size() != b.size
+}
+
+// BinaryStale reports whether the doit executable on disk has changed
+// since this engine started, as after `go install` or a package upgrade.
+// The process keeps running the old code until its MCP client restarts it.
+func (e *Engine) BinaryStale() bool {
+	return e.binary != nil && e.binary.changed()
+}
@@ -0,0 +1,23 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"log"
+
+	"github.com/marcelocantos/doit/internal/chaos"
+)
+
+// injector returns the failures $DOIT_CHAOS asks the engine to inject,
+// announcing them, or nil for none.
+func injector() *chaos.Injector {
+	in, err := chaos.FromEnv()
+	switch {
+	case err != nil:
+		log.Printf("doit: engine: %v (injecting no failures)", err)
+	case in != nil:
+		log.Printf("doit: engine: injecting failures for testing: %s", in)
+	}
+	return in
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// conditionsOf returns the conditions the policy decision in ctx holds
+// its command to, or nil.
+func conditionsOf(ctx context.Context) *policy.Conditions {
+	if info := policy.EvalFromContext(ctx); info != nil && !info.Conditions.IsZero() {
+		return info.Conditions
+	}
+	return nil
+}
+
+// checkConditions reports why a command cannot be held to c, if it
+// cannot: in process, there is no namespace to cut off from the network
+// and no separate command to dry-run first. A command that cannot be
+// held to its conditions does not run.
+func (e *Engine) checkConditions(c *policy.Conditions, inProcess bool, args []string, req Request) error {
+	if c == nil {
+		return nil
+	}
+	if c.NoNetwork {
+		switch {
+		case !netIsolationSupported:
+			return fmt.Errorf("it may run only without network access, which %s cannot enforce", runtime.GOOS)
+		case inProcess && len(args) > 0 && e.capUsesNetwork(args[0], args[1:]):
+			return fmt.Errorf("it may run only without network access, and %s reaches the network in process", args[0])
+		}
+	}
+	if c.DryRun != "" {
+		if inProcess {
+			return fmt.Errorf("it may run only after a dry run with %s, which %s, running in process, does not take", c.DryRun, args[0])
+		}
+		if _, ok := shellWords(req.shellCommand()); !ok {
+			return fmt.Errorf("it may run only after a dry run with %s, which needs a simple command, without shell operators", c.DryRun)
+		}
+	}
+	return nil
+}
+
+// withConditions returns ctx, stdout, and stderr bounded by c's timeout
+// and output limit, cancelling ctx with a cause that says which ran out.
+// Call cancel once the command has run.
+func withConditions(ctx context.Context, c *policy.Conditions, stdout, stderr io.Writer) (_ context.Context, _, _ io.Writer, cancel func()) {
+	ctx, stop := context.WithCancelCause(ctx)
+	cancel = func() { stop(nil) }
+	if c == nil {
+		return ctx, stdout, stderr, cancel
+	}
+	if c.Timeout > 0 {
+		var stopTimer context.CancelFunc
+		ctx, stopTimer = context.WithTimeoutCause(ctx, c.Timeout, fmt.Errorf("the command ran past its allowed %s", c.Timeout))
+		cancel = func() { stopTimer(); stop(nil) }
+	}
+	if c.MaxOutput > 0 {
+		l := &outputLimit{left: c.MaxOutput, stop: func() {
+			stop(fmt.Errorf("the command wrote more than its allowed %d bytes of output", c.MaxOutput))
+		}}
+		stdout, stderr = l.writer(stdout), l.writer(stderr)
+	}
+	return ctx, stdout, stderr, cancel
+}
+
+// outputLimit passes on at most left bytes across the writers it makes,
+// calling stop once they are exhausted and dropping what follows.
+type outputLimit struct {
+	mu   sync.Mutex
+	left int64
+	stop func()
+}
+
+func (l *outputLimit) writer(w io.Writer) io.Writer {
+	return limitedWriter{l, w}
+}
+
+type limitedWriter struct {
+	l *outputLimit
+	w io.Writer
+}
+
+func (lw limitedWriter) Write(p []byte) (int, error) {
+	lw.l.mu.Lock()
+	defer lw.l.mu.Unlock()
+	if lw.l.left <= 0 {
+		return len(p), nil
+	}
+	n := int64(len(p))
+	if n > lw.l.left {
+		lw.w.Write(p[:lw.l.left])
+		lw.l.left = 0
+		lw.l.stop()
+		return len(p), nil
+	}
+	lw.l.left -= n
+	return lw.w.Write(p)
+}
+
+// dryRun runs req's command with c's dry-run flag appended, reporting
+// whether it succeeded and so the command may run for real.
+func (e *Engine) dryRun(ctx context.Context, c *policy.Conditions, args []string, req Request, stdout, stderr io.Writer) bool {
+	dry := req
+	if len(req.Args) > 0 {
+		dry.Args = append(slices.Clone(req.Args), c.DryRun)
+	} else {
+		dry.Command = req.Command + " " + c.DryRun
+	}
+	fmt.Fprint(stderr, e.commentary(fmt.Sprintf("doit: dry run first: %s\n", dry.shellCommand())))
+	exitCode, _, _ := e.runShellCommand(ctx, append(slices.Clone(args), c.DryRun), dry, stdout, stderr)
+	return exitCode == 0 && ctx.Err() == nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import "context"
+
+// requestStarted counts a request as in flight until requestDone.
+func (e *Engine) requestStarted() {
+	e.activeMu.Lock()
+	e.active++
+	e.activeMu.Unlock()
+}
+
+// requestDone ends an in-flight request, waking Drain callers when it
+// was the last.
+func (e *Engine) requestDone() {
+	e.activeMu.Lock()
+	defer e.activeMu.Unlock()
+	e.active--
+	if e.active == 0 && e.idle != nil {
+		close(e.idle)
+		e.idle = nil
+	}
+}
+
+// Active returns the number of requests being evaluated or executed.
+func (e *Engine) Active() int {
+	e.activeMu.Lock()
+	defer e.activeMu.Unlock()
+	return e.active
+}
+
+// Drain waits until no request is in flight, however long they run, or
+// until ctx is done. Callers shutting down use it so that a long build
+// finishes and is audited rather than being cut off.
+func (e *Engine) Drain(ctx context.Context) error {
+	e.activeMu.Lock()
+	if e.active == 0 {
+		e.activeMu.Unlock()
+		return nil
+	}
+	if e.idle == nil {
+		e.idle = make(chan struct{})
+	}
+	idle := e.idle
+	e.activeMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
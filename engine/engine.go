@@ -12,22 +12,34 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/marcelocantos/doit/internal/agent"
 	"github.com/marcelocantos/doit/internal/audit"
 	"github.com/marcelocantos/doit/internal/cap"
 	"github.com/marcelocantos/doit/internal/cap/builtin"
+	"github.com/marcelocantos/doit/internal/chaos"
+	"github.com/marcelocantos/doit/internal/clock"
 	"github.com/marcelocantos/doit/internal/config"
 	doitctx "github.com/marcelocantos/doit/internal/context"
+	"github.com/marcelocantos/doit/internal/events"
 	"github.com/marcelocantos/doit/internal/llm"
+	"github.com/marcelocantos/doit/internal/manifest"
+	"github.com/marcelocantos/doit/internal/messages"
 	"github.com/marcelocantos/doit/internal/policy"
+	"github.com/marcelocantos/doit/internal/proc"
 	doitstar "github.com/marcelocantos/doit/internal/starlark"
+	"github.com/marcelocantos/doit/internal/tracing"
 )
 
 // Options configures Engine creation.
@@ -38,30 +50,75 @@ type Options struct {
 	// looks for .doit/config.yaml in this directory and merges it with
 	// the global config using tighten-only semantics.
 	ProjectRoot string
+	// Verbosity controls doit's own stderr commentary on results.
+	Verbosity Verbosity
+	// Offline denies every command that reaches the network, as the
+	// network.offline config setting does.
+	Offline bool
+	// TokenSession scopes approval tokens issued outside a work session.
+	// Empty scopes them to this process, so only it can redeem them; a
+	// front end whose every invocation is a new process, such as the CLI,
+	// names a session its invocations share.
+	TokenSession string
+	// Clock, if set, replaces the system clock in the expiry of approval
+	// tokens, work sessions, learned grants and remembered decisions,
+	// learned policy review schedules, the timestamps of audit entries,
+	// audit failures and events, so that tests can move time on rather
+	// than sleep.
+	Clock Clock
+	// NewID, if set, generates approval tokens, work session IDs, plan and
+	// batch audit groups, and the IDs of rules written for the user in
+	// place of random and time-based ones. Each ID must be unique.
+	NewID func() string
 }
 
+// Clock tells the time; see Options.Clock.
+type Clock = clock.Clock
+
 // Request describes a command to evaluate or execute.
 type Request struct {
 	Command       string            // shell command string (for sh -c) or space-joined args
 	Args          []string          // parsed args (takes precedence over Command if non-empty)
 	Justification string            // why the agent needs this command
 	SafetyArg     string            // why the agent believes it's safe
-	Cwd           string            // working directory
-	Env           map[string]string // environment variables
+	Cwd           string            // absolute working directory; "" for doit's own
+	Env           map[string]string // variables overlaid on doit's environment
+	Stdin         string            // standard input for the command
 	Approved      string            // approval token for escalated commands
 	Retry         bool              // bypass config rules for this invocation
+	RetryRef      string            // prior denial the retry overrides: audit seq or rule ID
+	Agent         string            // agent making the request; "" for $DOIT_AGENT
+	AgentSecret   string            // proves Agent's identity (with Agent only)
+
+	// Signals, if set, are forwarded to the command while it runs; one
+	// arriving before then abandons the request. Cancelling ctx instead
+	// sends SIGTERM, then SIGKILL after exec.kill_grace.
+	Signals <-chan os.Signal
+
+	retryRule    string         // rule the retry bypasses, resolved from RetryRef
+	retrySeq     uint64         // audit seq of the denial being retried, if referenced
+	userApproved bool           // a human approved the retry interactively (see ApproveByUser)
+	relay        *signalRelay   // delivers Signals for this request
+	planned      string         // command a human approved as a plan step (see RunPlan)
+	group        string         // audit group of the plan the request is a step of
+	hook         *hookCall      // set when the request is the run of a configured hook
+	events       *requestEvents // publishes the request's lifecycle, if it has one
+	violations   []string       // configured post-conditions the command broke
+	profile      string         // execution profile the command runs under (see config.ExecConfig)
 }
 
 // Result is returned by Execute.
 type Result struct {
 	ExitCode       int
+	Signal         string // signal that killed the command (e.g. "SIGKILL"), with ExitCode 128+n
 	Stdout         string
 	Stderr         string
 	PolicyLevel    int
 	PolicyDecision string // "allow", "deny", "escalate", or "" if no policy
 	PolicyReason   string
 	PolicyRuleID   string
-	EscalateToken  string // non-empty when policy escalated, token for approval
+	EscalateToken  string     // non-empty when policy escalated, token for approval
+	Error          *ErrorInfo // why doit stopped or refused the request; nil if the command ran
 }
 
 // EvalResult is returned by Evaluate (dry-run, no execution).
@@ -71,6 +128,7 @@ type EvalResult struct {
 	Reason     string // human-readable explanation
 	RuleID     string // which rule matched
 	Bypassable bool   // true if the denial can be overridden by the user
+	Tier       string // highest tier of anything the command runs
 }
 
 // WorkSession represents an active work session where L3 evaluations
@@ -81,11 +139,18 @@ type WorkSession struct {
 	Description string        `json:"description,omitempty"`
 	StartedAt   time.Time     `json:"started_at"`
 	Timeout     time.Duration `json:"timeout"`
+
+	clock clock.Clock // the engine's; nil for the system clock
 }
 
 // Expired returns true if the session has exceeded its timeout.
 func (s *WorkSession) Expired() bool {
-	return time.Since(s.StartedAt) > s.Timeout
+	return s.Remaining() < 0
+}
+
+// Remaining returns how long the session has left before it times out.
+func (s *WorkSession) Remaining() time.Duration {
+	return s.Timeout - clock.Or(s.clock).Now().Sub(s.StartedAt)
 }
 
 // Engine wraps the doit policy chain, capability registry, and audit log.
@@ -100,13 +165,40 @@ type Engine struct {
 	l3Deep     *llm.Client // deep reasoning client (opus) — may be nil
 	tokenStore *policy.TokenStore
 	storePath  string
+	feedback   string // path of human feedback on Level 3 decisions
 	promoteCh  chan struct{}
 	projectCtx *doitctx.ProjectContext // discovered project context (may be nil)
+	msgs       *messages.Set           // user-facing message templates
+	verbosity  Verbosity
+	events     *events.Bus
+	reqSeq     atomic.Uint64 // request IDs for events
+	pendingMu  sync.Mutex
+	pending    map[uint64]*pendingEscalation // outstanding escalations by request ID
+	netIsolate map[cap.Tier]bool             // tiers run without network access
+	offline    bool                          // deny commands that reach the network
+	tokenSess  string                        // token scope without a work session; "" for this process
+	umask      fs.FileMode                   // file mode creation mask for commands and the files they create
+	binary     *binaryStamp                  // the executable as it was at start; nil if unknown
+	tracer     *tracing.Tracer               // exports request spans; nil if tracing is not configured
+	clock      clock.Clock                   // tells the time of expiries, reviews, and audit entries
+	newID      func() string                 // generates token, session, and group IDs; nil for the defaults
+	chaos      *chaos.Injector               // failures to inject ($DOIT_CHAOS); nil for none
+	started    time.Time                     // when the engine started; bounds its session history
+	agents     *agent.Ledger                 // counts agents' commands for their rate limits
+	memo       decisionMemo                  // L2 and L3 decisions already made this session
+	auditMon   *auditMonitor                 // counts failed audit writes
+	groups     *groupTally                   // sums up open audit groups as their entries are written
 
 	l1Mu      sync.RWMutex
 	l2Mu      sync.RWMutex
+	l2Stat    os.FileInfo // learned policy store as last loaded; nil if there was none
+	storeMu   sync.Mutex  // serializes the engine's changes to the learned policy store
 	sessionMu sync.RWMutex
 	session   *WorkSession
+
+	activeMu sync.Mutex
+	active   int           // requests in flight
+	idle     chan struct{} // closed when active drops to zero; nil if nobody waits
 }
 
 // EngineOption configures optional Engine parameters.
@@ -148,12 +240,39 @@ func New(opts Options, engineOpts ...EngineOption) (*Engine, error) {
 
 	reg := cap.NewRegistry()
 	builtin.RegisterAll(reg)
+	// write keeps its backups beside the audit log that records their hashes.
+	reg.Register(&builtin.Write{BackupDir: filepath.Join(filepath.Dir(cfg.Audit.Path), "backups")})
+	for _, task := range builtin.ProjectTasks {
+		overrides := map[string]string{}
+		for kind, cmds := range cfg.Project.Commands {
+			if cmd, ok := cmds[task]; ok {
+				overrides[kind] = cmd
+			}
+		}
+		reg.Register(&builtin.ProjectTask{Task: task, Overrides: overrides})
+	}
 	cfg.ApplyTiers(reg)
 	cfg.ApplyRules(reg)
 
-	logger, err := audit.NewLogger(cfg.Audit.Path, int64(cfg.Audit.MaxSizeMB)*1024*1024)
+	fsync, err := audit.ParseFsyncPolicy(cfg.Audit.Fsync)
+	if err != nil {
+		log.Printf("doit: engine: audit: %v (using always)", err)
+		fsync = audit.FsyncAlways
+	}
+	clk := clock.Or(opts.Clock)
+	auditMon := &auditMonitor{clock: clk}
+	groups := &groupTally{}
+	logger, err := audit.OpenLogger(cfg.Audit.Path, audit.LoggerOptions{
+		MaxSizeBytes:  int64(cfg.Audit.MaxSizeMB) * 1024 * 1024,
+		Fsync:         fsync,
+		FsyncInterval: cfg.Audit.FsyncIntervalDuration(),
+		Clock:         clk,
+		OnError:       auditMon.failed,
+		OnWrite:       groups.add,
+	})
 	if err != nil {
 		log.Printf("doit: engine: audit logger: %v (continuing without audit)", err)
+		auditMon.failed(err)
 		logger = nil
 	}
 
@@ -161,55 +280,79 @@ func New(opts Options, engineOpts ...EngineOption) (*Engine, error) {
 		cfg:       cfg,
 		reg:       reg,
 		logger:    logger,
+		auditMon:  auditMon,
+		groups:    groups,
 		storePath: cfg.Policy.Level2Path,
+		feedback:  policy.DefaultFeedbackPath(),
 		promoteCh: make(chan struct{}, 1),
+		verbosity: opts.Verbosity,
+		events:    events.NewBus(),
+		offline:   opts.Offline || cfg.Network.Offline,
+		tokenSess: opts.TokenSession,
+		umask:     cfg.Exec.UmaskMode(),
+		started:   clk.Now(),
+		clock:     clk,
+		newID:     opts.NewID,
+		chaos:     injector(),
+		agents:    agent.NewLedger(agentLedgerPath(cfg), clk),
+		tracer: tracing.New(tracing.Options{
+			Endpoint: cfg.Tracing.Endpoint,
+			Service:  cfg.Tracing.Service,
+			Headers:  cfg.Tracing.Headers,
+		}),
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		if e.binary, err = stampBinary(exe); err != nil {
+			log.Printf("doit: engine: %v", err)
+		}
 	}
 
+	isolate, err := cfg.Network.IsolatedTiers()
+	switch {
+	case err != nil:
+		log.Printf("doit: engine: %v (network isolation disabled)", err)
+	case len(isolate) > 0 && !netIsolationSupported:
+		log.Printf("doit: engine: network isolation is not supported on %s (network.isolate ignored)", runtime.GOOS)
+	default:
+		e.netIsolate = isolate
+	}
+
+	msgs, err := messages.New(cfg.Messages)
+	if err != nil {
+		log.Printf("doit: engine: messages: %v (using built-in messages)", err)
+		msgs = nil
+	}
+	e.msgs = msgs
+
 	// Discover project context from project root (best-effort; non-fatal).
 	if opts.ProjectRoot != "" {
 		e.projectCtx = doitctx.Discover(opts.ProjectRoot)
 	}
 
-
 	if e.storePath == "" {
 		e.storePath = policy.DefaultStorePath()
 	}
 
 	// L1: deterministic rules.
 	if cfg.Policy.Level1Enabled {
-		cfgRules := cfg.Rules
-		if cfgRules == nil {
-			cfgRules = config.DefaultRules()
-		}
-		var starlarkEval *doitstar.Evaluator
-		if cfg.Policy.StarlarkRulesDir != "" {
-			starRules, starErr := doitstar.LoadDir(cfg.Policy.StarlarkRulesDir)
-			if starErr != nil {
-				log.Printf("doit: engine: starlark rules: %v (continuing without starlark rules)", starErr)
-			} else if len(starRules) > 0 {
-				starlarkEval = doitstar.NewEvaluator(starRules)
-				log.Printf("doit: engine: loaded %d starlark rules", len(starRules))
-			}
-		}
-		e.policyL1 = policy.NewLevel1WithStarlark(cfgRules, starlarkEval)
-
-		// Inject project-context-aware safe-command rules (🎯T13).
-		if e.projectCtx != nil && len(e.projectCtx.SafeCommands) > 0 {
-			e.policyL1.AddProjectContextRules(
-				string(e.projectCtx.Type),
-				e.projectCtx.SafeCommands,
-			)
+		var projectType string
+		var safeCommands []string
+		if e.projectCtx != nil {
+			projectType, safeCommands = string(e.projectCtx.Type), e.projectCtx.SafeCommands
 		}
+		e.policyL1 = cfg.Level1(projectType, safeCommands)
 	}
 
 	// L2: learned policy store.
 	if cfg.Policy.Level2Enabled {
+		e.l2Stat = storeStat(e.storePath)
 		entries, err := policy.LoadStore(e.storePath)
 		if err != nil {
 			log.Printf("doit: engine: failed to load learned policy: %v", err)
 		} else {
 			for _, ent := range entries {
-				if ent.Approved && !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview) {
+				if ent.Approved && !ent.Expired(clk.Now()) && !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview, clk.Now()) {
 					log.Printf("doit: learned policy %q is overdue for review (due %s)",
 						ent.ID, ent.Review.NextReview.Format("2006-01-02"))
 				}
@@ -231,7 +374,11 @@ func New(opts Options, engineOpts ...EngineOption) (*Engine, error) {
 	// returns, there is no "L3 policy engine not available" window,
 	// and each prompt is stateless.
 	if cfg.Policy.Level3Enabled {
-		e.tokenStore = policy.NewTokenStore(policy.DefaultTokenTTL)
+		tokenOpts := []policy.TokenStoreOption{policy.WithTokenClock(clk)}
+		if opts.NewID != nil {
+			tokenOpts = append(tokenOpts, policy.WithTokenIDs(opts.NewID))
+		}
+		e.tokenStore = policy.OpenTokenStore(policy.DefaultTokenPath(), policy.DefaultTokenTTL, tokenOpts...)
 
 		workDir := opts.ProjectRoot
 		if workDir == "" {
@@ -265,12 +412,35 @@ func New(opts Options, engineOpts ...EngineOption) (*Engine, error) {
 				SkipPermissions: true,
 			}
 			e.l3Deep = deepClient
-			e.policyL3 = policy.NewLevel3(fastClient, deepClient)
+			e.policyL3 = policy.NewLevel3(e.chaos.Prompter(fastClient), e.chaos.Prompter(deepClient))
 			log.Printf("doit: L3 ready (fast=%s, deep=%s)", fastModel, deepModel)
 		} else {
-			e.policyL3 = policy.NewLevel3(fastClient)
+			e.policyL3 = policy.NewLevel3(e.chaos.Prompter(fastClient))
 			log.Printf("doit: L3 ready (%s only)", fastModel)
 		}
+		e.policyL3.SetBreaker(cfg.LLM.Breaker())
+		e.policyL3.SetMinAllowConfidence(cfg.LLM.MinAllow())
+		if models := cfg.LLM.Consensus.Models; len(models) >= 2 {
+			judges := make([]policy.Prompter, len(models))
+			for i, model := range models {
+				judges[i] = e.chaos.Prompter(&llm.Client{
+					Model:           model,
+					Timeout:         timeout,
+					WorkDir:         workDir,
+					DisallowTools:   "Bash,Read,Write,Edit,Glob,Grep",
+					SkipPermissions: true,
+				})
+			}
+			e.policyL3.SetConsensus(judges...)
+			log.Printf("doit: L3 consensus for dangerous commands (%s)", strings.Join(models, ", "))
+		}
+		if path := cfg.LLM.PromptTemplate; path != "" {
+			if tmpl, err := policy.LoadPromptTemplate(path); err != nil {
+				log.Printf("doit: engine: %v (using the built-in prompt)", err)
+			} else {
+				e.policyL3.SetPromptTemplate(tmpl)
+			}
+		}
 	}
 
 	for _, opt := range engineOpts {
@@ -281,12 +451,19 @@ func New(opts Options, engineOpts ...EngineOption) (*Engine, error) {
 }
 
 // Close shuts down engine resources. L3 clients are stateless
-// `claude -p` wrappers with nothing to clean up — Close just ends
-// any active work session.
+// `claude -p` wrappers with nothing to clean up — Close ends any active
+// work session, flushes and closes the audit log, and exports the spans
+// not yet sent.
 func (e *Engine) Close() {
 	e.EndSession("") // end any active session
 	e.l3Fast = nil
 	e.l3Deep = nil
+	if e.logger != nil {
+		if err := e.logger.Close(); err != nil {
+			log.Printf("doit: engine: close audit log: %v", err)
+		}
+	}
+	e.tracer.Close()
 }
 
 // l3SessionClient returns the client to use for session interactions — the
@@ -320,14 +497,19 @@ func (e *Engine) StartSession(scope, description string, timeout time.Duration)
 		timeout = 30 * time.Minute
 	}
 
-	id := fmt.Sprintf("session-%d", time.Now().UnixMilli())
+	now := e.clock.Now()
+	id := fmt.Sprintf("session-%d", now.UnixMilli())
+	if e.newID != nil {
+		id = e.newID()
+	}
 
 	ws := &WorkSession{
 		ID:          id,
 		Scope:       scope,
 		Description: description,
-		StartedAt:   time.Now(),
+		StartedAt:   now,
 		Timeout:     timeout,
+		clock:       e.clock,
 	}
 
 	e.sessionMu.Lock()
@@ -356,6 +538,7 @@ func (e *Engine) EndSession(id string) bool {
 	e.sessionMu.Unlock()
 
 	log.Printf("doit: session ended: %s", ws.ID)
+	e.closeGroup(audit.GroupSession, ws.ID, ws.Scope, ws.ID)
 	return true
 }
 
@@ -380,13 +563,33 @@ func (e *Engine) ActiveSession() *WorkSession {
 	return ws
 }
 
+// NewID returns a new ID beginning with prefix, such as for a rule the
+// user has asked for: by Options.NewID if set, else by the time.
+func (e *Engine) NewID(prefix string) string {
+	if e.newID != nil {
+		return prefix + "-" + e.newID()
+	}
+	return fmt.Sprintf("%s-%d", prefix, e.clock.Now().UnixMilli())
+}
+
+// sessionID returns the ID of the active work session, or "".
+func (e *Engine) sessionID() string {
+	if ws := e.ActiveSession(); ws != nil {
+		return ws.ID
+	}
+	return ""
+}
+
 // Evaluate runs the policy chain without executing the command.
-// Returns the policy decision. Segment/tier analysis is a detail of the
-// individual policy layers and is not surfaced at this level.
+// Returns the policy decision and the command's tier (see
+// effectiveTier). Per-segment analysis is a detail of the individual
+// policy layers and is not surfaced at this level.
 func (e *Engine) Evaluate(ctx context.Context, req Request) *EvalResult {
 	args := req.args()
+	ctx, span := e.traceRequest(ctx, spanEvaluate, req)
+	defer span.End()
 
-	result, _, _ := e.evaluatePolicy(ctx, args, req)
+	result, _, _ := e.evaluatePolicy(ctx, args, &req)
 	if result == nil {
 		return &EvalResult{
 			Decision: "escalate",
@@ -400,55 +603,90 @@ func (e *Engine) Evaluate(ctx context.Context, req Request) *EvalResult {
 		Reason:     result.Reason,
 		RuleID:     result.RuleID,
 		Bypassable: result.Bypassable,
+		Tier:       e.effectiveTier(req.shellCommand()).String(),
 	}
 }
 
 // Execute evaluates policy and, if allowed, runs the command via sh -c.
 // Shell composition (pipes, redirects, &&, ||) is handled by the shell;
 // doit passes the command string through unchanged.
-func (e *Engine) Execute(ctx context.Context, req Request) *Result {
+func (e *Engine) Execute(ctx context.Context, req Request) (res *Result) {
 	args := req.args()
+	start := time.Now()
+	var stopRelay func()
+	ctx, req.relay, stopRelay = relaySignals(ctx, req.Signals)
+	defer stopRelay()
+	ctx, span := e.traceRequest(ctx, spanExecute, req)
+	defer func() { endRequestSpan(span, res) }()
+	ev := e.beginRequest(req, args)
+	defer func() { ev.exit(res) }()
+	req.events = ev
+	defer func() {
+		if p := recover(); p != nil {
+			res = e.panicResult(ctx, req, args, p)
+		}
+	}()
+
+	if err := e.validate(req, args); err != nil {
+		return e.rejectInvalid(ctx, req, args, err)
+	}
 
 	// Policy evaluation.
-	pResult, segments, tiers := e.evaluatePolicy(ctx, args, req)
+	pResult, segments, tiers := e.evaluatePolicy(ctx, args, &req)
+	ev.decision(pResult)
 
 	wasL3 := false
 	if pResult != nil {
-		if pResult.Decision == policy.Deny {
-			e.logPolicyResult(req, args, pResult, segments, tiers, 1)
-			if pResult.Level == 3 {
-				go e.tryPromote()
+		if e.needsHuman(pResult) {
+			token, tokenErr := e.tokenStore.IssueScoped(req.shellCommand(), ApprovalArgs(req.shellCommand()), e.tokenScope(req.Cwd))
+			if tokenErr != nil {
+				return &Result{
+					ExitCode: ExitInternal,
+					Stderr:   e.commentary(fmt.Sprintf("doit: token issue: %v", tokenErr)),
+					Error:    errorInfo(ErrorInternal, fmt.Errorf("token issue: %w", tokenErr)),
+				}
 			}
-			return &Result{
-				ExitCode:       1,
-				Stderr:         fmt.Sprintf("doit: policy: %s", pResult.Reason),
-				PolicyLevel:    pResult.Level,
-				PolicyDecision: pResult.Decision.String(),
-				PolicyReason:   pResult.Reason,
-				PolicyRuleID:   pResult.RuleID,
+			ev.escalation(pResult, token)
+			// A human may resolve the escalation while the request is
+			// parked; approval falls through to execution, denial to the
+			// deny path below.
+			if resolved := e.awaitEscalation(ctx, ev, token, nil); resolved != nil {
+				resolved.Exchanges = pResult.Exchanges // what the gatekeeper made of it
+				pResult = resolved
+			} else {
+				e.logPolicyResult(ctx, req, args, pResult, segments, tiers, ExitEscalationPending)
+				go e.tryPromote()
+				res = &Result{
+					ExitCode:       ExitEscalationPending,
+					Stderr:         e.commentary(e.msgs.Render(messages.PolicyEscalation, e.messageData(args, pResult, token))),
+					PolicyLevel:    pResult.Level,
+					PolicyDecision: pResult.Decision.String(),
+					PolicyReason:   pResult.Reason,
+					EscalateToken:  token,
+					Error:          policyErrorInfo(pResult, ExitEscalationPending),
+				}
+				res.Stderr = appendLine(res.Stderr, e.verboseTrace(res, tiers, time.Since(start)))
+				return res
 			}
 		}
 
-		if pResult.Decision == policy.Escalate && pResult.Level == 3 && e.tokenStore != nil {
-			e.logPolicyResult(req, args, pResult, segments, tiers, 1)
-			token, tokenErr := e.tokenStore.Issue(strings.Join(args, " "), args)
-			if tokenErr != nil {
-				return &Result{
-					ExitCode: 2,
-					Stderr:   fmt.Sprintf("doit: token issue: %v", tokenErr),
-				}
+		if pResult.Decision == policy.Deny {
+			exitCode := denyExitCode(pResult)
+			e.logPolicyResult(ctx, req, args, pResult, segments, tiers, exitCode)
+			if pResult.Level == 3 {
+				go e.tryPromote()
 			}
-			stderrMsg := fmt.Sprintf("doit: policy escalation (Level 3): %s\napproval-token: %s\n",
-				pResult.Reason, token)
-			go e.tryPromote()
-			return &Result{
-				ExitCode:       1,
-				Stderr:         stderrMsg,
+			res = &Result{
+				ExitCode:       exitCode,
+				Stderr:         e.commentary(e.msgs.Render(messages.PolicyDeny, e.messageData(args, pResult, ""))),
 				PolicyLevel:    pResult.Level,
 				PolicyDecision: pResult.Decision.String(),
 				PolicyReason:   pResult.Reason,
-				EscalateToken:  token,
+				PolicyRuleID:   pResult.RuleID,
+				Error:          policyErrorInfo(pResult, exitCode),
 			}
+			res.Stderr = appendLine(res.Stderr, e.verboseTrace(res, tiers, time.Since(start)))
+			return res
 		}
 
 		wasL3 = pResult.Level == 3
@@ -459,21 +697,25 @@ func (e *Engine) Execute(ctx context.Context, req Request) *Result {
 			RuleID:        pResult.RuleID,
 			Justification: req.Justification,
 			SafetyArg:     req.SafetyArg,
+			Exchanges:     pResult.Exchanges,
+			Conditions:    pResult.Conditions,
 		})
 	}
 
 	// Execute the command.
 	var stdoutBuf, stderrBuf bytes.Buffer
-	exitCode := e.runCommand(ctx, args, req, &stdoutBuf, &stderrBuf)
+	exitCode, signal, fail := e.runCommand(ctx, args, req, &stdoutBuf, &stderrBuf)
 
 	if wasL3 {
 		go e.tryPromote()
 	}
 
-	res := &Result{
+	res = &Result{
 		ExitCode: exitCode,
+		Signal:   signal,
 		Stdout:   stdoutBuf.String(),
 		Stderr:   stderrBuf.String(),
+		Error:    fail,
 	}
 	if pResult != nil {
 		res.PolicyLevel = pResult.Level
@@ -481,51 +723,96 @@ func (e *Engine) Execute(ctx context.Context, req Request) *Result {
 		res.PolicyReason = pResult.Reason
 		res.PolicyRuleID = pResult.RuleID
 	}
+	res.Stderr = appendLine(res.Stderr, e.verboseTrace(res, tiers, time.Since(start)))
 	return res
 }
 
 // ExecuteStreaming is like Execute but writes stdout/stderr to the provided
 // writers instead of buffering. Returns the result (Stdout/Stderr will be empty).
-func (e *Engine) ExecuteStreaming(ctx context.Context, req Request, stdout, stderr io.Writer) *Result {
+func (e *Engine) ExecuteStreaming(ctx context.Context, req Request, stdout, stderr io.Writer) (res *Result) {
 	args := req.args()
+	start := time.Now()
+	var stopRelay func()
+	ctx, req.relay, stopRelay = relaySignals(ctx, req.Signals)
+	defer stopRelay()
+	ctx, span := e.traceRequest(ctx, spanExecute, req)
+	defer func() { endRequestSpan(span, res) }()
+	ev := e.beginRequest(req, args)
+	defer func() { ev.exit(res) }()
+	req.events = ev
+	defer func() {
+		if p := recover(); p != nil {
+			res = e.panicResult(ctx, req, args, p)
+			if res.Stderr != "" {
+				fmt.Fprintln(stderr, res.Stderr)
+				res.Stderr = ""
+			}
+		}
+	}()
 
-	pResult, segments, tiers := e.evaluatePolicy(ctx, args, req)
+	if err := e.validate(req, args); err != nil {
+		res = e.rejectInvalid(ctx, req, args, err)
+		fmt.Fprintln(stderr, res.Stderr)
+		res.Stderr = ""
+		return res
+	}
+
+	pResult, segments, tiers := e.evaluatePolicy(ctx, args, &req)
+	ev.decision(pResult)
 
 	wasL3 := false
 	if pResult != nil {
-		if pResult.Decision == policy.Deny {
-			e.logPolicyResult(req, args, pResult, segments, tiers, 1)
-			if pResult.Level == 3 {
-				go e.tryPromote()
+		if e.needsHuman(pResult) {
+			token, tokenErr := e.tokenStore.IssueScoped(req.shellCommand(), ApprovalArgs(req.shellCommand()), e.tokenScope(req.Cwd))
+			if tokenErr != nil {
+				if msg := e.commentary(fmt.Sprintf("doit: token issue: %v", tokenErr)); msg != "" {
+					fmt.Fprintln(stderr, msg)
+				}
+				return &Result{ExitCode: ExitInternal, Error: errorInfo(ErrorInternal, fmt.Errorf("token issue: %w", tokenErr))}
 			}
-			msg := fmt.Sprintf("doit: policy: %s", pResult.Reason)
-			fmt.Fprintln(stderr, msg)
-			return &Result{
-				ExitCode:       1,
-				PolicyLevel:    pResult.Level,
-				PolicyDecision: pResult.Decision.String(),
-				PolicyReason:   pResult.Reason,
-				PolicyRuleID:   pResult.RuleID,
+			fmt.Fprint(stderr, e.commentary(e.msgs.Render(messages.PolicyEscalation, e.messageData(args, pResult, token))))
+			ev.escalation(pResult, token)
+			// A human may resolve the escalation while the request is
+			// parked; approval falls through to execution, denial to the
+			// deny path below.
+			if resolved := e.awaitEscalation(ctx, ev, token, stderr); resolved != nil {
+				resolved.Exchanges = pResult.Exchanges // what the gatekeeper made of it
+				pResult = resolved
+			} else {
+				e.logPolicyResult(ctx, req, args, pResult, segments, tiers, ExitEscalationPending)
+				go e.tryPromote()
+				res = &Result{
+					ExitCode:       ExitEscalationPending,
+					PolicyLevel:    pResult.Level,
+					PolicyDecision: pResult.Decision.String(),
+					PolicyReason:   pResult.Reason,
+					EscalateToken:  token,
+					Error:          policyErrorInfo(pResult, ExitEscalationPending),
+				}
+				fmt.Fprint(stderr, e.verboseTrace(res, tiers, time.Since(start)))
+				return res
 			}
 		}
 
-		if pResult.Decision == policy.Escalate && pResult.Level == 3 && e.tokenStore != nil {
-			e.logPolicyResult(req, args, pResult, segments, tiers, 1)
-			token, tokenErr := e.tokenStore.Issue(strings.Join(args, " "), args)
-			if tokenErr != nil {
-				fmt.Fprintf(stderr, "doit: token issue: %v\n", tokenErr)
-				return &Result{ExitCode: 2}
+		if pResult.Decision == policy.Deny {
+			exitCode := denyExitCode(pResult)
+			e.logPolicyResult(ctx, req, args, pResult, segments, tiers, exitCode)
+			if pResult.Level == 3 {
+				go e.tryPromote()
+			}
+			if msg := e.commentary(e.msgs.Render(messages.PolicyDeny, e.messageData(args, pResult, ""))); msg != "" {
+				fmt.Fprintln(stderr, msg)
 			}
-			fmt.Fprintf(stderr, "doit: policy escalation (Level 3): %s\napproval-token: %s\n",
-				pResult.Reason, token)
-			go e.tryPromote()
-			return &Result{
-				ExitCode:       1,
+			res = &Result{
+				ExitCode:       exitCode,
 				PolicyLevel:    pResult.Level,
 				PolicyDecision: pResult.Decision.String(),
 				PolicyReason:   pResult.Reason,
-				EscalateToken:  token,
+				PolicyRuleID:   pResult.RuleID,
+				Error:          policyErrorInfo(pResult, exitCode),
 			}
+			fmt.Fprint(stderr, e.verboseTrace(res, tiers, time.Since(start)))
+			return res
 		}
 
 		wasL3 = pResult.Level == 3
@@ -536,22 +823,25 @@ func (e *Engine) ExecuteStreaming(ctx context.Context, req Request, stdout, stde
 			RuleID:        pResult.RuleID,
 			Justification: req.Justification,
 			SafetyArg:     req.SafetyArg,
+			Exchanges:     pResult.Exchanges,
+			Conditions:    pResult.Conditions,
 		})
 	}
 
-	exitCode := e.runCommand(ctx, args, req, stdout, stderr)
+	exitCode, signal, fail := e.runCommand(ctx, args, req, stdout, stderr)
 
 	if wasL3 {
 		go e.tryPromote()
 	}
 
-	res := &Result{ExitCode: exitCode}
+	res = &Result{ExitCode: exitCode, Signal: signal, Error: fail}
 	if pResult != nil {
 		res.PolicyLevel = pResult.Level
 		res.PolicyDecision = pResult.Decision.String()
 		res.PolicyReason = pResult.Reason
 		res.PolicyRuleID = pResult.RuleID
 	}
+	fmt.Fprint(stderr, e.verboseTrace(res, tiers, time.Since(start)))
 	return res
 }
 
@@ -562,6 +852,13 @@ func (e *Engine) PolicyStatus() map[string]any {
 		"l2_enabled": e.cfg.Policy.Level2Enabled,
 		"l3_enabled": e.cfg.Policy.Level3Enabled,
 	}
+	if e.offline {
+		status["offline"] = true
+	}
+	status["audit"] = e.AuditHealth()
+	if e.BinaryStale() {
+		status["binary_stale"] = "the doit executable has changed since this server started; restart it to run the new version"
+	}
 
 	e.l1Mu.RLock()
 	if e.policyL1 != nil {
@@ -583,7 +880,7 @@ func (e *Engine) PolicyStatus() map[string]any {
 	if entries, err := policy.LoadStore(e.storePath); err == nil {
 		overdue := 0
 		for _, ent := range entries {
-			if ent.Approved && !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview) {
+			if ent.Approved && !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview, e.clock.Now()) {
 				overdue++
 			}
 		}
@@ -594,6 +891,12 @@ func (e *Engine) PolicyStatus() map[string]any {
 
 	if e.policyL3 != nil {
 		status["l3_model"] = e.cfg.Policy.Level3Model
+		switch s := e.policyL3.Status(); {
+		case !s.Enabled:
+			status["l3_gatekeeper"] = "disabled at run time; escalating to a human (doit --llm enable)"
+		case s.Open:
+			status["l3_gatekeeper"] = fmt.Sprintf("circuit breaker open after %d failures; escalating to a human until %s", s.Failures, s.RetryAt.Format(time.TimeOnly))
+		}
 	}
 
 	if ws := e.ActiveSession(); ws != nil {
@@ -602,18 +905,37 @@ func (e *Engine) PolicyStatus() map[string]any {
 			"scope":       ws.Scope,
 			"description": ws.Description,
 			"started_at":  ws.StartedAt.Format(time.RFC3339),
-			"remaining":   (ws.Timeout - time.Since(ws.StartedAt)).Truncate(time.Second).String(),
+			"remaining":   ws.Remaining().Truncate(time.Second).String(),
 		}
 	}
 
 	return status
 }
 
+// Messages returns the engine's message templates. The nil *messages.Set
+// renders built-in defaults, so the result is always safe to use.
+func (e *Engine) Messages() *messages.Set {
+	return e.msgs
+}
+
+func (e *Engine) messageData(args []string, r *policy.Result, token string) messages.Data {
+	return messages.Data{
+		Command:  strings.Join(args, " "),
+		Decision: r.Decision.String(),
+		Level:    r.Level,
+		Reason:   r.Reason,
+		RuleID:   r.RuleID,
+		Token:    token,
+	}
+}
+
 // CapabilityInfo describes a registered capability.
 type CapabilityInfo struct {
 	Name        string
 	Tier        string
 	Description string
+	Help        cap.Help // examples, flags, and tier rationale; zero if undocumented
+	Unavailable string   // why a program it needs cannot run; empty if it can
 }
 
 // ListCapabilities returns all registered capabilities.
@@ -625,16 +947,68 @@ func (e *Engine) ListCapabilities() []CapabilityInfo {
 			Name:        c.Name(),
 			Tier:        c.Tier().String(),
 			Description: c.Description(),
+			Help:        cap.HelpFor(c),
+		}
+		var uerr *cap.UnavailableError
+		if errors.As(e.reg.Available(c.Name()), &uerr) {
+			result[i].Unavailable = uerr.Reason
 		}
 	}
 	return result
 }
 
+// Manifest returns the catalogue of capabilities, tiers, and rules the
+// engine enforces. version is the doit version to report.
+func (e *Engine) Manifest(version string) *manifest.Manifest {
+	return manifest.Build(version, e.cfg, e.reg)
+}
+
+// AgentGuide returns the Markdown agent guide generated from the engine's
+// live configuration.
+func (e *Engine) AgentGuide(version string) string {
+	var b strings.Builder
+	e.Manifest(version).WriteAgentGuide(&b)
+	return b.String()
+}
+
+// CapabilityHelp returns the formatted extended help for a capability.
+func (e *Engine) CapabilityHelp(name string) (string, error) {
+	c, err := e.reg.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return cap.FormatHelp(c), nil
+}
+
 // AuditPath returns the configured audit log path.
 func (e *Engine) AuditPath() string {
 	return e.cfg.Audit.Path
 }
 
+// VerifyAudit checks the audit log hash chain. Unless full is set, it
+// resumes from the watermark left by the previous successful run, so only
+// entries appended since then are checked; the watermark is advanced on
+// success.
+func (e *Engine) VerifyAudit(full bool) (*audit.VerifyReport, error) {
+	if err := e.FlushAudit(); err != nil {
+		return nil, fmt.Errorf("flush audit log: %w", err)
+	}
+	path := e.cfg.Audit.Path
+	return audit.VerifyWith(path, audit.VerifyOptions{
+		Checkpoint: audit.CheckpointPath(path),
+		Full:       full,
+	})
+}
+
+// FlushAudit writes any buffered audit entries to disk so that readers
+// opening the log by path see everything logged so far.
+func (e *Engine) FlushAudit() error {
+	if e.logger == nil {
+		return nil
+	}
+	return e.logger.Flush()
+}
+
 // StorePath returns the L2 policy store path.
 func (e *Engine) StorePath() string {
 	return e.storePath
@@ -653,7 +1027,7 @@ func (e *Engine) OverdueReviews() ([]policy.PolicyEntry, error) {
 	}
 	var overdue []policy.PolicyEntry
 	for _, ent := range entries {
-		if ent.Approved && !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview) {
+		if ent.Approved && !ent.Review.NextReview.IsZero() && policy.NeedsReview(ent.Review.NextReview, e.clock.Now()) {
 			overdue = append(overdue, ent)
 		}
 	}
@@ -687,7 +1061,7 @@ func (e *Engine) SelfAudit() ([]policy.AuditFinding, error) {
 		}
 	}
 
-	return policy.AuditRules(l1Rules, entries, starlarkRules), nil
+	return policy.AuditRules(l1Rules, entries, starlarkRules, e.clock.Now()), nil
 }
 
 // ProjectContext returns the discovered project context, or nil if no project
@@ -700,6 +1074,10 @@ func (e *Engine) ProjectContext() *doitctx.ProjectContext {
 // pattern and reloads the L2 engine. The cap/subcmd match criteria are
 // derived by tokenising the command string.
 func (e *Engine) RecordDecision(command, decision string) error {
+	return e.recordDecision(command, decision, "User decision via MCP elicitation")
+}
+
+func (e *Engine) recordDecision(command, decision, reasoning string) error {
 	if e.storePath == "" {
 		return fmt.Errorf("no policy store configured")
 	}
@@ -714,7 +1092,7 @@ func (e *Engine) RecordDecision(command, decision string) error {
 		subcmd = parts[1]
 	}
 
-	now := time.Now().UTC()
+	now := e.clock.Now().UTC()
 	entry := policy.PolicyEntry{
 		ID:          fmt.Sprintf("user-%s-%d", cap, now.UnixMilli()),
 		Description: fmt.Sprintf("User %s for %s", decision, command),
@@ -723,7 +1101,7 @@ func (e *Engine) RecordDecision(command, decision string) error {
 			Subcmd: subcmd,
 		},
 		Decision:   decision,
-		Reasoning:  "User decision via MCP elicitation",
+		Reasoning:  reasoning,
 		Confidence: "high",
 		Provenance: "human",
 		Approved:   true,
@@ -733,16 +1111,27 @@ func (e *Engine) RecordDecision(command, decision string) error {
 		},
 	}
 
-	added, err := policy.AppendEntries(e.storePath, []policy.PolicyEntry{entry})
+	err := e.changeStore(func() error {
+		_, err := policy.AppendEntries(e.storePath, []policy.PolicyEntry{entry})
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("append policy entry: %w", err)
 	}
-	if added > 0 {
-		e.reloadL2()
-	}
 	return nil
 }
 
+// DeleteLearned removes the learned policy entry with the given ID and
+// reloads Level 2 without it.
+func (e *Engine) DeleteLearned(id string) error {
+	if e.storePath == "" {
+		return fmt.Errorf("no policy store configured")
+	}
+	return e.changeStore(func() error {
+		return policy.DeleteEntry(e.storePath, id)
+	})
+}
+
 // RuleProposal describes a proposed Starlark rule at a specific generality.
 type RuleProposal struct {
 	Description string // human-readable description of what this rule covers
@@ -1034,15 +1423,45 @@ func upperFirst(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
 
-// ValidateApproval checks an approval token. Returns nil on success.
-func (e *Engine) ValidateApproval(token string, args []string) error {
+// ValidateApproval checks an approval token for command run in cwd.
+// Returns nil on success.
+func (e *Engine) ValidateApproval(token, command, cwd string) error {
 	if e.tokenStore == nil {
 		return fmt.Errorf("approval tokens not enabled (L3 disabled)")
 	}
-	_, err := e.tokenStore.Validate(token, args)
+	_, err := e.tokenStore.ValidateScoped(token, ApprovalArgs(command), e.tokenScope(cwd))
 	return err
 }
 
+// ApprovalArgs returns what an approval token for command is bound to:
+// the command line exactly as sh runs it. Its words would not do, since
+// echo '$(x)' and echo $(x) have the same words once unquoted, and a
+// token for the first would run the substitution in the second.
+func ApprovalArgs(command string) []string {
+	return []string{command}
+}
+
+// tokenScope binds approval tokens to this agent session and the
+// directory the command runs in. Without a work session, the session is
+// Options.TokenSession or, failing that, this process, which serves a
+// single agent.
+func (e *Engine) tokenScope(cwd string) policy.TokenScope {
+	session := e.sessionID()
+	if session == "" {
+		session = e.tokenSess
+	}
+	if session == "" {
+		session = fmt.Sprintf("pid-%d", os.Getpid())
+	}
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	if cwd != "" {
+		cwd = filepath.Clean(cwd)
+	}
+	return policy.TokenScope{Session: session, Cwd: cwd}
+}
+
 // --- internal ---
 
 func (req *Request) args() []string {
@@ -1050,35 +1469,83 @@ func (req *Request) args() []string {
 		return req.Args
 	}
 	if req.Command != "" {
-		return strings.Fields(req.Command)
+		return SplitCommand(req.Command)
 	}
 	return nil
 }
 
-func (e *Engine) evaluatePolicy(ctx context.Context, args []string, req Request) (result *policy.Result, segments, tiers []string) {
+// shellCommand returns the command line sh runs: Command as written, so
+// that its quoting, newlines, and here-documents survive, or Args joined
+// with spaces.
+func (req *Request) shellCommand() string {
+	if len(req.Args) > 0 {
+		return strings.Join(req.Args, " ")
+	}
+	return req.Command
+}
+
+// approvalTokenRuleID is the RuleID reported for decisions made by
+// validating an approval token.
+const approvalTokenRuleID = "approval-token"
+
+func (e *Engine) evaluatePolicy(ctx context.Context, args []string, req *Request) (result *policy.Result, segments, tiers []string) {
 	if len(args) == 0 {
 		return nil, nil, nil
 	}
+	e.chaos.Delay(ctx)
+
+	// A tripwire outranks everything: no tier, approval, or retry lets a
+	// command touch one.
+	if denial := policy.Tripwires(e.cfg.Tripwires).Check(&policy.Request{Command: req.shellCommand(), Cwd: req.Cwd}); denial != nil {
+		return denial, nil, nil
+	}
+
+	// So does offline mode, approval tokens included.
+	if e.offline {
+		cmdStr := req.Command
+		if cmdStr == "" {
+			cmdStr = strings.Join(args, " ")
+		}
+		if use := policy.NetworkUse(cmdStr, e.capUsesNetwork); use != "" {
+			return &policy.Result{
+				Decision: policy.Deny,
+				Level:    1,
+				Reason:   fmt.Sprintf("offline mode: %q reaches the network", use),
+				RuleID:   policy.OfflineRuleID,
+			}, nil, nil
+		}
+	}
+
+	// So does an agent's tier ceiling: no approval lifts it.
+	if denial := e.checkAgentTier(req, req.shellCommand()); denial != nil {
+		return denial, nil, nil
+	}
 
-	// Token validation first.
+	// Token validation next.
 	if req.Approved != "" && e.tokenStore != nil {
-		_, err := e.tokenStore.Validate(req.Approved, args)
+		_, err := e.tokenStore.ValidateScoped(req.Approved, ApprovalArgs(req.shellCommand()), e.tokenScope(req.Cwd))
 		if err != nil {
 			return &policy.Result{
 				Decision: policy.Deny,
 				Level:    3,
 				Reason:   fmt.Sprintf("invalid approval token: %v", err),
-				RuleID:   "approval-token",
+				RuleID:   approvalTokenRuleID,
 			}, nil, nil
 		}
 		return &policy.Result{
 			Decision: policy.Allow,
 			Level:    3,
 			Reason:   "approved via approval token",
-			RuleID:   "approval-token",
+			RuleID:   approvalTokenRuleID,
 		}, nil, nil
 	}
 
+	if req.Retry {
+		if denial := e.resolveRetry(req, args); denial != nil {
+			return denial, nil, nil
+		}
+	}
+
 	// Extract the first word as the capability name for tier lookup.
 	// This is used only for audit log coarse-filtering (segments/tiers fields).
 	// The shell handles all composition (&&, |, ;, etc.) — the full command
@@ -1100,36 +1567,52 @@ func (e *Engine) evaluatePolicy(ctx context.Context, args []string, req Request)
 		Command:       cmdStr,
 		Cwd:           req.Cwd,
 		Retry:         req.Retry,
+		RetryRule:     req.retryRule,
 		Justification: req.Justification,
 		SafetyArg:     req.SafetyArg,
+		Stdin:         req.Stdin,
+		Segments:      segments,
+		Tiers:         tiers,
+		Tier:          e.effectiveTier(cmdStr).String(),
+		Now:           e.clock.Now(),
 	}
 	if e.projectCtx != nil {
 		policyReq.ProjectType = string(e.projectCtx.Type)
 	}
 
-	// L1: deterministic rules.
-	e.l1Mu.RLock()
-	l1 := e.policyL1
-	e.l1Mu.RUnlock()
-	if l1 != nil {
-		result = l1.Evaluate(policyReq)
-	} else {
-		result = &policy.Result{Decision: policy.Escalate, Level: 1, Reason: "L1 disabled"}
-	}
+	result = e.evaluateLocal(ctx, policyReq)
 
-	// L2: learned patterns.
-	if result.Decision == policy.Escalate && e.policyL2 != nil {
-		e.l2Mu.RLock()
-		result = e.policyL2.Evaluate(policyReq)
-		e.l2Mu.RUnlock()
+	// An agent over its quota, or one that has read a secret and would
+	// reach the network, needs a human, not the gatekeeper.
+	result = e.restrictByQuota(req, result)
+	result = e.restrictByTaint(req, cmdStr, result)
+
+	// A step of an approved plan needs no further judgment, provided it
+	// is the command that was approved; anything else is judged afresh.
+	if result.Decision == policy.Escalate && req.planned != "" && req.planned == cmdStr {
+		return &policy.Result{
+			Decision: policy.Allow,
+			Level:    3,
+			Reason:   "approved by a human as a step of a plan",
+			RuleID:   planApprovalRuleID,
+		}, segments, tiers
 	}
 
 	// L3: LLM evaluation via `claude -p`. Synchronous — L3 is always
 	// available the moment the engine finishes construction, so
 	// there is no readiness check here.
-	if result.Decision == policy.Escalate && e.policyL3 != nil {
+	if result.Decision == policy.Escalate && !humanOnly(result) && e.policyL3 != nil {
+		// The gatekeeper's allow or deny holds for the rest of the
+		// session; asking again would only cost time.
+		memoKey, session := l3MemoKey(policyReq, req.Agent), e.sessionID()
+		remembered, gen := e.memo.get(session, memoKey, policyReq.Now)
+		if remembered != nil && !req.Retry {
+			return remembered, segments, tiers
+		}
 		log.Printf("doit: L3 LLM call starting for %q", policyReq.Command)
 		t0 := time.Now()
+		l3ctx, span := e.tracer.Start(ctx, spanL3)
+		policyReq.Context = e.promptContext(l3ctx, policyReq)
 
 		ws := e.ActiveSession()
 		if ws != nil {
@@ -1137,97 +1620,441 @@ func (e *Engine) evaluatePolicy(ctx context.Context, args []string, req Request)
 				Scope:       ws.Scope,
 				Description: ws.Description,
 			}
-			result = e.policyL3.EvaluateInSession(ctx, policyReq, sessionCtx)
+			result = e.policyL3.EvaluateInSession(l3ctx, policyReq, sessionCtx)
 		} else {
-			result = e.policyL3.Evaluate(ctx, policyReq)
+			result = e.policyL3.Evaluate(l3ctx, policyReq)
 		}
+		endPolicySpan(span, result)
 
 		elapsed := time.Since(t0)
 		log.Printf("doit: L3 LLM call completed in %v: %s (%s)", elapsed, result.Decision, result.Reason)
+
+		if (result.Decision == policy.Allow || result.Decision == policy.Deny) && !req.Retry {
+			e.memo.put(session, gen, memoKey, result, time.Time{})
+		}
+
+		if learn := e.cfg.LLM.LearnConfidence; learn > 0 && result.Decision == policy.Allow &&
+			result.Confidence != nil && *result.Confidence >= learn {
+			go e.learnConfident(policyReq.Command, result)
+		}
 	}
 	return result, segments, tiers
 }
 
-func (e *Engine) runCommand(ctx context.Context, args []string, req Request, stdout, stderr io.Writer) int {
-	return e.runShellCommand(ctx, args, req, stdout, stderr)
+// evaluateLocal runs the deterministic (L1) and learned (L2) levels,
+// restricted by the policy of the repository the command runs in.
+func (e *Engine) evaluateLocal(ctx context.Context, policyReq *policy.Request) *policy.Result {
+	// L1: deterministic rules.
+	var result *policy.Result
+	e.l1Mu.RLock()
+	l1 := e.policyL1
+	e.l1Mu.RUnlock()
+	if l1 != nil {
+		_, span := e.tracer.Start(ctx, spanL1)
+		result = l1.Evaluate(policyReq)
+		endPolicySpan(span, result)
+	} else {
+		result = &policy.Result{Decision: policy.Escalate, Level: 1, Reason: "L1 disabled"}
+	}
+
+	// L2: learned patterns. An escalation by a named L1 rule is a call for
+	// review, which learned entries do not get to overturn.
+	if result.Decision == policy.Escalate && result.RuleID == "" && e.policyL2 != nil {
+		_, span := e.tracer.Start(ctx, spanL2)
+		e.refreshL2()
+		result = e.evaluateL2(policyReq)
+		endPolicySpan(span, result)
+	}
+
+	// The repository's committed policy may restrict the decision, but
+	// never loosen it.
+	return policy.RestrictByManifest(policyReq, result)
 }
 
-// runShellCommand executes a command via sh -c, propagating exit codes.
-// When args is non-empty, they are joined to form the command string.
-func (e *Engine) runShellCommand(ctx context.Context, args []string, req Request, stdout, stderr io.Writer) int {
+// denyExitCode distinguishes a rejected approval token or retry reference
+// (validation errors) from an ordinary policy denial.
+func denyExitCode(r *policy.Result) int {
+	if r.RuleID == approvalTokenRuleID || r.RuleID == retryRefRuleID {
+		return ExitValidation
+	}
+	return ExitPolicyDeny
+}
+
+// startFailureExitCode classifies an error from starting the shell: a
+// working directory that cannot be entered is the caller's mistake;
+// anything else means the shell itself is unavailable.
+func startFailureExitCode(err error) int {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) && pathErr.Op == "chdir" {
+		return ExitValidation
+	}
+	return ExitUnavailable
+}
+
+// checkDir reports a working directory that cannot be entered as a chdir
+// error. os.StartProcess does this itself only when no SysProcAttr is
+// set; process-group isolation always sets one.
+func checkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return &fs.PathError{Op: "chdir", Path: dir, Err: errors.Unwrap(err)}
+	}
+	if !fi.IsDir() {
+		return &fs.PathError{Op: "chdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+// validate checks that a request is well formed before policy sees it:
+// that there is a command, that its working directory is an absolute
+// path to a directory, that its environment is one doit will pass on,
+// that the agent it declares, if any, is who it says, and that a
+// capability doit runs itself is invoked on its own with valid arguments.
+// A malformed request is then rejected once, with one audit entry, rather
+// than after a policy decision or a human's approval.
+func (e *Engine) validate(req Request, args []string) error {
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	if err := checkCwd(req.Cwd); err != nil {
+		return err
+	}
+	if err := checkDir(req.Cwd); err != nil {
+		return err
+	}
+	if err := checkEnv(req.Env, e.cfg.Exec.RequestEnv); err != nil {
+		return err
+	}
+	if err := e.authenticate(req); err != nil {
+		return err
+	}
+	r, rargs, err := e.runner(req, args)
+	if err != nil {
+		return err
+	}
+	if c, ok := r.(cap.Capability); ok {
+		return c.Validate(rargs)
+	}
+	return nil
+}
+
+// rejectInvalid audits a request validate rejected and returns its result.
+func (e *Engine) rejectInvalid(ctx context.Context, req Request, args []string, err error) *Result {
 	cmdStr := req.Command
-	if len(args) > 0 {
+	if cmdStr == "" {
 		cmdStr = strings.Join(args, " ")
 	}
+	e.logExecution(ctx, cmdStr, nil, nil, ExitValidation, "", err.Error(), 0, req)
+	return &Result{ExitCode: ExitValidation, Stderr: "doit: " + err.Error(), Error: errorInfo(ErrorValidation, err)}
+}
+
+// runCommand runs an allowed command, returning its exit code, the signal
+// that killed it if any, and why it could not run if it did not.
+func (e *Engine) runCommand(ctx context.Context, args []string, req Request, stdout, stderr io.Writer) (int, string, *ErrorInfo) {
+	ctx, span := e.traceExec(ctx, req, args)
+	defer span.End()
+	e.chaos.MaybePanic("runCommand")
+	// With audit.fail_closed, nothing runs that might go unrecorded.
+	if err := e.checkAudit(); err != nil {
+		fmt.Fprintf(stderr, "doit: %v; not running the command\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitUnavailable, "", err.Error(), 0, req)
+		return ExitUnavailable, "", &ErrorInfo{Kind: ErrorInternal, Message: err.Error()}
+	}
+	// A before hook that fails stops the command; after hooks run once it
+	// has, whatever its outcome (see config.HookConfig).
+	if err := e.runHooks(ctx, config.HookBefore, req, args, stderr); err != nil {
+		fmt.Fprintf(stderr, "doit: %v; not running the command\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitPolicyDeny, "", err.Error(), 0, req)
+		return ExitPolicyDeny, "", &ErrorInfo{Kind: ErrorPolicy, Message: err.Error()}
+	}
+	defer e.runHooks(ctx, config.HookAfter, req, args, stderr)
+	if err := e.admitAgent(req); err != nil {
+		fmt.Fprintf(stderr, "doit: %v\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitPolicyDeny, "", err.Error(), 0, req)
+		return ExitPolicyDeny, "", &ErrorInfo{Kind: ErrorPolicy, Message: err.Error(), RuleID: agentRateRuleID}
+	}
+	// A missing program fails the command here, before any part of it
+	// runs, not with an exec error from the shell partway through.
+	if err := e.preflight(req, args); err != nil {
+		fmt.Fprintf(stderr, "doit: %v\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitUnavailable, "", err.Error(), 0, req)
+		return ExitUnavailable, "", errorInfo(ErrorExec, err)
+	}
+	r, rargs, err := e.runner(req, args)
+	if err != nil {
+		fmt.Fprintf(stderr, "doit: %v\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitValidation, "", err.Error(), 0, req)
+		return ExitValidation, "", errorInfo(ErrorValidation, err)
+	}
+	// An allowance on conditions holds only if the command can be held
+	// to them, and to the execution profile the decision puts it under.
+	conds := conditionsOf(ctx)
+	if r == nil {
+		req.profile = e.profileFor(ctx, req)
+	}
+	err = e.checkConditions(conds, r != nil, args, req)
+	if err == nil {
+		err = e.checkProfile(req.profile)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w; not running the command", err)
+		fmt.Fprintf(stderr, "doit: %v\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitPolicyDeny, "", err.Error(), 0, req)
+		return ExitPolicyDeny, "", &ErrorInfo{Kind: ErrorPolicy, Message: err.Error()}
+	}
+	cctx, cout, cerr, cancel := withConditions(ctx, conds, stdout, stderr)
+	defer cancel()
+	defer func() {
+		if cause := context.Cause(cctx); cctx.Err() != nil && ctx.Err() == nil {
+			fmt.Fprintf(stderr, "doit: %v; it was stopped\n", cause)
+		}
+	}()
+	// What the command writes counts against its agent's quotas; a
+	// secret it reads taints what its agent runs afterwards.
+	meter := e.meterQuota(req)
+	e.taintBy(req, stderr)
+	if r != nil {
+		cctx = cap.NewChangesContext(cctx, &cap.Changes{})
+		exitCode, fail := e.runInProcess(cctx, r, rargs, req, cout, cerr)
+		e.recordQuota(cctx, meter, stderr)
+		return exitCode, "", fail
+	}
+	// Point out a redirection that empties a pipe, which the shell
+	// accepts without complaint.
+	for _, note := range policy.PipedAway(req.shellCommand()) {
+		fmt.Fprint(stderr, e.commentary("doit: note: "+note+"\n"))
+	}
+	if conds != nil && conds.DryRun != "" && !e.dryRun(cctx, conds, args, req, cout, cerr) {
+		err := fmt.Errorf("the dry run with %s failed; not running the command", conds.DryRun)
+		fmt.Fprintf(stderr, "doit: %v\n", err)
+		e.logExecution(ctx, req.shellCommand(), nil, nil, ExitPolicyDeny, "", err.Error(), 0, req)
+		return ExitPolicyDeny, "", &ErrorInfo{Kind: ErrorPolicy, Message: err.Error()}
+	}
+	exitCode, signal, fail := e.runShellCommand(cctx, args, req, cout, cerr)
+	e.recordQuota(cctx, meter, stderr)
+	return exitCode, signal, fail
+}
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr)
+// runShellCommand executes a command via sh -c, propagating exit codes.
+// args are the request's words, which decide network isolation.
+func (e *Engine) runShellCommand(ctx context.Context, args []string, req Request, stdout, stderr io.Writer) (exitCode int, signal string, fail *ErrorInfo) {
+	cmdStr := req.shellCommand()
+
+	// The umask goes on the same line, so the shell's line numbers in
+	// error messages still match the command's.
+	prof := e.execProfile(req.profile)
+	argv := profileArgv(prof, fmt.Sprintf("umask %03o; %s", e.umask, cmdStr))
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if req.Stdin != "" {
+		cmd.Stdin = strings.NewReader(req.Stdin)
+	}
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
+	proc.Isolate(cmd, e.cfg.Exec.KillGraceDuration())
+	if c := conditionsOf(ctx); e.isolatesNetwork(args) || c != nil && c.NoNetwork || prof != nil && prof.NoNetwork {
+		isolateNetwork(cmd)
+	}
 	if req.Cwd != "" {
 		cmd.Dir = req.Cwd
 	}
-	if req.Env != nil {
-		cmd.Env = os.Environ()
-		for k, v := range req.Env {
-			cmd.Env = append(cmd.Env, k+"="+v)
-		}
-	}
+	cmd.Env = envList(commandEnv(req, prof))
 
 	start := time.Now()
-	err := cmd.Run()
+	err := checkDir(cmd.Dir)
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err == nil {
+		req.relay.running(cmd)
+		err = cmd.Wait()
+		req.relay.running(nil)
+	}
 	duration := time.Since(start)
 
-	exitCode := 0
 	errMsg := ""
 	if err != nil {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			exitCode = exitErr.ExitCode()
+			exitCode, signal = proc.ExitStatus(exitErr.ProcessState)
 		} else {
-			exitCode = 2
+			exitCode = startFailureExitCode(err)
 			errMsg = err.Error()
-			fmt.Fprintf(stderr, "doit: %v\n", err)
+			if e.verbosity != VerbosityQuiet {
+				fmt.Fprintf(stderr, "doit: %v\n", err)
+			}
+			fail = errorInfo(ErrorExec, err)
+			if exitCode == ExitValidation {
+				fail.Kind = ErrorValidation
+			}
 		}
 	}
 
-	e.logExecution(ctx, cmdStr, nil, nil, exitCode, errMsg, duration, req)
-	return exitCode
+	req.violations = e.checkAssertions(ctx, req, args, stderr)
+	e.logExecution(ctx, cmdStr, nil, nil, exitCode, signal, errMsg, duration, req)
+	return exitCode, signal, fail
+}
+
+// capUsesNetwork reports whether prog is a registered capability that
+// declares network use for args.
+func (e *Engine) capUsesNetwork(prog string, args []string) bool {
+	c, err := e.reg.Lookup(prog)
+	return err == nil && cap.UsesNetwork(c, args)
 }
 
-func (e *Engine) logExecution(ctx context.Context, cmdStr string, segments, tiers []string, exitCode int, errMsg string, duration time.Duration, req Request) {
+// isolatesNetwork reports whether a command runs without network access:
+// its tier is listed in network.isolate and the capability does not
+// declare network use for these arguments. As with audit tiers, the first
+// word decides for the whole command line.
+func (e *Engine) isolatesNetwork(args []string) bool {
+	if len(e.netIsolate) == 0 || len(args) == 0 {
+		return false
+	}
+	tier := cap.TierRead
+	if c, err := e.reg.Lookup(args[0]); err == nil {
+		if cap.UsesNetwork(c, args[1:]) {
+			return false
+		}
+		tier = c.Tier()
+	}
+	return e.netIsolate[tier]
+}
+
+func (e *Engine) logExecution(ctx context.Context, cmdStr string, segments, tiers []string, exitCode int, signal, errMsg string, duration time.Duration, req Request) {
 	if e.logger == nil {
 		return
 	}
-	var opts *audit.LogOptions
+	_, span := e.tracer.Start(ctx, spanAudit)
+	defer span.End()
+	// A command its caller stopped for a reason (see
+	// context.WithCancelCause), such as a client that stopped reading its
+	// output, is audited with the reason.
+	if errMsg == "" && ctx.Err() != nil {
+		if cause := context.Cause(ctx); cause != ctx.Err() {
+			errMsg = cause.Error()
+		}
+	}
+	opts := &audit.LogOptions{Tier: e.effectiveTier(cmdStr).String(), Session: e.sessionID(), Agent: req.agentName(), Signal: signal, Group: req.group, Redirects: policy.RedirectTargets(cmdStr)}
 	if info := policy.EvalFromContext(ctx); info != nil {
-		opts = &audit.LogOptions{
-			PolicyLevel:   info.Level,
-			PolicyResult:  info.Decision,
-			PolicyRuleID:  info.RuleID,
-			Justification: info.Justification,
-			SafetyArg:     info.SafetyArg,
+		opts.PolicyLevel = info.Level
+		opts.PolicyResult = info.Decision
+		opts.PolicyRuleID = info.RuleID
+		if policy.IsGrant(info.RuleID) {
+			opts.Grant = info.RuleID
+		}
+		opts.Justification = info.Justification
+		opts.SafetyArg = info.SafetyArg
+		opts.Conditions = info.Conditions.String()
+		opts.Profile = req.profile
+		opts.LLM = e.spillExchanges(info.Exchanges)
+	}
+	opts.RetryRule, opts.RetrySeq = req.retryRule, req.retrySeq
+	if req.hook != nil {
+		opts.Hook = req.hook.auditRecord()
+	}
+	opts.Violations = req.violations
+	if changes := cap.ChangesFromContext(ctx); changes != nil {
+		for _, fc := range changes.List() {
+			opts.Files = append(opts.Files, audit.FileChange{Path: fc.Path, Before: fc.Before, After: fc.After})
 		}
 	}
-	_ = e.logger.Log(cmdStr, segments, tiers, exitCode, errMsg, duration, req.Cwd, req.Retry, opts)
+	err := e.chaos.AuditError()
+	if err == nil {
+		err = e.logger.Log(cmdStr, segments, tiers, exitCode, errMsg, duration, req.Cwd, req.Retry, opts)
+	}
+	e.noteAudit(err)
+	if err != nil {
+		span.Fail(err.Error())
+	}
 }
 
-func (e *Engine) logPolicyResult(req Request, args []string, result *policy.Result, segments, tiers []string, exitCode int) {
+func (e *Engine) logPolicyResult(ctx context.Context, req Request, args []string, result *policy.Result, segments, tiers []string, exitCode int) {
+	// Touching a tripwire is reported however the request is logged.
+	tripped := result.RuleID == policy.TripwireRuleID
+	if tripped {
+		log.Printf("doit: %s (agent %q, cwd %s): %s", result.Reason, req.agentName(), req.Cwd, strings.Join(args, " "))
+		req.events.tripwire(result)
+	}
 	if e.logger == nil {
 		return
 	}
+	_, span := e.tracer.Start(ctx, spanAudit)
+	defer span.End()
 	opts := &audit.LogOptions{
 		PolicyLevel:   result.Level,
 		PolicyResult:  result.Decision.String(),
 		PolicyRuleID:  result.RuleID,
+		Tier:          e.effectiveTier(strings.Join(args, " ")).String(),
 		Justification: req.Justification,
 		SafetyArg:     req.SafetyArg,
+		Session:       e.sessionID(),
+		Agent:         req.agentName(),
+		RetryRule:     req.retryRule,
+		RetrySeq:      req.retrySeq,
+		LLM:           e.spillExchanges(result.Exchanges),
+		Group:         req.group,
+		Redirects:     policy.RedirectTargets(strings.Join(args, " ")),
+	}
+	if req.hook != nil {
+		opts.Hook = req.hook.auditRecord()
+	}
+	if tripped {
+		opts.Severity = audit.SeverityCritical
+	}
+	err := e.chaos.AuditError()
+	if err == nil {
+		err = e.logger.Log(
+			strings.Join(args, " "),
+			segments, tiers,
+			exitCode, result.Reason,
+			0, req.Cwd, req.Retry, opts,
+		)
+	}
+	e.noteAudit(err)
+	if err != nil {
+		span.Fail(err.Error())
 	}
-	_ = e.logger.Log(
-		strings.Join(args, " "),
-		segments, tiers,
-		exitCode, result.Reason,
-		0, req.Cwd, req.Retry, opts,
-	)
+}
+
+// closeGroup records the audit entry that closes a group — a plan, a
+// batch, or a work session — with the tally of its entries kept as they
+// were written. The entry's pipeline is a shell comment, so it never runs
+// as a command. A group with no entries is not closed.
+func (e *Engine) closeGroup(kind, group, label, session string) {
+	summary := e.groups.take(group)
+	if e.logger == nil || summary == nil {
+		return
+	}
+	summary.Kind, summary.Label = kind, label
+	e.noteAudit(e.logger.Log("# "+summary.String(), nil, nil, 0, "", 0, "", false, &audit.LogOptions{
+		Session: session,
+		Group:   group,
+		Summary: summary,
+	}))
+}
+
+// spillExchanges stores the prompts and responses of Level 3's LLM calls
+// beside the audit log and returns their audit records. A call whose text
+// cannot be stored is still recorded by hash.
+func (e *Engine) spillExchanges(exchanges []policy.Exchange) []audit.LLMCall {
+	dir := audit.SpillDir(e.logger.Path())
+	var calls []audit.LLMCall
+	for _, ex := range exchanges {
+		call := audit.LLMCall{Stage: ex.Stage, Error: ex.Error}
+		var err error
+		if call.Prompt, err = audit.Spill(dir, ex.Prompt); err != nil {
+			log.Printf("doit: audit: %v", err)
+		}
+		if ex.Error == "" {
+			if call.Response, err = audit.Spill(dir, ex.Response); err != nil {
+				log.Printf("doit: audit: %v", err)
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls
 }
 
 func (e *Engine) tryPromote() {
@@ -1241,6 +2068,9 @@ func (e *Engine) tryPromote() {
 		return
 	}
 
+	if err := e.logger.Flush(); err != nil {
+		log.Printf("doit: auto-promote: flush audit log: %v", err)
+	}
 	entries, err := audit.Query(e.logger.Path(), &audit.Filter{PolicyLevel: 3})
 	if err != nil {
 		log.Printf("doit: auto-promote: query audit log: %v", err)
@@ -1253,23 +2083,65 @@ func (e *Engine) tryPromote() {
 	}
 
 	var newEntries []policy.PolicyEntry
-	now := time.Now().UTC()
+	now := e.clock.Now().UTC()
 	for i := range candidates {
 		newEntries = append(newEntries, policy.CandidateToEntry(&candidates[i], now))
 	}
 
-	added, err := policy.AppendEntries(e.storePath, newEntries)
+	var added int
+	err = e.changeStore(func() (err error) {
+		added, err = policy.AppendEntries(e.storePath, newEntries)
+		return err
+	})
 	if err != nil {
 		log.Printf("doit: auto-promote: append entries: %v", err)
 		return
 	}
 	if added > 0 {
 		log.Printf("doit: auto-promote: added %d new learned policy entries", added)
-		e.reloadL2()
 	}
 }
 
+// learnConfident records a Level 3 allow the LLM was highly confident of
+// as an unapproved learned policy entry, for a human to approve.
+func (e *Engine) learnConfident(command string, result *policy.Result) {
+	if e.storePath == "" {
+		return
+	}
+	c := policy.ConfidentCandidate(command, *result.Confidence, result.Reason)
+	if c == nil {
+		return
+	}
+	var added int
+	err := e.changeStore(func() (err error) {
+		added, err = policy.AppendEntries(e.storePath, []policy.PolicyEntry{policy.CandidateToEntry(c, e.clock.Now().UTC())})
+		return err
+	})
+	if err != nil {
+		log.Printf("doit: learn: append entry: %v", err)
+		return
+	}
+	if added > 0 {
+		log.Printf("doit: learn: added an unapproved learned policy entry for %q", command)
+	}
+}
+
+// changeStore makes a change to the learned policy store, which takes the
+// store's lock (see policy.ModifyStore), and reloads Level 2 once it is
+// made. The engine's changes are made one at a time, so each reload
+// includes every change before it.
+func (e *Engine) changeStore(change func() error) error {
+	e.storeMu.Lock()
+	defer e.storeMu.Unlock()
+	if err := change(); err != nil {
+		return err
+	}
+	e.reloadL2()
+	return nil
+}
+
 func (e *Engine) reloadL2() {
+	stat := storeStat(e.storePath)
 	entries, err := policy.LoadStore(e.storePath)
 	if err != nil {
 		log.Printf("doit: auto-promote: reload L2: %v", err)
@@ -1277,5 +2149,38 @@ func (e *Engine) reloadL2() {
 	}
 	e.l2Mu.Lock()
 	e.policyL2 = policy.NewLevel2(entries)
+	e.l2Stat = stat
+	e.memo.forget()
 	e.l2Mu.Unlock()
 }
+
+// refreshL2 reloads the learned policy store if another process (such as
+// `doit --grants add`) has changed it since it was last loaded.
+func (e *Engine) refreshL2() {
+	e.l2Mu.RLock()
+	loaded, stale := e.policyL2 != nil, storeChanged(e.l2Stat, storeStat(e.storePath))
+	e.l2Mu.RUnlock()
+	if loaded && stale {
+		e.reloadL2()
+	}
+}
+
+// storeStat returns the file at path, or nil if it does not exist.
+func storeStat(path string) os.FileInfo {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// storeChanged reports whether the store was replaced, created, or
+// removed between was and is. Every change renames a new file into place
+// (see policy.SaveStore), so a change shows as a different file even
+// within the resolution of modification times.
+func storeChanged(was, is os.FileInfo) bool {
+	if was == nil || is == nil {
+		return was != is
+	}
+	return !os.SameFile(was, is) || !was.ModTime().Equal(is.ModTime()) || was.Size() != is.Size()
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/agent"
+)
+
+// baseEnv lists what of doit's own environment a command inherits: who
+// and where the user is, locale and terminal, and where toolchains keep
+// their files. Nothing else of it reaches a command, so what runs does not
+// depend on whatever doit happened to be started with.
+var baseEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TMPDIR",
+	"LANG", "LC_ALL", "LC_CTYPE", "LC_MESSAGES", "TZ", "TERM",
+	"SSH_AUTH_SOCK",
+	"XDG_CACHE_HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_RUNTIME_DIR", "XDG_STATE_HOME",
+	"GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "CARGO_HOME", "RUSTUP_HOME", "JAVA_HOME",
+}
+
+// requestVars are the variables a request may set: switches for locale,
+// colour, logging and build targets that pick no code to run. The global
+// config's exec.request_env names more.
+var requestVars = map[string]bool{
+	"LANG": true, "LC_ALL": true, "LC_CTYPE": true, "LC_MESSAGES": true, "TZ": true,
+	"CI": true, "NO_COLOR": true, "FORCE_COLOR": true, "CLICOLOR": true, "COLUMNS": true, "LINES": true,
+	"GOOS": true, "GOARCH": true, "CGO_ENABLED": true,
+	"RUST_BACKTRACE": true, "RUST_LOG": true,
+	"NODE_ENV": true, "PYTHONUNBUFFERED": true, "PYTHONDONTWRITEBYTECODE": true,
+}
+
+// envHooks are variables that choose what code runs, out of sight of
+// policy: they make the shell or the dynamic loader run code of the
+// caller's choosing before the command itself, pick the program a command
+// name or library resolves to, move the home directory policy expands ~
+// by, or have git, a pager, the JVM, node, perl, python or ruby run extra
+// code. Policy judges a command by what its words name, so a request may
+// not set them even if exec.request_env names them; doit's own
+// environment may. envHookPrefixes name whole families of them.
+var envHooks = map[string]bool{
+	"BASH_ENV":                   true,
+	"ENV":                        true,
+	"HOME":                       true,
+	"IFS":                        true,
+	"PATH":                       true,
+	"LD_AUDIT":                   true,
+	"LD_LIBRARY_PATH":            true,
+	"LD_PRELOAD":                 true,
+	"DYLD_FALLBACK_LIBRARY_PATH": true,
+	"DYLD_INSERT_LIBRARIES":      true,
+	"DYLD_LIBRARY_PATH":          true,
+	"EDITOR":                     true,
+	"VISUAL":                     true,
+	"PAGER":                      true,
+	"LESSOPEN":                   true,
+	"LESSCLOSE":                  true,
+	"SSH_ASKPASS":                true,
+	"GIT_ASKPASS":                true,
+	"GIT_DIR":                    true,
+	"GIT_EDITOR":                 true,
+	"GIT_EXEC_PATH":              true,
+	"GIT_EXTERNAL_DIFF":          true,
+	"GIT_PAGER":                  true,
+	"GIT_SSH":                    true,
+	"GIT_SSH_COMMAND":            true,
+	"GIT_TEMPLATE_DIR":           true,
+	"GIT_WORK_TREE":              true,
+	"GOFLAGS":                    true,
+	"JAVA_TOOL_OPTIONS":          true,
+	"_JAVA_OPTIONS":              true,
+	"NODE_OPTIONS":               true,
+	"PERL5LIB":                   true,
+	"PERL5OPT":                   true,
+	"PYTHONHOME":                 true,
+	"PYTHONPATH":                 true,
+	"PYTHONSTARTUP":              true,
+	"RUBYLIB":                    true,
+	"RUBYOPT":                    true,
+}
+
+// envHookPrefixes begin the names of envHooks that come in families: git's
+// GIT_CONFIG_COUNT, GIT_CONFIG_KEY_n and GIT_CONFIG_VALUE_n set any config
+// option, core.fsmonitor and core.pager among them, as GIT_CONFIG_PARAMETERS
+// and GIT_CONFIG_GLOBAL do by other means.
+var envHookPrefixes = []string{"GIT_CONFIG", "LD_", "DYLD_"}
+
+// isEnvHook reports whether name is one of envHooks.
+func isEnvHook(name string) bool {
+	if envHooks[name] {
+		return true
+	}
+	for _, p := range envHookPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEnv reports a request environment doit will not pass on: a
+// variable whose name or value the environment cannot hold, one of
+// envHooks, or one neither requestVars nor extra names.
+func checkEnv(env map[string]string, extra []string) error {
+	for k, v := range env {
+		switch {
+		case k == "" || strings.ContainsAny(k, "=\x00"):
+			return fmt.Errorf("invalid environment variable name %q", k)
+		case strings.ContainsRune(v, 0):
+			return fmt.Errorf("environment variable %s contains a NUL byte", k)
+		case isEnvHook(k):
+			return fmt.Errorf("environment variable %s would run code policy does not see; set it in doit's own environment if it is needed", k)
+		case !requestVars[k] && !slices.Contains(extra, k):
+			return fmt.Errorf("environment variable %s may not be set by a request; the global config's exec.request_env can allow it", k)
+		}
+	}
+	return nil
+}
+
+// checkCwd reports a working directory that is not absolute. Policy
+// judges the paths a command names relative to it, so it must not depend
+// on where doit happens to run.
+func checkCwd(dir string) error {
+	if dir != "" && !filepath.IsAbs(dir) {
+		return fmt.Errorf("working directory %q is not an absolute path", dir)
+	}
+	return nil
+}
+
+// requestEnv returns the environment a request runs with: baseEnv from
+// doit's own, overlaid by the request's, which checkEnv has vetted. The
+// shell and the programs doit runs itself for a request see the same
+// environment.
+func requestEnv(env map[string]string) map[string]string {
+	merged := make(map[string]string, len(baseEnv)+len(env))
+	for _, k := range baseEnv {
+		if v, ok := os.LookupEnv(k); ok {
+			merged[k] = v
+		}
+	}
+	maps.Copy(merged, env)
+	delete(merged, agent.SecretEnv)
+	return merged
+}
+
+// envList returns env as sorted KEY=value pairs for exec.Cmd.Env.
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
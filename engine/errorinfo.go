@@ -0,0 +1,85 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// Kinds of ErrorInfo.
+const (
+	ErrorValidation = "validation" // the request was malformed; it did not run
+	ErrorPolicy     = "policy"     // policy denied the command or it awaits approval
+	ErrorExec       = "exec"       // the command could not be started
+	ErrorInternal   = "internal"   // doit itself failed
+)
+
+// ErrorInfo explains, in machine-readable form, why doit rather than the
+// command decided a request's outcome, so that callers need not parse
+// Stderr. A command that ran and failed has none: its exit code speaks
+// for it.
+type ErrorInfo struct {
+	Kind       string `json:"kind"` // ErrorValidation, ErrorPolicy, ErrorExec, or ErrorInternal
+	Message    string `json:"message"`
+	Segment    int    `json:"segment,omitempty"`    // 1-based pipeline segment at fault, 0 for the whole command
+	RuleID     string `json:"rule_id,omitempty"`    // policy rule that decided
+	Suggestion string `json:"suggestion,omitempty"` // what to do next, if doit knows
+	Retryable  bool   `json:"retryable,omitempty"`  // a human may override the denial
+	Approval   bool   `json:"approval,omitempty"`   // awaiting a human; resubmit with Result.EscalateToken once approved
+}
+
+// segmentError is an error in one segment of a command line.
+type segmentError struct {
+	segment, segments int // 1-based
+	err               error
+	hint              string // suggestion, if any
+}
+
+func (e *segmentError) Error() string {
+	if e.segments > 1 {
+		return fmt.Sprintf("segment %d of %d: %v", e.segment, e.segments, e.err)
+	}
+	return e.err.Error()
+}
+
+func (e *segmentError) Unwrap() error { return e.err }
+
+// errorInfo describes err, of the given kind, picking out the segment and
+// suggestion it carries.
+func errorInfo(kind string, err error) *ErrorInfo {
+	info := &ErrorInfo{Kind: kind, Message: err.Error()}
+	var serr *segmentError
+	if errors.As(err, &serr) {
+		info.Segment, info.Suggestion = serr.segment, serr.hint
+	}
+	var uerr *cap.UnavailableError
+	if errors.As(err, &uerr) {
+		info.Suggestion = uerr.Hint
+	}
+	return info
+}
+
+// policyErrorInfo describes a policy result that stopped a command, with
+// exitCode as denyExitCode or ExitEscalationPending gives it.
+func policyErrorInfo(r *policy.Result, exitCode int) *ErrorInfo {
+	info := &ErrorInfo{Kind: ErrorPolicy, Message: r.Reason, RuleID: r.RuleID}
+	switch {
+	case exitCode == ExitValidation:
+		info.Kind = ErrorValidation
+		info.Suggestion = "resubmit the command exactly as it was escalated or denied, from the same session and working directory"
+	case exitCode == ExitEscalationPending:
+		info.Approval = true
+		info.Suggestion = "ask a human to approve it (doit --top, or doit --approve), then resubmit it with the approval token"
+	case r.Bypassable:
+		info.Retryable = true
+		info.Suggestion = "ask the user whether to override this rule; do not work around it"
+	default:
+		info.Suggestion = "do not retry or work around this denial; it cannot be overridden"
+	}
+	return info
+}
@@ -0,0 +1,220 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/events"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// humanApprovalRuleID tags policy results decided by a human resolving a
+// parked escalation.
+const humanApprovalRuleID = "human-approval"
+
+// pendingEscalation is an escalation whose approval token has been issued
+// to the agent but not yet used, revoked, or expired.
+type pendingEscalation struct {
+	events.Pending
+	token  string
+	parked chan bool // non-nil while the request waits; receives the decision
+	plan   bool      // the escalation covers a plan (see RunPlan)
+}
+
+// trackEscalation records an issued approval token as pending.
+func (e *Engine) trackEscalation(base events.Event, level int, reason, token string) {
+	entry, ok := e.tokenStore.Peek(token)
+	if !ok {
+		return
+	}
+	e.pendingMu.Lock()
+	defer e.pendingMu.Unlock()
+	if e.pending == nil {
+		e.pending = make(map[uint64]*pendingEscalation)
+	}
+	e.pending[base.Request] = &pendingEscalation{
+		Pending: events.Pending{
+			PID:       base.PID,
+			Request:   base.Request,
+			Command:   base.Command,
+			Cwd:       base.Cwd,
+			Session:   base.Session,
+			Level:     level,
+			Reason:    reason,
+			IssuedAt:  entry.CreatedAt,
+			ExpiresAt: entry.ExpiresAt,
+		},
+		token: token,
+	}
+}
+
+// PendingEscalations returns the escalations whose approval tokens are
+// still outstanding, oldest first. Tokens that have been used or have
+// expired are dropped from the list.
+func (e *Engine) PendingEscalations() []events.Pending {
+	e.pendingMu.Lock()
+	defer e.pendingMu.Unlock()
+	var out []events.Pending
+	for id, p := range e.pending {
+		if _, ok := e.tokenStore.Peek(p.token); !ok {
+			delete(e.pending, id)
+			continue
+		}
+		out = append(out, p.Pending)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Request < out[j].Request })
+	return out
+}
+
+// ResolveEscalation records a human decision on a pending escalation.
+// Denying revokes the approval token, so the agent's retry is refused.
+// Approving leaves the token valid for the agent's retry and removes the
+// escalation from the pending list. If the request is parked (see
+// policy.escalation_wait), the decision is handed to it instead. With
+// always, the decision is also recorded as a learned (L2) policy entry for
+// the command. Either way a resolution event is published.
+func (e *Engine) ResolveEscalation(request uint64, approve, always bool) error {
+	e.pendingMu.Lock()
+	p, ok := e.pending[request]
+	if ok && p.plan && always {
+		e.pendingMu.Unlock()
+		return fmt.Errorf("request %d is a plan; --always applies to single commands only", request)
+	}
+	var parked chan bool
+	if ok {
+		parked = p.parked
+		delete(e.pending, request)
+	}
+	e.pendingMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no pending escalation for request %d", request)
+	}
+
+	decision := "allow"
+	if !approve {
+		decision = "deny"
+		if !e.tokenStore.Revoke(p.token) {
+			return fmt.Errorf("escalation for request %d already used or expired", request)
+		}
+	} else if _, live := e.tokenStore.Peek(p.token); !live {
+		return fmt.Errorf("escalation for request %d already used or expired", request)
+	}
+	if parked != nil {
+		parked <- approve // buffered; the waiter may have just given up
+	}
+	if always {
+		if err := e.recordDecision(p.Command, decision, "User decision via doit --approve/--deny"); err != nil {
+			log.Printf("doit: resolve %d: %v", request, err)
+		}
+	}
+
+	e.events.Publish(events.Event{
+		Type:     events.Resolution,
+		Time:     e.clock.Now().UTC(),
+		PID:      p.PID,
+		Request:  p.Request,
+		Command:  p.Command,
+		Cwd:      p.Cwd,
+		Session:  p.Session,
+		Level:    p.Level,
+		Decision: decision,
+		Reason:   "resolved by human",
+	})
+	return nil
+}
+
+// awaitEscalation parks an escalated request for up to the configured
+// escalation wait so a human can resolve it via doit --top without the
+// agent resubmitting. It returns the human's decision as a policy result,
+// or nil if parking is disabled, the wait elapsed, or ctx was cancelled,
+// in which case the caller reports the escalation as usual. A notice is
+// written to w, if non-nil, before waiting.
+func (e *Engine) awaitEscalation(ctx context.Context, ev *requestEvents, token string, w io.Writer) *policy.Result {
+	return e.park(ctx, ev, token, w, e.cfg.Policy.EscalationWaitDuration())
+}
+
+// park waits up to wait for a human to resolve the pending escalation of
+// ev, as awaitEscalation does.
+func (e *Engine) park(ctx context.Context, ev *requestEvents, token string, w io.Writer, wait time.Duration) *policy.Result {
+	if wait <= 0 {
+		return nil
+	}
+	request := ev.base.Request
+	e.pendingMu.Lock()
+	parked := make(chan bool, 1)
+	p, ok := e.pending[request]
+	if ok {
+		p.parked = parked
+	}
+	e.pendingMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if w != nil {
+		io.WriteString(w, e.commentary(fmt.Sprintf("doit: escalated; waiting up to %s for a human decision (doit --top)\n", wait)))
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case approve := <-parked:
+		return e.humanDecision(approve, token)
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	// No decision in time: stop parking, leaving the escalation pending
+	// for the agent's retry with the token. A decision that raced the
+	// deadline still counts.
+	e.pendingMu.Lock()
+	p.parked = nil
+	e.pendingMu.Unlock()
+	select {
+	case approve := <-parked:
+		return e.humanDecision(approve, token)
+	default:
+		return nil
+	}
+}
+
+// humanDecision converts a human resolution of a parked escalation into a
+// policy result.
+func (e *Engine) humanDecision(approve bool, token string) *policy.Result {
+	if !approve {
+		return &policy.Result{Decision: policy.Deny, Level: 3, Reason: "denied by a human while parked", RuleID: humanApprovalRuleID}
+	}
+	// The parked request spends the approval itself.
+	if !e.tokenStore.Revoke(token) {
+		return nil
+	}
+	return &policy.Result{Decision: policy.Allow, Level: 3, Reason: "approved by a human while parked", RuleID: humanApprovalRuleID}
+}
+
+// escalationController adapts the engine to events.Controller and
+// events.GatekeeperSwitch.
+type escalationController struct{ e *Engine }
+
+func (c escalationController) Pending() []events.Pending { return c.e.PendingEscalations() }
+
+func (c escalationController) Resolve(request uint64, approve, always bool) error {
+	return c.e.ResolveEscalation(request, approve, always)
+}
+
+func (c escalationController) Gatekeeper() (events.Gatekeeper, error) {
+	return c.e.Gatekeeper()
+}
+
+func (c escalationController) SetGatekeeper(enabled bool) (events.Gatekeeper, error) {
+	if err := c.e.SetGatekeeper(enabled); err != nil {
+		return events.Gatekeeper{}, err
+	}
+	return c.e.Gatekeeper()
+}
@@ -0,0 +1,136 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/events"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// Events returns the bus on which the engine publishes each request's
+// lifecycle: request-start, decision, tripwire, escalation, violation, and
+// exit.
+func (e *Engine) Events() *events.Bus {
+	return e.events
+}
+
+// ServeEvents serves the event bus on this process's Unix socket in the
+// configured events directory until ctx is done. It returns nil at once
+// if events.socket is disabled.
+func (e *Engine) ServeEvents(ctx context.Context) error {
+	if !e.cfg.Events.Socket || e.cfg.Events.Dir == "" {
+		return nil
+	}
+	l, err := events.Listen(e.cfg.Events.Dir)
+	if err != nil {
+		return err
+	}
+	return events.Serve(ctx, l, e.events, escalationController{e})
+}
+
+// requestEvents publishes the lifecycle events of one Execute call.
+type requestEvents struct {
+	e     *Engine
+	base  events.Event
+	start time.Time
+}
+
+// beginRequest assigns the request an ID, counts it as in flight, and
+// publishes request-start.
+func (e *Engine) beginRequest(req Request, args []string) *requestEvents {
+	e.requestStarted()
+	r := &requestEvents{
+		e: e,
+		base: events.Event{
+			PID:     os.Getpid(),
+			Request: e.reqSeq.Add(1),
+			Command: strings.Join(args, " "),
+			Cwd:     req.Cwd,
+			Session: e.sessionID(),
+			Agent:   req.agentName(),
+		},
+		start: time.Now(),
+	}
+	r.publish(events.RequestStart, nil)
+	return r
+}
+
+func (r *requestEvents) publish(t events.Type, fill func(*events.Event)) {
+	ev := r.base
+	ev.Type = t
+	ev.Time = r.e.clock.Now().UTC()
+	if fill != nil {
+		fill(&ev)
+	}
+	if r.e.chaos.DropFrame() {
+		return
+	}
+	r.e.events.Publish(ev)
+}
+
+// decision publishes the policy chain's result, if there was one.
+func (r *requestEvents) decision(p *policy.Result) {
+	if p == nil {
+		return
+	}
+	r.publish(events.Decision, func(ev *events.Event) {
+		ev.Level = p.Level
+		ev.Decision = p.Decision.String()
+		ev.RuleID = p.RuleID
+		ev.Reason = p.Reason
+	})
+}
+
+// escalation records the issued approval token as pending and publishes
+// the escalation. The token itself stays out of the event.
+func (r *requestEvents) escalation(p *policy.Result, token string) {
+	r.e.trackEscalation(r.base, p.Level, p.Reason, token)
+	r.publish(events.Escalation, func(ev *events.Event) {
+		ev.Level = p.Level
+		ev.Reason = p.Reason
+	})
+}
+
+// violation publishes a post-condition the command broke. A nil r, for a
+// request run outside Execute, publishes nothing.
+func (r *requestEvents) violation(v string) {
+	if r == nil {
+		return
+	}
+	r.publish(events.Violation, func(ev *events.Event) {
+		ev.Reason = v
+	})
+}
+
+// tripwire publishes the denial of a command that touched a tripwire.
+func (r *requestEvents) tripwire(p *policy.Result) {
+	if r == nil {
+		return
+	}
+	r.publish(events.Tripwire, func(ev *events.Event) {
+		ev.Level = p.Level
+		ev.Decision = p.Decision.String()
+		ev.RuleID = p.RuleID
+		ev.Reason = p.Reason
+	})
+}
+
+// exit publishes the request's outcome and ends its time in flight.
+func (r *requestEvents) exit(res *Result) {
+	defer r.e.requestDone()
+	r.publish(events.Exit, func(ev *events.Event) {
+		code := res.ExitCode
+		ev.ExitCode = &code
+		ev.Signal = res.Signal
+		ev.Level = res.PolicyLevel
+		ev.Decision = res.PolicyDecision
+		ev.RuleID = res.PolicyRuleID
+		ev.DurationMS = float64(time.Since(r.start).Microseconds()) / 1000.0
+	})
+}
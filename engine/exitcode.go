@@ -0,0 +1,39 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+// Exit codes set in Result.ExitCode (and used by the doit binary) when
+// doit itself, rather than the command, determines the outcome. A command
+// that runs has its own exit code passed through unchanged.
+//
+// doit's codes sit in a reserved block (90–94) clear of the codes shells
+// and common tools use (1, 2, 126, 127, 128+signal), so wrappers can
+// branch on them. A command may still exit with one of these values
+// itself; when that matters, check Result.PolicyDecision as well.
+const (
+	// ExitOK means the command ran and succeeded.
+	ExitOK = 0
+
+	// ExitPolicyDeny means the policy engine denied the command; it did
+	// not run.
+	ExitPolicyDeny = 90
+
+	// ExitEscalationPending means the command needs human approval; it
+	// did not run. Result.EscalateToken carries the approval token to
+	// retry with.
+	ExitEscalationPending = 91
+
+	// ExitValidation means the request was malformed or invalid (e.g. a
+	// bad approval token, a missing working directory, or a usage error
+	// on the command line); it did not run.
+	ExitValidation = 92
+
+	// ExitUnavailable means a component doit needs to run the command was
+	// unavailable (e.g. the shell could not be started, or a program the
+	// command runs is not installed); it did not run.
+	ExitUnavailable = 93
+
+	// ExitInternal means doit failed internally.
+	ExitInternal = 94
+)
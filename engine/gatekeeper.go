@@ -0,0 +1,44 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+// Gatekeeper reports whether the Level 3 gatekeeper is consulting its LLM
+// or, turned off or with its circuit breaker open, escalating to a human.
+func (e *Engine) Gatekeeper() (events.Gatekeeper, error) {
+	if e.policyL3 == nil {
+		return events.Gatekeeper{}, fmt.Errorf("the LLM gatekeeper is not enabled (policy.level3_enabled)")
+	}
+	s := e.policyL3.Status()
+	return events.Gatekeeper{
+		PID:      os.Getpid(),
+		Enabled:  s.Enabled,
+		Open:     s.Open,
+		Failures: s.Failures,
+		RetryAt:  s.RetryAt,
+	}, nil
+}
+
+// SetGatekeeper turns the Level 3 gatekeeper on or off for the life of
+// this process. While it is off, commands it would judge are escalated to
+// a human.
+func (e *Engine) SetGatekeeper(enabled bool) error {
+	if e.policyL3 == nil {
+		return fmt.Errorf("the LLM gatekeeper is not enabled (policy.level3_enabled)")
+	}
+	e.policyL3.SetEnabled(enabled)
+	if enabled {
+		log.Printf("doit: L3 gatekeeper enabled")
+	} else {
+		log.Printf("doit: L3 gatekeeper disabled; escalating to a human")
+	}
+	return nil
+}
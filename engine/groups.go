@@ -0,0 +1,62 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/marcelocantos/doit/internal/audit"
+)
+
+// groupTally sums up audit groups — plans, batches, and work sessions —
+// as their entries are written, so that closing one need not read the
+// log back.
+type groupTally struct {
+	mu sync.Mutex
+	m  map[string]*audit.GroupSummary // by plan or batch group, or session ID
+}
+
+// add tallies e, just written, into its plan or batch and its session.
+func (t *groupTally) add(e *audit.Entry) {
+	if e.Summary != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range []string{e.Group, e.Session} {
+		if key == "" {
+			continue
+		}
+		s := t.m[key]
+		if s == nil {
+			if t.m == nil {
+				t.m = map[string]*audit.GroupSummary{}
+			}
+			s = &audit.GroupSummary{}
+			t.m[key] = s
+		}
+		s.Add(e)
+	}
+}
+
+// take returns the tally of group and forgets it, or nil if no entry of
+// group was written.
+func (t *groupTally) take(group string) *audit.GroupSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.m[group]
+	delete(t.m, group)
+	return s
+}
+
+// groupID names a new plan or batch group, of kind: by Options.NewID if
+// set, else by the process and the time.
+func (e *Engine) groupID(kind string) string {
+	if e.newID != nil {
+		return kind + "-" + e.newID()
+	}
+	return fmt.Sprintf("%s-%d-%d", kind, os.Getpid(), e.clock.Now().UnixMilli())
+}
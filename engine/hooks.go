@@ -0,0 +1,167 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// maxHookOutput bounds the output of a hook kept in its audit entry.
+const maxHookOutput = 16 << 10
+
+// hookCall marks a request as the run of a configured hook (see
+// config.HookConfig). Hooks do not trigger hooks.
+type hookCall struct {
+	hook    config.HookConfig
+	trigger string        // the command the hook runs for
+	out     *bytes.Buffer // the hook's output, as it runs
+}
+
+// auditRecord returns the audit form of the hook's run.
+func (h *hookCall) auditRecord() *audit.HookRun {
+	r := &audit.HookRun{Name: h.hook.Name, When: h.hook.When, Trigger: h.trigger}
+	if h.hook.Audit && h.out != nil {
+		r.Output = h.out.String()
+		if len(r.Output) > maxHookOutput {
+			r.Output = r.Output[:maxHookOutput] + "\n[truncated]"
+		}
+	}
+	return r
+}
+
+// runHooks runs the hooks configured for when that match the command of
+// req, in order, writing their output to w. It stops at, and returns, the
+// first failure of a before hook; after hooks all run, whatever happens,
+// and their failures are noted on w.
+func (e *Engine) runHooks(ctx context.Context, when string, req Request, args []string, w io.Writer) error {
+	if req.hook != nil || len(e.cfg.Hooks) == 0 || len(args) == 0 {
+		return nil
+	}
+	for _, h := range e.cfg.Hooks {
+		if h.When != when || !e.hookMatches(h.Match, req, args) {
+			continue
+		}
+		err := e.runHook(ctx, h, req, w)
+		switch {
+		case err == nil:
+		case when == config.HookBefore:
+			return err
+		default:
+			fmt.Fprint(w, e.commentary(fmt.Sprintf("doit: %v\n", err)))
+		}
+	}
+	return nil
+}
+
+// hookMatches reports whether m selects the command of req, whose words
+// are args. The first word names the program; the tier is the command's
+// (see effectiveTier).
+func (e *Engine) hookMatches(m config.HookMatch, req Request, args []string) bool {
+	if m.Cap != "" && args[0] != m.Cap {
+		return false
+	}
+	if m.Subcmd != "" && (len(args) < 2 || args[1] != m.Subcmd) {
+		return false
+	}
+	if m.Tier != "" && e.effectiveTier(req.shellCommand()).String() != m.Tier {
+		return false
+	}
+	if len(m.Paths) == 0 {
+		return true
+	}
+	paths := args[1:]
+	for _, r := range policy.RedirectTargets(req.shellCommand()) {
+		_, target, _ := strings.Cut(r, " ")
+		paths = append(paths, target)
+	}
+	for _, p := range paths {
+		for _, glob := range m.Paths {
+			if ok, _ := filepath.Match(glob, p); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(glob, filepath.Base(p)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runHook runs one hook for req: its command is judged by policy, run in
+// req's directory and environment, and audited as the hook's run. It
+// returns why the hook did not succeed, if it did not.
+func (e *Engine) runHook(ctx context.Context, h config.HookConfig, req Request, w io.Writer) error {
+	hc := &hookCall{hook: h, trigger: req.shellCommand(), out: &bytes.Buffer{}}
+	exitCode, err := e.runHookCall(ctx, hc, req)
+	if out := hc.out.String(); out != "" {
+		fmt.Fprint(w, e.commentary(fmt.Sprintf("doit: hook %s:\n%s", h.Name, ensureNewline(out))))
+	}
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s hook %s: %w", h.When, h.Name, err)
+	case exitCode != 0:
+		return fmt.Errorf("%s hook %s failed with exit code %d", h.When, h.Name, exitCode)
+	}
+	return nil
+}
+
+// runHookCall runs the command of hc for req, in req's directory and
+// environment, collecting its output in hc.out. It returns the command's
+// exit code, or why it did not run: it was invalid, or policy did not
+// allow it.
+func (e *Engine) runHookCall(ctx context.Context, hc *hookCall, req Request) (int, error) {
+	hreq := Request{
+		Command:     hc.hook.Run,
+		Cwd:         req.Cwd,
+		Env:         req.Env,
+		Agent:       req.Agent,
+		AgentSecret: req.AgentSecret,
+		group:       req.group,
+		hook:        hc,
+	}
+	args := hreq.args()
+	if err := e.validate(hreq, args); err != nil {
+		e.logExecution(ctx, hreq.Command, nil, nil, ExitValidation, "", err.Error(), 0, hreq)
+		return ExitValidation, err
+	}
+
+	// As in Execute, a denial stops the hook, as does an escalation to a
+	// human, whom a hook cannot wait for.
+	result, segments, tiers := e.evaluatePolicy(ctx, args, &hreq)
+	escalated := e.needsHuman(result)
+	if result.Decision == policy.Deny || escalated {
+		exitCode := denyExitCode(result)
+		if result.Decision == policy.Escalate {
+			exitCode = ExitEscalationPending
+		}
+		e.logPolicyResult(ctx, hreq, args, result, segments, tiers, exitCode)
+		return exitCode, fmt.Errorf("policy %s: %s", result.Decision, result.Reason)
+	}
+	ctx = policy.NewEvalContext(ctx, &policy.EvalInfo{
+		Level:      result.Level,
+		Decision:   result.Decision.String(),
+		RuleID:     result.RuleID,
+		Exchanges:  result.Exchanges,
+		Conditions: result.Conditions,
+	})
+
+	exitCode, _, _ := e.runCommand(ctx, args, hreq, hc.out, hc.out)
+	return exitCode, nil
+}
+
+func ensureNewline(s string) string {
+	if strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
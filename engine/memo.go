@@ -0,0 +1,125 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// memoLimit bounds the decisions a session remembers; past it, the memo
+// starts over.
+const memoLimit = 1024
+
+// decisionMemo remembers the learned (L2) and gatekeeper (L3) decisions
+// made in a session, so that a command repeated within it, as by an agent
+// retrying in a loop, is not judged afresh each time. It forgets them
+// when the session changes and when learned policy reloads.
+type decisionMemo struct {
+	mu      sync.Mutex
+	session string
+	gen     uint64 // counts forgettings, so a decision made across one is not kept
+	m       map[memoKey]memoEntry
+}
+
+// memoKey is what a decision was made on. Level 2 sees only the command
+// and where it runs; the gatekeeper sees the rest too.
+type memoKey struct {
+	level         int
+	command, cwd  string
+	justification string
+	safetyArg     string
+	stdin         string
+	agent         string
+}
+
+type memoEntry struct {
+	result  policy.Result
+	expires time.Time // when the entry that decided it expires; zero for never
+}
+
+// get returns the decision remembered for k in session, if it has not
+// expired by now, or nil, and the generation to put a new decision with.
+func (m *decisionMemo) get(session string, k memoKey, now time.Time) (*policy.Result, uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session != m.session {
+		m.session = session
+		m.forgetLocked()
+	}
+	ent, ok := m.m[k]
+	if !ok {
+		return nil, m.gen
+	}
+	if !ent.expires.IsZero() && !now.Before(ent.expires) {
+		delete(m.m, k)
+		return nil, m.gen
+	}
+	r := ent.result
+	r.Reason += " (remembered from earlier in this session)"
+	return &r, m.gen
+}
+
+// put remembers r for k in session, unless the memo has been forgotten
+// since gen, when r may have been decided by policy that is gone.
+func (m *decisionMemo) put(session string, gen uint64, k memoKey, r *policy.Result, expires time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session != m.session || gen != m.gen {
+		return
+	}
+	if m.m == nil || len(m.m) >= memoLimit {
+		m.m = map[memoKey]memoEntry{}
+	}
+	m.m[k] = memoEntry{result: *r, expires: expires}
+}
+
+// forget drops every remembered decision.
+func (m *decisionMemo) forget() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.forgetLocked()
+}
+
+func (m *decisionMemo) forgetLocked() {
+	m.m = nil
+	m.gen++
+}
+
+// evaluateL2 runs the learned level, or recalls what it decided for the
+// same command earlier in the session. A retry, which bypasses entries,
+// is always judged afresh.
+func (e *Engine) evaluateL2(req *policy.Request) *policy.Result {
+	if req.Now.IsZero() {
+		req.Now = e.clock.Now()
+	}
+	key := memoKey{level: 2, command: req.Command, cwd: req.Cwd}
+	session := e.sessionID()
+	r, gen := e.memo.get(session, key, req.Now)
+	if r != nil && !req.Retry {
+		return r
+	}
+	e.l2Mu.RLock()
+	defer e.l2Mu.RUnlock()
+	r = e.policyL2.Evaluate(req)
+	if !req.Retry {
+		e.memo.put(session, gen, key, r, e.policyL2.Expiry(r.RuleID))
+	}
+	return r
+}
+
+// l3MemoKey is the key for the gatekeeper's decision on req.
+func l3MemoKey(req *policy.Request, agent string) memoKey {
+	return memoKey{
+		level:         3,
+		command:       req.Command,
+		cwd:           req.Cwd,
+		justification: req.Justification,
+		safetyArg:     req.SafetyArg,
+		stdin:         req.Stdin,
+		agent:         agent,
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// netIsolationSupported reports whether isolateNetwork can cut a command
+// off from the network on this platform.
+const netIsolationSupported = true
+
+// isolateNetwork makes cmd start in new user and network namespaces. The
+// network namespace holds only a downed loopback interface; the user
+// namespace maps the caller to itself, so files keep their ownership.
+func isolateNetwork(cmd *exec.Cmd) {
+	uid, gid := os.Getuid(), os.Getgid()
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+}
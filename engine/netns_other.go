@@ -0,0 +1,15 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package engine
+
+import "os/exec"
+
+// netIsolationSupported reports whether isolateNetwork can cut a command
+// off from the network on this platform.
+const netIsolationSupported = false
+
+// isolateNetwork is a no-op: network namespaces are Linux-only.
+func isolateNetwork(cmd *exec.Cmd) {}
@@ -0,0 +1,229 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// planApprovalRuleID tags policy results allowed because a human approved
+// the plan the command is a step of.
+const planApprovalRuleID = "plan-approval"
+
+// Plan is an ordered list of commands submitted for a single approval
+// (see doit --plan).
+type Plan struct {
+	Description string     `yaml:"description,omitempty"`
+	Steps       []PlanStep `yaml:"steps"`
+}
+
+// PlanStep is one command of a plan.
+type PlanStep struct {
+	Command       string `yaml:"command"`
+	Cwd           string `yaml:"cwd,omitempty"`
+	Justification string `yaml:"justification,omitempty"`
+	SafetyArg     string `yaml:"safety_arg,omitempty"`
+}
+
+// LoadPlan reads a YAML plan of the form
+//
+//	description: release v1.2
+//	steps:
+//	  - command: go test ./...
+//	  - command: git tag v1.2
+//	  - command: git push origin v1.2
+//	    justification: publish the tag
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read plan: %w", err)
+	}
+	var p Plan
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parse plan %s: %w", path, err)
+	}
+	if len(p.Steps) == 0 {
+		return nil, fmt.Errorf("plan %s: no steps", path)
+	}
+	for i, s := range p.Steps {
+		if strings.TrimSpace(s.Command) == "" {
+			return nil, fmt.Errorf("plan %s: step %d: missing command", path, i+1)
+		}
+	}
+	return &p, nil
+}
+
+// summary describes the plan on one line, for the pending escalation a
+// human resolves.
+func (p *Plan) summary() string {
+	cmds := make([]string, len(p.Steps))
+	for i, s := range p.Steps {
+		cmds[i] = s.Command
+	}
+	s := fmt.Sprintf("plan of %d steps: %s", len(p.Steps), strings.Join(cmds, "; "))
+	if p.Description != "" {
+		s = p.Description + " — " + s
+	}
+	return s
+}
+
+// request builds the engine request for step i of p, audited in group.
+func (p *Plan) request(i int, group string) Request {
+	s := p.Steps[i]
+	return Request{
+		Command:       s.Command,
+		Cwd:           s.Cwd,
+		Justification: s.Justification,
+		SafetyArg:     s.SafetyArg,
+		group:         group,
+	}
+}
+
+// RunPlan evaluates every step of p before running any. If a step is
+// invalid or denied, nothing runs. If some steps escalate, one escalation covers the
+// whole plan, and RunPlan waits until its approval token expires for a
+// human to resolve it (doit --top, doit --approve). The steps then run in
+// order, each one audited, stopping at the first that fails. Only the
+// approved commands run with the plan's approval: each step is checked
+// against the command the human approved, and a command that differs goes
+// through the policy chain afresh. RunPlan returns the exit code of the
+// last step run, or the policy exit code if the plan was not run.
+func (e *Engine) RunPlan(ctx context.Context, p *Plan, stdout, stderr io.Writer) int {
+	notify := func(format string, args ...any) {
+		io.WriteString(stderr, e.commentary(fmt.Sprintf("doit: "+format+"\n", args...)))
+	}
+
+	// The plan's entries form one audit group, closed however it ends.
+	group := e.groupID(audit.GroupPlan)
+	defer e.closeGroup(audit.GroupPlan, group, p.Description, e.sessionID())
+
+	// Judge every step up front.
+	type judged struct {
+		req             Request
+		args            []string
+		result          *policy.Result
+		segments, tiers []string
+	}
+	var escalated []judged
+	var reasons []string
+	for i := range p.Steps {
+		req := p.request(i, group)
+		args := req.args()
+		if err := e.validate(req, args); err != nil {
+			e.rejectInvalid(ctx, req, args, err)
+			notify("plan refused: step %d (%s) is invalid: %v", i+1, req.Command, err)
+			return ExitValidation
+		}
+		result, segments, tiers := e.evaluatePolicy(ctx, args, &req)
+		if result == nil {
+			continue
+		}
+		switch {
+		case result.Decision == policy.Deny:
+			exitCode := denyExitCode(result)
+			e.logPolicyResult(ctx, req, args, result, segments, tiers, exitCode)
+			notify("plan refused: step %d (%s) is denied: %s", i+1, req.Command, result.Reason)
+			return exitCode
+		case e.needsHuman(result):
+			escalated = append(escalated, judged{req, args, result, segments, tiers})
+			reasons = append(reasons, fmt.Sprintf("step %d (%s): %s", i+1, req.Command, result.Reason))
+		}
+	}
+
+	approved := false
+	if len(escalated) > 0 {
+		decision, err := e.approvePlan(ctx, p, strings.Join(reasons, "; "), stderr)
+		if err != nil {
+			notify("%v", err)
+			return ExitInternal
+		}
+		if decision == nil || decision.Decision != policy.Allow {
+			code := ExitEscalationPending
+			if decision != nil {
+				code = denyExitCode(decision)
+			}
+			for _, j := range escalated {
+				result := j.result
+				if decision != nil {
+					result = &policy.Result{Decision: decision.Decision, Level: decision.Level, Reason: decision.Reason, RuleID: decision.RuleID, Exchanges: j.result.Exchanges}
+				}
+				e.logPolicyResult(ctx, j.req, j.args, result, j.segments, j.tiers, code)
+			}
+			if decision == nil {
+				notify("plan not approved in time; nothing ran")
+			} else {
+				notify("plan denied by a human; nothing ran")
+			}
+			return code
+		}
+		approved = true
+	}
+
+	for i := range p.Steps {
+		req := p.request(i, group)
+		if approved {
+			req.planned = req.Command
+		}
+		notify("plan step %d of %d: %s", i+1, len(p.Steps), req.Command)
+		res := e.ExecuteStreaming(ctx, req, stdout, stderr)
+		if res.ExitCode != 0 {
+			if i+1 < len(p.Steps) {
+				notify("plan stopped at step %d of %d (exit %d)", i+1, len(p.Steps), res.ExitCode)
+			}
+			return res.ExitCode
+		}
+	}
+	return 0
+}
+
+// approvePlan publishes one escalation for the whole plan and waits for a
+// human to resolve it. It returns the human's decision, or nil if none
+// came before the approval token expired.
+func (e *Engine) approvePlan(ctx context.Context, p *Plan, reason string, w io.Writer) (*policy.Result, error) {
+	summary := p.summary()
+	cwd := p.Steps[0].Cwd
+	token, err := e.tokenStore.IssueScoped(summary, []string{summary}, e.tokenScope(cwd))
+	if err != nil {
+		return nil, fmt.Errorf("token issue: %w", err)
+	}
+	ev := e.beginRequest(Request{Cwd: cwd}, []string{summary})
+	escalation := &policy.Result{Decision: policy.Escalate, Level: 3, Reason: reason}
+	ev.decision(escalation)
+	ev.escalation(escalation, token)
+	e.pendingMu.Lock()
+	if pe, ok := e.pending[ev.base.Request]; ok {
+		pe.plan = true
+	}
+	e.pendingMu.Unlock()
+
+	var wait time.Duration
+	if entry, ok := e.tokenStore.Peek(token); ok {
+		wait = entry.ExpiresAt.Sub(e.clock.Now())
+	}
+	decision := e.park(ctx, ev, token, w, wait)
+
+	res := &Result{ExitCode: ExitEscalationPending, PolicyLevel: 3, PolicyDecision: "escalate", PolicyReason: reason}
+	switch {
+	case decision == nil:
+		e.tokenStore.Revoke(token) // nobody is left to use it
+	case decision.Decision == policy.Allow:
+		res = &Result{PolicyLevel: 3, PolicyDecision: "allow", PolicyRuleID: planApprovalRuleID}
+	default:
+		res = &Result{ExitCode: denyExitCode(decision), PolicyLevel: 3, PolicyDecision: "deny", PolicyRuleID: decision.RuleID}
+	}
+	ev.exit(res)
+	return decision, nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// shellBuiltins are the sh builtins and special commands, which need no
+// program on PATH.
+var shellBuiltins = map[string]bool{
+	":": true, ".": true, "[": true, "alias": true, "bg": true, "break": true, "cd": true,
+	"command": true, "continue": true, "echo": true, "eval": true, "exec": true, "exit": true,
+	"export": true, "false": true, "fg": true, "getopts": true, "hash": true, "jobs": true,
+	"kill": true, "local": true, "printf": true, "pwd": true, "read": true, "readonly": true,
+	"return": true, "set": true, "shift": true, "source": true, "test": true, "times": true,
+	"trap": true, "true": true, "type": true, "ulimit": true, "umask": true, "unalias": true,
+	"unset": true, "wait": true,
+}
+
+// shellKeywords open compound commands, whose structure pipelinePrograms
+// does not follow.
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true, "for": true, "while": true,
+	"until": true, "do": true, "done": true, "case": true, "esac": true, "select": true,
+	"function": true, "{": true, "}": true, "[[": true, "]]": true,
+}
+
+// redefiners change what a later command name means: they define
+// aliases or functions, or run code doit does not see. probes ask whether
+// a program is installed, so the command line copes with its absence.
+var (
+	redefiners = map[string]bool{"alias": true, "eval": true, "source": true, ".": true, "hash": true}
+	probes     = map[string]bool{"command": true, "type": true, "which": true}
+)
+
+// pipelinePrograms returns the program each simple command in a shell
+// command line runs — one entry per pipeline or list segment, "" where
+// the program is only known when the command runs (e.g. "$CC"). It
+// reports false if the line uses anything it does not follow, such as
+// subshells, command substitution, here-documents, compound commands, or
+// changes to PATH, aliases, or functions; the shell is then left to find
+// the programs itself. So does a fallback (a || b) or a probe for a
+// program (command -v, which), which may be there to cope with a
+// missing program.
+func pipelinePrograms(command string) ([]string, bool) {
+	if strings.Contains(command, "||") || strings.Contains(command, "PATH=") {
+		return nil, false
+	}
+	var progs []string
+	var words []string
+	var word strings.Builder
+	inWord, literal, target := false, true, false
+	endWord := func() {
+		if !inWord {
+			return
+		}
+		switch {
+		case target:
+			target = false // a redirection target, not a word of the command
+		case len(words) == 0 && !literal:
+			words = append(words, "")
+		default:
+			words = append(words, word.String())
+		}
+		word.Reset()
+		inWord, literal = false, true
+	}
+	endSegment := func() bool {
+		endWord()
+		for len(words) > 0 && isAssignment(words[0]) {
+			words = words[1:]
+		}
+		if len(words) > 0 && words[0] == "!" {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			return true
+		}
+		if shellKeywords[words[0]] || redefiners[words[0]] || probes[words[0]] {
+			return false
+		}
+		progs = append(progs, words[0])
+		words = nil
+		return true
+	}
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t':
+			endWord()
+			continue
+		case c == '\\':
+			if i+1 < len(command) {
+				i++
+				if command[i] != '\n' {
+					word.WriteByte(command[i])
+				}
+			}
+		case c == '\'':
+			j := strings.IndexByte(command[i+1:], '\'')
+			if j < 0 {
+				return nil, false
+			}
+			word.WriteString(command[i+1 : i+1+j])
+			i += j + 1
+		case c == '"':
+			for i++; ; i++ {
+				if i == len(command) || command[i] == '`' || strings.HasPrefix(command[i:], "$(") {
+					return nil, false
+				}
+				if command[i] == '"' {
+					break
+				}
+				if command[i] == '$' {
+					literal = false
+				}
+				if command[i] == '\\' && i+1 < len(command) {
+					i++
+				}
+				word.WriteByte(command[i])
+			}
+		case c == '#' && !inWord:
+			for i < len(command) && command[i] != '\n' {
+				i++
+			}
+			if !endSegment() {
+				return nil, false
+			}
+			continue
+		case c == '|' || c == '&' || c == ';' || c == '\n':
+			if !endSegment() {
+				return nil, false
+			}
+			if i+1 < len(command) && (command[i+1] == c && c != '\n' || c == '|' && command[i+1] == '&') {
+				i++
+			}
+			continue
+		case c == '<' || c == '>':
+			if strings.HasPrefix(command[i:], "<<") {
+				return nil, false
+			}
+			// A file descriptor number belongs to the redirection.
+			if inWord && literal && strings.Trim(word.String(), "0123456789") == "" {
+				word.Reset()
+				inWord = false
+			}
+			endWord()
+			for i+1 < len(command) && strings.IndexByte(">&|", command[i+1]) >= 0 {
+				i++
+			}
+			target = true
+			continue
+		case c == '`' || c == '(' || c == ')' || strings.HasPrefix(command[i:], "$("):
+			return nil, false
+		case c == '$' || c == '*' || c == '?' || c == '[' || c == '~' && !inWord:
+			literal = false
+			word.WriteByte(c)
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if !endSegment() {
+		return nil, false
+	}
+	return progs, true
+}
+
+// isAssignment reports whether w is a NAME=value assignment.
+func isAssignment(w string) bool {
+	name, _, ok := strings.Cut(w, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// preflight checks, before anything runs, that every program a request
+// runs is installed, so that a command whose third segment is missing
+// fails before the first has had any effect. Programs named by a path or
+// only known when the command runs are left to the shell, as are command
+// lines pipelinePrograms does not follow, though a capability named
+// first is still checked. It returns nil or an error naming the first
+// missing program.
+func (e *Engine) preflight(req Request, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	progs, ok := args[:1], true
+	if len(req.Args) == 0 {
+		progs, ok = pipelinePrograms(req.Command)
+	}
+	if !ok {
+		return e.reg.Available(args[0])
+	}
+	path := os.Getenv("PATH") // a request may not set its own (see envHooks)
+	for i, prog := range progs {
+		serr := &segmentError{segment: i + 1, segments: len(progs), err: e.reg.Available(prog)}
+		if serr.err == nil && !e.isRegistered(prog) && prog != "" && !strings.Contains(prog, "/") &&
+			!shellBuiltins[prog] && !onPath(prog, path) {
+			serr.err = fmt.Errorf("program %q not found on PATH", prog)
+			serr.hint = fmt.Sprintf("check the spelling of %q, or install it", prog)
+		}
+		if serr.err != nil {
+			return serr
+		}
+	}
+	return nil
+}
+
+// isRegistered reports whether name is a registered capability.
+func (e *Engine) isRegistered(name string) bool {
+	_, err := e.reg.Lookup(name)
+	return err == nil
+}
+
+// onPath reports whether an executable named prog is in one of the
+// directories in path.
+func onPath(prog, path string) bool {
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		fi, err := os.Stat(filepath.Join(dir, prog))
+		if err == nil && !fi.IsDir() && fi.Mode()&0o111 != 0 {
+			return true
+		}
+	}
+	return false
+}
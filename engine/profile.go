@@ -0,0 +1,112 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/agent"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// profileFor returns the name of the execution profile req's command
+// runs under, picked by the decision in ctx (see config.ExecConfig), or
+// "" if none.
+func (e *Engine) profileFor(ctx context.Context, req Request) string {
+	if len(e.cfg.Exec.Select) == 0 {
+		return ""
+	}
+	level, rule := 0, ""
+	if info := policy.EvalFromContext(ctx); info != nil {
+		level, rule = info.Level, info.RuleID
+	}
+	name, _ := e.cfg.Exec.ProfileFor(level, e.effectiveTier(req.shellCommand()), rule)
+	return name
+}
+
+// execProfile returns the profile of the given name, or nil for "".
+func (e *Engine) execProfile(name string) *config.ExecProfile {
+	p, ok := e.cfg.Exec.Profiles[name]
+	if !ok {
+		return nil
+	}
+	return &p
+}
+
+// checkProfile reports why a command cannot run under the named profile,
+// if it cannot. As with conditions, a command that cannot be held to its
+// profile does not run.
+func (e *Engine) checkProfile(name string) error {
+	if p := e.execProfile(name); p != nil && p.NoNetwork && !netIsolationSupported {
+		return fmt.Errorf("it must run under profile %s, without network access, which %s cannot enforce", name, runtime.GOOS)
+	}
+	return nil
+}
+
+// profileArgv returns the command that runs script, an sh -c script,
+// under p: with p's resource limits set before it, and at p's CPU and
+// I/O priorities. A limit the shell cannot set stops the script with
+// ExitUnavailable. The limits go on the same line as script, so the
+// shell's line numbers still match.
+func profileArgv(p *config.ExecProfile, script string) []string {
+	if p == nil {
+		return []string{"sh", "-c", script}
+	}
+	var limits []string
+	limit := func(flag string, n int64) {
+		if n > 0 {
+			limits = append(limits, fmt.Sprintf("ulimit %s %d || exit %d; ", flag, n, ExitUnavailable))
+		}
+	}
+	if d, err := time.ParseDuration(p.Limits.CPU); err == nil {
+		limit("-t", int64((d+time.Second-1)/time.Second))
+	}
+	limit("-v", int64(p.Limits.MemoryMB)<<10)   // KiB
+	limit("-f", int64(p.Limits.FileSizeMB)<<11) // 512-byte blocks, as POSIX sh counts them
+	limit("-n", int64(p.Limits.OpenFiles))
+
+	var argv []string
+	switch p.IONice {
+	case "idle":
+		argv = append(argv, "ionice", "-c", "3")
+	case "best-effort":
+		argv = append(argv, "ionice", "-c", "2", "-n", "7")
+	}
+	if p.Nice > 0 {
+		argv = append(argv, "nice", "-n", strconv.Itoa(p.Nice))
+	}
+	return append(argv, "sh", "-c", strings.Join(limits, "")+script)
+}
+
+// clearedEnv lists what is kept of doit's environment under a profile
+// that clears it.
+var clearedEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "TERM", "TMPDIR"}
+
+// commandEnv returns the environment req's command runs with under p.
+func commandEnv(req Request, p *config.ExecProfile) map[string]string {
+	env := requestEnv(req.Env)
+	if p == nil {
+		return env
+	}
+	if p.ClearEnv {
+		kept := make(map[string]string, len(clearedEnv)+len(req.Env)+len(p.Env))
+		for _, k := range clearedEnv {
+			if v, ok := env[k]; ok {
+				kept[k] = v
+			}
+		}
+		maps.Copy(kept, req.Env)
+		delete(kept, agent.SecretEnv)
+		env = kept
+	}
+	maps.Copy(env, p.Env)
+	return env
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+const (
+	// gitStatusTimeout bounds the git status call made for Level 3.
+	gitStatusTimeout = 2 * time.Second
+
+	// maxGitStatusLines caps the changed files listed to Level 3.
+	maxGitStatusLines = 20
+
+	// historyScan is how many trailing audit entries are searched for
+	// this session's history; other agents' commands interleave with it.
+	historyScan = 500
+)
+
+// promptContext gathers what Level 3 should know about the situation a
+// command arises in: the working tree's git status, the session's recent
+// commands, learned policies that match but await approval, and how a
+// human judged similar past decisions. Each part is best effort; a part
+// that cannot be had is left out.
+func (e *Engine) promptContext(ctx context.Context, req *policy.Request) *policy.PromptContext {
+	pc := &policy.PromptContext{
+		Git:     gitStatus(ctx, req.Cwd),
+		History: e.recentHistory(e.cfg.Policy.Level3HistoryCount()),
+	}
+	if e.policyL2 != nil {
+		e.l2Mu.RLock()
+		candidates := e.policyL2.Candidates(req)
+		e.l2Mu.RUnlock()
+		for _, c := range candidates {
+			pc.Candidates = append(pc.Candidates, fmt.Sprintf("%s (%s, %s confidence): %s", c.ID, c.Decision, c.Confidence, c.Reasoning))
+		}
+	}
+	if n := e.cfg.LLM.FeedbackCount(); n > 0 {
+		all, err := policy.LoadFeedback(e.feedback)
+		if err != nil {
+			log.Printf("doit: L3 context: %v", err)
+		}
+		for _, fb := range policy.SelectFeedback(all, req.Command, n) {
+			pc.Feedback = append(pc.Feedback, fb.String())
+		}
+	}
+	return pc
+}
+
+// gitStatus summarises the branch and changes of the git work tree at
+// dir, or returns "" if dir is not in one.
+func gitStatus(ctx context.Context, dir string) string {
+	ctx, cancel := context.WithTimeout(ctx, gitStatusTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "status", "--branch", "--porcelain=v1", "--untracked-files=normal")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if extra := len(lines) - 1 - maxGitStatusLines; extra > 0 {
+		lines = append(lines[:1+maxGitStatusLines], fmt.Sprintf("... and %d more", extra))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// recentHistory returns up to n of this session's most recent audited
+// commands, oldest first, with how each was decided and how it exited.
+// The session is the active work session or, without one, this process.
+func (e *Engine) recentHistory(n int) []string {
+	if n <= 0 || e.logger == nil {
+		return nil
+	}
+	if err := e.logger.Flush(); err != nil {
+		return nil
+	}
+	entries, err := audit.Tail(e.logger.Path(), historyScan)
+	if err != nil && len(entries) == 0 {
+		return nil
+	}
+	session := e.sessionID()
+	var history []string
+	for i := len(entries) - 1; i >= 0 && len(history) < n; i-- {
+		ent := entries[i]
+		if ent.Session != session || ent.Time.Before(e.started) || ent.Summary != nil {
+			continue
+		}
+		outcome := fmt.Sprintf("exit %d", ent.ExitCode)
+		if ent.PolicyResult != "" && ent.PolicyResult != "allow" {
+			outcome = ent.PolicyResult
+		}
+		history = append(history, fmt.Sprintf("[%s] %s", outcome, ent.Pipeline))
+	}
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/agent"
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// quotaRuleID is the RuleID of the escalation of a command whose agent
+// has exceeded a quota.
+const quotaRuleID = "agent-quota"
+
+// ledgerKey returns the ledger key under which req's agent is counted in
+// the current work session: requests that declare no agent count as the
+// default agent's. Hooks and assertion checks are the user's and have
+// none.
+func (e *Engine) ledgerKey(req *Request) (name, key string) {
+	if req.hook != nil {
+		return "", ""
+	}
+	name = req.agentName()
+	if name == "" {
+		name = agent.Default
+	}
+	return name, agent.Key(name, e.sessionID())
+}
+
+// quotaFor returns the ledger key under which req is counted against its
+// agent's quotas, and the quotas; ok is false if it has none.
+func (e *Engine) quotaFor(req *Request) (key string, q agent.Quota, ok bool) {
+	name, key := e.ledgerKey(req)
+	if key == "" {
+		return "", q, false
+	}
+	qc := e.cfg.QuotasFor(name)
+	q = agent.Quota{
+		CommandsPerHour: qc.CommandsPerHour,
+		WriteOps:        qc.WriteOps,
+		BytesWritten:    int64(qc.WrittenMB) << 20,
+	}
+	if q == (agent.Quota{}) {
+		return "", q, false
+	}
+	return key, q, true
+}
+
+// restrictByQuota returns result, or an escalation to a human if req's
+// agent has exceeded a quota: only a human's approval lets its commands
+// run until the quota is reset. Without a human to ask, they are denied.
+// A denial stands.
+func (e *Engine) restrictByQuota(req *Request, result *policy.Result) *policy.Result {
+	key, _, ok := e.quotaFor(req)
+	if !ok || result == nil || result.Decision == policy.Deny {
+		return result
+	}
+	exceeded, err := e.agents.Exceeded(key)
+	if err != nil {
+		exceeded = fmt.Sprintf("quota ledger unreadable: %v", err)
+	}
+	if exceeded == "" {
+		return result
+	}
+	r := &policy.Result{
+		Decision: policy.Escalate,
+		Level:    1,
+		Reason:   fmt.Sprintf("%s has exceeded its quota (%s); each command needs a human's approval until it is reset (doit --quota reset %s)", key, exceeded, key),
+		RuleID:   quotaRuleID,
+	}
+	if e.tokenStore == nil {
+		r.Decision = policy.Deny
+		r.Reason = fmt.Sprintf("%s has exceeded its quota (%s) and there is no one to approve its commands; reset it with doit --quota reset %s", key, exceeded, key)
+	}
+	return r
+}
+
+// needsHuman reports whether r sends a command to a human, who approves
+// it by token: an escalation past the gatekeeper, or one only a human
+// may decide.
+func (e *Engine) needsHuman(r *policy.Result) bool {
+	return r.Decision == policy.Escalate && (r.Level == 3 || humanOnly(r)) && e.tokenStore != nil
+}
+
+// humanOnly reports whether r is an escalation the gatekeeper does not
+// get to decide: by an agent over its quota, or by one that has read a
+// secret and would reach the network.
+func humanOnly(r *policy.Result) bool {
+	return r.RuleID == quotaRuleID || r.RuleID == taintRuleID
+}
+
+// quotaMeter measures a command for its agent's quotas.
+type quotaMeter struct {
+	key   string
+	quota agent.Quota
+	write bool             // the command is write-tier or above
+	sizes map[string]int64 // files it redirects output to, by their size before it ran
+}
+
+// meterQuota starts measuring req's command, or returns nil if its agent
+// has no quotas.
+func (e *Engine) meterQuota(req Request) *quotaMeter {
+	key, q, ok := e.quotaFor(&req)
+	if !ok {
+		return nil
+	}
+	cmdStr := req.shellCommand()
+	m := &quotaMeter{key: key, quota: q, write: e.effectiveTier(cmdStr) >= cap.TierWrite, sizes: map[string]int64{}}
+	for _, r := range policy.RedirectTargets(cmdStr) {
+		op, target, _ := strings.Cut(r, " ")
+		if !strings.Contains(op, ">") || strings.ContainsAny(target, "$`~*?") {
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(req.Cwd, target)
+		}
+		m.sizes[target] = -1 // truncated: all it holds afterwards was written
+		if strings.HasSuffix(op, ">>") {
+			m.sizes[target] = fileSize(target)
+		}
+	}
+	return m
+}
+
+// recordQuota counts the command m measured, which has run, against its
+// agent's quotas, noting on w if that exceeds one.
+func (e *Engine) recordQuota(ctx context.Context, m *quotaMeter, w io.Writer) {
+	if m == nil {
+		return
+	}
+	var written int64
+	for path, before := range m.sizes {
+		written += max(fileSize(path)-max(before, 0), 0)
+	}
+	if changes := cap.ChangesFromContext(ctx); changes != nil {
+		for _, fc := range changes.List() {
+			written += fileSize(fc.Path)
+		}
+	}
+	was, _ := e.agents.Exceeded(m.key)
+	exceeded, err := e.agents.Record(m.key, m.write, written, m.quota)
+	if err != nil {
+		log.Printf("doit: quota: %v", err)
+		return
+	}
+	if exceeded != "" && was == "" {
+		log.Printf("doit: %s has exceeded its quota (%s)", m.key, exceeded)
+		fmt.Fprint(w, e.commentary(fmt.Sprintf("doit: %s has exceeded its quota (%s); further commands need a human's approval\n", m.key, exceeded)))
+	}
+}
+
+// fileSize returns the size of the regular file at path, or 0.
+func fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0
+	}
+	return fi.Size()
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+)
+
+// panicResult turns a panic raised while handling a request into an
+// internal-error result, so one request's failure does not take down the
+// process serving others. The stack goes to the log and the panic to the
+// audit log.
+func (e *Engine) panicResult(ctx context.Context, req Request, args []string, p any) *Result {
+	stack := debug.Stack()
+	log.Printf("doit: panic handling %q: %v\n%s", strings.Join(args, " "), p, stack)
+	msg := fmt.Sprintf("internal error: panic: %v", p)
+	e.logExecution(ctx, strings.Join(args, " "), nil, nil, ExitInternal, "", msg, 0, req)
+	return &Result{
+		ExitCode: ExitInternal,
+		Stderr:   e.commentary("doit: " + msg),
+		Error:    &ErrorInfo{Kind: ErrorInternal, Message: msg},
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// RetryUserApproval is the RetryRef of a retry a human approved
+// interactively (MCP elicitation), which bypasses every bypassable rule,
+// as the human has vouched for the command itself. It is reserved: only
+// ApproveByUser sets it, and a request naming it otherwise is refused, so
+// that a client cannot vouch for its own commands.
+const RetryUserApproval = "user-approval"
+
+// ApproveByUser marks r as a retry a human approved interactively.
+func (r *Request) ApproveByUser() {
+	r.Retry, r.RetryRef = true, RetryUserApproval
+	r.userApproved = true
+}
+
+// retryRefRuleID is the RuleID reported when a retry's reference to the
+// prior denial is missing or does not check out.
+const retryRefRuleID = "retry-reference"
+
+// resolveRetry links a retry to the denial it overrides, recording the
+// bypassed rule (and audit seq) in req. RetryRef is an audit seq, whose
+// entry must be a denial or escalation of the same command, or a rule ID.
+// Without a reference the retry is blanket, which only policy.blanket_retry
+// permits. Returns a denial if the reference is unusable.
+func (e *Engine) resolveRetry(req *Request, args []string) *policy.Result {
+	ref := strings.TrimSpace(req.RetryRef)
+	switch {
+	case ref == RetryUserApproval:
+		if req.userApproved {
+			return nil
+		}
+		return retryRefDenial(fmt.Sprintf("retry reference %q is reserved for approvals a human gives interactively", RetryUserApproval))
+	case ref == "":
+		if e.cfg.Policy.BlanketRetry {
+			return nil
+		}
+		return retryRefDenial("retry requires a reference to the prior denial (its audit seq or rule id); blanket retry is disabled (policy.blanket_retry)")
+	}
+
+	seq, err := strconv.ParseUint(ref, 10, 64)
+	if err != nil {
+		req.retryRule = ref
+		return nil
+	}
+	if e.logger == nil {
+		return retryRefDenial(fmt.Sprintf("retry references audit entry #%d but the audit log is disabled", seq))
+	}
+	if err := e.logger.Flush(); err != nil {
+		return retryRefDenial(fmt.Sprintf("retry references audit entry #%d: flush audit log: %v", seq, err))
+	}
+	entry, err := audit.Lookup(e.logger.Path(), seq)
+	if err != nil {
+		return retryRefDenial(fmt.Sprintf("retry references audit entry #%d: %v", seq, err))
+	}
+	switch {
+	case entry.PolicyResult != "deny" && entry.PolicyResult != "escalate":
+		return retryRefDenial(fmt.Sprintf("audit entry #%d is not a denial", seq))
+	case entry.PolicyRuleID == "":
+		return retryRefDenial(fmt.Sprintf("audit entry #%d names no rule to retry past", seq))
+	case entry.Pipeline != strings.Join(args, " "):
+		return retryRefDenial(fmt.Sprintf("audit entry #%d denied a different command: %s", seq, entry.Pipeline))
+	}
+	req.retryRule = entry.PolicyRuleID
+	req.retrySeq = seq
+	return nil
+}
+
+func retryRefDenial(reason string) *policy.Result {
+	return &policy.Result{
+		Decision: policy.Deny,
+		Level:    1,
+		Reason:   reason,
+		RuleID:   retryRefRuleID,
+	}
+}
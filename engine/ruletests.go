@@ -0,0 +1,130 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// RuleTestCase is one case of a policy regression suite: a command and the
+// decision the local (L1/L2) policy should reach for it.
+type RuleTestCase struct {
+	Command string `yaml:"command"`
+	Expect  string `yaml:"expect"`         // "allow", "deny", or "escalate"
+	Rule    string `yaml:"rule,omitempty"` // expected rule ID, if any
+	Cwd     string `yaml:"cwd,omitempty"`
+}
+
+// RuleTestResult is the outcome of one RuleTestCase.
+type RuleTestResult struct {
+	Case     RuleTestCase
+	Decision string
+	Level    int
+	RuleID   string
+	Reason   string
+	Pass     bool
+}
+
+// LoadRuleTests reads a YAML suite of the form
+//
+//	cases:
+//	  - command: git push --force
+//	    expect: deny
+//	    rule: deny-git-push-flags
+func LoadRuleTests(path string) ([]RuleTestCase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rule tests: %w", err)
+	}
+	var suite struct {
+		Cases []RuleTestCase `yaml:"cases"`
+	}
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("parse rule tests %s: %w", path, err)
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("rule tests %s: no cases", path)
+	}
+	for i, c := range suite.Cases {
+		if c.Command == "" {
+			return nil, fmt.Errorf("rule tests %s: case %d: missing command", path, i+1)
+		}
+		if _, err := policy.ParseDecision(c.Expect); err != nil {
+			return nil, fmt.Errorf("rule tests %s: case %d (%s): expect: %w", path, i+1, c.Command, err)
+		}
+	}
+	return suite.Cases, nil
+}
+
+// RunRuleTests evaluates each case against the engine's live L1 and L2
+// policy. L3 is never consulted, so a command no local rule decides
+// yields "escalate". Nothing is executed or audited.
+func (e *Engine) RunRuleTests(cases []RuleTestCase) []RuleTestResult {
+	results := make([]RuleTestResult, 0, len(cases))
+	for _, c := range cases {
+		req := &policy.Request{Command: c.Command, Cwd: c.Cwd}
+		if e.projectCtx != nil {
+			req.ProjectType = string(e.projectCtx.Type)
+		}
+		r := e.evaluateLocal(context.Background(), req)
+		res := RuleTestResult{
+			Case:     c,
+			Decision: r.Decision.String(),
+			Level:    r.Level,
+			RuleID:   r.RuleID,
+			Reason:   r.Reason,
+		}
+		res.Pass = res.Decision == c.Expect && (c.Rule == "" || c.Rule == r.RuleID)
+		results = append(results, res)
+	}
+	return results
+}
+
+// Coverage outcomes reported by AuditCoverage.
+const (
+	CoverageL1     = "caught-l1" // denied or escalated by a deterministic rule
+	CoverageL2     = "caught-l2" // denied or escalated by a learned entry
+	CoverageL3     = "relies-l3" // no local opinion; only the LLM gatekeeper stands in the way
+	CoverageAllows = "passes"    // allowed by local policy
+)
+
+// CoverageResult is how the local policy treats one corpus command.
+type CoverageResult struct {
+	Entry   policy.CorpusEntry
+	Outcome string
+	RuleID  string
+	Reason  string
+}
+
+// AuditCoverage evaluates each corpus command against the live L1 and L2
+// policy and classifies where, if anywhere, it is caught.
+func (e *Engine) AuditCoverage(corpus []policy.CorpusEntry) []CoverageResult {
+	results := make([]CoverageResult, 0, len(corpus))
+	for _, c := range corpus {
+		req := &policy.Request{Command: c.Command}
+		if e.projectCtx != nil {
+			req.ProjectType = string(e.projectCtx.Type)
+		}
+		r := e.evaluateLocal(context.Background(), req)
+		res := CoverageResult{Entry: c, RuleID: r.RuleID, Reason: r.Reason}
+		switch {
+		case r.Decision == policy.Allow:
+			res.Outcome = CoverageAllows
+		case r.Decision == policy.Escalate && r.RuleID == "":
+			res.Outcome = CoverageL3
+		case r.Level == 1:
+			res.Outcome = CoverageL1
+		default:
+			res.Outcome = CoverageL2
+		}
+		results = append(results, res)
+	}
+	return results
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/cap/builtin"
+)
+
+// runner returns the in-process capability a request invokes and its
+// arguments, or nil if the shell runs the request. doit, not the shell,
+// runs such a capability, so combining it with pipes, redirection, or
+// expansion is an error.
+func (e *Engine) runner(req Request, args []string) (cap.Runner, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, nil
+	}
+	c, err := e.reg.Lookup(args[0])
+	if err != nil {
+		return nil, nil, nil
+	}
+	r, ok := c.(cap.Runner)
+	if !ok {
+		return nil, nil, nil
+	}
+	if len(req.Args) > 0 {
+		return r, req.Args[1:], nil
+	}
+	words, ok := shellWords(req.Command)
+	if !ok || len(words) == 0 || words[0] != args[0] {
+		return nil, nil, fmt.Errorf("%s runs inside doit and cannot be combined with shell syntax (pipes, redirection, globs, or $ expansion); run it on its own", args[0])
+	}
+	return r, words[1:], nil
+}
+
+// runInProcess runs an in-process capability with the request's working
+// directory, environment, and stdin, auditing it as runShellCommand does
+// along with any files it changed, as recorded in ctx (see
+// cap.NewChangesContext).
+func (e *Engine) runInProcess(ctx context.Context, r cap.Runner, args []string, req Request, stdout, stderr io.Writer) (int, *ErrorInfo) {
+	cmdStr := req.Command
+	if len(req.Args) > 0 {
+		cmdStr = strings.Join(req.Args, " ")
+	}
+
+	start := time.Now()
+	exitCode := 0
+	errMsg := ""
+	err := checkDir(req.Cwd)
+	if err == nil {
+		if v, ok := r.(cap.Capability); ok {
+			if verr := v.Validate(args); verr != nil {
+				err = verr
+				exitCode = ExitValidation
+			}
+		}
+	} else {
+		exitCode = ExitValidation
+	}
+	if err == nil {
+		rctx := cap.NewEnvContext(cap.NewCwdContext(ctx, req.Cwd), requestEnv(req.Env))
+		rctx = cap.NewUmaskContext(rctx, e.umask)
+		err = r.Run(rctx, args, strings.NewReader(req.Stdin), stdout, stderr)
+		var exitErr *builtin.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.Code
+			err = nil
+		} else if err != nil {
+			exitCode = 1
+		}
+	}
+	var fail *ErrorInfo
+	if err != nil {
+		errMsg = err.Error()
+		fmt.Fprintln(stderr, errMsg)
+		kind := ErrorExec
+		if exitCode == ExitValidation {
+			kind = ErrorValidation
+		}
+		fail = errorInfo(kind, err)
+	}
+
+	duration := time.Since(start)
+	req.violations = e.checkAssertions(ctx, req, req.args(), stderr)
+	e.logExecution(ctx, cmdStr, nil, nil, exitCode, "", errMsg, duration, req)
+	return exitCode, fail
+}
+
+// shellWords splits a command line into words the way sh would, honouring
+// quotes and backslashes. It reports false if the line uses anything else
+// the shell would interpret: operators, redirection, globs, expansion.
+func shellWords(s string) ([]string, bool) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, false
+			}
+			i++
+			word.WriteByte(s[i])
+		case c == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, false
+			}
+			word.WriteString(s[i+1 : i+1+j])
+			i += j + 1
+		case c == '"':
+			for i++; ; i++ {
+				if i == len(s) || s[i] == '$' || s[i] == '`' {
+					return nil, false
+				}
+				if s[i] == '"' {
+					break
+				}
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+		case strings.IndexByte("|&;<>()$`*?[\n", c) >= 0, c == '~' && !inWord, c == '#' && !inWord:
+			return nil, false
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, true
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/marcelocantos/doit/internal/proc"
+)
+
+// signalRelay delivers the signals of Request.Signals: to the command
+// while it runs, and before then by cancelling the request, so an
+// interrupt during policy evaluation or an escalation wait still ends it.
+type signalRelay struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd // running command, or nil
+	cancel context.CancelFunc
+}
+
+// relaySignals starts relaying sigs for one request. The returned context
+// is cancelled by a signal arriving while no command runs; stop ends the
+// relay. A nil sigs relays nothing.
+func relaySignals(ctx context.Context, sigs <-chan os.Signal) (context.Context, *signalRelay, func()) {
+	if sigs == nil {
+		return ctx, nil, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	r := &signalRelay{cancel: cancel}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case s := <-sigs:
+				r.deliver(s)
+			}
+		}
+	}()
+	return ctx, r, func() {
+		close(done)
+		cancel()
+	}
+}
+
+func (r *signalRelay) deliver(s os.Signal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cmd == nil {
+		r.cancel()
+		return
+	}
+	proc.Signal(r.cmd, s)
+}
+
+// running records cmd as the command to forward signals to, until
+// running(nil).
+func (r *signalRelay) running(cmd *exec.Cmd) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.cmd = cmd
+	r.mu.Unlock()
+}
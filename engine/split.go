@@ -0,0 +1,56 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import "strings"
+
+// SplitCommand splits a command line into words at unquoted blanks,
+// removing quotes and backslash escapes as sh would, so that
+// grep 'hello world' src is three words, not four. Operators and
+// expansions are left in the words that contain them; an unterminated
+// quote runs to the end of the line. The engine matches capabilities and
+// approval tokens against these words.
+func SplitCommand(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		case c == '\\' && i+1 < len(command):
+			i++
+			if command[i] != '\n' {
+				word.WriteByte(command[i])
+			}
+		case c == '\'':
+			j := strings.IndexByte(command[i+1:], '\'')
+			if j < 0 {
+				j = len(command) - i - 1
+			}
+			word.WriteString(command[i+1 : i+1+j])
+			i += j + 1
+		case c == '"':
+			for i++; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`\n", command[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(command[i])
+			}
+		default:
+			word.WriteByte(c)
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
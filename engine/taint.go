@@ -0,0 +1,71 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// taintRuleID is the RuleID of the escalation of a network-capable
+// command whose agent has read a sensitive path in this session.
+const taintRuleID = "session-tainted"
+
+// taintBy notes that req's agent has read a secret, if req's command
+// touches a sensitive path, saying so on w the first time.
+func (e *Engine) taintBy(req Request, w io.Writer) {
+	_, key := e.ledgerKey(&req)
+	if key == "" || len(e.cfg.SensitivePaths) == 0 {
+		return
+	}
+	path := policy.SensitivePaths(e.cfg.SensitivePaths).Touched(&policy.Request{Command: req.shellCommand(), Cwd: req.Cwd})
+	if path == "" {
+		return
+	}
+	was, _ := e.agents.Tainted(key)
+	if err := e.agents.Taint(key, path); err != nil {
+		log.Printf("doit: taint: %v", err)
+		return
+	}
+	if was == "" {
+		log.Printf("doit: %s read sensitive %s; its network commands now need a human's approval", key, path)
+		fmt.Fprint(w, e.commentary(fmt.Sprintf("doit: note: %s is sensitive; commands that reach the network now need a human's approval (doit --quota reset %s)\n", path, key)))
+	}
+}
+
+// restrictByTaint returns result, or an escalation to a human if req's
+// command reaches the network after its agent has read a sensitive path
+// in this session: whatever it read may leave with it. Without a human
+// to ask, the command is denied. A denial stands.
+func (e *Engine) restrictByTaint(req *Request, cmdStr string, result *policy.Result) *policy.Result {
+	_, key := e.ledgerKey(req)
+	if key == "" || len(e.cfg.SensitivePaths) == 0 || result == nil || result.Decision == policy.Deny {
+		return result
+	}
+	use := policy.NetworkUse(cmdStr, e.capUsesNetwork)
+	if use == "" {
+		return result
+	}
+	tainted, err := e.agents.Tainted(key)
+	if err != nil {
+		tainted = fmt.Sprintf("(ledger unreadable: %v)", err)
+	}
+	if tainted == "" {
+		return result
+	}
+	r := &policy.Result{
+		Decision: policy.Escalate,
+		Level:    1,
+		Reason:   fmt.Sprintf("%s has read %s this session and %q reaches the network; a human must approve it (doit --quota reset %s clears the taint)", key, tainted, use, key),
+		RuleID:   taintRuleID,
+	}
+	if e.tokenStore == nil {
+		r.Decision = policy.Deny
+		r.Reason = fmt.Sprintf("%s has read %s this session and %q reaches the network, and there is no one to approve it; clear the taint with doit --quota reset %s", key, tainted, use, key)
+	}
+	return r
+}
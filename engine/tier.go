@@ -0,0 +1,72 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// descriptorDup matches redirections that duplicate or close a file
+// descriptor (2>&1, >&-), which write no file.
+var descriptorDup = regexp.MustCompile(`[0-9]*[<>]&([0-9]+|-)`)
+
+// effectiveTier returns the highest tier of anything command runs: each
+// program at its capability's tier for its arguments, a shell builtin at
+// read, and any other program at write, since doit cannot tell what it
+// does. A builtin that runs code doit does not see (eval, source) is
+// dangerous, as is kill; a redirection to a file is a write.
+func (e *Engine) effectiveTier(command string) cap.Tier {
+	tier := cap.TierRead
+	for _, r := range policy.RedirectTargets(command) {
+		if strings.Contains(r, ">") && !strings.HasSuffix(r, " /dev/null") {
+			tier = cap.TierWrite
+		}
+	}
+	command = descriptorDup.ReplaceAllString(command, " ")
+	for _, words := range policy.SimpleCommands(command) {
+		for len(words) > 0 && (shellKeywords[words[0]] || words[0] == "!") {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		tier = max(tier, e.programTier(filepath.Base(words[0]), withoutRedirects(words[1:])))
+	}
+	return tier
+}
+
+// programTier returns the tier of running prog with args.
+func (e *Engine) programTier(prog string, args []string) cap.Tier {
+	switch {
+	case redefiners[prog] || prog == "kill":
+		return cap.TierDangerous
+	case shellBuiltins[prog]:
+		return cap.TierRead
+	}
+	if c, err := e.reg.Lookup(prog); err == nil {
+		return cap.TierOf(c, args)
+	}
+	return cap.TierWrite
+}
+
+// withoutRedirects returns args less any redirections and their targets.
+func withoutRedirects(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		a := strings.TrimLeft(args[i], "0123456789&")
+		if !strings.HasPrefix(a, "<") && !strings.HasPrefix(a, ">") {
+			out = append(out, args[i])
+			continue
+		}
+		if strings.Trim(a, "<>&|") == "" {
+			i++ // the target is the next word
+		}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+
+	"github.com/marcelocantos/doit/internal/policy"
+	"github.com/marcelocantos/doit/internal/tracing"
+)
+
+// Span names. A request's span is the parent of one span per policy
+// level consulted, one for running the command, and one per audit write.
+const (
+	spanExecute  = "doit.execute"
+	spanEvaluate = "doit.evaluate"
+	spanL1       = "doit.policy.l1"
+	spanL2       = "doit.policy.l2"
+	spanL3       = "doit.policy.l3"
+	spanExec     = "doit.exec"
+	spanAudit    = "doit.audit.write"
+)
+
+// traceRequest starts the span of a request, which endRequestSpan ends.
+// Without tracing configured (see config.TracingConfig), it does nothing.
+func (e *Engine) traceRequest(ctx context.Context, name string, req Request) (context.Context, *tracing.Span) {
+	return e.tracer.Start(ctx, name,
+		tracing.String("doit.command", req.shellCommand()),
+		tracing.String("doit.cwd", req.Cwd),
+		tracing.String("doit.session", e.sessionID()),
+		tracing.Bool("doit.retry", req.Retry),
+	)
+}
+
+// endRequestSpan records a request's outcome on its span and ends it.
+// Failures of doit, rather than policy refusals, mark the span failed.
+func endRequestSpan(span *tracing.Span, res *Result) {
+	if res != nil {
+		span.SetAttrs(tracing.Int("doit.exit_code", res.ExitCode))
+		if res.PolicyDecision != "" {
+			span.SetAttrs(
+				tracing.String("doit.policy.decision", res.PolicyDecision),
+				tracing.Int("doit.policy.level", res.PolicyLevel),
+			)
+		}
+		if res.Signal != "" {
+			span.SetAttrs(tracing.String("doit.signal", res.Signal))
+		}
+		if res.Error != nil {
+			span.SetAttrs(tracing.String("doit.error.kind", res.Error.Kind))
+			if res.Error.Kind != ErrorPolicy {
+				span.Fail(res.Error.Message)
+			}
+		}
+	}
+	span.End()
+}
+
+// endPolicySpan records a policy level's decision on its span and ends it.
+func endPolicySpan(span *tracing.Span, r *policy.Result) {
+	if r != nil {
+		span.SetAttrs(
+			tracing.String("doit.policy.decision", r.Decision.String()),
+			tracing.String("doit.policy.reason", r.Reason),
+		)
+		if r.RuleID != "" {
+			span.SetAttrs(tracing.String("doit.policy.rule_id", r.RuleID))
+		}
+	}
+	span.End()
+}
+
+// traceExec starts the span of running a command, naming the program of
+// each of its pipeline segments where they are known before it runs.
+func (e *Engine) traceExec(ctx context.Context, req Request, args []string) (context.Context, *tracing.Span) {
+	ctx, span := e.tracer.Start(ctx, spanExec)
+	if span == nil {
+		return ctx, nil
+	}
+	progs := args[:min(len(args), 1)]
+	if len(req.Args) == 0 {
+		if p, ok := pipelinePrograms(req.Command); ok {
+			progs = p
+		}
+	}
+	span.SetAttrs(tracing.Strings("doit.segments", progs))
+	return ctx, span
+}
@@ -0,0 +1,84 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Verbosity controls how much commentary doit adds to a result's stderr
+// alongside the command's own output.
+type Verbosity int
+
+const (
+	// VerbosityNormal reports denials, escalations, and doit errors.
+	VerbosityNormal Verbosity = iota
+	// VerbosityQuiet suppresses doit's own commentary: stderr carries only
+	// the command's output, and the outcome is conveyed by the exit code
+	// and the structured policy fields.
+	VerbosityQuiet
+	// VerbosityVerbose adds a trace line per command with the deciding
+	// policy level and rule, the tier of each segment, elapsed time, and
+	// how the command was routed.
+	VerbosityVerbose
+)
+
+func (v Verbosity) String() string {
+	switch v {
+	case VerbosityNormal:
+		return "normal"
+	case VerbosityQuiet:
+		return "quiet"
+	case VerbosityVerbose:
+		return "verbose"
+	default:
+		return fmt.Sprintf("verbosity(%d)", int(v))
+	}
+}
+
+// commentary returns msg unless the engine is quiet.
+func (e *Engine) commentary(msg string) string {
+	if e.verbosity == VerbosityQuiet {
+		return ""
+	}
+	return msg
+}
+
+// verboseTrace returns the verbose trace line for res, or "" unless the
+// engine is verbose. Commands always run in-process via sh -c; the route
+// is reported so traces stay comparable if that ever changes.
+func (e *Engine) verboseTrace(res *Result, tiers []string, elapsed time.Duration) string {
+	if e.verbosity != VerbosityVerbose {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("doit: trace:")
+	if res.PolicyDecision != "" {
+		fmt.Fprintf(&b, " policy=L%d/%s", res.PolicyLevel, res.PolicyDecision)
+		if res.PolicyRuleID != "" {
+			fmt.Fprintf(&b, " rule=%s", res.PolicyRuleID)
+		}
+	} else {
+		b.WriteString(" policy=none")
+	}
+	if len(tiers) > 0 {
+		fmt.Fprintf(&b, " tier=%s", strings.Join(tiers, ","))
+	}
+	fmt.Fprintf(&b, " exit=%d elapsed=%s route=in-process\n", res.ExitCode, elapsed.Round(time.Microsecond))
+	return b.String()
+}
+
+// appendLine appends line to s, inserting a newline first if s does not
+// already end with one.
+func appendLine(s, line string) string {
+	if line == "" {
+		return s
+	}
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s + line
+}
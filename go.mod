@@ -5,6 +5,7 @@ go 1.26.1
 require (
 	github.com/mark3labs/mcp-go v0.47.0
 	go.starlark.net v0.0.0-20260326113308-fadfc96def35
+	golang.org/x/sys v0.42.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -13,5 +14,4 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/spf13/cast v1.7.1 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
-	golang.org/x/sys v0.42.0 // indirect
 )
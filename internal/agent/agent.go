@@ -0,0 +1,342 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package agent identifies the agents that share a doit, so each can be
+// held to its own limits: an agent names itself (DOIT_AGENT) and proves
+// it with a secret (DOIT_AGENT_SECRET) whose SHA-256 the config records.
+// A Ledger counts each agent's commands, across doit processes, for its
+// rate limit and quotas, and notes which agents have read secrets.
+package agent
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/clock"
+)
+
+// Environment variables by which a client declares its identity.
+const (
+	NameEnv   = "DOIT_AGENT"
+	SecretEnv = "DOIT_AGENT_SECRET"
+)
+
+// Default names the agent whose limits apply to requests that declare
+// no agent. It needs no secret.
+const Default = "default"
+
+// HashSecret returns the hex SHA-256 of secret, as the config records it.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether secret hashes to hash.
+func Verify(hash, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(strings.ToLower(hash))) == 1
+}
+
+// LedgerFile is the name of the ledger shared by doit processes, kept
+// beside the audit log.
+const LedgerFile = "agents.json"
+
+// LedgerPath returns the path of the ledger kept beside the audit log at
+// auditPath.
+func LedgerPath(auditPath string) string {
+	return filepath.Join(filepath.Dir(auditPath), LedgerFile)
+}
+
+// Key returns the ledger key under which an agent's use in a session is
+// counted against its quotas: the agent's name, or name@session inside a
+// work session.
+func Key(name, session string) string {
+	if session == "" {
+		return name
+	}
+	return name + "@" + session
+}
+
+// Rate is a number of commands per period.
+type Rate struct {
+	N   int
+	Per time.Duration
+}
+
+// ParseRate parses a rate such as 60/m, 1000/h, or 5/30s: a count, a
+// slash, and s, m, h, or a duration.
+func ParseRate(s string) (Rate, error) {
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("rate %q: want count/period, e.g. 60/m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("rate %q: count must be a positive integer", s)
+	}
+	var per time.Duration
+	switch period = strings.TrimSpace(period); period {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(period)
+		if err != nil || per <= 0 {
+			return Rate{}, fmt.Errorf("rate %q: period must be s, m, h, or a positive duration", s)
+		}
+	}
+	return Rate{N: n, Per: per}, nil
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%d per %s", r.N, r.Per)
+}
+
+// Ledger records when each agent ran its commands. With a path it is
+// kept in that file, shared by every doit process; without, in memory.
+// It is safe for concurrent use.
+type Ledger struct {
+	path  string
+	clock clock.Clock
+
+	mu      sync.Mutex
+	records map[string]*record
+}
+
+type record struct {
+	Commands []time.Time `json:"commands,omitempty"` // within the rate limit's period
+	Recent   []time.Time `json:"recent,omitempty"`   // within the last hour, for quotas
+	Writes   int         `json:"writes,omitempty"`   // write-tier commands
+	Bytes    int64       `json:"bytes,omitempty"`    // bytes written to files
+	Exceeded string      `json:"exceeded,omitempty"` // the quota exceeded, until reset
+	Tainted  string      `json:"tainted,omitempty"`  // the sensitive path read, until reset
+}
+
+// Quota bounds an agent's use; zero fields are unbounded.
+type Quota struct {
+	CommandsPerHour int
+	WriteOps        int
+	BytesWritten    int64
+}
+
+// Usage is an agent's use as the ledger counts it against its quotas.
+type Usage struct {
+	Key      string // see Key
+	Commands int    // in the last hour
+	Writes   int    // write-tier commands
+	Bytes    int64  // bytes written to files
+	Exceeded string // the quota exceeded, or ""
+	Tainted  string // the sensitive path read, or ""
+}
+
+// NewLedger returns a Ledger kept at path, or in memory if path is "". A
+// nil clk is the system clock.
+func NewLedger(path string, clk clock.Clock) *Ledger {
+	return &Ledger{path: path, clock: clock.Or(clk), records: map[string]*record{}}
+}
+
+// Admit records a command by agent if rate allows one more, returning
+// 0. Otherwise it records nothing and returns how long until it would.
+func (l *Ledger) Admit(agent string, rate Rate) (wait time.Duration, err error) {
+	err = l.update(func() bool {
+		now := l.clock.Now()
+		r := l.records[agent]
+		if r == nil {
+			r = &record{}
+			l.records[agent] = r
+		}
+		n := len(r.Commands)
+		r.Commands = pruned(r.Commands, now.Add(-rate.Per))
+		if len(r.Commands) >= rate.N {
+			wait = r.Commands[len(r.Commands)-rate.N].Add(rate.Per).Sub(now)
+			return len(r.Commands) < n
+		}
+		r.Commands = append(r.Commands, now)
+		return true
+	})
+	return wait, err
+}
+
+// Record counts a command run under key — a write-tier one if write, which
+// wrote bytes to files — and returns the quota it exceeded, if any. A
+// quota is exceeded once use reaches it, and stays so until Reset, however
+// old the commands that exceeded it.
+func (l *Ledger) Record(key string, write bool, bytes int64, q Quota) (exceeded string, err error) {
+	err = l.update(func() bool {
+		now := l.clock.Now()
+		r := l.records[key]
+		if r == nil {
+			r = &record{}
+			l.records[key] = r
+		}
+		r.Recent = append(pruned(r.Recent, now.Add(-time.Hour)), now)
+		if write {
+			r.Writes++
+		}
+		r.Bytes += bytes
+		if r.Exceeded == "" {
+			switch {
+			case q.CommandsPerHour > 0 && len(r.Recent) >= q.CommandsPerHour:
+				r.Exceeded = fmt.Sprintf("%d commands in an hour", q.CommandsPerHour)
+			case q.WriteOps > 0 && r.Writes >= q.WriteOps:
+				r.Exceeded = fmt.Sprintf("%d write-tier commands", q.WriteOps)
+			case q.BytesWritten > 0 && r.Bytes >= q.BytesWritten:
+				r.Exceeded = fmt.Sprintf("%d bytes written", q.BytesWritten)
+			}
+		}
+		exceeded = r.Exceeded
+		return true
+	})
+	return exceeded, err
+}
+
+// Exceeded returns the quota use under key has exceeded, or "".
+func (l *Ledger) Exceeded(key string) (string, error) {
+	var exceeded string
+	err := l.update(func() bool {
+		if r := l.records[key]; r != nil {
+			exceeded = r.Exceeded
+		}
+		return false
+	})
+	return exceeded, err
+}
+
+// Taint notes that a command run under key read the sensitive path, so
+// whatever it ran afterwards may carry the secret. The first path noted
+// stands until Reset.
+func (l *Ledger) Taint(key, path string) error {
+	return l.update(func() bool {
+		r := l.records[key]
+		if r == nil {
+			r = &record{}
+			l.records[key] = r
+		}
+		if r.Tainted != "" {
+			return false
+		}
+		r.Tainted = path
+		return true
+	})
+}
+
+// Tainted returns the sensitive path a command run under key read, or
+// "".
+func (l *Ledger) Tainted(key string) (string, error) {
+	var tainted string
+	err := l.update(func() bool {
+		if r := l.records[key]; r != nil {
+			tainted = r.Tainted
+		}
+		return false
+	})
+	return tainted, err
+}
+
+// Usage returns the use counted under each key, sorted by key.
+func (l *Ledger) Usage() ([]Usage, error) {
+	var out []Usage
+	err := l.update(func() bool {
+		cutoff := l.clock.Now().Add(-time.Hour)
+		for key, r := range l.records {
+			u := Usage{Key: key, Commands: len(pruned(r.Recent, cutoff)), Writes: r.Writes, Bytes: r.Bytes, Exceeded: r.Exceeded, Tainted: r.Tainted}
+			if u != (Usage{Key: key}) {
+				out = append(out, u)
+			}
+		}
+		return false
+	})
+	slices.SortFunc(out, func(a, b Usage) int { return strings.Compare(a.Key, b.Key) })
+	return out, err
+}
+
+// Reset clears the use counted under key against its quotas, and with it
+// any quota exceeded and any taint, reporting whether there was any.
+func (l *Ledger) Reset(key string) (bool, error) {
+	found := false
+	err := l.update(func() bool {
+		r := l.records[key]
+		if r == nil || len(r.Recent) == 0 && r.Writes == 0 && r.Bytes == 0 && r.Exceeded == "" && r.Tainted == "" {
+			return false
+		}
+		found = true
+		r.Recent, r.Writes, r.Bytes, r.Exceeded, r.Tainted = nil, 0, 0, "", ""
+		if len(r.Commands) == 0 {
+			delete(l.records, key)
+		}
+		return true
+	})
+	return found, err
+}
+
+// pruned returns times less those no later than cutoff. times is in
+// order.
+func pruned(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func (l *Ledger) update(fn func() (changed bool)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		fn()
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("agent ledger: %w", err)
+	}
+	lock, err := os.OpenFile(l.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("agent ledger: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("agent ledger: %w", err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	records := map[string]*record{}
+	data, err := os.ReadFile(l.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("agent ledger: %w", err)
+	default:
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("agent ledger %s: %w", l.path, err)
+		}
+	}
+	l.records = records
+	if !fn() {
+		return nil
+	}
+	if data, err = json.Marshal(l.records); err != nil {
+		return fmt.Errorf("agent ledger: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("agent ledger: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("agent ledger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package agent
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/clock"
+)
+
+func TestVerify(t *testing.T) {
+	hash := HashSecret("s3cret")
+	if !Verify(hash, "s3cret") || !Verify(strings.ToUpper(hash), "s3cret") {
+		t.Error("Verify rejected the secret")
+	}
+	if Verify(hash, "guess") || Verify("", "") {
+		t.Error("Verify accepted a wrong secret")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for s, want := range map[string]Rate{
+		"60/m":   {60, time.Minute},
+		"1000/h": {1000, time.Hour},
+		"5/30s":  {5, 30 * time.Second},
+		" 2 / s": {2, time.Second},
+	} {
+		if got, err := ParseRate(s); err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	for _, bad := range []string{"60", "0/m", "-1/m", "x/m", "5/day", "5/-1s"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("ParseRate(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLedger(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	path := filepath.Join(t.TempDir(), "agents.json")
+	rate := Rate{N: 2, Per: time.Minute}
+
+	// Two ledgers on one file stand for two doit processes.
+	a, b := NewLedger(path, clk), NewLedger(path, clk)
+	admit := func(l *Ledger, agent string) time.Duration {
+		t.Helper()
+		wait, err := l.Admit(agent, rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wait
+	}
+	if admit(a, "dev") != 0 {
+		t.Fatal("first command refused")
+	}
+	clk.Advance(20 * time.Second)
+	if admit(b, "dev") != 0 {
+		t.Fatal("second command refused")
+	}
+	if wait := admit(a, "dev"); wait != 40*time.Second {
+		t.Errorf("third command: wait %v, want 40s", wait)
+	}
+	if admit(b, "reviewer") != 0 {
+		t.Error("another agent's commands counted")
+	}
+	clk.Advance(40 * time.Second)
+	if admit(a, "dev") != 0 {
+		t.Error("command refused once the first aged out")
+	}
+
+	mem := NewLedger("", clk)
+	admit(mem, "dev")
+	admit(mem, "dev")
+	if admit(mem, "dev") == 0 {
+		t.Error("in-memory ledger admitted past the rate")
+	}
+}
+
+func TestLedger_Taint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.json")
+	a, b := NewLedger(path, nil), NewLedger(path, nil)
+	if err := a.Taint("dev@s1", ".env"); err != nil {
+		t.Fatal(err)
+	}
+	a.Taint("dev@s1", "id_rsa")
+	if got, err := b.Tainted("dev@s1"); got != ".env" || err != nil {
+		t.Errorf("Tainted = %q, %v; want the first path", got, err)
+	}
+	if got, _ := b.Tainted("dev"); got != "" {
+		t.Errorf("another session tainted: %q", got)
+	}
+	usage, _ := b.Usage()
+	if want := []Usage{{Key: "dev@s1", Tainted: ".env"}}; !slices.Equal(usage, want) {
+		t.Errorf("Usage = %+v, want %+v", usage, want)
+	}
+	if ok, err := b.Reset("dev@s1"); !ok || err != nil {
+		t.Fatalf("Reset = %v, %v", ok, err)
+	}
+	if got, _ := a.Tainted("dev@s1"); got != "" {
+		t.Errorf("tainted after reset: %q", got)
+	}
+}
+
+func TestLedger_Quota(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	l := NewLedger(filepath.Join(t.TempDir(), "agents.json"), clk)
+	q := Quota{CommandsPerHour: 3, WriteOps: 2, BytesWritten: 1000}
+	record := func(key string, write bool, bytes int64) string {
+		t.Helper()
+		exceeded, err := l.Record(key, write, bytes, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return exceeded
+	}
+
+	if record("dev", false, 0) != "" || record("dev", true, 400) != "" {
+		t.Fatal("quota exceeded early")
+	}
+	clk.Advance(time.Hour)
+	if got := record("dev", true, 0); got != "2 write-tier commands" {
+		t.Errorf("second write: exceeded %q", got)
+	}
+	// Exceeded stays so, whatever happens next.
+	if got := record("dev", false, 0); got != "2 write-tier commands" {
+		t.Errorf("after exceeding: %q", got)
+	}
+	if got := record(Key("dev", "s1"), false, 1000); got != "1000 bytes written" {
+		t.Errorf("in a session: %q", got)
+	}
+
+	usage, err := l.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Usage{
+		{Key: "dev", Commands: 2, Writes: 2, Bytes: 400, Exceeded: "2 write-tier commands"},
+		{Key: "dev@s1", Commands: 1, Bytes: 1000, Exceeded: "1000 bytes written"},
+	}
+	if !slices.Equal(usage, want) {
+		t.Errorf("Usage = %+v, want %+v", usage, want)
+	}
+
+	if ok, err := l.Reset("dev"); !ok || err != nil {
+		t.Fatalf("Reset = %v, %v", ok, err)
+	}
+	if got, _ := l.Exceeded("dev"); got != "" {
+		t.Errorf("exceeded after reset: %q", got)
+	}
+	if got, _ := l.Exceeded("dev@s1"); got == "" {
+		t.Error("reset cleared another session")
+	}
+	if ok, _ := l.Reset("nobody"); ok {
+		t.Error("Reset found an unknown key")
+	}
+	for range 2 {
+		record("busy", false, 0)
+	}
+	if got := record("busy", false, 0); got != "3 commands in an hour" {
+		t.Errorf("hourly: %q", got)
+	}
+}
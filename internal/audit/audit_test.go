@@ -4,6 +4,8 @@
 package audit
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -195,8 +197,10 @@ func TestLoggerSizeLimit(t *testing.T) {
 	// which should set sizeLimitHit=true for future writes.
 	_ = logger.Log("trigger", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
 
-	// Further writes should be skipped.
-	_ = logger.Log("skipped", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+	// Further writes should be skipped, and say so.
+	if err := logger.Log("skipped", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil); !errors.Is(err, ErrFull) {
+		t.Errorf("write past the limit: got %v, want ErrFull", err)
+	}
 	_ = logger.Log("skipped2", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
 
 	infoAfter, err := os.Stat(path)
@@ -248,3 +252,291 @@ func TestTailMalformedEntries(t *testing.T) {
 		t.Errorf("expected 4 valid entries, got %d", len(entries))
 	}
 }
+
+func TestLoggerResumesAfterTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	logger1, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = logger1.Log("first", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+	_ = logger1.Log("second", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+	logger1.Close()
+
+	// Simulate a crash part-way through writing a third entry.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"seq":3,"ts":"2026-01-01T00:00:00Z","prev_hash":"ab`)
+	f.Close()
+
+	logger2, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger2.Close()
+	_ = logger2.Log("third", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+
+	if err := Verify(path); err != nil {
+		t.Fatalf("chain should be valid after torn-write recovery: %v", err)
+	}
+	entries, err := Tail(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[2].Pipeline != "third" || entries[2].Seq != 3 {
+		t.Errorf("unexpected entries after recovery: %+v", entries)
+	}
+}
+
+func TestLoggerKeepsUnterminatedCompleteEntry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	logger1, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = logger1.Log("first", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+	logger1.Close()
+
+	// Drop the trailing newline, as if the crash happened just before it.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-1], 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger2, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger2.Close()
+	_ = logger2.Log("second", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+
+	if err := Verify(path); err != nil {
+		t.Fatalf("chain should be valid: %v", err)
+	}
+	entries, _ := Tail(path, 10)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(entries))
+	}
+}
+
+func TestLoggerFsyncInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	logger, err := OpenLogger(path, LoggerOptions{Fsync: FsyncInterval, FsyncInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = logger.Log("buffered", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+
+	// Nothing reaches the file until a flush.
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Fatalf("expected empty file before flush, got size %d (err %v)", info.Size(), err)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Tail(path, 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 entry after flush, got %d (err %v)", len(entries), err)
+	}
+
+	// Close flushes the remainder.
+	_ = logger.Log("on close", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(path); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if err := logger.Log("after close", nil, nil, 0, "", 0, "", false, nil); err == nil {
+		t.Error("expected error logging to a closed logger")
+	}
+}
+
+func TestParseFsyncPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FsyncPolicy
+		wantErr bool
+	}{
+		{"", FsyncAlways, false},
+		{"always", FsyncAlways, false},
+		{"interval", FsyncInterval, false},
+		{"never", FsyncNever, false},
+		{"sometimes", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFsyncPolicy(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFsyncPolicy(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFsyncPolicy(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTailAcrossChunks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	logger, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+
+	// Entries large enough that the log spans several read chunks, with
+	// one entry bigger than a chunk on its own.
+	big := strings.Repeat("x", tailChunk+100)
+	for i := 0; i < 50; i++ {
+		cmd := fmt.Sprintf("cmd-%d %s", i, strings.Repeat("y", 4000))
+		if i == 45 {
+			cmd = "big " + big
+		}
+		if err := logger.Log(cmd, []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := Tail(path, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 7 {
+		t.Fatalf("expected 7 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if want := uint64(44 + i); e.Seq != want {
+			t.Errorf("entry %d: seq = %d, want %d", i, e.Seq, want)
+		}
+	}
+	if !strings.HasPrefix(entries[2].Pipeline, "big ") {
+		t.Errorf("expected the oversized entry at index 2, got %.20q", entries[2].Pipeline)
+	}
+
+	all, err := Tail(path, 1000)
+	if err != nil || len(all) != 50 {
+		t.Fatalf("Tail(1000) = %d entries (err %v), want 50", len(all), err)
+	}
+
+	if err := Verify(path); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+}
+
+func TestVerifyShortHashNoPanic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"seq":1,"prev_hash":"abc","hash":"def"}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(path); err == nil || !strings.Contains(err.Error(), "prev_hash mismatch") {
+		t.Fatalf("expected prev_hash mismatch, got %v", err)
+	}
+}
+
+func TestVerifyWithCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	cpPath := CheckpointPath(path)
+
+	logger, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	logN := func(n int) {
+		for i := 0; i < n; i++ {
+			_ = logger.Log("test", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+		}
+	}
+
+	logN(5)
+	report, err := VerifyWith(path, VerifyOptions{Checkpoint: cpPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Incremental || report.Checked != 5 || report.LastSeq != 5 {
+		t.Fatalf("first run: %+v", report)
+	}
+
+	// Only new entries are checked on the next run.
+	logN(3)
+	report, err = VerifyWith(path, VerifyOptions{Checkpoint: cpPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Incremental || report.Checked != 3 || report.LastSeq != 8 {
+		t.Fatalf("incremental run: %+v", report)
+	}
+
+	// Nothing new: nothing checked.
+	report, err = VerifyWith(path, VerifyOptions{Checkpoint: cpPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Incremental || report.Checked != 0 {
+		t.Fatalf("no-op run: %+v", report)
+	}
+
+	// Full forces a re-check from genesis.
+	report, err = VerifyWith(path, VerifyOptions{Checkpoint: cpPath, Full: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Incremental || report.Checked != 8 {
+		t.Fatalf("full run: %+v", report)
+	}
+}
+
+func TestVerifyWithStaleCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	cpPath := CheckpointPath(path)
+
+	logger, err := NewLogger(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		_ = logger.Log("test", []string{"cat"}, []string{"read"}, 0, "", time.Millisecond, "/tmp", false, nil)
+	}
+	logger.Close()
+	if _, err := VerifyWith(path, VerifyOptions{Checkpoint: cpPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tamper with an entry before the watermark. The incremental run
+	// notices the watermark no longer matches and falls back to a full
+	// verification, which catches the tampering.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := splitLines(data)
+	lines[3] = []byte(strings.Replace(string(lines[3]), `"pipeline":"test"`, `"pipeline":"tset"`, 1))
+	var newData []byte
+	for _, line := range lines {
+		newData = append(newData, line...)
+		newData = append(newData, '\n')
+	}
+	if err := os.WriteFile(path, newData, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifyWith(path, VerifyOptions{Checkpoint: cpPath}); err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("expected full-chain violation at line 4, got %v", err)
+	}
+}
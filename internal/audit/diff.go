@@ -0,0 +1,148 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"strings"
+)
+
+// ChangeKind classifies a filesystem-affecting command.
+type ChangeKind string
+
+const (
+	ChangeDelete ChangeKind = "delete" // removes files (rm, rmdir, ...)
+	ChangeGit    ChangeKind = "git"    // mutates a git repository or its refs
+	ChangeWrite  ChangeKind = "write"  // creates or modifies files
+)
+
+// Change is an audit entry that affected the filesystem, with every kind
+// of change its command makes.
+type Change struct {
+	Entry Entry
+	Kinds []ChangeKind
+}
+
+// Changes returns the entries whose commands write, delete, or mutate git
+// state, in log order. Classification is a heuristic over the command
+// string: it splits on shell operators and looks at each command's name,
+// subcommand, and output redirections, without interpreting quoting. It
+// answers "what might the agent have changed", not "what did change".
+func Changes(entries []Entry) []Change {
+	var changes []Change
+	for _, e := range entries {
+		if kinds := classifyCommand(e.Pipeline); len(kinds) > 0 {
+			changes = append(changes, Change{Entry: e, Kinds: kinds})
+		}
+	}
+	return changes
+}
+
+var (
+	deleteCommands = map[string]bool{"rm": true, "rmdir": true, "unlink": true, "shred": true}
+	writeCommands  = map[string]bool{
+		"mv": true, "cp": true, "mkdir": true, "touch": true, "tee": true,
+		"ln": true, "chmod": true, "chown": true, "install": true,
+		"truncate": true, "dd": true, "patch": true, "rsync": true,
+	}
+	gitReadOnly = map[string]bool{
+		"status": true, "log": true, "diff": true, "show": true, "blame": true,
+		"grep": true, "ls-files": true, "rev-parse": true, "describe": true,
+		"shortlog": true, "reflog": true, "fetch": true, "remote": true,
+		"config": true, "help": true, "version": true,
+	}
+)
+
+// classifyCommand returns the change kinds of a shell command string, in
+// the order delete, git, write.
+func classifyCommand(command string) []ChangeKind {
+	found := map[ChangeKind]bool{}
+	for _, simple := range splitSimpleCommands(command) {
+		words := strings.Fields(simple)
+		// Skip leading environment assignments (FOO=bar cmd).
+		for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "-") {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		name := words[0]
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		switch {
+		case deleteCommands[name]:
+			found[ChangeDelete] = true
+		case writeCommands[name]:
+			found[ChangeWrite] = true
+		case name == "sed" && hasInPlaceFlag(words[1:]):
+			found[ChangeWrite] = true
+		case name == "git":
+			if sub := gitSubcommand(words[1:]); sub != "" && !gitReadOnly[sub] {
+				found[ChangeGit] = true
+			}
+		}
+		if redirectsToFile(words[1:]) {
+			found[ChangeWrite] = true
+		}
+	}
+	var kinds []ChangeKind
+	for _, k := range []ChangeKind{ChangeDelete, ChangeGit, ChangeWrite} {
+		if found[k] {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+// splitSimpleCommands splits a command string on ;, &&, ||, |, and
+// newlines.
+func splitSimpleCommands(command string) []string {
+	return strings.FieldsFunc(strings.NewReplacer("&&", ";", "||", ";", "|", ";", "\n", ";").Replace(command), func(r rune) bool {
+		return r == ';'
+	})
+}
+
+// gitSubcommand returns the first non-flag argument to git, skipping the
+// values of -C and -c.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-C" || a == "-c":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			return a
+		}
+	}
+	return ""
+}
+
+func hasInPlaceFlag(args []string) bool {
+	for _, a := range args {
+		if a == "-i" || strings.HasPrefix(a, "-i") || a == "--in-place" || strings.HasPrefix(a, "--in-place=") {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectsToFile reports whether words contain an output redirection to
+// something other than /dev/null or another file descriptor.
+func redirectsToFile(words []string) bool {
+	for i, w := range words {
+		j := strings.IndexByte(w, '>')
+		if j < 0 {
+			continue
+		}
+		target := strings.TrimLeft(w[j:], ">|")
+		if target == "" && i+1 < len(words) {
+			target = words[i+1]
+		}
+		if target == "" || strings.HasPrefix(target, "&") || target == "/dev/null" {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []ChangeKind
+	}{
+		{"ls -la", nil},
+		{"go test ./... 2>&1", nil},
+		{"grep foo bar >/dev/null", nil},
+		{"git status", nil},
+		{"git -C repo log --oneline", nil},
+		{"rm -rf build", []ChangeKind{ChangeDelete}},
+		{"/bin/rm x", []ChangeKind{ChangeDelete}},
+		{"git commit -m msg", []ChangeKind{ChangeGit}},
+		{"git -C repo push origin main", []ChangeKind{ChangeGit}},
+		{"echo hi > out.txt", []ChangeKind{ChangeWrite}},
+		{"echo hi >>out.txt", []ChangeKind{ChangeWrite}},
+		{"sed -i s/a/b/ f", []ChangeKind{ChangeWrite}},
+		{"sed s/a/b/ f", nil},
+		{"CGO_ENABLED=0 cp a b", []ChangeKind{ChangeWrite}},
+		{"go build ./... && git add -A && git commit -m x; rm -f tmp", []ChangeKind{ChangeDelete, ChangeGit}},
+		{"cat a | tee b", []ChangeKind{ChangeWrite}},
+	}
+	for _, tt := range tests {
+		if got := classifyCommand(tt.command); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("classifyCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestChanges(t *testing.T) {
+	entries := []Entry{
+		{Seq: 1, Pipeline: "ls"},
+		{Seq: 2, Pipeline: "rm -rf x"},
+		{Seq: 3, Pipeline: "git status"},
+		{Seq: 4, Pipeline: "echo x > y"},
+	}
+	changes := Changes(entries)
+	if len(changes) != 2 || changes[0].Entry.Seq != 2 || changes[1].Entry.Seq != 4 {
+		t.Fatalf("Changes = %+v, want seqs 2 and 4", changes)
+	}
+	if changes[1].Kinds[0] != ChangeWrite {
+		t.Errorf("seq 4 kinds = %v, want [write]", changes[1].Kinds)
+	}
+}
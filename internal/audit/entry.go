@@ -7,23 +7,60 @@ import "time"
 
 // Entry represents a single audit log record.
 type Entry struct {
-	Seq           uint64    `json:"seq"`
-	Time          time.Time `json:"ts"`
-	PrevHash      string    `json:"prev_hash"`
-	Pipeline      string    `json:"pipeline"`                  // raw pipeline description
-	Segments      []string  `json:"segments"`                  // capability names
-	Tiers         []string  `json:"tiers"`                     // tier of each segment
-	Retry         bool      `json:"retry,omitempty"`           // true if --retry was used
-	ExitCode      int       `json:"exit_code"`                 // 0 = success
-	Error         string    `json:"error,omitempty"`           // error message if failed
-	Duration      float64   `json:"duration_ms"`               // execution time in milliseconds
-	Cwd           string    `json:"cwd"`                       // working directory
-	PolicyLevel   int       `json:"policy_level,omitempty"`    // 1, 2, or 3
-	PolicyResult  string    `json:"policy_result,omitempty"`   // "allow", "deny", "escalate"
-	PolicyRuleID  string    `json:"policy_rule_id,omitempty"`  // which rule matched
-	Justification string    `json:"justification,omitempty"`   // worker's justification
-	SafetyArg     string    `json:"safety_arg,omitempty"`      // worker's safety argument
-	Hash          string    `json:"hash"`                      // SHA-256 of this entry (with hash field empty)
+	Seq           uint64        `json:"seq"`
+	Time          time.Time     `json:"ts"`
+	PrevHash      string        `json:"prev_hash"`
+	Pipeline      string        `json:"pipeline"`                 // raw pipeline description
+	Segments      []string      `json:"segments"`                 // capability names
+	Tiers         []string      `json:"tiers"`                    // tier of each segment
+	Tier          string        `json:"tier,omitempty"`           // highest tier of anything the command runs, redirections included
+	Retry         bool          `json:"retry,omitempty"`          // true if --retry was used
+	RetryRule     string        `json:"retry_rule,omitempty"`     // rule the retry bypassed (empty: blanket)
+	RetrySeq      uint64        `json:"retry_seq,omitempty"`      // audit seq of the denial being retried
+	ExitCode      int           `json:"exit_code"`                // 0 = success
+	Signal        string        `json:"signal,omitempty"`         // signal that killed the command (exit code 128+n)
+	Error         string        `json:"error,omitempty"`          // error message if failed
+	Duration      float64       `json:"duration_ms"`              // execution time in milliseconds
+	Cwd           string        `json:"cwd"`                      // working directory
+	PolicyLevel   int           `json:"policy_level,omitempty"`   // 1, 2, or 3
+	PolicyResult  string        `json:"policy_result,omitempty"`  // "allow", "deny", "escalate"
+	PolicyRuleID  string        `json:"policy_rule_id,omitempty"` // which rule matched
+	Conditions    string        `json:"conditions,omitempty"`     // what an allow held the command to, e.g. "timeout 1m0s, no network"
+	Profile       string        `json:"profile,omitempty"`        // execution profile it ran under
+	Severity      string        `json:"severity,omitempty"`       // "critical" for an attempt to be looked into, such as touching a tripwire
+	Justification string        `json:"justification,omitempty"`  // worker's justification
+	SafetyArg     string        `json:"safety_arg,omitempty"`     // worker's safety argument
+	Session       string        `json:"session,omitempty"`        // work session active at the time
+	Agent         string        `json:"agent,omitempty"`          // agent that made the request, if it declared one
+	Grant         string        `json:"grant,omitempty"`          // temporary grant that allowed it
+	Files         []FileChange  `json:"files,omitempty"`          // files written by an in-process capability
+	Redirects     []string      `json:"redirects,omitempty"`      // redirections in the command line, e.g. "2> err.log"
+	LLM           []LLMCall     `json:"llm,omitempty"`            // Level 3 calls behind the decision
+	Group         string        `json:"group,omitempty"`          // plan or session the entry belongs to
+	Summary       *GroupSummary `json:"summary,omitempty"`        // set on the entry that closes a group
+	Hook          *HookRun      `json:"hook,omitempty"`           // set on the run of a configured hook
+	Violations    []string      `json:"violations,omitempty"`     // configured post-conditions the command broke
+	Hash          string        `json:"hash"`                     // SHA-256 of this entry (with hash field empty)
+}
+
+// SeverityCritical marks an entry recording an attempt someone should
+// look into, such as a command touching a tripwire.
+const SeverityCritical = "critical"
+
+// FileChange records a file a command wrote, by SHA-256 of its content.
+type FileChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"` // empty if the file was created
+	After  string `json:"after"`
+}
+
+// HookRun marks an entry as the run of a configured hook, or of the
+// check of a configured assertion.
+type HookRun struct {
+	Name    string `json:"name"`
+	When    string `json:"when"`             // "before", "after", or "check"
+	Trigger string `json:"trigger"`          // the command the hook ran for
+	Output  string `json:"output,omitempty"` // the hook's output, if its config asks to keep it
 }
 
 // LogOptions carries optional metadata for audit entries.
@@ -31,6 +68,23 @@ type LogOptions struct {
 	PolicyLevel   int
 	PolicyResult  string
 	PolicyRuleID  string
+	Conditions    string
+	Profile       string
+	Tier          string
+	Severity      string
 	Justification string
 	SafetyArg     string
+	Session       string
+	Agent         string
+	Grant         string
+	RetryRule     string
+	RetrySeq      uint64
+	Signal        string
+	Files         []FileChange
+	Redirects     []string
+	LLM           []LLMCall
+	Group         string
+	Summary       *GroupSummary
+	Hook          *HookRun
+	Violations    []string
 }
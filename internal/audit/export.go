@@ -0,0 +1,203 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Transcript formats accepted by WriteTranscript.
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// WriteTranscript renders entries as a human-readable transcript in the
+// given format (FormatMarkdown or FormatHTML), for code review or incident
+// write-ups. The audit log does not record command output, so the
+// transcript covers commands, policy decisions, agent justifications,
+// exit codes, and durations.
+func WriteTranscript(w io.Writer, title string, entries []Entry, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return writeMarkdown(w, title, entries)
+	case FormatHTML:
+		return transcriptHTML.Execute(w, struct {
+			Title   string
+			Summary string
+			Units   []Unit
+		}{title, summarize(entries), Units(entries)})
+	default:
+		return fmt.Errorf("unknown transcript format %q (want %s or %s)", format, FormatMarkdown, FormatHTML)
+	}
+}
+
+func writeMarkdown(w io.Writer, title string, entries []Entry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", title, summarize(entries))
+	for _, u := range Units(entries) {
+		if u.Key == "" {
+			writeMarkdownEntry(&b, u.Entries[0], "##")
+			continue
+		}
+		// Groups collapse, as GitHub and most renderers show <details>.
+		fmt.Fprintf(&b, "\n<details>\n<summary>%s</summary>\n", template.HTMLEscapeString(unitTitle(u)))
+		for _, e := range u.Entries {
+			writeMarkdownEntry(&b, e, "###")
+		}
+		b.WriteString("\n</details>\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownEntry(b *strings.Builder, e Entry, heading string) {
+	fmt.Fprintf(b, "\n%s #%d — %s\n\n", heading, e.Seq, e.Time.UTC().Format(time.RFC3339))
+	fence := "```"
+	for strings.Contains(e.Pipeline, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%ssh\n%s\n%s\n\n", fence, e.Pipeline, fence)
+	fmt.Fprintf(b, "- **Decision:** %s\n", decisionText(e))
+	if e.Justification != "" {
+		fmt.Fprintf(b, "- **Justification:** %s\n", e.Justification)
+	}
+	if e.SafetyArg != "" {
+		fmt.Fprintf(b, "- **Safety argument:** %s\n", e.SafetyArg)
+	}
+	if e.Cwd != "" {
+		fmt.Fprintf(b, "- **Directory:** `%s`\n", e.Cwd)
+	}
+	fmt.Fprintf(b, "- **Exit code:** %d\n", e.ExitCode)
+	if e.Signal != "" {
+		fmt.Fprintf(b, "- **Killed by:** %s\n", e.Signal)
+	}
+	for _, f := range e.Files {
+		fmt.Fprintf(b, "- **Wrote:** `%s` (%s)\n", f.Path, fileChangeText(f))
+	}
+	fmt.Fprintf(b, "- **Duration:** %s\n", durationText(e.Duration))
+	if e.Error != "" {
+		fmt.Fprintf(b, "- **Error:** %s\n", e.Error)
+	}
+}
+
+// unitTitle describes a group for its collapsed form: its summary entry's
+// tally, or, for a group not yet closed, one computed from its entries.
+func unitTitle(u Unit) string {
+	if u.Summary != nil {
+		return fmt.Sprintf("#%d %s", u.Summary.Seq, u.Summary.Summary)
+	}
+	kind := GroupSession
+	if len(u.Entries) > 0 && u.Entries[0].Group != "" {
+		kind = GroupPlan
+		if strings.HasPrefix(u.Entries[0].Group, GroupBatch+"-") {
+			kind = GroupBatch
+		}
+	}
+	return Summarize(kind, u.Key, u.Entries).String() + " (not closed)"
+}
+
+// summarize returns a one-line overview: command count, time span, and
+// decision counts. Group summary entries are not commands.
+func summarize(entries []Entry) string {
+	var commands []Entry
+	for _, e := range entries {
+		if e.Summary == nil {
+			commands = append(commands, e)
+		}
+	}
+	if len(commands) == 0 {
+		return "No commands recorded."
+	}
+	counts := map[string]int{}
+	for _, e := range commands {
+		counts[e.PolicyResult]++
+	}
+	first, last := commands[0].Time.UTC(), commands[len(commands)-1].Time.UTC()
+	return fmt.Sprintf("%d commands from %s to %s (%d allowed, %d denied, %d escalated).",
+		len(commands), first.Format(time.RFC3339), last.Format(time.RFC3339),
+		counts["allow"], counts["deny"], counts["escalate"])
+}
+
+func decisionText(e Entry) string {
+	if e.PolicyResult == "" {
+		return "no policy decision recorded"
+	}
+	s := fmt.Sprintf("%s (L%d)", e.PolicyResult, e.PolicyLevel)
+	if e.Grant != "" {
+		s += ", temporary grant " + e.Grant
+	} else if e.PolicyRuleID != "" {
+		s += ", rule " + e.PolicyRuleID
+	}
+	if e.Retry {
+		s += ", retry"
+		if e.RetryRule != "" {
+			s += " past " + e.RetryRule
+		}
+		if e.RetrySeq != 0 {
+			s += fmt.Sprintf(" (denied in #%d)", e.RetrySeq)
+		}
+	}
+	return s
+}
+
+// fileChangeText abbreviates a file change's hashes: "sha256 1a2b… → 3c4d…".
+func fileChangeText(f FileChange) string {
+	if f.Before == "" {
+		return fmt.Sprintf("created, sha256 %.12s", f.After)
+	}
+	return fmt.Sprintf("sha256 %.12s → %.12s", f.Before, f.After)
+}
+
+func durationText(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Millisecond).String()
+}
+
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"decision":   decisionText,
+	"duration":   durationText,
+	"title":      unitTitle,
+	"filechange": fileChangeText,
+	"ts":         func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; }
+pre { background: #f4f4f4; padding: 0.75em; overflow-x: auto; }
+.deny { color: #b00020; } .escalate { color: #b36b00; } .allow { color: #1b5e20; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 0.25em 1em; }
+dt { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Summary}}</p>
+{{range .Units}}{{if .Key}}<details open>
+<summary>{{title .}}</summary>
+{{range .Entries}}{{template "entry" .}}{{end}}</details>
+{{else}}{{range .Entries}}{{template "entry" .}}{{end}}{{end}}{{end}}</body>
+</html>
+{{define "entry"}}<section>
+<h2>#{{.Seq}} — {{ts .Time}}</h2>
+<pre><code>{{.Pipeline}}</code></pre>
+<dl>
+<dt>Decision</dt><dd class="{{.PolicyResult}}">{{decision .}}</dd>
+{{if .Justification}}<dt>Justification</dt><dd>{{.Justification}}</dd>
+{{end}}{{if .SafetyArg}}<dt>Safety argument</dt><dd>{{.SafetyArg}}</dd>
+{{end}}{{if .Cwd}}<dt>Directory</dt><dd><code>{{.Cwd}}</code></dd>
+{{end}}<dt>Exit code</dt><dd>{{.ExitCode}}</dd>
+{{if .Signal}}<dt>Killed by</dt><dd>{{.Signal}}</dd>
+{{end}}{{range .Files}}<dt>Wrote</dt><dd><code>{{.Path}}</code> ({{filechange .}})</dd>
+{{end}}<dt>Duration</dt><dd>{{duration .Duration}}</dd>
+{{if .Error}}<dt>Error</dt><dd>{{.Error}}</dd>
+{{end}}</dl>
+</section>
+{{end}}`))
@@ -0,0 +1,67 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTranscript(t *testing.T) {
+	path, logger := seedTestLog(t)
+	if err := logger.Log("echo '<b>' ``` x", []string{"echo"}, []string{"read"}, 0, "", 1500*time.Millisecond, "/tmp", false, &LogOptions{
+		PolicyLevel: 1, PolicyResult: "allow", Justification: "check output", Session: "session-1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	all, err := Query(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := Query(path, &Filter{Session: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session) != 1 || len(all) != 6 {
+		t.Fatalf("session filter: got %d of %d entries, want 1 of 6", len(session), len(all))
+	}
+
+	var md strings.Builder
+	if err := WriteTranscript(&md, "Session session-1", all, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Session session-1",
+		"6 commands from",
+		"(5 allowed, 1 denied, 0 escalated)",
+		"````sh\necho '<b>' ``` x\n````",
+		"- **Decision:** deny (L3)",
+		"- **Justification:** check output",
+		"- **Duration:** 1.5s",
+		"- **Error:** denied",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var html strings.Builder
+	if err := WriteTranscript(&html, "Session <1>", session, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>Session &lt;1&gt;</title>",
+		"echo &#39;&lt;b&gt;&#39;",
+		`<dd class="allow">allow (L1)</dd>`,
+	} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("html missing %q:\n%s", want, html.String())
+		}
+	}
+
+	if err := WriteTranscript(&md, "x", all, "pdf"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
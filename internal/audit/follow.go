@@ -0,0 +1,152 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// defaultFollowInterval is how often Follow polls the log for new entries.
+const defaultFollowInterval = 250 * time.Millisecond
+
+// FollowOptions controls Follow.
+type FollowOptions struct {
+	// Backlog is the number of existing entries to emit before following.
+	Backlog int
+	// Interval is the polling period; zero means 250ms.
+	Interval time.Duration
+}
+
+// Follow calls fn for each entry appended to the audit log at path until
+// ctx is done or fn returns an error, like tail -f. It first emits the
+// last opts.Backlog entries. The log is written by whichever process owns
+// the engine, so Follow watches the file rather than subscribing to a
+// writer: entries appear once the writer flushes them (immediately under
+// the default fsync policy). A torn trailing write is held back until its
+// newline arrives; if the log is truncated or replaced, Follow restarts
+// from the beginning of the new file. Malformed entries are skipped.
+// Follow returns nil when ctx is done.
+func Follow(ctx context.Context, path string, opts FollowOptions, fn func(Entry) error) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultFollowInterval
+	}
+
+	var (
+		f    *os.File
+		info os.FileInfo
+		off  int64  // offset just past the last complete line consumed
+		rest []byte // partial line read beyond off
+	)
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	emit := func(line []byte) error {
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil
+		}
+		return fn(e)
+	}
+
+	// open (re)opens the log. The first open emits the backlog and starts
+	// at the last complete line; reopening after truncation or replacement
+	// starts from the beginning.
+	first := true
+	open := func() error {
+		nf, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		ninfo, err := nf.Stat()
+		if err != nil {
+			nf.Close()
+			return err
+		}
+		if f != nil {
+			f.Close()
+		}
+		f, info, off, rest = nf, ninfo, 0, nil
+		if !first {
+			return nil
+		}
+		first = false
+		_, end, err := lastLine(f, info.Size())
+		if err != nil {
+			return err
+		}
+		lines, err := tailLinesAt(f, end, opts.Backlog)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+		off = end
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	buf := make([]byte, tailChunk)
+	for {
+		if f == nil {
+			if err := open(); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("follow audit log: %w", err)
+			}
+		}
+		if f != nil {
+			// Detect truncation or replacement (e.g. the log was moved).
+			if cur, err := os.Stat(path); err == nil && (!os.SameFile(cur, info) || cur.Size() < off) {
+				if err := open(); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("follow audit log: %w", err)
+				}
+			}
+			for {
+				n, err := f.ReadAt(buf, off+int64(len(rest)))
+				rest = append(rest, buf[:n]...)
+				for {
+					i := bytes.IndexByte(rest, '\n')
+					if i < 0 {
+						break
+					}
+					line := rest[:i]
+					rest = rest[i+1:]
+					off += int64(i) + 1
+					if len(line) > 0 {
+						if err := emit(line); err != nil {
+							return err
+						}
+					}
+				}
+				if len(rest) > maxLineSize {
+					return fmt.Errorf("follow audit log: entry exceeds %d bytes", maxLineSize)
+				}
+				if err == io.EOF || n == 0 {
+					break
+				}
+				if err != nil {
+					return fmt.Errorf("follow audit log: %w", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFollow(t *testing.T) {
+	path, logger := seedTestLog(t)
+
+	// A torn write must be held back until its newline arrives.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got := make(chan Entry, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- Follow(ctx, path, FollowOptions{Backlog: 2, Interval: 5 * time.Millisecond}, func(e Entry) error {
+			got <- e
+			return nil
+		})
+	}()
+
+	next := func() Entry {
+		t.Helper()
+		select {
+		case e := <-got:
+			return e
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for entry")
+			return Entry{}
+		}
+	}
+
+	// Backlog: the last two seeded entries.
+	if e := next(); e.Pipeline != "go test ./..." {
+		t.Errorf("backlog[0] = %q", e.Pipeline)
+	}
+	if e := next(); e.Pipeline != "go vet ./..." {
+		t.Errorf("backlog[1] = %q", e.Pipeline)
+	}
+
+	if err := logger.Log("make", []string{"make"}, []string{"build"}, 0, "", time.Millisecond, "/tmp", false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(); e.Pipeline != "make" || e.Seq != 6 {
+		t.Errorf("followed entry = #%d %q, want #6 make", e.Seq, e.Pipeline)
+	}
+
+	if _, err := f.WriteString(`{"seq":7,"pipeline":"torn`); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-got:
+		t.Fatalf("torn write emitted early: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := f.WriteString(`"}` + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(); e.Pipeline != "torn" {
+		t.Errorf("completed entry = %q, want torn", e.Pipeline)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Follow returned %v after cancel", err)
+	}
+}
+
+func TestFollow_CallbackError(t *testing.T) {
+	path, _ := seedTestLog(t)
+	stop := errors.New("stop")
+	err := Follow(context.Background(), path, FollowOptions{Backlog: 1}, func(Entry) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Follow = %v, want callback error", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import "fmt"
+
+// Group kinds.
+const (
+	GroupPlan    = "plan"
+	GroupBatch   = "batch"
+	GroupSession = "session"
+)
+
+// GroupSummary closes a group of related entries — the steps of a plan,
+// the lines of a batch, or the commands of a work session — so the chain records how the unit as a
+// whole went.
+type GroupSummary struct {
+	Kind      string `json:"kind"` // GroupPlan, GroupBatch, or GroupSession
+	Label     string `json:"label,omitempty"`
+	Commands  int    `json:"commands"`
+	Failed    int    `json:"failed"` // ran and exited non-zero
+	Denied    int    `json:"denied"`
+	Escalated int    `json:"escalated"`
+	FirstSeq  uint64 `json:"first_seq,omitempty"`
+}
+
+// Summarize tallies entries, the members of a group, into its summary.
+// Summary entries among them are skipped.
+func Summarize(kind, label string, entries []Entry) *GroupSummary {
+	s := &GroupSummary{Kind: kind, Label: label}
+	for i := range entries {
+		s.Add(&entries[i])
+	}
+	return s
+}
+
+// Add tallies e, a member of the group, into s, unless it is a summary.
+func (s *GroupSummary) Add(e *Entry) {
+	if e.Summary != nil {
+		return
+	}
+	if s.FirstSeq == 0 {
+		s.FirstSeq = e.Seq
+	}
+	s.Commands++
+	switch {
+	case e.PolicyResult == "deny":
+		s.Denied++
+	case e.PolicyResult == "escalate":
+		s.Escalated++
+	case e.ExitCode != 0:
+		s.Failed++
+	}
+}
+
+// String renders the summary on one line.
+func (s *GroupSummary) String() string {
+	what := s.Kind
+	if s.Label != "" {
+		what += " " + s.Label
+	}
+	return fmt.Sprintf("%s: %d commands (%d failed, %d denied, %d escalated)",
+		what, s.Commands, s.Failed, s.Denied, s.Escalated)
+}
+
+// GroupKey is the group an entry belongs to: the plan or batch it was
+// part of, else its work session, else none ("").
+func GroupKey(e Entry) string {
+	if e.Group != "" {
+		return e.Group
+	}
+	return e.Session
+}
+
+// Unit is one run of a transcript: a group of related entries, or a lone
+// entry (Key "").
+type Unit struct {
+	Key     string
+	Entries []Entry
+	Summary *Entry // the entry closing the group, if it has been closed
+}
+
+// Units gathers entries into units in order of each unit's first entry.
+// Entries of a group that interleave with other commands are gathered
+// into the group.
+func Units(entries []Entry) []Unit {
+	var units []Unit
+	index := map[string]int{}
+	for _, e := range entries {
+		key := GroupKey(e)
+		i, ok := index[key]
+		if key == "" || !ok {
+			i = len(units)
+			units = append(units, Unit{Key: key})
+			if key != "" {
+				index[key] = i
+			}
+		}
+		if e.Summary != nil {
+			summary := e
+			units[i].Summary = &summary
+			continue
+		}
+		units[i].Entries = append(units[i].Entries, e)
+	}
+	return units
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	entries := []Entry{
+		{Seq: 4, PolicyResult: "allow"},
+		{Seq: 5, PolicyResult: "allow", ExitCode: 1},
+		{Seq: 6, PolicyResult: "deny", ExitCode: 126},
+		{Seq: 7, PolicyResult: "escalate", ExitCode: 125},
+		{Seq: 8, Summary: &GroupSummary{Kind: GroupPlan}},
+	}
+	s := Summarize(GroupPlan, "release", entries)
+	want := GroupSummary{Kind: GroupPlan, Label: "release", Commands: 4, Failed: 1, Denied: 1, Escalated: 1, FirstSeq: 4}
+	if *s != want {
+		t.Errorf("Summarize = %+v, want %+v", *s, want)
+	}
+	if got := s.String(); got != "plan release: 4 commands (1 failed, 1 denied, 1 escalated)" {
+		t.Errorf("String = %q", got)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	entries := []Entry{
+		{Seq: 1, Pipeline: "ls"},
+		{Seq: 2, Pipeline: "make", Group: "plan-1", Session: "s"},
+		{Seq: 3, Pipeline: "pwd"},
+		{Seq: 4, Pipeline: "make test", Group: "plan-1", Session: "s"},
+		{Seq: 5, Pipeline: "go vet", Session: "s"},
+		{Seq: 6, Pipeline: "# plan", Group: "plan-1", Summary: &GroupSummary{Kind: GroupPlan}},
+	}
+	units := Units(entries)
+	var got []string
+	for _, u := range units {
+		var seqs []string
+		for _, e := range u.Entries {
+			seqs = append(seqs, e.Pipeline)
+		}
+		s := u.Key + ":" + strings.Join(seqs, ",")
+		if u.Summary != nil {
+			s += "+summary"
+		}
+		got = append(got, s)
+	}
+	want := []string{":ls", "plan-1:make,make test+summary", ":pwd", "s:go vet"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Units = %q, want %q", got, want)
+	}
+}
+
+func TestWriteTranscript_Groups(t *testing.T) {
+	entries := []Entry{
+		{Seq: 1, Pipeline: "ls", PolicyResult: "allow"},
+		{Seq: 2, Pipeline: "make", PolicyResult: "allow", Group: "plan-1"},
+		{Seq: 3, Pipeline: "# plan <x>", Group: "plan-1", Summary: &GroupSummary{Kind: GroupPlan, Label: "<x>", Commands: 1}},
+		{Seq: 4, Pipeline: "go vet", PolicyResult: "deny", Session: "s"},
+		{Seq: 5, Pipeline: "false", PolicyResult: "allow", ExitCode: 1, Group: "batch-1"},
+	}
+	var md strings.Builder
+	if err := WriteTranscript(&md, "t", entries, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"4 commands from",
+		"## #1",
+		"<summary>#3 plan &lt;x&gt;: 1 commands (0 failed, 0 denied, 0 escalated)</summary>",
+		"### #2",
+		"<summary>session s: 1 commands (0 failed, 1 denied, 0 escalated) (not closed)</summary>",
+		"### #4",
+		"<summary>batch batch-1: 1 commands (1 failed, 0 denied, 0 escalated) (not closed)</summary>",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+	if strings.Contains(md.String(), "#3 —") {
+		t.Errorf("summary entry rendered as a command:\n%s", md.String())
+	}
+
+	var html strings.Builder
+	if err := WriteTranscript(&html, "t", entries, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "<details open>\n<summary>#3 plan &lt;x&gt;: 1 commands") {
+		t.Errorf("html missing plan group:\n%s", html.String())
+	}
+}
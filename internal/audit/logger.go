@@ -4,14 +4,18 @@
 package audit
 
 import (
+	"bufio"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/marcelocantos/doit/internal/clock"
 )
 
 const genesisInput = "doit-genesis"
@@ -20,21 +24,101 @@ const genesisInput = "doit-genesis"
 // exceeded MaxSizeBytes.
 const sizeCheckInterval = 100
 
-// Logger is an append-only, hash-chained audit log writer.
+// DefaultFsyncInterval is the flush period used by FsyncInterval and
+// FsyncNever when LoggerOptions.FsyncInterval is zero.
+const DefaultFsyncInterval = time.Second
+
+// FsyncPolicy controls when buffered audit entries are flushed and synced
+// to stable storage.
+type FsyncPolicy int
+
+const (
+	FsyncAlways   FsyncPolicy = iota // flush and fsync after every entry
+	FsyncInterval                    // flush and fsync periodically
+	FsyncNever                       // flush periodically, leave syncing to the OS
+)
+
+func (p FsyncPolicy) String() string {
+	switch p {
+	case FsyncAlways:
+		return "always"
+	case FsyncInterval:
+		return "interval"
+	case FsyncNever:
+		return "never"
+	default:
+		return fmt.Sprintf("fsync(%d)", int(p))
+	}
+}
+
+// ParseFsyncPolicy converts a string to an FsyncPolicy. The empty string
+// selects FsyncAlways.
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch s {
+	case "", "always":
+		return FsyncAlways, nil
+	case "interval":
+		return FsyncInterval, nil
+	case "never":
+		return FsyncNever, nil
+	default:
+		return 0, fmt.Errorf("unknown fsync policy: %q (want always, interval, or never)", s)
+	}
+}
+
+// LoggerOptions configures OpenLogger.
+type LoggerOptions struct {
+	MaxSizeBytes  int64         // 0 = unlimited
+	Fsync         FsyncPolicy   // default FsyncAlways
+	FsyncInterval time.Duration // flush period for FsyncInterval/FsyncNever; 0 = DefaultFsyncInterval
+	Clock         clock.Clock   // stamps entries; nil = the system clock
+
+	// OnError, if set, is told of failures no Log call returns: those of
+	// the periodic flush under FsyncInterval and FsyncNever.
+	OnError func(error)
+
+	// OnWrite, if set, is given each entry as it is written. It is called
+	// with the logger locked, so it must not log.
+	OnWrite func(*Entry)
+}
+
+// ErrFull is returned by Log for an entry not written because the log has
+// reached its size limit.
+var ErrFull = errors.New("audit log has reached its size limit")
+
+// Logger is an append-only, hash-chained audit log writer. It keeps the
+// log file open for its lifetime and writes through a buffer; when entries
+// reach the file is governed by the configured FsyncPolicy.
 type Logger struct {
 	mu           sync.Mutex
 	path         string
+	f            *os.File
+	w            *bufio.Writer
+	fsync        FsyncPolicy
 	seq          uint64
 	prevHash     string
 	maxSizeBytes int64 // 0 = unlimited
 	writesSince  int   // writes since last size check
 	sizeLimitHit bool  // true once the limit has been reached
+	onError      func(error)
+	onWrite      func(*Entry)
+	clock        clock.Clock
+
+	stop chan struct{} // closed by Close to end the flush loop
+	done chan struct{} // closed when the flush loop exits
 }
 
-// NewLogger opens or creates an audit log at the given path.
-// It reads the last entry to resume the hash chain.
+// NewLogger opens or creates an audit log at the given path with
+// FsyncAlways. It reads the last entry to resume the hash chain.
 // maxSizeBytes controls the maximum file size; 0 means unlimited.
 func NewLogger(path string, maxSizeBytes int64) (*Logger, error) {
+	return OpenLogger(path, LoggerOptions{MaxSizeBytes: maxSizeBytes})
+}
+
+// OpenLogger opens or creates an audit log at the given path. It resumes
+// the hash chain from the last complete entry; a torn final line left by
+// a crash mid-write is truncated away before appending.
+func OpenLogger(path string, opts LoggerOptions) (*Logger, error) {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, fmt.Errorf("create audit dir: %w", err)
@@ -42,25 +126,70 @@ func NewLogger(path string, maxSizeBytes int64) (*Logger, error) {
 
 	l := &Logger{
 		path:         path,
+		fsync:        opts.Fsync,
 		prevHash:     genesisHash(),
-		maxSizeBytes: maxSizeBytes,
+		maxSizeBytes: opts.MaxSizeBytes,
+		clock:        clock.Or(opts.Clock),
+		onError:      opts.OnError,
+		onWrite:      opts.OnWrite,
 	}
 
-	// Read existing log to find last entry.
-	if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
-		lines := splitLines(data)
-		if len(lines) > 0 {
-			var last Entry
-			if err := json.Unmarshal(lines[len(lines)-1], &last); err == nil {
-				l.seq = last.Seq
-				l.prevHash = last.Hash
-			}
+	last, err := recoverTail(path)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		var entry Entry
+		if err := json.Unmarshal(last, &entry); err == nil {
+			l.seq = entry.Seq
+			l.prevHash = entry.Hash
+		}
+	}
+
+	// O_APPEND keeps entries at the end of the file even if something else
+	// appends to it between our writes.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	l.f = f
+	l.w = bufio.NewWriter(f)
+
+	if l.fsync != FsyncAlways {
+		interval := opts.FsyncInterval
+		if interval <= 0 {
+			interval = DefaultFsyncInterval
 		}
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.flushLoop(interval)
 	}
 
 	return l, nil
 }
 
+// flushLoop periodically flushes buffered entries until Close is called.
+func (l *Logger) flushLoop(interval time.Duration) {
+	defer close(l.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.mu.Lock()
+			if err := l.flushLocked(); err != nil {
+				log.Printf("doit: audit log %s: flush: %v", l.path, err)
+				if l.onError != nil {
+					l.onError(fmt.Errorf("flush audit log: %w", err))
+				}
+			}
+			l.mu.Unlock()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
 // checkSize returns true if the log file has exceeded the configured size limit.
 // Must be called with l.mu held.
 func (l *Logger) checkSize() bool {
@@ -75,11 +204,11 @@ func (l *Logger) checkSize() bool {
 		return false
 	}
 	l.writesSince = 0
-	info, err := os.Stat(l.path)
+	info, err := l.f.Stat()
 	if err != nil {
 		return false
 	}
-	if info.Size() >= l.maxSizeBytes {
+	if info.Size()+int64(l.w.Buffered()) >= l.maxSizeBytes {
 		log.Printf("doit: audit log %s has reached size limit (%d bytes); further entries will not be written", l.path, l.maxSizeBytes)
 		l.sizeLimitHit = true
 		return true
@@ -93,14 +222,17 @@ func (l *Logger) Log(pipeline string, segments, tiers []string, exitCode int, er
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if l.f == nil {
+		return fmt.Errorf("audit log %s is closed", l.path)
+	}
+
 	if l.checkSize() {
-		return nil // silently skip when size limit reached (warning already logged)
+		return fmt.Errorf("%s: %w", l.path, ErrFull)
 	}
 
-	l.seq++
 	entry := Entry{
-		Seq:      l.seq,
-		Time:     time.Now().UTC(),
+		Seq:      l.seq + 1,
+		Time:     l.clock.Now().UTC(),
 		PrevHash: l.prevHash,
 		Pipeline: pipeline,
 		Segments: segments,
@@ -115,13 +247,29 @@ func (l *Logger) Log(pipeline string, segments, tiers []string, exitCode int, er
 		entry.PolicyLevel = opts.PolicyLevel
 		entry.PolicyResult = opts.PolicyResult
 		entry.PolicyRuleID = opts.PolicyRuleID
+		entry.Conditions = opts.Conditions
+		entry.Profile = opts.Profile
+		entry.Tier = opts.Tier
+		entry.Severity = opts.Severity
 		entry.Justification = opts.Justification
 		entry.SafetyArg = opts.SafetyArg
+		entry.Session = opts.Session
+		entry.Agent = opts.Agent
+		entry.Grant = opts.Grant
+		entry.RetryRule = opts.RetryRule
+		entry.RetrySeq = opts.RetrySeq
+		entry.Signal = opts.Signal
+		entry.Files = opts.Files
+		entry.Redirects = opts.Redirects
+		entry.LLM = opts.LLM
+		entry.Group = opts.Group
+		entry.Summary = opts.Summary
+		entry.Hook = opts.Hook
+		entry.Violations = opts.Violations
 	}
 
 	// Compute hash with Hash field empty.
 	entry.Hash = computeHash(entry)
-	l.prevHash = entry.Hash
 
 	data, err := json.Marshal(entry)
 	if err != nil {
@@ -129,23 +277,118 @@ func (l *Logger) Log(pipeline string, segments, tiers []string, exitCode int, er
 	}
 	data = append(data, '\n')
 
-	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
-	if err != nil {
-		return fmt.Errorf("open audit log: %w", err)
+	if _, err := l.w.Write(data); err != nil {
+		return fmt.Errorf("write audit entry: %w", err)
+	}
+	// The chain only advances once the entry is in the buffer, so a failed
+	// write leaves seq/prevHash pointing at the last entry actually written.
+	l.seq = entry.Seq
+	l.prevHash = entry.Hash
+	if l.onWrite != nil {
+		l.onWrite(&entry)
 	}
-	defer f.Close()
 
-	if _, err := f.Write(data); err != nil {
-		return fmt.Errorf("write audit entry: %w", err)
+	if l.fsync == FsyncAlways {
+		if err := l.flushLocked(); err != nil {
+			return fmt.Errorf("write audit entry: %w", err)
+		}
 	}
 	return nil
 }
 
+// Flush writes any buffered entries to the file and, unless the policy is
+// FsyncNever, syncs them to stable storage. Readers that open the log by
+// path (Query, Tail, Verify) should be preceded by Flush when the logger
+// is live in the same process.
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	return l.flushLocked()
+}
+
+// flushLocked flushes the buffer and syncs per policy. Must be called with
+// l.mu held.
+func (l *Logger) flushLocked() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if l.fsync == FsyncNever {
+		return nil
+	}
+	return l.f.Sync()
+}
+
+// Close flushes buffered entries and closes the log file. The logger must
+// not be used afterwards.
+func (l *Logger) Close() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop = nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.flushLocked()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
 // Path returns the audit log file path.
 func (l *Logger) Path() string {
 	return l.path
 }
 
+// recoverTail repairs the end of the log after a crash and returns its
+// last complete entry line (nil if there is none). An unterminated final
+// line is either a complete entry whose newline never reached the disk
+// (kept and terminated) or a torn write (truncated away).
+func recoverTail(path string) ([]byte, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat audit log: %w", err)
+	}
+	last, end, err := lastLine(f, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	if info.Size() == end {
+		return last, nil
+	}
+
+	rest := make([]byte, info.Size()-end)
+	if _, err := f.ReadAt(rest, end); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	var entry Entry
+	if json.Unmarshal(rest, &entry) == nil && entry.Hash != "" {
+		if _, err := f.WriteAt([]byte{'\n'}, info.Size()); err != nil {
+			return nil, fmt.Errorf("terminate audit entry: %w", err)
+		}
+		return rest, nil
+	}
+	log.Printf("doit: audit log %s: truncating %d bytes of torn trailing entry", path, len(rest))
+	if err := f.Truncate(end); err != nil {
+		return nil, fmt.Errorf("truncate torn audit entry: %w", err)
+	}
+	return last, nil
+}
+
 func genesisHash() string {
 	h := sha256.Sum256([]byte(genesisInput))
 	return fmt.Sprintf("%x", h)
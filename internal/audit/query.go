@@ -5,6 +5,8 @@ package audit
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"time"
 )
@@ -17,30 +19,37 @@ type Filter struct {
 	After        time.Time
 	Before       time.Time
 	Cap          string
+	Session      string
+	Group        string
 }
 
 // Query reads the audit log at path and returns entries matching f. If f is
 // nil, all entries are returned. If the file does not exist, nil, nil is
-// returned.
+// returned. The log is streamed, so memory use is bounded by the matching
+// entries rather than the size of the file.
 func Query(path string, f *Filter) ([]Entry, error) {
-	data, err := os.ReadFile(path)
+	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	defer file.Close()
 
-	lines := splitLines(data)
 	var entries []Entry
-	for _, line := range lines {
+	err = scanLines(file, func(line []byte, _ int64) error {
 		var entry Entry
 		if err := json.Unmarshal(line, &entry); err != nil {
-			continue
+			return nil
 		}
 		if f == nil || matches(entry, f) {
 			entries = append(entries, entry)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return entries, nil
 }
@@ -58,6 +67,12 @@ func matches(e Entry, f *Filter) bool {
 	if !f.Before.IsZero() && !e.Time.Before(f.Before) {
 		return false
 	}
+	if f.Session != "" && e.Session != f.Session {
+		return false
+	}
+	if f.Group != "" && e.Group != f.Group {
+		return false
+	}
 	if f.Cap != "" {
 		found := false
 		for _, seg := range e.Segments {
@@ -72,3 +87,35 @@ func matches(e Entry, f *Filter) bool {
 	}
 	return true
 }
+
+// Lookup returns the entry with sequence number seq from the audit log at
+// path. It returns an error if the log cannot be read or no entry has that
+// sequence number.
+func Lookup(path string, seq uint64) (*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var found *Entry
+	errFound := errors.New("found")
+	err = scanLines(file, func(line []byte, _ int64) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil
+		}
+		if entry.Seq == seq {
+			found = &entry
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no audit entry with seq %d", seq)
+	}
+	return found, nil
+}
@@ -215,3 +215,26 @@ func TestQueryEmptyLog(t *testing.T) {
 		t.Fatalf("expected 0 entries from empty log, got %d", len(entries))
 	}
 }
+
+func TestLookup(t *testing.T) {
+	path, _ := seedTestLog(t)
+	all, err := Query(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := all[2]
+	got, err := Lookup(path, want.Seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Pipeline != "rm -rf /tmp/x" || got.Hash != want.Hash {
+		t.Errorf("Lookup(%d) = %q (%s), want %q", want.Seq, got.Pipeline, got.Hash, want.Pipeline)
+	}
+
+	if _, err := Lookup(path, 9999); err == nil || !strings.Contains(err.Error(), "no audit entry") {
+		t.Errorf("expected missing-seq error, got %v", err)
+	}
+	if _, err := Lookup(filepath.Join(t.TempDir(), "missing.jsonl"), 1); err == nil {
+		t.Error("expected error for missing log")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+// maxLineSize bounds a single audit entry. Entries carry the full command
+// string plus justification text, so the bufio.Scanner default (64 KiB) is
+// too small; anything larger than this is treated as corruption.
+const maxLineSize = 16 * 1024 * 1024
+
+// scanLines calls fn for each non-empty line in r, stopping at the first
+// error fn returns. end is the number of bytes of r consumed up to and
+// including the line's newline. The line slice is only valid for the
+// duration of the call.
+func scanLines(r io.Reader, fn func(line []byte, end int64) error) error {
+	var off int64
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		off += int64(advance)
+		return advance, token, err
+	})
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line, off); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// tailChunk is the read size used when scanning a file backwards.
+const tailChunk = 64 * 1024
+
+// tailLines returns up to the last n non-empty lines of f in file order,
+// reading backwards from the end in fixed-size chunks.
+func tailLines(f *os.File, n int) ([][]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return tailLinesAt(f, info.Size(), n)
+}
+
+// tailLinesAt is tailLines over the first size bytes of f.
+func tailLinesAt(f *os.File, size int64, n int) ([][]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	var (
+		lines [][]byte // collected in reverse order
+		rest  []byte   // partial line carried over from the previous chunk
+		pos   = size
+	)
+	for pos > 0 && len(lines) < n {
+		size := int64(tailChunk)
+		if size > pos {
+			size = pos
+		}
+		pos -= size
+		buf := make([]byte, size, size+int64(len(rest)))
+		if _, err := f.ReadAt(buf, pos); err != nil {
+			return nil, err
+		}
+		buf = append(buf, rest...)
+
+		// Every line after the first newline in buf is complete; the
+		// leading fragment may continue in the preceding chunk.
+		for len(lines) < n {
+			i := bytes.LastIndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			if line := buf[i+1:]; len(line) > 0 {
+				lines = append(lines, line)
+			}
+			buf = buf[:i]
+		}
+		rest = buf
+	}
+	if pos == 0 && len(rest) > 0 && len(lines) < n {
+		lines = append(lines, rest)
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines, nil
+}
+
+// lastLine returns the last complete (newline-terminated) line in the
+// first size bytes of f and the offset just past its newline. Bytes after
+// that offset belong to a torn write. A nil line means there is no
+// complete entry.
+func lastLine(f *os.File, size int64) (line []byte, end int64, err error) {
+	// Find the final newline: everything after it is a torn write.
+	end = -1
+	var tail []byte // bytes from the current read position to end
+	pos := size
+	for pos > 0 {
+		n := int64(tailChunk)
+		if n > pos {
+			n = pos
+		}
+		pos -= n
+		buf := make([]byte, n)
+		if _, err := f.ReadAt(buf, pos); err != nil {
+			return nil, 0, err
+		}
+		tail = append(buf, tail...)
+
+		if end < 0 {
+			i := bytes.LastIndexByte(tail, '\n')
+			if i < 0 {
+				continue
+			}
+			end = pos + int64(i) + 1
+		}
+
+		// Look for the newline preceding the final line, skipping blank lines.
+		body := bytes.TrimRight(tail[:end-pos], "\n")
+		if len(body) == 0 {
+			if pos == 0 {
+				return nil, end, nil
+			}
+			continue
+		}
+		if j := bytes.LastIndexByte(body, '\n'); j >= 0 {
+			return body[j+1:], end, nil
+		}
+		if pos == 0 {
+			return body, end, nil
+		}
+	}
+	if end < 0 {
+		end = 0
+	}
+	return nil, end, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LLMCall records one call the Level 3 gatekeeper made. The prompt and
+// response are too large for the log itself, so it holds their SHA-256
+// and the text is spilled, content-addressed, into the directory given by
+// SpillDir. The hash chain then vouches for the spilled text too.
+type LLMCall struct {
+	Stage    string `json:"stage"`              // "fast", "deep", or "judge N"
+	Prompt   string `json:"prompt"`             // SHA-256 of the prompt
+	Response string `json:"response,omitempty"` // SHA-256 of the raw response
+	Error    string `json:"error,omitempty"`    // the call failed
+}
+
+// SpillDir returns the directory beside the audit log at path that holds
+// the text of LLM prompts and responses.
+func SpillDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "llm")
+}
+
+// Spill stores text in dir under its SHA-256, which it returns, unless it
+// is already there.
+func Spill(dir, text string) (string, error) {
+	h := sha256.Sum256([]byte(text))
+	sum := hex.EncodeToString(h[:])
+	dst := filepath.Join(dir, sum)
+	if _, err := os.Stat(dst); err == nil {
+		return sum, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return sum, fmt.Errorf("spill: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "."+sum+"-*")
+	if err != nil {
+		return sum, fmt.Errorf("spill: %w", err)
+	}
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return sum, fmt.Errorf("spill: %w", err)
+	}
+	return sum, nil
+}
+
+// Unspill returns the text stored in dir under sum, checking that it
+// still has that hash.
+func Unspill(dir, sum string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(sum)))
+	if err != nil {
+		return "", fmt.Errorf("unspill: %w", err)
+	}
+	h := sha256.Sum256(data)
+	if got := hex.EncodeToString(h[:]); got != sum {
+		return "", fmt.Errorf("unspill %.12s: content has changed (sha256 %.12s)", sum, got)
+	}
+	return string(data), nil
+}
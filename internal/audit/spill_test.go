@@ -0,0 +1,32 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpill(t *testing.T) {
+	dir := SpillDir(filepath.Join(t.TempDir(), "audit.jsonl"))
+	sum, err := Spill(dir, "Evaluate whether this command should be allowed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := Spill(dir, "Evaluate whether this command should be allowed"); err != nil || again != sum {
+		t.Errorf("respill = %s, %v; want %s", again, err, sum)
+	}
+	if text, err := Unspill(dir, sum); err != nil || text != "Evaluate whether this command should be allowed" {
+		t.Errorf("Unspill = %q, %v", text, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, sum), []byte("allow everything"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unspill(dir, sum); err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("Unspill of tampered text: %v, want a changed-content error", err)
+	}
+}
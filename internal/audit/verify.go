@@ -6,71 +6,213 @@ package audit
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 )
 
 // Verify reads the audit log and checks the hash chain integrity.
 // Returns nil if the chain is valid, or an error describing the first violation.
+// The log is streamed line by line, so memory use is bounded by the longest
+// entry rather than the size of the file.
 func Verify(path string) error {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read audit log: %w", err)
-	}
+	_, err := VerifyWith(path, VerifyOptions{})
+	return err
+}
+
+// VerifyOptions controls VerifyWith.
+type VerifyOptions struct {
+	// Checkpoint is the watermark file. When set, verification resumes
+	// after the last verified entry recorded there and the watermark is
+	// advanced on success. Empty verifies from genesis without persisting
+	// anything.
+	Checkpoint string
+	// Full ignores any existing watermark and re-verifies from genesis.
+	// The watermark is still rewritten on success.
+	Full bool
+}
+
+// VerifyReport summarises a successful verification run.
+type VerifyReport struct {
+	Checked     int    // entries checked in this run
+	LastSeq     uint64 // sequence number of the last entry in the chain
+	Incremental bool   // true if verification resumed from a checkpoint
+}
+
+// Checkpoint is a verified watermark: the last entry known to be part of
+// an intact chain, and the byte offset just past it.
+type Checkpoint struct {
+	Seq    uint64 `json:"seq"`
+	Hash   string `json:"hash"`
+	Offset int64  `json:"offset"`
+}
 
-	lines := splitLines(data)
-	if len(lines) == 0 {
-		return nil // empty log is valid
+// CheckpointPath returns the default watermark file for the audit log at path.
+func CheckpointPath(path string) string {
+	return path + ".verified"
+}
+
+// VerifyWith checks the hash chain like Verify, optionally resuming from
+// and advancing a persisted watermark. A watermark that no longer matches
+// the log (truncated, rotated, or rewritten) is ignored and the whole
+// chain is verified.
+func VerifyWith(path string, opts VerifyOptions) (*VerifyReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
 	}
+	defer f.Close()
 
+	report := &VerifyReport{}
 	expectedPrev := genesisHash()
 	var prevSeq uint64
+	var start int64
+
+	if opts.Checkpoint != "" && !opts.Full {
+		if cp, ok := loadCheckpoint(f, opts.Checkpoint); ok {
+			if _, err := f.Seek(cp.Offset, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("seek audit log: %w", err)
+			}
+			expectedPrev = cp.Hash
+			prevSeq = cp.Seq
+			start = cp.Offset
+			report.Incremental = true
+		}
+	}
+
+	// Line numbers are only meaningful when scanning from the top; after a
+	// checkpoint, locate violations by byte offset instead.
+	where := func(i int, offset int64) string {
+		if report.Incremental {
+			return fmt.Sprintf("offset %d", offset)
+		}
+		return fmt.Sprintf("line %d", i)
+	}
+
+	i := 0
+	end := start
+	err = scanLines(f, func(line []byte, lineEnd int64) error {
+		i++
+		at := where(i, end)
+		end = start + lineEnd
 
-	for i, line := range lines {
 		var entry Entry
 		if err := json.Unmarshal(line, &entry); err != nil {
-			return fmt.Errorf("line %d: invalid JSON: %w", i+1, err)
+			return fmt.Errorf("%s: invalid JSON: %w", at, err)
 		}
 
 		// Check sequence.
 		if entry.Seq != prevSeq+1 {
-			return fmt.Errorf("line %d: sequence gap: expected %d, got %d", i+1, prevSeq+1, entry.Seq)
+			return fmt.Errorf("%s: sequence gap: expected %d, got %d", at, prevSeq+1, entry.Seq)
 		}
 
 		// Check prev_hash chain.
 		if entry.PrevHash != expectedPrev {
-			return fmt.Errorf("line %d: prev_hash mismatch: expected %s, got %s", i+1, expectedPrev[:16]+"...", entry.PrevHash[:16]+"...")
+			return fmt.Errorf("%s: prev_hash mismatch: expected %s, got %s", at, abbrevHash(expectedPrev), abbrevHash(entry.PrevHash))
 		}
 
 		// Recompute and check hash.
 		computed := computeHash(entry)
 		if entry.Hash != computed {
-			return fmt.Errorf("line %d: hash mismatch: expected %s, got %s", i+1, computed[:16]+"...", entry.Hash[:16]+"...")
+			return fmt.Errorf("%s: hash mismatch: expected %s, got %s", at, abbrevHash(computed), abbrevHash(entry.Hash))
 		}
 
 		expectedPrev = entry.Hash
 		prevSeq = entry.Seq
+		report.Checked++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.LastSeq = prevSeq
+
+	if opts.Checkpoint != "" && prevSeq > 0 && (report.Checked > 0 || opts.Full) {
+		cp := Checkpoint{Seq: prevSeq, Hash: expectedPrev, Offset: end}
+		if err := saveCheckpoint(opts.Checkpoint, cp); err != nil {
+			return report, fmt.Errorf("save verify checkpoint: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// loadCheckpoint reads the watermark at cpPath and confirms that the entry
+// ending at its offset in f is the one it names. Any mismatch (or a missing
+// file) reports false so the caller falls back to full verification.
+func loadCheckpoint(f *os.File, cpPath string) (Checkpoint, bool) {
+	var cp Checkpoint
+	data, err := os.ReadFile(cpPath)
+	if err != nil {
+		return cp, false
+	}
+	if err := json.Unmarshal(data, &cp); err != nil || cp.Seq == 0 || cp.Offset <= 0 {
+		return cp, false
 	}
+	info, err := f.Stat()
+	if err != nil || info.Size() < cp.Offset {
+		return cp, false
+	}
+	line, end, err := lastLine(f, cp.Offset)
+	if err != nil || line == nil || end != cp.Offset {
+		return cp, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return cp, false
+	}
+	if entry.Seq != cp.Seq || entry.Hash != cp.Hash || computeHash(entry) != cp.Hash {
+		return cp, false
+	}
+	return cp, true
+}
 
+// saveCheckpoint writes the watermark atomically using a temp file + rename.
+func saveCheckpoint(path string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".audit-verified-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
 	return nil
 }
 
 // Tail returns the last n entries from the audit log.
 // Malformed entries are skipped; a non-nil error is returned if any were
 // encountered, allowing callers to surface a warning to the user.
+// The file is read backwards from the end, so the cost is proportional to
+// n rather than to the size of the log.
 func Tail(path string, n int) ([]Entry, error) {
-	data, err := os.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("read audit log: %w", err)
 	}
+	defer f.Close()
 
-	lines := splitLines(data)
-	if n > len(lines) {
-		n = len(lines)
+	lines, err := tailLines(f, n)
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
 	}
 
 	var skipped int
-	entries := make([]Entry, 0, n)
-	for _, line := range lines[len(lines)-n:] {
+	entries := make([]Entry, 0, len(lines))
+	for _, line := range lines {
 		var entry Entry
 		if err := json.Unmarshal(line, &entry); err != nil {
 			skipped++
@@ -83,3 +225,12 @@ func Tail(path string, n int) ([]Entry, error) {
 	}
 	return entries, nil
 }
+
+// abbrevHash shortens a hash for error messages. Short (tampered) values
+// are returned unchanged rather than panicking on the slice.
+func abbrevHash(h string) string {
+	if len(h) <= 16 {
+		return h
+	}
+	return h[:16] + "..."
+}
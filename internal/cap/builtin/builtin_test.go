@@ -6,9 +6,16 @@ package builtin
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/marcelocantos/doit/internal/cap"
 )
@@ -18,7 +25,7 @@ func TestRegisterAll(t *testing.T) {
 	RegisterAll(r)
 
 	caps := r.All()
-	const expectedCount = 19
+	const expectedCount = 33
 	if len(caps) != expectedCount {
 		t.Fatalf("expected %d capabilities, got %d", expectedCount, len(caps))
 	}
@@ -39,6 +46,31 @@ func TestRegisterAll(t *testing.T) {
 }
 
 
+func TestHelpMetadata(t *testing.T) {
+	r := cap.NewRegistry()
+	RegisterAll(r)
+
+	for _, c := range r.All() {
+		if _, ok := c.(cap.Documented); !ok {
+			t.Errorf("capability %q does not implement cap.Documented", c.Name())
+			continue
+		}
+		h := cap.HelpFor(c)
+		if len(h.Examples) == 0 {
+			t.Errorf("capability %q has no examples", c.Name())
+		}
+		if h.TierRationale == "" {
+			t.Errorf("capability %q has no tier rationale", c.Name())
+		}
+		// Flags documented as denied must actually be rejected.
+		for _, f := range h.Flags {
+			if f.Denied && c.Validate([]string{f.Name, "x"}) == nil {
+				t.Errorf("capability %q: flag %s documented as denied but Validate accepts it", c.Name(), f.Name)
+			}
+		}
+	}
+}
+
 func TestExitError(t *testing.T) {
 	e := &ExitError{Code: 42}
 	if msg := e.Error(); msg != "" {
@@ -83,6 +115,21 @@ func TestRunExternalNonZeroExit(t *testing.T) {
 	if exitErr.Code != 42 {
 		t.Errorf("ExitError.Code = %d, want 42", exitErr.Code)
 	}
+	if exitErr.Signal != "" {
+		t.Errorf("ExitError.Signal = %q, want empty", exitErr.Signal)
+	}
+}
+
+func TestRunExternalKilledBySignal(t *testing.T) {
+	err := runExternal(context.Background(), "sh", []string{"-c", "kill -KILL $$"}, nil, io.Discard, io.Discard)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %T: %v", err, err)
+	}
+	if exitErr.Code != 137 || exitErr.Signal != "SIGKILL" {
+		t.Errorf("ExitError = {%d %q}, want {137 \"SIGKILL\"}", exitErr.Code, exitErr.Signal)
+	}
 }
 
 func TestRunExternalWithCwd(t *testing.T) {
@@ -135,6 +182,28 @@ func TestGitValidate(t *testing.T) {
 	}
 }
 
+func TestTierFor(t *testing.T) {
+	for _, tc := range []struct {
+		c    cap.Capability
+		args string
+		want cap.Tier
+	}{
+		{&Git{}, "-C repo status --short", cap.TierRead},
+		{&Git{}, "commit -m x", cap.TierWrite},
+		{&Git{}, "reset HEAD~", cap.TierWrite},
+		{&Git{}, "reset --hard", cap.TierDangerous},
+		{&Git{}, "-c x=y push origin main", cap.TierDangerous},
+		{&GoCmd{}, "test ./...", cap.TierBuild},
+		{&GoCmd{}, "env GOPATH", cap.TierRead},
+		{&GoCmd{}, "mod tidy", cap.TierWrite},
+		{&Cat{}, "x", cap.TierRead},
+	} {
+		if got := cap.TierOf(tc.c, strings.Fields(tc.args)); got != tc.want {
+			t.Errorf("%s %s: tier %s, want %s", tc.c.Name(), tc.args, got, tc.want)
+		}
+	}
+}
+
 func TestMakeValidate(t *testing.T) {
 	m := &Make{}
 
@@ -190,3 +259,440 @@ func TestRmValidate(t *testing.T) {
 		t.Errorf("Rm.Validate([-rf dir/]) returned unexpected error: %v", err)
 	}
 }
+
+func TestReadValidate(t *testing.T) {
+	r := &Read{}
+	for _, args := range [][]string{{"main.go"}, {"main.go:10-20"}, {"-N", "a.go:5", "b.go:7-"}, {"-c", "100", "x"}, {"-c0", "x"}} {
+		if err := r.Validate(args); err != nil {
+			t.Errorf("Read.Validate(%q) returned unexpected error: %v", args, err)
+		}
+	}
+	for _, args := range [][]string{nil, {"-N"}, {"a.go:0"}, {"a.go:20-10"}, {"-c", "x", "a.go"}, {"-z", "a.go"}} {
+		if err := r.Validate(args); err == nil {
+			t.Errorf("Read.Validate(%q) should return error", args)
+		}
+	}
+}
+
+func TestReadRun(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "odd:name"), []byte("colon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := cap.NewCwdContext(context.Background(), dir)
+
+	tests := []struct {
+		args   []string
+		stdout string
+		stderr string
+	}{
+		{[]string{"f.txt:3-4"}, "     3\tline 3\n     4\tline 4\n", ""},
+		{[]string{"-N", "f.txt:9-"}, "line 9\nline 10\n", ""},
+		{[]string{"-N", "f.txt:2"}, "line 2\n", ""},
+		{[]string{"-N", "odd:name"}, "colon\n", ""},
+		{[]string{"-N", "-c", "14", "f.txt"}, "line 1\nline 2\n", "read: output truncated at 14 bytes; continue with: read f.txt:3-\n"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if err := (&Read{}).Run(ctx, tt.args, nil, &stdout, &stderr); err != nil {
+			t.Errorf("read %q: %v", tt.args, err)
+			continue
+		}
+		if stdout.String() != tt.stdout || stderr.String() != tt.stderr {
+			t.Errorf("read %q: got stdout %q stderr %q, want %q %q", tt.args, stdout.String(), stderr.String(), tt.stdout, tt.stderr)
+		}
+	}
+
+	if err := (&Read{}).Run(ctx, []string{"f.txt:20-"}, nil, io.Discard, io.Discard); err == nil || !strings.Contains(err.Error(), "has 10 lines") {
+		t.Errorf("range past the end: got %v", err)
+	}
+}
+
+func TestWriteRun(t *testing.T) {
+	root := t.TempDir()
+	backups := t.TempDir()
+	w := &Write{BackupDir: backups}
+	changes := &cap.Changes{}
+	ctx := cap.NewChangesContext(cap.NewCwdContext(context.Background(), root), changes)
+	run := func(stdin string, args ...string) error {
+		return w.Run(ctx, args, strings.NewReader(stdin), io.Discard, io.Discard)
+	}
+
+	if err := run("one\ntwo\nthree\n", "dir/f.txt"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	before := hashString("one\ntwo\nthree\n")
+	patch := "--- a/dir/f.txt\n+++ b/dir/f.txt\n@@ -2,2 +2,2 @@\n two\n-three\n+THREE\n"
+	if err := run(patch, "--patch"); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(root, "dir/f.txt"))
+	if string(got) != "one\ntwo\nTHREE\n" {
+		t.Errorf("after patch: %q", got)
+	}
+	if backup, err := os.ReadFile(filepath.Join(backups, before)); err != nil || string(backup) != "one\ntwo\nthree\n" {
+		t.Errorf("backup: %q, %v", backup, err)
+	}
+	list := changes.List()
+	if len(list) != 2 || list[0].Before != "" || list[1].Before != before || list[1].After != hashString(string(got)) {
+		t.Errorf("changes = %+v", list)
+	}
+
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(root, ".git"), 0o755)
+	// The workspace is the home directory, which holds doit's configuration.
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, ".config"))
+	os.MkdirAll(filepath.Join(root, ".config", "doit"), 0o755)
+	if err := os.Symlink(filepath.Join(root, ".config", "doit"), filepath.Join(root, "config-link")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		stdin string
+		args  []string
+		want  string
+	}{
+		{"x", []string{"../x.txt"}, "outside the workspace"},
+		{"x", []string{filepath.Join(outside, "x.txt")}, "outside the workspace"},
+		{"x", []string{"escape/x.txt"}, "resolves outside the workspace"},
+		{"x", []string{".git/config"}, ".git"},
+		{"x", []string{".config/doit/config.yaml"}, "doit's own configuration"},
+		{"x", []string{"bin/tool"}, "directory of executables"},
+		{"x", []string{"config-link/config.yaml"}, "resolves into doit's own configuration"},
+		{"", []string{"new.txt"}, "empty"},
+		{"--- a/dir/f.txt\n+++ /dev/null\n@@ -1,3 +0,0 @@\n-one\n-two\n-THREE\n", []string{"--patch"}, "does not delete"},
+		{"--- a/dir/f.txt\n+++ b/dir/f.txt\n@@ -1,1 +1,1 @@\n-nope\n+yes\n", []string{"--patch"}, "does not apply"},
+	} {
+		if err := run(tt.stdin, tt.args...); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("write %q: got %v, want error containing %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	// The hunk claims line 1, but two lines were added above it since.
+	diff := "--- f\n+++ f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -5,0 +6,1 @@\n+f\n"
+	patches, err := parsePatch(diff)
+	if err != nil || len(patches) != 1 {
+		t.Fatalf("parsePatch: %v (%d patches)", err, len(patches))
+	}
+	got, err := patches[0].apply("x\ny\na\nb\nc\nd\ne\n")
+	if err != nil || got != "x\ny\na\nB\nc\nd\ne\nf\n" {
+		t.Errorf("apply = %q, %v", got, err)
+	}
+
+	// No newline at end of file on either side.
+	diff = "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"
+	patches, err = parsePatch(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := patches[0].apply("a\nb"); err != nil || got != "a\nc" {
+		t.Errorf("apply without trailing newline = %q, %v", got, err)
+	}
+}
+
+func TestSearchArgs(t *testing.T) {
+	rgArgs, limit, err := parseSearchArgs([]string{"-i", "-g", "*.go", "--max", "5", "func New", "internal/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rgArgs, " "); got != "-i -g *.go --no-config --json -e func New -- internal/" || limit != 5 {
+		t.Errorf("got %q limit %d", got, limit)
+	}
+	for _, args := range [][]string{nil, {"-i"}, {"--pre", "sh", "x"}, {"--pre=sh", "x"}, {"--max", "0", "x"}, {"-g"}} {
+		if _, _, err := parseSearchArgs(args); err == nil {
+			t.Errorf("parseSearchArgs(%q) should return error", args)
+		}
+	}
+}
+
+func TestParseRipgrepJSON(t *testing.T) {
+	out := `{"type":"begin","data":{"path":{"text":"a.go"}}}
+{"type":"match","data":{"path":{"text":"a.go"},"lines":{"text":"x := foo(foo)\n"},"line_number":3,"absolute_offset":10,"submatches":[{"match":{"text":"foo"},"start":5,"end":8},{"match":{"text":"foo"},"start":9,"end":12}]}}
+{"type":"match","data":{"path":{"bytes":"Yi5nbw=="},"lines":{"text":"foo\n"},"line_number":1,"absolute_offset":0,"submatches":[{"match":{"text":"foo"},"start":0,"end":3}]}}
+{"type":"end","data":{}}
+{"type":"summary","data":{}}
+`
+	res, err := parseRipgrepJSON(strings.NewReader(out), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchMatch{
+		{Path: "a.go", Line: 3, Column: 6, Match: "foo", Text: "x := foo(foo)"},
+		{Path: "a.go", Line: 3, Column: 10, Match: "foo", Text: "x := foo(foo)"},
+		{Path: "b.go", Line: 1, Column: 1, Match: "foo", Text: "foo"},
+	}
+	if fmt.Sprint(res.Matches) != fmt.Sprint(want) || res.Truncated {
+		t.Errorf("got %+v truncated=%v", res.Matches, res.Truncated)
+	}
+
+	res, _ = parseRipgrepJSON(strings.NewReader(out), 2)
+	if len(res.Matches) != 2 || !res.Truncated {
+		t.Errorf("limit 2: got %d matches truncated=%v", len(res.Matches), res.Truncated)
+	}
+}
+
+func TestSearchRun(t *testing.T) {
+	if _, err := exec.LookPath("rg"); err != nil {
+		t.Skip("rg not installed")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha\nbeta alpha\n"), 0o644)
+	var stdout bytes.Buffer
+	ctx := cap.NewCwdContext(context.Background(), dir)
+	if err := (&Search{}).Run(ctx, []string{"alpha"}, nil, &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var res SearchResult
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil || len(res.Matches) != 2 {
+		t.Errorf("got %s, %v", stdout.String(), err)
+	}
+}
+
+func TestOverviewRun(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"go.mod":               "module x\n",
+		"main.go":              "package main\n",
+		"internal/a/a.go":      "package a\n",
+		"internal/a/deep/b.go": "package deep\n",
+		"docs/guide.md":        "# Guide\n",
+		"build/out.bin":        "ignored\n",
+		".gitignore":           "build/\n",
+	} {
+		p := filepath.Join(root, path)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		os.WriteFile(p, []byte(content), 0o644)
+	}
+	ctx := cap.NewCwdContext(context.Background(), root)
+
+	var stdout bytes.Buffer
+	if err := (&Overview{}).Run(ctx, []string{"-d", "1"}, nil, &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	out := stdout.String()
+	for _, want := range []string{"7 files", "internal/", "docs/", "main.go", "languages: Go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("overview output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "deep/") {
+		t.Errorf("depth 1 listed a second level:\n%s", out)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if err := exec.Command("git", "-C", root, "init", "-q").Run(); err != nil {
+		t.Skip("git init failed")
+	}
+	stdout.Reset()
+	if err := (&Overview{}).Run(ctx, nil, nil, &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	out = stdout.String()
+	if strings.Contains(out, "build/") || !strings.Contains(out, "6 files") || !strings.Contains(out, "  a/") {
+		t.Errorf("git overview should skip build/ and list two levels:\n%s", out)
+	}
+}
+
+func TestTestsDetect(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		args  []string
+		want  string
+	}{
+		{map[string]string{"go.mod": "module x\n", "Makefile": "test:\n"}, nil, "go project: go test ./..."},
+		{map[string]string{"go.mod": "module x\n"}, []string{"--", "-run", "TestX", "./pkg"}, "go test -run TestX ./pkg"},
+		{map[string]string{"Cargo.toml": ""}, nil, "rust project: cargo test"},
+		{map[string]string{"package.json": `{"scripts":{"test":"vitest"}}`, "pnpm-lock.yaml": ""}, nil, "node project: pnpm test"},
+		{map[string]string{"package.json": `{"scripts":{"test":"jest"}}`}, []string{"--", "-t", "x"}, "npm test -- -t x"},
+		{map[string]string{"pyproject.toml": "[tool.pytest.ini_options]\n"}, nil, "python project: python3 -m pytest -q"},
+		{map[string]string{"Makefile": "all:\n\ntest: all\n\tgo test\n"}, nil, "make project: make test"},
+		{map[string]string{"go.mod": "module x\n"}, []string{"--type", "make"}, "make project: make test"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for name, content := range tt.files {
+			os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		}
+		var stderr bytes.Buffer
+		ctx := cap.NewCwdContext(context.Background(), dir)
+		if err := (&ProjectTask{Task: "tests"}).Run(ctx, append([]string{"-n"}, tt.args...), nil, io.Discard, &stderr); err != nil {
+			t.Errorf("%v %q: %v", tt.files, tt.args, err)
+			continue
+		}
+		if !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("%v %q: got %q, want %q", tt.files, tt.args, stderr.String(), tt.want)
+		}
+	}
+
+	ctx := cap.NewCwdContext(context.Background(), t.TempDir())
+	if err := (&ProjectTask{Task: "tests"}).Run(ctx, []string{"-n"}, nil, io.Discard, io.Discard); err == nil {
+		t.Error("empty directory: expected an error")
+	}
+	for _, args := range [][]string{{"-run", "X"}, {"--type", "cobol"}, {"--timeout", "soon"}} {
+		if err := (&ProjectTask{Task: "tests"}).Validate(args); err == nil {
+			t.Errorf("tests Validate(%q) should return error", args)
+		}
+	}
+}
+
+func TestProjectTaskCommands(t *testing.T) {
+	tests := []struct {
+		task      string
+		files     map[string]string
+		overrides map[string]string
+		args      []string
+		want      string
+	}{
+		{"build", map[string]string{"go.mod": "module x\n"}, nil, nil, "go project: go build ./..."},
+		{"lint", map[string]string{"go.mod": "module x\n"}, nil, nil, "go project: go vet ./..."},
+		{"fmt", map[string]string{"go.mod": "module x\n"}, nil, nil, "go project: gofmt -l -w ."},
+		{"lint", map[string]string{"go.mod": "module x\n"}, map[string]string{"go": "golangci-lint run"}, []string{"--", "./pkg"}, "go project: golangci-lint run ./pkg"},
+		{"lint", map[string]string{"Cargo.toml": ""}, nil, nil, "rust project: cargo clippy"},
+		{"build", map[string]string{"package.json": `{"scripts":{"build":"tsc"}}`, "yarn.lock": ""}, nil, nil, "node project: yarn run build"},
+		{"fmt", map[string]string{"package.json": `{"scripts":{"format":"prettier -w ."}}`}, nil, nil, "node project: npm run format"},
+		{"fmt", map[string]string{"pyproject.toml": ""}, nil, nil, "python project: ruff format ."},
+		{"build", map[string]string{"pyproject.toml": "", "Makefile": "all:\n\ttrue\n"}, nil, nil, "python project: make all"},
+		{"fmt", map[string]string{"Makefile": "format:\n"}, nil, nil, "make project: make format"},
+		{"build", map[string]string{"Makefile": "all:\n"}, nil, nil, "make project: make"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for name, content := range tt.files {
+			os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		}
+		var stderr bytes.Buffer
+		ctx := cap.NewCwdContext(context.Background(), dir)
+		p := &ProjectTask{Task: tt.task, Overrides: tt.overrides}
+		if err := p.Run(ctx, append([]string{"-n"}, tt.args...), nil, io.Discard, &stderr); err != nil {
+			t.Errorf("%s %v: %v", tt.task, tt.files, err)
+			continue
+		}
+		if !strings.Contains(stderr.String(), tt.want) {
+			t.Errorf("%s %v: got %q, want %q", tt.task, tt.files, stderr.String(), tt.want)
+		}
+	}
+
+	// No default and no Makefile target: point at the config.
+	ctx := cap.NewCwdContext(context.Background(), t.TempDir())
+	err := (&ProjectTask{Task: "build"}).Run(ctx, []string{"-n", "--type", "python"}, nil, io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "project.commands.python.build") {
+		t.Errorf("python build: got %v, want a pointer to project.commands", err)
+	}
+}
+
+func TestTestsRun(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not installed")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Makefile"), []byte("test:\n\t@echo ran\n\t@exit 3\n"), 0o644)
+	var stdout bytes.Buffer
+	err := (&ProjectTask{Task: "tests"}).Run(cap.NewCwdContext(context.Background(), dir), nil, nil, &stdout, io.Discard)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 2 || strings.TrimSpace(stdout.String()) != "ran" {
+		t.Errorf("got err %v stdout %q, want make's exit 2 and its output", err, stdout.String())
+	}
+
+	os.WriteFile(filepath.Join(dir, "Makefile"), []byte("test:\n\t@sleep 5\n"), 0o644)
+	var stderr bytes.Buffer
+	start := time.Now()
+	err = (&ProjectTask{Task: "tests"}).Run(cap.NewCwdContext(context.Background(), dir), []string{"--timeout", "100ms"}, nil, io.Discard, &stderr)
+	if err == nil || !strings.Contains(stderr.String(), "timed out") || time.Since(start) > 3*time.Second {
+		t.Errorf("timeout: err %v stderr %q after %s", err, stderr.String(), time.Since(start))
+	}
+}
+
+func TestTargets(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Makefile"), []byte(`VERSION := 1.0
+GOFLAGS ?= -trimpath
+.PHONY: all build test
+
+all: build ## build everything
+
+# Compile the binary.
+build:
+	go build $(GOFLAGS)
+
+test lint:
+	go test ./...
+
+%.o: %.c
+	cc -c $<
+
+define RECIPE
+fake: target
+endef
+`), 0o644)
+	os.WriteFile(filepath.Join(dir, "justfile"), []byte(`set shell := ["bash", "-c"]
+alias b := build
+
+# Build the project.
+build:
+    go build
+
+[doc("Deploy to an environment.")]
+deploy env='staging' *flags: build
+    ./deploy {{env}} {{flags}}
+
+_helper:
+    true
+
+[private]
+hidden:
+    true
+`), 0o644)
+	os.WriteFile(filepath.Join(dir, "Taskfile.yml"), []byte(`version: '3'
+tasks:
+  gen:
+    desc: Generate code
+    cmds: [go generate ./...]
+  setup:
+    internal: true
+  clean: rm -rf bin
+`), 0o644)
+
+	var stdout bytes.Buffer
+	ctx := cap.NewCwdContext(context.Background(), dir)
+	if err := (&Targets{}).Run(ctx, nil, nil, &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want := `Makefile (make):
+  all    build everything
+  build  Compile the binary.
+  test
+  lint
+
+justfile (just):
+  build                        Build the project.
+  deploy env='staging' *flags  Deploy to an environment.
+
+Taskfile.yml (task):
+  gen    Generate code
+  clean
+`
+	if got := stdout.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := (&Targets{}).Run(cap.NewCwdContext(context.Background(), t.TempDir()), nil, nil, io.Discard, io.Discard); err == nil {
+		t.Error("empty directory: expected an error")
+	}
+	if err := (&Targets{}).Validate([]string{"a", "b"}); err == nil {
+		t.Error("two directories: expected an error")
+	}
+}
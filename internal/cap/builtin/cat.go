@@ -17,3 +17,12 @@ func (c *Cat) Description() string { return "concatenate and display files" }
 func (c *Cat) Tier() cap.Tier      { return cap.TierRead }
 func (c *Cat) Validate(args []string) error { return nil }
 
+func (c *Cat) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"cat go.mod", "cat -n main.go"},
+		Flags: []cap.Flag{
+			{Name: "-n", Description: "number all output lines"},
+		},
+		TierRationale: "only reads files",
+	}
+}
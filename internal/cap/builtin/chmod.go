@@ -24,3 +24,12 @@ func (c *Chmod) Validate(args []string) error {
 	return nil
 }
 
+func (c *Chmod) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"chmod +x scripts/build.sh", "chmod 644 config.yaml"},
+		Flags: []cap.Flag{
+			{Name: "-R", Description: "change files and directories recursively"},
+		},
+		TierRationale: "changes who can read, write, or execute files, which can expose secrets or break the tree",
+	}
+}
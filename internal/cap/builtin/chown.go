@@ -0,0 +1,35 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"fmt"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+type Chown struct{}
+
+var _ cap.Capability = (*Chown)(nil)
+
+func (c *Chown) Name() string        { return "chown" }
+func (c *Chown) Description() string { return "change file ownership (dangerous)" }
+func (c *Chown) Tier() cap.Tier      { return cap.TierDangerous }
+
+func (c *Chown) Validate(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("chown requires an owner and at least one file")
+	}
+	return nil
+}
+
+func (c *Chown) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"chown $USER build/output.log", "chown -R $USER:staff dist"},
+		Flags: []cap.Flag{
+			{Name: "-R", Description: "change files and directories recursively"},
+		},
+		TierRationale: "hands files to another user, which can lock the owner out or expose files to others",
+	}
+}
@@ -17,3 +17,13 @@ func (c *Cp) Description() string { return "copy files and directories" }
 func (c *Cp) Tier() cap.Tier      { return cap.TierWrite }
 func (c *Cp) Validate(args []string) error { return nil }
 
+func (c *Cp) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"cp config.example.yaml config.yaml", "cp -r testdata/ /tmp/fixture/"},
+		Flags: []cap.Flag{
+			{Name: "-r", Description: "copy directories recursively"},
+			{Name: "-p", Description: "preserve mode, ownership, and timestamps"},
+		},
+		TierRationale: "creates or overwrites files",
+	}
+}
@@ -5,17 +5,22 @@ package builtin
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 
 	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/proc"
 )
 
 // ExitError represents a command that exited with a non-zero status.
 // It carries the exit code so callers can propagate it without extra messaging.
+// A command killed by a signal has Code 128+n and Signal set, so a build
+// that was OOM-killed (137, SIGKILL) is distinguishable from one that failed.
 type ExitError struct {
-	Code int
+	Code   int
+	Signal string // e.g. "SIGKILL"; empty if the command exited normally
 }
 
 func (e *ExitError) Error() string {
@@ -26,19 +31,10 @@ func (e *ExitError) Error() string {
 // Non-zero exit codes are returned as *ExitError so callers can propagate
 // the code directly. Other errors (e.g. command not found) are returned as-is.
 // If the context carries a working directory (via cap.NewCwdContext), child
-// processes run in that directory.
+// processes run in that directory. The command leads its own process
+// group, so cancellation reaches everything it spawned.
 func runExternal(ctx context.Context, name string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
-	cmd := exec.CommandContext(ctx, name, args...)
-	if cwd := cap.CwdFromContext(ctx); cwd != "" {
-		cmd.Dir = cwd
-	}
-	if env := cap.EnvFromContext(ctx); env != nil {
-		envSlice := make([]string, 0, len(env))
-		for k, v := range env {
-			envSlice = append(envSlice, k+"="+v)
-		}
-		cmd.Env = envSlice
-	}
+	cmd := externalCommand(ctx, name, args)
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	if stderr != nil {
@@ -49,9 +45,33 @@ func runExternal(ctx context.Context, name string, args []string, stdin io.Reade
 
 	if err := cmd.Run(); err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return &ExitError{Code: exitErr.ExitCode()}
+			code, signal := proc.ExitStatus(exitErr.ProcessState)
+			return &ExitError{Code: code, Signal: signal}
 		}
 		return err
 	}
 	return nil
 }
+
+// externalCommand returns an unstarted command in its own process group,
+// with the working directory, environment, and umask carried by ctx.
+func externalCommand(ctx context.Context, name string, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if mask, ok := cap.UmaskFromContext(ctx); ok && cmd.Err == nil {
+		// Go cannot set a child's umask, so sh sets it and execs the program.
+		script := fmt.Sprintf(`umask %03o && exec "$0" "$@"`, mask)
+		cmd = exec.CommandContext(ctx, "sh", append([]string{"-c", script, cmd.Path}, args...)...)
+	}
+	proc.Isolate(cmd, proc.DefaultGrace)
+	if cwd := cap.CwdFromContext(ctx); cwd != "" {
+		cmd.Dir = cwd
+	}
+	if env := cap.EnvFromContext(ctx); env != nil {
+		envSlice := make([]string, 0, len(env))
+		for k, v := range env {
+			envSlice = append(envSlice, k+"="+v)
+		}
+		cmd.Env = envSlice
+	}
+	return cmd
+}
@@ -27,3 +27,18 @@ func (f *Find) Validate(args []string) error {
 	return nil
 }
 
+func (f *Find) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"find . -name \"*.go\" -newer go.mod", "find internal -type d"},
+		Flags: []cap.Flag{
+			{Name: "-name", Description: "match base name against a glob"},
+			{Name: "-type", Description: "match file type (f, d, l)"},
+			{Name: "-exec", Description: "run a command per match", Denied: true},
+			{Name: "-execdir", Description: "run a command per match in its directory", Denied: true},
+			{Name: "-ok", Description: "run a command per match after confirmation", Denied: true},
+			{Name: "-okdir", Description: "like -ok, in the match directory", Denied: true},
+			{Name: "-delete", Description: "delete matches", Denied: true},
+		},
+		TierRationale: "only reads the directory tree; actions that mutate or run commands are denied",
+	}
+}
@@ -5,6 +5,8 @@ package builtin
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/marcelocantos/doit/internal/cap"
 )
@@ -12,6 +14,8 @@ import (
 type Git struct{}
 
 var _ cap.Capability = (*Git)(nil)
+var _ cap.Networked = (*Git)(nil)
+var _ cap.Tiered = (*Git)(nil)
 
 func (g *Git) Name() string        { return "git" }
 func (g *Git) Description() string { return "git version control (tier varies by subcommand)" }
@@ -23,3 +27,62 @@ func (g *Git) Validate(args []string) error {
 	}
 	return nil
 }
+
+func (g *Git) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"git status", "git diff --stat", "git commit -m \"Fix parser\"", "git push origin main"},
+		Flags: []cap.Flag{
+			{Name: "--force", Description: "git push: overwrite remote history; rejected by the default config rule"},
+			{Name: "--hard", Description: "git reset: discard uncommitted changes; rejected by the default config rule"},
+		},
+		TierRationale: "varies by subcommand: status, log, diff and show are read; add, commit and checkout are write; push is dangerous because it publishes changes",
+	}
+}
+
+// gitNetworkSubcommands talk to a remote.
+var gitNetworkSubcommands = map[string]bool{
+	"push": true, "pull": true, "fetch": true, "clone": true, "ls-remote": true, "submodule": true,
+}
+
+func (g *Git) UsesNetwork(args []string) bool {
+	sub, rest := gitSubcommand(args)
+	if sub == "remote" {
+		return len(rest) > 0 && (rest[0] == "update" || rest[0] == "prune" || rest[0] == "show")
+	}
+	return gitNetworkSubcommands[sub]
+}
+
+// gitReadSubcommands only inspect the repository.
+var gitReadSubcommands = map[string]bool{
+	"status": true, "log": true, "diff": true, "show": true, "blame": true, "grep": true,
+	"ls-files": true, "ls-tree": true, "rev-parse": true, "describe": true, "shortlog": true,
+	"cat-file": true, "version": true, "help": true,
+}
+
+// TierFor places inspection at read, publishing and discarding work (push,
+// clean, reset --hard) at dangerous, and everything else at write.
+func (g *Git) TierFor(args []string) cap.Tier {
+	sub, rest := gitSubcommand(args)
+	switch {
+	case gitReadSubcommands[sub]:
+		return cap.TierRead
+	case sub == "push" || sub == "clean" || sub == "reset" && slices.Contains(rest, "--hard"):
+		return cap.TierDangerous
+	}
+	return cap.TierWrite
+}
+
+// gitSubcommand returns git's subcommand and its arguments, skipping
+// global options, including -C and -c, which take a value.
+func gitSubcommand(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-C" || a == "-c":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			return a, args[i+1:]
+		}
+	}
+	return "", nil
+}
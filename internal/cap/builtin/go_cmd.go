@@ -12,6 +12,8 @@ import (
 type GoCmd struct{}
 
 var _ cap.Capability = (*GoCmd)(nil)
+var _ cap.Networked = (*GoCmd)(nil)
+var _ cap.Tiered = (*GoCmd)(nil)
 
 func (g *GoCmd) Name() string        { return "go" }
 func (g *GoCmd) Description() string { return "go build, test, vet, and other go commands (tier varies by subcommand)" }
@@ -23,3 +25,46 @@ func (g *GoCmd) Validate(args []string) error {
 	}
 	return nil
 }
+
+func (g *GoCmd) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"go build ./...", "go test -run TestFoo ./internal/...", "go vet ./..."},
+		Flags: []cap.Flag{
+			{Name: "-run", Description: "go test: run only tests matching a regexp"},
+			{Name: "-race", Description: "enable the race detector"},
+			{Name: "-v", Description: "verbose output"},
+		},
+		TierRationale: "builds and tests write only to the build cache; subcommands such as mod tidy or get that edit go.mod run at a higher tier",
+	}
+}
+
+// UsesNetwork reports subcommands that download modules: go get, go
+// install, and go mod download, tidy, and vendor.
+func (g *GoCmd) UsesNetwork(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "get", "install":
+		return true
+	case "mod":
+		return len(args) > 1 && (args[1] == "download" || args[1] == "tidy" || args[1] == "vendor")
+	}
+	return false
+}
+
+// TierFor places builds and tests at build, queries such as go env and
+// go list at read, and everything else — go run, and subcommands that
+// edit go.mod or source files — at write.
+func (g *GoCmd) TierFor(args []string) cap.Tier {
+	if len(args) == 0 {
+		return cap.TierBuild
+	}
+	switch args[0] {
+	case "build", "test", "vet":
+		return cap.TierBuild
+	case "version", "env", "list", "doc", "help":
+		return cap.TierRead
+	}
+	return cap.TierWrite
+}
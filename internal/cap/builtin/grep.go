@@ -21,3 +21,15 @@ func (g *Grep) Validate(args []string) error {
 	return nil
 }
 
+func (g *Grep) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"grep -rn TODO src/", "grep -l \"func main\" *.go"},
+		Flags: []cap.Flag{
+			{Name: "-r", Description: "search directories recursively"},
+			{Name: "-n", Description: "prefix matches with line numbers"},
+			{Name: "-i", Description: "ignore case"},
+			{Name: "-l", Description: "list matching file names only"},
+		},
+		TierRationale: "only reads files",
+	}
+}
@@ -17,3 +17,13 @@ func (h *Head) Description() string { return "output the first part of files or
 func (h *Head) Tier() cap.Tier      { return cap.TierRead }
 func (h *Head) Validate(args []string) error { return nil }
 
+func (h *Head) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"head -20 README.md", "head -c 512 data.bin"},
+		Flags: []cap.Flag{
+			{Name: "-n", Description: "number of lines to print"},
+			{Name: "-c", Description: "number of bytes to print"},
+		},
+		TierRationale: "only reads files",
+	}
+}
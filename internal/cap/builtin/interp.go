@@ -0,0 +1,46 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import "github.com/marcelocantos/doit/internal/cap"
+
+// Interpreter is a language runtime — python, python3, node, or ruby —
+// run on a script in the workspace. Inline code (-c, -e) and programs
+// read from stdin are arbitrary execution, so policy always sends them to
+// Level 3 for review along with the code; see escalate-interpreter-code.
+type Interpreter struct {
+	Command    string // python3, node, ...
+	Lang       string // Python, JavaScript, ...
+	InlineFlag string // -c or -e
+	Script     string // an example script name
+}
+
+var _ cap.Capability = (*Interpreter)(nil)
+
+// Interpreters are the language runtimes RegisterAll adds.
+var Interpreters = []*Interpreter{
+	{Command: "node", Lang: "JavaScript", InlineFlag: "-e", Script: "scripts/gen.js"},
+	{Command: "python", Lang: "Python", InlineFlag: "-c", Script: "scripts/gen.py"},
+	{Command: "python3", Lang: "Python", InlineFlag: "-c", Script: "scripts/gen.py"},
+	{Command: "ruby", Lang: "Ruby", InlineFlag: "-e", Script: "scripts/gen.rb"},
+}
+
+func (in *Interpreter) Name() string        { return in.Command }
+func (in *Interpreter) Description() string { return "run a " + in.Lang + " script in the workspace" }
+func (in *Interpreter) Tier() cap.Tier      { return cap.TierWrite }
+
+func (in *Interpreter) Validate(args []string) error {
+	return nil
+}
+
+func (in *Interpreter) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{in.Command + " " + in.Script, in.Command + " " + in.Script + " --dry-run"},
+		Flags: []cap.Flag{
+			{Name: in.InlineFlag, Description: "run inline code; always reviewed by the Level 3 gatekeeper, which sees the code"},
+			{Name: "-", Description: "read the program from stdin; always reviewed like inline code"},
+		},
+		TierRationale: "a workspace script can do anything the project's own code can, including modifying files; scripts outside the workspace are reviewed",
+	}
+}
@@ -17,3 +17,13 @@ func (l *Ls) Description() string { return "list directory contents" }
 func (l *Ls) Tier() cap.Tier      { return cap.TierRead }
 func (l *Ls) Validate(args []string) error { return nil }
 
+func (l *Ls) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"ls -la", "ls internal/"},
+		Flags: []cap.Flag{
+			{Name: "-l", Description: "long listing format"},
+			{Name: "-a", Description: "include hidden entries"},
+		},
+		TierRationale: "only reads directory metadata",
+	}
+}
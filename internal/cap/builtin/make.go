@@ -34,3 +34,15 @@ func (m *Make) Validate(args []string) error {
 	return nil
 }
 
+func (m *Make) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"make", "make test", "make build VERSION=1.2.3"},
+		Flags: []cap.Flag{
+			{Name: "-n", Description: "print commands without running them"},
+			{Name: "-j", Description: "parallel jobs; rejected by the default config rule because it can mask errors"},
+			{Name: "-f", Description: "use another makefile", Denied: true},
+			{Name: "-C", Description: "run in another directory", Denied: true},
+		},
+		TierRationale: "runs the project's own build recipes, which produce build outputs",
+	}
+}
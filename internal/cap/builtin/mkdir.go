@@ -17,3 +17,12 @@ func (m *Mkdir) Description() string { return "create directories" }
 func (m *Mkdir) Tier() cap.Tier      { return cap.TierWrite }
 func (m *Mkdir) Validate(args []string) error { return nil }
 
+func (m *Mkdir) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"mkdir build", "mkdir -p internal/newpkg"},
+		Flags: []cap.Flag{
+			{Name: "-p", Description: "create parent directories as needed"},
+		},
+		TierRationale: "creates directories",
+	}
+}
@@ -17,3 +17,13 @@ func (m *Mv) Description() string { return "move or rename files and directories
 func (m *Mv) Tier() cap.Tier      { return cap.TierWrite }
 func (m *Mv) Validate(args []string) error { return nil }
 
+func (m *Mv) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"mv old.go new.go", "mv -n draft.md docs/"},
+		Flags: []cap.Flag{
+			{Name: "-n", Description: "do not overwrite an existing file"},
+			{Name: "-f", Description: "overwrite without prompting"},
+		},
+		TierRationale: "renames and can overwrite files",
+	}
+}
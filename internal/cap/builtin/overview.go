@@ -0,0 +1,329 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// DefaultOverviewDepth is how many directory levels overview lists unless
+// -d says otherwise.
+const DefaultOverviewDepth = 2
+
+// maxOverviewEntries caps the entries overview lists per directory.
+const maxOverviewEntries = 40
+
+// Overview summarises a directory tree for an agent orienting itself in a
+// repository: a depth-limited listing with file counts, sizes, and the
+// main language of each directory, skipping whatever .gitignore excludes.
+// doit runs it in-process.
+type Overview struct{}
+
+var (
+	_ cap.Capability = (*Overview)(nil)
+	_ cap.Runner     = (*Overview)(nil)
+)
+
+func (o *Overview) Name() string        { return "overview" }
+func (o *Overview) Description() string { return "summarise a directory tree with sizes and languages" }
+func (o *Overview) Tier() cap.Tier      { return cap.TierRead }
+
+func (o *Overview) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"overview", "overview -d 3 internal"},
+		Flags: []cap.Flag{
+			{Name: "-d", Description: fmt.Sprintf("directory levels to list (default %d)", DefaultOverviewDepth)},
+		},
+		TierRationale: "only reads directory listings",
+	}
+}
+
+func (o *Overview) Validate(args []string) error {
+	_, _, err := parseOverviewArgs(args)
+	return err
+}
+
+func parseOverviewArgs(args []string) (depth int, path string, err error) {
+	depth, path = DefaultOverviewDepth, "."
+	seen := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-d" || a == "--depth":
+			if i+1 == len(args) {
+				return 0, "", fmt.Errorf("overview: %s requires a depth", a)
+			}
+			i++
+			if depth, err = strconv.Atoi(args[i]); err != nil || depth < 1 {
+				return 0, "", fmt.Errorf("overview: invalid depth %q", args[i])
+			}
+		case strings.HasPrefix(a, "-"):
+			return 0, "", fmt.Errorf("overview: unknown flag %s", a)
+		case seen:
+			return 0, "", fmt.Errorf("overview takes one directory")
+		default:
+			path, seen = a, true
+		}
+	}
+	return depth, path, nil
+}
+
+// dirSummary accumulates the files beneath one directory.
+type dirSummary struct {
+	name  string
+	files int
+	size  int64
+	langs map[string]int64 // bytes per language
+	dirs  map[string]*dirSummary
+	own   []fileInfo // files directly in this directory
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	lang string
+}
+
+func newDirSummary(name string) *dirSummary {
+	return &dirSummary{name: name, langs: map[string]int64{}, dirs: map[string]*dirSummary{}}
+}
+
+// add records the file at rel, a slash-separated path below d.
+func (d *dirSummary) add(rel string, size int64) {
+	lang := guessLanguage(rel)
+	for {
+		d.files++
+		d.size += size
+		if lang != "" {
+			d.langs[lang] += size
+		}
+		dir, rest, ok := strings.Cut(rel, "/")
+		if !ok {
+			d.own = append(d.own, fileInfo{name: rel, size: size, lang: lang})
+			return
+		}
+		sub := d.dirs[dir]
+		if sub == nil {
+			sub = newDirSummary(dir)
+			d.dirs[dir] = sub
+		}
+		d, rel = sub, rest
+	}
+}
+
+// mainLanguages names the languages making up most of d's bytes.
+func (d *dirSummary) mainLanguages(n int) string {
+	type lb struct {
+		lang  string
+		bytes int64
+	}
+	var ls []lb
+	var total int64
+	for l, b := range d.langs {
+		ls = append(ls, lb{l, b})
+		total += b
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		if ls[i].bytes != ls[j].bytes {
+			return ls[i].bytes > ls[j].bytes
+		}
+		return ls[i].lang < ls[j].lang
+	})
+	var parts []string
+	for i, l := range ls {
+		if i == n || total == 0 {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %d%%", l.lang, (l.bytes*100+total/2)/total))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (o *Overview) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	depth, path, err := parseOverviewArgs(args)
+	if err != nil {
+		return err
+	}
+	root := path
+	if cwd := cap.CwdFromContext(ctx); cwd != "" && !filepath.IsAbs(root) {
+		root = filepath.Join(cwd, root)
+	}
+	if fi, err := os.Stat(root); err != nil {
+		return fmt.Errorf("overview: %w", err)
+	} else if !fi.IsDir() {
+		return fmt.Errorf("overview: %s is not a directory", path)
+	}
+
+	files, source := gitFiles(ctx, root)
+	if files == nil {
+		if files, err = walkFiles(root); err != nil {
+			return fmt.Errorf("overview: %w", err)
+		}
+		source = "all files; not a git work tree"
+	}
+	top := newDirSummary(path)
+	for _, rel := range files {
+		fi, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue // deleted but still tracked, or not a file
+		}
+		top.add(rel, fi.Size())
+	}
+
+	fmt.Fprintf(stdout, "%s: %s, %s (%s)\n", path, countFiles(top.files), formatSize(top.size), source)
+	if langs := top.mainLanguages(5); langs != "" {
+		fmt.Fprintf(stdout, "languages: %s\n", langs)
+	}
+	writeOverview(stdout, top, "", depth)
+	return nil
+}
+
+// writeOverview lists d's subdirectories, then its files, descending
+// depth levels.
+func writeOverview(w io.Writer, d *dirSummary, indent string, depth int) {
+	names := make([]string, 0, len(d.dirs))
+	for name := range d.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Slice(d.own, func(i, j int) bool { return d.own[i].name < d.own[j].name })
+
+	listed := 0
+	for _, name := range names {
+		if listed == maxOverviewEntries {
+			break
+		}
+		listed++
+		sub := d.dirs[name]
+		line := fmt.Sprintf("%s%s/", indent, name)
+		fmt.Fprintf(w, "%-40s %11s %9s", line, countFiles(sub.files), formatSize(sub.size))
+		if langs := sub.mainLanguages(2); langs != "" {
+			fmt.Fprintf(w, "  %s", langs)
+		}
+		fmt.Fprintln(w)
+		if depth > 1 {
+			writeOverview(w, sub, indent+"  ", depth-1)
+		}
+	}
+	for _, f := range d.own {
+		if listed == maxOverviewEntries {
+			break
+		}
+		listed++
+		fmt.Fprintf(w, "%-40s %11s %9s", indent+f.name, "", formatSize(f.size))
+		if f.lang != "" {
+			fmt.Fprintf(w, "  %s", f.lang)
+		}
+		fmt.Fprintln(w)
+	}
+	if more := len(names) + len(d.own) - listed; more > 0 {
+		fmt.Fprintf(w, "%s... %d more\n", indent, more)
+	}
+}
+
+// gitFiles lists the files under root that git does not ignore, tracked
+// or not, and describes where the list came from. It returns nil if root
+// is not in a git work tree.
+func gitFiles(ctx context.Context, root string) ([]string, string) {
+	cmd := externalCommand(ctx, "git", []string{"-C", root, "ls-files", "-z", "--cached", "--others", "--exclude-standard"})
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, ""
+	}
+	files := []string{}
+	for _, f := range bytes.Split(out, []byte{0}) {
+		if len(f) > 0 {
+			files = append(files, string(f))
+		}
+	}
+	// ls-files repeats unmerged files.
+	slices.Sort(files)
+	return slices.Compact(files), "excluding .gitignored files"
+}
+
+// walkFiles lists every file under root, skipping .git and dependency
+// directories.
+func walkFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable entries are left out
+		}
+		if d.IsDir() {
+			if p != root && (d.Name() == ".git" || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+func countFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// formatSize renders a byte count compactly: 912 B, 4.2 KiB, 31.0 MiB.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// languageByName and languageByExt guess a file's language from its name.
+var (
+	languageByName = map[string]string{
+		"Makefile": "Make", "GNUmakefile": "Make", "Dockerfile": "Docker",
+		"go.mod": "Go", "go.sum": "Go", "Cargo.toml": "Rust", "package.json": "JavaScript",
+		"CMakeLists.txt": "CMake", "BUILD": "Bazel", "BUILD.bazel": "Bazel",
+	}
+	languageByExt = map[string]string{
+		".go": "Go", ".rs": "Rust", ".py": "Python", ".rb": "Ruby", ".java": "Java",
+		".kt": "Kotlin", ".scala": "Scala", ".swift": "Swift", ".m": "Objective-C",
+		".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".cxx": "C++", ".hpp": "C++", ".hh": "C++",
+		".cs": "C#", ".js": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript", ".jsx": "JavaScript",
+		".ts": "TypeScript", ".tsx": "TypeScript", ".php": "PHP", ".lua": "Lua", ".pl": "Perl",
+		".sh": "Shell", ".bash": "Shell", ".zsh": "Shell", ".star": "Starlark", ".bzl": "Starlark",
+		".html": "HTML", ".css": "CSS", ".scss": "CSS", ".sql": "SQL", ".proto": "Protobuf",
+		".md": "Markdown", ".rst": "reStructuredText", ".txt": "Text",
+		".json": "JSON", ".yaml": "YAML", ".yml": "YAML", ".toml": "TOML", ".xml": "XML",
+		".ex": "Elixir", ".exs": "Elixir", ".erl": "Erlang", ".hs": "Haskell", ".ml": "OCaml",
+		".clj": "Clojure", ".dart": "Dart", ".zig": "Zig", ".r": "R", ".jl": "Julia",
+	}
+)
+
+func guessLanguage(path string) string {
+	base := path[strings.LastIndex(path, "/")+1:]
+	if l, ok := languageByName[base]; ok {
+		return l
+	}
+	return languageByExt[strings.ToLower(filepath.Ext(base))]
+}
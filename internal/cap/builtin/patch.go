@@ -0,0 +1,185 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filePatch is the part of a unified diff that changes one file.
+type filePatch struct {
+	oldPath, newPath string // "" for /dev/null
+	hunks            []hunk
+}
+
+// hunk is one @@ section. Each line keeps its ' ', '-', or '+' prefix and
+// its newline, which is absent when the diff marks "No newline at end of
+// file".
+type hunk struct {
+	oldStart int
+	lines    []string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatch parses a unified diff as produced by diff -u or git diff.
+func parsePatch(diff string) ([]filePatch, error) {
+	lines := strings.SplitAfter(diff, "\n")
+	var patches []filePatch
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "--- ") || i+1 == len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+			continue
+		}
+		fp := filePatch{oldPath: patchPath(lines[i][4:]), newPath: patchPath(lines[i+1][4:])}
+		i += 2
+		for i < len(lines) {
+			m := hunkHeader.FindStringSubmatch(lines[i])
+			if m == nil {
+				break
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			oldLeft, newLeft := hunkCount(m[2]), hunkCount(m[4])
+			h := hunk{oldStart: oldStart}
+			for i++; i < len(lines) && lines[i] != "" && (oldLeft > 0 || newLeft > 0); i++ {
+				l := lines[i]
+				if l == "\n" {
+					l = " \n" // context line whose space an editor stripped
+				}
+				switch l[0] {
+				case ' ':
+					oldLeft--
+					newLeft--
+				case '-':
+					oldLeft--
+				case '+':
+					newLeft--
+				case '\\':
+					if n := len(h.lines); n > 0 {
+						h.lines[n-1] = strings.TrimSuffix(h.lines[n-1], "\n")
+					}
+					continue
+				default:
+					return nil, fmt.Errorf("write: malformed hunk at line %d of the patch: %q", i+1, strings.TrimSuffix(l, "\n"))
+				}
+				h.lines = append(h.lines, l)
+			}
+			if oldLeft != 0 || newLeft != 0 {
+				return nil, fmt.Errorf("write: patch for %s ends inside a hunk", fp.name())
+			}
+			if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+				if n := len(h.lines); n > 0 {
+					h.lines[n-1] = strings.TrimSuffix(h.lines[n-1], "\n")
+				}
+				i++
+			}
+			fp.hunks = append(fp.hunks, h)
+		}
+		i--
+		patches = append(patches, fp)
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("write: no unified diff found on stdin")
+	}
+	return patches, nil
+}
+
+// patchPath strips the timestamp diff -u appends and git's a/ or b/
+// prefix. /dev/null becomes "".
+func patchPath(s string) string {
+	s = strings.TrimRight(s, "\r\n")
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return ""
+	}
+	for _, p := range []string{"a/", "b/"} {
+		if rest, ok := strings.CutPrefix(s, p); ok {
+			return rest
+		}
+	}
+	return s
+}
+
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// name is the path the patch writes, or deletes.
+func (fp filePatch) name() string {
+	if fp.newPath != "" {
+		return fp.newPath
+	}
+	return fp.oldPath
+}
+
+// apply returns content with the patch's hunks applied. A hunk whose
+// context has moved is found by searching outward from where it claims to
+// start, and later hunks are assumed to have moved as far; one that
+// matches nowhere fails the whole patch.
+func (fp filePatch) apply(content string) (string, error) {
+	old := strings.SplitAfter(content, "\n")
+	if old[len(old)-1] == "" {
+		old = old[:len(old)-1]
+	}
+	var out []string
+	pos, offset := 0, 0
+	for n, h := range fp.hunks {
+		var from, to []string
+		for _, l := range h.lines {
+			if l[0] != '+' {
+				from = append(from, l[1:])
+			}
+			if l[0] != '-' {
+				to = append(to, l[1:])
+			}
+		}
+		want := h.oldStart - 1 + offset
+		if len(from) == 0 {
+			want++
+		}
+		at := findHunk(old, from, want, pos)
+		if at < 0 {
+			return "", fmt.Errorf("write: hunk %d of %s does not apply", n+1, fp.name())
+		}
+		offset += at - want
+		out = append(out, old[pos:at]...)
+		out = append(out, to...)
+		pos = at + len(from)
+	}
+	out = append(out, old[pos:]...)
+	return strings.Join(out, ""), nil
+}
+
+// findHunk returns where from occurs in lines at or after min, preferring
+// the position nearest want, or -1.
+func findHunk(lines, from []string, want, min int) int {
+	matches := func(at int) bool {
+		if at < min || at+len(from) > len(lines) {
+			return false
+		}
+		for i, l := range from {
+			if lines[at+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; want-d >= min || want+d <= len(lines); d++ {
+		if matches(want - d) {
+			return want - d
+		}
+		if d > 0 && matches(want+d) {
+			return want + d
+		}
+	}
+	return -1
+}
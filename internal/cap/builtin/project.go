@@ -0,0 +1,311 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// DefaultTaskTimeout bounds a project task unless --timeout says otherwise.
+const DefaultTaskTimeout = 10 * time.Minute
+
+// ProjectTypes are the kinds of project the project tasks recognise, in
+// detection order.
+var ProjectTypes = []string{"go", "rust", "node", "python", "make"}
+
+// ProjectTasks are the names of the project meta-capabilities.
+var ProjectTasks = []string{"build", "fmt", "lint", "tests"}
+
+// runnerFunc returns the command that performs a task for a project, given
+// the project root and any arguments that replace the defaults, or nil if
+// there is no default.
+type runnerFunc func(root string, extra []string, replace bool) []string
+
+// projectTask describes one meta-capability.
+type projectTask struct {
+	tier        cap.Tier
+	description string
+	rationale   string
+	makeTargets []string // Makefile targets that perform the task
+	runners     map[string]runnerFunc
+}
+
+// fixed returns a runnerFunc for a command that takes the extra arguments
+// after its own, or in place of defaults when replace is set.
+func fixed(argv []string, defaults ...string) runnerFunc {
+	return func(_ string, extra []string, replace bool) []string {
+		if !replace {
+			extra = defaults
+		}
+		return append(append([]string(nil), argv...), extra...)
+	}
+}
+
+// script returns a runnerFunc that runs a package.json script with the
+// project's package manager, if the script exists.
+func script(names ...string) runnerFunc {
+	return func(root string, extra []string, _ bool) []string {
+		scripts := packageScripts(filepath.Join(root, "package.json"))
+		for _, name := range names {
+			s := scripts[name]
+			if s == "" || name == "test" && strings.Contains(s, "no test specified") {
+				continue
+			}
+			pm := "npm"
+			switch {
+			case exists(filepath.Join(root, "pnpm-lock.yaml")):
+				pm = "pnpm"
+			case exists(filepath.Join(root, "yarn.lock")):
+				pm = "yarn"
+			}
+			cmd := []string{pm, "run", name}
+			if name == "test" {
+				cmd = []string{pm, "test"}
+			}
+			if len(extra) > 0 && pm == "npm" {
+				cmd = append(cmd, "--")
+			}
+			return append(cmd, extra...)
+		}
+		return nil
+	}
+}
+
+var projectTaskTable = map[string]projectTask{
+	"build": {
+		tier:        cap.TierBuild,
+		description: "detect the project type and build it",
+		rationale:   "compiles the project",
+		makeTargets: []string{"build", "all"},
+		runners: map[string]runnerFunc{
+			"go":   fixed([]string{"go", "build"}, "./..."),
+			"rust": fixed([]string{"cargo", "build"}),
+			"node": script("build"),
+			"make": fixed([]string{"make"}),
+		},
+	},
+	"fmt": {
+		tier:        cap.TierWrite,
+		description: "detect the project type and format its source in place",
+		rationale:   "rewrites source files",
+		makeTargets: []string{"fmt", "format"},
+		runners: map[string]runnerFunc{
+			"go":     fixed([]string{"gofmt", "-l", "-w"}, "."),
+			"rust":   fixed([]string{"cargo", "fmt"}),
+			"node":   script("format", "fmt"),
+			"python": fixed([]string{"ruff", "format"}, "."),
+		},
+	},
+	"lint": {
+		tier:        cap.TierBuild,
+		description: "detect the project type and run its linter",
+		rationale:   "linters may compile the project, though they change nothing",
+		makeTargets: []string{"lint"},
+		runners: map[string]runnerFunc{
+			"go":     fixed([]string{"go", "vet"}, "./..."),
+			"rust":   fixed([]string{"cargo", "clippy"}),
+			"node":   script("lint"),
+			"python": fixed([]string{"ruff", "check"}, "."),
+		},
+	},
+	"tests": {
+		tier:        cap.TierBuild,
+		description: "detect the project type and run its tests",
+		rationale:   "builds and runs the project's own tests",
+		makeTargets: []string{"test"},
+		runners: map[string]runnerFunc{
+			"go":     fixed([]string{"go", "test"}, "./..."),
+			"rust":   fixed([]string{"cargo", "test"}),
+			"node":   script("test"),
+			"python": fixed([]string{"python3", "-m", "pytest", "-q"}),
+		},
+	},
+}
+
+// ProjectTask is a meta-capability — build, fmt, lint, or tests — that
+// detects the kind of project in the working directory and runs the
+// appropriate tool, so that policy can allow "lint" broadly while the
+// tool varies by repository. Overrides, from project.commands in the
+// global config, replace the built-in command by project type. tests is
+// not named test, which would shadow test(1). doit runs these in-process.
+type ProjectTask struct {
+	Task      string
+	Overrides map[string]string // project type → command line
+}
+
+var (
+	_ cap.Capability = (*ProjectTask)(nil)
+	_ cap.Runner     = (*ProjectTask)(nil)
+)
+
+func (p *ProjectTask) Name() string        { return p.Task }
+func (p *ProjectTask) Description() string { return projectTaskTable[p.Task].description }
+func (p *ProjectTask) Tier() cap.Tier      { return projectTaskTable[p.Task].tier }
+
+func (p *ProjectTask) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{p.Task, p.Task + " -n", p.Task + " --type make", p.Task + " --timeout 2m -- <runner arguments>"},
+		Flags: []cap.Flag{
+			{Name: "-n", Description: "print the command without running it"},
+			{Name: "--type", Description: strings.Join(ProjectTypes, ", ") + ", instead of detecting it"},
+			{Name: "--timeout", Description: fmt.Sprintf("stop the run after this long (default %s)", DefaultTaskTimeout)},
+			{Name: "--", Description: "pass the remaining arguments to the tool in place of its defaults"},
+		},
+		TierRationale: projectTaskTable[p.Task].rationale,
+	}
+}
+
+func (p *ProjectTask) Validate(args []string) error {
+	_, err := p.parseArgs(args)
+	return err
+}
+
+// taskArgs is a parsed project task command line.
+type taskArgs struct {
+	dryRun  bool
+	kind    string
+	timeout time.Duration
+	extra   []string // for the tool, after --
+	replace bool     // -- was given
+}
+
+func (p *ProjectTask) parseArgs(args []string) (*taskArgs, error) {
+	ta := &taskArgs{timeout: DefaultTaskTimeout}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch a {
+		case "--":
+			ta.extra, ta.replace = args[i+1:], true
+			return ta, nil
+		case "-n", "--dry-run":
+			ta.dryRun = true
+		case "--type", "--timeout":
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("%s: %s requires a value", p.Task, a)
+			}
+			i++
+			if a == "--type" {
+				if !slices.Contains(ProjectTypes, args[i]) {
+					return nil, fmt.Errorf("%s: unknown project type %q (want %s)", p.Task, args[i], strings.Join(ProjectTypes, ", "))
+				}
+				ta.kind = args[i]
+				continue
+			}
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s: invalid timeout %q", p.Task, args[i])
+			}
+			ta.timeout = d
+		default:
+			return nil, fmt.Errorf("%s: unexpected argument %q; pass tool arguments after --", p.Task, a)
+		}
+	}
+	return ta, nil
+}
+
+// command returns the command for the task in a project of the given
+// kind: a configured override, the built-in runner, or a Makefile target.
+// A make project without a matching target gets the conventional one, so
+// that make can report what is missing.
+func (p *ProjectTask) command(root, kind string, ta *taskArgs) []string {
+	if o := strings.Fields(p.Overrides[kind]); len(o) > 0 {
+		return append(o, ta.extra...)
+	}
+	if run := projectTaskTable[p.Task].runners[kind]; run != nil {
+		if argv := run(root, ta.extra, ta.replace); argv != nil {
+			return argv
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "Makefile")); err == nil {
+		for _, target := range projectTaskTable[p.Task].makeTargets {
+			if regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(target) + `\s*:`).Match(data) {
+				return append([]string{"make", target}, ta.extra...)
+			}
+		}
+	}
+	if kind == "make" {
+		return append([]string{"make", projectTaskTable[p.Task].makeTargets[0]}, ta.extra...)
+	}
+	return nil
+}
+
+// detectProject names the kind of project at root, or "" if it cannot
+// tell. A Makefile is the last resort, since most projects with one also
+// have a native toolchain.
+func detectProject(root string) string {
+	has := func(name string) bool { return exists(filepath.Join(root, name)) }
+	switch {
+	case has("go.mod"):
+		return "go"
+	case has("Cargo.toml"):
+		return "rust"
+	case has("package.json"):
+		return "node"
+	case has("pyproject.toml") || has("setup.py") || has("setup.cfg") || has("requirements.txt") || has("pytest.ini"):
+		return "python"
+	case has("Makefile"):
+		return "make"
+	}
+	return ""
+}
+
+// packageScripts returns the scripts of the package.json at path.
+func packageScripts(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return nil
+	}
+	return pkg.Scripts
+}
+
+func (p *ProjectTask) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	ta, err := p.parseArgs(args)
+	if err != nil {
+		return err
+	}
+	root := cap.CwdFromContext(ctx)
+	if root == "" {
+		root = "."
+	}
+	kind := ta.kind
+	if kind == "" {
+		if kind = detectProject(root); kind == "" {
+			return fmt.Errorf("%s: no go.mod, Cargo.toml, package.json, Python project files, or Makefile found; use --type", p.Task)
+		}
+	}
+	argv := p.command(root, kind, ta)
+	if argv == nil {
+		return fmt.Errorf("%s: no default for %s projects and no Makefile target; set project.commands.%s.%s in the config", p.Task, kind, kind, p.Task)
+	}
+	fmt.Fprintf(stderr, "%s: %s project: %s\n", p.Task, kind, strings.Join(argv, " "))
+	if ta.dryRun {
+		return nil
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, ta.timeout)
+	defer cancel()
+	err = runExternal(tctx, argv[0], argv[1:], stdin, stdout, stderr)
+	if errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		fmt.Fprintf(stderr, "%s: timed out after %s\n", p.Task, ta.timeout)
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// DefaultReadLimit caps read's output unless -c says otherwise.
+const DefaultReadLimit = 64 << 10
+
+// Read prints files, or line ranges of them, with line numbers. Unlike cat
+// piped through sed -n, the range is part of the command policy sees, and
+// output is capped so that reading a large file pages instead of flooding
+// the caller. doit runs it in-process.
+type Read struct{}
+
+var (
+	_ cap.Capability = (*Read)(nil)
+	_ cap.Runner     = (*Read)(nil)
+)
+
+func (r *Read) Name() string        { return "read" }
+func (r *Read) Description() string { return "print files or line ranges with line numbers" }
+func (r *Read) Tier() cap.Tier      { return cap.TierRead }
+
+func (r *Read) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"read main.go", "read engine/engine.go:100-160", "read -N go.mod", "read -c 200000 big.log:5000-"},
+		Flags: []cap.Flag{
+			{Name: "-N", Description: "omit line numbers"},
+			{Name: "-c", Description: fmt.Sprintf("maximum bytes of output (default %d; 0 for no limit)", DefaultReadLimit)},
+		},
+		TierRationale: "only reads files",
+	}
+}
+
+func (r *Read) Validate(args []string) error {
+	_, err := parseReadArgs(args)
+	return err
+}
+
+// readArgs is a parsed read command line.
+type readArgs struct {
+	numbers bool
+	limit   int
+	specs   []string
+}
+
+func parseReadArgs(args []string) (*readArgs, error) {
+	ra := &readArgs{numbers: true, limit: DefaultReadLimit}
+	opts := true
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case opts && a == "--":
+			opts = false
+		case opts && (a == "-N" || a == "--no-numbers"):
+			ra.numbers = false
+		case opts && (a == "-c" || strings.HasPrefix(a, "-c") || strings.HasPrefix(a, "--bytes=")):
+			v := strings.TrimPrefix(strings.TrimPrefix(a, "--bytes="), "-c")
+			if v == "" {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("read: -c requires a byte count")
+				}
+				i++
+				v = args[i]
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("read: invalid byte count %q", v)
+			}
+			ra.limit = n
+		case opts && strings.HasPrefix(a, "-") && a != "-":
+			return nil, fmt.Errorf("read: unknown flag %s", a)
+		default:
+			if _, _, _, err := splitReadSpec(a, ""); err != nil {
+				return nil, err
+			}
+			ra.specs = append(ra.specs, a)
+		}
+	}
+	if len(ra.specs) == 0 {
+		return nil, fmt.Errorf("read requires at least one file")
+	}
+	return ra, nil
+}
+
+// lineRange matches the range suffix of a read spec: 100, 100-160, or 100-.
+var lineRange = regexp.MustCompile(`^(\d+)(-(\d*))?$`)
+
+// splitReadSpec splits file:start-end into its path and line range. end is
+// 0 for "to the end of the file". A spec naming an existing file is taken
+// whole, so files with colons in their names can still be read. With cwd
+// empty, no file is looked up.
+func splitReadSpec(spec, cwd string) (path string, start, end int, err error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 || cwd != "" && exists(resolve(spec, cwd)) {
+		return spec, 1, 0, nil
+	}
+	m := lineRange.FindStringSubmatch(spec[i+1:])
+	if m == nil {
+		return spec, 1, 0, nil
+	}
+	start, _ = strconv.Atoi(m[1])
+	switch {
+	case m[2] == "":
+		end = start
+	case m[3] != "":
+		end, _ = strconv.Atoi(m[3])
+	}
+	if start < 1 || end != 0 && end < start {
+		return "", 0, 0, fmt.Errorf("read: invalid line range %q", spec[i+1:])
+	}
+	return spec[:i], start, end, nil
+}
+
+func resolve(path, cwd string) string {
+	if filepath.IsAbs(path) || cwd == "" {
+		return path
+	}
+	return filepath.Join(cwd, path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// errReadLimit stops output once the byte limit is reached.
+var errReadLimit = errors.New("read limit reached")
+
+func (r *Read) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	ra, err := parseReadArgs(args)
+	if err != nil {
+		return err
+	}
+	cwd := cap.CwdFromContext(ctx)
+	if cwd == "" {
+		cwd = "."
+	}
+	budget := ra.limit
+	for n, spec := range ra.specs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path, start, end, err := splitReadSpec(spec, cwd)
+		if err != nil {
+			return err
+		}
+		if len(ra.specs) > 1 {
+			if n > 0 {
+				fmt.Fprintln(stdout)
+			}
+			fmt.Fprintf(stdout, "==> %s <==\n", spec)
+		}
+		next, err := readRange(resolve(path, cwd), start, end, ra.numbers, stdout, &budget, ra.limit > 0)
+		if errors.Is(err, errReadLimit) {
+			fmt.Fprintf(stderr, "read: output truncated at %d bytes; continue with: read %s:%d-\n", ra.limit, path, next)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRange writes lines start through end (0: the last line) of the file
+// at path, charging each line to *budget when limited. On errReadLimit, it
+// returns the first line it did not write.
+func readRange(path string, start, end int, numbers bool, w io.Writer, budget *int, limited bool) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("read: %w", err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if head, _ := br.Peek(8 << 10); bytes.IndexByte(head, 0) >= 0 {
+		return 0, fmt.Errorf("read: %s: binary file", path)
+	}
+	line := 0
+	for {
+		text, err := br.ReadString('\n')
+		if text == "" && err != nil {
+			if err != io.EOF {
+				return 0, fmt.Errorf("read: %s: %w", path, err)
+			}
+			if line < start && !(line == 0 && start == 1) {
+				return 0, fmt.Errorf("read: %s has %d lines; range starts at %d", path, line, start)
+			}
+			return 0, nil
+		}
+		line++
+		if line < start {
+			continue
+		}
+		if end != 0 && line > end {
+			return 0, nil
+		}
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		if numbers {
+			text = fmt.Sprintf("%6d\t%s", line, text)
+		}
+		if limited {
+			if len(text) > *budget {
+				return line, errReadLimit
+			}
+			*budget -= len(text)
+		}
+		if _, err := io.WriteString(w, text); err != nil {
+			return 0, err
+		}
+	}
+}
@@ -3,12 +3,16 @@
 
 package builtin
 
-import "github.com/marcelocantos/doit/internal/cap"
+import (
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/paths"
+)
 
 // RegisterAll adds all built-in capabilities to the registry.
 func RegisterAll(r *cap.Registry) {
 	r.Register(&Cat{})
 	r.Register(&Chmod{})
+	r.Register(&Chown{})
 	r.Register(&Cp{})
 	r.Register(&Find{})
 	r.Register(&Git{})
@@ -19,11 +23,22 @@ func RegisterAll(r *cap.Registry) {
 	r.Register(&Make{})
 	r.Register(&Mkdir{})
 	r.Register(&Mv{})
+	r.Register(&Overview{})
+	r.Register(&Read{})
 	r.Register(&Rm{})
+	r.Register(&Search{})
 	r.Register(&Sort{})
 	r.Register(&Tail{})
+	r.Register(&Targets{})
 	r.Register(&Tee{})
 	r.Register(&Tr{})
 	r.Register(&Uniq{})
 	r.Register(&Wc{})
+	r.Register(&Write{BackupDir: paths.Backups()})
+	for _, in := range Interpreters {
+		r.Register(in)
+	}
+	for _, task := range ProjectTasks {
+		r.Register(&ProjectTask{Task: task})
+	}
 }
@@ -24,3 +24,13 @@ func (r *Rm) Validate(args []string) error {
 	return nil
 }
 
+func (r *Rm) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"rm build/output.o", "rm -r /tmp/scratch-dir"},
+		Flags: []cap.Flag{
+			{Name: "-r", Description: "remove directories recursively; never allowed on /, ., .., or ~"},
+			{Name: "-f", Description: "ignore missing files, never prompt"},
+		},
+		TierRationale: "deletes files irrecoverably",
+	}
+}
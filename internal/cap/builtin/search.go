@@ -0,0 +1,251 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// DefaultSearchLimit caps search's matches unless --max says otherwise.
+const DefaultSearchLimit = 200
+
+// maxMatchText caps the length of a matched line in search results.
+const maxMatchText = 500
+
+// Search runs ripgrep and reports its matches as JSON in a stable schema,
+// so that agents need not parse grep's output and the number of results
+// can be capped. doit runs it in-process.
+type Search struct{}
+
+var (
+	_ cap.Capability = (*Search)(nil)
+	_ cap.Runner     = (*Search)(nil)
+	_ cap.Dependent  = (*Search)(nil)
+)
+
+func (s *Search) Name() string        { return "search" }
+func (s *Search) Description() string { return "search files with ripgrep, returning matches as JSON" }
+func (s *Search) Tier() cap.Tier      { return cap.TierRead }
+
+func (s *Search) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"search TODO", "search -i -t go 'func New' internal/", "search -F --max 50 'err != nil'"},
+		Flags: []cap.Flag{
+			{Name: "-i", Description: "case-insensitive"},
+			{Name: "-F", Description: "treat the pattern as a literal string"},
+			{Name: "-w", Description: "match whole words only"},
+			{Name: "-g", Description: "include or exclude files by glob (-g '!*_test.go')"},
+			{Name: "-t", Description: "search only files of a type (go, py, js, ...)"},
+			{Name: "--hidden", Description: "search hidden files and directories"},
+			{Name: "--max", Description: fmt.Sprintf("maximum matches to return (default %d)", DefaultSearchLimit)},
+			{Name: "--pre", Description: "run a preprocessor on each file", Denied: true},
+		},
+		TierRationale: "only reads files",
+	}
+}
+
+// Requires names ripgrep, from the release that added --json.
+func (s *Search) Requires() []cap.Requirement {
+	return []cap.Requirement{{Program: "rg", Package: "ripgrep", MinVersion: "0.10.0"}}
+}
+
+func (s *Search) Validate(args []string) error {
+	_, _, err := parseSearchArgs(args)
+	return err
+}
+
+// searchFlags are the ripgrep flags search passes through; the value says
+// whether the flag takes an argument. Anything else is rejected, in
+// particular --pre, which runs arbitrary programs.
+var searchFlags = map[string]bool{
+	"-i": false, "--ignore-case": false, "-S": false, "--smart-case": false,
+	"-F": false, "--fixed-strings": false, "-w": false, "--word-regexp": false,
+	"--hidden": false, "-g": true, "--glob": true, "-t": true, "--type": true,
+	"-T": true, "--type-not": true,
+}
+
+// parseSearchArgs returns the arguments for rg and the match limit.
+func parseSearchArgs(args []string) (rgArgs []string, limit int, err error) {
+	limit = DefaultSearchLimit
+	var operands []string
+	opts := true
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case !opts || !strings.HasPrefix(a, "-") || a == "-":
+			operands = append(operands, a)
+		case a == "--":
+			opts = false
+		case a == "--max" || strings.HasPrefix(a, "--max="):
+			v, ok := strings.CutPrefix(a, "--max=")
+			if !ok {
+				if i+1 == len(args) {
+					return nil, 0, fmt.Errorf("search: --max requires a count")
+				}
+				i++
+				v = args[i]
+			}
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+				return nil, 0, fmt.Errorf("search: invalid --max %q", v)
+			}
+		default:
+			name, _, hasValue := strings.Cut(a, "=")
+			takesArg, ok := searchFlags[name]
+			if !ok {
+				return nil, 0, fmt.Errorf("search: flag %s is not supported", name)
+			}
+			rgArgs = append(rgArgs, a)
+			if takesArg && !hasValue {
+				if i+1 == len(args) {
+					return nil, 0, fmt.Errorf("search: %s requires a value", a)
+				}
+				i++
+				rgArgs = append(rgArgs, args[i])
+			}
+		}
+	}
+	if len(operands) == 0 {
+		return nil, 0, fmt.Errorf("search requires a pattern")
+	}
+	// --no-config keeps a RIPGREP_CONFIG_PATH file from adding flags,
+	// --pre among them.
+	rgArgs = append(rgArgs, "--no-config", "--json", "-e", operands[0], "--")
+	return append(rgArgs, operands[1:]...), limit, nil
+}
+
+// SearchMatch is one match in search's output.
+type SearchMatch struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`   // 1-based
+	Column int    `json:"column"` // 1-based byte offset within the line
+	Match  string `json:"match"`  // the matched text
+	Text   string `json:"text"`   // the whole line, without its newline, capped at 500 bytes
+}
+
+// SearchResult is search's output.
+type SearchResult struct {
+	Matches   []SearchMatch `json:"matches"`
+	Truncated bool          `json:"truncated"` // more matches were found than --max allows
+}
+
+func (s *Search) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	rgArgs, limit, err := parseSearchArgs(args)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("rg"); err != nil {
+		return fmt.Errorf("search requires ripgrep (rg) on PATH")
+	}
+
+	// Stop rg once the limit is reached rather than reading everything.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := externalCommand(ctx, "rg", rgArgs)
+	var rgErr strings.Builder
+	cmd.Stderr = &rgErr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	res, perr := parseRipgrepJSON(out, limit)
+	if res.Truncated || perr != nil {
+		cancel()
+	}
+	werr := cmd.Wait()
+	if perr != nil {
+		return perr
+	}
+	// rg exits 1 when nothing matched and 2 on errors, which it also
+	// reports for unreadable files alongside real matches.
+	var exitErr *exec.ExitError
+	if !res.Truncated && errors.As(werr, &exitErr) && exitErr.ExitCode() == 2 && len(res.Matches) == 0 {
+		return fmt.Errorf("search: %s", strings.TrimSpace(rgErr.String()))
+	}
+	if res.Matches == nil {
+		res.Matches = []SearchMatch{}
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+// rgData is ripgrep's representation of possibly non-UTF-8 text.
+type rgData struct {
+	Text  *string `json:"text"`
+	Bytes string  `json:"bytes"`
+}
+
+func (d rgData) String() string {
+	if d.Text != nil {
+		return *d.Text
+	}
+	b, _ := base64.StdEncoding.DecodeString(d.Bytes)
+	return string(b)
+}
+
+// rgEvent is the part of a ripgrep --json message search uses.
+type rgEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Path       rgData `json:"path"`
+		Lines      rgData `json:"lines"`
+		LineNumber int    `json:"line_number"`
+		Submatches []struct {
+			Match rgData `json:"match"`
+			Start int    `json:"start"`
+		} `json:"submatches"`
+	} `json:"data"`
+}
+
+// parseRipgrepJSON reads ripgrep --json output, keeping up to limit
+// matches. A line with several matches yields one SearchMatch for each.
+func parseRipgrepJSON(r io.Reader, limit int) (SearchResult, error) {
+	var res SearchResult
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		var ev rgEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return res, fmt.Errorf("search: reading rg output: %w", err)
+		}
+		if ev.Type != "match" {
+			continue
+		}
+		text := strings.TrimRight(ev.Data.Lines.String(), "\r\n")
+		if len(text) > maxMatchText {
+			text = text[:maxMatchText]
+		}
+		for _, sm := range ev.Data.Submatches {
+			if len(res.Matches) == limit {
+				res.Truncated = true
+				return res, nil
+			}
+			res.Matches = append(res.Matches, SearchMatch{
+				Path:   ev.Data.Path.String(),
+				Line:   ev.Data.LineNumber,
+				Column: sm.Start + 1,
+				Match:  sm.Match.String(),
+				Text:   text,
+			})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return res, fmt.Errorf("search: reading rg output: %w", err)
+	}
+	return res, nil
+}
@@ -17,3 +17,15 @@ func (s *Sort) Description() string { return "sort lines of text" }
 func (s *Sort) Tier() cap.Tier      { return cap.TierRead }
 func (s *Sort) Validate(args []string) error { return nil }
 
+func (s *Sort) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"sort -u names.txt", "sort -k2 -n data.tsv"},
+		Flags: []cap.Flag{
+			{Name: "-u", Description: "output unique lines only"},
+			{Name: "-n", Description: "numeric sort"},
+			{Name: "-k", Description: "sort by key field"},
+			{Name: "-o", Description: "write to a file instead of stdout"},
+		},
+		TierRationale: "reads input and writes to stdout",
+	}
+}
@@ -17,3 +17,13 @@ func (t *Tail) Description() string { return "output the last part of files or s
 func (t *Tail) Tier() cap.Tier      { return cap.TierRead }
 func (t *Tail) Validate(args []string) error { return nil }
 
+func (t *Tail) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"tail -50 server.log", "tail -n +2 data.csv"},
+		Flags: []cap.Flag{
+			{Name: "-n", Description: "number of lines to print (+N starts at line N)"},
+			{Name: "-c", Description: "number of bytes to print"},
+		},
+		TierRationale: "only reads files",
+	}
+}
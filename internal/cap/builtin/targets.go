@@ -0,0 +1,272 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// Targets lists the targets a project's Makefile, justfile, and Taskfile
+// define, with their descriptions, so that agents can find the automation
+// a project sanctions rather than inventing raw commands. It parses the
+// files itself instead of asking make, just, or task, none of which can
+// list targets without evaluating the file, and so possibly running
+// $(shell ...) or backticks. doit runs it in-process.
+type Targets struct{}
+
+var (
+	_ cap.Capability = (*Targets)(nil)
+	_ cap.Runner     = (*Targets)(nil)
+)
+
+func (t *Targets) Name() string        { return "targets" }
+func (t *Targets) Description() string { return "list make, just, and task targets with descriptions" }
+func (t *Targets) Tier() cap.Tier      { return cap.TierRead }
+
+func (t *Targets) Help() cap.Help {
+	return cap.Help{
+		Examples:      []string{"targets", "targets tools/"},
+		TierRationale: "only reads build files, without evaluating them",
+	}
+}
+
+func (t *Targets) Validate(args []string) error {
+	_, err := parseTargetsArgs(args)
+	return err
+}
+
+func parseTargetsArgs(args []string) (string, error) {
+	switch {
+	case len(args) > 1:
+		return "", fmt.Errorf("targets takes one directory")
+	case len(args) == 1 && strings.HasPrefix(args[0], "-"):
+		return "", fmt.Errorf("targets: unknown flag %s", args[0])
+	case len(args) == 1:
+		return args[0], nil
+	}
+	return ".", nil
+}
+
+// target is one entry in targets' output.
+type target struct {
+	name string // with any parameters, for just recipes
+	desc string
+}
+
+// targetSources are the files targets reads, by tool, each with the names
+// the tool looks for in the order it looks.
+var targetSources = []struct {
+	tool  string
+	names []string
+	parse func(data []byte) []target
+}{
+	{"make", []string{"GNUmakefile", "makefile", "Makefile"}, parseMakeTargets},
+	{"just", []string{"justfile", "Justfile", ".justfile"}, parseJustRecipes},
+	{"task", []string{"Taskfile.yml", "taskfile.yml", "Taskfile.yaml", "taskfile.yaml"}, parseTaskfile},
+}
+
+func (t *Targets) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	path, err := parseTargetsArgs(args)
+	if err != nil {
+		return err
+	}
+	dir := path
+	if cwd := cap.CwdFromContext(ctx); cwd != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+
+	found := false
+	for _, src := range targetSources {
+		for _, name := range src.names {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			if found {
+				fmt.Fprintln(stdout)
+			}
+			found = true
+			targets := src.parse(data)
+			fmt.Fprintf(stdout, "%s (%s):\n", name, src.tool)
+			if len(targets) == 0 {
+				fmt.Fprintln(stdout, "  (no targets)")
+			}
+			width := 0
+			for _, tg := range targets {
+				width = max(width, len(tg.name))
+			}
+			for _, tg := range targets {
+				if tg.desc == "" {
+					fmt.Fprintf(stdout, "  %s\n", tg.name)
+				} else {
+					fmt.Fprintf(stdout, "  %-*s  %s\n", width, tg.name, tg.desc)
+				}
+			}
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("targets: no Makefile, justfile, or Taskfile in %s", path)
+	}
+	return nil
+}
+
+// parseMakeTargets returns the explicit targets of a Makefile, in order.
+// A target's description is a "## text" comment on its rule line or, failing
+// that, the comment line just above it. Pattern rules, special targets
+// such as .PHONY, and targets built from variables are left out, as are
+// included files.
+func parseMakeTargets(data []byte) []target {
+	var targets []target
+	index := map[string]int{}
+	comment, inDefine := "", false
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		line := sc.Text()
+		if f := strings.Fields(line); len(f) > 0 && (f[0] == "define" || f[0] == "endef") {
+			inDefine = f[0] == "define"
+			continue
+		}
+		if inDefine {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		}
+		prev := comment
+		comment = ""
+		if strings.HasPrefix(line, "\t") {
+			continue // recipe
+		}
+		desc := ""
+		if i := strings.Index(line, "##"); i >= 0 {
+			line, desc = line[:i], strings.TrimSpace(line[i+2:])
+		} else if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		lhs, rest, ok := strings.Cut(line, ":")
+		if !ok || strings.ContainsAny(lhs, "=$%") || strings.HasPrefix(rest, "=") ||
+			strings.HasPrefix(rest, ":=") || strings.HasPrefix(rest, "::=") {
+			continue // not a rule, or an assignment
+		}
+		if desc == "" {
+			desc = prev
+		}
+		for _, name := range strings.Fields(lhs) {
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			if i, ok := index[name]; ok {
+				if targets[i].desc == "" {
+					targets[i].desc = desc
+				}
+				continue
+			}
+			index[name] = len(targets)
+			targets = append(targets, target{name: name, desc: desc})
+		}
+	}
+	return targets
+}
+
+// justRecipe matches a justfile recipe line: an optional @, the name, any
+// parameters, and the colon.
+var justRecipe = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)((?:\s+[^:]*?)?)\s*:([^=]|$)`)
+
+// justDoc matches a [doc("text")] attribute.
+var justDoc = regexp.MustCompile(`^\[doc\(\s*["'](.*)["']\s*\)\]$`)
+
+// parseJustRecipes returns the public recipes of a justfile, in order,
+// with their parameters. A recipe's description is its [doc] attribute
+// or the comment line just above it, as in just --list.
+func parseJustRecipes(data []byte) []target {
+	var targets []target
+	comment, private := "", false
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t")
+		switch {
+		case strings.HasPrefix(line, "#"):
+			comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			continue
+		case strings.HasPrefix(line, "["):
+			if m := justDoc.FindStringSubmatch(line); m != nil {
+				comment = m[1]
+			} else if strings.Contains(line, "private") {
+				private = true
+			}
+			continue
+		}
+		m := justRecipe.FindStringSubmatch(line)
+		desc, hidden := comment, private
+		comment, private = "", false
+		if m == nil || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		switch m[1] {
+		case "alias", "export", "set", "import", "mod":
+			continue
+		}
+		if hidden || strings.HasPrefix(m[1], "_") {
+			continue
+		}
+		name := m[1]
+		if params := strings.TrimSpace(m[2]); params != "" {
+			name += " " + params
+		}
+		targets = append(targets, target{name: name, desc: desc})
+	}
+	return targets
+}
+
+// parseTaskfile returns the public tasks of a Taskfile, in order, with
+// their desc (or, failing that, summary) fields.
+func parseTaskfile(data []byte) []target {
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	var tasks *yaml.Node
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "tasks" {
+			tasks = root.Content[i+1]
+		}
+	}
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil
+	}
+	var targets []target
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		var task struct {
+			Desc     string `yaml:"desc"`
+			Summary  string `yaml:"summary"`
+			Internal bool   `yaml:"internal"`
+		}
+		// A task may also be a command string or list; those have no
+		// description.
+		_ = tasks.Content[i+1].Decode(&task)
+		if task.Internal {
+			continue
+		}
+		desc := task.Desc
+		if desc == "" {
+			desc, _, _ = strings.Cut(strings.TrimSpace(task.Summary), "\n")
+		}
+		targets = append(targets, target{name: tasks.Content[i].Value, desc: desc})
+	}
+	return targets
+}
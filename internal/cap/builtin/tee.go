@@ -17,3 +17,12 @@ func (t *Tee) Description() string { return "duplicate stdin to stdout and files
 func (t *Tee) Tier() cap.Tier      { return cap.TierWrite }
 func (t *Tee) Validate(args []string) error { return nil }
 
+func (t *Tee) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"go test ./... | tee test.log", "tee -a notes.txt"},
+		Flags: []cap.Flag{
+			{Name: "-a", Description: "append instead of overwriting"},
+		},
+		TierRationale: "writes its input to files",
+	}
+}
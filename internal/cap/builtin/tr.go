@@ -24,3 +24,13 @@ func (t *Tr) Validate(args []string) error {
 	return nil
 }
 
+func (t *Tr) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"tr a-z A-Z", "tr -d \"\\r\""},
+		Flags: []cap.Flag{
+			{Name: "-d", Description: "delete characters in the set"},
+			{Name: "-s", Description: "squeeze repeated characters"},
+		},
+		TierRationale: "filters stdin to stdout",
+	}
+}
@@ -17,3 +17,13 @@ func (u *Uniq) Description() string { return "report or omit repeated lines" }
 func (u *Uniq) Tier() cap.Tier      { return cap.TierRead }
 func (u *Uniq) Validate(args []string) error { return nil }
 
+func (u *Uniq) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"sort words.txt | uniq -c", "uniq -d sorted.txt"},
+		Flags: []cap.Flag{
+			{Name: "-c", Description: "prefix lines with occurrence counts"},
+			{Name: "-d", Description: "only print duplicated lines"},
+		},
+		TierRationale: "filters input to stdout",
+	}
+}
@@ -17,3 +17,14 @@ func (w *Wc) Description() string { return "word, line, character, and byte coun
 func (w *Wc) Tier() cap.Tier      { return cap.TierRead }
 func (w *Wc) Validate(args []string) error { return nil }
 
+func (w *Wc) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"wc -l *.go", "git ls-files | wc -l"},
+		Flags: []cap.Flag{
+			{Name: "-l", Description: "count lines"},
+			{Name: "-w", Description: "count words"},
+			{Name: "-c", Description: "count bytes"},
+		},
+		TierRationale: "only reads input",
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package builtin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/policy"
+)
+
+// Write replaces a file with the content on stdin, or applies a unified
+// diff from stdin, as a safer alternative to sed -i and shell redirection.
+// Files must lie within the working directory and outside the directories
+// redirection into escalates (.git, directories of executables, and doit's
+// own configuration and state); each is replaced atomically, its previous
+// content is kept in BackupDir under its SHA-256, and the hashes before
+// and after are recorded for the audit log.
+// doit runs it in-process.
+type Write struct {
+	BackupDir string
+}
+
+var (
+	_ cap.Capability = (*Write)(nil)
+	_ cap.Runner     = (*Write)(nil)
+)
+
+func (w *Write) Name() string { return "write" }
+func (w *Write) Description() string {
+	return "write a file from stdin, or apply a unified diff, within the workspace"
+}
+func (w *Write) Tier() cap.Tier { return cap.TierWrite }
+
+func (w *Write) Help() cap.Help {
+	return cap.Help{
+		Examples: []string{"write internal/config/defaults.go", "write --patch"},
+		Flags: []cap.Flag{
+			{Name: "--patch", Description: "stdin is a unified diff (diff -u or git diff) naming the files to change"},
+		},
+		TierRationale: "modifies files, though only inside the workspace and with backups",
+	}
+}
+
+func (w *Write) Validate(args []string) error {
+	_, _, err := parseWriteArgs(args)
+	return err
+}
+
+func parseWriteArgs(args []string) (patch bool, path string, err error) {
+	for _, a := range args {
+		switch {
+		case a == "--patch" || a == "-p":
+			patch = true
+		case strings.HasPrefix(a, "-"):
+			return false, "", fmt.Errorf("write: unknown flag %s", a)
+		case path != "":
+			return false, "", fmt.Errorf("write takes one file; use --patch to change several")
+		default:
+			path = a
+		}
+	}
+	switch {
+	case patch && path != "":
+		return false, "", fmt.Errorf("write --patch takes the file names from the diff, not the command line")
+	case !patch && path == "":
+		return false, "", fmt.Errorf("write requires a file, or --patch")
+	}
+	return patch, path, nil
+}
+
+// pendingWrite is a file's new content, checked and ready to write.
+type pendingWrite struct {
+	name    string // as given, for messages
+	path    string // absolute
+	content string
+	before  string // hash of the current content; empty for a new file
+	mode    fs.FileMode
+}
+
+func (w *Write) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	patch, path, err := parseWriteArgs(args)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(cap.CwdFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("write: reading stdin: %w", err)
+	}
+	if len(data) == 0 {
+		// Also guards replays (doit --rerun), which have no stdin.
+		return fmt.Errorf("write reads the new content from stdin, which is empty")
+	}
+
+	// Check every file before writing any, so that a patch that fails
+	// part-way through changes nothing.
+	var writes []pendingWrite
+	if patch {
+		patches, err := parsePatch(string(data))
+		if err != nil {
+			return err
+		}
+		for _, fp := range patches {
+			if fp.newPath == "" {
+				return fmt.Errorf("write does not delete files (%s); use rm", fp.oldPath)
+			}
+			if fp.oldPath != "" && fp.oldPath != fp.newPath {
+				return fmt.Errorf("write does not rename files (%s → %s); use mv", fp.oldPath, fp.newPath)
+			}
+			pw, old, err := prepareWrite(root, fp.newPath)
+			if err != nil {
+				return err
+			}
+			if fp.oldPath == "" && pw.before != "" {
+				return fmt.Errorf("write: patch creates %s, which already exists", fp.newPath)
+			}
+			if fp.oldPath != "" && pw.before == "" {
+				return fmt.Errorf("write: patch changes %s, which does not exist", fp.newPath)
+			}
+			if pw.content, err = fp.apply(old); err != nil {
+				return err
+			}
+			writes = append(writes, pw)
+		}
+	} else {
+		pw, _, err := prepareWrite(root, path)
+		if err != nil {
+			return err
+		}
+		pw.content = string(data)
+		writes = append(writes, pw)
+	}
+
+	for _, pw := range writes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		after := hashString(pw.content)
+		if pw.before != "" {
+			if pw.before == after {
+				fmt.Fprintf(stdout, "unchanged %s\n", pw.name)
+				continue
+			}
+			if err := w.backup(pw.path, pw.before); err != nil {
+				return err
+			}
+		}
+		if pw.before == "" {
+			pw.mode = newFileMode(ctx)
+		}
+		if err := writeAtomic(pw.path, pw.content, pw.mode); err != nil {
+			return err
+		}
+		cap.RecordChange(ctx, cap.FileChange{Path: pw.path, Before: pw.before, After: after})
+		if pw.before == "" {
+			fmt.Fprintf(stdout, "created %s (sha256 %.12s)\n", pw.name, after)
+		} else {
+			fmt.Fprintf(stdout, "updated %s (sha256 %.12s → %.12s)\n", pw.name, pw.before, after)
+		}
+	}
+	return nil
+}
+
+// prepareWrite checks that name may be written and returns its current
+// content, which is empty for a new file.
+func prepareWrite(root, name string) (pendingWrite, string, error) {
+	pw := pendingWrite{name: name, path: name, mode: 0o644}
+	if !filepath.IsAbs(pw.path) {
+		pw.path = filepath.Join(root, pw.path)
+	}
+	pw.path = filepath.Clean(pw.path)
+	if !within(pw.path, root) {
+		return pw, "", fmt.Errorf("write: %s is outside the workspace %s", name, root)
+	}
+	if dir := policy.ProtectedDir(pw.path); dir != "" {
+		return pw, "", fmt.Errorf("write: %s is in %s", name, dir)
+	}
+	// Resolve symlinks in the directories, so that a link cannot lead out
+	// of the workspace.
+	dir, err := existingAncestor(filepath.Dir(pw.path))
+	if err != nil {
+		return pw, "", fmt.Errorf("write: %w", err)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return pw, "", fmt.Errorf("write: %w", err)
+	}
+	if !within(dir, realRoot) {
+		return pw, "", fmt.Errorf("write: %s resolves outside the workspace %s", name, root)
+	}
+	if pd := policy.ProtectedDir(dir); pd != "" {
+		return pw, "", fmt.Errorf("write: %s resolves into %s", name, pd)
+	}
+
+	fi, err := os.Lstat(pw.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return pw, "", nil
+	case err != nil:
+		return pw, "", fmt.Errorf("write: %w", err)
+	case fi.Mode()&fs.ModeSymlink != 0:
+		return pw, "", fmt.Errorf("write: %s is a symlink; write its target instead", name)
+	case !fi.Mode().IsRegular():
+		return pw, "", fmt.Errorf("write: %s is not a regular file", name)
+	}
+	old, err := os.ReadFile(pw.path)
+	if err != nil {
+		return pw, "", fmt.Errorf("write: %w", err)
+	}
+	pw.before = hashString(string(old))
+	pw.mode = fi.Mode().Perm()
+	return pw, string(old), nil
+}
+
+// existingAncestor resolves the symlinks in the nearest existing ancestor
+// of dir.
+func existingAncestor(dir string) (string, error) {
+	for {
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return real, nil
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
+
+// within reports whether path is root or lies beneath it.
+func within(path, root string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// backup copies the file at path into BackupDir under its hash, unless a
+// copy of that content is already there.
+func (w *Write) backup(path, hash string) error {
+	if w.BackupDir == "" {
+		return nil
+	}
+	dst := filepath.Join(w.BackupDir, hash)
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("write: backing up %s: %w", path, err)
+	}
+	if err := os.MkdirAll(w.BackupDir, 0o700); err != nil {
+		return fmt.Errorf("write: backing up %s: %w", path, err)
+	}
+	if err := writeAtomic(dst, string(data), 0o600); err != nil {
+		return fmt.Errorf("write: backing up %s: %w", path, err)
+	}
+	return nil
+}
+
+// newFileMode returns the mode of a file write creates: 0644, or 0666
+// less the umask in ctx.
+func newFileMode(ctx context.Context) fs.FileMode {
+	if mask, ok := cap.UmaskFromContext(ctx); ok {
+		return 0o666 &^ mask
+	}
+	return 0o644
+}
+
+// writeAtomic replaces path with content by writing a temporary file in
+// the same directory and renaming it over path. Missing directories are
+// created searchable by whoever may read the file.
+func writeAtomic(path, content string, mode fs.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, mode|mode&0o444>>2); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".doit-*")
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	tmp := f.Name()
+	_, err = io.WriteString(f, content)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,7 +6,10 @@ package cap
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/marcelocantos/doit/internal/rules"
@@ -69,12 +72,114 @@ type Capability interface {
 	Validate(args []string) error
 }
 
+// Flag documents a single flag of a capability.
+type Flag struct {
+	Name        string `json:"name"` // e.g. "-n" or "--force"
+	Description string `json:"description"`
+	Denied      bool   `json:"denied,omitempty"` // rejected by Validate regardless of config
+}
+
+// Help is extended, per-capability guidance surfaced by `doit --help <cap>`,
+// `--help-agent`, and doit_list_capabilities.
+type Help struct {
+	Examples      []string // complete command lines, e.g. "grep -rn TODO src/"
+	Flags         []Flag   // commonly used and denied flags
+	TierRationale string   // why the capability has its tier
+}
+
+// Documented is implemented by capabilities that provide extended help.
+// It is optional: capabilities without it are described by Description
+// alone.
+type Documented interface {
+	Help() Help
+}
+
+// HelpFor returns the extended help for c, or the zero Help if c does not
+// implement Documented.
+func HelpFor(c Capability) Help {
+	if d, ok := c.(Documented); ok {
+		return d.Help()
+	}
+	return Help{}
+}
+
+// Networked is implemented by capabilities some of whose invocations
+// reach the network, such as git fetch or go mod download. It is
+// optional: capabilities without it are taken to work offline.
+type Networked interface {
+	UsesNetwork(args []string) bool
+}
+
+// UsesNetwork reports whether running c with args reaches the network.
+func UsesNetwork(c Capability, args []string) bool {
+	if n, ok := c.(Networked); ok {
+		return n.UsesNetwork(args)
+	}
+	return false
+}
+
+// Tiered is implemented by capabilities whose tier depends on how they
+// are invoked, such as git, whose log is read but whose push is
+// dangerous. It is optional: other capabilities are always at Tier.
+type Tiered interface {
+	TierFor(args []string) Tier
+}
+
+// TierOf returns the tier of running c with args.
+func TierOf(c Capability, args []string) Tier {
+	if t, ok := c.(Tiered); ok {
+		return t.TierFor(args)
+	}
+	return c.Tier()
+}
+
+// Runner is implemented by capabilities that doit executes itself
+// instead of handing to the shell, such as read. It is optional: other
+// capabilities name programs the shell runs. Run finds the working
+// directory, environment, and umask in ctx (CwdFromContext,
+// EnvFromContext, UmaskFromContext) and reports a non-zero exit as an
+// error carrying the code.
+type Runner interface {
+	Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// FormatHelp renders c's description, tier, and extended help as plain
+// text for terminal and tool output.
+func FormatHelp(c Capability) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s — %s\n", c.Name(), c.Description())
+	h := HelpFor(c)
+	fmt.Fprintf(&b, "\nTier: %s", c.Tier())
+	if h.TierRationale != "" {
+		fmt.Fprintf(&b, " (%s)", h.TierRationale)
+	}
+	b.WriteString("\n")
+	if len(h.Examples) > 0 {
+		b.WriteString("\nExamples:\n")
+		for _, ex := range h.Examples {
+			fmt.Fprintf(&b, "  %s\n", ex)
+		}
+	}
+	if len(h.Flags) > 0 {
+		b.WriteString("\nFlags:\n")
+		for _, f := range h.Flags {
+			desc := f.Description
+			if f.Denied {
+				desc += " [denied]"
+			}
+			fmt.Fprintf(&b, "  %-10s %s\n", f.Name, desc)
+		}
+	}
+	return b.String()
+}
+
 // Registry maps capability names to implementations and controls tier access.
 type Registry struct {
-	mu    sync.RWMutex
-	caps  map[string]Capability
-	tiers map[Tier]bool
-	rules *rules.RuleSet
+	mu        sync.RWMutex
+	caps      map[string]Capability
+	tiers     map[Tier]bool
+	rules     *rules.RuleSet
+	available map[string]bool // capabilities whose requirements were met
 }
 
 // NewRegistry creates a registry with all tiers enabled except Dangerous.
@@ -88,7 +193,8 @@ func NewRegistry() *Registry {
 			TierWrite:     true,
 			TierDangerous: false,
 		},
-		rules: rules.NewRuleSet(rules.Hardcoded()...),
+		rules:     rules.NewRuleSet(rules.Hardcoded()...),
+		available: make(map[string]bool),
 	}
 }
 
@@ -110,6 +216,27 @@ func (r *Registry) Lookup(name string) (Capability, error) {
 	return c, nil
 }
 
+// Available checks the named capability's requirements on first use (see
+// CheckRequirements), returning nil or an *UnavailableError. Unknown names
+// are not checked. Success is remembered; a failure is checked afresh
+// next time, so installing a missing program needs no restart.
+func (r *Registry) Available(name string) error {
+	r.mu.RLock()
+	c, ok := r.caps[name]
+	known := r.available[name]
+	r.mu.RUnlock()
+	if !ok || known {
+		return nil
+	}
+	if err := CheckRequirements(c); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.available[name] = true
+	r.mu.Unlock()
+	return nil
+}
+
 // CheckTier returns an error if the given tier is not enabled.
 func (r *Registry) CheckTier(t Tier) error {
 	r.mu.RLock()
@@ -188,6 +315,70 @@ func EnvFromContext(ctx context.Context) map[string]string {
 	return env
 }
 
+type umaskKey struct{}
+
+// NewUmaskContext returns a context with the file mode creation mask for
+// files a capability creates and programs it runs.
+func NewUmaskContext(ctx context.Context, mask fs.FileMode) context.Context {
+	return context.WithValue(ctx, umaskKey{}, mask)
+}
+
+// UmaskFromContext retrieves the umask from a context. ok is false if
+// none is set (meaning use the current process's).
+func UmaskFromContext(ctx context.Context) (mask fs.FileMode, ok bool) {
+	mask, ok = ctx.Value(umaskKey{}).(fs.FileMode)
+	return mask, ok
+}
+
+// FileChange records a file an in-process capability wrote, by SHA-256
+// of its content before and after.
+type FileChange struct {
+	Path   string
+	Before string // empty if the file was created
+	After  string
+}
+
+// Changes collects the files written while running a capability.
+type Changes struct {
+	mu   sync.Mutex
+	list []FileChange
+}
+
+// Record adds a change.
+func (c *Changes) Record(fc FileChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.list = append(c.list, fc)
+}
+
+// List returns the changes recorded so far.
+func (c *Changes) List() []FileChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FileChange(nil), c.list...)
+}
+
+type changesKey struct{}
+
+// NewChangesContext returns a context whose file changes are recorded in c.
+func NewChangesContext(ctx context.Context, c *Changes) context.Context {
+	return context.WithValue(ctx, changesKey{}, c)
+}
+
+// ChangesFromContext retrieves the change recorder from a context.
+// Returns nil if not set.
+func ChangesFromContext(ctx context.Context) *Changes {
+	c, _ := ctx.Value(changesKey{}).(*Changes)
+	return c
+}
+
+// RecordChange records fc in the context's recorder, if it has one.
+func RecordChange(ctx context.Context, fc FileChange) {
+	if c := ChangesFromContext(ctx); c != nil {
+		c.Record(fc)
+	}
+}
+
 // All returns all registered capabilities sorted by name.
 func (r *Registry) All() []Capability {
 	r.mu.RLock()
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/marcelocantos/doit/internal/rules"
@@ -27,6 +28,40 @@ func (m *mockCap) Run(_ context.Context, _ []string, _ io.Reader, _, _ io.Writer
 	return nil
 }
 
+// docCap is a mockCap with extended help.
+type docCap struct {
+	mockCap
+	help Help
+}
+
+func (d *docCap) Help() Help { return d.help }
+
+func TestFormatHelp(t *testing.T) {
+	plain := &mockCap{name: "plain", desc: "a plain cap", tier: TierRead}
+	if h := HelpFor(plain); len(h.Examples) != 0 || h.TierRationale != "" {
+		t.Errorf("HelpFor(undocumented) = %+v, want zero", h)
+	}
+	out := FormatHelp(plain)
+	if !strings.Contains(out, "plain — a plain cap") || !strings.Contains(out, "Tier: read") {
+		t.Errorf("FormatHelp(plain) = %q", out)
+	}
+
+	doc := &docCap{
+		mockCap: mockCap{name: "doc", desc: "documented", tier: TierWrite},
+		help: Help{
+			Examples:      []string{"doc -x file"},
+			Flags:         []Flag{{Name: "-x", Description: "do x"}, {Name: "-y", Description: "do y", Denied: true}},
+			TierRationale: "writes files",
+		},
+	}
+	out = FormatHelp(doc)
+	for _, want := range []string{"Tier: write (writes files)", "  doc -x file", "-x", "do y [denied]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatHelp(doc) missing %q:\n%s", want, out)
+		}
+	}
+}
+
 func TestParseTier(t *testing.T) {
 	tests := []struct {
 		input   string
@@ -0,0 +1,134 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package cap
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Requirement is an external program a capability needs in order to run.
+type Requirement struct {
+	Program    string // looked up on PATH, e.g. "rg"
+	Package    string // what to install to get it, e.g. "ripgrep"; defaults to Program
+	MinVersion string // oldest version that works, e.g. "0.10.0"; empty for any
+}
+
+func (r Requirement) pkg() string {
+	if r.Package != "" {
+		return r.Package
+	}
+	return r.Program
+}
+
+// Dependent is implemented by capabilities that declare the programs
+// they need. It is optional: a capability that is not a Runner needs the
+// program it is named after, and a Runner needs nothing.
+type Dependent interface {
+	Requires() []Requirement
+}
+
+// RequirementsFor returns the programs c needs.
+func RequirementsFor(c Capability) []Requirement {
+	if d, ok := c.(Dependent); ok {
+		return d.Requires()
+	}
+	if _, ok := c.(Runner); ok {
+		return nil
+	}
+	return []Requirement{{Program: c.Name()}}
+}
+
+// UnavailableError reports a capability that cannot run because a
+// program it needs is missing or too old.
+type UnavailableError struct {
+	Capability string
+	Reason     string // e.g. "ripgrep not installed"
+	Hint       string // what to do about it, e.g. "install ripgrep, or put rg on PATH"
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("capability %q unavailable: %s", e.Capability, e.Reason)
+}
+
+// lookPath and programVersion find programs and their versions; tests
+// replace them.
+var (
+	lookPath       = exec.LookPath
+	programVersion = versionOf
+)
+
+// versionPattern matches the first dotted version number in a program's
+// --version output, e.g. "14.1.0" in "ripgrep 14.1.0 (rev e50df40a19)".
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// versionOf runs path --version and returns the version it prints, or ""
+// if it prints none.
+func versionOf(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	return versionPattern.FindString(string(out)), nil
+}
+
+// CheckRequirements reports whether the programs c needs are installed
+// and new enough, returning nil or an *UnavailableError. A program whose
+// version cannot be determined is given the benefit of the doubt.
+func CheckRequirements(c Capability) error {
+	for _, req := range RequirementsFor(c) {
+		path, err := lookPath(req.Program)
+		if err != nil {
+			hint := "install " + req.pkg()
+			if req.pkg() != req.Program {
+				hint += ", or put " + req.Program + " on PATH"
+			}
+			return &UnavailableError{Capability: c.Name(), Reason: req.pkg() + " not installed", Hint: hint}
+		}
+		if req.MinVersion == "" {
+			continue
+		}
+		v, err := programVersion(path)
+		if err != nil || v == "" {
+			continue
+		}
+		if compareVersions(v, req.MinVersion) < 0 {
+			return &UnavailableError{
+				Capability: c.Name(),
+				Reason:     fmt.Sprintf("%s %s is older than the required %s", req.Program, v, req.MinVersion),
+				Hint:       fmt.Sprintf("upgrade %s to %s or later", req.pkg(), req.MinVersion),
+			}
+		}
+	}
+	return nil
+}
+
+// compareVersions compares dotted version numbers component by component,
+// treating missing components as zero: -1 if a < b, 0 if equal, 1 if a > b.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
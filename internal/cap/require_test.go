@@ -0,0 +1,129 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package cap
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+// progCap is a capability the shell runs, so it needs the program it is
+// named after.
+type progCap struct{ name string }
+
+func (p *progCap) Name() string                 { return p.name }
+func (p *progCap) Description() string          { return "" }
+func (p *progCap) Tier() Tier                   { return TierRead }
+func (p *progCap) Validate(args []string) error { return nil }
+
+// depCap declares its requirements.
+type depCap struct {
+	mockCap
+	reqs []Requirement
+}
+
+func (d *depCap) Requires() []Requirement { return d.reqs }
+
+// fakePrograms replaces PATH lookup and --version with the given
+// versions, keyed by program name, for the duration of the test.
+func fakePrograms(t *testing.T, versions map[string]string) *int {
+	lookups := 0
+	oldLook, oldVersion := lookPath, programVersion
+	lookPath = func(name string) (string, error) {
+		lookups++
+		if _, ok := versions[name]; !ok {
+			return "", exec.ErrNotFound
+		}
+		return "/bin/" + name, nil
+	}
+	programVersion = func(path string) (string, error) {
+		return versions[path[len("/bin/"):]], nil
+	}
+	t.Cleanup(func() { lookPath, programVersion = oldLook, oldVersion })
+	return &lookups
+}
+
+func TestCheckRequirements(t *testing.T) {
+	fakePrograms(t, map[string]string{"make": "", "rg": "0.9.0", "jq": "1.7.1"})
+
+	cases := []struct {
+		c      Capability
+		reason string // "" if available
+		hint   string
+	}{
+		{&progCap{"make"}, "", ""},
+		{&progCap{"ruby"}, "ruby not installed", "install ruby"},
+		{&mockCap{name: "runner"}, "", ""},
+		{&depCap{mockCap{name: "search"}, []Requirement{{Program: "rg", Package: "ripgrep"}}}, "", ""},
+		{&depCap{mockCap{name: "search"}, []Requirement{{Program: "rg", Package: "ripgrep", MinVersion: "0.10.0"}}},
+			"rg 0.9.0 is older than the required 0.10.0", "upgrade ripgrep to 0.10.0 or later"},
+		{&depCap{mockCap{name: "fd"}, []Requirement{{Program: "fd", Package: "fd-find"}}},
+			"fd-find not installed", "install fd-find, or put fd on PATH"},
+		{&depCap{mockCap{name: "json"}, []Requirement{{Program: "jq", MinVersion: "1.6"}}}, "", ""},
+		// An unknown version gets the benefit of the doubt.
+		{&depCap{mockCap{name: "build"}, []Requirement{{Program: "make", MinVersion: "4"}}}, "", ""},
+	}
+	for _, tc := range cases {
+		err := CheckRequirements(tc.c)
+		if tc.reason == "" {
+			if err != nil {
+				t.Errorf("%s: %v, want available", tc.c.Name(), err)
+			}
+			continue
+		}
+		var uerr *UnavailableError
+		if !errors.As(err, &uerr) || uerr.Reason != tc.reason || uerr.Hint != tc.hint {
+			t.Errorf("%s: %#v, want reason %q, hint %q", tc.c.Name(), err, tc.reason, tc.hint)
+		}
+	}
+
+	err := CheckRequirements(&progCap{"ruby"})
+	if err == nil || err.Error() != `capability "ruby" unavailable: ruby not installed` {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"0.10.0", "0.9.5", 1},
+		{"2.39", "2.39.0", 0},
+		{"1.2.3", "1.10", -1},
+		{"14.1.0", "14.1.0", 0},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestRegistryAvailable(t *testing.T) {
+	lookups := fakePrograms(t, map[string]string{"make": ""})
+	r := NewRegistry()
+	r.Register(&progCap{"make"})
+	r.Register(&progCap{"ruby"})
+
+	for range 2 {
+		if err := r.Available("make"); err != nil {
+			t.Errorf("make: %v", err)
+		}
+	}
+	if *lookups != 1 {
+		t.Errorf("make looked up %d times, want once", *lookups)
+	}
+	for range 2 {
+		if err := r.Available("ruby"); err == nil {
+			t.Error("ruby available, want not installed")
+		}
+	}
+	if *lookups != 3 {
+		t.Errorf("%d lookups, want a missing program checked every time", *lookups)
+	}
+	if err := r.Available("nosuchcap"); err != nil {
+		t.Errorf("unknown capability: %v", err)
+	}
+}
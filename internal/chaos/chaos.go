@@ -0,0 +1,215 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package chaos injects failures into doit so that its resilience — the
+// Level 3 circuit breaker, recovery from panics, monitors that miss
+// events, audit write failures — can be exercised in CI, or by an
+// operator rehearsing them, without waiting for the real thing.
+//
+// It is off unless $DOIT_CHAOS names the faults to inject:
+//
+//	DOIT_CHAOS=audit_error=0.2,llm_error=1,policy_delay=500ms,drop_frames=0.1,panic=0.05,seed=42
+//
+// Probabilities are between 0 and 1; seed makes the choice of which
+// calls fail repeatable. A nil *Injector injects nothing, so code can
+// consult one unconditionally.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvVar names the faults to inject; see the package documentation.
+const EnvVar = "DOIT_CHAOS"
+
+// Faults are the failures an Injector injects.
+type Faults struct {
+	AuditError  float64       // probability an audit write fails
+	LLMError    float64       // probability a Level 3 LLM call fails
+	PolicyDelay time.Duration // added to every policy evaluation
+	DropFrame   float64       // probability an event is dropped before reaching monitors
+	Panic       float64       // probability a request panics before its command runs
+	Seed        uint64        // seeds the choice of failing calls; 0 for a random seed
+}
+
+// Parse parses a comma-separated list of name=value faults, as $DOIT_CHAOS
+// holds them.
+func Parse(spec string) (Faults, error) {
+	var f Faults
+	for field := range strings.SplitSeq(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return f, fmt.Errorf("chaos: %q: want name=value", field)
+		}
+		var err error
+		switch name {
+		case "audit_error":
+			f.AuditError, err = probability(value)
+		case "llm_error":
+			f.LLMError, err = probability(value)
+		case "drop_frames":
+			f.DropFrame, err = probability(value)
+		case "panic":
+			f.Panic, err = probability(value)
+		case "policy_delay":
+			f.PolicyDelay, err = time.ParseDuration(value)
+			if err == nil && f.PolicyDelay < 0 {
+				err = errors.New("negative delay")
+			}
+		case "seed":
+			f.Seed, err = strconv.ParseUint(value, 10, 64)
+		default:
+			return f, fmt.Errorf("chaos: unknown fault %q (want audit_error, llm_error, policy_delay, drop_frames, panic, or seed)", name)
+		}
+		if err != nil {
+			return f, fmt.Errorf("chaos: %s: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+func probability(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("probability %s is not between 0 and 1", s)
+	}
+	return p, nil
+}
+
+// Injector decides, call by call, whether to inject each fault.
+type Injector struct {
+	f Faults
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New returns an Injector of f.
+func New(f Faults) *Injector {
+	seed := f.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &Injector{f: f, rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// FromEnv returns an Injector of the faults $DOIT_CHAOS names, or nil if
+// it is unset or empty.
+func FromEnv() (*Injector, error) {
+	spec := os.Getenv(EnvVar)
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	f, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return New(f), nil
+}
+
+// String describes the faults in, as Parse accepts them.
+func (in *Injector) String() string {
+	if in == nil {
+		return ""
+	}
+	var parts []string
+	add := func(name string, p float64) {
+		if p > 0 {
+			parts = append(parts, name+"="+strconv.FormatFloat(p, 'g', -1, 64))
+		}
+	}
+	add("audit_error", in.f.AuditError)
+	add("llm_error", in.f.LLMError)
+	if in.f.PolicyDelay > 0 {
+		parts = append(parts, "policy_delay="+in.f.PolicyDelay.String())
+	}
+	add("drop_frames", in.f.DropFrame)
+	add("panic", in.f.Panic)
+	return strings.Join(parts, ",")
+}
+
+func (in *Injector) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.rng.Float64() < p
+}
+
+// ErrInjected is the cause of every failure an Injector injects.
+var ErrInjected = errors.New("chaos: injected failure")
+
+// AuditError returns an error if an audit write should fail.
+func (in *Injector) AuditError() error {
+	if in != nil && in.roll(in.f.AuditError) {
+		return fmt.Errorf("audit write: %w", ErrInjected)
+	}
+	return nil
+}
+
+// DropFrame reports whether to drop an event.
+func (in *Injector) DropFrame() bool {
+	return in != nil && in.roll(in.f.DropFrame)
+}
+
+// MaybePanic panics if a request should, naming where.
+func (in *Injector) MaybePanic(where string) {
+	if in != nil && in.roll(in.f.Panic) {
+		panic(fmt.Sprintf("%v in %s", ErrInjected, where))
+	}
+}
+
+// Delay waits out the policy delay, or until ctx is done.
+func (in *Injector) Delay(ctx context.Context) {
+	if in == nil || in.f.PolicyDelay <= 0 {
+		return
+	}
+	t := time.NewTimer(in.f.PolicyDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// Prompter is the LLM client of Level 3 (policy.Prompter).
+type Prompter interface {
+	Prompt(ctx context.Context, prompt string) (string, error)
+}
+
+// Prompter returns p, made to fail as often as the faults say LLM calls
+// should. It returns p itself if they never should.
+func (in *Injector) Prompter(p Prompter) Prompter {
+	if in == nil || in.f.LLMError <= 0 || p == nil {
+		return p
+	}
+	return failingPrompter{p, in}
+}
+
+type failingPrompter struct {
+	Prompter
+	in *Injector
+}
+
+func (f failingPrompter) Prompt(ctx context.Context, prompt string) (string, error) {
+	if f.in.roll(f.in.f.LLMError) {
+		return "", fmt.Errorf("LLM call: %w", ErrInjected)
+	}
+	return f.Prompter.Prompt(ctx, prompt)
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	f, err := Parse(" audit_error=0.5, llm_error=1,policy_delay=250ms,drop_frames=0,panic=0.01,seed=42,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Faults{AuditError: 0.5, LLMError: 1, PolicyDelay: 250 * time.Millisecond, Panic: 0.01, Seed: 42}
+	if f != want {
+		t.Errorf("Parse = %+v, want %+v", f, want)
+	}
+	if got := New(f).String(); got != "audit_error=0.5,llm_error=1,policy_delay=250ms,panic=0.01" {
+		t.Errorf("String = %q", got)
+	}
+
+	for _, bad := range []string{"audit_error", "audit_error=2", "panic=-0.1", "policy_delay=-1s", "policy_delay=soon", "seed=x", "slow_disk=1"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvVar, "")
+	if in, err := FromEnv(); in != nil || err != nil {
+		t.Errorf("FromEnv unset = %v, %v", in, err)
+	}
+	t.Setenv(EnvVar, "panic=1")
+	if in, err := FromEnv(); in == nil || err != nil {
+		t.Errorf("FromEnv = %v, %v", in, err)
+	}
+	t.Setenv(EnvVar, "panic=yes")
+	if _, err := FromEnv(); err == nil {
+		t.Error("FromEnv accepted a bad spec")
+	}
+}
+
+func TestInjector_Nil(t *testing.T) {
+	var in *Injector
+	if in.AuditError() != nil || in.DropFrame() || in.String() != "" {
+		t.Error("nil Injector injected a failure")
+	}
+	in.MaybePanic("test")
+	in.Delay(context.Background())
+	p := prompterFunc(func() (string, error) { return "ok", nil })
+	if got := in.Prompter(p); got == nil {
+		t.Error("nil Injector lost the prompter")
+	}
+}
+
+func TestInjector_Always(t *testing.T) {
+	in := New(Faults{AuditError: 1, LLMError: 1, DropFrame: 1, Panic: 1, PolicyDelay: time.Hour})
+	if err := in.AuditError(); !errors.Is(err, ErrInjected) {
+		t.Errorf("AuditError = %v", err)
+	}
+	if !in.DropFrame() {
+		t.Error("DropFrame = false")
+	}
+	if _, err := in.Prompter(prompterFunc(func() (string, error) { return "ok", nil })).Prompt(context.Background(), "x"); !errors.Is(err, ErrInjected) {
+		t.Errorf("Prompt = %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MaybePanic did not panic")
+			}
+		}()
+		in.MaybePanic("test")
+	}()
+
+	// The delay gives way to a cancelled context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	in.Delay(ctx)
+}
+
+func TestInjector_Seeded(t *testing.T) {
+	run := func() []bool {
+		in := New(Faults{DropFrame: 0.5, Seed: 7})
+		var out []bool
+		for range 32 {
+			out = append(out, in.DropFrame())
+		}
+		return out
+	}
+	a, b := run(), run()
+	dropped := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("seeded runs diverged at %d", i)
+		}
+		if a[i] {
+			dropped++
+		}
+	}
+	if dropped == 0 || dropped == len(a) {
+		t.Errorf("dropped %d of %d at probability 0.5", dropped, len(a))
+	}
+}
+
+type prompterFunc func() (string, error)
+
+func (f prompterFunc) Prompt(context.Context, string) (string, error) { return f() }
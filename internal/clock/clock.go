@@ -0,0 +1,83 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clock abstracts the time of day, so that logic which expires,
+// schedules, or stamps things by it — approval tokens, learned policy
+// reviews, audit entries, work sessions — can be tested by moving a fake
+// clock on rather than sleeping. Sequence does the same for the random
+// or time-based IDs such logic hands out.
+package clock
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Clock tells the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the real clock.
+var System Clock = system{}
+
+type system struct{}
+
+func (system) Now() time.Time { return time.Now() }
+
+// Or returns c, or System if c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
+
+// Fake is a clock that only moves when told to. It is safe for
+// concurrent use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake clock reading now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock on by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set sets the clock to now.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Sequence returns an ID generator yielding prefix-1, prefix-2, and so
+// on, for tests that inject the IDs of tokens or sessions.
+func Sequence(prefix string) func() string {
+	var (
+		mu sync.Mutex
+		n  int
+	)
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return prefix + "-" + strconv.Itoa(n)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	if !f.Now().Equal(start) {
+		t.Fatalf("Now = %v, want %v", f.Now(), start)
+	}
+	f.Advance(90 * time.Second)
+	if got := f.Now().Sub(start); got != 90*time.Second {
+		t.Errorf("after Advance, %v past start, want 1m30s", got)
+	}
+	f.Set(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("after Set, Now = %v", f.Now())
+	}
+	if Or(f) != Clock(f) || Or(nil) != System {
+		t.Error("Or did not prefer its argument over System")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	next := Sequence("id")
+	for _, want := range []string{"id-1", "id-2", "id-3"} {
+		if got := next(); got != want {
+			t.Errorf("Sequence() = %q, want %q", got, want)
+		}
+	}
+	if got := Sequence("id")(); got != "id-1" {
+		t.Errorf("a new Sequence started at %q", got)
+	}
+}
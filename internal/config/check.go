@@ -0,0 +1,496 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/marcelocantos/doit/internal/agent"
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/cap/builtin"
+	"github.com/marcelocantos/doit/internal/messages"
+	"github.com/marcelocantos/doit/internal/policy"
+	doitstar "github.com/marcelocantos/doit/internal/starlark"
+)
+
+// Problem is a single config validation finding.
+type Problem struct {
+	Line    int    // 1-based line in the config file; 0 if not tied to a line
+	Message string // human-readable description
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// Check strictly parses the config file at path and returns every problem
+// found: unknown keys (typos are otherwise silently ignored by Load),
+// type errors, invalid durations and enum values, malformed rules, and
+// Starlark rule files that fail to load. An empty result means the file
+// is valid. The error return is reserved for failing to read the file.
+func Check(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return CheckData(data), nil
+}
+
+// CheckData is Check for config content already in memory.
+func CheckData(data []byte) []Problem {
+	var problems []Problem
+
+	// Pass 1: strict decode for unknown keys and type mismatches.
+	cfg := DefaultConfig()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			for _, msg := range typeErr.Errors {
+				problems = append(problems, parseYAMLError(msg))
+			}
+		} else {
+			// Syntax error: nothing else can be checked.
+			return []Problem{parseYAMLError(err.Error())}
+		}
+	}
+
+	// Pass 2: semantic checks, located via the node tree.
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return problems
+	}
+	line := func(keys ...string) int {
+		if n := lookupNode(&root, keys...); n != nil {
+			return n.Line
+		}
+		return 0
+	}
+
+	checkDuration := func(value string, keys ...string) {
+		if value == "" {
+			return
+		}
+		if d, err := time.ParseDuration(value); err != nil {
+			problems = append(problems, Problem{line(keys...), fmt.Sprintf("%s: invalid duration %q", strings.Join(keys, "."), value)})
+		} else if d <= 0 {
+			problems = append(problems, Problem{line(keys...), fmt.Sprintf("%s: duration must be positive, got %q", strings.Join(keys, "."), value)})
+		}
+	}
+	checkDuration(cfg.Policy.Level3Timeout, "policy", "level3_timeout")
+	checkDuration(cfg.Policy.EscalationWait, "policy", "escalation_wait")
+	checkDuration(cfg.LLM.BreakerCooldown, "llm", "breaker_cooldown")
+	checkDuration(cfg.Audit.FsyncInterval, "audit", "fsync_interval")
+	checkDuration(cfg.Exec.KillGrace, "exec", "kill_grace")
+	if cfg.Exec.Umask != "" {
+		if _, err := ParseUmask(cfg.Exec.Umask); err != nil {
+			problems = append(problems, Problem{line("exec", "umask"), "exec.umask: " + err.Error()})
+		}
+	}
+
+	if _, err := audit.ParseFsyncPolicy(cfg.Audit.Fsync); err != nil {
+		problems = append(problems, Problem{line("audit", "fsync"), "audit.fsync: " + err.Error()})
+	}
+	if cfg.Audit.MaxSizeMB < 0 {
+		problems = append(problems, Problem{line("audit", "max_size_mb"), fmt.Sprintf("audit.max_size_mb: must not be negative, got %d", cfg.Audit.MaxSizeMB)})
+	}
+	if cfg.Audit.Path == "" {
+		problems = append(problems, Problem{line("audit", "path"), "audit.path: must not be empty"})
+	}
+
+	for i, name := range cfg.Network.Isolate {
+		if _, err := cap.ParseTier(name); err != nil {
+			problems = append(problems, Problem{line("network", "isolate", strconv.Itoa(i)), "network.isolate: " + err.Error()})
+		}
+	}
+
+	// Rules: every rejected flag must look like a flag, or it can never match.
+	capNames := make([]string, 0, len(cfg.Rules))
+	for name := range cfg.Rules {
+		capNames = append(capNames, name)
+	}
+	sort.Strings(capNames)
+	for _, name := range capNames {
+		rule := cfg.Rules[name]
+		for i, f := range rule.RejectFlags {
+			if msg := checkFlag(f); msg != "" {
+				problems = append(problems, Problem{
+					line("rules", name, "reject_flags", strconv.Itoa(i)),
+					fmt.Sprintf("rules.%s.reject_flags: %s", name, msg),
+				})
+			}
+		}
+		subs := make([]string, 0, len(rule.Subcommands))
+		for sub := range rule.Subcommands {
+			subs = append(subs, sub)
+		}
+		sort.Strings(subs)
+		for _, sub := range subs {
+			for i, f := range rule.Subcommands[sub].RejectFlags {
+				if msg := checkFlag(f); msg != "" {
+					problems = append(problems, Problem{
+						line("rules", name, "subcommands", sub, "reject_flags", strconv.Itoa(i)),
+						fmt.Sprintf("rules.%s.subcommands.%s.reject_flags: %s", name, sub, msg),
+					})
+				}
+			}
+		}
+	}
+
+	// Approved scripts: an absolute path and a SHA-256 digest.
+	for i, sc := range cfg.Policy.ApprovedScripts {
+		at := line("policy", "approved_scripts", strconv.Itoa(i))
+		if !filepath.IsAbs(sc.Path) {
+			problems = append(problems, Problem{at, fmt.Sprintf("policy.approved_scripts: path %q is not absolute", sc.Path)})
+		}
+		if !sha256Hex.MatchString(sc.SHA256) {
+			problems = append(problems, Problem{at, fmt.Sprintf("policy.approved_scripts: %q is not a SHA-256 hex digest", sc.SHA256)})
+		}
+	}
+
+	// Project commands: known project types and tasks, and a command to run.
+	kinds := make([]string, 0, len(cfg.Project.Commands))
+	for kind := range cfg.Project.Commands {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		if !slices.Contains(builtin.ProjectTypes, kind) {
+			problems = append(problems, Problem{
+				line("project", "commands", kind),
+				fmt.Sprintf("project.commands: unknown project type %q (want %s)", kind, strings.Join(builtin.ProjectTypes, ", ")),
+			})
+			continue
+		}
+		tasks := make([]string, 0, len(cfg.Project.Commands[kind]))
+		for task := range cfg.Project.Commands[kind] {
+			tasks = append(tasks, task)
+		}
+		sort.Strings(tasks)
+		for _, task := range tasks {
+			switch {
+			case !slices.Contains(builtin.ProjectTasks, task):
+				problems = append(problems, Problem{
+					line("project", "commands", kind, task),
+					fmt.Sprintf("project.commands.%s: unknown task %q (want %s)", kind, task, strings.Join(builtin.ProjectTasks, ", ")),
+				})
+			case strings.TrimSpace(cfg.Project.Commands[kind][task]) == "":
+				problems = append(problems, Problem{
+					line("project", "commands", kind, task),
+					fmt.Sprintf("project.commands.%s.%s: empty command", kind, task),
+				})
+			}
+		}
+	}
+
+	// Message templates must parse and reference only known fields.
+	if len(cfg.Messages) > 0 {
+		if _, err := messages.New(cfg.Messages); err != nil {
+			problems = append(problems, Problem{line("messages"), "messages: " + err.Error()})
+		}
+	}
+
+	// The gatekeeper prompt template must load and reference only known
+	// fields.
+	if path := cfg.LLM.PromptTemplate; path != "" {
+		if _, err := policy.LoadPromptTemplate(path); err != nil {
+			problems = append(problems, Problem{line("llm", "prompt_template"), "llm.prompt_template: " + err.Error()})
+		}
+	}
+
+	// Confidence thresholds are fractions.
+	if c := cfg.LLM.MinAllowConfidence; c > 1 {
+		problems = append(problems, Problem{line("llm", "min_allow_confidence"), fmt.Sprintf("llm.min_allow_confidence: %g is above 1", c)})
+	}
+	if c := cfg.LLM.LearnConfidence; c < 0 || c > 1 {
+		problems = append(problems, Problem{line("llm", "learn_confidence"), fmt.Sprintf("llm.learn_confidence: %g is outside [0, 1]", c)})
+	}
+
+	// Consensus needs at least two different models.
+	if models := cfg.LLM.Consensus.Models; len(models) > 0 {
+		seen := map[string]bool{}
+		for i, m := range models {
+			if strings.TrimSpace(m) == "" || seen[m] {
+				problems = append(problems, Problem{line("llm", "consensus", "models", strconv.Itoa(i)), fmt.Sprintf("llm.consensus.models: empty or repeated model %q", m)})
+			}
+			seen[m] = true
+		}
+		if len(seen) < 2 {
+			problems = append(problems, Problem{line("llm", "consensus", "models"), "llm.consensus.models: consensus needs at least two different models"})
+		}
+	}
+
+	// The trace collector must be an http(s) URL.
+	if ep := cfg.Tracing.Endpoint; ep != "" {
+		if u, err := url.Parse(ep); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, Problem{line("tracing", "endpoint"), fmt.Sprintf("tracing.endpoint: %q is not an http(s) URL", ep)})
+		}
+	}
+
+	// Hooks need a unique name, a phase, a command, and well-formed
+	// matching.
+	hookNames := map[string]bool{}
+	for i, h := range cfg.Hooks {
+		at := func(keys ...string) int { return line(append([]string{"hooks", strconv.Itoa(i)}, keys...)...) }
+		switch {
+		case strings.TrimSpace(h.Name) == "":
+			problems = append(problems, Problem{at(), fmt.Sprintf("hooks[%d]: missing name", i)})
+		case hookNames[h.Name]:
+			problems = append(problems, Problem{at("name"), fmt.Sprintf("hooks[%d]: duplicate name %q", i, h.Name)})
+		}
+		hookNames[h.Name] = true
+		if h.When != HookBefore && h.When != HookAfter {
+			problems = append(problems, Problem{at("when"), fmt.Sprintf("hooks[%d].when: %q is not before or after", i, h.When)})
+		}
+		if strings.TrimSpace(h.Run) == "" {
+			problems = append(problems, Problem{at("run"), fmt.Sprintf("hooks[%d].run: empty command", i)})
+		}
+		problems = append(problems, checkMatch(h.Match, fmt.Sprintf("hooks[%d]", i), at)...)
+	}
+
+	// Assertions need a unique name, something to check, and well-formed
+	// matching.
+	assertNames := map[string]bool{}
+	for i, a := range cfg.Assertions {
+		at := func(keys ...string) int { return line(append([]string{"assertions", strconv.Itoa(i)}, keys...)...) }
+		switch {
+		case strings.TrimSpace(a.Name) == "":
+			problems = append(problems, Problem{at(), fmt.Sprintf("assertions[%d]: missing name", i)})
+		case assertNames[a.Name]:
+			problems = append(problems, Problem{at("name"), fmt.Sprintf("assertions[%d]: duplicate name %q", i, a.Name)})
+		}
+		assertNames[a.Name] = true
+		if strings.TrimSpace(a.Check) == "" && len(a.Exists) == 0 {
+			problems = append(problems, Problem{at(), fmt.Sprintf("assertions[%d]: nothing to check (want check or exists)", i)})
+		}
+		switch {
+		case a.MaxLines < 0:
+			problems = append(problems, Problem{at("max_lines"), fmt.Sprintf("assertions[%d].max_lines: %d is negative", i, a.MaxLines)})
+		case a.MaxLines > 0 && strings.TrimSpace(a.Check) == "":
+			problems = append(problems, Problem{at("max_lines"), fmt.Sprintf("assertions[%d].max_lines: needs a check to count the lines of", i)})
+		}
+		problems = append(problems, checkMatch(a.Match, fmt.Sprintf("assertions[%d]", i), at)...)
+	}
+
+	// Agents other than the default need a secret; tiers and rates must
+	// parse.
+	for _, name := range slices.Sorted(maps.Keys(cfg.Agents)) {
+		a := cfg.Agents[name]
+		at := func(key string) int { return line("agents", name, key) }
+		if h := a.SecretSHA256; h == "" && name != agent.Default {
+			problems = append(problems, Problem{line("agents", name), fmt.Sprintf("agents.%s: missing secret_sha256", name)})
+		} else if b, err := hex.DecodeString(h); h != "" && (err != nil || len(b) != sha256.Size) {
+			problems = append(problems, Problem{at("secret_sha256"), fmt.Sprintf("agents.%s.secret_sha256: not a hex SHA-256", name)})
+		}
+		if t := a.MaxTier; t != "" {
+			if _, err := cap.ParseTier(t); err != nil {
+				problems = append(problems, Problem{at("max_tier"), fmt.Sprintf("agents.%s.max_tier: %v", name, err)})
+			}
+		}
+		if r := a.RateLimit; r != "" {
+			if _, err := agent.ParseRate(r); err != nil {
+				problems = append(problems, Problem{at("rate_limit"), fmt.Sprintf("agents.%s.rate_limit: %v", name, err)})
+			}
+		}
+		if a.Quotas != nil {
+			problems = append(problems, checkQuotas(*a.Quotas, "agents."+name+".quotas", func(key string) int { return line("agents", name, "quotas", key) })...)
+		}
+	}
+	problems = append(problems, checkQuotas(cfg.Quotas, "quotas", func(key string) int { return line("quotas", key) })...)
+
+	// Execution profiles must be enforceable, and selectors must name
+	// one.
+	for _, name := range slices.Sorted(maps.Keys(cfg.Exec.Profiles)) {
+		p := cfg.Exec.Profiles[name]
+		at := func(keys ...string) int { return line(append([]string{"exec", "profiles", name}, keys...)...) }
+		field := "exec.profiles." + name
+		if p.Nice < 0 || p.Nice > 19 {
+			problems = append(problems, Problem{at("nice"), fmt.Sprintf("%s.nice: must be from 1 to 19, got %d", field, p.Nice)})
+		}
+		if p.IONice != "" && p.IONice != "idle" && p.IONice != "best-effort" {
+			problems = append(problems, Problem{at("ionice"), fmt.Sprintf("%s.ionice: %q is not idle or best-effort", field, p.IONice)})
+		}
+		if d := p.Limits.CPU; d != "" {
+			if v, err := time.ParseDuration(d); err != nil || v < time.Second {
+				problems = append(problems, Problem{at("limits", "cpu"), fmt.Sprintf("%s.limits.cpu: want a duration of at least 1s, got %q", field, d)})
+			}
+		}
+		for _, f := range []struct {
+			key string
+			n   int
+		}{{"memory_mb", p.Limits.MemoryMB}, {"file_size_mb", p.Limits.FileSizeMB}, {"open_files", p.Limits.OpenFiles}} {
+			if f.n < 0 {
+				problems = append(problems, Problem{at("limits", f.key), fmt.Sprintf("%s.limits.%s: must not be negative, got %d", field, f.key, f.n)})
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+			if k == "" || strings.ContainsAny(k, "= \t\n") {
+				problems = append(problems, Problem{at("env"), fmt.Sprintf("%s.env: %q is not a variable name", field, k)})
+			}
+		}
+	}
+	for i, s := range cfg.Exec.Select {
+		at := func(key string) int { return line("exec", "select", strconv.Itoa(i), key) }
+		field := fmt.Sprintf("exec.select[%d]", i)
+		if _, ok := cfg.Exec.Profiles[s.Profile]; !ok {
+			problems = append(problems, Problem{at("profile"), fmt.Sprintf("%s.profile: no profile %q in exec.profiles", field, s.Profile)})
+		}
+		if s.Level < 0 || s.Level > 3 {
+			problems = append(problems, Problem{at("level"), fmt.Sprintf("%s.level: must be 1, 2, or 3, got %d", field, s.Level)})
+		}
+		if s.Tier != "" {
+			if _, err := cap.ParseTier(s.Tier); err != nil {
+				problems = append(problems, Problem{at("tier"), fmt.Sprintf("%s.tier: %v", field, err)})
+			}
+		}
+		if _, err := path.Match(s.Rule, ""); err != nil {
+			problems = append(problems, Problem{at("rule"), fmt.Sprintf("%s.rule: bad glob %q", field, s.Rule)})
+		}
+	}
+
+	// Tripwires and sensitive paths must be paths or globs.
+	for _, list := range []struct {
+		key   string
+		paths []string
+	}{{"tripwires", cfg.Tripwires}, {"sensitive_paths", cfg.SensitivePaths}} {
+		for i, p := range list.paths {
+			if strings.TrimSpace(p) == "" {
+				problems = append(problems, Problem{line(list.key, strconv.Itoa(i)), list.key + ": empty path"})
+			} else if _, err := filepath.Match(p, ""); err != nil {
+				problems = append(problems, Problem{line(list.key, strconv.Itoa(i)), fmt.Sprintf("%s: bad glob %q", list.key, p)})
+			}
+		}
+	}
+
+	// Starlark rules must load (each file's embedded tests must pass).
+	if dir := cfg.Policy.StarlarkRulesDir; dir != "" {
+		if _, err := doitstar.LoadDir(dir); err != nil {
+			problems = append(problems, Problem{line("policy", "starlark_rules_dir"), "policy.starlark_rules_dir: " + err.Error()})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
+
+// checkMatch checks the tier and path globs of a hook's or assertion's
+// match; at locates a key below the entry, named by field.
+func checkMatch(m HookMatch, field string, at func(keys ...string) int) []Problem {
+	var problems []Problem
+	if t := m.Tier; t != "" {
+		if _, err := cap.ParseTier(t); err != nil {
+			problems = append(problems, Problem{at("match", "tier"), fmt.Sprintf("%s.match.tier: %v", field, err)})
+		}
+	}
+	for j, p := range m.Paths {
+		if _, err := filepath.Match(p, ""); err != nil {
+			problems = append(problems, Problem{at("match", "paths", strconv.Itoa(j)), fmt.Sprintf("%s.match.paths: bad glob %q", field, p)})
+		}
+	}
+	return problems
+}
+
+// sha256Hex matches a lowercase hex SHA-256 digest.
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// checkFlag returns a description of what is wrong with a reject_flags
+// entry, or "" if it is a well-formed flag.
+func checkFlag(f string) string {
+	switch {
+	case f == "":
+		return "empty flag"
+	case !strings.HasPrefix(f, "-"):
+		return fmt.Sprintf("%q is not a flag (must start with -)", f)
+	case f == "-" || f == "--":
+		return fmt.Sprintf("%q matches nothing", f)
+	}
+	return ""
+}
+
+// lookupNode walks a YAML document by mapping keys (and sequence indices)
+// and returns the node found, or nil.
+func lookupNode(n *yaml.Node, keys ...string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	for _, key := range keys {
+		switch n.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					next = n.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return nil
+			}
+			n = next
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(n.Content) {
+				return nil
+			}
+			n = n.Content[idx]
+		default:
+			return nil
+		}
+	}
+	return n
+}
+
+var yamlLineRE = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// parseYAMLError splits a yaml.v3 message of the form "line N: ..." into
+// a Problem.
+func parseYAMLError(msg string) Problem {
+	if m := yamlLineRE.FindStringSubmatch(msg); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return Problem{Line: n, Message: m[2]}
+	}
+	return Problem{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+// checkQuotas reports negative quotas in q, which is at field.
+func checkQuotas(q QuotaConfig, field string, at func(key string) int) []Problem {
+	var problems []Problem
+	for _, f := range []struct {
+		key string
+		n   int
+	}{{"commands_per_hour", q.CommandsPerHour}, {"write_ops", q.WriteOps}, {"written_mb", q.WrittenMB}} {
+		if f.n < 0 {
+			problems = append(problems, Problem{at(f.key), fmt.Sprintf("%s.%s: must not be negative, got %d", field, f.key, f.n)})
+		}
+	}
+	return problems
+}
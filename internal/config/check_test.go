@@ -0,0 +1,332 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckValid(t *testing.T) {
+	data := []byte(`tiers:
+  dangerous: true
+audit:
+  fsync: interval
+  fsync_interval: 500ms
+policy:
+  level3_timeout: 90s
+rules:
+  make:
+    reject_flags: ["-j"]
+network:
+  isolate: [read, build]
+  offline: true
+`)
+	if problems := CheckData(data); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestCheckEmpty(t *testing.T) {
+	if problems := CheckData(nil); len(problems) != 0 {
+		t.Errorf("expected no problems for empty config, got %v", problems)
+	}
+}
+
+func TestCheckProblems(t *testing.T) {
+	data := []byte(`tiers:
+  read: true
+  dangerus: true
+audit:
+  fsync: sometimes
+policy:
+  level3_timeout: 5 minutes
+  level1_enabled: maybe
+rules:
+  make:
+    reject_flags: ["j"]
+  git:
+    subcommands:
+      push:
+        reject_flags: ["--force", ""]
+`)
+	problems := CheckData(data)
+
+	want := []struct {
+		line int
+		text string
+	}{
+		{3, "dangerus"},
+		{5, "audit.fsync"},
+		{7, "policy.level3_timeout"},
+		{8, "maybe"},
+		{11, "rules.make.reject_flags"},
+		{15, "rules.git.subcommands.push.reject_flags"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %d: %v", len(want), len(problems), problems)
+	}
+	for i, w := range want {
+		if problems[i].Line != w.line || !strings.Contains(problems[i].Message, w.text) {
+			t.Errorf("problem %d = %v, want line %d containing %q", i, problems[i], w.line, w.text)
+		}
+	}
+}
+
+func TestCheckNetworkIsolate(t *testing.T) {
+	problems := CheckData([]byte("network:\n  isolate:\n    - read\n    - bulid\n"))
+	if len(problems) != 1 || problems[0].Line != 4 || !strings.Contains(problems[0].Message, "network.isolate") {
+		t.Errorf("expected one network.isolate problem on line 4, got %v", problems)
+	}
+}
+
+func TestCheckUmask(t *testing.T) {
+	problems := CheckData([]byte("exec:\n  umask: \"0o22\"\n"))
+	if len(problems) != 1 || problems[0].Line != 2 || !strings.Contains(problems[0].Message, "exec.umask") {
+		t.Errorf("expected one exec.umask problem on line 2, got %v", problems)
+	}
+}
+
+func TestCheckProjectCommands(t *testing.T) {
+	data := []byte("project:\n  commands:\n    go:\n      lint: golangci-lint run\n      tset: go test\n    cobol:\n      build: cobc\n    rust:\n      fmt: \"\"\n")
+	problems := CheckData(data)
+	var lines []int
+	for _, p := range problems {
+		lines = append(lines, p.Line)
+	}
+	if len(problems) != 3 || lines[0] != 5 || lines[1] != 7 || lines[2] != 9 {
+		t.Errorf("expected problems on lines 5, 7, and 9, got %v", problems)
+	}
+}
+
+func TestCheckSyntaxError(t *testing.T) {
+	problems := CheckData([]byte("tiers:\n  read: [\n"))
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	if problems[0].Line == 0 {
+		t.Errorf("expected a line number, got %v", problems[0])
+	}
+}
+
+func TestCheckStarlarkRulesDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.star"), []byte("rule_id = \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("policy:\n  starlark_rules_dir: " + dir + "\n")
+	problems := CheckData(data)
+	if len(problems) != 1 || problems[0].Line != 2 || !strings.Contains(problems[0].Message, "starlark_rules_dir") {
+		t.Errorf("expected starlark_rules_dir problem on line 2, got %v", problems)
+	}
+}
+
+func TestCheckPromptTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Default}}\nJudge {{.Comand}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("llm:\n  prompt_template: " + path + "\n")
+	problems := CheckData(data)
+	if len(problems) != 1 || problems[0].Line != 2 || !strings.Contains(problems[0].Message, "Comand") {
+		t.Errorf("expected prompt_template problem on line 2, got %v", problems)
+	}
+}
+
+func TestCheckConsensus(t *testing.T) {
+	if problems := CheckData([]byte("llm:\n  consensus:\n    models: [opus, sonnet]\n")); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+	problems := CheckData([]byte("llm:\n  consensus:\n    models:\n      - opus\n      - opus\n"))
+	if len(problems) != 2 || problems[0].Line != 4 || problems[1].Line != 5 {
+		t.Errorf("expected problems on lines 4 and 5, got %v", problems)
+	}
+}
+
+func TestCheckConfidence(t *testing.T) {
+	if problems := CheckData([]byte("llm:\n  min_allow_confidence: -1\n  learn_confidence: 0.95\n")); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+	problems := CheckData([]byte("llm:\n  min_allow_confidence: 70\n  learn_confidence: 2\n"))
+	if len(problems) != 2 || problems[0].Line != 2 || problems[1].Line != 3 {
+		t.Errorf("expected problems on lines 2 and 3, got %v", problems)
+	}
+}
+
+func TestCheckMissingFile(t *testing.T) {
+	if _, err := Check(filepath.Join(t.TempDir(), "nope.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestCheckMessages(t *testing.T) {
+	data := []byte("messages:\n  policy_deny: \"{{.Reasn}}\"\n")
+	problems := CheckData(data)
+	if len(problems) != 1 || problems[0].Line != 2 || !strings.Contains(problems[0].Message, "policy_deny") {
+		t.Errorf("expected messages problem on line 2, got %v", problems)
+	}
+	if problems := CheckData([]byte("messages:\n  user_deny: \"no: {{.Command}}\"\n")); len(problems) != 0 {
+		t.Errorf("expected valid messages, got %v", problems)
+	}
+}
+
+func TestCheckTracingEndpoint(t *testing.T) {
+	problems := CheckData([]byte("tracing:\n  endpoint: localhost:4318\n"))
+	if len(problems) != 1 || problems[0].Line != 2 || !strings.Contains(problems[0].Message, "tracing.endpoint") {
+		t.Errorf("expected one tracing.endpoint problem on line 2, got %v", problems)
+	}
+	if problems := CheckData([]byte("tracing:\n  endpoint: http://localhost:4318/v1/traces\n")); len(problems) != 0 {
+		t.Errorf("valid endpoint: %v", problems)
+	}
+}
+
+func TestCheckHooks(t *testing.T) {
+	problems := CheckData([]byte(`hooks:
+  - name: lint
+    when: after
+    match: {paths: ["*.go"]}
+    run: golangci-lint run
+  - name: lint
+    when: during
+    match: {tier: risky, paths: ["[go"]}
+    run: " "
+`))
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d %s", p.Line, p.Message))
+	}
+	want := []string{
+		`6 hooks[1]: duplicate name "lint"`,
+		`7 hooks[1].when: "during" is not before or after`,
+		`8 hooks[1].match.tier: unknown tier: "risky"`,
+		`8 hooks[1].match.paths: bad glob "[go"`,
+		`9 hooks[1].run: empty command`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	problems := CheckData([]byte(`assertions:
+  - name: tidy
+    match: {cap: go, subcmd: mod}
+    check: git diff --stat go.sum
+    max_lines: 5
+  - name: tidy
+    match: {paths: ["[go"]}
+    max_lines: 3
+  - match: {tier: risky}
+    exists: [go.mod]
+`))
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d %s", p.Line, p.Message))
+	}
+	want := []string{
+		`6 assertions[1]: duplicate name "tidy"`,
+		`6 assertions[1]: nothing to check (want check or exists)`,
+		`7 assertions[1].match.paths: bad glob "[go"`,
+		`8 assertions[1].max_lines: needs a check to count the lines of`,
+		`9 assertions[2]: missing name`,
+		`9 assertions[2].match.tier: unknown tier: "risky"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckAgents(t *testing.T) {
+	problems := CheckData([]byte(`agents:
+  default:
+    max_tier: read
+  reviewer:
+    secret_sha256: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+    max_tier: build
+    rate_limit: 60/m
+  builder:
+    max_tier: everything
+    rate_limit: 60
+  deployer:
+    secret_sha256: abc123
+    quotas: {write_ops: -1}
+quotas:
+  commands_per_hour: 100
+  written_mb: -5
+`))
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d %s", p.Line, p.Message))
+	}
+	want := []string{
+		`9 agents.builder: missing secret_sha256`,
+		`9 agents.builder.max_tier: unknown tier: "everything"`,
+		`10 agents.builder.rate_limit: rate "60": want count/period, e.g. 60/m`,
+		`12 agents.deployer.secret_sha256: not a hex SHA-256`,
+		`13 agents.deployer.quotas.write_ops: must not be negative, got -1`,
+		`16 quotas.written_mb: must not be negative, got -5`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckPathLists(t *testing.T) {
+	problems := CheckData([]byte(`tripwires:
+  - ~/.ssh/id_rsa
+  - ""
+  - secrets[.env
+sensitive_paths:
+  - .env
+  - "[.pem"
+`))
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d %s", p.Line, p.Message))
+	}
+	want := []string{
+		`3 tripwires: empty path`,
+		`4 tripwires: bad glob "secrets[.env"`,
+		`7 sensitive_paths: bad glob "[.pem"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckExecProfiles(t *testing.T) {
+	problems := CheckData([]byte(`exec:
+  profiles:
+    strict:
+      nice: 20
+      ionice: realtime
+      limits:
+        cpu: 10ms
+        open_files: -1
+  select:
+    - level: 3
+      tier: dangerous
+      profile: strict
+    - tier: risky
+      profile: lax
+`))
+	var got []string
+	for _, p := range problems {
+		got = append(got, fmt.Sprintf("%d %s", p.Line, p.Message))
+	}
+	want := []string{
+		`4 exec.profiles.strict.nice: must be from 1 to 19, got 20`,
+		`5 exec.profiles.strict.ionice: "realtime" is not idle or best-effort`,
+		`7 exec.profiles.strict.limits.cpu: want a duration of at least 1s, got "10ms"`,
+		`8 exec.profiles.strict.limits.open_files: must not be negative, got -1`,
+		`13 exec.select[1].tier: unknown tier: "risky"`,
+		`14 exec.select[1].profile: no profile "lax" in exec.profiles`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
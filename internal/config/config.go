@@ -5,22 +5,61 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/marcelocantos/doit/internal/audit"
 	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/paths"
+	"github.com/marcelocantos/doit/internal/proc"
 	"github.com/marcelocantos/doit/internal/rules"
 )
 
 // Config holds the global doit configuration.
 type Config struct {
-	Tiers  TierConfig                     `yaml:"tiers"`
-	Audit  AuditConfig                    `yaml:"audit"`
-	Rules  map[string]rules.CapRuleConfig `yaml:"rules"`
-	Policy PolicyConfig                   `yaml:"policy"`
+	Tiers   TierConfig                     `yaml:"tiers"`
+	Audit   AuditConfig                    `yaml:"audit"`
+	Rules   map[string]rules.CapRuleConfig `yaml:"rules"`
+	Policy  PolicyConfig                   `yaml:"policy"`
+	Events  EventsConfig                   `yaml:"events"`
+	Network NetworkConfig                  `yaml:"network"`
+	Exec    ExecConfig                     `yaml:"exec"`
+	Project ProjectConfig                  `yaml:"project"`
+	LLM     LLMConfig                      `yaml:"llm"`
+	Tracing TracingConfig                  `yaml:"tracing"`
+	Hooks   []HookConfig                   `yaml:"hooks,omitempty"`
+
+	// Assertions check post-conditions after the commands they match.
+	Assertions []AssertionConfig `yaml:"assertions,omitempty"`
+
+	// Agents holds each agent's limits, by the name it declares (see
+	// AgentConfig).
+	Agents map[string]AgentConfig `yaml:"agents,omitempty"`
+
+	// Quotas bound what each agent may do (see QuotaConfig).
+	Quotas QuotaConfig `yaml:"quotas,omitempty"`
+
+	// Tripwires are paths, ~ for the home directory, or globs that no
+	// command may touch, such as a private key or a decoy secrets file:
+	// one that does is denied and reported, whatever the tiers allow. An
+	// absolute one names that path; a relative one names it in any
+	// directory.
+	Tripwires []string `yaml:"tripwires,omitempty"`
+
+	// SensitivePaths are paths whose contents are secret, such as .env
+	// files or private keys, written as Tripwires are. Once a command
+	// touches one, the agent's network-capable commands need a human's
+	// approval until its session ends or it is reset.
+	SensitivePaths []string `yaml:"sensitive_paths,omitempty"`
+
+	// Messages overrides user-facing policy message templates by key
+	// (see internal/messages). Unset keys use the built-in text.
+	Messages map[string]string `yaml:"messages,omitempty"`
 }
 
 // PolicyConfig controls the policy engine.
@@ -32,7 +71,125 @@ type PolicyConfig struct {
 	Level3FastModel  string `yaml:"level3_fast_model,omitempty"`  // fast triage model (default: sonnet)
 	Level3Model      string `yaml:"level3_model,omitempty"`       // deep reasoning model (default: opus)
 	Level3Timeout    string `yaml:"level3_timeout,omitempty"`
+	Level3History    int    `yaml:"level3_history,omitempty"` // recent session commands shown to Level 3 (default 10; negative: none)
 	StarlarkRulesDir string `yaml:"starlark_rules_dir,omitempty"`
+	EscalationWait   string `yaml:"escalation_wait,omitempty"` // park escalations awaiting a human (default: off)
+	BlanketRetry     bool   `yaml:"blanket_retry,omitempty"`   // let retry without a denial reference bypass every bypassable rule
+
+	// ApprovedScripts may run without review while their content is
+	// unchanged (see doit --policy approve-script). Only the global config
+	// may list them.
+	ApprovedScripts []ApprovedScript `yaml:"approved_scripts,omitempty"`
+}
+
+// LLMConfig controls the Level 3 gatekeeper's prompt. Only the global
+// config may set it: a project must not be able to talk the gatekeeper
+// round.
+type LLMConfig struct {
+	// PromptTemplate is a text/template file that replaces the built-in
+	// gatekeeper prompt. It sees .Command, .Segments, .Tiers, .Tier, .Cwd,
+	// .Justification, .SafetyArg, .Fast, and .Default (the built-in
+	// prompt, to extend rather than replace). The response format is
+	// always appended.
+	PromptTemplate string `yaml:"prompt_template,omitempty"`
+
+	// Consensus has dangerous-tier commands judged by several models at
+	// once, allowing or denying only when all of them agree.
+	Consensus ConsensusConfig `yaml:"consensus,omitempty"`
+
+	// BreakerFailures is how many consecutive LLM errors or timeouts stop
+	// Level 3 calling it, escalating to a human instead, until
+	// BreakerCooldown has passed (defaults 3 and 5m; negative failures
+	// turn the breaker off).
+	BreakerFailures int    `yaml:"breaker_failures,omitempty"`
+	BreakerCooldown string `yaml:"breaker_cooldown,omitempty"`
+
+	// MinAllowConfidence is the confidence below which the gatekeeper's
+	// allow escalates instead (default 0.7; negative turns it off).
+	// LearnConfidence, if set, is the confidence at which an allow also
+	// becomes an unapproved learned policy candidate for the command.
+	MinAllowConfidence float64 `yaml:"min_allow_confidence,omitempty"`
+	LearnConfidence    float64 `yaml:"learn_confidence,omitempty"`
+
+	// FeedbackExamples is how many past decisions a human has judged
+	// (see doit --feedback) Level 3 is shown as examples (default 5;
+	// negative: none).
+	FeedbackExamples int `yaml:"feedback_examples,omitempty"`
+}
+
+// DefaultFeedbackExamples is used when no feedback_examples is
+// configured.
+const DefaultFeedbackExamples = 5
+
+// FeedbackCount returns how many judged decisions to show Level 3.
+func (l *LLMConfig) FeedbackCount() int {
+	switch {
+	case l.FeedbackExamples < 0:
+		return 0
+	case l.FeedbackExamples == 0:
+		return DefaultFeedbackExamples
+	}
+	return l.FeedbackExamples
+}
+
+// DefaultMinAllowConfidence is used when no min_allow_confidence is
+// configured.
+const DefaultMinAllowConfidence = 0.7
+
+// MinAllow returns the confidence an LLM allow needs to stand.
+func (l *LLMConfig) MinAllow() float64 {
+	switch {
+	case l.MinAllowConfidence < 0:
+		return 0
+	case l.MinAllowConfidence == 0:
+		return DefaultMinAllowConfidence
+	}
+	return l.MinAllowConfidence
+}
+
+// Default circuit breaker settings for the Level 3 gatekeeper.
+const (
+	DefaultBreakerFailures = 3
+	DefaultBreakerCooldown = 5 * time.Minute
+)
+
+// Breaker returns the consecutive failures that open the Level 3 circuit
+// breaker (0: never) and how long it stays open.
+func (l *LLMConfig) Breaker() (failures int, cooldown time.Duration) {
+	failures, cooldown = l.BreakerFailures, DefaultBreakerCooldown
+	switch {
+	case failures < 0:
+		failures = 0
+	case failures == 0:
+		failures = DefaultBreakerFailures
+	}
+	if l.BreakerCooldown != "" {
+		if dur, err := time.ParseDuration(l.BreakerCooldown); err == nil && dur > 0 {
+			cooldown = dur
+		}
+	}
+	return failures, cooldown
+}
+
+// ConsensusConfig lists the models that must agree on dangerous-tier
+// commands. Fewer than two models leave consensus off.
+type ConsensusConfig struct {
+	Models []string `yaml:"models,omitempty"`
+}
+
+// ApprovedScript is a script blessed by content hash.
+type ApprovedScript struct {
+	Path   string `yaml:"path"`   // absolute, with symlinks resolved
+	SHA256 string `yaml:"sha256"` // hex digest of the approved content
+}
+
+// ScriptApprovals maps each approved script's path to its digest.
+func (p *PolicyConfig) ScriptApprovals() map[string]string {
+	m := make(map[string]string, len(p.ApprovedScripts))
+	for _, s := range p.ApprovedScripts {
+		m[s.Path] = s.SHA256
+	}
+	return m
 }
 
 // DefaultLevel3Timeout is used when no level3_timeout is configured.
@@ -49,6 +206,35 @@ func (p *PolicyConfig) Level3TimeoutDuration() time.Duration {
 	return DefaultLevel3Timeout
 }
 
+// DefaultLevel3History is how many recent commands Level 3 sees when no
+// level3_history is configured.
+const DefaultLevel3History = 10
+
+// Level3HistoryCount returns how many recent commands of the session to
+// show Level 3.
+func (p *PolicyConfig) Level3HistoryCount() int {
+	switch {
+	case p.Level3History < 0:
+		return 0
+	case p.Level3History == 0:
+		return DefaultLevel3History
+	}
+	return p.Level3History
+}
+
+// EscalationWaitDuration parses how long an escalated request waits for a
+// human decision before returning. Zero (the default) disables parking.
+func (p *PolicyConfig) EscalationWaitDuration() time.Duration {
+	if p.EscalationWait == "" {
+		return 0
+	}
+	dur, err := time.ParseDuration(p.EscalationWait)
+	if err != nil || dur < 0 {
+		return 0
+	}
+	return dur
+}
+
 // TierConfig controls which safety tiers are enabled.
 type TierConfig struct {
 	Read      bool `yaml:"read"`
@@ -59,13 +245,214 @@ type TierConfig struct {
 
 // AuditConfig controls audit log settings.
 type AuditConfig struct {
-	Path      string `yaml:"path"`
-	MaxSizeMB int    `yaml:"max_size_mb"`
+	Path          string `yaml:"path"`
+	MaxSizeMB     int    `yaml:"max_size_mb"`
+	Fsync         string `yaml:"fsync,omitempty"`          // "always" (default), "interval", or "never"
+	FsyncInterval string `yaml:"fsync_interval,omitempty"` // flush period for interval/never (default 1s)
+	FailClosed    bool   `yaml:"fail_closed,omitempty"`    // refuse commands while audit entries cannot be written
+}
+
+// FsyncIntervalDuration parses the configured fsync interval or returns the default.
+func (a *AuditConfig) FsyncIntervalDuration() time.Duration {
+	if a.FsyncInterval != "" {
+		dur, err := time.ParseDuration(a.FsyncInterval)
+		if err == nil && dur > 0 {
+			return dur
+		}
+	}
+	return audit.DefaultFsyncInterval
+}
+
+// EventsConfig controls the live event subscription socket.
+type EventsConfig struct {
+	Socket bool   `yaml:"socket"`        // serve events on a Unix socket (default true)
+	Dir    string `yaml:"dir,omitempty"` // socket directory (default $XDG_STATE_HOME/doit/events)
+}
+
+// TracingConfig exports OpenTelemetry spans of each request — its
+// policy levels, execution, and audit write — to an OTLP/HTTP collector.
+// Only the global config may set it: spans carry command lines.
+type TracingConfig struct {
+	// Endpoint is the collector's traces URL, such as
+	// http://localhost:4318/v1/traces. Without it, the standard
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT
+	// variables apply; with neither, nothing is traced.
+	Endpoint string            `yaml:"endpoint,omitempty"`
+	Service  string            `yaml:"service,omitempty"` // service.name (default $OTEL_SERVICE_NAME, else doit)
+	Headers  map[string]string `yaml:"headers,omitempty"` // sent with every export, e.g. a collector's API key
+}
+
+// Hook phases: a hook runs before or after the commands it matches.
+const (
+	HookBefore = "before"
+	HookAfter  = "after"
+)
+
+// HookConfig runs a command before or after each command it matches,
+// such as a linter after a write to a Go file. The hook's command is
+// judged by policy and audited like any other; a before hook that fails,
+// or that policy does not allow, stops the command it guards. Only the
+// global config may set hooks.
+type HookConfig struct {
+	Name  string    `yaml:"name"`
+	When  string    `yaml:"when"` // HookBefore or HookAfter
+	Match HookMatch `yaml:"match"`
+	Run   string    `yaml:"run"`             // shell command, run in the matched command's directory
+	Audit bool      `yaml:"audit,omitempty"` // keep the hook's output in its audit entry
+}
+
+// HookMatch selects the commands a hook runs for. Every field given must
+// match; an empty HookMatch matches every command.
+type HookMatch struct {
+	Cap    string   `yaml:"cap,omitempty"`    // program, e.g. git
+	Subcmd string   `yaml:"subcmd,omitempty"` // its first argument, e.g. push
+	Tier   string   `yaml:"tier,omitempty"`   // the command's tier, the highest of anything it runs, e.g. dangerous
+	Paths  []string `yaml:"paths,omitempty"`  // globs, e.g. "*.go", one of which an argument or redirect target must match
+}
+
+// AssertionConfig checks a post-condition after each command it matches,
+// such as that go mod tidy changed go.sum by only a few lines, or that an
+// rm spared the paths it must not touch. The command has already run, so
+// a violation undoes nothing: it is recorded in the command's audit entry
+// and reported to the human. A check command is judged by policy and
+// audited like a hook. Only the global config may set assertions.
+type AssertionConfig struct {
+	Name     string    `yaml:"name"`
+	Match    HookMatch `yaml:"match"`
+	Check    string    `yaml:"check,omitempty"`     // shell command that must succeed, run in the matched command's directory
+	MaxLines int       `yaml:"max_lines,omitempty"` // most lines the check may print (0: any number)
+	Exists   []string  `yaml:"exists,omitempty"`    // paths that must still exist, relative to the command's directory or ~
+}
+
+// AgentConfig holds the limits of one agent, which declares its name in
+// $DOIT_AGENT or its request and proves it with the secret in
+// $DOIT_AGENT_SECRET. The agent named "default" needs no secret: its
+// limits apply to requests that declare no agent. A request naming an
+// agent not listed, or with the wrong secret, is rejected. Only the
+// global config may declare agents.
+type AgentConfig struct {
+	SecretSHA256 string `yaml:"secret_sha256,omitempty"` // hex SHA-256 of the agent's secret
+	MaxTier      string `yaml:"max_tier,omitempty"`      // highest tier it may run (default: dangerous)
+	RateLimit    string `yaml:"rate_limit,omitempty"`    // most commands per period, e.g. 60/m (default: none)
+
+	// Quotas, if set, replace the global quotas for this agent.
+	Quotas *QuotaConfig `yaml:"quotas,omitempty"`
+}
+
+// QuotaConfig bounds what each agent may do: its commands in any hour,
+// and its write-tier commands and the data it writes to files in a work
+// session, or outside one until reset. An agent that reaches a quota has
+// every command escalated to a human until one resets it (doit --quota
+// reset). Zero fields are unbounded. Only the global config may set
+// quotas.
+type QuotaConfig struct {
+	CommandsPerHour int `yaml:"commands_per_hour,omitempty"`
+	WriteOps        int `yaml:"write_ops,omitempty"`  // write- and dangerous-tier commands
+	WrittenMB       int `yaml:"written_mb,omitempty"` // megabytes written to files
+}
+
+// QuotasFor returns the quotas of the named agent: its own, if it has
+// any, or else the global ones.
+func (c *Config) QuotasFor(agent string) QuotaConfig {
+	if a, ok := c.Agents[agent]; ok && a.Quotas != nil {
+		return *a.Quotas
+	}
+	return c.Quotas
+}
+
+// NetworkConfig controls network access for executed commands.
+type NetworkConfig struct {
+	// Isolate lists the tiers whose commands run in an empty network
+	// namespace (Linux only), e.g. [read, build]. Invocations that declare
+	// network use, such as git fetch or go mod download, keep the network.
+	Isolate []string `yaml:"isolate,omitempty"`
+
+	// Offline denies every command that reaches the network: downloads,
+	// remote shells, package installs, git push/pull/fetch, and the like.
+	Offline bool `yaml:"offline,omitempty"`
+}
+
+// IsolatedTiers parses Isolate into a set of tiers.
+func (n *NetworkConfig) IsolatedTiers() (map[cap.Tier]bool, error) {
+	tiers := make(map[cap.Tier]bool, len(n.Isolate))
+	for _, name := range n.Isolate {
+		t, err := cap.ParseTier(name)
+		if err != nil {
+			return nil, fmt.Errorf("network.isolate: %w", err)
+		}
+		tiers[t] = true
+	}
+	return tiers, nil
+}
+
+// ExecConfig controls how commands are run.
+type ExecConfig struct {
+	// KillGrace is how long a cancelled command has to exit after SIGTERM
+	// before it is killed.
+	KillGrace string `yaml:"kill_grace,omitempty"`
+	// Umask is the octal file mode creation mask for commands doit runs
+	// and files it writes for them, whatever doit's own umask.
+	Umask string `yaml:"umask,omitempty"`
+	// RequestEnv names variables a request may set in its command's
+	// environment beyond the few doit allows by default. Those that choose
+	// what code runs stay refused. Only the global config may set it.
+	RequestEnv []string `yaml:"request_env,omitempty"`
+	// Profiles are named environments to run commands in, and Select
+	// picks one by the decision that let a command run; the first
+	// selector that matches wins. Only the global config may set them.
+	Profiles map[string]ExecProfile `yaml:"profiles,omitempty"`
+	Select   []ProfileSelector      `yaml:"select,omitempty"`
+}
+
+// ProjectConfig controls the project meta-capabilities (build, fmt, lint,
+// tests).
+type ProjectConfig struct {
+	// Commands overrides the command a meta-capability runs, by project
+	// type (go, rust, node, python, make) and then capability name. The
+	// command is split into words, not run through a shell. Only the
+	// global config may set it: a project config could otherwise make a
+	// broadly allowed "lint" run anything.
+	Commands map[string]map[string]string `yaml:"commands,omitempty"`
+}
+
+// DefaultKillGrace is used when no kill_grace is configured.
+const DefaultKillGrace = proc.DefaultGrace
+
+// KillGraceDuration parses the configured kill grace or returns the default.
+func (x *ExecConfig) KillGraceDuration() time.Duration {
+	if x.KillGrace != "" {
+		dur, err := time.ParseDuration(x.KillGrace)
+		if err == nil && dur > 0 {
+			return dur
+		}
+	}
+	return DefaultKillGrace
+}
+
+// DefaultUmask is used when no umask is configured.
+const DefaultUmask fs.FileMode = 0o022
+
+// UmaskMode parses the configured umask or returns the default. The mask
+// always denies write to others: doit never creates world-writable files.
+func (x *ExecConfig) UmaskMode() fs.FileMode {
+	mask := DefaultUmask
+	if m, err := ParseUmask(x.Umask); err == nil && x.Umask != "" {
+		mask = m
+	}
+	return mask | 0o002
+}
+
+// ParseUmask parses an octal umask such as "022" or "0077".
+func ParseUmask(s string) (fs.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0o777 {
+		return 0, fmt.Errorf("invalid umask %q: want octal 000-777", s)
+	}
+	return fs.FileMode(m), nil
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
-	home, _ := os.UserHomeDir()
 	return &Config{
 		Tiers: TierConfig{
 			Read:      true,
@@ -74,7 +461,7 @@ func DefaultConfig() *Config {
 			Dangerous: false,
 		},
 		Audit: AuditConfig{
-			Path:      filepath.Join(home, ".local", "share", "doit", "audit.jsonl"),
+			Path:      paths.AuditLog(),
 			MaxSizeMB: 100,
 		},
 		Policy: PolicyConfig{
@@ -82,19 +469,18 @@ func DefaultConfig() *Config {
 			Level2Enabled: true,
 			Level3Enabled: true,
 		},
+		Events: EventsConfig{
+			Socket: true,
+			Dir:    paths.EventsDir(),
+		},
 	}
 }
 
-// Load reads the config from the standard location (~/.config/doit/config.yaml).
-// If the file doesn't exist, returns the default config.
+// Load reads the config from the standard location
+// ($XDG_CONFIG_HOME/doit/config.yaml). If the file doesn't exist, returns
+// the default config.
 func Load() (*Config, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return DefaultConfig(), nil
-	}
-
-	path := filepath.Join(home, ".config", "doit", "config.yaml")
-	return LoadFrom(path)
+	return LoadFrom(ConfigPath())
 }
 
 // LoadFrom reads the config from the given path.
@@ -147,9 +533,13 @@ func (c *Config) ApplyRules(reg *cap.Registry) {
 		cfgRules = DefaultRules()
 	}
 	for name, capRule := range cfgRules {
-		for _, fn := range rules.CompileCapRule(name, capRule) {
+		bypassable, permanent := rules.CompileCapRuleByBypass(name, capRule)
+		for _, fn := range bypassable {
 			rs.AddConfig(fn)
 		}
+		for _, fn := range permanent {
+			rs.AddPermanent(fn)
+		}
 	}
 	// Programmatic default rules that can't be expressed in YAML config.
 	rs.AddConfig(rules.CheckGitCheckoutAll)
@@ -166,8 +556,7 @@ func (c *Config) ApplyTiers(reg *cap.Registry) {
 
 // ConfigPath returns the standard config file path.
 func ConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".config", "doit", "config.yaml")
+	return paths.ConfigFile()
 }
 
 // ProjectConfigPath returns the config file path for a project root.
@@ -216,6 +605,27 @@ func (c *Config) MergeProject(proj *Config) {
 		c.Tiers.Dangerous = false
 	}
 
+	// Network: project can isolate more tiers or go offline, never the
+	// reverse.
+	c.Network.Isolate = mergeFlags(c.Network.Isolate, proj.Network.Isolate)
+	c.Network.Offline = c.Network.Offline || proj.Network.Offline
+
+	// Tripwires and sensitive paths: project can add them, never remove
+	// them.
+	c.Tripwires = mergeFlags(c.Tripwires, proj.Tripwires)
+	c.SensitivePaths = mergeFlags(c.SensitivePaths, proj.SensitivePaths)
+
+	// Umask: project can mask more permission bits, never fewer.
+	if m, err := ParseUmask(proj.Exec.Umask); err == nil && proj.Exec.Umask != "" {
+		c.Exec.Umask = fmt.Sprintf("%03o", c.Exec.UmaskMode()|m)
+	}
+
+	// Project commands, approved scripts, the gatekeeper prompt,
+	// tracing, hooks, assertions, agents, quotas, execution profiles,
+	// and the request environment allowlist are ignored: only the global config may set them (see
+	// ProjectConfig, PolicyConfig, LLMConfig, TracingConfig, HookConfig,
+	// AssertionConfig, AgentConfig, QuotaConfig, and ExecConfig).
+
 	// Rules: merge project rules into global. Project rules add to
 	// (never replace) global rules.
 	if len(proj.Rules) > 0 {
@@ -230,6 +640,7 @@ func (c *Config) MergeProject(proj *Config) {
 			}
 			// Merge reject flags (deduplicated).
 			existing.RejectFlags = mergeFlags(existing.RejectFlags, projRule.RejectFlags)
+			existing.Bypassable = tightenBypass(existing.Bypassable, projRule.Bypassable)
 			// Merge subcommand rules.
 			if len(projRule.Subcommands) > 0 {
 				if existing.Subcommands == nil {
@@ -238,6 +649,7 @@ func (c *Config) MergeProject(proj *Config) {
 				for sub, subRule := range projRule.Subcommands {
 					if es, ok := existing.Subcommands[sub]; ok {
 						es.RejectFlags = mergeFlags(es.RejectFlags, subRule.RejectFlags)
+						es.Bypassable = tightenBypass(es.Bypassable, subRule.Bypassable)
 						existing.Subcommands[sub] = es
 					} else {
 						existing.Subcommands[sub] = subRule
@@ -249,6 +661,15 @@ func (c *Config) MergeProject(proj *Config) {
 	}
 }
 
+// tightenBypass merges a project's bypassable setting into the global one:
+// a project may make a rule non-bypassable but never the reverse.
+func tightenBypass(global, proj *bool) *bool {
+	if proj != nil && !*proj {
+		return proj
+	}
+	return global
+}
+
 // mergeFlags appends new flags to existing, skipping duplicates.
 func mergeFlags(existing, new []string) []string {
 	seen := make(map[string]bool, len(existing))
@@ -4,6 +4,7 @@
 package config
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,6 +15,7 @@ import (
 )
 
 func TestDefaultConfig(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "")
 	cfg := DefaultConfig()
 
 	// Tiers: read, build, write enabled; dangerous disabled.
@@ -32,7 +34,7 @@ func TestDefaultConfig(t *testing.T) {
 
 	// Audit defaults.
 	home, _ := os.UserHomeDir()
-	wantPath := filepath.Join(home, ".local", "share", "doit", "audit.jsonl")
+	wantPath := filepath.Join(home, ".local", "state", "doit", "audit.jsonl")
 	if cfg.Audit.Path != wantPath {
 		t.Errorf("Audit.Path = %q, want %q", cfg.Audit.Path, wantPath)
 	}
@@ -208,6 +210,25 @@ tiers:
 	}
 }
 
+func TestUmaskMode(t *testing.T) {
+	for _, tc := range []struct {
+		umask string
+		want  fs.FileMode
+	}{
+		{"", DefaultUmask},
+		{"077", 0o077},
+		{"0027", 0o027},
+		{"000", 0o002}, // never world-writable
+		{"999", DefaultUmask},
+		{"1777", DefaultUmask},
+	} {
+		x := &ExecConfig{Umask: tc.umask}
+		if got := x.UmaskMode(); got != tc.want {
+			t.Errorf("UmaskMode(%q) = %03o, want %03o", tc.umask, got, tc.want)
+		}
+	}
+}
+
 func TestLevel3TimeoutDuration(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -525,6 +546,19 @@ func TestMergeProjectTightenOnly(t *testing.T) {
 		}
 	})
 
+	t.Run("project umask only adds bits", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Exec.Umask = "027"
+		cfg.MergeProject(&Config{Exec: ExecConfig{Umask: "000"}})
+		if got := cfg.Exec.UmaskMode(); got != 0o027 {
+			t.Errorf("umask = %03o, want 027", got)
+		}
+		cfg.MergeProject(&Config{Exec: ExecConfig{Umask: "077"}})
+		if got := cfg.Exec.UmaskMode(); got != 0o077 {
+			t.Errorf("umask = %03o, want 077", got)
+		}
+	})
+
 	t.Run("project cannot enable globally disabled tier", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.Tiers.Dangerous = false
@@ -556,6 +590,28 @@ func TestMergeProjectRules(t *testing.T) {
 		}
 	})
 
+	t.Run("bypassable only tightens", func(t *testing.T) {
+		no, yes := false, true
+		cfg := DefaultConfig()
+		cfg.Rules = map[string]rules.CapRuleConfig{
+			"make": {RejectFlags: []string{"-j"}},
+			"npm":  {RejectFlags: []string{"-g"}, Bypassable: &no},
+		}
+		proj := &Config{
+			Rules: map[string]rules.CapRuleConfig{
+				"make": {Bypassable: &no},
+				"npm":  {Bypassable: &yes},
+			},
+		}
+		cfg.MergeProject(proj)
+		if cfg.Rules["make"].IsBypassable() {
+			t.Error("expected project to make the make rule non-bypassable")
+		}
+		if cfg.Rules["npm"].IsBypassable() {
+			t.Error("expected project not to make the npm rule bypassable")
+		}
+	})
+
 	t.Run("merges flags into existing capability", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.Rules = map[string]rules.CapRuleConfig{
@@ -620,3 +676,28 @@ func TestMergeProjectRules(t *testing.T) {
 		}
 	})
 }
+
+func TestProfileFor(t *testing.T) {
+	x := ExecConfig{
+		Profiles: map[string]ExecProfile{"strict": {Nice: 10}, "grants": {NoNetwork: true}},
+		Select: []ProfileSelector{
+			{Level: 3, Tier: "dangerous", Profile: "strict"},
+			{Rule: "grant-*", Profile: "grants"},
+		},
+	}
+	for _, tc := range []struct {
+		level int
+		tier  cap.Tier
+		rule  string
+		want  string
+	}{
+		{3, cap.TierDangerous, "", "strict"},
+		{3, cap.TierWrite, "", ""},
+		{1, cap.TierDangerous, "", ""},
+		{2, cap.TierRead, "grant-20260101-abcd", "grants"},
+	} {
+		if got, _ := x.ProfileFor(tc.level, tc.tier, tc.rule); got != tc.want {
+			t.Errorf("ProfileFor(%d, %s, %q) = %q, want %q", tc.level, tc.tier, tc.rule, got, tc.want)
+		}
+	}
+}
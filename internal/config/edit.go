@@ -0,0 +1,332 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GetKey returns the value of a dotted key (e.g. "tiers.dangerous",
+// "rules.make.reject_flags.0") as YAML text. If the key is not set in
+// data, the built-in default is returned; set reports which was used.
+func GetKey(data []byte, key string) (value string, set bool, err error) {
+	keys, err := splitKey(key)
+	if err != nil {
+		return "", false, err
+	}
+	root, err := parseDoc(data)
+	if err != nil {
+		return "", false, err
+	}
+	n := lookupNode(root, keys...)
+	if n == nil {
+		defaults := DefaultConfig()
+		defaults.Rules = DefaultRules()
+		var def yaml.Node
+		if err := def.Encode(defaults); err != nil {
+			return "", false, fmt.Errorf("encode defaults: %w", err)
+		}
+		if n = lookupNode(&def, keys...); n == nil {
+			return "", false, fmt.Errorf("%s: not set", key)
+		}
+	} else {
+		set = true
+	}
+	if n.Kind == yaml.ScalarNode {
+		return n.Value, set, nil
+	}
+	out, err := encodeNode(n)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(string(out), "\n"), set, nil
+}
+
+// SetKey sets a dotted key to value, which is parsed as YAML (so "true",
+// "30s" and `["-j"]` all work), creating intermediate mappings as needed.
+// Comments and the order of existing keys are preserved. The result must
+// pass CheckData; otherwise the edit is rejected.
+func SetKey(data []byte, key, value string) ([]byte, error) {
+	keys, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var val yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &val); err != nil {
+		return nil, fmt.Errorf("parse value %q: %w", value, err)
+	}
+	newNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""}
+	if len(val.Content) > 0 {
+		newNode = val.Content[0]
+	}
+
+	doc, err := parseDoc(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	n := doc.Content[0]
+	for i, k := range keys {
+		last := i == len(keys)-1
+		switch n.Kind {
+		case yaml.MappingNode:
+			j := mappingIndex(n, k)
+			if j < 0 {
+				child := &yaml.Node{Kind: yaml.MappingNode}
+				if last {
+					child = newNode
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k}, child)
+				n = child
+				continue
+			}
+			if last {
+				replaceValue(n.Content[j+1], newNode)
+				continue
+			}
+			n = n.Content[j+1]
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(k)
+			if err != nil || idx < 0 || idx > len(n.Content) {
+				return nil, fmt.Errorf("%s: index %q out of range", key, k)
+			}
+			if idx == len(n.Content) {
+				// Appending one past the end is allowed.
+				child := &yaml.Node{Kind: yaml.MappingNode}
+				if last {
+					child = newNode
+				}
+				n.Content = append(n.Content, child)
+				n = child
+				continue
+			}
+			if last {
+				replaceValue(n.Content[idx], newNode)
+				continue
+			}
+			n = n.Content[idx]
+		case yaml.ScalarNode:
+			if n.Tag != "!!null" {
+				return nil, fmt.Errorf("%s: %s is a scalar", key, strings.Join(keys[:i], "."))
+			}
+			// An empty "key:" becomes a mapping.
+			n.Kind, n.Tag, n.Value = yaml.MappingNode, "", ""
+			child := &yaml.Node{Kind: yaml.MappingNode}
+			if last {
+				child = newNode
+			}
+			n.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Value: k}, child}
+			n = child
+		default:
+			return nil, fmt.Errorf("%s: cannot descend into %s", key, strings.Join(keys[:i], "."))
+		}
+	}
+	return encodeChecked(doc)
+}
+
+// UnsetKey removes a dotted key, so the built-in default applies again.
+// Removing a key that is not set is not an error.
+func UnsetKey(data []byte, key string) ([]byte, error) {
+	keys, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDoc(data)
+	if err != nil {
+		return nil, err
+	}
+	parent := lookupNode(doc, keys[:len(keys)-1]...)
+	if parent == nil {
+		return data, nil
+	}
+	k := keys[len(keys)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		j := mappingIndex(parent, k)
+		if j < 0 {
+			return data, nil
+		}
+		parent.Content = append(parent.Content[:j], parent.Content[j+2:]...)
+	case yaml.SequenceNode:
+		idx, err := strconv.Atoi(k)
+		if err != nil || idx < 0 || idx >= len(parent.Content) {
+			return data, nil
+		}
+		parent.Content = append(parent.Content[:idx], parent.Content[idx+1:]...)
+	default:
+		return data, nil
+	}
+	return encodeChecked(doc)
+}
+
+// ApproveScript records sum as the approved digest of the script at path,
+// replacing any earlier approval of it.
+func ApproveScript(data []byte, path, sum string) ([]byte, error) {
+	doc, list, err := approvedScripts(data)
+	if err != nil {
+		return nil, err
+	}
+	var entry yaml.Node
+	if err := entry.Encode(ApprovedScript{Path: path, SHA256: sum}); err != nil {
+		return nil, fmt.Errorf("encode approval: %w", err)
+	}
+	for i, n := range list.Content {
+		if p := lookupNode(n, "path"); p != nil && p.Value == path {
+			replaceValue(list.Content[i], &entry)
+			return encodeChecked(doc)
+		}
+	}
+	list.Content = append(list.Content, &entry)
+	return encodeChecked(doc)
+}
+
+// RevokeScript removes the approval of the script at path and reports
+// whether there was one.
+func RevokeScript(data []byte, path string) ([]byte, bool, error) {
+	doc, list, err := approvedScripts(data)
+	if err != nil {
+		return nil, false, err
+	}
+	for i, n := range list.Content {
+		if p := lookupNode(n, "path"); p != nil && p.Value == path {
+			list.Content = append(list.Content[:i], list.Content[i+1:]...)
+			out, err := encodeChecked(doc)
+			return out, err == nil, err
+		}
+	}
+	return data, false, nil
+}
+
+// approvedScripts returns the config document and its
+// policy.approved_scripts sequence, creating an empty one if need be.
+func approvedScripts(data []byte) (*yaml.Node, *yaml.Node, error) {
+	doc, err := parseDoc(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lookupNode(doc, "policy", "approved_scripts") == nil {
+		if data, err = SetKey(data, "policy.approved_scripts", "[]"); err != nil {
+			return nil, nil, err
+		}
+		if doc, err = parseDoc(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	list := lookupNode(doc, "policy", "approved_scripts")
+	if list.Kind == yaml.ScalarNode && list.Tag == "!!null" {
+		list.Kind, list.Tag, list.Value = yaml.SequenceNode, "", ""
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, nil, fmt.Errorf("policy.approved_scripts is not a list")
+	}
+	list.Style = 0 // an empty list is written in flow style
+	return doc, list, nil
+}
+
+// WriteFileAtomic replaces path with data via a temporary file and rename,
+// preserving the existing file mode (0600 for new files).
+func WriteFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+func splitKey(key string) ([]string, error) {
+	keys := strings.Split(key, ".")
+	for _, k := range keys {
+		if k == "" {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return keys, nil
+}
+
+func parseDoc(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	return &doc, nil
+}
+
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// replaceValue overwrites dst with src while keeping dst's comments.
+func replaceValue(dst, src *yaml.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+}
+
+func encodeNode(n *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeChecked(doc *yaml.Node) ([]byte, error) {
+	out, err := encodeNode(doc)
+	if err != nil {
+		return nil, err
+	}
+	if problems := CheckData(out); len(problems) > 0 {
+		msgs := make([]string, len(problems))
+		for i, p := range problems {
+			msgs[i] = p.Message
+		}
+		return nil, fmt.Errorf("resulting config is invalid: %s", strings.Join(msgs, "; "))
+	}
+	return out, nil
+}
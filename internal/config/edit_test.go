@@ -0,0 +1,163 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const editSample = `# doit config
+tiers:
+  # allow rm and friends
+  dangerous: false # flip with care
+audit:
+  max_size_mb: 50
+`
+
+func TestGetKey(t *testing.T) {
+	v, set, err := GetKey([]byte(editSample), "audit.max_size_mb")
+	if err != nil || !set || v != "50" {
+		t.Errorf("GetKey(audit.max_size_mb) = %q, %v, %v; want 50, true, nil", v, set, err)
+	}
+
+	// Falls back to defaults when unset.
+	v, set, err = GetKey([]byte(editSample), "tiers.read")
+	if err != nil || set || v != "true" {
+		t.Errorf("GetKey(tiers.read) = %q, %v, %v; want true, false, nil", v, set, err)
+	}
+	v, _, err = GetKey(nil, "rules.make.reject_flags.0")
+	if err != nil || v != "-j" {
+		t.Errorf("GetKey(rules.make.reject_flags.0) = %q, %v; want -j", v, err)
+	}
+
+	if _, _, err := GetKey([]byte(editSample), "tiers.bogus"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	if _, _, err := GetKey(nil, "tiers..read"); err == nil {
+		t.Error("expected error for malformed key")
+	}
+}
+
+func TestSetKeyPreservesComments(t *testing.T) {
+	out, err := SetKey([]byte(editSample), "tiers.dangerous", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, want := range []string{"# doit config", "# allow rm and friends", "dangerous: true # flip with care", "max_size_mb: 50"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q:\n%s", want, s)
+		}
+	}
+	if v, _, _ := GetKey(out, "tiers.dangerous"); v != "true" {
+		t.Errorf("tiers.dangerous = %q after set, want true", v)
+	}
+}
+
+func TestSetKeyCreatesPath(t *testing.T) {
+	out, err := SetKey(nil, "rules.make.reject_flags", `["-j", "-l"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = SetKey(out, "policy.level3_timeout", "30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, set, _ := GetKey(out, "rules.make.reject_flags.1"); !set || v != "-l" {
+		t.Errorf("rules.make.reject_flags.1 = %q (set %v), want -l", v, set)
+	}
+	if v, _, _ := GetKey(out, "policy.level3_timeout"); v != "30s" {
+		t.Errorf("policy.level3_timeout = %q, want 30s", v)
+	}
+}
+
+func TestSetKeyRejectsInvalid(t *testing.T) {
+	cases := []struct{ key, value string }{
+		{"tiers.dangerus", "true"},
+		{"tiers.dangerous", "maybe"},
+		{"policy.level3_timeout", "soon"},
+		{"audit.max_size_mb.x", "1"},
+	}
+	for _, c := range cases {
+		if _, err := SetKey([]byte(editSample), c.key, c.value); err == nil {
+			t.Errorf("SetKey(%s, %s): expected error", c.key, c.value)
+		}
+	}
+}
+
+func TestUnsetKey(t *testing.T) {
+	out, err := UnsetKey([]byte(editSample), "audit.max_size_mb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "max_size_mb") {
+		t.Errorf("max_size_mb still present:\n%s", out)
+	}
+	if !strings.Contains(string(out), "# allow rm and friends") {
+		t.Errorf("comment lost:\n%s", out)
+	}
+
+	// Unsetting something absent is a no-op.
+	again, err := UnsetKey(out, "policy.level3_timeout")
+	if err != nil || string(again) != string(out) {
+		t.Errorf("UnsetKey of absent key changed output or failed: %v", err)
+	}
+}
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("tiers: {}\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("tiers:\n  read: true\n")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+}
+
+func TestApproveScript(t *testing.T) {
+	sum1 := strings.Repeat("a", 64)
+	sum2 := strings.Repeat("b", 64)
+	data := []byte("# my config\npolicy:\n  level3_enabled: false\n")
+	out, err := ApproveScript(data, "/repo/dev.sh", sum1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, err = ApproveScript(out, "/repo/ci.sh", sum1); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = ApproveScript(out, "/repo/dev.sh", sum2); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, out, 0o600)
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.Policy.ScriptApprovals()
+	if len(got) != 2 || got["/repo/dev.sh"] != sum2 || got["/repo/ci.sh"] != sum1 || !strings.Contains(string(out), "# my config") {
+		t.Errorf("approvals %v in:\n%s", got, out)
+	}
+
+	out, found, err := RevokeScript(out, "/repo/dev.sh")
+	if err != nil || !found {
+		t.Fatalf("RevokeScript: found %v, err %v", found, err)
+	}
+	if _, found, _ := RevokeScript(out, "/repo/dev.sh"); found {
+		t.Error("revoking twice should find nothing")
+	}
+	if _, err := ApproveScript(nil, "dev.sh", sum1); err == nil {
+		t.Error("relative path: expected an error")
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"log"
+
+	"github.com/marcelocantos/doit/internal/policy"
+	doitstar "github.com/marcelocantos/doit/internal/starlark"
+)
+
+// Level1 builds the deterministic policy level c describes: the
+// hardcoded rules, the configured capability rules (or the defaults), any
+// Starlark rules, the approved scripts, and the sensitive paths to watch
+// for being sent over the network. A project's safe commands, if
+// any, are allowed too. Starlark rules that fail to load are logged and
+// left out rather than failing the whole level.
+func (c *Config) Level1(projectType string, safeCommands []string) *policy.Level1 {
+	cfgRules := c.Rules
+	if cfgRules == nil {
+		cfgRules = DefaultRules()
+	}
+	var starlarkEval *doitstar.Evaluator
+	if c.Policy.StarlarkRulesDir != "" {
+		starRules, err := doitstar.LoadDir(c.Policy.StarlarkRulesDir)
+		if err != nil {
+			log.Printf("doit: policy: starlark rules: %v (continuing without starlark rules)", err)
+		} else if len(starRules) > 0 {
+			starlarkEval = doitstar.NewEvaluator(starRules)
+			log.Printf("doit: policy: loaded %d starlark rules", len(starRules))
+		}
+	}
+	l1 := policy.NewLevel1WithStarlark(cfgRules, starlarkEval)
+	l1.AddApprovedScripts(c.Policy.ScriptApprovals())
+	l1.SetSensitivePaths(c.SensitivePaths)
+
+	// Inject project-context-aware safe-command rules (🎯T13).
+	if len(safeCommands) > 0 {
+		l1.AddProjectContextRules(projectType, safeCommands)
+	}
+	return l1
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"path"
+
+	"github.com/marcelocantos/doit/internal/cap"
+)
+
+// ExecProfile is a named environment commands run in, chosen by the
+// decision that let them run (see ProfileSelector). It applies to the
+// commands doit runs through the shell; in-process capabilities run
+// inside doit itself.
+type ExecProfile struct {
+	// NoNetwork runs commands in an empty network namespace (Linux
+	// only; elsewhere they do not run).
+	NoNetwork bool `yaml:"no_network,omitempty"`
+
+	// Env is set in commands' environment, over what they would
+	// otherwise have. With ClearEnv, they start from only PATH, HOME,
+	// USER, LOGNAME, LANG, TERM, TMPDIR, and the request's own.
+	Env      map[string]string `yaml:"env,omitempty"`
+	ClearEnv bool              `yaml:"clear_env,omitempty"`
+
+	// Limits are resource limits, set with ulimit before the command.
+	Limits ProfileLimits `yaml:"limits,omitempty"`
+
+	// Nice lowers commands' CPU priority, from 1 to 19 (nice -n).
+	Nice int `yaml:"nice,omitempty"`
+
+	// IONice lowers their I/O priority (Linux ionice): "idle" or
+	// "best-effort", the lowest best-effort priority.
+	IONice string `yaml:"ionice,omitempty"`
+}
+
+// ProfileLimits are resource limits on each process of a command. Zero
+// fields are unlimited.
+type ProfileLimits struct {
+	CPU        string `yaml:"cpu,omitempty"`          // CPU time, e.g. 5m
+	MemoryMB   int    `yaml:"memory_mb,omitempty"`    // address space
+	FileSizeMB int    `yaml:"file_size_mb,omitempty"` // largest file it may write
+	OpenFiles  int    `yaml:"open_files,omitempty"`   // open file descriptors
+}
+
+// ProfileSelector picks the profile for commands by the decision that
+// let them run. Zero fields match anything.
+type ProfileSelector struct {
+	Level   int    `yaml:"level,omitempty"` // decided at this policy level: 1, 2, or 3
+	Tier    string `yaml:"tier,omitempty"`  // commands of this effective tier or above
+	Rule    string `yaml:"rule,omitempty"`  // decided by this rule or grant ID; may be a glob
+	Profile string `yaml:"profile"`
+}
+
+// ProfileFor returns the profile for a command of tier whose decision
+// was made at level by rule, and its name: that of the first selector
+// that matches, or none.
+func (x *ExecConfig) ProfileFor(level int, tier cap.Tier, rule string) (string, *ExecProfile) {
+	for _, s := range x.Select {
+		if s.Level != 0 && s.Level != level {
+			continue
+		}
+		if s.Tier != "" {
+			if min, err := cap.ParseTier(s.Tier); err != nil || tier < min {
+				continue
+			}
+		}
+		if s.Rule != "" {
+			if ok, _ := path.Match(s.Rule, rule); !ok {
+				continue
+			}
+		}
+		if p, ok := x.Profiles[s.Profile]; ok {
+			return s.Profile, &p
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package events publishes the engine's request lifecycle — start, policy
+// decision, escalation, exit — to in-process subscribers and, via a Unix
+// socket, to external monitors such as dashboards or status bars.
+package events
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Type identifies what happened.
+type Type string
+
+const (
+	RequestStart Type = "request-start" // a command was submitted
+	Decision     Type = "decision"      // the policy chain decided
+	Escalation   Type = "escalation"    // an approval token was issued
+	Exit         Type = "exit"          // the request finished
+	Resolution   Type = "resolution"    // a human approved or denied an escalation
+	Violation    Type = "violation"     // a command broke a configured post-condition
+	Tripwire     Type = "tripwire"      // a command touched a configured tripwire
+)
+
+// Types lists every event type in lifecycle order.
+var Types = []Type{RequestStart, Decision, Tripwire, Escalation, Violation, Exit, Resolution}
+
+// ParseType validates an event type name.
+func ParseType(s string) (Type, error) {
+	for _, t := range Types {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q (want request-start, decision, tripwire, escalation, violation, exit, or resolution)", s)
+}
+
+// Event is one step in a request's lifecycle. PID and Request together
+// identify the request across every doit process. Approval tokens are
+// never included: a monitor can observe escalations but not approve them
+// by replaying the token.
+type Event struct {
+	Type       Type      `json:"type"`
+	Time       time.Time `json:"ts"`
+	PID        int       `json:"pid"`
+	Request    uint64    `json:"request"`
+	Command    string    `json:"command"`
+	Cwd        string    `json:"cwd,omitempty"`
+	Session    string    `json:"session,omitempty"`
+	Agent      string    `json:"agent,omitempty"`
+	Level      int       `json:"policy_level,omitempty"`
+	Decision   string    `json:"policy_decision,omitempty"`
+	RuleID     string    `json:"policy_rule_id,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	ExitCode   *int      `json:"exit_code,omitempty"`   // exit events only
+	Signal     string    `json:"signal,omitempty"`      // exit events only, when a signal killed the command
+	DurationMS float64   `json:"duration_ms,omitempty"` // exit events only
+}
+
+// Bus fans events out to subscribers. Publishing never blocks: a
+// subscriber whose buffer is full misses the event, and the miss is
+// counted in its Dropped total.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBus returns an empty Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Publish delivers e to every subscriber interested in its type.
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if len(s.types) > 0 && !s.types[e.Type] {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe registers a subscriber with the given buffer size for the
+// given event types (all types if none are given). Call Close when done.
+func (b *Bus) Subscribe(buffer int, types ...Type) *Subscription {
+	s := &Subscription{bus: b, ch: make(chan Event, buffer)}
+	if len(types) > 0 {
+		s.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+	s.C = s.ch
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Subscription receives events from a Bus on C until closed.
+type Subscription struct {
+	C <-chan Event
+
+	bus     *Bus
+	ch      chan Event
+	types   map[Type]bool
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Dropped returns the number of events missed because C was full.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Close unsubscribes and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		close(s.ch)
+		s.bus.mu.Unlock()
+	})
+}
+
+// Pending is an escalation whose approval token is still outstanding:
+// the agent has been told to retry with it, and a human may approve or
+// deny it in the meantime.
+type Pending struct {
+	PID       int       `json:"pid"`
+	Request   uint64    `json:"request"`
+	Command   string    `json:"command"`
+	Cwd       string    `json:"cwd,omitempty"`
+	Session   string    `json:"session,omitempty"`
+	Level     int       `json:"policy_level"`
+	Reason    string    `json:"reason,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Controller answers the control requests a socket client may send after
+// subscribing.
+type Controller interface {
+	// Pending lists outstanding escalations.
+	Pending() []Pending
+	// Resolve approves or denies the escalation of request. With always,
+	// the decision is also remembered as a learned policy entry.
+	Resolve(request uint64, approve, always bool) error
+}
+
+// Gatekeeper is the state of a doit's Level 3 LLM gatekeeper.
+type Gatekeeper struct {
+	PID      int       `json:"pid"`
+	Enabled  bool      `json:"enabled"`            // false once turned off with doit --llm disable
+	Open     bool      `json:"open,omitempty"`     // the circuit breaker has tripped
+	Failures int       `json:"failures,omitempty"` // consecutive LLM errors
+	RetryAt  time.Time `json:"retry_at,omitempty"` // when an open breaker next tries the LLM
+}
+
+// GatekeeperSwitch is implemented by Controllers that can turn the Level 3
+// gatekeeper off and on.
+type GatekeeperSwitch interface {
+	// Gatekeeper reports the gatekeeper's state.
+	Gatekeeper() (Gatekeeper, error)
+	// SetGatekeeper turns the gatekeeper on or off.
+	SetGatekeeper(enabled bool) (Gatekeeper, error)
+}
@@ -0,0 +1,261 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBus_FilterAndDrop(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe(8)
+	exits := bus.Subscribe(1, Exit)
+
+	bus.Publish(Event{Type: RequestStart, Request: 1})
+	bus.Publish(Event{Type: Exit, Request: 1})
+	bus.Publish(Event{Type: Exit, Request: 2}) // exits' buffer is full
+
+	if len(all.C) != 3 {
+		t.Errorf("all: %d events buffered, want 3", len(all.C))
+	}
+	if e := <-exits.C; e.Type != Exit || e.Request != 1 {
+		t.Errorf("exits got %+v, want exit for request 1", e)
+	}
+	if exits.Dropped() != 1 {
+		t.Errorf("exits dropped %d, want 1", exits.Dropped())
+	}
+
+	exits.Close()
+	exits.Close() // idempotent
+	if _, ok := <-exits.C; ok {
+		t.Error("closed subscription still delivering")
+	}
+	bus.Publish(Event{Type: Exit}) // must not panic on the closed channel
+}
+
+func TestParseType(t *testing.T) {
+	for _, typ := range Types {
+		if got, err := ParseType(string(typ)); err != nil || got != typ {
+			t.Errorf("ParseType(%q) = %q, %v", typ, got, err)
+		}
+	}
+	if _, err := ParseType("bogus"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestSocket(t *testing.T) {
+	// Unix socket paths are length-limited; t.TempDir can be too deep.
+	dir, err := os.MkdirTemp("", "ev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := SocketPath(dir, os.Getpid())
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket %s: %v, mode %v", path, err, info.Mode())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	bus := NewBus()
+	served := make(chan error, 1)
+	ctrl := &fakeController{pending: []Pending{{Request: 7, Command: "git push"}}, gatekeeper: Gatekeeper{Enabled: true}}
+	go func() { served <- Serve(ctx, l, bus, ctrl) }()
+
+	if socks, _ := Sockets(dir); len(socks) != 1 || socks[0] != path {
+		t.Errorf("Sockets = %v, want [%s]", socks, path)
+	}
+
+	got := make(chan Event, 4)
+	subErr := make(chan error, 1)
+	go func() {
+		subErr <- Subscribe(ctx, path, []Type{Decision}, func(e Event) error {
+			got <- e
+			return nil
+		})
+	}()
+
+	// Publish until the subscriber is registered and receives one.
+	deadline := time.After(2 * time.Second)
+	var e Event
+wait:
+	for {
+		bus.Publish(Event{Type: RequestStart, Command: "ls"})
+		bus.Publish(Event{Type: Decision, Command: "ls", Decision: "allow"})
+		select {
+		case e = <-got:
+			break wait
+		case <-deadline:
+			t.Fatal("no event received over socket")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if e.Type != Decision || e.Command != "ls" || e.Decision != "allow" {
+		t.Errorf("received %+v, want decision for ls", e)
+	}
+
+	// Control requests are answered in the stream.
+	c, err := Dial(ctx, path, []Type{Resolution})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.RequestPending(); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := c.Recv(); err != nil || f.Reply != "pending" || len(f.Pending) != 1 || f.Pending[0].Request != 7 {
+		t.Errorf("pending reply = %+v, %v", f, err)
+	}
+	if err := c.Resolve(7, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := c.Recv(); err != nil || f.Reply != "resolve" || f.Resolved != 7 {
+		t.Errorf("resolve reply = %+v, %v", f, err)
+	}
+	if ctrl.resolved != "7:false:false" {
+		t.Errorf("controller saw %q, want 7:false:false", ctrl.resolved)
+	}
+	if err := c.Resolve(8, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := c.Recv(); err != nil || f.Error == "" {
+		t.Errorf("resolve of unknown request = %+v, %v; want error frame", f, err)
+	}
+
+	// One-shot helpers for the CLI.
+	if p, err := ListPending(ctx, path); err != nil || len(p) != 1 || p[0].Command != "git push" {
+		t.Errorf("ListPending = %+v, %v", p, err)
+	}
+	if err := Resolve(ctx, path, 7, true, true); err != nil || ctrl.resolved != "7:true:true" {
+		t.Errorf("Resolve = %v; controller saw %q", err, ctrl.resolved)
+	}
+	if err := Resolve(ctx, path, 8, true, false); err == nil {
+		t.Error("Resolve of unknown request should fail")
+	}
+	if g, err := SetGatekeeper(ctx, path, "disable"); err != nil || g.Enabled || ctrl.gatekeeper.Enabled {
+		t.Errorf("SetGatekeeper(disable) = %+v, %v", g, err)
+	}
+	if g, err := SetGatekeeper(ctx, path, ""); err != nil || g.Enabled {
+		t.Errorf("SetGatekeeper(status) = %+v, %v", g, err)
+	}
+	if _, err := SetGatekeeper(ctx, path, "reboot"); err == nil {
+		t.Error("SetGatekeeper of unknown action should fail")
+	}
+
+	// A bad subscription is rejected with an error.
+	err = Subscribe(ctx, path, []Type{"bogus"}, func(Event) error { return nil })
+	if err == nil {
+		t.Error("expected error for unknown event type")
+	}
+
+	cancel()
+	if err := <-subErr; err != nil {
+		t.Errorf("Subscribe returned %v after cancel", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v after cancel", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket not removed after Serve: %v", err)
+	}
+}
+
+type fakeController struct {
+	pending    []Pending
+	resolved   string
+	gatekeeper Gatekeeper
+}
+
+func (f *fakeController) Gatekeeper() (Gatekeeper, error) { return f.gatekeeper, nil }
+
+func (f *fakeController) SetGatekeeper(enabled bool) (Gatekeeper, error) {
+	f.gatekeeper.Enabled = enabled
+	return f.gatekeeper, nil
+}
+
+func (f *fakeController) Pending() []Pending { return f.pending }
+
+func (f *fakeController) Resolve(request uint64, approve, always bool) error {
+	if request != 7 {
+		return fmt.Errorf("no pending escalation for request %d", request)
+	}
+	f.resolved = fmt.Sprintf("%d:%v:%v", request, approve, always)
+	return nil
+}
+
+func TestReadRequest_TooLong(t *testing.T) {
+	fits := strings.Repeat("x", maxRequestLine-1) + "\n"
+	if line, err := readRequest(bufio.NewReader(strings.NewReader(fits))); err != nil || len(line) != maxRequestLine {
+		t.Errorf("%d-byte line: %d bytes, %v", len(fits), len(line), err)
+	}
+	endless := io.MultiReader(strings.NewReader(`{"subscribe": [`), neverEnding('x'))
+	if _, err := readRequest(bufio.NewReader(endless)); !errors.Is(err, errRequestTooLong) {
+		t.Errorf("endless line: %v, want errRequestTooLong", err)
+	}
+}
+
+// neverEnding is an endless stream of one byte.
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
+
+// FuzzReadRequest feeds arbitrary bytes to the socket's request reader
+// and subscribe parser: neither may panic, and no line may come back
+// longer than maxRequestLine.
+func FuzzReadRequest(f *testing.F) {
+	for _, seed := range []string{
+		"\n",
+		`{"subscribe": ["decision", "exit"]}` + "\n",
+		`{"subscribe": ["nope"]}` + "\n",
+		`{"subscribe": "exit"}` + "\n",
+		"{\"subscribe\": [\"exit\"]}\r\n{\"op\": \"pending\"}\n",
+		"\x00\xff{",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		line, err := readRequest(bufio.NewReaderSize(bytes.NewReader(data), 16))
+		if len(line) > maxRequestLine {
+			t.Fatalf("read a %d-byte line", len(line))
+		}
+		if errors.Is(err, errRequestTooLong) {
+			if i := bytes.IndexByte(data, '\n'); i >= 0 && i < maxRequestLine {
+				t.Fatalf("a %d-byte line is too long", i+1)
+			}
+			return
+		}
+		if !bytes.HasPrefix(data, line) {
+			t.Fatalf("read %q, not a prefix of the input", line)
+		}
+		req, err := parseSubscribe(line)
+		if err != nil {
+			return
+		}
+		for _, typ := range req.Subscribe {
+			if _, err := ParseType(string(typ)); err != nil {
+				t.Fatalf("accepted subscription to %q", typ)
+			}
+		}
+	})
+}
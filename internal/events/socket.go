@@ -0,0 +1,487 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Socket protocol: a client connects to <dir>/<pid>.sock, one per running
+// doit, and sends a single JSON line naming the event types it wants:
+//
+//	{"subscribe": ["decision", "exit"]}
+//
+// An empty list (or an empty line) subscribes to everything. The server
+// then streams Event values, one JSON object per line, until either side
+// closes the connection. The client may also send control requests, each
+// answered in the same stream:
+//
+//	{"op": "pending"}                                → {"reply": "pending", "pending": [...]}
+//	{"op": "resolve", "request": 7, "approve": true} → {"reply": "resolve", "resolved": 7}
+//
+// A resolve request may add "always": true to also remember the decision
+// as a learned (L2) policy entry.
+//
+//	{"op": "llm", "llm": "disable"} → {"reply": "llm", "gatekeeper": {...}}
+//
+// turns the Level 3 gatekeeper off ("disable"), back on ("enable"), or,
+// with "llm" empty, just reports its state.
+//
+// Failures are reported as {"error": "..."}. A request line may be at
+// most 64 KiB; a longer one is refused and ends the connection, as does
+// taking more than 5 seconds to subscribe or leaving a line the server
+// sends unread for 10.
+
+// SubscribeRequest is the first line a client sends.
+type SubscribeRequest struct {
+	Subscribe []Type `json:"subscribe"`
+}
+
+// ControlRequest is a request sent after subscribing.
+type ControlRequest struct {
+	Op      string `json:"op"` // "pending", "resolve", or "llm"
+	Request uint64 `json:"request,omitempty"`
+	Approve bool   `json:"approve,omitempty"`
+	Always  bool   `json:"always,omitempty"`
+	LLM     string `json:"llm,omitempty"` // "enable", "disable", or "" for the state
+}
+
+// Frame is one line from the server: an event (Event set), a control
+// reply (Reply names the op), or an error.
+type Frame struct {
+	Event      *Event      `json:"-"`
+	Reply      string      `json:"reply,omitempty"`
+	Pending    []Pending   `json:"pending,omitempty"`
+	Resolved   uint64      `json:"resolved,omitempty"`
+	Gatekeeper *Gatekeeper `json:"gatekeeper,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// socketBuffer is the per-connection event buffer. Slow readers miss
+// events rather than stalling the engine.
+const socketBuffer = 256
+
+// handshakeTimeout bounds how long a client may take to subscribe.
+const handshakeTimeout = 5 * time.Second
+
+// writeTimeout bounds each write to a client. One that stops reading is
+// dropped rather than left holding its connection's goroutines.
+const writeTimeout = 10 * time.Second
+
+// maxRequestLine bounds a subscribe or control request, so that a client
+// cannot make doit buffer an endless line.
+const maxRequestLine = 64 << 10
+
+// errRequestTooLong reports a request line over maxRequestLine.
+var errRequestTooLong = fmt.Errorf("request longer than %d bytes", maxRequestLine)
+
+// SocketPath returns the socket path for process pid in dir.
+func SocketPath(dir string, pid int) string {
+	return filepath.Join(dir, strconv.Itoa(pid)+".sock")
+}
+
+// Listen creates dir (mode 0700) and listens on this process's socket in
+// it, replacing any stale socket left by an earlier process with the same
+// PID and removing those of processes that have exited (see CleanStale).
+// Closing the listener removes the socket.
+func Listen(dir string) (net.Listener, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("events socket: %w", err)
+	}
+	_, _ = CleanStale(dir) // best-effort housekeeping
+	path := SocketPath(dir, os.Getpid())
+	_ = os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("events socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("events socket: %w", err)
+	}
+	return l, nil
+}
+
+// Serve accepts subscribers on l and streams events from bus to them
+// until ctx is done, then closes l. Control requests go to ctrl; if ctrl
+// is nil they are refused.
+func Serve(ctx context.Context, l net.Listener, bus *Bus, ctrl Controller) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("events socket: %w", err)
+		}
+		go serveConn(ctx, conn, bus, ctrl)
+	}
+}
+
+func serveConn(ctx context.Context, conn net.Conn, bus *Bus, ctrl Controller) {
+	defer conn.Close()
+	defer func() {
+		// A failure serving one subscriber must not take down the process.
+		if p := recover(); p != nil {
+			log.Printf("doit: events socket: panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	var mu sync.Mutex // serializes writes from the event and control paths
+	enc := json.NewEncoder(conn)
+	send := func(v any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return enc.Encode(v)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	r := bufio.NewReader(conn)
+	line, err := readRequest(r)
+	if errors.Is(err, errRequestTooLong) {
+		send(Frame{Error: "bad subscribe request: " + err.Error()})
+		return
+	}
+	if err != nil {
+		return
+	}
+	req, err := parseSubscribe(line)
+	if err != nil {
+		send(Frame{Error: err.Error()})
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	sub := bus.Subscribe(socketBuffer, req.Subscribe...)
+	defer sub.Close()
+
+	// Control requests; the loop ends when the client hangs up.
+	hangup := make(chan struct{})
+	go func() {
+		defer close(hangup)
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, maxRequestLine)
+		for sc.Scan() {
+			var creq ControlRequest
+			if err := json.Unmarshal(sc.Bytes(), &creq); err != nil {
+				send(Frame{Error: "bad control request: " + err.Error()})
+				continue
+			}
+			if send(control(ctrl, creq)) != nil {
+				return
+			}
+		}
+		if errors.Is(sc.Err(), bufio.ErrTooLong) {
+			send(Frame{Error: "bad control request: " + errRequestTooLong.Error()})
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			return
+		case e := <-sub.C:
+			if err := send(e); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readRequest reads one request line from r, failing with
+// errRequestTooLong once it passes maxRequestLine bytes rather than
+// buffering the rest.
+func readRequest(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxRequestLine {
+			return nil, errRequestTooLong
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// parseSubscribe parses a subscribe request line. A blank line
+// subscribes to everything.
+func parseSubscribe(line []byte) (SubscribeRequest, error) {
+	var req SubscribeRequest
+	if len(bytes.TrimSpace(line)) > 0 {
+		if err := json.Unmarshal(line, &req); err != nil {
+			return req, fmt.Errorf("bad subscribe request: %w", err)
+		}
+	}
+	for _, t := range req.Subscribe {
+		if _, err := ParseType(string(t)); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// control answers one control request. A panic in ctrl becomes an error
+// frame.
+func control(ctrl Controller, req ControlRequest) (f Frame) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("doit: events socket: panic in %s: %v\n%s", req.Op, p, debug.Stack())
+			f = Frame{Error: fmt.Sprintf("internal error: %v", p)}
+		}
+	}()
+	if ctrl == nil {
+		return Frame{Error: "control requests not supported"}
+	}
+	switch req.Op {
+	case "pending":
+		return Frame{Reply: req.Op, Pending: ctrl.Pending()}
+	case "resolve":
+		if err := ctrl.Resolve(req.Request, req.Approve, req.Always); err != nil {
+			return Frame{Error: err.Error()}
+		}
+		return Frame{Reply: req.Op, Resolved: req.Request}
+	case "llm":
+		sw, ok := ctrl.(GatekeeperSwitch)
+		if !ok {
+			return Frame{Error: "llm requests not supported"}
+		}
+		var (
+			g   Gatekeeper
+			err error
+		)
+		switch req.LLM {
+		case "":
+			g, err = sw.Gatekeeper()
+		case "enable", "disable":
+			g, err = sw.SetGatekeeper(req.LLM == "enable")
+		default:
+			return Frame{Error: fmt.Sprintf("unknown llm action %q", req.LLM)}
+		}
+		if err != nil {
+			return Frame{Error: err.Error()}
+		}
+		return Frame{Reply: req.Op, Gatekeeper: &g}
+	default:
+		return Frame{Error: fmt.Sprintf("unknown op %q", req.Op)}
+	}
+}
+
+// Sockets returns the event sockets in dir, one per running doit.
+func Sockets(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*.sock"))
+}
+
+// Client is a connection to one doit's event socket.
+type Client struct {
+	conn net.Conn
+	sc   *bufio.Scanner
+	mu   sync.Mutex
+	enc  *json.Encoder
+}
+
+// Dial connects to the socket at path and subscribes to the given event
+// types (all if none).
+func Dial(ctx context.Context, path string, types []Type) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("events: %w", err)
+	}
+	c := &Client{conn: conn, sc: bufio.NewScanner(conn), enc: json.NewEncoder(conn)}
+	c.sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	if err := c.send(SubscribeRequest{Subscribe: types}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) send(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(v); err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+	return nil
+}
+
+// RequestPending asks for the outstanding escalations; the reply arrives
+// as a Frame with Reply "pending".
+func (c *Client) RequestPending() error {
+	return c.send(ControlRequest{Op: "pending"})
+}
+
+// Resolve approves or denies an escalation, remembering the decision if
+// always is set; the reply arrives as a Frame with Reply "resolve" or
+// Error set.
+func (c *Client) Resolve(request uint64, approve, always bool) error {
+	return c.send(ControlRequest{Op: "resolve", Request: request, Approve: approve, Always: always})
+}
+
+// RequestGatekeeper turns the Level 3 gatekeeper on or off ("enable" or
+// "disable") or, with action empty, asks for its state; the reply
+// arrives as a Frame with Reply "llm" or Error set.
+func (c *Client) RequestGatekeeper(action string) error {
+	return c.send(ControlRequest{Op: "llm", LLM: action})
+}
+
+// reply waits for the next control reply, skipping events.
+func (c *Client) reply() (Frame, error) {
+	for {
+		f, err := c.Recv()
+		if err != nil {
+			return Frame{}, err
+		}
+		if f.Error != "" {
+			return Frame{}, fmt.Errorf("events: %s", f.Error)
+		}
+		if f.Reply != "" {
+			return f, nil
+		}
+	}
+}
+
+// Recv returns the next frame, or an error once the server closes the
+// stream or the client is closed.
+func (c *Client) Recv() (Frame, error) {
+	if !c.sc.Scan() {
+		err := c.sc.Err()
+		if err == nil {
+			err = errEOF
+		}
+		return Frame{}, err
+	}
+	line := c.sc.Bytes()
+	var f Frame
+	if err := json.Unmarshal(line, &f); err != nil {
+		return Frame{}, fmt.Errorf("events: %w", err)
+	}
+	if f.Reply == "" && f.Error == "" {
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return Frame{}, fmt.Errorf("events: %w", err)
+		}
+		f.Event = &e
+	}
+	return f, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+var errEOF = errors.New("events: server closed the stream")
+
+// Subscribe connects to the socket at path and calls fn for each event of
+// the given types (all if none) until ctx is done, the server goes away,
+// or fn returns an error. It returns nil when ctx is done or the server
+// closes the stream.
+func Subscribe(ctx context.Context, path string, types []Type, fn func(Event) error) error {
+	c, err := Dial(ctx, path, types)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	for {
+		f, err := c.Recv()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, errEOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if f.Error != "" {
+			return fmt.Errorf("events: %s", f.Error)
+		}
+		if f.Event != nil {
+			if err := fn(*f.Event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ListPending returns the outstanding escalations of the doit listening on
+// the socket at path.
+func ListPending(ctx context.Context, path string) ([]Pending, error) {
+	c, err := Dial(ctx, path, []Type{Resolution})
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+	if err := c.RequestPending(); err != nil {
+		return nil, err
+	}
+	f, err := c.reply()
+	if err != nil {
+		return nil, err
+	}
+	return f.Pending, nil
+}
+
+// Resolve approves or denies an escalation of the doit listening on the
+// socket at path and waits for it to be acknowledged.
+func Resolve(ctx context.Context, path string, request uint64, approve, always bool) error {
+	c, err := Dial(ctx, path, []Type{Resolution})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+	if err := c.Resolve(request, approve, always); err != nil {
+		return err
+	}
+	_, err = c.reply()
+	return err
+}
+
+// SetGatekeeper turns the Level 3 gatekeeper of the doit listening on the
+// socket at path on or off ("enable" or "disable"), or with action empty
+// just reports it, and returns its state.
+func SetGatekeeper(ctx context.Context, path, action string) (*Gatekeeper, error) {
+	c, err := Dial(ctx, path, []Type{Resolution})
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+	if err := c.RequestGatekeeper(action); err != nil {
+		return nil, err
+	}
+	f, err := c.reply()
+	if err != nil {
+		return nil, err
+	}
+	return f.Gatekeeper, nil
+}
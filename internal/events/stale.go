@@ -0,0 +1,70 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// staleDialTimeout bounds the liveness probe of a socket whose PID is in
+// use.
+const staleDialTimeout = 500 * time.Millisecond
+
+// CleanStale removes the sockets in dir left behind by doit processes that
+// are gone, returning their paths. A socket is stale when its process has
+// exited, or when its PID now belongs to some other process and nothing
+// answers on the socket. Sockets owned by another user, files that are not
+// sockets, and this process's own socket are never touched.
+func CleanStale(dir string) ([]string, error) {
+	socks, err := Sockets(dir)
+	if err != nil {
+		return nil, fmt.Errorf("events socket: %w", err)
+	}
+	var removed []string
+	for _, path := range socks {
+		if !isStale(path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("events socket: %w", err)
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// isStale reports whether the socket at path is ours to remove and no
+// longer served.
+func isStale(path string) bool {
+	pid, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), ".sock"))
+	if err != nil || pid <= 0 || pid == os.Getpid() {
+		return false
+	}
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode().Type() != os.ModeSocket {
+		return false
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Getuid() {
+		return false
+	}
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	// The PID is in use, perhaps recycled by an unrelated process: only a
+	// refused connection proves nobody serves the socket.
+	conn, err := net.DialTimeout("unix", path, staleDialTimeout)
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	conn.Close()
+	return false
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// leaveSocket creates a socket file for pid in dir with no listener.
+func leaveSocket(t *testing.T, dir string, pid int) string {
+	t.Helper()
+	path := SocketPath(dir, pid)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	return path
+}
+
+func TestCleanStale(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dead := leaveSocket(t, dir, exited.Process.Pid)
+	// The parent is alive but does not serve this socket, as when a PID
+	// has been recycled.
+	recycled := leaveSocket(t, dir, os.Getppid())
+
+	// A live process serving its socket is kept.
+	live := filepath.Join(dir, "1.sock")
+	l, err := net.Listen("unix", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	// Files that are not sockets are not ours to remove.
+	plain := filepath.Join(dir, "2.sock")
+	os.WriteFile(plain, nil, 0o600)
+
+	removed, err := CleanStale(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{dead: true, recycled: true}
+	if len(removed) != len(want) {
+		t.Fatalf("removed %v, want %s and %s", removed, dead, recycled)
+	}
+	for _, p := range removed {
+		if !want[p] {
+			t.Errorf("removed %s unexpectedly", p)
+		}
+	}
+	for _, p := range []string{live, plain} {
+		if _, err := os.Lstat(p); err != nil {
+			t.Errorf("%s was removed: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package httpapi serves doit's engine as a REST API (doit --serve-http),
+// for web dashboards and agent frameworks that speak HTTP rather than MCP.
+// Requests and results take the JSON form of the doitclient package.
+//
+//	POST /v1/execute       run a command; stream its output as server-sent
+//	                       events if the client accepts text/event-stream
+//	POST /v1/evaluate      judge a command without running it
+//	GET  /v1/capabilities  the capability catalogue
+//	GET  /v1/audit?n=N     the last N audit entries (default 20)
+//
+// Every request must carry the server's bearer token. A client that takes
+// over a minute to send its request, or leaves a write of the response
+// unread for 30 seconds, is disconnected, stopping a command whose output
+// it was streaming.
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcelocantos/doit/doitclient"
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/wire"
+)
+
+// maxBody bounds a request body, which carries the command's stdin.
+const maxBody = 32 << 20
+
+// A client gets readTimeout to send a request body, and writeTimeout to
+// accept each write of the response, so that a stalled one cannot hold a
+// connection open for ever.
+const (
+	readTimeout  = time.Minute
+	writeTimeout = 30 * time.Second
+)
+
+// defaultAuditCount is the number of entries /v1/audit returns without n.
+const defaultAuditCount = 20
+
+// evaluation is the response to /v1/evaluate.
+type evaluation struct {
+	Decision   string `json:"decision"` // "allow", "deny", or "escalate"
+	Level      int    `json:"level"`
+	Reason     string `json:"reason"`
+	RuleID     string `json:"rule_id,omitempty"`
+	Bypassable bool   `json:"bypassable,omitempty"`
+	Tier       string `json:"tier,omitempty"` // highest tier of anything the command runs
+}
+
+type server struct {
+	eng     *engine.Engine
+	token   string
+	version string
+}
+
+// Handler returns the API over eng. Requests must carry token as
+// "Authorization: Bearer <token>"; version is reported in the
+// capability catalogue.
+func Handler(eng *engine.Engine, token, version string) http.Handler {
+	s := &server{eng: eng, token: token, version: version}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/execute", s.execute)
+	mux.HandleFunc("POST /v1/evaluate", s.evaluate)
+	mux.HandleFunc("GET /v1/capabilities", s.capabilities)
+	mux.HandleFunc("GET /v1/audit", s.audit)
+	return s.authorize(mux)
+}
+
+// authorize rejects requests without the server's bearer token.
+func (s *server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="doit"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// request decodes the doitclient.Request in the body of r.
+func request(w http.ResponseWriter, r *http.Request) (doitclient.Request, error) {
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(readTimeout))
+	defer rc.SetReadDeadline(time.Time{})
+
+	var req doitclient.Request
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return req, fmt.Errorf("invalid request: %w", err)
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		return req, errors.New("missing required field: command")
+	}
+	return req, nil
+}
+
+func (s *server) execute(w http.ResponseWriter, r *http.Request) {
+	req, err := request(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// The client hanging up cancels the request, stopping the command.
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeJSON(w, http.StatusOK, wire.Result(s.eng.Execute(r.Context(), wire.Request(req))))
+		return
+	}
+
+	// A client that stops reading the stream stops the command too, which
+	// would otherwise run on, blocked on its output or with its output
+	// discarded.
+	ctx, cancel := context.WithCancelCause(r.Context())
+	defer cancel(nil)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	ev := &events{w: w, rc: http.NewResponseController(w), stalled: func() { cancel(errClientStalled) }}
+	res := s.eng.ExecuteStreaming(ctx, wire.Request(req), ev.stream("stdout"), ev.stream("stderr"))
+	ev.send("result", wire.Result(res))
+}
+
+// errClientStalled stops, and is audited as the error of, a command whose
+// client has stopped reading its output.
+var errClientStalled = errors.New("client stalled: stopped reading the output stream")
+
+func (s *server) evaluate(w http.ResponseWriter, r *http.Request) {
+	req, err := request(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res := s.eng.Evaluate(r.Context(), wire.Request(req))
+	writeJSON(w, http.StatusOK, evaluation{
+		Decision:   res.Decision,
+		Level:      res.Level,
+		Reason:     res.Reason,
+		RuleID:     res.RuleID,
+		Bypassable: res.Bypassable,
+		Tier:       res.Tier,
+	})
+}
+
+func (s *server) capabilities(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.eng.Manifest(s.version).Capabilities)
+}
+
+func (s *server) audit(w http.ResponseWriter, r *http.Request) {
+	n := defaultAuditCount
+	if v := r.URL.Query().Get("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid n %q: want a positive count", v))
+			return
+		}
+	}
+	if err := s.eng.FlushAudit(); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("flush audit log: %w", err))
+		return
+	}
+	entries, err := audit.Tail(s.eng.AuditPath(), n)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("read audit log: %w", err))
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// events writes server-sent events: "stdout" and "stderr" events whose
+// data is a JSON string of output, as the command writes it, then one
+// "result" event with the doitclient.Result. Once a write fails — the
+// client has gone, or stopped reading for writeTimeout — the rest are
+// dropped and stalled is called.
+type events struct {
+	mu      sync.Mutex
+	w       io.Writer
+	rc      *http.ResponseController
+	stalled func()
+	failed  bool
+}
+
+// send writes one event and flushes it to the client.
+func (e *events) send(event string, v any) {
+	data, _ := json.Marshal(v)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failed {
+		return
+	}
+	e.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, data)
+	if err == nil {
+		err = e.rc.Flush()
+	}
+	if err != nil {
+		e.failed = true
+		e.stalled()
+	}
+}
+
+// stream returns a writer whose writes are sent as event. Output that is
+// not UTF-8 is sent with U+FFFD in place of its invalid bytes.
+func (e *events) stream(event string) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		e.send(event, string(p))
+		return len(p), nil
+	})
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(writeTimeout))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,220 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcelocantos/doit/doitclient"
+	"github.com/marcelocantos/doit/engine"
+	"github.com/marcelocantos/doit/internal/audit"
+	"github.com/marcelocantos/doit/internal/manifest"
+)
+
+const testToken = "secret"
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	cfg := "audit:\n  path: " + filepath.Join(dir, "audit.jsonl") + "\n" +
+		"policy:\n  level1_enabled: true\n  level2_enabled: false\n  level3_enabled: false\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	eng, err := engine.New(engine.Options{ConfigPath: cfgPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(Handler(eng, testToken, "test"))
+	t.Cleanup(func() {
+		srv.Close()
+		eng.Close()
+	})
+	return srv
+}
+
+// call sends a request to the API and returns the response.
+func call(t *testing.T, srv *httptest.Server, method, path, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decode decodes the JSON body of resp into v, checking its status.
+func decode(t *testing.T, resp *http.Response, status int, v any) {
+	t.Helper()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s: status %d, want %d: %s", resp.Request.URL.Path, resp.StatusCode, status, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	srv := newServer(t)
+	for _, auth := range []string{"", "Bearer wrong", testToken} {
+		req, _ := http.NewRequest("GET", srv.URL+"/v1/capabilities", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: status %d", auth, resp.StatusCode)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	srv := newServer(t)
+	dir := t.TempDir()
+	body, _ := json.Marshal(doitclient.Request{Command: "echo 'hello  world'", Cwd: dir})
+	var res doitclient.Result
+	decode(t, call(t, srv, "POST", "/v1/execute", string(body), nil), http.StatusOK, &res)
+	if res.ExitCode != 0 || res.Stdout != "hello  world\n" {
+		t.Errorf("echo: %+v", res)
+	}
+
+	body, _ = json.Marshal(doitclient.Request{Command: "rm -rf /", Cwd: dir})
+	res = doitclient.Result{}
+	decode(t, call(t, srv, "POST", "/v1/execute", string(body), nil), http.StatusOK, &res)
+	if res.ExitCode != doitclient.ExitPolicyDeny || res.Policy == nil || res.Policy.Decision != "deny" || res.Error == nil {
+		t.Errorf("rm -rf /: %+v", res)
+	}
+
+	for _, bad := range []string{`{"command": ""}`, `{"command": "ls", "nope": 1}`, `not json`} {
+		var e map[string]string
+		decode(t, call(t, srv, "POST", "/v1/execute", bad, nil), http.StatusBadRequest, &e)
+		if e["error"] == "" {
+			t.Errorf("%s: no error message", bad)
+		}
+	}
+}
+
+func TestExecute_Stream(t *testing.T) {
+	srv := newServer(t)
+	body, _ := json.Marshal(doitclient.Request{Command: "echo out; echo err >&2", Cwd: t.TempDir()})
+	resp := call(t, srv, "POST", "/v1/execute", string(body), map[string]string{"Accept": "text/event-stream"})
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	output := map[string]string{}
+	var res *doitclient.Result
+	var event string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := []byte(strings.TrimPrefix(line, "data: "))
+			if event == "result" {
+				res = &doitclient.Result{}
+				if err := json.Unmarshal(data, res); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			var chunk string
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				t.Fatal(err)
+			}
+			output[event] += chunk
+		}
+	}
+	if output["stdout"] != "out\n" || output["stderr"] != "err\n" {
+		t.Errorf("streamed output = %q", output)
+	}
+	if res == nil || res.ExitCode != 0 || res.Stdout != "" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	srv := newServer(t)
+	var ev evaluation
+	decode(t, call(t, srv, "POST", "/v1/evaluate", `{"command": "rm -rf /", "cwd": "/tmp"}`, nil), http.StatusOK, &ev)
+	if ev.Decision != "deny" || ev.Level != 1 || ev.RuleID == "" || ev.Tier != "dangerous" {
+		t.Errorf("evaluation = %+v", ev)
+	}
+}
+
+func TestCapabilitiesAndAudit(t *testing.T) {
+	srv := newServer(t)
+	var caps []manifest.Capability
+	decode(t, call(t, srv, "GET", "/v1/capabilities", "", nil), http.StatusOK, &caps)
+	if len(caps) == 0 || caps[0].Name == "" {
+		t.Errorf("capabilities = %+v", caps)
+	}
+
+	var entries []audit.Entry
+	decode(t, call(t, srv, "GET", "/v1/audit", "", nil), http.StatusOK, &entries)
+	if len(entries) != 0 {
+		t.Errorf("fresh audit log has %d entries", len(entries))
+	}
+	for _, cmd := range []string{"echo one", "echo two", "echo three"} {
+		body, _ := json.Marshal(doitclient.Request{Command: cmd})
+		call(t, srv, "POST", "/v1/execute", string(body), nil)
+	}
+	decode(t, call(t, srv, "GET", "/v1/audit?n=2", "", nil), http.StatusOK, &entries)
+	if len(entries) != 2 || entries[0].Pipeline != "echo two" || entries[1].Pipeline != "echo three" {
+		t.Errorf("audit = %+v", entries)
+	}
+	var e map[string]string
+	decode(t, call(t, srv, "GET", "/v1/audit?n=x", "", nil), http.StatusBadRequest, &e)
+}
+
+// stuckWriter is a response writer whose client has stopped reading.
+type stuckWriter struct {
+	http.ResponseWriter
+	writes int
+}
+
+func (w *stuckWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, os.ErrDeadlineExceeded
+}
+
+func TestEvents_Stalled(t *testing.T) {
+	w := &stuckWriter{ResponseWriter: httptest.NewRecorder()}
+	stalled := 0
+	ev := &events{w: w, rc: http.NewResponseController(w), stalled: func() { stalled++ }}
+	out := ev.stream("stdout")
+	for range 3 {
+		if n, err := out.Write([]byte("x")); n != 1 || err != nil {
+			t.Errorf("Write = %d, %v; the command's writes must not fail", n, err)
+		}
+	}
+	ev.send("result", doitclient.Result{})
+	if stalled != 1 || w.writes != 1 {
+		t.Errorf("stalled called %d times after %d writes, want once after one", stalled, w.writes)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/rules"
+)
+
+// WriteAgentGuide renders m as a Markdown usage guide for agents. Unlike
+// agents-guide.md, which documents the defaults, the guide reflects the
+// live configuration: which tiers and policy levels are on, which rules
+// (including site-specific config and Starlark rules) are in force, and
+// each capability's examples and flags.
+func (m *Manifest) WriteAgentGuide(w io.Writer) {
+	fmt.Fprintf(w, "# doit — Agent Usage Guide\n\n")
+	fmt.Fprintf(w, "Generated by doit %s from the active configuration; it describes exactly what this broker enforces.\n", m.Version)
+
+	fmt.Fprintf(w, "\n## Executing commands\n\n")
+	fmt.Fprintf(w, "Never use Bash directly: run every command through `doit_execute`. ")
+	fmt.Fprintf(w, "Commands are passed verbatim to `%s`, so shell operators work: %s.\n", m.Execution.Shell, codeList(m.Execution.Operators))
+	fmt.Fprintf(w, "Use `doit_dry_run` to check policy without executing.\n")
+
+	fmt.Fprintf(w, "\n## Safety tiers\n\n| Tier | Status |\n|---|---|\n")
+	for _, t := range m.Tiers {
+		status := "enabled"
+		if !t.Enabled {
+			status = "**disabled** — commands in this tier are rejected; do not try to work around it"
+		}
+		fmt.Fprintf(w, "| %s | %s |\n", t.Name, status)
+	}
+
+	fmt.Fprintf(w, "\n## Policy levels\n\n")
+	fmt.Fprintf(w, "- L1 deterministic rules: %s\n", onOff(m.Policy.Level1))
+	fmt.Fprintf(w, "- L2 learned policy: %s\n", onOff(m.Policy.Level2))
+	fmt.Fprintf(w, "- L3 LLM review: %s\n", onOff(m.Policy.Level3))
+
+	fmt.Fprintf(w, "\n## Rules in force\n")
+	writeRules(w, "Hardcoded (never bypassable — do not retry)", m.Rules.Hardcoded)
+	writeRules(w, "Config (the user is prompted to allow or deny)", m.Rules.Config)
+	writeRules(w, "Starlark (site-specific)", m.Rules.Starlark)
+	if len(m.Warnings) > 0 {
+		fmt.Fprintf(w, "\nWarnings:\n\n")
+		for _, warn := range m.Warnings {
+			fmt.Fprintf(w, "- %s\n", warn)
+		}
+	}
+
+	m.writeCapabilityReference(w)
+}
+
+func writeRules(w io.Writer, title string, infos []rules.Info) {
+	if len(infos) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n### %s\n\n", title)
+	for _, r := range infos {
+		subject := r.Capability
+		if r.Subcommand != "" {
+			subject += " " + r.Subcommand
+		}
+		switch {
+		case subject != "" && len(r.RejectFlags) > 0:
+			fmt.Fprintf(w, "- `%s`: rejects %s\n", subject, codeList(r.RejectFlags))
+		case subject != "":
+			fmt.Fprintf(w, "- `%s`: %s\n", subject, r.Description)
+		default:
+			fmt.Fprintf(w, "- `%s`: %s\n", r.ID, r.Description)
+		}
+	}
+}
+
+// writeCapabilityReference renders every capability with its examples,
+// flags, and tier rationale.
+func (m *Manifest) writeCapabilityReference(w io.Writer) {
+	fmt.Fprintf(w, "\n## Capability reference\n")
+	for _, c := range m.Capabilities {
+		fmt.Fprintf(w, "\n### %s (%s)\n\n%s.", c.Name, c.Tier, c.Description)
+		if c.TierRationale != "" {
+			fmt.Fprintf(w, " Tier rationale: %s.", c.TierRationale)
+		}
+		if !c.Enabled {
+			fmt.Fprintf(w, " **Currently unavailable: tier %q is disabled.**", c.Tier)
+		} else if c.Unavailable != "" {
+			fmt.Fprintf(w, " **Currently unavailable: %s.**", c.Unavailable)
+		}
+		fmt.Fprintln(w)
+		if len(c.Examples) > 0 {
+			fmt.Fprintf(w, "\n```\n%s\n```\n", strings.Join(c.Examples, "\n"))
+		}
+		if len(c.Flags) > 0 || len(c.RejectedFlags) > 0 {
+			fmt.Fprintf(w, "\n| Flag | Description |\n|---|---|\n")
+			for _, f := range c.Flags {
+				desc := f.Description
+				if f.Denied {
+					desc = "**Denied.** " + desc
+				}
+				fmt.Fprintf(w, "| `%s` | %s |\n", f.Name, strings.ReplaceAll(desc, "|", "\\|"))
+			}
+			for _, f := range c.RejectedFlags {
+				fmt.Fprintf(w, "| `%s` | **Rejected by config rule** (user may override) |\n", f)
+			}
+		}
+	}
+}
+
+func codeList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = "`" + s + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func onOff(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
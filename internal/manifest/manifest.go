@@ -0,0 +1,133 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package manifest builds a machine-readable catalogue of what doit
+// enforces: capabilities, tiers, active rules, and how commands are run.
+// It backs `doit --list --json` / `--manifest` and is suitable for
+// injecting into an agent's system prompt or generating tool schemas.
+package manifest
+
+import (
+	"errors"
+
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/rules"
+	doitstar "github.com/marcelocantos/doit/internal/starlark"
+)
+
+// Manifest is the top-level catalogue.
+type Manifest struct {
+	Version      string       `json:"version"`
+	Execution    Execution    `json:"execution"`
+	Policy       Policy       `json:"policy"`
+	Tiers        []Tier       `json:"tiers"`
+	Capabilities []Capability `json:"capabilities"`
+	Rules        Rules        `json:"rules"`
+	Warnings     []string     `json:"warnings,omitempty"`
+}
+
+// Execution describes how commands are run.
+type Execution struct {
+	Shell     string   `json:"shell"`
+	Operators []string `json:"operators"`
+}
+
+// Policy reports which policy levels are enabled.
+type Policy struct {
+	Level1 bool `json:"level1"`
+	Level2 bool `json:"level2"`
+	Level3 bool `json:"level3"`
+}
+
+// Tier is one safety tier and whether it is enabled.
+type Tier struct {
+	Name    string `json:"name"`
+	Level   int    `json:"level"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Capability describes one registered capability.
+type Capability struct {
+	Name          string     `json:"name"`
+	Tier          string     `json:"tier"`
+	Enabled       bool       `json:"enabled"`
+	Description   string     `json:"description"`
+	TierRationale string     `json:"tier_rationale,omitempty"`
+	Examples      []string   `json:"examples,omitempty"`
+	Flags         []cap.Flag `json:"flags,omitempty"`
+	RejectedFlags []string   `json:"rejected_flags,omitempty"` // from config rules, whole capability
+	Unavailable   string     `json:"unavailable,omitempty"`    // why a program it needs cannot run, e.g. "ripgrep not installed"
+}
+
+// Rules groups active rules by source, in evaluation order.
+type Rules struct {
+	Hardcoded []rules.Info `json:"hardcoded"`
+	Config    []rules.Info `json:"config"`
+	Starlark  []rules.Info `json:"starlark,omitempty"`
+}
+
+// shellOperators are the shell control and redirection operators that work
+// in commands, since commands are passed verbatim to sh -c.
+var shellOperators = []string{"|", "&&", "||", ";", ">", ">>", "<", "2>&1", "$(...)"}
+
+// Build assembles the manifest for cfg. Starlark rules that fail to load
+// are reported in Warnings rather than failing the build.
+func Build(version string, cfg *config.Config, reg *cap.Registry) *Manifest {
+	m := &Manifest{
+		Version:   version,
+		Execution: Execution{Shell: "sh -c", Operators: shellOperators},
+		Policy: Policy{
+			Level1: cfg.Policy.Level1Enabled,
+			Level2: cfg.Policy.Level2Enabled,
+			Level3: cfg.Policy.Level3Enabled,
+		},
+	}
+
+	for _, t := range []cap.Tier{cap.TierRead, cap.TierBuild, cap.TierWrite, cap.TierDangerous} {
+		m.Tiers = append(m.Tiers, Tier{Name: t.String(), Level: int(t), Enabled: reg.CheckTier(t) == nil})
+	}
+
+	cfgRules := cfg.Rules
+	if cfgRules == nil {
+		cfgRules = config.DefaultRules()
+	}
+
+	for _, c := range reg.All() {
+		h := cap.HelpFor(c)
+		var unavailable string
+		var uerr *cap.UnavailableError
+		if errors.As(reg.Available(c.Name()), &uerr) {
+			unavailable = uerr.Reason
+		}
+		m.Capabilities = append(m.Capabilities, Capability{
+			Name:          c.Name(),
+			Tier:          c.Tier().String(),
+			Enabled:       reg.CheckTier(c.Tier()) == nil,
+			Description:   c.Description(),
+			TierRationale: h.TierRationale,
+			Examples:      h.Examples,
+			Flags:         h.Flags,
+			RejectedFlags: cfgRules[c.Name()].RejectFlags,
+			Unavailable:   unavailable,
+		})
+	}
+
+	m.Rules.Hardcoded = rules.HardcodedInfo()
+	m.Rules.Config = append(rules.DescribeCapRules(cfgRules), rules.DefaultProgrammaticInfo()...)
+
+	if dir := cfg.Policy.StarlarkRulesDir; dir != "" && cfg.Policy.Level1Enabled {
+		starRules, err := doitstar.LoadDir(dir)
+		if err != nil {
+			m.Warnings = append(m.Warnings, "starlark rules: "+err.Error())
+		}
+		for _, r := range starRules {
+			m.Rules.Starlark = append(m.Rules.Starlark, rules.Info{
+				ID:          r.ID,
+				Description: r.Description,
+				Bypassable:  r.Bypassable,
+			})
+		}
+	}
+	return m
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcelocantos/doit/internal/cap"
+	"github.com/marcelocantos/doit/internal/cap/builtin"
+	"github.com/marcelocantos/doit/internal/config"
+	"github.com/marcelocantos/doit/internal/rules"
+)
+
+func newRegistry(cfg *config.Config) *cap.Registry {
+	reg := cap.NewRegistry()
+	builtin.RegisterAll(reg)
+	cfg.ApplyTiers(reg)
+	return reg
+}
+
+func TestBuildDefaults(t *testing.T) {
+	cfg := config.DefaultConfig()
+	m := Build("v1.2.3", cfg, newRegistry(cfg))
+
+	if m.Version != "v1.2.3" || m.Execution.Shell != "sh -c" {
+		t.Errorf("unexpected header: %+v", m)
+	}
+	if len(m.Tiers) != 4 || m.Tiers[3].Name != "dangerous" || m.Tiers[3].Enabled {
+		t.Errorf("unexpected tiers: %+v", m.Tiers)
+	}
+
+	byName := map[string]Capability{}
+	for _, c := range m.Capabilities {
+		byName[c.Name] = c
+	}
+	if len(byName) != 33 {
+		t.Errorf("expected 33 capabilities, got %d", len(byName))
+	}
+	if rm := byName["rm"]; rm.Enabled {
+		t.Error("rm should be disabled by default")
+	}
+	if mk := byName["make"]; len(mk.RejectedFlags) != 1 || mk.RejectedFlags[0] != "-j" {
+		t.Errorf("make rejected flags = %v, want [-j]", mk.RejectedFlags)
+	}
+	if len(byName["find"].Examples) == 0 {
+		t.Error("expected find examples")
+	}
+
+	if len(m.Rules.Hardcoded) == 0 {
+		t.Error("expected hardcoded rules")
+	}
+	ids := map[string]bool{}
+	for _, r := range m.Rules.Config {
+		ids[r.ID] = true
+	}
+	for _, id := range []string{"config:make", "config:git.push", "config:git.reset", "git-checkout-all"} {
+		if !ids[id] {
+			t.Errorf("missing config rule %s in %+v", id, m.Rules.Config)
+		}
+	}
+
+	// Must serialise cleanly.
+	if _, err := json.Marshal(m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildConfigOverrides(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tiers.Dangerous = true
+	cfg.Rules = map[string]rules.CapRuleConfig{"grep": {RejectFlags: []string{"-P"}}}
+	m := Build("dev", cfg, newRegistry(cfg))
+
+	for _, c := range m.Capabilities {
+		if !c.Enabled {
+			t.Errorf("%s should be enabled with all tiers on", c.Name)
+		}
+		if c.Name == "make" && len(c.RejectedFlags) != 0 {
+			t.Errorf("make should have no rejected flags when rules are overridden, got %v", c.RejectedFlags)
+		}
+		if c.Name == "grep" && (len(c.RejectedFlags) != 1 || c.RejectedFlags[0] != "-P") {
+			t.Errorf("grep rejected flags = %v, want [-P]", c.RejectedFlags)
+		}
+	}
+}
+
+func TestBuildStarlark(t *testing.T) {
+	dir := t.TempDir()
+	src := `rule_id = "no-curl-pipe"
+description = "block curl piped to a shell"
+bypassable = True
+
+def check(command, args):
+    return {"decision": "allow", "reason": "ok"}
+
+tests = [{"command": "ls", "args": [], "expect": "allow"}]
+`
+	if err := os.WriteFile(filepath.Join(dir, "r.star"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Policy.StarlarkRulesDir = dir
+	m := Build("dev", cfg, newRegistry(cfg))
+	if len(m.Warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", m.Warnings)
+	}
+	if len(m.Rules.Starlark) != 1 || m.Rules.Starlark[0].ID != "no-curl-pipe" || !m.Rules.Starlark[0].Bypassable {
+		t.Errorf("unexpected starlark rules: %+v", m.Rules.Starlark)
+	}
+}
+
+func TestWriteAgentGuide(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Policy.Level3Enabled = false
+	cfg.Rules = map[string]rules.CapRuleConfig{"grep": {RejectFlags: []string{"-P"}}}
+	var b strings.Builder
+	Build("v9", cfg, newRegistry(cfg)).WriteAgentGuide(&b)
+	guide := b.String()
+
+	for _, want := range []string{
+		"doit v9",
+		"| dangerous | **disabled**",
+		"L3 LLM review: disabled",
+		"`grep`: rejects `-P`",
+		"`git checkout`:",
+		"### find (read)",
+		"**Denied.** delete matches",
+		"| `-P` | **Rejected by config rule**",
+		"**Currently unavailable: tier \"dangerous\" is disabled.**",
+	} {
+		if !strings.Contains(guide, want) {
+			t.Errorf("guide missing %q", want)
+		}
+	}
+	// Overridden rules must not leak the defaults.
+	if strings.Contains(guide, "`make`: rejects") {
+		t.Error("guide lists default make rule despite config override")
+	}
+}
+
+func TestBuildUnavailable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	cfg := config.DefaultConfig()
+	m := Build("v1", cfg, newRegistry(cfg))
+	byName := map[string]Capability{}
+	for _, c := range m.Capabilities {
+		byName[c.Name] = c
+	}
+	if got := byName["cat"].Unavailable; got != "cat not installed" {
+		t.Errorf("cat unavailable = %q, want %q", got, "cat not installed")
+	}
+	if got := byName["search"].Unavailable; got != "ripgrep not installed" {
+		t.Errorf("search unavailable = %q, want %q", got, "ripgrep not installed")
+	}
+	if got := byName["read"].Unavailable; got != "" {
+		t.Errorf("read runs in doit, but unavailable = %q", got)
+	}
+
+	var b strings.Builder
+	m.WriteAgentGuide(&b)
+	if !strings.Contains(b.String(), "**Currently unavailable: ripgrep not installed.**") {
+		t.Errorf("guide does not flag search as unavailable:\n%s", b.String())
+	}
+}
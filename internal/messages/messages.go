@@ -0,0 +1,147 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package messages holds the user-facing policy and rule messages as
+// text/template strings that config can override, so sites can adjust
+// tone, link internal runbooks, or translate them.
+package messages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Key identifies a message template.
+type Key string
+
+const (
+	// PolicyDeny is written to stderr when the policy engine denies a command.
+	PolicyDeny Key = "policy_deny"
+	// PolicyEscalation is written to stderr when L3 escalates and issues an
+	// approval token; it carries the retry instructions.
+	PolicyEscalation Key = "policy_escalation"
+	// HardDeny is the MCP error for a non-bypassable denial.
+	HardDeny Key = "hard_deny"
+	// UserDeny is the MCP error when the user denies a command once.
+	UserDeny Key = "user_deny"
+	// UserDenyAlways is the MCP error when the user denies permanently.
+	UserDenyAlways Key = "user_deny_always"
+	// ElicitDecision is the prompt shown to the user for an escalation or
+	// bypassable denial.
+	ElicitDecision Key = "elicit_decision"
+	// ElicitPromotion is the prompt offering to turn a decision into a rule.
+	ElicitPromotion Key = "elicit_promotion"
+)
+
+// Data is the value templates are executed against. Not every field is
+// set for every key.
+type Data struct {
+	Command  string // the command string as submitted
+	Decision string // "allow", "deny", or "escalate"
+	Level    int    // policy level that decided (1-3)
+	Reason   string // policy reason
+	RuleID   string // matching rule, if any
+	Token    string // approval token (PolicyEscalation only)
+}
+
+var defaults = map[Key]string{
+	PolicyDeny:       "doit: policy: {{.Reason}}",
+	PolicyEscalation: "doit: policy escalation (Level {{.Level}}): {{.Reason}}\napproval-token: {{.Token}}\n",
+	HardDeny:         "Denied by policy (L{{.Level}}): {{.RuleID}} — {{.Reason}}",
+	UserDeny:         "Denied by user: {{.Command}}",
+	UserDenyAlways:   "Denied by user (permanent): {{.Command}}",
+	ElicitDecision:   "Policy {{.Decision}} for command: {{.Command}}\n\nLevel: L{{.Level}}\nReason: {{.Reason}}{{if .RuleID}}\nRule: {{.RuleID}}{{end}}",
+	ElicitPromotion:  "Would you like to create a permanent rule for `{{.Command}}`?",
+}
+
+// Keys returns every message key, sorted.
+func Keys() []Key {
+	keys := make([]Key, 0, len(defaults))
+	for k := range defaults {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Default returns the built-in template source for k.
+func Default(k Key) string {
+	return defaults[k]
+}
+
+// Set is a compiled collection of message templates. The nil *Set renders
+// the built-in defaults.
+type Set struct {
+	templates map[Key]*template.Template
+}
+
+var builtin = mustCompile(nil)
+
+func mustCompile(overrides map[string]string) *Set {
+	s, err := New(overrides)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// New compiles the built-in templates with overrides (from the config
+// `messages` section) applied on top. Unknown keys and templates that fail
+// to parse are errors.
+func New(overrides map[string]string) (*Set, error) {
+	s := &Set{templates: make(map[Key]*template.Template, len(defaults))}
+	for k, src := range defaults {
+		t, err := template.New(string(k)).Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("message %s: %w", k, err)
+		}
+		s.templates[k] = t
+	}
+	var errs []string
+	for name, src := range overrides {
+		k := Key(name)
+		if _, ok := defaults[k]; !ok {
+			errs = append(errs, fmt.Sprintf("unknown message %q", name))
+			continue
+		}
+		t, err := template.New(name).Parse(src)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("message %s: %v", name, err))
+			continue
+		}
+		// Execute against sample data so field typos fail at load time
+		// rather than when a denial is being reported.
+		if err := t.Execute(&strings.Builder{}, Data{}); err != nil {
+			errs = append(errs, fmt.Sprintf("message %s: %v", name, err))
+			continue
+		}
+		s.templates[k] = t
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return s, nil
+}
+
+// Render executes the template for k. If an override fails at execution
+// time, the built-in default is used instead so a message is always
+// produced.
+func (s *Set) Render(k Key, d Data) string {
+	if s == nil {
+		s = builtin
+	}
+	var b strings.Builder
+	if t, ok := s.templates[k]; ok {
+		if err := t.Execute(&b, d); err == nil {
+			return b.String()
+		}
+		b.Reset()
+	}
+	if t, ok := builtin.templates[k]; ok && t.Execute(&b, d) == nil {
+		return b.String()
+	}
+	return string(k)
+}
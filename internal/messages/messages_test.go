@@ -0,0 +1,66 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package messages
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	var s *Set // nil renders defaults
+	got := s.Render(PolicyEscalation, Data{Level: 3, Reason: "needs review", Token: "tok"})
+	want := "doit: policy escalation (Level 3): needs review\napproval-token: tok\n"
+	if got != want {
+		t.Errorf("Render(PolicyEscalation) = %q, want %q", got, want)
+	}
+
+	got = s.Render(ElicitDecision, Data{Decision: "deny", Command: "make -j", Level: 1, Reason: "r"})
+	if strings.Contains(got, "Rule:") {
+		t.Errorf("ElicitDecision without RuleID should omit Rule line: %q", got)
+	}
+	got = s.Render(ElicitDecision, Data{Decision: "deny", Command: "make -j", Level: 1, Reason: "r", RuleID: "config:make"})
+	if !strings.HasSuffix(got, "\nRule: config:make") {
+		t.Errorf("ElicitDecision with RuleID = %q", got)
+	}
+
+	for _, k := range Keys() {
+		if Default(k) == "" {
+			t.Errorf("key %s has no default", k)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	s, err := New(map[string]string{
+		"policy_deny": "doit: blocked — {{.Reason}}. See https://wiki.example/doit",
+		"user_deny":   "Abgelehnt: {{.Command}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Render(PolicyDeny, Data{Reason: "no"}); got != "doit: blocked — no. See https://wiki.example/doit" {
+		t.Errorf("PolicyDeny = %q", got)
+	}
+	if got := s.Render(UserDeny, Data{Command: "rm x"}); got != "Abgelehnt: rm x" {
+		t.Errorf("UserDeny = %q", got)
+	}
+	// Keys not overridden keep their defaults.
+	if got := s.Render(UserDenyAlways, Data{Command: "rm x"}); got != "Denied by user (permanent): rm x" {
+		t.Errorf("UserDenyAlways = %q", got)
+	}
+}
+
+func TestOverrideErrors(t *testing.T) {
+	cases := map[string]map[string]string{
+		"unknown key":   {"policy_denny": "x"},
+		"parse error":   {"policy_deny": "{{.Reason"},
+		"unknown field": {"policy_deny": "{{.Reasn}}"},
+	}
+	for name, overrides := range cases {
+		if _, err := New(overrides); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package paths resolves doit's on-disk locations according to the XDG
+// Base Directory Specification and migrates files from the pre-XDG
+// locations doit used to hardcode.
+//
+//	config.yaml          $XDG_CONFIG_HOME/doit  (default ~/.config/doit)
+//	learned-policy.yaml  $XDG_DATA_HOME/doit    (default ~/.local/share/doit)
+//	audit.jsonl          $XDG_STATE_HOME/doit   (default ~/.local/state/doit)
+//	events/<pid>.sock    $XDG_STATE_HOME/doit
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// App is the subdirectory name used under each XDG base directory.
+const App = "doit"
+
+// ConfigHome returns $XDG_CONFIG_HOME, or ~/.config if it is unset or
+// not absolute (the spec says relative values must be ignored).
+func ConfigHome() string { return xdg("XDG_CONFIG_HOME", ".config") }
+
+// DataHome returns $XDG_DATA_HOME, or ~/.local/share.
+func DataHome() string { return xdg("XDG_DATA_HOME", ".local", "share") }
+
+// StateHome returns $XDG_STATE_HOME, or ~/.local/state.
+func StateHome() string { return xdg("XDG_STATE_HOME", ".local", "state") }
+
+// ConfigDir returns doit's config directory.
+func ConfigDir() string { return filepath.Join(ConfigHome(), App) }
+
+// DataDir returns doit's data directory.
+func DataDir() string { return filepath.Join(DataHome(), App) }
+
+// StateDir returns doit's state directory.
+func StateDir() string { return filepath.Join(StateHome(), App) }
+
+// ConfigFile returns the global config file path.
+func ConfigFile() string { return filepath.Join(ConfigDir(), "config.yaml") }
+
+// LearnedPolicy returns the default L2 learned policy store path.
+func LearnedPolicy() string { return filepath.Join(DataDir(), "learned-policy.yaml") }
+
+// Feedback returns the default path of the human feedback on Level 3
+// decisions.
+func Feedback() string { return filepath.Join(DataDir(), "feedback.jsonl") }
+
+// AuditLog returns the default audit log path.
+func AuditLog() string { return filepath.Join(StateDir(), "audit.jsonl") }
+
+// Tokens returns the default path of the persisted approval token store.
+func Tokens() string { return filepath.Join(StateDir(), "tokens.json") }
+
+// EventsDir returns the default directory for event subscription sockets.
+func EventsDir() string { return filepath.Join(StateDir(), "events") }
+
+// Backups returns the default directory where write keeps the previous
+// content of files it replaces.
+func Backups() string { return filepath.Join(StateDir(), "backups") }
+
+func xdg(env string, fallback ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
+
+// Move is a single file relocation performed by Migrate.
+type Move struct {
+	From, To string
+}
+
+// legacy returns the pre-XDG location of each file, keyed by its current
+// default location.
+func legacy() []Move {
+	home, _ := os.UserHomeDir()
+	oldConfig := filepath.Join(home, ".config", "doit")
+	oldData := filepath.Join(home, ".local", "share", "doit")
+	return []Move{
+		{filepath.Join(oldConfig, "config.yaml"), ConfigFile()},
+		{filepath.Join(oldConfig, "learned-policy.yaml"), LearnedPolicy()},
+		{filepath.Join(oldData, "audit.jsonl"), AuditLog()},
+		{filepath.Join(oldData, "audit.jsonl.verified"), AuditLog() + ".verified"},
+	}
+}
+
+// Migrate relocates files from their pre-XDG locations to the current
+// defaults. A file is moved only if it exists at the old location and
+// nothing exists at the new one, so Migrate is idempotent and never
+// overwrites. Targets for which keep returns false are skipped; callers
+// use this to leave alone files whose location the user has configured
+// explicitly. It returns the moves actually performed.
+func Migrate(keep func(to string) bool) ([]Move, error) {
+	var done []Move
+	for _, m := range legacy() {
+		if m.From == m.To || (keep != nil && !keep(m.To)) {
+			continue
+		}
+		if _, err := os.Stat(m.From); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(m.To); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(m.To), 0700); err != nil {
+			return done, fmt.Errorf("migrate %s: %w", m.From, err)
+		}
+		if err := os.Rename(m.From, m.To); err != nil {
+			return done, fmt.Errorf("migrate %s: %w", m.From, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestXDGOverrides(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+
+	if got, want := ConfigFile(), "/xdg/config/doit/config.yaml"; got != want {
+		t.Errorf("ConfigFile() = %q, want %q", got, want)
+	}
+	if got, want := LearnedPolicy(), "/xdg/data/doit/learned-policy.yaml"; got != want {
+		t.Errorf("LearnedPolicy() = %q, want %q", got, want)
+	}
+	if got, want := AuditLog(), "/xdg/state/doit/audit.jsonl"; got != want {
+		t.Errorf("AuditLog() = %q, want %q", got, want)
+	}
+}
+
+func TestXDGFallbacks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "relative/ignored")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	if got, want := ConfigDir(), filepath.Join(home, ".config", "doit"); got != want {
+		t.Errorf("ConfigDir() = %q, want %q", got, want)
+	}
+	if got, want := DataDir(), filepath.Join(home, ".local", "share", "doit"); got != want {
+		t.Errorf("DataDir() = %q, want %q", got, want)
+	}
+	if got, want := StateDir(), filepath.Join(home, ".local", "state", "doit"); got != want {
+		t.Errorf("StateDir() = %q, want %q", got, want)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg-config"))
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	oldConfig := filepath.Join(home, ".config", "doit")
+	oldData := filepath.Join(home, ".local", "share", "doit")
+	writeFile(t, filepath.Join(oldConfig, "config.yaml"), "tiers: {}\n")
+	writeFile(t, filepath.Join(oldConfig, "learned-policy.yaml"), "entries: []\n")
+	writeFile(t, filepath.Join(oldData, "audit.jsonl"), "old\n")
+	// An existing file at the new location must not be overwritten.
+	writeFile(t, AuditLog(), "new\n")
+
+	moves, err := Migrate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 2 {
+		t.Fatalf("expected 2 moves, got %v", moves)
+	}
+	for _, p := range []string{ConfigFile(), LearnedPolicy()} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s to exist: %v", p, err)
+		}
+	}
+	if data, _ := os.ReadFile(AuditLog()); string(data) != "new\n" {
+		t.Errorf("audit log overwritten: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(oldData, "audit.jsonl")); err != nil {
+		t.Errorf("expected unmigrated legacy audit log to remain: %v", err)
+	}
+
+	// Second run is a no-op.
+	moves, err = Migrate(nil)
+	if err != nil || len(moves) != 0 {
+		t.Errorf("second Migrate = %v, %v; want no moves", moves, err)
+	}
+}
+
+func TestMigrateKeep(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+
+	legacyAudit := filepath.Join(home, ".local", "share", "doit", "audit.jsonl")
+	writeFile(t, legacyAudit, "x\n")
+
+	moves, err := Migrate(func(to string) bool { return to != AuditLog() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 0 {
+		t.Errorf("expected no moves, got %v", moves)
+	}
+	if _, err := os.Stat(legacyAudit); err != nil {
+		t.Errorf("expected legacy audit log to stay put: %v", err)
+	}
+}
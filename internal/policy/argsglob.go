@@ -0,0 +1,132 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// An args_glob pattern is a glob, in which ** spans any number of path
+// segments (build/** is anything under build, at any depth); "re:" and
+// an anchored regular expression; or either after "!", which the
+// argument must not match.
+const (
+	negatePrefix = "!"
+	regexPrefix  = "re:"
+)
+
+// matchArgs reports whether every arg matches patterns: at least one of
+// the positive ones, if there are any, and none of the negated ones.
+// Arguments are matched as cleaned paths, so ./build/x is build/x.
+func matchArgs(args, patterns []string) bool {
+	var positive, negative []string
+	for _, p := range patterns {
+		if n, ok := strings.CutPrefix(p, negatePrefix); ok {
+			negative = append(negative, n)
+		} else {
+			positive = append(positive, p)
+		}
+	}
+	for _, arg := range args {
+		arg = path.Clean(arg)
+		if len(positive) > 0 && !matchAny(arg, positive) {
+			return false
+		}
+		if matchAny(arg, negative) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny reports whether s matches any of patterns, none negated. A
+// malformed pattern matches nothing.
+func matchAny(s string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := matchPattern(p, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern reports whether s matches p, a glob or "re:" pattern.
+func matchPattern(p, s string) (bool, error) {
+	if expr, ok := strings.CutPrefix(p, regexPrefix); ok {
+		re, err := compileArgRegexp(expr)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(s), nil
+	}
+	return matchGlob(strings.Split(p, "/"), strings.Split(s, "/"))
+}
+
+// matchGlob matches path segments against pattern segments, a "**"
+// segment spanning any number of them, none included.
+func matchGlob(pattern, segs []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(segs); i >= 0; i-- {
+				if ok, err := matchGlob(pattern[1:], segs[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(segs) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], segs[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0, nil
+}
+
+// argRegexps caches compiled "re:" patterns, which Level 2 matches on
+// every evaluation.
+var argRegexps sync.Map // string → *regexp.Regexp
+
+// compileArgRegexp compiles expr anchored at both ends, so that it must
+// match the whole argument, as a glob does.
+func compileArgRegexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := argRegexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	argRegexps.Store(expr, re)
+	return re, nil
+}
+
+// checkArgsGlob reports the first malformed pattern among patterns.
+func checkArgsGlob(patterns []string) error {
+	for _, p := range patterns {
+		pat := strings.TrimPrefix(p, negatePrefix)
+		if pat == "" {
+			return fmt.Errorf("args_glob: empty pattern %q", p)
+		}
+		if expr, ok := strings.CutPrefix(pat, regexPrefix); ok {
+			if _, err := compileArgRegexp(expr); err != nil {
+				return fmt.Errorf("args_glob: bad pattern %q: %w", p, err)
+			}
+			continue
+		}
+		for _, seg := range strings.Split(pat, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("args_glob: bad pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
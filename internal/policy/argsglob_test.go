@@ -0,0 +1,43 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import "testing"
+
+func TestMatchArgs(t *testing.T) {
+	for _, tc := range []struct {
+		patterns []string
+		args     []string
+		want     bool
+	}{
+		{[]string{"build/*"}, []string{"build/a.o"}, true},
+		{[]string{"build/*"}, []string{"build/obj/a.o"}, false},
+		{[]string{"build/**"}, []string{"build/obj/a.o", "./build/b.o", "build"}, true},
+		{[]string{"build/**"}, []string{"build/../src/main.go"}, false},
+		{[]string{"**/*.go"}, []string{"main.go", "cmd/doit/main.go"}, true},
+		{[]string{"**/*.go"}, []string{"main.c"}, false},
+		{[]string{"**", "!vendor/**"}, []string{"src/a.go"}, true},
+		{[]string{"**", "!vendor/**"}, []string{"src/a.go", "vendor/x/y.go"}, false},
+		{[]string{"!vendor/**"}, []string{"internal/x.go"}, true}, // negation alone
+		{[]string{`re:v\d+\.\d+\.\d+`}, []string{"v1.2.3"}, true},
+		{[]string{`re:v\d+\.\d+\.\d+`}, []string{"v1.2.3-rc1"}, false}, // anchored
+		{[]string{"re:feature/.*", "!re:.*-wip"}, []string{"feature/login-wip"}, false},
+		{[]string{"re:("}, []string{"("}, false}, // malformed: matches nothing
+	} {
+		if got := matchArgs(tc.args, tc.patterns); got != tc.want {
+			t.Errorf("matchArgs(%q, %q) = %v, want %v", tc.args, tc.patterns, got, tc.want)
+		}
+	}
+}
+
+func TestCheckArgsGlob(t *testing.T) {
+	if err := checkArgsGlob([]string{"build/**", "!vendor/**", `re:v\d+`, "!re:x"}); err != nil {
+		t.Errorf("valid patterns: %v", err)
+	}
+	for _, bad := range []string{"src/[", "re:(", "!", ""} {
+		if err := checkArgsGlob([]string{bad}); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// GatekeeperStatus describes whether Level 3 is consulting its LLM.
+type GatekeeperStatus struct {
+	Enabled  bool      // false after SetEnabled(false)
+	Open     bool      // the circuit breaker has tripped
+	Failures int       // consecutive LLM errors
+	RetryAt  time.Time // when an open breaker next lets a call through
+}
+
+// SetBreaker makes Level 3 stop calling its LLM after failures consecutive
+// errors or timeouts, escalating straight to a human instead, and try
+// again once cooldown has passed. A success closes the breaker. Zero
+// failures turns the breaker off.
+func (l *Level3) SetBreaker(failures int, cooldown time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.threshold, l.cooldown = failures, cooldown
+}
+
+// SetEnabled turns the LLM gatekeeper on or off at run time. While it is
+// off, every request Level 3 sees is escalated to a human.
+func (l *Level3) SetEnabled(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.disabled = !enabled
+	if enabled {
+		l.failures = 0
+	}
+}
+
+// Status reports whether the gatekeeper is enabled and its breaker state.
+func (l *Level3) Status() GatekeeperStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := GatekeeperStatus{Enabled: !l.disabled, Failures: l.failures}
+	if l.tripped() {
+		s.Open, s.RetryAt = true, l.retryAt
+	}
+	return s
+}
+
+func (l *Level3) tripped() bool {
+	return l.threshold > 0 && l.failures >= l.threshold
+}
+
+// unavailable returns why the LLM must not be called now, or "" if it
+// may be. Once an open breaker's cooldown has passed, one call goes
+// through as a probe while the rest keep escalating.
+func (l *Level3) unavailable() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case l.disabled:
+		return "the LLM gatekeeper is disabled (doit --llm enable turns it back on)"
+	case !l.tripped():
+		return ""
+	case time.Now().Before(l.retryAt):
+		return fmt.Sprintf("the LLM gatekeeper is unavailable after %d consecutive failures; it will be retried at %s",
+			l.failures, l.retryAt.Format(time.TimeOnly))
+	}
+	l.retryAt = time.Now().Add(l.cooldown)
+	return ""
+}
+
+// record notes the outcome of an LLM call for the breaker.
+func (l *Level3) record(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		if l.tripped() {
+			log.Printf("doit: L3 breaker closed: the LLM answered")
+		}
+		l.failures = 0
+		return
+	}
+	l.failures++
+	if l.tripped() {
+		l.retryAt = time.Now().Add(l.cooldown)
+		if l.failures == l.threshold {
+			log.Printf("doit: L3 breaker open after %d consecutive failures (last: %v); escalating to a human until %s",
+				l.failures, err, l.retryAt.Format(time.TimeOnly))
+		}
+	}
+}
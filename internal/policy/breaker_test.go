@@ -0,0 +1,78 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	mock := &mockPrompter{err: errors.New("claude: timed out")}
+	l3 := NewLevel3(mock)
+	l3.SetBreaker(2, time.Hour)
+	req := &Request{Command: "make deploy"}
+
+	for i := range 2 {
+		mock.called = false
+		if r := l3.Evaluate(context.Background(), req); r.Decision != Escalate || !mock.called {
+			t.Fatalf("call %d: %+v (called %v), want an escalating LLM call", i, r, mock.called)
+		}
+	}
+	if s := l3.Status(); !s.Open || s.Failures != 2 {
+		t.Errorf("status = %+v, want open after 2 failures", s)
+	}
+
+	// Open: escalate without calling the LLM.
+	mock.called = false
+	r := l3.Evaluate(context.Background(), req)
+	if r.Decision != Escalate || mock.called || !strings.Contains(r.Reason, "unavailable") {
+		t.Errorf("open breaker: %+v (called %v)", r, mock.called)
+	}
+
+	// After the cooldown one probe goes through, and success closes it.
+	l3.mu.Lock()
+	l3.retryAt = time.Now().Add(-time.Second)
+	l3.mu.Unlock()
+	mock.err, mock.response = nil, `{"decision":"allow","reasoning":"ok"}`
+	if r := l3.Evaluate(context.Background(), req); r.Decision != Allow {
+		t.Errorf("probe: %+v, want allow", r)
+	}
+	if s := l3.Status(); s.Open || s.Failures != 0 {
+		t.Errorf("status after success = %+v, want closed", s)
+	}
+}
+
+func TestBreakerProbeFails(t *testing.T) {
+	mock := &mockPrompter{err: errors.New("boom")}
+	l3 := NewLevel3(mock)
+	l3.SetBreaker(1, time.Hour)
+	req := &Request{Command: "make deploy"}
+	l3.Evaluate(context.Background(), req)
+
+	l3.mu.Lock()
+	l3.retryAt = time.Now().Add(-time.Second)
+	l3.mu.Unlock()
+	l3.Evaluate(context.Background(), req) // the probe fails
+	if s := l3.Status(); !s.Open || time.Until(s.RetryAt) < 59*time.Minute {
+		t.Errorf("status = %+v, want reopened for the cooldown", s)
+	}
+}
+
+func TestGatekeeperDisabled(t *testing.T) {
+	mock := &mockPrompter{response: `{"decision":"allow","reasoning":"ok"}`}
+	l3 := NewLevel3(mock)
+	l3.SetEnabled(false)
+	r := l3.Evaluate(context.Background(), &Request{Command: "make deploy"})
+	if r.Decision != Escalate || mock.called || !strings.Contains(r.Reason, "disabled") {
+		t.Errorf("disabled: %+v (called %v)", r, mock.called)
+	}
+	l3.SetEnabled(true)
+	if r := l3.Evaluate(context.Background(), &Request{Command: "make deploy"}); r.Decision != Allow {
+		t.Errorf("re-enabled: %+v, want allow", r)
+	}
+}
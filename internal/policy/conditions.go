@@ -0,0 +1,62 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Conditions qualify an Allow: the command may run, but only as they
+// say. The engine enforces them, refusing to run a command it cannot hold
+// to them.
+type Conditions struct {
+	Timeout   time.Duration `yaml:"timeout,omitempty"`    // kill the command after this long, e.g. 60s
+	MaxOutput int64         `yaml:"max_output,omitempty"` // bytes of stdout and stderr together; kill the command past them
+	NoNetwork bool          `yaml:"no_network,omitempty"` // run it without network access
+	DryRun    string        `yaml:"dry_run,omitempty"`    // flag, e.g. --dry-run: run it so first, and for real only if that succeeds
+}
+
+// IsZero reports whether c imposes nothing.
+func (c *Conditions) IsZero() bool {
+	return c == nil || *c == Conditions{}
+}
+
+// String describes c, e.g. "timeout 1m0s, no network", or "" if c
+// imposes nothing.
+func (c *Conditions) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	var parts []string
+	if c.Timeout > 0 {
+		parts = append(parts, "timeout "+c.Timeout.String())
+	}
+	if c.MaxOutput > 0 {
+		parts = append(parts, fmt.Sprintf("max output %d bytes", c.MaxOutput))
+	}
+	if c.NoNetwork {
+		parts = append(parts, "no network")
+	}
+	if c.DryRun != "" {
+		parts = append(parts, "dry run with "+c.DryRun+" first")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Check reports what is wrong with c, if anything.
+func (c *Conditions) Check() error {
+	switch {
+	case c == nil:
+		return nil
+	case c.Timeout < 0:
+		return fmt.Errorf("timeout %s is negative", c.Timeout)
+	case c.MaxOutput < 0:
+		return fmt.Errorf("max_output %d is negative", c.MaxOutput)
+	case c.DryRun != "" && (!strings.HasPrefix(c.DryRun, "-") || strings.ContainsAny(c.DryRun, " \t\n'\"\\$`;&|<>()*?[")):
+		return fmt.Errorf("dry_run %q is not a single flag", c.DryRun)
+	}
+	return nil
+}
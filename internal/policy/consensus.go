@@ -0,0 +1,85 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ConsensusRuleID is the RuleID of a unanimous consensus verdict.
+const ConsensusRuleID = GatekeeperRuleID + "-consensus"
+
+// consensusTier is the tier whose commands need consensus.
+const consensusTier = "dangerous"
+
+// SetConsensus makes Level 3 judge dangerous-tier commands by asking every
+// one of judges, in parallel, instead of running the fast/deep cascade.
+// A verdict stands only if all judges return it; any disagreement, error,
+// or escalation goes to a human. Fewer than two judges turn consensus
+// off.
+func (l *Level3) SetConsensus(judges ...Prompter) {
+	if len(judges) < 2 {
+		l.judges = nil
+		return
+	}
+	l.judges = judges
+}
+
+// needsConsensus reports whether req is a dangerous-tier command and
+// consensus is configured.
+func (l *Level3) needsConsensus(req *Request) bool {
+	return len(l.judges) > 0 && req.Tier == consensusTier
+}
+
+// consensus fans req out to every judge and combines their verdicts.
+func (l *Level3) consensus(ctx context.Context, req *Request, session *SessionContext) *Result {
+	results := make([]*Result, len(l.judges))
+	var wg sync.WaitGroup
+	for i, judge := range l.judges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = l.callLLM(ctx, req, session, judge, false)
+			results[i].Exchanges[0].Stage = fmt.Sprintf("judge %d", i+1)
+		}()
+	}
+	wg.Wait()
+
+	verdicts := make([]string, len(results))
+	var exchanges []Exchange
+	agreed := true
+	for i, r := range results {
+		verdicts[i] = fmt.Sprintf("judge %d: %s (%s)", i+1, r.Decision, r.Reason)
+		exchanges = append(exchanges, r.Exchanges...)
+		agreed = agreed && r.Decision == results[0].Decision
+	}
+	if !agreed || results[0].Decision == Escalate {
+		return &Result{
+			Decision:  Escalate,
+			Level:     3,
+			Reason:    "gatekeepers did not agree on a dangerous command: " + strings.Join(verdicts, "; "),
+			RuleID:    ConsensusRuleID,
+			Exchanges: exchanges,
+		}
+	}
+	// The verdict is as sure as its least sure judge.
+	var confidence *float64
+	for _, r := range results {
+		if c := r.Confidence; c != nil && (confidence == nil || *c < *confidence) {
+			confidence = c
+		}
+	}
+	return &Result{
+		Decision:   results[0].Decision,
+		Level:      3,
+		Reason:     "unanimous: " + strings.Join(verdicts, "; "),
+		RuleID:     ConsensusRuleID,
+		Bypassable: true,
+		Confidence: confidence,
+		Exchanges:  exchanges,
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestConsensus(t *testing.T) {
+	const (
+		allow    = `{"decision":"allow","reasoning":"fine"}`
+		deny     = `{"decision":"deny","reasoning":"no"}`
+		escalate = `{"decision":"escalate","reasoning":"unsure"}`
+	)
+	tests := []struct {
+		name      string
+		responses []string
+		want      Decision
+	}{
+		{"both allow", []string{allow, allow}, Allow},
+		{"both deny", []string{deny, deny}, Deny},
+		{"disagree", []string{allow, deny}, Escalate},
+		{"one unsure", []string{allow, escalate}, Escalate},
+		{"one garbled", []string{allow, "yes"}, Escalate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fast := &mockPrompter{response: allow}
+			a := &mockPrompter{response: tt.responses[0]}
+			b := &mockPrompter{response: tt.responses[1]}
+			l3 := NewLevel3(fast)
+			l3.SetConsensus(a, b)
+
+			r := l3.Evaluate(context.Background(), &Request{Command: "rm -rf build", Tier: "dangerous"})
+			if r.Decision != tt.want || r.RuleID != ConsensusRuleID {
+				t.Errorf("got %s (%s), want %s (%s)", r.Decision, r.RuleID, tt.want, ConsensusRuleID)
+			}
+			if fast.called || !a.called || !b.called {
+				t.Errorf("called: fast=%v a=%v b=%v, want only the judges", fast.called, a.called, b.called)
+			}
+			if tt.want == Escalate && !strings.Contains(r.Reason, "judge 2") {
+				t.Errorf("reason should give each verdict: %s", r.Reason)
+			}
+		})
+	}
+}
+
+func TestConsensusOnlyForDangerous(t *testing.T) {
+	fast := &mockPrompter{response: `{"decision":"allow","reasoning":"fine"}`}
+	a, b := &mockPrompter{}, &mockPrompter{}
+	l3 := NewLevel3(fast)
+	l3.SetConsensus(a, b)
+
+	r := l3.Evaluate(context.Background(), &Request{Command: "go build ./...", Tier: "build"})
+	if r.Decision != Allow || !fast.called || a.called || b.called {
+		t.Errorf("non-dangerous command should use the cascade: %+v", r)
+	}
+
+	l3.SetConsensus(a) // a single judge turns consensus off
+	fast.called = false
+	l3.Evaluate(context.Background(), &Request{Command: "rm -rf build", Tier: "dangerous"})
+	if !fast.called || a.called {
+		t.Error("a single judge should leave consensus off")
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCorpus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.yaml")
+	os.WriteFile(path, []byte("corpus:\n  - command: terraform destroy -auto-approve\n  - command: kubectl delete ns prod\n    category: infra\n"), 0600)
+	entries, err := LoadCorpus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Category != "custom" || entries[1].Category != "infra" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	os.WriteFile(path, []byte("corpus:\n  - category: infra\n"), 0600)
+	if _, err := LoadCorpus(path); err == nil {
+		t.Error("expected an error for an entry without a command")
+	}
+}
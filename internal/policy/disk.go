@@ -0,0 +1,77 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// blockDevice matches raw disk and partition device paths on Linux and
+// macOS.
+var blockDevice = regexp.MustCompile(`^/dev/(sd[a-z]|hd[a-z]|vd[a-z]|xvd[a-z]|nvme\d|mmcblk\d|disk\d|rdisk\d)`)
+
+// deviceRedirect matches output redirected onto a block device.
+var deviceRedirect = regexp.MustCompile(`>\|?\s*(/dev/(sd[a-z]|hd[a-z]|vd[a-z]|xvd[a-z]|nvme\d|mmcblk\d|disk\d|rdisk\d)\S*)`)
+
+// deviceWriters write to or destroy every device they are given.
+var deviceWriters = map[string]bool{
+	"mkswap": true, "mke2fs": true, "wipefs": true, "fdisk": true, "sfdisk": true, "cfdisk": true,
+	"gdisk": true, "sgdisk": true, "parted": true, "shred": true, "blkdiscard": true, "badblocks": true,
+	"tee": true, "diskutil": true, "newfs": true, "zpool": true, "pvcreate": true, "cryptsetup": true,
+}
+
+// deviceCopiers write only to their last argument.
+var deviceCopiers = map[string]bool{"cp": true, "mv": true, "install": true, "rsync": true}
+
+// checkBlockDeviceWrite denies writing to raw block devices: dd of=,
+// mkfs and partitioning tools, copies onto a device, and redirection.
+// It reads the raw command, so it applies whether or not any capability
+// for these programs is registered or enabled. Reading a device
+// (dd if=/dev/sda of=disk.img) is left to the other rules.
+func checkBlockDeviceWrite(req *Request) *Result {
+	deny := func(prog, dev string) *Result {
+		return &Result{
+			Decision: Deny,
+			Level:    1,
+			Reason:   fmt.Sprintf("%s writes to block device %s (permanently blocked)", prog, dev),
+			RuleID:   "deny-block-device-write",
+		}
+	}
+	if m := deviceRedirect.FindStringSubmatch(req.Command); m != nil {
+		return deny("redirection", m[1])
+	}
+	for _, words := range commandWords(req.Command) {
+		at := spawnedAt(words)
+		for n, i := range at {
+			end := len(words)
+			if n+1 < len(at) {
+				end = at[n+1]
+			}
+			prog := filepath.Base(words[i])
+			args := words[i+1 : end]
+			switch {
+			case prog == "dd":
+				for _, a := range args {
+					if dev, ok := strings.CutPrefix(a, "of="); ok && blockDevice.MatchString(dev) {
+						return deny(prog, dev)
+					}
+				}
+			case deviceWriters[prog] || strings.HasPrefix(prog, "mkfs") || strings.HasPrefix(prog, "newfs"):
+				for _, a := range args {
+					if blockDevice.MatchString(a) {
+						return deny(prog, a)
+					}
+				}
+			case deviceCopiers[prog]:
+				if len(args) > 0 && blockDevice.MatchString(args[len(args)-1]) {
+					return deny(prog, args[len(args)-1])
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import "testing"
+
+func TestBlockDeviceWrite(t *testing.T) {
+	l1 := defaultLevel1()
+	for _, cmd := range []string{
+		"dd if=/dev/zero of=/dev/sda bs=1M",
+		"sudo dd if=image.iso of=/dev/disk2",
+		"mkfs.ext4 /dev/sda1",
+		"mkfs -t xfs /dev/nvme0n1p2",
+		"cat /dev/urandom > /dev/sda",
+		"echo x >/dev/rdisk3",
+		"wipefs -a /dev/vdb",
+		"shred -n 1 /dev/sdb",
+		"cp image.img /dev/mmcblk0",
+		"xzcat image.xz | sudo tee /dev/sdc > /dev/null",
+		"diskutil eraseDisk APFS Blank /dev/disk4",
+	} {
+		result := l1.Evaluate(&Request{Command: cmd})
+		if result.Decision != Deny || result.RuleID != "deny-block-device-write" {
+			t.Errorf("%q: got decision=%v rule=%q, want deny by deny-block-device-write", cmd, result.Decision, result.RuleID)
+		}
+	}
+
+	for _, cmd := range []string{
+		"dd if=/dev/sda of=disk.img",
+		"cat /dev/sda1 | gzip > backup.gz",
+		"ls -l /dev/disk1",
+		"go test ./... > /dev/null",
+		"cp /dev/sda backup.img",
+		"mkfs.ext4 disk.img",
+	} {
+		if result := l1.Evaluate(&Request{Command: cmd}); result.RuleID == "deny-block-device-write" {
+			t.Errorf("%q: unexpectedly matched deny-block-device-write", cmd)
+		}
+	}
+}
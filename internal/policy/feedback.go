@@ -0,0 +1,172 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/paths"
+)
+
+// Feedback verdicts.
+const (
+	FeedbackGood = "good"
+	FeedbackBad  = "bad"
+)
+
+// Feedback is a human's judgment of a past Level 3 decision. It is shown
+// to the gatekeeper as a worked example when similar commands come up,
+// and alongside matching candidates when learned policies are reviewed.
+type Feedback struct {
+	Seq      uint64    `json:"seq"`      // audit seq of the decision
+	Time     time.Time `json:"time"`     // when the feedback was given
+	Command  string    `json:"command"`  // the command as judged
+	Decision string    `json:"decision"` // the gatekeeper's decision
+	Verdict  string    `json:"verdict"`  // FeedbackGood or FeedbackBad
+	Note     string    `json:"note,omitempty"`
+}
+
+// DefaultFeedbackPath returns the default path of the feedback file.
+func DefaultFeedbackPath() string {
+	return paths.Feedback()
+}
+
+// ParseVerdict validates a feedback verdict.
+func ParseVerdict(s string) (string, error) {
+	switch s {
+	case FeedbackGood, FeedbackBad:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid verdict %q (want good or bad)", s)
+}
+
+// AppendFeedback records fb at the end of the feedback file at path.
+func AppendFeedback(path string, fb Feedback) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("create feedback dir: %w", err)
+	}
+	data, err := json.Marshal(fb)
+	if err != nil {
+		return fmt.Errorf("marshal feedback: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open feedback: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write feedback: %w", err)
+	}
+	return f.Close()
+}
+
+// LoadFeedback reads the feedback file at path, oldest first. Feedback
+// given again for the same decision replaces the earlier judgment. A
+// missing file holds no feedback.
+func LoadFeedback(path string) ([]Feedback, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read feedback: %w", err)
+	}
+	defer f.Close()
+
+	var raw []Feedback
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var fb Feedback
+		if err := json.Unmarshal(scanner.Bytes(), &fb); err != nil {
+			return nil, fmt.Errorf("parse feedback %s:%d: %w", path, line, err)
+		}
+		raw = append(raw, fb)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read feedback: %w", err)
+	}
+
+	last := map[uint64]int{}
+	for i, fb := range raw {
+		last[fb.Seq] = i
+	}
+	var all []Feedback
+	for i, fb := range raw {
+		if last[fb.Seq] == i {
+			all = append(all, fb)
+		}
+	}
+	return all, nil
+}
+
+// SelectFeedback picks up to n examples from all (oldest first) for the
+// gatekeeper judging command: the most recent feedback on commands with
+// the same program first, then the most recent of the rest. The result is
+// oldest first.
+func SelectFeedback(all []Feedback, command string, n int) []Feedback {
+	if n <= 0 || len(all) == 0 {
+		return nil
+	}
+	program := parseFirstSegment(command).CapName
+	var same, other []Feedback
+	for i := len(all) - 1; i >= 0; i-- {
+		if parseFirstSegment(all[i].Command).CapName == program {
+			same = append(same, all[i])
+		} else {
+			other = append(other, all[i])
+		}
+	}
+	picked := append(same, other...)
+	if len(picked) > n {
+		picked = picked[:n]
+	}
+	// Restore chronological order.
+	seqs := map[uint64]bool{}
+	for _, fb := range picked {
+		seqs[fb.Seq] = true
+	}
+	out := make([]Feedback, 0, len(picked))
+	for _, fb := range all {
+		if seqs[fb.Seq] {
+			out = append(out, fb)
+		}
+	}
+	return out
+}
+
+// FeedbackFor returns the feedback on commands that entry matches.
+func FeedbackFor(entry *PolicyEntry, all []Feedback) []Feedback {
+	var out []Feedback
+	for _, fb := range all {
+		seg := parseFirstSegment(fb.Command)
+		if (len(entry.Match.Pipeline) > 0 || !isCompound(fb.Command)) && entryMatches(entry, &seg, fb.Command) {
+			out = append(out, fb)
+		}
+	}
+	return out
+}
+
+// String renders the feedback as one line, as shown to the gatekeeper and
+// in reviews.
+func (fb Feedback) String() string {
+	verdict := "right"
+	if fb.Verdict == FeedbackBad {
+		verdict = "wrong"
+	}
+	s := fmt.Sprintf("%s: decided %s; a human judged this %s", fb.Command, fb.Decision, verdict)
+	if fb.Note != "" {
+		s += fmt.Sprintf(" (%s)", fb.Note)
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFeedbackRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "feedback.jsonl")
+	if all, err := LoadFeedback(path); err != nil || all != nil {
+		t.Fatalf("missing file: got %v, %v", all, err)
+	}
+	for _, fb := range []Feedback{
+		{Seq: 1, Command: "rm -rf build", Decision: "allow", Verdict: FeedbackGood},
+		{Seq: 2, Command: "git push --force", Decision: "allow", Verdict: FeedbackGood},
+		{Seq: 2, Command: "git push --force", Decision: "allow", Verdict: FeedbackBad, Note: "never force-push"},
+	} {
+		if err := AppendFeedback(path, fb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := LoadFeedback(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Seq != 2 || all[1].Verdict != FeedbackBad {
+		t.Errorf("later feedback should replace earlier: got %+v", all)
+	}
+
+	if err := os.WriteFile(path, []byte("{bad\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFeedback(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("want a parse error naming the line, got %v", err)
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	if v, err := ParseVerdict("bad"); err != nil || v != FeedbackBad {
+		t.Errorf("got %q, %v", v, err)
+	}
+	if _, err := ParseVerdict("meh"); err == nil {
+		t.Error("want an error for an unknown verdict")
+	}
+}
+
+func TestSelectFeedback(t *testing.T) {
+	all := []Feedback{
+		{Seq: 1, Command: "git push"},
+		{Seq: 2, Command: "rm a"},
+		{Seq: 3, Command: "git status"},
+		{Seq: 4, Command: "make"},
+		{Seq: 5, Command: "rm b"},
+	}
+	got := SelectFeedback(all, "git push --force", 3)
+	var seqs []uint64
+	for _, fb := range got {
+		seqs = append(seqs, fb.Seq)
+	}
+	// Both git commands, then the most recent other, oldest first.
+	if len(seqs) != 3 || seqs[0] != 1 || seqs[1] != 3 || seqs[2] != 5 {
+		t.Errorf("got seqs %v, want [1 3 5]", seqs)
+	}
+	if got := SelectFeedback(all, "ls", 0); got != nil {
+		t.Errorf("n=0: got %v", got)
+	}
+}
+
+func TestFeedbackFor(t *testing.T) {
+	all := []Feedback{
+		{Seq: 1, Command: "go test ./..."},
+		{Seq: 2, Command: "go build"},
+		{Seq: 3, Command: "go test ./... && rm -rf /"},
+	}
+	entry := &PolicyEntry{Match: MatchCriteria{Cap: "go", Subcmd: "test"}}
+	got := FeedbackFor(entry, all)
+	if len(got) != 1 || got[0].Seq != 1 {
+		t.Errorf("got %+v, want only seq 1", got)
+	}
+}
+
+func TestFeedbackInPrompt(t *testing.T) {
+	fb := Feedback{Command: "rm -rf build", Decision: "deny", Verdict: FeedbackBad, Note: "build is scratch"}
+	if got, want := fb.String(), "rm -rf build: decided deny; a human judged this wrong (build is scratch)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	prompt := buildPrompt(&Request{Command: "rm -rf build", Context: &PromptContext{Feedback: []string{fb.String()}}}, false)
+	if !strings.Contains(prompt, "reviewed by a human") || !strings.Contains(prompt, "  "+fb.String()+"\n") {
+		t.Errorf("prompt lacks the feedback:\n%s", prompt)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fetchers download content from the network.
+var fetchers = map[string]bool{
+	"curl": true, "wget": true, "http": true, "https": true, "fetch": true, "aria2c": true,
+}
+
+// isInterpreter reports whether prog executes a script it is given: a
+// shell or a scripting language runtime.
+func isInterpreter(prog string) bool {
+	if shells[prog] || strings.HasPrefix(prog, "python") {
+		return true
+	}
+	switch prog {
+	case "perl", "ruby", "node", "deno", "bun", "php", "lua", "source", ".":
+		return true
+	}
+	return false
+}
+
+// pipelineSep splits a command line into pipelines; what remains of each
+// is split into segments at single pipes.
+var pipelineSep = regexp.MustCompile("&&|\\|\\||[;&\n]")
+
+// pipelines returns each pipeline in command as its segments' words, with
+// quotes removed and leading assignments skipped, as commandWords does.
+func pipelines(command string) [][][]string {
+	command = strings.NewReplacer(`'`, " ", `"`, " ").Replace(command)
+	var out [][][]string
+	for _, pl := range pipelineSep.Split(command, -1) {
+		var segs [][]string
+		for _, seg := range strings.Split(pl, "|") {
+			words := strings.Fields(seg)
+			for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "-") {
+				words = words[1:]
+			}
+			if len(words) > 0 {
+				segs = append(segs, words)
+			}
+		}
+		if len(segs) > 0 {
+			out = append(out, segs)
+		}
+	}
+	return out
+}
+
+// readsScriptFromStdin reports whether an interpreter given args takes its
+// script from standard input, as opposed to a file or an inline script
+// (python -c, perl -ne, node -e), where piped input is only data.
+func readsScriptFromStdin(args []string) bool {
+	for _, a := range args {
+		switch {
+		case a == "-":
+			return true
+		case strings.HasPrefix(a, "--"):
+			continue
+		case strings.HasPrefix(a, "-"):
+			if strings.ContainsAny(a[1:], "cemEpr") {
+				return false
+			}
+		default:
+			return false // a script file
+		}
+	}
+	return true
+}
+
+// fetchSubstitution matches a download run inside $(...) or <(...), whose
+// output becomes a script or an argument.
+var fetchSubstitution = regexp.MustCompile(`[$<]\(\s*(curl|wget|https?|fetch|aria2c)\b`)
+
+// checkRemoteCodeExecution denies running downloaded content: a fetcher
+// piped into an interpreter (curl | sh, wget -O- | python), or an
+// interpreter fed a download by substitution (bash <(curl ...),
+// sh -c "$(curl ...)").
+func checkRemoteCodeExecution(req *Request) *Result {
+	deny := func(fetcher, interp string) *Result {
+		return &Result{
+			Decision: Deny,
+			Level:    1,
+			Reason:   fmt.Sprintf("runs content fetched by %s with %s; download it, review it, then run it (permanently blocked)", fetcher, interp),
+			RuleID:   "deny-remote-code-execution",
+		}
+	}
+	for _, segs := range pipelines(req.Command) {
+		fetcher := ""
+		for _, words := range segs {
+			for _, i := range spawnedAt(words) {
+				prog := filepath.Base(words[i])
+				if fetcher != "" && isInterpreter(prog) && readsScriptFromStdin(words[i+1:]) {
+					return deny(fetcher, prog)
+				}
+				if fetchers[prog] && fetcher == "" {
+					fetcher = prog
+				}
+			}
+		}
+	}
+	// Substitutions are judged within the pipeline segment they appear in.
+	for _, segs := range pipelines(req.Command) {
+		for _, words := range segs {
+			m := fetchSubstitution.FindStringSubmatch(strings.Join(words, " "))
+			if m == nil {
+				continue
+			}
+			for _, prog := range spawned(words) {
+				if isInterpreter(prog) {
+					return deny(m[1], prog)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// execDirs are directories whose contents run as commands.
+var execDirs = []string{
+	"/bin/", "/sbin/", "/usr/bin/", "/usr/sbin/", "/usr/local/bin/", "/usr/local/sbin/",
+	"/opt/homebrew/bin/", "~/bin/", "~/.local/bin/", "$HOME/bin/", "$HOME/.local/bin/",
+}
+
+// outputFlags are the fetcher options naming where the download is saved.
+var outputFlags = map[string][]string{
+	"curl":   {"-o", "--output"},
+	"wget":   {"-O", "--output-document", "-P", "--directory-prefix"},
+	"http":   {"-o", "--output"},
+	"https":  {"-o", "--output"},
+	"aria2c": {"-d", "--dir"},
+}
+
+// checkFetchToExecPath escalates downloads saved straight into a directory
+// of executables or git hooks, where they would run without review.
+func checkFetchToExecPath(req *Request) *Result {
+	for _, words := range commandWords(req.Command) {
+		prog := filepath.Base(words[0])
+		flags := outputFlags[prog]
+		if flags == nil {
+			continue
+		}
+		for i := 1; i < len(words); i++ {
+			dest := ""
+			for _, f := range flags {
+				switch {
+				case words[i] == f && i+1 < len(words):
+					dest = words[i+1]
+				case strings.HasPrefix(words[i], f+"="):
+					dest = strings.TrimPrefix(words[i], f+"=")
+				}
+			}
+			if dest != "" && inExecDir(dest) {
+				return &Result{
+					Decision: Escalate,
+					Level:    1,
+					Reason:   fmt.Sprintf("%s saves a download into %s, where it would run unreviewed", prog, dest),
+					RuleID:   "escalate-fetch-to-exec-path",
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// inExecDir reports whether dest lies in (or is) a directory of
+// executables or git hooks.
+func inExecDir(dest string) bool {
+	if strings.Contains(dest, ".git/hooks") {
+		return true
+	}
+	for _, dir := range execDirs {
+		if strings.HasPrefix(dest, dir) || dest+"/" == dir {
+			return true
+		}
+	}
+	return false
+}
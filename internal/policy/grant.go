@@ -0,0 +1,104 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GrantIDPrefix starts the ID of every temporary grant, marking it in the
+// audit log.
+const GrantIDPrefix = "grant-"
+
+// IsGrant reports whether a policy rule ID names a temporary grant.
+func IsGrant(ruleID string) bool {
+	return strings.HasPrefix(ruleID, GrantIDPrefix)
+}
+
+// NewGrant builds a temporary, human-approved L2 entry allowing commands
+// that match pattern until expires. The pattern is a capability, an
+// optional subcommand, and optional positional-argument globs:
+//
+//	go test                   any go test
+//	git push origin agent/*   git push whose arguments are origin or agent/...
+//
+// Flags are not constrained, so patterns may not contain them.
+func NewGrant(pattern string, expires, now time.Time) (PolicyEntry, error) {
+	words := strings.Fields(pattern)
+	if len(words) == 0 {
+		return PolicyEntry{}, fmt.Errorf("empty grant pattern")
+	}
+	for _, w := range words {
+		if strings.HasPrefix(w, "-") {
+			return PolicyEntry{}, fmt.Errorf("grant pattern %q: flags are not matched; name the command and arguments only", pattern)
+		}
+	}
+	if !expires.After(now) {
+		return PolicyEntry{}, fmt.Errorf("grant expiry %s is not in the future", expires.Format(time.RFC3339))
+	}
+
+	m := MatchCriteria{Cap: words[0]}
+	rest := words[1:]
+	if len(rest) > 0 && !strings.ContainsAny(rest[0], "*?[") {
+		m.Subcmd, rest = rest[0], rest[1:]
+	}
+	m.ArgsGlob = rest
+
+	return PolicyEntry{
+		ID:          fmt.Sprintf("%s%s-%d", GrantIDPrefix, m.Cap, now.UnixMilli()),
+		Description: fmt.Sprintf("Temporary grant for %s", strings.Join(words, " ")),
+		Match:       m,
+		Decision:    "allow",
+		Reasoning:   fmt.Sprintf("temporary grant until %s", expires.UTC().Format(time.RFC3339)),
+		Confidence:  "high",
+		Provenance:  "human",
+		Approved:    true,
+		ExpiresAt:   expires.UTC(),
+		Review:      ReviewSchedule{Created: now.UTC(), NextReview: expires.UTC()},
+	}, nil
+}
+
+// Expired reports whether a temporary entry has passed its expiry.
+func (e *PolicyEntry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// PruneExpired removes expired temporary entries from the store at path.
+// Returns the number removed.
+func PruneExpired(path string, now time.Time) (int, error) {
+	var removed int
+	err := ModifyStore(path, func(entries []PolicyEntry) ([]PolicyEntry, error) {
+		kept := entries[:0]
+		for _, e := range entries {
+			if !e.Expired(now) {
+				kept = append(kept, e)
+			}
+		}
+		if removed = len(entries) - len(kept); removed == 0 {
+			return nil, errUnchanged
+		}
+		return kept, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// compoundOperators are shell constructs that let a command do more than
+// its leading words show.
+var compoundOperators = []string{";", "&", "|", "`", "$(", "<", ">", "\n"}
+
+// isCompound reports whether command uses shell composition. Grants cover
+// simple commands only.
+func isCompound(command string) bool {
+	for _, op := range compoundOperators {
+		if strings.Contains(command, op) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewGrant(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g, err := NewGrant("git push origin agent/*", now.Add(time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsGrant(g.ID) || g.Match.Cap != "git" || g.Match.Subcmd != "push" || len(g.Match.ArgsGlob) != 2 || !g.Approved {
+		t.Errorf("grant = %+v", g)
+	}
+	if g, _ := NewGrant("ls *.go", now.Add(time.Hour), now); g.Match.Subcmd != "" || len(g.Match.ArgsGlob) != 1 {
+		t.Errorf("glob taken as subcommand: %+v", g.Match)
+	}
+
+	for _, bad := range []string{"", "go test -race"} {
+		if _, err := NewGrant(bad, now.Add(time.Hour), now); err == nil {
+			t.Errorf("NewGrant(%q): expected error", bad)
+		}
+	}
+	if _, err := NewGrant("go test", now, now); err == nil {
+		t.Error("expected error for an expiry that is not in the future")
+	}
+}
+
+func TestLevel2_Grants(t *testing.T) {
+	now := time.Now()
+	live, _ := NewGrant("git push origin agent/*", now.Add(time.Hour), now)
+	stale, _ := NewGrant("go test", now.Add(time.Minute), now.Add(-time.Hour))
+	stale.ExpiresAt = now.Add(-time.Minute)
+	l2 := NewLevel2([]PolicyEntry{live, stale})
+
+	for _, tc := range []struct {
+		command string
+		want    Decision
+	}{
+		{"git push origin agent/fix-42", Allow},
+		{"git push origin main", Escalate},
+		{"git push origin agent/x && rm -rf build", Escalate}, // compound
+		{"git push origin agent/$(whoami)", Escalate},
+		{"go test ./...", Escalate}, // expired
+	} {
+		if r := l2.Evaluate(&Request{Command: tc.command}); r.Decision != tc.want {
+			t.Errorf("%q: got %v (%s), want %v", tc.command, r.Decision, r.Reason, tc.want)
+		}
+	}
+}
+
+func TestPruneExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "learned.yaml")
+	now := time.Now()
+	live, _ := NewGrant("go test", now.Add(time.Hour), now)
+	stale, _ := NewGrant("go vet", now.Add(time.Hour), now)
+	stale.ID += "-stale"
+	stale.ExpiresAt = now.Add(-time.Second)
+	learned := PolicyEntry{ID: "learned-ls", Match: MatchCriteria{Cap: "ls"}, Decision: "allow", Approved: true}
+	if err := SaveStore(path, []PolicyEntry{learned, live, stale}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := PruneExpired(path, now); err != nil || n != 1 {
+		t.Fatalf("PruneExpired = %d, %v; want 1", n, err)
+	}
+	entries, err := LoadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ID != "learned-ls" || entries[1].ID != live.ID || !entries[1].ExpiresAt.Equal(live.ExpiresAt) {
+		t.Errorf("entries after prune = %+v", entries)
+	}
+}
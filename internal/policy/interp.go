@@ -0,0 +1,214 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// interpreter describes how a language runtime takes its program.
+type interpreter struct {
+	lang       string
+	inline     map[string]bool // flags whose value is the program
+	valueFlags map[string]bool // other flags that take a separate value
+	module     map[string]bool // flags naming an installed module to run instead of a file
+	exits      map[string]bool // flags that print something and exit without running a program
+}
+
+// interpreters are the runtimes whose inline programs need review.
+var interpreters = map[string]*interpreter{
+	"python": pythonInterp, "python3": pythonInterp,
+	"node": {
+		lang:       "JavaScript",
+		inline:     map[string]bool{"-e": true, "--eval": true, "-p": true, "--print": true},
+		valueFlags: map[string]bool{"-r": true, "--require": true, "--import": true, "--input-type": true, "-C": true, "--conditions": true},
+		exits:      map[string]bool{"-v": true, "--version": true, "-h": true, "--help": true},
+	},
+	"ruby": {
+		lang:       "Ruby",
+		inline:     map[string]bool{"-e": true},
+		valueFlags: map[string]bool{"-I": true, "-r": true, "-C": true, "-E": true, "--encoding": true},
+		exits:      map[string]bool{"-v": true, "--version": true, "-h": true, "--help": true},
+	},
+}
+
+var pythonInterp = &interpreter{
+	lang:       "Python",
+	inline:     map[string]bool{"-c": true},
+	valueFlags: map[string]bool{"-W": true, "-X": true, "--check-hash-based-pycs": true},
+	module:     map[string]bool{"-m": true},
+	exits:      map[string]bool{"-V": true, "--version": true, "-h": true, "--help": true},
+}
+
+// interpreterProgram describes where an interpreter invocation gets its
+// program: inline on the command line, from stdin, or from a file. For an
+// inline program, code is the program; for a file, it is the path. An
+// installed module (python -m) or a flag such as --version reports an
+// empty source.
+func interpreterProgram(in *interpreter, args []string) (source, code string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		name, value, hasValue := strings.Cut(a, "=")
+		switch {
+		case a == "-":
+			return "stdin", ""
+		case a == "--":
+			if i+1 < len(args) {
+				return "file", args[i+1]
+			}
+			return "stdin", ""
+		case in.inline[a]:
+			if i+1 < len(args) {
+				return "inline", args[i+1]
+			}
+			return "inline", ""
+		case hasValue && in.inline[name] && strings.HasPrefix(a, "--"):
+			return "inline", value
+		case strings.HasPrefix(a, "<"):
+			return "stdin", "" // a redirection or here-document
+		case strings.HasPrefix(strings.TrimLeft(a, "0123456789"), ">"):
+			if strings.HasSuffix(a, ">") {
+				i++ // the target is the next word
+			}
+		case in.module[a] || in.exits[a]:
+			return "", ""
+		case in.valueFlags[a]:
+			i++
+		case strings.HasPrefix(a, "--"):
+		case strings.HasPrefix(a, "-"):
+			// Short flags may be combined (python -uc CODE, ruby -we CODE)
+			// or carry their value (-W error, -Idir).
+			for j := 1; j < len(a); j++ {
+				f := "-" + a[j:j+1]
+				switch {
+				case in.inline[f] && j+1 < len(a):
+					return "inline", a[j+1:]
+				case in.inline[f] && i+1 < len(args):
+					return "inline", args[i+1]
+				case in.inline[f]:
+					return "inline", ""
+				case in.module[f]:
+					return "", ""
+				case in.valueFlags[f]:
+					if j+1 == len(a) {
+						i++
+					}
+					j = len(a)
+				}
+			}
+		default:
+			return "file", a
+		}
+	}
+	return "stdin", ""
+}
+
+// checkInterpreterCode escalates interpreters given their program inline
+// (python -c, node -e, ruby -e), on stdin, or from a file outside the
+// working directory. Running a workspace file is an ordinary write-tier
+// command; code the agent wrote on the spot is arbitrary execution that
+// no other rule inspects, so it always goes to Level 3, which sees it.
+func checkInterpreterCode(req *Request) *Result {
+	for _, words := range commandWords(req.Command) {
+		for _, i := range spawnedAt(words) {
+			prog := filepath.Base(words[i])
+			in := interpreters[prog]
+			if in == nil {
+				continue
+			}
+			source, code := interpreterProgram(in, words[i+1:])
+			reason := ""
+			switch source {
+			case "inline":
+				reason = fmt.Sprintf("runs inline %s code; the code needs review", in.lang)
+			case "stdin":
+				reason = fmt.Sprintf("runs a %s program read from stdin; the program needs review", in.lang)
+			case "file":
+				path := code
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(req.Cwd, path)
+				}
+				if req.Cwd != "" && !withinDir(filepath.Clean(path), req.Cwd) {
+					reason = fmt.Sprintf("runs %s from %s, outside the working directory; it needs review", in.lang, code)
+				}
+			}
+			if reason != "" {
+				return &Result{
+					Decision: Escalate,
+					Level:    1,
+					Reason:   reason,
+					RuleID:   "escalate-interpreter-code",
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// InlineProgram returns the program an interpreter in command runs
+// inline or from stdin, for a reviewer to read, and the language it is
+// in. It returns empty strings for commands that run files.
+func InlineProgram(command, stdin string) (lang, program string) {
+	words := quotedWords(command)
+	for i, w := range words {
+		in := interpreters[filepath.Base(w)]
+		if in == nil {
+			continue
+		}
+		switch source, code := interpreterProgram(in, words[i+1:]); source {
+		case "inline":
+			return in.lang, code
+		case "stdin":
+			return in.lang, stdin
+		}
+	}
+	return "", ""
+}
+
+// quotedWords splits command at unquoted blanks, removing quotes, so that
+// a quoted program stays one word. Shell operators are not separated.
+func quotedWords(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == '\\' && i+1 < len(command):
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// withinDir reports whether path is dir or lies beneath it.
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
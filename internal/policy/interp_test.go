@@ -0,0 +1,62 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpreterCode(t *testing.T) {
+	l1 := defaultLevel1()
+	escalated := []string{
+		`python3 -c 'import os; os.remove("x")'`,
+		`python -uc 'print(1)'`,
+		`node -e 'require("fs").rmSync("x")'`,
+		`node --eval='1'`,
+		`ruby -we 'puts 1'`,
+		"python3",
+		"python3 -",
+		"cat gen.py | python3",
+		"python3 <<EOF\nprint(1)\nEOF",
+		"timeout 60 node -p 1+1",
+		"python3 ../other/gen.py",
+		"ruby /tmp/x.rb",
+	}
+	for _, cmd := range escalated {
+		result := l1.Evaluate(&Request{Command: cmd, Cwd: "/work/repo"})
+		if result.Decision != Escalate || result.RuleID != "escalate-interpreter-code" {
+			t.Errorf("%q: got decision=%v rule=%q, want escalation by escalate-interpreter-code", cmd, result.Decision, result.RuleID)
+		}
+	}
+	for _, cmd := range []string{
+		"python3 scripts/gen.py --out x",
+		"python3 -W error -X dev gen.py",
+		"python3 -m pytest -q",
+		"python3 --version",
+		"node scripts/build.js",
+		"node -r ts-node/register src/main.ts",
+		"ruby -Ilib test/all.rb",
+		"/work/repo/tools/gen.py",
+		"grep python3 README.md",
+	} {
+		if result := checkInterpreterCode(&Request{Command: cmd, Cwd: "/work/repo"}); result != nil {
+			t.Errorf("%q: unexpected %v (%s)", cmd, result.Decision, result.Reason)
+		}
+	}
+}
+
+func TestInlineProgramInPrompt(t *testing.T) {
+	prompt := buildPrompt(&Request{Command: `python3 -c 'import shutil; shutil.rmtree("build")'`}, false)
+	if !strings.Contains(prompt, "Python program") || !strings.Contains(prompt, "\nimport shutil; shutil.rmtree(\"build\")\n") {
+		t.Errorf("inline code missing from prompt:\n%s", prompt)
+	}
+	prompt = buildPrompt(&Request{Command: "node", Stdin: "process.exit(3)"}, false)
+	if !strings.Contains(prompt, "JavaScript program") || !strings.Contains(prompt, "\nprocess.exit(3)\n") {
+		t.Errorf("stdin program missing from prompt:\n%s", prompt)
+	}
+	if prompt := buildPrompt(&Request{Command: "python3 gen.py", Stdin: "data"}, false); strings.Contains(prompt, "PROGRAM") {
+		t.Errorf("file run should not quote a program:\n%s", prompt)
+	}
+}
@@ -14,8 +14,9 @@ import (
 
 // Level1 evaluates commands against deterministic rules.
 type Level1 struct {
-	rules    []Rule
-	starlark *doitstar.Evaluator
+	rules     []Rule
+	starlark  *doitstar.Evaluator
+	sensitive SensitivePaths // watched for being sent over the network
 }
 
 // Rule is a named, testable deterministic rule.
@@ -34,7 +35,7 @@ func NewLevel1(cfgRules map[string]rules.CapRuleConfig) *Level1 {
 // NewLevel1WithStarlark creates a Level1 engine with built-in, config-derived,
 // and Starlark rules. Starlark rules are evaluated after built-in rules.
 func NewLevel1WithStarlark(cfgRules map[string]rules.CapRuleConfig, starlarkEval *doitstar.Evaluator) *Level1 {
-	l := &Level1{starlark: starlarkEval}
+	l := &Level1{starlark: starlarkEval, sensitive: DefaultSensitivePaths}
 
 	// Hardcoded deny rules (never bypassable).
 	l.rules = append(l.rules, Rule{
@@ -42,8 +43,77 @@ func NewLevel1WithStarlark(cfgRules map[string]rules.CapRuleConfig, starlarkEval
 		Description: "Block recursive removal of root, home, or current directory",
 		Check:       checkRmCatastrophic,
 	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-doit-self-approval",
+		Description: "Block agents from approving their own escalations through doit",
+		Check:       checkDoitSelfApproval,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-doit-recursion",
+		Description: "Block doit from running doit",
+		Check:       checkDoitRecursion,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-fork-bomb",
+		Description: "Block fork-bomb signatures",
+		Check:       checkForkBomb,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-perm-catastrophic",
+		Description: "Block recursive chmod/chown of root, home, or .git directories",
+		Check:       checkPermCatastrophic,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-block-device-write",
+		Description: "Block writes to raw block devices",
+		Check:       checkBlockDeviceWrite,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-redirect-special",
+		Description: "Block redirection onto devices and set-uid or set-gid files",
+		Check:       checkRedirectSpecial,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "deny-remote-code-execution",
+		Description: "Block running downloaded content through an interpreter",
+		Check:       checkRemoteCodeExecution,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "escalate-fetch-to-exec-path",
+		Description: "Escalate downloads saved into executable or hook directories",
+		Bypassable:  true,
+		Check:       checkFetchToExecPath,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "escalate-perm-change",
+		Description: "Escalate world-writable modes and ownership changes outside the workspace",
+		Bypassable:  true,
+		Check:       checkPermEscalation,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "escalate-redirect-target",
+		Description: "Escalate redirection outside the workspace, into protected directories, or over uncommitted changes",
+		Bypassable:  true,
+		Check:       checkRedirectTarget,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "escalate-nested-shell",
+		Description: "Escalate commands that start another shell",
+		Bypassable:  true,
+		Check:       checkNestedShell,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          "escalate-interpreter-code",
+		Description: "Escalate interpreters running inline code, stdin, or files outside the workspace",
+		Check:       checkInterpreterCode,
+	})
+	l.rules = append(l.rules, Rule{
+		ID:          exfiltrationRuleID,
+		Description: "Escalate pipelines that read a sensitive file and send to the network",
+		Check:       l.checkExfiltration,
+	})
 
-	// Config deny rules (bypassable with --retry).
+	// Config deny rules (bypassable with --retry unless configured not to be).
 	for capName, cfg := range cfgRules {
 		l.rules = append(l.rules, compileConfigRules(capName, cfg)...)
 	}
@@ -63,7 +133,7 @@ func NewLevel1WithStarlark(cfgRules map[string]rules.CapRuleConfig, starlarkEval
 // Returns Escalate if no rule has an opinion.
 func (l *Level1) Evaluate(req *Request) *Result {
 	for _, r := range l.rules {
-		if r.Bypassable && req.Retry {
+		if r.Bypassable && req.Bypasses(r.ID) {
 			continue
 		}
 		if result := r.Check(req); result != nil {
@@ -78,7 +148,7 @@ func (l *Level1) Evaluate(req *Request) *Result {
 		if len(parts) > 0 {
 			capName := parts[0]
 			args := parts[1:]
-			starResult, ruleID, starBypassable := l.starlark.EvaluateCommand(capName, args, req.Retry)
+			starResult, ruleID, starBypassable := l.starlark.EvaluateCommandBypassing(capName, args, req.Bypasses)
 			if starResult != nil {
 				dec := Escalate
 				switch starResult.Decision {
@@ -210,12 +280,12 @@ func isCatastrophicPath(path string) bool {
 // Parses the raw command string — only matches when the command starts
 // with "rm" and has -r/-R.
 func checkRmCatastrophic(req *Request) *Result {
-	parts := strings.Fields(req.Command)
+	parts := rules.Normalize(strings.Fields(req.Command))
 	if len(parts) == 0 || parts[0] != "rm" {
 		return nil
 	}
 	args := parts[1:]
-	if !HasAnyFlag(args, "-r", "-R") {
+	if !rules.HasFlag("rm", args, "-r", "-R") {
 		return nil
 	}
 	deny := func(arg, reason string) *Result {
@@ -262,6 +332,45 @@ func checkRmCatastrophic(req *Request) *Result {
 	return nil
 }
 
+// selfApprovalFlags are the doit flags that grant or withdraw approvals,
+// retry past a denial, reset quotas, or coach the gatekeeper with
+// feedback. They are for humans; an agent running them through doit could
+// approve its own escalations.
+var selfApprovalFlags = map[string]bool{
+	"--approve":  true,
+	"--deny":     true,
+	"--tokens":   true,
+	"--grants":   true,
+	"--quota":    true,
+	"--retry":    true,
+	"--policy":   true,
+	"--feedback": true,
+}
+
+// checkDoitSelfApproval blocks doit invocations that approve, deny, or
+// mint approvals. Any word naming the doit binary followed later by one
+// of the approval flags matches, so wrappers such as env or sh -c are
+// caught too.
+func checkDoitSelfApproval(req *Request) *Result {
+	sawDoit := false
+	for _, f := range strings.Fields(req.Command) {
+		f = strings.Trim(f, `'"`)
+		if filepath.Base(f) == "doit" {
+			sawDoit = true
+			continue
+		}
+		if sawDoit && selfApprovalFlags[f] {
+			return &Result{
+				Decision: Deny,
+				Level:    1,
+				Reason:   fmt.Sprintf("doit %s is for humans; agents cannot resolve approvals (permanently blocked)", f),
+				RuleID:   "deny-doit-self-approval",
+			}
+		}
+	}
+	return nil
+}
+
 // checkGitCheckoutAll blocks "git checkout ." which discards all local changes.
 // Parses the raw command string.
 func checkGitCheckoutAll(req *Request) *Result {
@@ -303,21 +412,22 @@ func compileConfigRules(capName string, cfg rules.CapRuleConfig) []Rule {
 	if len(cfg.RejectFlags) > 0 {
 		flags := cfg.RejectFlags
 		name := capName
+		note := rules.BypassNote(cfg.IsBypassable())
 		result = append(result, Rule{
 			ID:          fmt.Sprintf("deny-%s-flags", name),
 			Description: fmt.Sprintf("Reject flags %v for %s", flags, name),
-			Bypassable:  true,
+			Bypassable:  cfg.IsBypassable(),
 			Check: func(req *Request) *Result {
-				parts := strings.Fields(req.Command)
+				parts := rules.Normalize(strings.Fields(req.Command))
 				if len(parts) == 0 || parts[0] != name {
 					return nil
 				}
 				args := parts[1:]
-				if HasAnyFlag(args, flags...) {
+				if rules.HasFlag(name, args, flags...) {
 					return &Result{
 						Decision: Deny,
 						Level:    1,
-						Reason:   fmt.Sprintf("rejected flag for %s (config rule, bypassable)", name),
+						Reason:   fmt.Sprintf("rejected flag for %s (%s)", name, note),
 						RuleID:   fmt.Sprintf("deny-%s-flags", name),
 					}
 				}
@@ -331,21 +441,23 @@ func compileConfigRules(capName string, cfg rules.CapRuleConfig) []Rule {
 			flags := subRule.RejectFlags
 			name := capName
 			sub := subcmd
+			bypassable := cfg.SubBypassable(sub)
+			note := rules.BypassNote(bypassable)
 			result = append(result, Rule{
 				ID:          fmt.Sprintf("deny-%s-%s-flags", name, sub),
 				Description: fmt.Sprintf("Reject flags %v for %s %s", flags, name, sub),
-				Bypassable:  true,
+				Bypassable:  bypassable,
 				Check: func(req *Request) *Result {
-					parts := strings.Fields(req.Command)
+					parts := rules.Normalize(strings.Fields(req.Command))
 					if len(parts) < 2 || parts[0] != name || parts[1] != sub {
 						return nil
 					}
 					args := parts[2:]
-					if HasAnyFlag(args, flags...) {
+					if rules.HasFlag(name+" "+sub, args, flags...) {
 						return &Result{
 							Decision: Deny,
 							Level:    1,
-							Reason:   fmt.Sprintf("%s: rejected flag for %s (config rule, bypassable)", sub, name),
+							Reason:   fmt.Sprintf("%s: rejected flag for %s (%s)", sub, name, note),
 							RuleID:   fmt.Sprintf("deny-%s-%s-flags", name, sub),
 						}
 					}
@@ -43,6 +43,9 @@ func TestDenyRmCatastrophic(t *testing.T) {
 		{"grep -rf / (not rm)", "grep -rf /", false},
 		{"rm -fr /", "rm -fr /", true},
 		{"rm -rf //", "rm -rf //", true},
+		{"rm --recursive /", "rm --recursive /", true},
+		{"rm --recur --force / (abbreviated)", "rm --recur --force /", true},
+		{"rm -vR ~", "rm -vR ~", true},
 		// System directories (blacklist).
 		{"rm -rf /usr", "rm -rf /usr", true},
 		{"rm -rf /etc", "rm -rf /etc", true},
@@ -90,6 +93,29 @@ func TestDenyRmCatastrophicNotBypassable(t *testing.T) {
 	}
 }
 
+func TestDenyDoitSelfApproval(t *testing.T) {
+	l1 := defaultLevel1()
+	for _, cmd := range []string{
+		"doit --approve 4242/7",
+		"doit --deny 7 --always",
+		"/usr/local/bin/doit --tokens issue git push --force",
+		"sh -c 'doit --approve 7'",
+		"env FOO=1 doit --config x.yaml --tokens revoke abc",
+		"doit --feedback 12 good --note 'force-push is fine'",
+		"doit --rerun 12 --retry",
+	} {
+		result := l1.Evaluate(&Request{Command: cmd, Retry: true})
+		if result.Decision != Deny || result.RuleID != "deny-doit-self-approval" {
+			t.Errorf("%q: got decision=%v rule=%q, want deny by deny-doit-self-approval", cmd, result.Decision, result.RuleID)
+		}
+	}
+	for _, cmd := range []string{"doit --pending", "doit --tokens-help", "echo --approve", "grep -r doit . --deny-list"} {
+		if result := l1.Evaluate(&Request{Command: cmd}); result.RuleID == "deny-doit-self-approval" {
+			t.Errorf("%q: unexpectedly denied", cmd)
+		}
+	}
+}
+
 func TestDenyMakeFlags(t *testing.T) {
 	l1 := defaultLevel1()
 	tests := []struct {
@@ -127,6 +153,12 @@ func TestDenyGitPushFlags(t *testing.T) {
 		{"git push --force", "git push --force", true},
 		{"git push -f", "git push -f", true},
 		{"git push --force-with-lease", "git push --force-with-lease", true},
+		{"git push --force=true", "git push --force=true", true},
+		{"git push -uf (combined)", "git push -uf origin main", true},
+		{"git push --force-w (abbreviated)", "git push --force-w", true},
+		{"git -C dir push -f (global flags first)", "git -C dir push -f", true},
+		{"git --git-dir .git push --force", "git --git-dir .git push --force", true},
+		{"git push --repo origin", "git push --repo origin", false},
 		{"git push", "git push", false},
 		{"git push origin master", "git push origin master", false},
 	}
@@ -227,6 +259,40 @@ func TestRetryBypassesConfigRules(t *testing.T) {
 	}
 }
 
+func TestConfigRuleNotBypassable(t *testing.T) {
+	no := false
+	l1 := NewLevel1(map[string]rules.CapRuleConfig{
+		"git": {
+			Subcommands: map[string]rules.SubRuleConfig{
+				"push":  {RejectFlags: []string{"--force"}, Bypassable: &no},
+				"reset": {RejectFlags: []string{"--hard"}},
+			},
+		},
+	})
+
+	result := l1.Evaluate(&Request{Command: "git push --force", Retry: true})
+	if result.Decision != Deny || result.Bypassable {
+		t.Errorf("push --force on retry: got decision=%v bypassable=%v, want a non-bypassable deny", result.Decision, result.Bypassable)
+	}
+	if result := l1.Evaluate(&Request{Command: "git reset --hard", Retry: true}); result.Decision == Deny {
+		t.Errorf("reset --hard on retry: got deny, want it bypassed: %s", result.Reason)
+	}
+}
+
+func TestTargetedRetryBypassesOnlyNamedRule(t *testing.T) {
+	l1 := defaultLevel1()
+
+	result := l1.Evaluate(&Request{Command: "git checkout .", Retry: true, RetryRule: "deny-git-checkout-all"})
+	if result.Decision == Deny {
+		t.Errorf("named rule: got deny, want it bypassed: %s", result.Reason)
+	}
+
+	result = l1.Evaluate(&Request{Command: "git checkout .", Retry: true, RetryRule: "deny-make-flags"})
+	if result.Decision != Deny || result.RuleID != "deny-git-checkout-all" {
+		t.Errorf("other rule: got decision=%v rule=%q, want deny by deny-git-checkout-all", result.Decision, result.RuleID)
+	}
+}
+
 func TestEscalateWhenNoRuleMatches(t *testing.T) {
 	l1 := defaultLevel1()
 	result := l1.Evaluate(&Request{Command: "make"})
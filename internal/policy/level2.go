@@ -5,18 +5,63 @@ package policy
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/rules"
 )
 
 // Level2 evaluates commands against the learned policy store.
 type Level2 struct {
 	entries []PolicyEntry
+
+	// A command can match only the entries for its cap and those matching
+	// pipelines, so they are indexed to spare large stores a full scan.
+	byCap     map[string][]int // indexes in entries of each cap's entries, in order
+	pipelines []int            // indexes of the entries matching pipelines, in order
+	byID      map[string]int
 }
 
 // NewLevel2 creates a Level2 engine from ordered policy entries.
 func NewLevel2(entries []PolicyEntry) *Level2 {
-	return &Level2{entries: entries}
+	l := &Level2{
+		entries: entries,
+		byCap:   make(map[string][]int),
+		byID:    make(map[string]int, len(entries)),
+	}
+	for i := range entries {
+		m := &entries[i].Match
+		if len(m.Pipeline) > 0 {
+			l.pipelines = append(l.pipelines, i)
+		} else {
+			l.byCap[m.Cap] = append(l.byCap[m.Cap], i)
+		}
+		if _, dup := l.byID[entries[i].ID]; !dup {
+			l.byID[entries[i].ID] = i
+		}
+	}
+	return l
+}
+
+// candidates returns the indexes, in order, of the entries a command
+// whose first segment runs capName might match.
+func (l *Level2) candidates(capName string) []int {
+	a, b := l.byCap[capName], l.pipelines
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	out := make([]int, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] < b[0] {
+			out, a = append(out, a[0]), a[1:]
+		} else {
+			out, b = append(out, b[0]), b[1:]
+		}
+	}
+	return append(append(out, a...), b...)
 }
 
 // EntryCount returns the number of loaded policy entries.
@@ -24,10 +69,20 @@ func (l *Level2) EntryCount() int {
 	return len(l.entries)
 }
 
+// Expiry returns when the entry with the given ID expires, or the zero
+// time if it does not, or there is no such entry.
+func (l *Level2) Expiry(id string) time.Time {
+	if i, ok := l.byID[id]; ok {
+		return l.entries[i].ExpiresAt
+	}
+	return time.Time{}
+}
+
 // Evaluate runs matching against the learned policy store.
 //
-// When req.Retry is true, Level 2 is bypassed entirely (returns Escalate).
-// Learned policies are not hardcoded safety rules.
+// A retry skips the entries it bypasses (every entry for a blanket retry,
+// the named one for a targeted retry), except entries marked
+// bypassable: false. Learned policies are not hardcoded safety rules.
 //
 // The engine treats req.Command as an opaque string. L2 matches by parsing
 // the first one or two tokens of the raw command string against the stored
@@ -38,14 +93,6 @@ func (l *Level2) EntryCount() int {
 // does not auto-allow commands whose first token looks read-only — any command
 // that is not matched by a specific approved entry escalates to L3.
 func (l *Level2) Evaluate(req *Request) *Result {
-	if req.Retry {
-		return &Result{
-			Decision: Escalate,
-			Level:    2,
-			Reason:   "--retry bypasses Level 2",
-		}
-	}
-
 	if req.Command == "" {
 		return &Result{
 			Decision: Escalate,
@@ -60,45 +107,84 @@ func (l *Level2) Evaluate(req *Request) *Result {
 	// the full command, because the full command may contain shell
 	// composition that L2 is not equipped to reason about.
 	seg := parseFirstSegment(req.Command)
+	seg.Cwd = req.Cwd
+
+	return l.matchSegment(&seg, req.Command, req.now(), req.Bypasses)
+}
 
-	return l.matchSegment(&seg)
+// Candidates returns the unapproved entries that match req, such as
+// auto-promoted patterns awaiting review. They decide nothing, but tell
+// Level 3 how similar commands were judged before.
+func (l *Level2) Candidates(req *Request) []PolicyEntry {
+	seg := parseFirstSegment(req.Command)
+	seg.Cwd = req.Cwd
+	now := req.now()
+	var out []PolicyEntry
+	for _, i := range l.candidates(seg.CapName) {
+		entry := &l.entries[i]
+		if entry.Approved || entry.Expired(now) {
+			continue
+		}
+		if entryMatches(entry, &seg, req.Command) {
+			out = append(out, *entry)
+		}
+	}
+	return out
 }
 
 // parseFirstSegment builds a Segment from the leading tokens of the raw
-// command string. It does not interpret shell operators; callers rely on
-// the segment for stored-criteria matching only.
+// command string. It does not interpret shell operators, but notes their
+// presence; callers rely on the segment for stored-criteria matching only.
 func parseFirstSegment(command string) Segment {
-	parts := strings.Fields(command)
+	parts := rules.Normalize(strings.Fields(command))
 	if len(parts) == 0 {
 		return Segment{}
 	}
 	return Segment{
-		CapName: parts[0],
-		Args:    parts[1:],
+		CapName:  parts[0],
+		Args:     parts[1:],
+		Compound: isCompound(command),
 	}
 }
 
-// matchSegment finds the first matching approved entry for a segment.
+// matchSegment finds the first matching approved entry for a segment, or,
+// for an entry matching pipelines, for the command it begins.
+// Grants expired by now are ignored, as are all grants when the command is
+// compound, since a grant vouches only for the command it names. Bypassable
+// entries for which bypass returns true are ignored too (a retry).
 // Returns Allow/Deny/Escalate per the matched entry, or Escalate if nothing
 // matches. Unlike the pre-🎯T17 code, there is no implicit TierRead allow —
 // all commands that lack a specific learned-policy match escalate to L3 so
 // that shell composition is evaluated by the LLM gatekeeper.
-func (l *Level2) matchSegment(seg *Segment) *Result {
-	for _, entry := range l.entries {
-		if !entry.Approved {
+func (l *Level2) matchSegment(seg *Segment, command string, now time.Time, bypass func(ruleID string) bool) *Result {
+	compound := isCompound(command)
+	for _, i := range l.candidates(seg.CapName) {
+		entry := &l.entries[i]
+		if !entry.Approved || entry.Expired(now) {
+			continue
+		}
+		if entry.IsBypassable() && bypass(entry.ID) {
+			continue
+		}
+		if compound && IsGrant(entry.ID) {
 			continue
 		}
-		if matchesCriteria(seg, &entry.Match) {
+		if entryMatches(entry, seg, command) {
 			dec, err := ParseDecision(entry.Decision)
 			if err != nil {
 				continue // skip entries with invalid decisions
 			}
-			return &Result{
+			r := &Result{
 				Decision: dec,
 				Level:    2,
 				Reason:   fmt.Sprintf("matched learned policy %q: %s", entry.ID, entry.Reasoning),
 				RuleID:   entry.ID,
 			}
+			if dec == Allow && !entry.Conditions.IsZero() {
+				r.Conditions = entry.Conditions
+				r.Reason += fmt.Sprintf(" (on conditions: %s)", entry.Conditions)
+			}
+			return r
 		}
 	}
 
@@ -112,8 +198,8 @@ func (l *Level2) matchSegment(seg *Segment) *Result {
 // matchesCriteria checks whether a segment satisfies all constraints in the
 // match criteria. All specified fields must hold.
 func matchesCriteria(seg *Segment, m *MatchCriteria) bool {
-	// Cap must match exactly.
-	if seg.CapName != m.Cap {
+	// Cap must match exactly, unless it is a pipeline stage's "*".
+	if m.Cap != anyCap && seg.CapName != m.Cap {
 		return false
 	}
 
@@ -130,7 +216,7 @@ func matchesCriteria(seg *Segment, m *MatchCriteria) bool {
 		if m.Subcmd != "" && len(args) > 0 {
 			args = args[1:]
 		}
-		if !HasAnyFlag(args, m.HasFlags...) {
+		if !rules.HasFlag(strings.TrimSpace(m.Cap+" "+m.Subcmd), args, m.HasFlags...) {
 			return false
 		}
 	}
@@ -141,22 +227,35 @@ func matchesCriteria(seg *Segment, m *MatchCriteria) bool {
 		if m.Subcmd != "" && len(args) > 0 {
 			args = args[1:]
 		}
-		if HasAnyFlag(args, m.NoFlags...) {
+		if rules.HasFlag(strings.TrimSpace(m.Cap+" "+m.Subcmd), args, m.NoFlags...) {
 			return false
 		}
 	}
 
 	// ArgsGlob: every non-flag positional arg (after subcmd) must match
-	// at least one glob pattern.
+	// at least one pattern and no negated one (see matchArgs).
 	if len(m.ArgsGlob) > 0 {
 		positional := extractPositionalArgs(seg.Args, m.Subcmd)
 		if len(positional) == 0 {
 			return false // no positional args to match against
 		}
-		for _, arg := range positional {
-			if !matchAnyGlob(arg, m.ArgsGlob) {
-				return false
-			}
+		if !matchArgs(positional, m.ArgsGlob) {
+			return false
+		}
+	}
+
+	// PathsWithin, PathsOutside: every positional arg, as an absolute
+	// path, must lie within one of the first and outside all of the
+	// second. Without a working directory, relative args mean nothing,
+	// and in a compound command the args are not all the paths it
+	// touches, so the entry does not match.
+	if len(m.PathsWithin) > 0 || len(m.PathsOutside) > 0 {
+		positional := extractPositionalArgs(seg.Args, m.Subcmd)
+		if len(positional) == 0 || seg.Cwd == "" || seg.Compound {
+			return false
+		}
+		if !matchPaths(positional, seg.Cwd, m.PathsWithin, m.PathsOutside) {
+			return false
 		}
 	}
 
@@ -184,20 +283,12 @@ func extractPositionalArgs(args []string, subcmd string) []string {
 	return pos
 }
 
-// matchAnyGlob checks if s matches any of the glob patterns.
-func matchAnyGlob(s string, patterns []string) bool {
-	for _, p := range patterns {
-		if matched, _ := filepath.Match(p, s); matched {
-			return true
-		}
-	}
-	return false
-}
-
 // Segment is used internally by L2 for matching against stored criteria.
 // It is not part of the public policy.Request — the engine treats the
 // full command as opaque and never exposes a parsed segment externally.
 type Segment struct {
-	CapName string
-	Args    []string
+	CapName  string
+	Args     []string
+	Cwd      string // where relative args resolve
+	Compound bool   // the command does more than this segment, so its args are not all its paths
 }
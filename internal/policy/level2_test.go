@@ -4,6 +4,7 @@
 package policy
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -140,6 +141,35 @@ func TestLevel2NoFlagsMatch(t *testing.T) {
 	}
 }
 
+func TestLevel2FlagsNormalized(t *testing.T) {
+	l2 := NewLevel2([]PolicyEntry{
+		{
+			ID:       "deny-git-push-force",
+			Match:    MatchCriteria{Cap: "git", Subcmd: "push", HasFlags: []string{"-f"}},
+			Decision: "deny",
+			Approved: true,
+		},
+		{
+			ID:       "allow-git-push-origin",
+			Match:    MatchCriteria{Cap: "git", Subcmd: "push", ArgsGlob: []string{"origin", "main"}},
+			Decision: "allow",
+			Approved: true,
+		},
+	})
+	for _, cmd := range []string{"git push --force origin main", "git push -uf origin main", "git -C src push -f origin main"} {
+		if r := l2.Evaluate(&Request{Command: cmd}); r.Decision != Deny {
+			t.Errorf("%s: got %v by %q, want deny", cmd, r.Decision, r.RuleID)
+		}
+	}
+	// The value of --repo is not a positional argument.
+	if r := l2.Evaluate(&Request{Command: "git push --repo origin main"}); r.Decision != Allow {
+		t.Errorf("git push --repo origin main: got %v by %q, want allow", r.Decision, r.RuleID)
+	}
+	if r := l2.Evaluate(&Request{Command: "git push --repo upstream main"}); r.Decision != Allow {
+		t.Errorf("git push --repo upstream main: got %v by %q, want allow", r.Decision, r.RuleID)
+	}
+}
+
 func TestLevel2UnapprovedSkipped(t *testing.T) {
 	l2 := NewLevel2(testEntries())
 	result := l2.Evaluate(&Request{Command: "python script.py"})
@@ -159,6 +189,40 @@ func TestLevel2RetryBypasses(t *testing.T) {
 	}
 }
 
+func TestLevel2TargetedRetrySkipsNamedEntry(t *testing.T) {
+	l2 := NewLevel2(testEntries())
+
+	result := l2.Evaluate(&Request{Command: "npm install -g foo", Retry: true, RetryRule: "deny-npm-global"})
+	if result.Decision == Deny {
+		t.Errorf("named entry: got deny, want it skipped: %s", result.Reason)
+	}
+
+	result = l2.Evaluate(&Request{Command: "npm install -g foo", Retry: true, RetryRule: "allow-go-test"})
+	if result.Decision != Deny || result.RuleID != "deny-npm-global" {
+		t.Errorf("other entry: got decision=%v rule=%q, want deny by deny-npm-global", result.Decision, result.RuleID)
+	}
+}
+
+func TestLevel2EntryNotBypassable(t *testing.T) {
+	no := false
+	entries := testEntries()
+	for i := range entries {
+		if entries[i].ID == "deny-npm-global" {
+			entries[i].Bypassable = &no
+		}
+	}
+	l2 := NewLevel2(entries)
+
+	for _, req := range []*Request{
+		{Command: "npm install -g foo", Retry: true},
+		{Command: "npm install -g foo", Retry: true, RetryRule: "deny-npm-global"},
+	} {
+		if result := l2.Evaluate(req); result.Decision != Deny {
+			t.Errorf("retry %q: got %v, want deny", req.RetryRule, result.Decision)
+		}
+	}
+}
+
 // TestLevel2CompositeCommandEscalates verifies that commands containing shell
 // composition operators are not auto-allowed by L2, even if the first token
 // matches a learned allow entry. Shell composition is opaque to L2 — the full
@@ -248,3 +312,64 @@ func TestExtractPositionalArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestLevel2IndexKeepsOrder(t *testing.T) {
+	l2 := NewLevel2([]PolicyEntry{
+		{ID: "allow-make-test", Match: MatchCriteria{Cap: "make", Subcmd: "test"}, Decision: "allow", Approved: true},
+		{ID: "deny-to-sh", Match: MatchCriteria{Pipeline: []MatchCriteria{{Cap: "*"}, {Cap: "sh"}}}, Decision: "deny", Approved: true},
+		{ID: "allow-make", Match: MatchCriteria{Cap: "make"}, Decision: "allow", Approved: true},
+		{ID: "allow-go", Match: MatchCriteria{Cap: "go"}, Decision: "allow", Approved: true},
+	})
+	for _, tc := range []struct{ command, rule string }{
+		{"make test | sh", "allow-make-test"}, // earlier entry for the cap wins
+		{"make all | sh", "deny-to-sh"},       // earlier pipeline entry wins
+		{"make all", "allow-make"},
+		{"go env | sh", "deny-to-sh"},
+		{"go build", "allow-go"},
+	} {
+		if r := l2.Evaluate(&Request{Command: tc.command}); r.RuleID != tc.rule {
+			t.Errorf("%q: matched %q, want %q", tc.command, r.RuleID, tc.rule)
+		}
+	}
+}
+
+// benchEntries returns n approved entries spread over caps tool0 to
+// tool99, each with a subcommand, so that most of a cap's entries do
+// not match.
+func benchEntries(n int) []PolicyEntry {
+	entries := make([]PolicyEntry, n)
+	for i := range entries {
+		entries[i] = PolicyEntry{
+			ID:       fmt.Sprintf("entry-%d", i),
+			Match:    MatchCriteria{Cap: fmt.Sprintf("tool%d", i%100), Subcmd: fmt.Sprintf("sub%d", i), NoFlags: []string{"--force"}},
+			Decision: "allow",
+			Approved: true,
+		}
+	}
+	return entries
+}
+
+func BenchmarkLevel2Evaluate(b *testing.B) {
+	for _, n := range []int{10, 100, 1000, 10000} {
+		l2 := NewLevel2(benchEntries(n))
+		last := &Request{Command: fmt.Sprintf("tool%d sub%d x", (n-1)%100, n-1)}
+		miss := &Request{Command: "unknown sub x"}
+		b.Run(fmt.Sprintf("entries=%d/last", n), func(b *testing.B) {
+			for b.Loop() {
+				l2.Evaluate(last)
+			}
+		})
+		b.Run(fmt.Sprintf("entries=%d/miss", n), func(b *testing.B) {
+			for b.Loop() {
+				l2.Evaluate(miss)
+			}
+		})
+	}
+}
+
+func BenchmarkNewLevel2(b *testing.B) {
+	entries := benchEntries(1000)
+	for b.Loop() {
+		NewLevel2(entries)
+	}
+}
@@ -11,13 +11,15 @@ import (
 )
 
 type mockPrompter struct {
-	response string
-	err      error
-	called   bool
+	response   string
+	err        error
+	called     bool
+	lastPrompt string
 }
 
 func (m *mockPrompter) Prompt(ctx context.Context, prompt string) (string, error) {
 	m.called = true
+	m.lastPrompt = prompt
 	return m.response, m.err
 }
 
@@ -59,6 +61,17 @@ func TestParseL3Decision(t *testing.T) {
 			wantDecision: Deny,
 			wantReason:   "bad",
 		},
+		{
+			name:         "with confidence",
+			input:        `{"decision":"allow","confidence":0.85,"reasoning":"ok"}`,
+			wantDecision: Allow,
+			wantReason:   "ok",
+		},
+		{
+			name:    "confidence out of range",
+			input:   `{"decision":"allow","confidence":85,"reasoning":"ok"}`,
+			wantErr: true,
+		},
 		{
 			name:    "invalid json",
 			input:   "not json",
@@ -73,7 +86,7 @@ func TestParseL3Decision(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			dec, reason, err := parseL3Decision(tt.input)
+			dec, reason, _, err := parseL3Decision(tt.input)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatal("expected error, got nil")
@@ -119,6 +132,60 @@ func TestBuildPrompt(t *testing.T) {
 	}
 }
 
+func TestBuildPromptContext(t *testing.T) {
+	req := &Request{
+		Command: "git reset --hard",
+		Context: &PromptContext{
+			Git:        "## main...origin/main [ahead 2]\n M engine.go",
+			History:    []string{"[exit 0] go test ./...", "[deny] git push --force"},
+			Candidates: []string{"auto-git-reset (deny, high confidence): discards work"},
+		},
+	}
+	prompt := buildPrompt(req, false)
+	for _, s := range []string{
+		"    ## main...origin/main [ahead 2]\n     M engine.go\n",
+		"    [deny] git push --force\n",
+		"await human approval:\n    auto-git-reset",
+	} {
+		if !strings.Contains(prompt, s) {
+			t.Errorf("prompt missing %q:\n%s", s, prompt)
+		}
+	}
+
+	if prompt := buildPrompt(&Request{Command: "ls", Context: &PromptContext{}}, false); strings.Contains(prompt, "Context (") {
+		t.Errorf("empty context should be left out:\n%s", prompt)
+	}
+}
+
+func TestLevel3LowConfidenceAllow(t *testing.T) {
+	fast := &mockPrompter{response: `{"decision":"allow","confidence":0.4,"reasoning":"probably fine"}`}
+	deep := &mockPrompter{response: `{"decision":"allow","confidence":0.9,"reasoning":"fine"}`}
+	l3 := NewLevel3(fast, deep)
+	l3.SetMinAllowConfidence(0.7)
+
+	r := l3.Evaluate(context.Background(), &Request{Command: "make deploy"})
+	if r.Decision != Allow || !deep.called || r.Confidence == nil || *r.Confidence != 0.9 {
+		t.Errorf("got %+v (deep called %v), want the deep model's confident allow", r, deep.called)
+	}
+
+	deep.response = `{"decision":"allow","confidence":0.5,"reasoning":"hmm"}`
+	r = l3.Evaluate(context.Background(), &Request{Command: "make deploy"})
+	if r.Decision != Escalate || !strings.Contains(r.Reason, "low confidence (0.50 < 0.70)") {
+		t.Errorf("got %+v, want a low-confidence escalation", r)
+	}
+
+	// Denials stand however unsure, and an allow without a confidence is
+	// taken at its word.
+	fast.response = `{"decision":"deny","confidence":0.2,"reasoning":"risky"}`
+	if r := l3.Evaluate(context.Background(), &Request{Command: "make deploy"}); r.Decision != Deny {
+		t.Errorf("got %+v, want deny", r)
+	}
+	fast.response = `{"decision":"allow","reasoning":"fine"}`
+	if r := l3.Evaluate(context.Background(), &Request{Command: "make deploy"}); r.Decision != Allow || r.Confidence != nil {
+		t.Errorf("got %+v, want allow without confidence", r)
+	}
+}
+
 func TestLevel3EvaluateRetry(t *testing.T) {
 	mock := &mockPrompter{}
 	l3 := NewLevel3(mock)
@@ -294,6 +361,22 @@ func TestLevel3EvaluateInSessionFallback(t *testing.T) {
 	}
 }
 
+func TestLevel3TargetedRetry(t *testing.T) {
+	mock := &mockPrompter{response: `{"decision":"deny","reasoning":"no"}`}
+	l3 := NewLevel3(mock)
+
+	result := l3.Evaluate(context.Background(), &Request{Command: "rm -rf .", Retry: true, RetryRule: "deny-make-flags"})
+	if !mock.called || result.Decision != Deny {
+		t.Errorf("retry past an L1 rule: called=%v decision=%v, want the gatekeeper consulted", mock.called, result.Decision)
+	}
+
+	mock.called = false
+	result = l3.Evaluate(context.Background(), &Request{Command: "rm -rf .", Retry: true, RetryRule: "llm-gatekeeper-fast"})
+	if mock.called || result.Decision != Allow {
+		t.Errorf("retry past the gatekeeper: called=%v decision=%v, want allow without a call", mock.called, result.Decision)
+	}
+}
+
 func TestLevel3EvaluateInSessionRetry(t *testing.T) {
 	mock := &mockSessionPrompter{}
 	l3 := NewLevel3(mock)
@@ -334,3 +417,33 @@ func TestBuildSessionPrefix(t *testing.T) {
 		t.Error("prefix should contain scope instructions")
 	}
 }
+
+// FuzzParseL3Decision feeds arbitrary model output to the response
+// parser, which must not panic and must accept only a known decision
+// with a confidence in [0, 1].
+func FuzzParseL3Decision(f *testing.F) {
+	for _, seed := range []string{
+		`{"decision":"allow","reasoning":"read-only"}`,
+		"```json\n{\"decision\":\"deny\",\"confidence\":0.9,\"reasoning\":\"x\"}\n```",
+		"```\n```",
+		"```",
+		`{"decision":"escalate","confidence":1e309}`,
+		`{"decision":"ALLOW","confidence":-0}`,
+		`{"decision":["allow"]}`,
+		`null`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		dec, _, conf, err := parseL3Decision(raw)
+		if err != nil {
+			return
+		}
+		if dec != Allow && dec != Deny && dec != Escalate {
+			t.Errorf("parseL3Decision(%q): decision %v", raw, dec)
+		}
+		if conf != nil && !(*conf >= 0 && *conf <= 1) {
+			t.Errorf("parseL3Decision(%q): confidence %g", raw, *conf)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/marcelocantos/doit/internal/rules"
+)
+
+// ManifestFile is where a repository keeps its committed policy,
+// relative to the repository root.
+const ManifestFile = ".doit/policy.yaml"
+
+// ManifestInvalidRuleID marks the escalation of a command in a
+// repository whose manifest cannot be read.
+const ManifestInvalidRuleID = "repo-policy-invalid"
+
+// Manifest is a repository's committed policy: guardrails its
+// maintainers ship for agents working in it. It can only restrict what
+// the user's own policy decides — deny commands, send them for review,
+// or confine agents to an allowlist — and never allows anything.
+type Manifest struct {
+	Deny     []ManifestRule `yaml:"deny,omitempty"`
+	Escalate []ManifestRule `yaml:"escalate,omitempty"`
+
+	// Allow, if set, lists the only commands that may be decided without
+	// review: a command line containing any other goes to review. Each
+	// is a program and, optionally, its leading arguments, e.g. "go test".
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// ManifestRule selects commands by their leading words and, optionally,
+// their flags.
+type ManifestRule struct {
+	ID      string   `yaml:"id,omitempty"`     // names the rule in audit entries (default: deny-N or escalate-N)
+	Command string   `yaml:"command"`          // program and leading arguments, e.g. "git push"
+	Flags   []string `yaml:"flags,omitempty"`  // only with one of these flags, e.g. --force
+	Reason  string   `yaml:"reason,omitempty"` // shown to the agent
+}
+
+// FindManifest returns the manifest of the repository containing dir, or
+// nil if it has none. It looks in dir and each parent up to the root of
+// the repository (the first directory holding .git) or of the file
+// system.
+func FindManifest(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		path := filepath.Join(dir, ManifestFile)
+		if _, err := os.Stat(path); err == nil {
+			return LoadManifest(path)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadManifest reads the manifest at path.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read repository policy: %w", err)
+	}
+	m := &Manifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse repository policy %s: %w", path, err)
+	}
+	ids := map[string]bool{}
+	for _, kind := range []struct {
+		name  string
+		rules []ManifestRule
+	}{{"deny", m.Deny}, {"escalate", m.Escalate}} {
+		for i := range kind.rules {
+			r := &kind.rules[i]
+			if len(strings.Fields(r.Command)) == 0 {
+				return nil, fmt.Errorf("repository policy %s: %s[%d]: missing command", path, kind.name, i)
+			}
+			if r.ID == "" {
+				r.ID = fmt.Sprintf("%s-%d", kind.name, i+1)
+			}
+			if ids[r.ID] {
+				return nil, fmt.Errorf("repository policy %s: duplicate id %q", path, r.ID)
+			}
+			ids[r.ID] = true
+		}
+	}
+	for i, a := range m.Allow {
+		if len(strings.Fields(a)) == 0 {
+			return nil, fmt.Errorf("repository policy %s: allow[%d]: empty command", path, i)
+		}
+	}
+	return m, nil
+}
+
+// RestrictByManifest applies the manifest of the repository containing
+// req.Cwd to result, the decision of the user's own policy: it returns
+// result, or the manifest's decision where that is stricter. A denial
+// outranks everything; an escalation outranks an allow, and an
+// escalation that names no rule. A manifest that cannot be read sends
+// the command for review rather than being ignored.
+func RestrictByManifest(req *Request, result *Result) *Result {
+	if result.Decision == Deny {
+		return result
+	}
+	dir := req.Cwd
+	if dir == "" {
+		dir = "."
+	}
+	m, err := FindManifest(dir)
+	var r *Result
+	switch {
+	case err != nil:
+		r = &Result{
+			Decision: Escalate,
+			Level:    1,
+			Reason:   fmt.Sprintf("this repository's policy is unreadable: %v", err),
+			RuleID:   ManifestInvalidRuleID,
+		}
+	case m != nil:
+		r = m.Evaluate(req)
+	}
+	if r == nil || r.Decision == Escalate && result.Decision == Escalate && result.RuleID != "" {
+		return result
+	}
+	return r
+}
+
+// Evaluate returns the manifest's decision on req: Deny or Escalate, or
+// nil if it has no objection.
+func (m *Manifest) Evaluate(req *Request) *Result {
+	cmds := SimpleCommands(req.Command)
+	for i, cmd := range cmds {
+		cmds[i] = rules.Normalize(cmd)
+	}
+	for _, kind := range []struct {
+		rules    []ManifestRule
+		decision Decision
+		verb     string
+	}{{m.Deny, Deny, "denies"}, {m.Escalate, Escalate, "sends for review"}} {
+		for _, r := range kind.rules {
+			for _, cmd := range cmds {
+				if !r.matches(cmd) {
+					continue
+				}
+				reason := fmt.Sprintf("this repository's policy (%s) %s %s", ManifestFile, kind.verb, r.Command)
+				if r.Reason != "" {
+					reason += ": " + r.Reason
+				}
+				return &Result{Decision: kind.decision, Level: 1, Reason: reason, RuleID: "repo-" + r.ID}
+			}
+		}
+	}
+	if len(m.Allow) == 0 {
+		return nil
+	}
+	for _, cmd := range cmds {
+		if !m.allows(cmd) {
+			return &Result{
+				Decision: Escalate,
+				Level:    1,
+				Reason:   fmt.Sprintf("this repository's policy (%s) does not list %s among its allowed commands", ManifestFile, strings.Join(cmd, " ")),
+				RuleID:   "repo-allowlist",
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Manifest) allows(cmd []string) bool {
+	for _, a := range m.Allow {
+		if hasPrefixWords(cmd, strings.Fields(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ManifestRule) matches(cmd []string) bool {
+	prefix := strings.Fields(r.Command)
+	if !hasPrefixWords(cmd, prefix) {
+		return false
+	}
+	if len(r.Flags) == 0 {
+		return true
+	}
+	command := strings.Join(prefix[:min(len(prefix), 2)], " ")
+	return rules.HasFlag(command, cmd[len(prefix):], r.Flags...)
+}
+
+// hasPrefixWords reports whether cmd begins with the words of prefix,
+// the program matching by base name.
+func hasPrefixWords(cmd, prefix []string) bool {
+	if len(cmd) < len(prefix) || len(prefix) == 0 {
+		return false
+	}
+	if filepath.Base(cmd[0]) != prefix[0] {
+		return false
+	}
+	for i := 1; i < len(prefix); i++ {
+		if cmd[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SimpleCommands returns every command command runs, as its words: each
+// simple command and, through wrappers such as env or sh -c, the
+// commands they run. Like commandWords, it errs towards finding more.
+func SimpleCommands(command string) [][]string {
+	var out [][]string
+	for _, words := range commandWords(command) {
+		at := spawnedAt(words)
+		for n, i := range at {
+			end := len(words)
+			if n+1 < len(at) {
+				end = at[n+1]
+			}
+			out = append(out, words[i:end])
+		}
+	}
+	return out
+}
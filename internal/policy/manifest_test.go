@@ -0,0 +1,96 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeManifest makes a repository holding the manifest data and returns
+// a directory inside it.
+func writeManifest(t *testing.T, data string) string {
+	t.Helper()
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0o755)
+	os.Mkdir(filepath.Join(repo, ".doit"), 0o755)
+	if err := os.WriteFile(filepath.Join(repo, ManifestFile), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(repo, "pkg", "sub")
+	os.MkdirAll(sub, 0o755)
+	return sub
+}
+
+func TestRestrictByManifest(t *testing.T) {
+	dir := writeManifest(t, `
+deny:
+  - command: git push
+    flags: [--force, -f]
+    reason: history is shared
+  - id: no-publish
+    command: npm publish
+escalate:
+  - command: git push
+allow: [go, git, make test, cd]
+`)
+	allow := &Result{Decision: Allow, Level: 2, RuleID: "learned"}
+	open := &Result{Decision: Escalate, Level: 2}
+	named := &Result{Decision: Escalate, Level: 1, RuleID: "escalate-nested-shell"}
+	deny := &Result{Decision: Deny, Level: 1, RuleID: "deny-rm-catastrophic"}
+	for _, tc := range []struct {
+		command string
+		prior   *Result
+		want    string // decision/rule
+	}{
+		{"go test ./...", allow, "allow/learned"},
+		{"cd x && go test ./...", open, "escalate/"},
+		{"git push -f origin main", allow, "deny/repo-deny-1"},
+		{"cd x && env GIT=1 git push --force", allow, "deny/repo-deny-1"},
+		{"sh -c 'npm publish'", named, "deny/repo-no-publish"},
+		{"git push origin main", allow, "escalate/repo-escalate-1"},
+		{"git push origin main", open, "escalate/repo-escalate-1"},
+		{"git push origin main", named, "escalate/escalate-nested-shell"},
+		{"make build", allow, "escalate/repo-allowlist"},
+		{"make test", allow, "allow/learned"},
+		{"go vet | tee out", allow, "escalate/repo-allowlist"},
+		{"rm -rf /", deny, "deny/deny-rm-catastrophic"},
+	} {
+		r := RestrictByManifest(&Request{Command: tc.command, Cwd: dir}, tc.prior)
+		if got := r.Decision.String() + "/" + r.RuleID; got != tc.want {
+			t.Errorf("%q after %s: got %s, want %s", tc.command, tc.prior.Decision, got, tc.want)
+		}
+	}
+
+	r := RestrictByManifest(&Request{Command: "git push --force", Cwd: dir}, allow)
+	if !strings.Contains(r.Reason, "denies git push: history is shared") {
+		t.Errorf("reason = %q", r.Reason)
+	}
+}
+
+func TestRestrictByManifest_Scope(t *testing.T) {
+	prior := &Result{Decision: Allow, Level: 1}
+
+	// The search stops at the repository root.
+	outer := writeManifest(t, "deny:\n  - command: ls\n")
+	inner := filepath.Join(outer, "inner")
+	os.MkdirAll(filepath.Join(inner, ".git"), 0o755)
+	if r := RestrictByManifest(&Request{Command: "ls", Cwd: inner}, prior); r != prior {
+		t.Errorf("manifest above the repository applied: %+v", r)
+	}
+	if r := RestrictByManifest(&Request{Command: "ls", Cwd: outer}, prior); r.Decision != Deny {
+		t.Errorf("manifest not applied: %+v", r)
+	}
+
+	// An unreadable manifest sends everything for review.
+	for _, bad := range []string{"deny: [{command: ' '}]", "deny: {", "escalate: [{id: x, command: a}, {id: x, command: b}]", "allow: ['']"} {
+		dir := writeManifest(t, bad)
+		r := RestrictByManifest(&Request{Command: "ls", Cwd: dir}, prior)
+		if r.Decision != Escalate || r.RuleID != ManifestInvalidRuleID {
+			t.Errorf("manifest %q: %+v", bad, r)
+		}
+	}
+}
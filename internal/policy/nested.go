@@ -0,0 +1,160 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shells are the interpreters that, run as a command, execute an arbitrary
+// script doit never sees.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true, "csh": true, "tcsh": true,
+}
+
+// wrappers run their arguments as a command, so a shell or doit they name
+// is spawned just the same.
+var wrappers = map[string]bool{
+	"env": true, "exec": true, "command": true, "nohup": true, "nice": true, "time": true,
+	"timeout": true, "sudo": true, "xargs": true, "watch": true, "parallel": true, "setsid": true,
+}
+
+// simpleCommandSep splits a command line into simple commands at shell
+// operators and grouping punctuation.
+var simpleCommandSep = regexp.MustCompile("&&|\\|\\||[;&|()`{}\n]|\\$\\(")
+
+// commandWords returns the words of each simple command in command, with
+// quotes removed so that commands inside sh -c '...' are seen too. This is
+// a deliberately coarse reading: the rules using it err towards matching.
+func commandWords(command string) [][]string {
+	command = strings.NewReplacer(`'`, " ", `"`, " ").Replace(command)
+	var out [][]string
+	for _, part := range simpleCommandSep.Split(command, -1) {
+		words := strings.Fields(part)
+		// Skip leading VAR=value assignments.
+		for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "-") {
+			words = words[1:]
+		}
+		if len(words) > 0 {
+			out = append(out, words)
+		}
+	}
+	return out
+}
+
+// spawned returns the programs a simple command runs: its first word and,
+// through wrappers such as env or xargs, find -exec, and sh -c, the
+// programs they are given.
+func spawned(words []string) []string {
+	var progs []string
+	for _, i := range spawnedAt(words) {
+		progs = append(progs, filepath.Base(words[i]))
+	}
+	return progs
+}
+
+// spawnedAt is like spawned but returns the programs' indexes in words.
+func spawnedAt(words []string) []int {
+	var at []int
+	for i := 0; i < len(words); i++ {
+		prog := filepath.Base(words[i])
+		at = append(at, i)
+		if prog == "find" {
+			for j := i + 1; j < len(words); j++ {
+				if w := words[j]; (w == "-exec" || w == "-execdir" || w == "-ok") && j+1 < len(words) {
+					for _, k := range spawnedAt(words[j+1:]) {
+						at = append(at, j+1+k)
+					}
+				}
+			}
+			return at
+		}
+		if shells[prog] && i+2 < len(words) && words[i+1] == "-c" {
+			i++ // the script runs as a command in its own right
+			continue
+		}
+		if !wrappers[prog] {
+			return at
+		}
+		// The wrapped program is the next word that is not an option,
+		// an assignment, or an option value such as a timeout.
+		for i+1 < len(words) && !isProgramWord(words[i+1]) {
+			i++
+		}
+	}
+	return at
+}
+
+// isProgramWord reports whether w could name a program rather than be an
+// option, assignment, or option value.
+func isProgramWord(w string) bool {
+	if strings.HasPrefix(w, "-") || strings.Contains(w, "=") {
+		return false
+	}
+	return strings.ContainsFunc(w, func(r rune) bool { return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' })
+}
+
+// checkDoitRecursion denies running doit through doit. A confused agent
+// invoking the broker from inside the broker can only loop or try to
+// escape policy.
+func checkDoitRecursion(req *Request) *Result {
+	for _, words := range commandWords(req.Command) {
+		for _, prog := range spawned(words) {
+			if prog == "doit" {
+				return &Result{
+					Decision: Deny,
+					Level:    1,
+					Reason:   "doit cannot run doit recursively (permanently blocked)",
+					RuleID:   "deny-doit-recursion",
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkNestedShell escalates commands that start another shell, whose
+// script the deterministic rules cannot inspect.
+func checkNestedShell(req *Request) *Result {
+	for _, words := range commandWords(req.Command) {
+		for _, prog := range spawned(words) {
+			if shells[prog] {
+				return &Result{
+					Decision: Escalate,
+					Level:    1,
+					Reason:   fmt.Sprintf("runs a nested %s; its script needs review", prog),
+					RuleID:   "escalate-nested-shell",
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// shellFunctionDef matches a shell function definition and its body.
+var shellFunctionDef = regexp.MustCompile(`([A-Za-z_:.][\w:.-]*)\s*\(\)\s*\{([^}]*)\}`)
+
+// checkForkBomb denies fork-bomb signatures: a shell function that pipes
+// into itself in the background, or a fork-while-fork loop.
+func checkForkBomb(req *Request) *Result {
+	deny := &Result{
+		Decision: Deny,
+		Level:    1,
+		Reason:   "fork bomb (permanently blocked)",
+		RuleID:   "deny-fork-bomb",
+	}
+	for _, m := range shellFunctionDef.FindAllStringSubmatch(req.Command, -1) {
+		name, body := m[1], strings.Join(strings.Fields(m[2]), "")
+		if strings.Contains(body, name+"|"+name) && strings.Contains(body, "&") {
+			return deny
+		}
+	}
+	if strings.Contains(strings.Join(strings.Fields(req.Command), " "), "fork while fork") {
+		return deny
+	}
+	return nil
+}
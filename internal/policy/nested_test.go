@@ -0,0 +1,60 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import "testing"
+
+func TestNestedExecutionRules(t *testing.T) {
+	l1 := defaultLevel1()
+	tests := []struct {
+		command  string
+		decision Decision
+		rule     string
+	}{
+		{":(){ :|:& };:", Deny, "deny-fork-bomb"},
+		{"bomb() { bomb | bomb & }; bomb", Deny, "deny-fork-bomb"},
+		{`perl -e "fork while fork"`, Deny, "deny-fork-bomb"},
+		{"doit --history", Deny, "deny-doit-recursion"},
+		{"cd sub && /usr/local/bin/doit", Deny, "deny-doit-recursion"},
+		{"env FOO=1 doit", Deny, "deny-doit-recursion"},
+		{"sh -c 'doit --list'", Deny, "deny-doit-recursion"},
+		{"bash -c 'make test'", Escalate, "escalate-nested-shell"},
+		{"cat build.sh | sh", Escalate, "escalate-nested-shell"},
+		{"timeout 5 zsh script.zsh", Escalate, "escalate-nested-shell"},
+		{"find . -name '*.go' -exec bash -c 'gofmt -l {}' ;", Escalate, "escalate-nested-shell"},
+		{"ls | xargs -n 1 sh -c 'echo $0'", Escalate, "escalate-nested-shell"},
+	}
+	for _, tt := range tests {
+		result := l1.Evaluate(&Request{Command: tt.command})
+		if result.Decision != tt.decision || result.RuleID != tt.rule {
+			t.Errorf("%q: got decision=%v rule=%q, want %v by %s", tt.command, result.Decision, result.RuleID, tt.decision, tt.rule)
+		}
+	}
+
+	for _, cmd := range []string{
+		"grep -r doit .",
+		"ls | xargs grep doit",
+		"go test ./...",
+		"echo bash",
+		"find . -name '*.sh'",
+		"git commit -m 'teach doit about bash'",
+		"f() { echo hi; }; f",
+	} {
+		if result := l1.Evaluate(&Request{Command: cmd}); result.RuleID != "" {
+			t.Errorf("%q: unexpectedly matched %s", cmd, result.RuleID)
+		}
+	}
+}
+
+func TestNestedShellBypassable(t *testing.T) {
+	l1 := defaultLevel1()
+	result := l1.Evaluate(&Request{Command: "bash -c 'make test'", Retry: true, RetryRule: "escalate-nested-shell"})
+	if result.RuleID == "escalate-nested-shell" {
+		t.Error("expected a targeted retry to bypass escalate-nested-shell")
+	}
+	result = l1.Evaluate(&Request{Command: "doit --list", Retry: true})
+	if result.Decision != Deny {
+		t.Errorf("doit recursion on retry: got %v, want deny", result.Decision)
+	}
+}
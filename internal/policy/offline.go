@@ -0,0 +1,72 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// OfflineRuleID identifies denials made because doit is in offline mode.
+const OfflineRuleID = "deny-offline"
+
+// networkPrograms always reach the network.
+var networkPrograms = map[string]bool{
+	"ssh": true, "scp": true, "sftp": true, "nc": true, "ncat": true, "netcat": true, "telnet": true,
+	"ftp": true, "ping": true, "dig": true, "nslookup": true, "gh": true,
+}
+
+// networkSubcommands are package-manager and registry subcommands that
+// download or publish.
+var networkSubcommands = map[string]map[string]bool{
+	"pip":     {"install": true, "download": true},
+	"pip3":    {"install": true, "download": true},
+	"npm":     {"install": true, "i": true, "ci": true, "add": true, "update": true, "publish": true},
+	"pnpm":    {"install": true, "i": true, "add": true, "update": true, "publish": true},
+	"yarn":    {"install": true, "add": true, "upgrade": true, "publish": true},
+	"cargo":   {"install": true, "fetch": true, "update": true, "publish": true},
+	"gem":     {"install": true, "update": true, "push": true},
+	"brew":    {"install": true, "upgrade": true, "update": true, "fetch": true},
+	"apt":     {"install": true, "update": true, "upgrade": true},
+	"apt-get": {"install": true, "update": true, "upgrade": true},
+	"docker":  {"pull": true, "push": true, "login": true},
+}
+
+// NetworkUse returns the first simple command in command that reaches the
+// network, or "" if none does. Downloaders, remote shells, and package
+// installs are recognised here; uses reports the rest, typically from the
+// capabilities' own declarations (git fetch, go mod download).
+func NetworkUse(command string, uses func(prog string, args []string) bool) string {
+	for _, words := range commandWords(command) {
+		at := spawnedAt(words)
+		for n, i := range at {
+			end := len(words)
+			if n+1 < len(at) {
+				end = at[n+1]
+			}
+			prog := filepath.Base(words[i])
+			args := words[i+1 : end]
+			if fetchers[prog] || networkPrograms[prog] || isRemoteRsync(prog, args) ||
+				len(args) > 0 && networkSubcommands[prog][args[0]] ||
+				uses != nil && uses(prog, args) {
+				return strings.Join(words[i:end], " ")
+			}
+		}
+	}
+	return ""
+}
+
+// isRemoteRsync reports whether an rsync invocation names a remote host
+// (host:path or rsync://).
+func isRemoteRsync(prog string, args []string) bool {
+	if prog != "rsync" {
+		return false
+	}
+	for _, a := range args {
+		if strings.HasPrefix(a, "rsync://") || !strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "/") && strings.Contains(a, ":") {
+			return true
+		}
+	}
+	return false
+}
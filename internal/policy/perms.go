@@ -0,0 +1,182 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/rules"
+)
+
+// permProgs are the programs that change file permissions or ownership.
+var permProgs = map[string]bool{"chmod": true, "chown": true, "chgrp": true}
+
+// permOption matches the options chmod, chown, and chgrp share. Modes
+// such as -x or -w look like options but contain none of these letters.
+var permOption = regexp.MustCompile(`^(--.*|-[RvcfhHLP]+)$`)
+
+// permCommand is one chmod, chown, or chgrp invocation.
+type permCommand struct {
+	prog      string
+	spec      string // mode or owner; empty with --reference
+	targets   []string
+	recursive bool
+}
+
+// permCommands returns each chmod, chown, or chgrp run by command,
+// including through wrappers such as sudo, xargs, or find -exec.
+func permCommands(command string) []permCommand {
+	var out []permCommand
+	for _, words := range commandWords(command) {
+		at := spawnedAt(words)
+		for n, i := range at {
+			prog := filepath.Base(words[i])
+			if !permProgs[prog] {
+				continue
+			}
+			end := len(words)
+			if n+1 < len(at) {
+				end = at[n+1]
+			}
+			pc := permCommand{prog: prog}
+			needSpec := true
+			for _, a := range words[i+1 : end] {
+				switch {
+				case permOption.MatchString(a):
+					if rules.HasFlag(prog, []string{a}, "-R") {
+						pc.recursive = true
+					}
+					if strings.HasPrefix(a, "--reference") {
+						needSpec = false
+					}
+				case needSpec && pc.spec == "":
+					pc.spec = a
+				default:
+					pc.targets = append(pc.targets, a)
+				}
+			}
+			if !needSpec && pc.spec != "" {
+				pc.targets = append([]string{pc.spec}, pc.targets...)
+				pc.spec = ""
+			}
+			out = append(out, pc)
+		}
+	}
+	return out
+}
+
+// expandHome replaces a leading ~ or $HOME in path with the home directory.
+func expandHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	for _, h := range []string{"~", "$HOME", "${HOME}"} {
+		if path == h || strings.HasPrefix(path, h+"/") {
+			return home + path[len(h):]
+		}
+	}
+	return path
+}
+
+// isHomeDir reports whether path names the user's home directory.
+func isHomeDir(path string) bool {
+	switch strings.TrimSuffix(path, "/") {
+	case "~", "$HOME", "${HOME}":
+		return true
+	}
+	home, err := os.UserHomeDir()
+	return err == nil && filepath.Clean(path) == filepath.Clean(home)
+}
+
+// inGitDir reports whether path is or lies within a .git directory.
+func inGitDir(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPermCatastrophic denies recursive permission or ownership changes
+// on the root directory, the home directory, or a repository's .git
+// directory.
+func checkPermCatastrophic(req *Request) *Result {
+	for _, pc := range permCommands(req.Command) {
+		if !pc.recursive {
+			continue
+		}
+		for _, t := range pc.targets {
+			what := ""
+			switch {
+			case filepath.Clean(t) == "/" || t == "/*":
+				what = "the root directory"
+			case isHomeDir(t):
+				what = "the home directory"
+			case inGitDir(t):
+				what = "a .git directory"
+			default:
+				continue
+			}
+			return &Result{
+				Decision: Deny,
+				Level:    1,
+				Reason:   fmt.Sprintf("refusing recursive %s of %s %q (permanently blocked)", pc.prog, what, t),
+				RuleID:   "deny-perm-catastrophic",
+			}
+		}
+	}
+	return nil
+}
+
+// worldWritable matches modes granting everyone full access: 777, 0777,
+// a+rwx, and the like.
+var worldWritable = regexp.MustCompile(`^(0?[0-7]?777|(a|ugo)?[+=]rwx)$`)
+
+// checkPermEscalation escalates chmod to a world-writable mode and
+// ownership changes outside the workspace. The workspace is the request's
+// working directory; without one, only relative paths are inside it.
+func checkPermEscalation(req *Request) *Result {
+	escalate := func(reason string) *Result {
+		return &Result{
+			Decision: Escalate,
+			Level:    1,
+			Reason:   reason,
+			RuleID:   "escalate-perm-change",
+		}
+	}
+	for _, pc := range permCommands(req.Command) {
+		if pc.prog == "chmod" && worldWritable.MatchString(pc.spec) {
+			return escalate(fmt.Sprintf("chmod %s makes files writable by everyone", pc.spec))
+		}
+		if pc.prog == "chmod" {
+			continue
+		}
+		for _, t := range pc.targets {
+			if !inWorkspace(t, req.Cwd) {
+				return escalate(fmt.Sprintf("%s changes ownership of %s, outside the workspace", pc.prog, t))
+			}
+		}
+	}
+	return nil
+}
+
+// inWorkspace reports whether path lies within root. Relative paths are
+// resolved against root.
+func inWorkspace(path, root string) bool {
+	path = expandHome(path)
+	if root == "" {
+		return !filepath.IsAbs(path) && !strings.HasPrefix(filepath.Clean(path), "..")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
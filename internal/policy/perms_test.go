@@ -0,0 +1,47 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import "testing"
+
+func TestPermRules(t *testing.T) {
+	l1 := defaultLevel1()
+	tests := []struct {
+		command  string
+		decision Decision
+		rule     string
+	}{
+		{"chmod -R 777 /", Deny, "deny-perm-catastrophic"},
+		{"chown -R nobody /", Deny, "deny-perm-catastrophic"},
+		{"sudo chown -R root:root ~", Deny, "deny-perm-catastrophic"},
+		{"chmod --recursive 700 $HOME", Deny, "deny-perm-catastrophic"},
+		{"chmod -Rv u+w .git", Deny, "deny-perm-catastrophic"},
+		{"chgrp -R staff repo/.git/objects", Deny, "deny-perm-catastrophic"},
+		{"chmod 777 build", Escalate, "escalate-perm-change"},
+		{"chmod -R a+rwx dist", Escalate, "escalate-perm-change"},
+		{"chown nobody /etc/hosts", Escalate, "escalate-perm-change"},
+		{"chown me ../elsewhere/file", Escalate, "escalate-perm-change"},
+		{"find . -name '*.sh' -exec chmod 0777 {} +", Escalate, "escalate-perm-change"},
+	}
+	for _, tt := range tests {
+		result := l1.Evaluate(&Request{Command: tt.command, Cwd: "/work/repo"})
+		if result.Decision != tt.decision || result.RuleID != tt.rule {
+			t.Errorf("%q: got decision=%v rule=%q, want %v by %s", tt.command, result.Decision, result.RuleID, tt.decision, tt.rule)
+		}
+	}
+
+	for _, cmd := range []string{
+		"chmod +x scripts/build.sh",
+		"chmod -x scripts/build.sh",
+		"chmod -R 755 dist",
+		"chmod 644 .git/config",
+		"chown -R me:staff build",
+		"chown me /work/repo/out.log",
+		"chmod --reference=a.txt b.txt",
+	} {
+		if result := l1.Evaluate(&Request{Command: cmd, Cwd: "/work/repo"}); result.RuleID == "deny-perm-catastrophic" || result.RuleID == "escalate-perm-change" {
+			t.Errorf("%q: unexpectedly matched %s", cmd, result.RuleID)
+		}
+	}
+}
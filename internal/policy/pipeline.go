@@ -0,0 +1,106 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/rules"
+)
+
+// anyCap, as the cap of a pipeline stage, matches any command.
+const anyCap = "*"
+
+// entryMatches reports whether e matches command, whose first segment is
+// seg: by its pipeline stages if it has them, or else by seg.
+func entryMatches(e *PolicyEntry, seg *Segment, command string) bool {
+	if len(e.Match.Pipeline) > 0 {
+		return matchesPipeline(command, seg.Cwd, e.Match.Pipeline, e.Decision == "allow")
+	}
+	return matchesCriteria(seg, &e.Match)
+}
+
+// matchesPipeline reports whether command has a pipeline whose segments
+// match stages in order. With whole, as for an allow, which vouches for
+// everything the command does, the pipeline must be all of command, with
+// no other shell composition, and match stages one for one. Otherwise it
+// is enough for a run of consecutive segments of any pipeline to match,
+// so that "anything | http" catches curl x | jq . | http post too.
+func matchesPipeline(command, cwd string, stages []MatchCriteria, whole bool) bool {
+	pls := pipelines(command)
+	if whole {
+		if len(pls) != 1 || len(pls[0]) != len(stages) || isCompound(strings.ReplaceAll(command, "|", " ")) {
+			return false
+		}
+		return matchStages(pls[0], cwd, stages)
+	}
+	for _, segs := range pls {
+		for i := 0; i+len(stages) <= len(segs); i++ {
+			if matchStages(segs[i:i+len(stages)], cwd, stages) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchStages reports whether each of segs, a segment's words, matches
+// the corresponding stage.
+func matchStages(segs [][]string, cwd string, stages []MatchCriteria) bool {
+	for i, words := range segs {
+		words = rules.Normalize(words)
+		seg := Segment{CapName: words[0], Args: words[1:], Cwd: cwd, Compound: true}
+		if !matchesCriteria(&seg, &stages[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMatch reports what is wrong with m, an entry's criteria, if
+// anything: either a cap and what qualifies it, or pipeline stages,
+// each a cap (or *) and what qualifies it.
+func checkMatch(m *MatchCriteria) error {
+	if len(m.Pipeline) == 0 {
+		if m.Cap == "" {
+			return fmt.Errorf("match.cap is required")
+		}
+		if m.Cap == anyCap {
+			return fmt.Errorf("match.cap %s is for pipeline stages only", anyCap)
+		}
+		return checkStage(m)
+	}
+	if m.Cap != "" || m.Subcmd != "" || len(m.HasFlags) > 0 || len(m.NoFlags) > 0 ||
+		len(m.ArgsGlob) > 0 || len(m.PathsWithin) > 0 || len(m.PathsOutside) > 0 {
+		return fmt.Errorf("match.pipeline excludes other match criteria, which go in its stages")
+	}
+	if len(m.Pipeline) < 2 {
+		return fmt.Errorf("match.pipeline needs at least two stages")
+	}
+	for i := range m.Pipeline {
+		s := &m.Pipeline[i]
+		if s.Cap == "" {
+			return fmt.Errorf("match.pipeline stage %d: cap is required", i+1)
+		}
+		if len(s.Pipeline) > 0 {
+			return fmt.Errorf("match.pipeline stage %d: stages do not nest", i+1)
+		}
+		if err := checkStage(s); err != nil {
+			return fmt.Errorf("match.pipeline stage %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// checkStage reports the first malformed pattern or directory in m.
+func checkStage(m *MatchCriteria) error {
+	if err := checkArgsGlob(m.ArgsGlob); err != nil {
+		return err
+	}
+	if err := checkPathDirs("paths_within", m.PathsWithin); err != nil {
+		return err
+	}
+	return checkPathDirs("paths_outside", m.PathsOutside)
+}
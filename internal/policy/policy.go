@@ -5,6 +5,7 @@ package policy
 
 import (
 	"context"
+	"time"
 )
 
 // Decision represents the outcome of policy evaluation.
@@ -36,6 +37,25 @@ type Result struct {
 	Reason     string // human-readable explanation
 	RuleID     string // which rule matched (empty if none)
 	Bypassable bool   // true if the user can override this decision
+
+	// Confidence is the LLM's confidence in a Level 3 decision, from 0 to
+	// 1; nil if it gave none.
+	Confidence *float64
+
+	// Exchanges are the LLM calls behind a Level 3 decision, in order.
+	Exchanges []Exchange
+
+	// Conditions qualify an Allow: the command runs only as they say.
+	Conditions *Conditions
+}
+
+// Exchange is one LLM call Level 3 made: what it was asked and what it
+// answered, so that the decision can be reviewed after the fact.
+type Exchange struct {
+	Stage    string // "fast", "deep", or "judge N" for consensus
+	Prompt   string
+	Response string // raw, before parsing
+	Error    string // the call failed; Response is empty
 }
 
 // Request is the structured input to the policy engine.
@@ -45,9 +65,42 @@ type Request struct {
 	Command       string // raw command string passed to sh -c
 	Cwd           string
 	Retry         bool
-	Justification string // why the worker needs this command
-	SafetyArg     string // why the worker believes it's safe
-	ProjectType   string // project type discovered from context (e.g. "go", "node")
+	RetryRule     string    // rule a retry bypasses; empty bypasses every bypassable rule
+	Justification string    // why the worker needs this command
+	SafetyArg     string    // why the worker believes it's safe
+	ProjectType   string    // project type discovered from context (e.g. "go", "node")
+	Stdin         string    // standard input for the command, which may be a program
+	Segments      []string  // capability names, for the gatekeeper prompt
+	Tiers         []string  // tier of each segment, for the gatekeeper prompt
+	Tier          string    // highest tier of anything the command runs, redirections included
+	Now           time.Time // when the request is judged, for expiries; zero for the system clock
+
+	// Context, if set, gives Level 3 the situation the command arises in.
+	Context *PromptContext
+}
+
+// PromptContext is situational context for the Level 3 gatekeeper. Every
+// field is optional.
+type PromptContext struct {
+	Git        string   // branch and short status of the working tree
+	History    []string // recent commands in this session, oldest first
+	Candidates []string // learned policies that match but await approval
+	Feedback   []string // human judgments of past decisions, oldest first
+}
+
+// Bypasses reports whether the request retries past the bypassable rule
+// ruleID: a targeted retry bypasses only the rule it names, a blanket
+// retry (empty RetryRule) every bypassable rule.
+func (r *Request) Bypasses(ruleID string) bool {
+	return r.Retry && (r.RetryRule == "" || r.RetryRule == ruleID)
+}
+
+// now returns when the request is judged.
+func (r *Request) now() time.Time {
+	if r.Now.IsZero() {
+		return time.Now()
+	}
+	return r.Now
 }
 
 // EvalInfo carries policy evaluation metadata through context for audit logging.
@@ -57,6 +110,8 @@ type EvalInfo struct {
 	RuleID        string
 	Justification string
 	SafetyArg     string
+	Exchanges     []Exchange  // the LLM calls behind a Level 3 decision
+	Conditions    *Conditions // what an Allow holds the command to
 }
 
 type evalInfoKey struct{}
@@ -18,8 +18,8 @@ type Candidate struct {
 	Decision   string
 	Reasoning  string
 	Count      int
-	Uniformity float64
-	Source     string // "uniform" (same decision), "conditional" (flag-dependent)
+	Uniformity float64 // share of decisions that agree; for "confident", the LLM's confidence
+	Source     string  // "uniform" (same decision), "conditional" (flag-dependent), "confident" (one sure decision)
 }
 
 // PromoteOptions controls the thresholds for promotion analysis.
@@ -282,6 +282,35 @@ func analyseConditionalBranching(key groupKey, infos []entryInfo, opts PromoteOp
 	return candidates
 }
 
+// ConfidentCandidate turns a single Level 3 allow the LLM was highly
+// confident of into a candidate for the command's capability and
+// subcommand, so that a human can approve it rather than wait for the
+// pattern to recur. Compound commands yield none: the allow vouched for
+// the whole command, not its first word.
+func ConfidentCandidate(command string, confidence float64, reasoning string) *Candidate {
+	if isCompound(command) {
+		return nil
+	}
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return nil
+	}
+	m := MatchCriteria{Cap: words[0]}
+	label := words[0]
+	if len(words) >= 2 && !strings.HasPrefix(words[1], "-") {
+		m.Subcmd = words[1]
+		label += " " + words[1]
+	}
+	return &Candidate{
+		Match:      m,
+		Decision:   "allow",
+		Reasoning:  fmt.Sprintf("auto-learned: gatekeeper allowed %s with confidence %.2f: %s", label, confidence, reasoning),
+		Count:      1,
+		Uniformity: confidence,
+		Source:     "confident",
+	}
+}
+
 // CandidateToEntry converts a promotion candidate to a PolicyEntry ready for
 // insertion into the learned policy store.
 func CandidateToEntry(c *Candidate, now time.Time) PolicyEntry {
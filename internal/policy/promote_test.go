@@ -341,3 +341,23 @@ func TestCandidateToEntryHighConfidence(t *testing.T) {
 		t.Errorf("Confidence: want %q, got %q", "medium", e2.Confidence)
 	}
 }
+
+func TestConfidentCandidate(t *testing.T) {
+	c := ConfidentCandidate("go test ./...", 0.95, "runs tests")
+	if c == nil {
+		t.Fatal("want a candidate")
+	}
+	if c.Match.Cap != "go" || c.Match.Subcmd != "test" || c.Decision != "allow" || c.Source != "confident" {
+		t.Errorf("got %+v", c)
+	}
+	if c.Uniformity != 0.95 {
+		t.Errorf("Uniformity: want 0.95, got %v", c.Uniformity)
+	}
+
+	if c := ConfidentCandidate("ls -la", 0.9, "lists"); c == nil || c.Match.Subcmd != "" {
+		t.Errorf("flag must not become a subcommand: %+v", c)
+	}
+	if c := ConfidentCandidate("make && rm -rf build", 0.99, "builds"); c != nil {
+		t.Errorf("compound command: want nil, got %+v", c)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// PromptData is the value a site prompt template is executed against.
+type PromptData struct {
+	Command       string   // the command string as submitted
+	Segments      []string // capability names
+	Tiers         []string // tier of each segment
+	Tier          string   // highest tier of anything the command runs
+	Cwd           string
+	Justification string
+	SafetyArg     string
+	Fast          bool   // true for the fast triage tier
+	Default       string // the built-in prompt, for templates that extend it
+}
+
+// PromptTemplate is a site's replacement for the gatekeeper prompt, so
+// that organizations can state their own risk appetite and house rules.
+// The nil *PromptTemplate builds the built-in prompt.
+type PromptTemplate struct {
+	t *template.Template
+}
+
+// ParsePromptTemplate compiles a prompt template. It is executed against
+// sample data so that field typos fail at load time rather than when a
+// command is being judged.
+func ParsePromptTemplate(src string) (*PromptTemplate, error) {
+	t, err := template.New("prompt").Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(&strings.Builder{}, PromptData{}); err != nil {
+		return nil, err
+	}
+	return &PromptTemplate{t: t}, nil
+}
+
+// LoadPromptTemplate reads and compiles the prompt template at path.
+func LoadPromptTemplate(path string) (*PromptTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("prompt template: %w", err)
+	}
+	t, err := ParsePromptTemplate(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("prompt template %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// SetPromptTemplate replaces the built-in prompt with t; nil restores it.
+func (l *Level3) SetPromptTemplate(t *PromptTemplate) {
+	l.tmpl = t
+}
+
+// build renders the prompt for req. The response format is appended
+// whatever the template says. If the template fails to execute, the
+// built-in prompt is used so that a command is never judged on a
+// half-rendered prompt.
+func (p *PromptTemplate) build(req *Request, fast bool) string {
+	if p == nil {
+		return buildPrompt(req, fast)
+	}
+	body := promptBody(req, fast)
+	var sb strings.Builder
+	err := p.t.Execute(&sb, PromptData{
+		Command:       req.Command,
+		Segments:      req.Segments,
+		Tiers:         req.Tiers,
+		Tier:          req.Tier,
+		Cwd:           req.Cwd,
+		Justification: req.Justification,
+		SafetyArg:     req.SafetyArg,
+		Fast:          fast,
+		Default:       body,
+	})
+	if err != nil {
+		log.Printf("doit: prompt template: %v (using the built-in prompt)", err)
+		return body + responseFormat
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n" + responseFormat
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPromptTemplate(t *testing.T) {
+	tmpl, err := ParsePromptTemplate(`House rules: never touch prod.
+{{if .Fast}}(fast tier){{end}}
+Command: {{.Command}} in {{.Cwd}}
+{{range $i, $s := .Segments}}{{$s}}={{index $.Tiers $i}} {{end}}
+Why: {{.Justification}}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockPrompter{response: `{"decision":"allow","reasoning":"ok"}`}
+	l3 := NewLevel3(mock)
+	l3.SetPromptTemplate(tmpl)
+	l3.Evaluate(context.Background(), &Request{
+		Command:       "kubectl apply -f deploy.yaml",
+		Cwd:           "/work",
+		Segments:      []string{"kubectl"},
+		Tiers:         []string{"dangerous"},
+		Justification: "roll out the fix",
+	})
+
+	for _, want := range []string{
+		"House rules: never touch prod.\n(fast tier)\n",
+		"Command: kubectl apply -f deploy.yaml in /work\n",
+		"kubectl=dangerous",
+		"Why: roll out the fix\n",
+		`Respond with JSON only`,
+	} {
+		if !strings.Contains(mock.lastPrompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, mock.lastPrompt)
+		}
+	}
+	if strings.Contains(mock.lastPrompt, "security gatekeeper") {
+		t.Errorf("template should replace the built-in prompt:\n%s", mock.lastPrompt)
+	}
+}
+
+func TestPromptTemplateExtends(t *testing.T) {
+	tmpl, err := ParsePromptTemplate("{{.Default}}\nAlso deny anything touching /srv.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompt := tmpl.build(&Request{Command: "ls /srv"}, false)
+	if !strings.Contains(prompt, "security gatekeeper") || !strings.Contains(prompt, "Also deny anything touching /srv.\n\nRespond with JSON only") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+}
+
+func TestParsePromptTemplateErrors(t *testing.T) {
+	for _, src := range []string{"{{.Command", "{{.Comand}}"} {
+		if _, err := ParsePromptTemplate(src); err == nil {
+			t.Errorf("ParsePromptTemplate(%q): expected error", src)
+		}
+	}
+}
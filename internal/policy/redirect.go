@@ -0,0 +1,408 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcelocantos/doit/internal/paths"
+)
+
+// redirection is one file redirection in a command line.
+type redirection struct {
+	fd     string // file descriptor number written before op, if any
+	op     string // ">", ">>", ">|", "&>", "&>>", ">&", "<", or "<>"
+	target string // the file, quotes removed
+}
+
+// output reports whether the redirection writes its target.
+func (r redirection) output() bool {
+	return strings.Contains(r.op, ">")
+}
+
+// truncates reports whether the redirection replaces its target's
+// content rather than appending to or reading it.
+func (r redirection) truncates() bool {
+	return r.output() && !strings.HasSuffix(r.op, ">>") && r.op != "<>"
+}
+
+// RedirectTargets returns the files command redirects to or from, each
+// as its operator and file, such as "> out.txt", for the audit log.
+func RedirectTargets(command string) []string {
+	var out []string
+	for _, r := range redirections(command) {
+		out = append(out, r.fd+r.op+" "+r.target)
+	}
+	return out
+}
+
+// redirectOps are the redirection operators, longest first.
+var redirectOps = []string{"&>>", "<<<", "<<-", "&>", ">>", ">|", ">&", "<<", "<>", "<&", ">", "<"}
+
+// redirections returns the file redirections in command, in order.
+func redirections(command string) []redirection {
+	var out []redirection
+	for _, st := range steps(command) {
+		out = append(out, st.redirs...)
+	}
+	return out
+}
+
+// step is one simple command in a command line, with the redirections
+// that apply to it. As in the shell, a redirection binds to the simple
+// command it is written in, more tightly than |, &&, ||, ;, or &: in
+// make > build.log | tail, make's output goes to build.log and tail
+// reads nothing. One written after a ( ) or { } group applies to the
+// whole group; steps attributes it to the group's last command.
+type step struct {
+	redirs []redirection
+	dups   bool // duplicates or closes a descriptor (2>&1, >&-)
+	piped  bool // its output is piped to the next step
+}
+
+// steps splits command into its simple commands and finds their file
+// redirections. It follows quoting, comments, and here-documents well
+// enough to find them, but not the shell grammar: a > inside [[ ]] or
+// (( )) reads as a redirection. Duplicated or closed file descriptors
+// (2>&1, <&-), here-documents, and process substitutions name no file
+// and are left out.
+func steps(command string) []step {
+	out := []step{{}}
+	cur := func() *step { return &out[len(out)-1] }
+	next := func() { out = append(out, step{}) }
+	var heredocs []heredoc // bodies start at the next newline
+	wordStart := true
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '\\':
+			i++
+			wordStart = false
+		case c == '\'':
+			if j := strings.IndexByte(command[i+1:], '\''); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(command)
+			}
+			wordStart = false
+		case c == '"':
+			for i++; i < len(command) && command[i] != '"'; i++ {
+				if command[i] == '\\' {
+					i++
+				}
+			}
+			wordStart = false
+		case c == '#' && wordStart:
+			for i+1 < len(command) && command[i+1] != '\n' {
+				i++
+			}
+		case c == '\n':
+			for _, h := range heredocs {
+				i = h.skip(command, i)
+			}
+			heredocs = nil
+			wordStart = true
+			next()
+		case (c == '<' || c == '>') && strings.HasPrefix(command[i+1:], "("):
+			wordStart = false // process substitution
+		case c == '<' || c == '>' || c == '&' && strings.HasPrefix(command[i:], "&>"):
+			fd := fdBefore(command, i)
+			var op string
+			for _, o := range redirectOps {
+				if strings.HasPrefix(command[i:], o) {
+					op = o
+					break
+				}
+			}
+			i += len(op)
+			for i < len(command) && (command[i] == ' ' || command[i] == '\t') {
+				i++
+			}
+			word, end := redirectWord(command, i)
+			i = end - 1
+			wordStart = false
+			switch {
+			case op == "<<" || op == "<<-":
+				heredocs = append(heredocs, heredoc{delim: word, tabs: op == "<<-"})
+			case op == "<<<":
+				// A here-string: the word is the input.
+			case op == "<&", op == ">&" && strings.Trim(word, "0123456789-") == "":
+				cur().dups = true
+			case word == "":
+				// No file named: a syntax error the shell reports.
+			default:
+				cur().redirs = append(cur().redirs, redirection{fd: fd, op: op, target: word})
+			}
+		case c == '|':
+			switch {
+			case strings.HasPrefix(command[i:], "||"):
+				i++
+			default:
+				cur().piped = true
+				if strings.HasPrefix(command[i:], "|&") {
+					cur().dups = true // stderr joins the pipe
+					i++
+				}
+			}
+			wordStart = true
+			next()
+		case c == '&' || c == ';':
+			if i+1 < len(command) && command[i+1] == c {
+				i++
+			}
+			wordStart = true
+			next()
+		default:
+			wordStart = strings.IndexByte(" \t()", c) >= 0
+		}
+	}
+	return out
+}
+
+// PipedAway describes each step of command whose output a redirection
+// sends elsewhere although it is also piped to the next step, which then
+// reads nothing: make > build.log | tail. Steps that also duplicate a
+// descriptor (2>&1 > build.log | tail) may mean to pipe another stream,
+// and are left alone.
+func PipedAway(command string) []string {
+	var notes []string
+	for n, st := range steps(command) {
+		if !st.piped || st.dups {
+			continue
+		}
+		for _, r := range st.redirs {
+			if !r.output() || r.fd != "" && r.fd != "1" {
+				continue
+			}
+			dest := "to " + r.target
+			if r.target == "/dev/null" {
+				dest = "away (/dev/null)"
+			}
+			notes = append(notes, fmt.Sprintf("step %d sends its output %s, so the pipe to step %d carries nothing; redirections bind to a single command, not the pipeline", n+1, dest, n+2))
+			break
+		}
+	}
+	return notes
+}
+
+// fdBefore returns the file descriptor number that ends just before
+// command[i], if a word of digits does.
+func fdBefore(command string, i int) string {
+	j := i
+	for j > 0 && command[j-1] >= '0' && command[j-1] <= '9' {
+		j--
+	}
+	if j == i || j > 0 && strings.IndexByte(" \t\n|&;()", command[j-1]) < 0 {
+		return ""
+	}
+	return command[j:i]
+}
+
+// redirectWord returns the word starting at command[i], with quotes
+// removed, and the index just past it.
+func redirectWord(command string, i int) (string, int) {
+	var word strings.Builder
+	for ; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case strings.IndexByte(" \t\n|&;<>()", c) >= 0:
+			return word.String(), i
+		case c == '\\' && i+1 < len(command):
+			i++
+			word.WriteByte(command[i])
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(command[i+1:], c)
+			if j < 0 {
+				word.WriteString(command[i+1:])
+				return word.String(), len(command)
+			}
+			word.WriteString(command[i+1 : i+1+j])
+			i += j + 1
+		default:
+			word.WriteByte(c)
+		}
+	}
+	return word.String(), i
+}
+
+// heredoc is a here-document whose body is still to come.
+type heredoc struct {
+	delim string
+	tabs  bool // <<-: leading tabs are stripped
+}
+
+// skip returns the index of the newline ending the here-document's body,
+// which starts after command[nl].
+func (h heredoc) skip(command string, nl int) int {
+	for nl < len(command) {
+		end := strings.IndexByte(command[nl+1:], '\n')
+		if end < 0 {
+			return len(command)
+		}
+		line := command[nl+1 : nl+1+end]
+		nl += end + 1
+		if h.tabs {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if line == h.delim {
+			return nl
+		}
+	}
+	return nl
+}
+
+// pseudoDevices are the device files a command may harmlessly write.
+var pseudoDevices = map[string]bool{
+	"/dev/null": true, "/dev/zero": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true,
+}
+
+// isPseudoDevice reports whether path is one of pseudoDevices or a file
+// descriptor under /dev/fd.
+func isPseudoDevice(path string) bool {
+	return pseudoDevices[path] || strings.HasPrefix(path, "/dev/fd/")
+}
+
+// redirectPath returns a redirection target as a clean path, resolved
+// against cwd if it is relative and cwd is known.
+func redirectPath(target, cwd string) string {
+	path := expandHome(target)
+	if !filepath.IsAbs(path) && cwd != "" {
+		path = filepath.Join(cwd, path)
+	}
+	return filepath.Clean(path)
+}
+
+// checkRedirectSpecial denies redirecting output onto a device other
+// than the harmless ones such as /dev/null, or onto a set-uid or set-gid
+// file, which a write would either clobber or leave privileged with new
+// content. Relative targets are resolved against the working directory.
+func checkRedirectSpecial(req *Request) *Result {
+	deny := func(target, what string) *Result {
+		return &Result{
+			Decision: Deny,
+			Level:    1,
+			Reason:   fmt.Sprintf("redirection onto %s %s (permanently blocked)", what, target),
+			RuleID:   "deny-redirect-special",
+		}
+	}
+	for _, r := range redirections(req.Command) {
+		if !r.output() || r.target == "" {
+			continue
+		}
+		path := redirectPath(r.target, req.Cwd)
+		if isPseudoDevice(path) {
+			continue
+		}
+		if strings.HasPrefix(path, "/dev/") {
+			return deny(r.target, "device")
+		}
+		fi, err := os.Stat(path)
+		switch {
+		case err != nil:
+		case fi.Mode()&fs.ModeDevice != 0:
+			return deny(r.target, "device")
+		case fi.Mode()&(fs.ModeSetuid|fs.ModeSetgid) != 0:
+			return deny(r.target, "set-uid or set-gid file")
+		}
+	}
+	return nil
+}
+
+// scratchDirs returns where commands may write outside the workspace. It
+// is a variable so tests can stub it.
+var scratchDirs = func() []string {
+	return []string{os.TempDir(), "/tmp"}
+}
+
+// protectedDir returns the protected directory path lies in, if any: a
+// repository's .git directory, a directory of executables, or doit's own
+// configuration and state, which hold its policy and audit log.
+func protectedDir(target, path string) string {
+	switch {
+	case inGitDir(path):
+		return "a .git directory"
+	case inExecDir(target) || inExecDir(path+"/") || inExecDir(filepath.Dir(path)+"/"):
+		return "a directory of executables"
+	}
+	for _, dir := range []string{paths.ConfigDir(), paths.DataDir(), paths.StateDir()} {
+		if within(path, dir) {
+			return "doit's own configuration and state"
+		}
+	}
+	return ""
+}
+
+// ProtectedDir returns the protected directory path, an absolute path,
+// lies in, if any, as escalate-redirect-target judges a redirection's
+// target: the write capability keeps out of the same places.
+func ProtectedDir(path string) string {
+	target := path
+	if home, err := os.UserHomeDir(); err == nil && within(path, home) {
+		rel, _ := filepath.Rel(home, path)
+		target = "~/" + rel
+	}
+	return protectedDir(target, path)
+}
+
+// within reports whether path is dir or lies beneath it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// gitModified reports whether the file at path is tracked by git in dir
+// and has uncommitted changes. It is a variable so tests can stub it.
+var gitModified = func(dir, path string) bool {
+	out, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--untracked-files=no", "--", path).Output()
+	return err == nil && len(strings.TrimSpace(string(out))) > 0
+}
+
+// checkRedirectTarget escalates output redirected outside the workspace
+// (other than to a temporary directory), into a protected directory, or
+// over a tracked file with uncommitted changes, which the redirection
+// would discard. Targets known only when the command runs ($OUT) are
+// left to the later levels.
+func checkRedirectTarget(req *Request) *Result {
+	escalate := func(format string, args ...any) *Result {
+		return &Result{
+			Decision: Escalate,
+			Level:    1,
+			Reason:   fmt.Sprintf(format, args...),
+			RuleID:   "escalate-redirect-target",
+		}
+	}
+	for _, r := range redirections(req.Command) {
+		if !r.output() || r.target == "" || strings.ContainsAny(r.target, "$`*?[") {
+			continue
+		}
+		path := redirectPath(r.target, req.Cwd)
+		if isPseudoDevice(path) {
+			continue
+		}
+		if dir := protectedDir(r.target, path); dir != "" {
+			return escalate("redirects output into %s, in %s", r.target, dir)
+		}
+		if !inWorkspace(r.target, req.Cwd) {
+			inScratch := false
+			for _, dir := range scratchDirs() {
+				inScratch = inScratch || within(path, dir)
+			}
+			if !inScratch {
+				return escalate("redirects output to %s, outside the workspace", r.target)
+			}
+			continue
+		}
+		if r.truncates() && req.Cwd != "" {
+			if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() && gitModified(req.Cwd, path) {
+				return escalate("redirection would overwrite uncommitted changes to %s", r.target)
+			}
+		}
+	}
+	return nil
+}
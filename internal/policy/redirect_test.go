@@ -0,0 +1,201 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedirections(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		want    []string // as RedirectTargets gives them
+	}{
+		{"echo hi > out.txt", []string{"> out.txt"}},
+		{"make 2>&1 >>build.log", []string{">> build.log"}},
+		{"sort <in >|out", []string{"< in", ">| out"}},
+		{"cmd &> 'all of it.log'", []string{"&> all of it.log"}},
+		{"cmd 2>err x2>y", []string{"2> err", "> y"}},
+		{"cmd >&2; cmd <&- 3>&-", nil},
+		{`echo "a > b" '>c' \> d`, nil},
+		{"echo # > not-a-file", nil},
+		{"cat <<EOF > out\nx > y\nEOF\necho >> log", []string{"> out", ">> log"}},
+		{"cat <<-END\n\tx > y\n\tEND\n", nil},
+		{"grep x <<< 'a > b' | tee >(wc) > n", []string{"> n"}},
+		{"echo hi >; sort <", nil},
+	} {
+		if got := RedirectTargets(tc.command); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("RedirectTargets(%q) = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestSteps(t *testing.T) {
+	// Each step's redirections, as RedirectTargets gives them, with "|"
+	// appended to a step piped to the next.
+	for _, tc := range []struct {
+		command string
+		want    []string
+	}{
+		{"a > x && b > y", []string{"> x", "> y"}},
+		{"a > x || b 2> y; c", []string{"> x", "2> y", ""}},
+		{"a | b > x | c", []string{"|", "> x |", ""}},
+		{"a |& b >> x & c < y", []string{"|", ">> x", "< y"}},
+		{"a > x\nb > y", []string{"> x", "> y"}},
+		{"(a; b) > x | c", []string{"", "> x |", ""}},
+		{"echo 'a | b' > x", []string{"> x"}},
+		{"a &> x;; b", []string{"&> x", ""}},
+	} {
+		var got []string
+		for _, st := range steps(tc.command) {
+			var parts []string
+			for _, r := range st.redirs {
+				parts = append(parts, r.fd+r.op+" "+r.target)
+			}
+			if st.piped {
+				parts = append(parts, "|")
+			}
+			got = append(got, strings.Join(parts, " "))
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("steps(%q) = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestPipedAway(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		want    string // "" for none
+	}{
+		{"make > build.log | tail", "step 1 sends its output to build.log, so the pipe to step 2"},
+		{"go vet | grep x >/dev/null | wc -l", "step 2 sends its output away (/dev/null), so the pipe to step 3"},
+		{"a 1>>log | b", "step 1 sends its output to log"},
+		{"make 2> err.log | tail", ""},
+		{"make 2>&1 > build.log | tail", ""},
+		{"make > build.log && tail build.log", ""},
+		{"make | tee build.log > /dev/null", ""},
+	} {
+		notes := PipedAway(tc.command)
+		switch {
+		case tc.want == "" && len(notes) != 0:
+			t.Errorf("PipedAway(%q) = %q, want none", tc.command, notes)
+		case tc.want != "" && (len(notes) != 1 || !strings.HasPrefix(notes[0], tc.want)):
+			t.Errorf("PipedAway(%q) = %q, want %q...", tc.command, notes, tc.want)
+		}
+	}
+}
+
+func TestRedirectSpecial(t *testing.T) {
+	dir := t.TempDir()
+	setuid := filepath.Join(dir, "helper")
+	os.WriteFile(setuid, nil, 0o755)
+	if err := os.Chmod(setuid, 0o755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+
+	l1 := defaultLevel1()
+	for _, cmd := range []string{
+		"echo 1 > /dev/mem",
+		"printf x >>/dev/kmsg",
+		"cat payload > helper",
+		"cat payload &> " + setuid,
+		"echo x > /dev/../dev/port",
+	} {
+		result := l1.Evaluate(&Request{Command: cmd, Cwd: dir})
+		if result.Decision != Deny || result.RuleID != "deny-redirect-special" {
+			t.Errorf("%q: got decision=%v rule=%q, want deny by deny-redirect-special", cmd, result.Decision, result.RuleID)
+		}
+	}
+
+	for _, cmd := range []string{
+		"go test ./... > /dev/null 2>&1",
+		"echo hi > /dev/stderr",
+		"cat helper > copy",
+		"echo x > out.txt",
+		"exec 3>/dev/fd/1",
+	} {
+		if result := l1.Evaluate(&Request{Command: cmd, Cwd: dir}); result.RuleID == "deny-redirect-special" {
+			t.Errorf("%q: unexpectedly matched deny-redirect-special", cmd)
+		}
+	}
+}
+
+func TestRedirectTarget(t *testing.T) {
+	dir := t.TempDir()
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	os.WriteFile(filepath.Join(dir, "dirty.go"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "clean.go"), nil, 0o644)
+	oldModified, oldScratch := gitModified, scratchDirs
+	gitModified = func(_, path string) bool { return filepath.Base(path) == "dirty.go" }
+	scratchDirs = func() []string { return []string{"/scratch"} }
+	t.Cleanup(func() { gitModified, scratchDirs = oldModified, oldScratch })
+
+	l1 := defaultLevel1()
+	for _, cmd := range []string{
+		"echo x > ../sibling.txt",
+		"echo x >> /etc/hosts",
+		"echo 'alias ls=rm' >> ~/.bashrc",
+		"echo '#!/bin/sh' > .git/hooks/pre-commit",
+		"cat tool > ~/.local/bin/tool",
+		"echo '{}' > " + filepath.Join(state, "doit", "tokens.json"),
+		"gofmt dirty.go > dirty.go",
+		"go test ./... 2> dirty.go",
+	} {
+		result := l1.Evaluate(&Request{Command: cmd, Cwd: dir})
+		if result.Decision != Escalate || result.RuleID != "escalate-redirect-target" {
+			t.Errorf("%q: got decision=%v rule=%q, want escalate by escalate-redirect-target", cmd, result.Decision, result.RuleID)
+		}
+	}
+
+	for _, cmd := range []string{
+		"echo x > out.txt",
+		"gofmt clean.go > clean.go",
+		"echo note >> dirty.go",
+		"go test ./... > /scratch/test.log 2>&1",
+		"sort < /etc/hosts > hosts.sorted",
+		`echo x > "$OUT"`,
+	} {
+		result := l1.Evaluate(&Request{Command: cmd, Cwd: dir})
+		if result.RuleID == "escalate-redirect-target" {
+			t.Errorf("%q: unexpectedly matched escalate-redirect-target: %s", cmd, result.Reason)
+		}
+		if result := l1.Evaluate(&Request{Command: cmd, Cwd: dir, Retry: true}); result.RuleID == "escalate-redirect-target" {
+			t.Errorf("%q: retry did not bypass escalate-redirect-target", cmd)
+		}
+	}
+}
+
+// FuzzRedirectTargets throws operator edge cases at the redirection
+// scanner behind the audit log's redirects and the piped-away notes. It
+// must not panic, and every target it reports names a file.
+func FuzzRedirectTargets(f *testing.F) {
+	for _, seed := range []string{
+		"echo hi > out.txt",
+		"make 2>&1 >>build.log | tee -a log",
+		"cmd &> 'all of it.log' <&- 3>&-",
+		"cat <<EOF > out\nx > y\nEOF\n",
+		"cat <<-",
+		"grep x <<< 'a > b' | tee >(wc) > n",
+		`echo "a > b" '>c' \> d # > e`,
+		"a | b > /dev/null | c",
+		">", "<<", "'", `\`, "\"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, command string) {
+		for _, r := range RedirectTargets(command) {
+			op, target, ok := strings.Cut(r, " ")
+			if !ok || op == "" || target == "" {
+				t.Errorf("RedirectTargets(%q): malformed %q", command, r)
+			}
+		}
+		PipedAway(command)
+	})
+}
@@ -32,7 +32,7 @@ func NextReviewTime(lastReviewed time.Time, reviewCount int) time.Time {
 	return lastReviewed.Add(NextReviewInterval(reviewCount))
 }
 
-// NeedsReview reports whether the next review time has passed.
-func NeedsReview(nextReview time.Time) bool {
-	return time.Now().After(nextReview)
+// NeedsReview reports whether the next review time has passed as of now.
+func NeedsReview(nextReview, now time.Time) bool {
+	return now.After(nextReview)
 }
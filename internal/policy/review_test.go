@@ -42,13 +42,14 @@ func TestNextReviewTime(t *testing.T) {
 }
 
 func TestNeedsReview(t *testing.T) {
-	past := time.Now().Add(-time.Hour)
-	future := time.Now().Add(time.Hour)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
 
-	if !NeedsReview(past) {
+	if !NeedsReview(past, now) {
 		t.Error("NeedsReview(past) = false, want true")
 	}
-	if NeedsReview(future) {
+	if NeedsReview(future, now) {
 		t.Error("NeedsReview(future) = true, want false")
 	}
 }
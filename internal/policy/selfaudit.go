@@ -24,12 +24,9 @@ type AuditFinding struct {
 //   - Missing tests: Starlark rule IDs referenced but not found in the loaded
 //     rule set (identified by absence from starlarkRules slice).
 //   - Duplicate coverage: Multiple L2 entries covering the same cap+subcmd.
-func AuditRules(l1Rules []string, l2Entries []PolicyEntry, starlarkRules []string) []AuditFinding {
-	return auditRulesAt(l1Rules, l2Entries, starlarkRules, time.Now())
-}
-
-// auditRulesAt is the internal implementation with injectable clock for testing.
-func auditRulesAt(l1Rules []string, l2Entries []PolicyEntry, starlarkRules []string, now time.Time) []AuditFinding {
+//
+// Reviews are judged overdue as of now.
+func AuditRules(l1Rules []string, l2Entries []PolicyEntry, starlarkRules []string, now time.Time) []AuditFinding {
 	var findings []AuditFinding
 
 	// Index Starlark rule IDs for fast lookup.
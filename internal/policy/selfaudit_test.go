@@ -17,7 +17,7 @@ func TestAuditRules_Contradiction(t *testing.T) {
 		},
 	}
 	// L1 rule denies git push — contradicts the L2 allow.
-	findings := auditRulesAt([]string{"deny git push"}, l2, nil, time.Now())
+	findings := AuditRules([]string{"deny git push"}, l2, nil, time.Now())
 
 	found := false
 	for _, f := range findings {
@@ -38,7 +38,7 @@ func TestAuditRules_NoContradiction_SameDecision(t *testing.T) {
 			Match:    MatchCriteria{Cap: "rm", Subcmd: "-rf"},
 		},
 	}
-	findings := auditRulesAt([]string{"deny rm -rf"}, l2, nil, time.Now())
+	findings := AuditRules([]string{"deny rm -rf"}, l2, nil, time.Now())
 
 	for _, f := range findings {
 		if f.Category == "contradiction" {
@@ -59,7 +59,7 @@ func TestAuditRules_StaleEntry(t *testing.T) {
 		},
 	}
 
-	findings := auditRulesAt(nil, l2, nil, time.Now())
+	findings := AuditRules(nil, l2, nil, time.Now())
 
 	found := false
 	for _, f := range findings {
@@ -84,7 +84,7 @@ func TestAuditRules_NotStale_LessThan90Days(t *testing.T) {
 		},
 	}
 
-	findings := auditRulesAt(nil, l2, nil, time.Now())
+	findings := AuditRules(nil, l2, nil, time.Now())
 
 	for _, f := range findings {
 		if f.Category == "stale" {
@@ -103,7 +103,7 @@ func TestAuditRules_MissingTest(t *testing.T) {
 	}
 	// "deny-rm" is not in the Starlark rules list.
 	starlarkRules := []string{"allow-cat"}
-	findings := auditRulesAt(nil, l2, starlarkRules, time.Now())
+	findings := AuditRules(nil, l2, starlarkRules, time.Now())
 
 	found := false
 	for _, f := range findings {
@@ -125,7 +125,7 @@ func TestAuditRules_MissingTest_SkipsUserEntries(t *testing.T) {
 		},
 	}
 	starlarkRules := []string{"other-rule"}
-	findings := auditRulesAt(nil, l2, starlarkRules, time.Now())
+	findings := AuditRules(nil, l2, starlarkRules, time.Now())
 
 	for _, f := range findings {
 		if f.Category == "missing_test" {
@@ -148,7 +148,7 @@ func TestAuditRules_Duplicate(t *testing.T) {
 		},
 	}
 
-	findings := auditRulesAt(nil, l2, nil, time.Now())
+	findings := AuditRules(nil, l2, nil, time.Now())
 
 	found := false
 	for _, f := range findings {
@@ -175,7 +175,7 @@ func TestAuditRules_NoDuplicate_DifferentSubcmd(t *testing.T) {
 		},
 	}
 
-	findings := auditRulesAt(nil, l2, nil, time.Now())
+	findings := AuditRules(nil, l2, nil, time.Now())
 
 	for _, f := range findings {
 		if f.Category == "duplicate" {
@@ -185,7 +185,7 @@ func TestAuditRules_NoDuplicate_DifferentSubcmd(t *testing.T) {
 }
 
 func TestAuditRules_Empty(t *testing.T) {
-	findings := AuditRules(nil, nil, nil)
+	findings := AuditRules(nil, nil, nil, time.Now())
 	if len(findings) != 0 {
 		t.Errorf("expected no findings for empty input, got %+v", findings)
 	}
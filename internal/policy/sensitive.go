@@ -0,0 +1,64 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SensitivePaths are paths whose contents are secret, such as .env files
+// or private keys, written as Tripwires are. Unlike a tripwire, a command
+// may touch one; what it may do afterwards is what changes.
+type SensitivePaths []string
+
+// DefaultSensitivePaths are the secrets Level 1 watches for being sent
+// over the network, besides any configured.
+var DefaultSensitivePaths = SensitivePaths{
+	".env", ".env.*", "*.pem", "*.key", "id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	".netrc", ".pgpass", ".npmrc", ".aws/credentials", ".docker/config.json", ".kube/config",
+}
+
+// Touched returns the sensitive path the command of req references, or
+// "", resolving paths as Tripwires.Check does.
+func (s SensitivePaths) Touched(req *Request) string {
+	return touchedPath(s, req)
+}
+
+// exfiltrationRuleID identifies escalations of pipelines that send a
+// secret over the network.
+const exfiltrationRuleID = "escalate-secret-exfiltration"
+
+// SetSensitivePaths has Level 1 watch paths, as well as
+// DefaultSensitivePaths, for being sent over the network.
+func (l *Level1) SetSensitivePaths(paths []string) {
+	l.sensitive = append(append(SensitivePaths{}, DefaultSensitivePaths...), paths...)
+}
+
+// checkExfiltration escalates a pipeline that reads a sensitive path and
+// then reaches the network, in the same segment or a later one
+// (cat .env | curl -d @- ..., curl -d @.env ...). Each part may be
+// harmless alone; together they are how a secret leaves.
+func (l *Level1) checkExfiltration(req *Request) *Result {
+	for _, segs := range pipelines(req.Command) {
+		secret := ""
+		for _, words := range segs {
+			if secret == "" {
+				secret = touchedBy(l.sensitive, words, req.Cwd)
+			}
+			if secret == "" {
+				continue
+			}
+			if use := NetworkUse(strings.Join(words, " "), nil); use != "" {
+				return &Result{
+					Decision: Escalate,
+					Level:    1,
+					Reason:   fmt.Sprintf("reads %s, which is sensitive, and sends to the network with %q; the combination needs review", secret, use),
+					RuleID:   exfiltrationRuleID,
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -4,12 +4,15 @@
 package policy
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/marcelocantos/doit/internal/paths"
 )
 
 // PolicyEntry is a single learned policy rule.
@@ -23,15 +26,37 @@ type PolicyEntry struct {
 	Provenance  string        `yaml:"provenance"`   // "human", "gatekeeper"
 	Approved    bool          `yaml:"approved"`
 	Review      ReviewSchedule `yaml:"review"`
+	ExpiresAt   time.Time     `yaml:"expires_at,omitempty"` // temporary grants only
+	Bypassable  *bool         `yaml:"bypassable,omitempty"` // false: retries never skip this entry (default true)
+	Conditions  *Conditions   `yaml:"conditions,omitempty"` // what an allow holds the command to
+}
+
+// IsBypassable reports whether a retry may skip the entry.
+func (e *PolicyEntry) IsBypassable() bool {
+	return e.Bypassable == nil || *e.Bypassable
 }
 
 // MatchCriteria defines what a policy entry matches against.
 type MatchCriteria struct {
-	Cap      string   `yaml:"cap"`
+	Cap      string   `yaml:"cap,omitempty"`
 	Subcmd   string   `yaml:"subcmd,omitempty"`
 	HasFlags []string `yaml:"has_flags,omitempty"`
 	NoFlags  []string `yaml:"no_flags,omitempty"`
 	ArgsGlob []string `yaml:"args_glob,omitempty"`
+
+	// PathsWithin and PathsOutside are directories (absolute, or
+	// beginning ~ or $REPO, the root of the command's repository) that
+	// every positional arg, resolved against the command's working
+	// directory, must lie within one of, and outside all of.
+	PathsWithin  []string `yaml:"paths_within,omitempty"`
+	PathsOutside []string `yaml:"paths_outside,omitempty"`
+
+	// Pipeline, in place of the fields above, matches a pipeline by its
+	// segments, each matched by a stage in order. A stage's cap may be
+	// "*", any command. An allow matches only a command that is exactly
+	// such a pipeline; a deny or escalate matches any command containing
+	// one (see matchesPipeline).
+	Pipeline []MatchCriteria `yaml:"pipeline,omitempty"`
 }
 
 // ReviewSchedule tracks spaced repetition review state.
@@ -49,8 +74,7 @@ type storeFile struct {
 
 // DefaultStorePath returns the default path for the learned policy store.
 func DefaultStorePath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".config", "doit", "learned-policy.yaml")
+	return paths.LearnedPolicy()
 }
 
 // LoadStore reads policy entries from YAML. Returns an empty slice (not error)
@@ -73,12 +97,18 @@ func LoadStore(path string) ([]PolicyEntry, error) {
 		if e.ID == "" {
 			return nil, fmt.Errorf("learned policy %s: entry %d: missing id", path, i)
 		}
-		if e.Match.Cap == "" {
-			return nil, fmt.Errorf("learned policy %s: entry %q: match.cap is required", path, e.ID)
+		if err := checkMatch(&e.Match); err != nil {
+			return nil, fmt.Errorf("learned policy %s: entry %q: %w", path, e.ID, err)
 		}
 		if err := validateDecision(e.Decision); err != nil {
 			return nil, fmt.Errorf("learned policy %s: entry %q: %w", path, e.ID, err)
 		}
+		if e.Conditions != nil && e.Decision != "allow" {
+			return nil, fmt.Errorf("learned policy %s: entry %q: conditions qualify only an allow", path, e.ID)
+		}
+		if err := e.Conditions.Check(); err != nil {
+			return nil, fmt.Errorf("learned policy %s: entry %q: conditions: %w", path, e.ID, err)
+		}
 	}
 
 	return sf.Entries, nil
@@ -94,7 +124,9 @@ func validateDecision(s string) error {
 }
 
 // SaveStore writes policy entries to path atomically using a temp file + rename.
-// Parent directories are created if they don't exist.
+// Parent directories are created if they don't exist. To change the store,
+// rather than replace it, use ModifyStore, lest another process's change
+// be lost.
 func SaveStore(path string, entries []PolicyEntry) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
@@ -117,6 +149,13 @@ func SaveStore(path string, entries []PolicyEntry) error {
 		os.Remove(tmpName)
 		return fmt.Errorf("write temp policy file: %w", err)
 	}
+	// Flushed before the rename, so that a crash leaves the old store or
+	// the new one, never an empty file in its place.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("sync temp policy file: %w", err)
+	}
 	if err := tmp.Close(); err != nil {
 		os.Remove(tmpName)
 		return fmt.Errorf("close temp policy file: %w", err)
@@ -128,28 +167,57 @@ func SaveStore(path string, entries []PolicyEntry) error {
 	return nil
 }
 
-// AppendEntries adds newEntries to the store at path, skipping any whose ID
-// already exists. Returns the count of entries actually added.
-func AppendEntries(path string, newEntries []PolicyEntry) (int, error) {
-	existing, err := LoadStore(path)
+// errUnchanged, returned by a ModifyStore function, leaves the store as it
+// was.
+var errUnchanged = errors.New("store unchanged")
+
+// ModifyStore replaces the entries of the store at path with what fn makes
+// of them, holding a lock on the store throughout, so that processes
+// changing it at once (the MCP server learning while the CLI adds a grant)
+// each see the others' changes. Readers need no lock: SaveStore replaces
+// the file whole.
+func ModifyStore(path string, fn func([]PolicyEntry) ([]PolicyEntry, error)) error {
+	unlock, err := lockFile(path, "learned policy")
 	if err != nil {
-		return 0, err
+		return err
 	}
-
-	seen := make(map[string]bool, len(existing))
-	for _, e := range existing {
-		seen[e.ID] = true
+	defer unlock()
+	entries, err := LoadStore(path)
+	if err != nil {
+		return err
 	}
+	entries, err = fn(entries)
+	if err == errUnchanged {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return SaveStore(path, entries)
+}
 
+// AppendEntries adds newEntries to the store at path, skipping any whose ID
+// already exists. Returns the count of entries actually added.
+func AppendEntries(path string, newEntries []PolicyEntry) (int, error) {
 	var added int
-	for _, e := range newEntries {
-		if !seen[e.ID] {
-			existing = append(existing, e)
-			added++
+	err := ModifyStore(path, func(existing []PolicyEntry) ([]PolicyEntry, error) {
+		seen := make(map[string]bool, len(existing))
+		for _, e := range existing {
+			seen[e.ID] = true
 		}
-	}
-
-	if err := SaveStore(path, existing); err != nil {
+		for _, e := range newEntries {
+			if !seen[e.ID] {
+				existing = append(existing, e)
+				seen[e.ID] = true
+				added++
+			}
+		}
+		if added == 0 {
+			return nil, errUnchanged
+		}
+		return existing, nil
+	})
+	if err != nil {
 		return 0, err
 	}
 	return added, nil
@@ -158,35 +226,28 @@ func AppendEntries(path string, newEntries []PolicyEntry) (int, error) {
 // UpdateEntry loads the store, applies fn to the entry with the given id, and
 // saves. Returns an error if the id is not found.
 func UpdateEntry(path string, id string, fn func(*PolicyEntry)) error {
-	entries, err := LoadStore(path)
-	if err != nil {
-		return err
-	}
-
-	for i := range entries {
-		if entries[i].ID == id {
-			fn(&entries[i])
-			return SaveStore(path, entries)
+	return ModifyStore(path, func(entries []PolicyEntry) ([]PolicyEntry, error) {
+		for i := range entries {
+			if entries[i].ID == id {
+				fn(&entries[i])
+				return entries, nil
+			}
 		}
-	}
-	return fmt.Errorf("policy entry %q: not found", id)
+		return nil, fmt.Errorf("policy entry %q: not found", id)
+	})
 }
 
 // DeleteEntry removes the entry with the given id from the store. Returns an
 // error if the id is not found.
 func DeleteEntry(path string, id string) error {
-	entries, err := LoadStore(path)
-	if err != nil {
-		return err
-	}
-
-	for i, e := range entries {
-		if e.ID == id {
-			remaining := append(entries[:i:i], entries[i+1:]...)
-			return SaveStore(path, remaining)
+	return ModifyStore(path, func(entries []PolicyEntry) ([]PolicyEntry, error) {
+		for i, e := range entries {
+			if e.ID == id {
+				return append(entries[:i:i], entries[i+1:]...), nil
+			}
 		}
-	}
-	return fmt.Errorf("policy entry %q: not found", id)
+		return nil, fmt.Errorf("policy entry %q: not found", id)
+	})
 }
 
 // ParseDecision converts a decision string to a Decision enum.
@@ -4,9 +4,12 @@
 package policy
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadStoreMissingFile(t *testing.T) {
@@ -136,6 +139,38 @@ func TestLoadStoreInvalidDecision(t *testing.T) {
 	}
 }
 
+func TestLoadStoreConditions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "learned-policy.yaml")
+	entry := `entries:
+  - id: make-release
+    match: {cap: make, subcmd: release}
+    decision: %s
+    approved: true
+    conditions: {timeout: 90s, max_output: 4096, no_network: true, dry_run: %s}
+`
+	os.WriteFile(path, []byte(fmt.Sprintf(entry, "allow", "-n")), 0644)
+	entries, err := LoadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Conditions{Timeout: 90 * time.Second, MaxOutput: 4096, NoNetwork: true, DryRun: "-n"}
+	if c := entries[0].Conditions; c == nil || *c != want {
+		t.Fatalf("conditions = %+v, want %+v", c, want)
+	}
+	r := NewLevel2(entries).Evaluate(&Request{Command: "make release"})
+	if r.Decision != Allow || r.Conditions == nil || !strings.Contains(r.Reason, "on conditions: timeout 1m30s, max output 4096 bytes, no network, dry run with -n first") {
+		t.Errorf("result = %+v", r)
+	}
+
+	for _, bad := range [][2]string{{"deny", "-n"}, {"allow", "'-n; rm -rf ~'"}} {
+		os.WriteFile(path, []byte(fmt.Sprintf(entry, bad[0], bad[1])), 0644)
+		if _, err := LoadStore(path); err == nil {
+			t.Errorf("decision %s, dry_run %s: want error", bad[0], bad[1])
+		}
+	}
+}
+
 func TestLoadStoreMalformedYAML(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "learned-policy.yaml")
@@ -352,6 +387,47 @@ func TestDeleteEntryNotFound(t *testing.T) {
 	}
 }
 
+func TestModifyStoreConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "learned-policy.yaml")
+	const n = 20
+	errs := make(chan error, n)
+	for i := range n {
+		go func() {
+			_, err := AppendEntries(path, []PolicyEntry{makeEntry(fmt.Sprintf("e%d", i), "go")})
+			errs <- err
+		}()
+	}
+	for range n {
+		if err := <-errs; err != nil {
+			t.Fatalf("AppendEntries: %v", err)
+		}
+	}
+	entries, err := LoadStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != n {
+		t.Errorf("got %d entries after %d concurrent appends, want all of them", len(entries), n)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".learned-policy-*")); len(tmps) > 0 {
+		t.Errorf("temp files left behind: %v", tmps)
+	}
+}
+
+func TestModifyStoreUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "learned-policy.yaml")
+	if err := SaveStore(path, []PolicyEntry{makeEntry("e1", "go")}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.Stat(path)
+	if added, err := AppendEntries(path, []PolicyEntry{makeEntry("e1", "go")}); err != nil || added != 0 {
+		t.Fatalf("AppendEntries of a duplicate: %d, %v", added, err)
+	}
+	if after, _ := os.Stat(path); !os.SameFile(before, after) {
+		t.Error("adding nothing rewrote the store")
+	}
+}
+
 func TestParseDecision(t *testing.T) {
 	tests := []struct {
 		input string
@@ -6,100 +6,390 @@ package policy
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
 	"slices"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/marcelocantos/doit/internal/clock"
+	"github.com/marcelocantos/doit/internal/paths"
 )
 
 const DefaultTokenTTL = 10 * time.Minute
 
+// tokenRetention is how long a used, revoked, or expired token stays on
+// record (past its expiry) so it can still be listed.
+const tokenRetention = 24 * time.Hour
+
+// Token states reported by TokenEntry.State.
+const (
+	TokenOutstanding = "outstanding"
+	TokenUsed        = "used"
+	TokenRevoked     = "revoked"
+	TokenExpired     = "expired"
+)
+
+// TokenScope binds a token to the agent session and working directory it
+// was issued for, so it cannot be replayed elsewhere. Empty fields are
+// unbound.
+type TokenScope struct {
+	Session string `json:"session,omitempty"`
+	Cwd     string `json:"cwd,omitempty"`
+}
+
 // TokenEntry holds metadata for an issued approval token.
 type TokenEntry struct {
-	Command   string
-	Args      []string
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	TokenScope
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at,omitzero"`
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+}
+
+// State reports whether the token is outstanding, used, revoked, or
+// expired as of now.
+func (e *TokenEntry) State(now time.Time) string {
+	switch {
+	case !e.UsedAt.IsZero():
+		return TokenUsed
+	case !e.RevokedAt.IsZero():
+		return TokenRevoked
+	case now.After(e.ExpiresAt):
+		return TokenExpired
+	default:
+		return TokenOutstanding
+	}
+}
+
+// TokenRecord is a token and its entry, as returned by List.
+type TokenRecord struct {
+	Token string
+	TokenEntry
 }
 
-// TokenStore manages time-limited, single-use approval tokens.
+// TokenStore manages time-limited, single-use approval tokens. A store
+// opened with OpenTokenStore is persisted to a file shared by every doit
+// process, so tokens can be listed and revoked from the CLI.
 type TokenStore struct {
 	mu     sync.Mutex
 	tokens map[string]*TokenEntry
 	ttl    time.Duration
+	path   string // empty for an in-memory store
+	clock  clock.Clock
+	newID  func() string // nil for random tokens
 }
 
-func NewTokenStore(ttl time.Duration) *TokenStore {
-	return &TokenStore{
+// TokenStoreOption configures optional TokenStore parameters.
+type TokenStoreOption func(*TokenStore)
+
+// WithTokenClock makes the store issue and expire tokens by c rather
+// than the system clock.
+func WithTokenClock(c clock.Clock) TokenStoreOption {
+	return func(s *TokenStore) { s.clock = clock.Or(c) }
+}
+
+// WithTokenIDs makes the store issue the tokens newID returns rather
+// than random ones. Each must be unique.
+func WithTokenIDs(newID func() string) TokenStoreOption {
+	return func(s *TokenStore) { s.newID = newID }
+}
+
+// NewTokenStore returns an in-memory token store.
+func NewTokenStore(ttl time.Duration, opts ...TokenStoreOption) *TokenStore {
+	s := &TokenStore{
 		tokens: make(map[string]*TokenEntry),
 		ttl:    ttl,
+		clock:  clock.System,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// OpenTokenStore returns a token store persisted at path. The file is
+// created on first use; every operation rereads it under an exclusive
+// lock, so concurrent processes see each other's tokens.
+func OpenTokenStore(path string, ttl time.Duration, opts ...TokenStoreOption) *TokenStore {
+	s := NewTokenStore(ttl, opts...)
+	s.path = path
+	return s
+}
+
+// DefaultTokenPath returns the default path for the persisted token store.
+func DefaultTokenPath() string {
+	return paths.Tokens()
 }
 
-// Issue generates a new approval token for the given command and args.
-// Returns a hex-encoded 128-bit random token string.
+// Issue generates a new approval token for the given command and args,
+// usable from any session or directory.
 func (s *TokenStore) Issue(command string, args []string) (string, error) {
-	var raw [16]byte
-	if _, err := rand.Read(raw[:]); err != nil {
+	return s.IssueScoped(command, args, TokenScope{})
+}
+
+// IssueScoped generates a new approval token for the given command and
+// args, bound to scope. Returns a hex-encoded 128-bit random token string,
+// unless the store was given WithTokenIDs.
+func (s *TokenStore) IssueScoped(command string, args []string, scope TokenScope) (string, error) {
+	token, err := s.generate()
+	if err != nil {
 		return "", err
 	}
-	token := hex.EncodeToString(raw[:])
 
-	now := time.Now()
-	s.mu.Lock()
-	s.tokens[token] = &TokenEntry{
-		Command:   command,
-		Args:      args,
-		CreatedAt: now,
-		ExpiresAt: now.Add(s.ttl),
+	now := s.clock.Now()
+	err = s.update(func() bool {
+		s.tokens[token] = &TokenEntry{
+			Command:    command,
+			Args:       args,
+			TokenScope: scope,
+			CreatedAt:  now,
+			ExpiresAt:  now.Add(s.ttl),
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
 	}
-	s.mu.Unlock()
-
 	return token, nil
 }
 
-// Validate checks the token and consumes it (single-use). Returns the entry on success.
-// It also purges any expired tokens to keep the store bounded.
+func (s *TokenStore) generate() (string, error) {
+	if s.newID != nil {
+		return s.newID(), nil
+	}
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw[:]), nil
+}
+
+// Validate checks an unscoped use of the token and consumes it
+// (single-use). Returns the entry on success.
 func (s *TokenStore) Validate(token string, args []string) (*TokenEntry, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.ValidateScoped(token, args, TokenScope{})
+}
 
-	// Purge expired tokens inline (lock already held).
-	now := time.Now()
-	for tok, entry := range s.tokens {
-		if now.After(entry.ExpiresAt) {
-			delete(s.tokens, tok)
+// ValidateScoped checks the token and consumes it (single-use). A token
+// bound to a session or directory only validates from that same session
+// or directory. Returns the entry on success.
+func (s *TokenStore) ValidateScoped(token string, args []string, scope TokenScope) (*TokenEntry, error) {
+	var (
+		result *TokenEntry
+		verr   error
+	)
+	err := s.update(func() bool {
+		entry, ok := s.tokens[token]
+		if !ok {
+			verr = errors.New("unknown or expired approval token")
+			return false
+		}
+		switch entry.State(s.clock.Now()) {
+		case TokenUsed, TokenRevoked:
+			verr = errors.New("unknown or expired approval token")
+			return false
+		case TokenExpired:
+			verr = errors.New("approval token expired")
+			return false
 		}
-	}
 
-	entry, ok := s.tokens[token]
-	if !ok {
-		return nil, errors.New("unknown or expired approval token")
+		// Mark used immediately — single use regardless of outcome.
+		entry.UsedAt = s.clock.Now()
+
+		if !slices.Equal(args, entry.Args) {
+			verr = errors.New("approval token args mismatch")
+			return true
+		}
+		if entry.Session != "" && entry.Session != scope.Session {
+			verr = errors.New("approval token was issued to a different session")
+			return true
+		}
+		if entry.Cwd != "" && entry.Cwd != scope.Cwd {
+			verr = fmt.Errorf("approval token was issued for %s, not %s", entry.Cwd, scope.Cwd)
+			return true
+		}
+		e := *entry
+		result = &e
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, verr
+}
 
-	// Delete immediately — single use regardless of outcome.
-	delete(s.tokens, token)
+// Purge removes all expired tokens from the store.
+func (s *TokenStore) Purge() {
+	now := s.clock.Now()
+	err := s.update(func() bool {
+		changed := false
+		for token, entry := range s.tokens {
+			if now.After(entry.ExpiresAt) {
+				delete(s.tokens, token)
+				changed = true
+			}
+		}
+		return changed
+	})
+	if err != nil {
+		log.Printf("doit: tokens: %v", err)
+	}
+}
 
-	if time.Now().After(entry.ExpiresAt) {
-		return nil, errors.New("approval token expired")
+// Peek returns a copy of the entry for an outstanding (issued, unconsumed,
+// unexpired) token without consuming it.
+func (s *TokenStore) Peek(token string) (TokenEntry, bool) {
+	var (
+		entry TokenEntry
+		ok    bool
+	)
+	err := s.update(func() bool {
+		e, found := s.tokens[token]
+		if found && e.State(s.clock.Now()) == TokenOutstanding {
+			entry, ok = *e, true
+		}
+		return false
+	})
+	if err != nil {
+		log.Printf("doit: tokens: %v", err)
 	}
+	return entry, ok
+}
 
-	if !slices.Equal(args, entry.Args) {
-		return nil, errors.New("approval token args mismatch")
+// Revoke invalidates an outstanding token so it can no longer be used.
+// It reports whether the token was outstanding.
+func (s *TokenStore) Revoke(token string) bool {
+	revoked := false
+	err := s.update(func() bool {
+		e, ok := s.tokens[token]
+		if !ok || e.State(s.clock.Now()) != TokenOutstanding {
+			return false
+		}
+		e.RevokedAt = s.clock.Now()
+		revoked = true
+		return true
+	})
+	if err != nil {
+		log.Printf("doit: tokens: %v", err)
+		return false
 	}
+	return revoked
+}
 
-	return entry, nil
+// List returns every token on record, outstanding or not, oldest first.
+func (s *TokenStore) List() ([]TokenRecord, error) {
+	var out []TokenRecord
+	err := s.update(func() bool {
+		for token, e := range s.tokens {
+			out = append(out, TokenRecord{Token: token, TokenEntry: *e})
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
 }
 
-// Purge removes all expired tokens from the store.
-func (s *TokenStore) Purge() {
-	now := time.Now()
+// update runs fn with the store's tokens loaded and, if fn reports a
+// change, saves them. Records past their retention are dropped first.
+func (s *TokenStore) update(fn func() (changed bool)) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for token, entry := range s.tokens {
-		if now.After(entry.ExpiresAt) {
+	if s.path == "" {
+		s.prune()
+		fn()
+		return nil
+	}
+
+	unlock, err := lockTokenFile(s.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	pruned := s.prune()
+	if fn() || pruned {
+		return s.save()
+	}
+	return nil
+}
+
+// prune drops records that expired more than tokenRetention ago.
+func (s *TokenStore) prune() bool {
+	cutoff := s.clock.Now().Add(-tokenRetention)
+	pruned := false
+	for token, e := range s.tokens {
+		if e.ExpiresAt.Before(cutoff) {
 			delete(s.tokens, token)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+func (s *TokenStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.tokens = make(map[string]*TokenEntry)
+			return nil
 		}
+		return fmt.Errorf("read tokens: %w", err)
+	}
+	tokens := make(map[string]*TokenEntry)
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("parse tokens %s: %w", s.path, err)
+	}
+	s.tokens = tokens
+	return nil
+}
+
+func (s *TokenStore) save() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create tokens dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal tokens: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tokens-*.json")
+	if err != nil {
+		return fmt.Errorf("create temp tokens file: %w", err)
 	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("write temp tokens file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("close temp tokens file: %w", err)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("rename temp tokens file: %w", err)
+	}
+	return nil
+}
+
+// lockTokenFile takes an exclusive lock on path's companion lock file,
+// serialising token updates across processes.
+func lockTokenFile(path string) (unlock func(), err error) {
+	return lockFile(path, "tokens")
 }
@@ -4,9 +4,13 @@
 package policy
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/marcelocantos/doit/internal/clock"
 )
 
 func TestTokenIssueAndValidate(t *testing.T) {
@@ -48,13 +52,21 @@ func TestTokenSingleUse(t *testing.T) {
 }
 
 func TestTokenExpired(t *testing.T) {
-	store := NewTokenStore(1 * time.Millisecond)
+	fake := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewTokenStore(time.Minute, WithTokenClock(fake), WithTokenIDs(clock.Sequence("tok")))
 	args := []string{"push"}
 	token, err := store.Issue("git push", args)
 	if err != nil {
 		t.Fatalf("Issue: %v", err)
 	}
-	time.Sleep(5 * time.Millisecond)
+	if token != "tok-1" {
+		t.Errorf("token = %q, want tok-1", token)
+	}
+	fake.Advance(time.Minute)
+	if _, ok := store.Peek(token); !ok {
+		t.Fatal("token expired before its TTL had passed")
+	}
+	fake.Advance(time.Second)
 	_, err = store.Validate(token, args)
 	if err == nil {
 		t.Fatal("Validate: expected error for expired token, got nil")
@@ -91,7 +103,8 @@ func TestTokenUnknown(t *testing.T) {
 }
 
 func TestTokenPurge(t *testing.T) {
-	store := NewTokenStore(5 * time.Millisecond)
+	fake := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewTokenStore(5*time.Millisecond, WithTokenClock(fake))
 
 	tok1, err := store.Issue("cmd1", []string{"a"})
 	if err != nil {
@@ -102,11 +115,11 @@ func TestTokenPurge(t *testing.T) {
 		t.Fatalf("Issue tok2: %v", err)
 	}
 
-	time.Sleep(10 * time.Millisecond)
+	fake.Advance(10 * time.Millisecond)
 
 	// Issue a fresh token with a new store TTL isn't adjustable per-token, so create
 	// a new store with longer TTL for the fresh token.
-	freshStore := NewTokenStore(DefaultTokenTTL)
+	freshStore := NewTokenStore(DefaultTokenTTL, WithTokenClock(fake))
 	tok3, err := freshStore.Issue("cmd3", []string{"c"})
 	if err != nil {
 		t.Fatalf("Issue tok3: %v", err)
@@ -129,6 +142,34 @@ func TestTokenPurge(t *testing.T) {
 	}
 }
 
+func TestTokenPeekAndRevoke(t *testing.T) {
+	store := NewTokenStore(DefaultTokenTTL)
+	args := []string{"push"}
+	token, err := store.Issue("git push", args)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if entry, ok := store.Peek(token); !ok || entry.Command != "git push" {
+		t.Fatalf("Peek = %+v, %v", entry, ok)
+	}
+	// Peek does not consume.
+	if _, ok := store.Peek(token); !ok {
+		t.Fatal("token consumed by Peek")
+	}
+	if !store.Revoke(token) {
+		t.Fatal("Revoke returned false for outstanding token")
+	}
+	if store.Revoke(token) {
+		t.Error("second Revoke returned true")
+	}
+	if _, err := store.Validate(token, args); err == nil {
+		t.Error("revoked token still validates")
+	}
+	if _, ok := store.Peek(token); ok {
+		t.Error("Peek found revoked token")
+	}
+}
+
 func TestTokenIssueUniqueness(t *testing.T) {
 	store := NewTokenStore(DefaultTokenTTL)
 	tok1, err := store.Issue("cmd", []string{})
@@ -143,3 +184,90 @@ func TestTokenIssueUniqueness(t *testing.T) {
 		t.Errorf("tokens are identical: %q", tok1)
 	}
 }
+
+func TestTokenStorePersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	issuer := OpenTokenStore(path, DefaultTokenTTL)
+	other := OpenTokenStore(path, DefaultTokenTTL) // another process
+
+	args := []string{"git", "push"}
+	used, err := issuer.Issue("git push", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := issuer.Issue("rm -rf build", []string{"rm", "-rf", "build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := other.Peek(used); !ok {
+		t.Fatal("token not visible to a second store on the same file")
+	}
+	if !other.Revoke(revoked) {
+		t.Fatal("Revoke via second store failed")
+	}
+	if _, err := other.Validate(used, args); err != nil {
+		t.Fatalf("Validate via second store: %v", err)
+	}
+	if _, err := issuer.Validate(used, args); err == nil {
+		t.Error("token used by one store still validates in the other")
+	}
+
+	records, err := issuer.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]string{}
+	for _, r := range records {
+		states[r.Token] = r.State(time.Now())
+	}
+	if len(records) != 2 || states[used] != TokenUsed || states[revoked] != TokenRevoked {
+		t.Errorf("states = %v", states)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("token file: %v, mode %v", err, info.Mode())
+	}
+}
+
+func TestTokenScope(t *testing.T) {
+	store := NewTokenStore(DefaultTokenTTL)
+	args := []string{"git", "push"}
+	scope := TokenScope{Session: "s1", Cwd: "/repo/a"}
+
+	for _, tc := range []struct {
+		name  string
+		scope TokenScope
+		want  string // error substring; empty for success
+	}{
+		{"same scope", scope, ""},
+		{"other session", TokenScope{Session: "s2", Cwd: "/repo/a"}, "different session"},
+		{"other cwd", TokenScope{Session: "s1", Cwd: "/repo/b"}, "issued for /repo/a"},
+		{"unscoped", TokenScope{}, "different session"},
+	} {
+		token, err := store.IssueScoped("git push", args, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.ValidateScoped(token, args, tc.scope)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+		// A failed replay still spends the token.
+		if _, err := store.ValidateScoped(token, args, scope); err == nil {
+			t.Errorf("%s: token reusable after first use", tc.name)
+		}
+	}
+
+	// Unbound tokens, such as those issued from the CLI, work anywhere.
+	token, err := store.Issue("git push", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ValidateScoped(token, args, scope); err != nil {
+		t.Errorf("unbound token: %v", err)
+	}
+}
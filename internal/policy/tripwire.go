@@ -0,0 +1,131 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// TripwireRuleID marks the denial of a command that touches a tripwire.
+const TripwireRuleID = "tripwire"
+
+// Tripwires are paths no command has reason to touch, such as a real
+// private key or a decoy secrets file planted as a honeytoken: touching
+// one is a sign of an agent gone astray. Each is a path, with ~ for the
+// home directory, or a glob. An absolute one names that path; a relative
+// one, such as secrets.env or .aws/credentials, names it in any
+// directory.
+type Tripwires []string
+
+// Check denies req if its command references a tripwire: as an argument,
+// an option's value, an @file argument, or a redirection, as written,
+// expanded as a glob, or through a symbolic link; relative paths resolve
+// against req.Cwd.
+func (t Tripwires) Check(req *Request) *Result {
+	tw := t.Touched(req)
+	if tw == "" {
+		return nil
+	}
+	return &Result{
+		Decision: Deny,
+		Level:    1,
+		Reason:   fmt.Sprintf("TRIPWIRE: the command touches %s, which no command may touch; the attempt has been reported", tw),
+		RuleID:   TripwireRuleID,
+	}
+}
+
+// redirectPrefix matches a redirection operator written against its
+// target, as in 2>err.log or <input.
+var redirectPrefix = regexp.MustCompile(`^[0-9]*[<>&|]+`)
+
+// Touched returns the tripwire the command of req references, or "".
+func (t Tripwires) Touched(req *Request) string {
+	return touchedPath(t, req)
+}
+
+// touchedPath returns the first of patterns the command of req
+// references, as Tripwires.Check describes, or "".
+func touchedPath(patterns []string, req *Request) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+	for _, words := range commandWords(req.Command) {
+		if p := touchedBy(patterns, words, req.Cwd); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// touchedBy returns the first of patterns that words, a simple command
+// run in cwd, references, or "".
+func touchedBy(patterns, words []string, cwd string) string {
+	for _, w := range words {
+		w = redirectPrefix.ReplaceAllString(w, "")
+		candidates := []string{w}
+		if _, v, ok := strings.Cut(w, "="); ok {
+			candidates = append(candidates, v)
+		}
+		if f, ok := strings.CutPrefix(w, "@"); ok {
+			candidates = append(candidates, f) // curl -d @file
+		}
+		for _, c := range candidates {
+			if c == "" {
+				continue
+			}
+			for _, path := range resolvedPaths(c, cwd) {
+				if p := matchPath(patterns, path); p != "" {
+					return p
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// resolvedPaths returns the paths word may name: the clean path, what a
+// glob expands to, and the targets of symbolic links among them.
+func resolvedPaths(word, cwd string) []string {
+	path := redirectPath(word, cwd)
+	paths := []string{path}
+	if strings.ContainsAny(word, "*?[") {
+		if matches, err := filepath.Glob(path); err == nil {
+			paths = append(paths, matches...)
+		}
+	}
+	for _, p := range paths {
+		if real, err := filepath.EvalSymlinks(p); err == nil && real != p {
+			paths = append(paths, real)
+		}
+	}
+	return paths
+}
+
+// matchPath returns the first of patterns that names path, or "".
+func matchPath(patterns []string, path string) string {
+	for _, p := range patterns {
+		pattern := filepath.Clean(expandHome(p))
+		if filepath.IsAbs(pattern) {
+			if ok, _ := filepath.Match(pattern, path); ok {
+				return p
+			}
+			if real, err := filepath.EvalSymlinks(pattern); err == nil && real == path {
+				return p
+			}
+			continue
+		}
+		n := strings.Count(pattern, "/") + 1
+		parts := strings.Split(path, "/")
+		if len(parts) < n {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, strings.Join(parts[len(parts)-n:], "/")); ok {
+			return p
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTripwires(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0o700)
+	os.WriteFile(filepath.Join(home, ".ssh", "id_rsa"), nil, 0o600)
+	os.WriteFile(filepath.Join(dir, "secrets.env"), nil, 0o600)
+	os.Symlink(filepath.Join(home, ".ssh", "id_rsa"), filepath.Join(dir, "key"))
+
+	tw := Tripwires{"~/.ssh/id_rsa", "secrets.env", ".aws/cred*"}
+	for cmd, want := range map[string]string{
+		"cat ~/.ssh/id_rsa":                         "~/.ssh/id_rsa",
+		"cat " + home + "/.ssh/../.ssh/id_rsa":      "~/.ssh/id_rsa",
+		"cat ~/.ssh/id_*":                           "~/.ssh/id_rsa",
+		"cat key":                                   "~/.ssh/id_rsa",
+		"ssh -o IdentityFile=~/.ssh/id_rsa host":    "~/.ssh/id_rsa",
+		"source ./secrets.env":                      "secrets.env",
+		"grep KEY < secrets.env":                    "secrets.env",
+		"echo x 2>secrets.env":                      "secrets.env",
+		"sh -c 'cat sub/secrets.env'":               "secrets.env",
+		"cat ~/.aws/credentials":                    ".aws/cred*",
+		"cat ~/.ssh/id_rsa.pub secrets.env.example": "",
+		"ls ~/.ssh":                                 "",
+	} {
+		if got := tw.Touched(&Request{Command: cmd, Cwd: dir}); got != want {
+			t.Errorf("%q touches %q, want %q", cmd, got, want)
+		}
+	}
+
+	r := tw.Check(&Request{Command: "cat secrets.env", Cwd: dir})
+	if r == nil || r.Decision != Deny || r.RuleID != TripwireRuleID {
+		t.Errorf("Check = %+v", r)
+	}
+	if r := Tripwires(nil).Check(&Request{Command: "cat secrets.env", Cwd: dir}); r != nil {
+		t.Errorf("no tripwires: %+v", r)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repoVar stands, in paths_within and paths_outside, for the root of the
+// repository the command runs in.
+const repoVar = "$REPO"
+
+// matchPaths reports whether every arg, resolved against cwd to an
+// absolute path without symbolic links, lies within one of within (if
+// any) and outside all of outside. Each of those is a directory: an
+// absolute path, or one beginning ~ or $REPO. Without a repository,
+// entries beginning $REPO name nothing. An arg the shell would expand or
+// unquote is not the path it names, so it matches neither.
+func matchPaths(args []string, cwd string, within, outside []string) bool {
+	in := resolveDirs(within, cwd)
+	out := resolveDirs(outside, cwd)
+	if len(within) > 0 && len(in) == 0 {
+		return false
+	}
+	for _, arg := range args {
+		if !literalPath(arg) {
+			return false
+		}
+		p := resolvePath(redirectPath(arg, cwd))
+		if len(within) > 0 && !underAny(p, in) {
+			return false
+		}
+		if underAny(p, out) {
+			return false
+		}
+	}
+	return true
+}
+
+// literalPath reports whether the shell passes arg on as it is, save for
+// a leading ~ or ~/, which redirectPath expands as the shell does: no
+// variables, substitutions, globs, braces, quotes, or escapes.
+func literalPath(arg string) bool {
+	if arg == "~" || strings.HasPrefix(arg, "~/") {
+		arg = arg[1:]
+	}
+	return !strings.ContainsAny(arg, "$`*?[{~'\"\\")
+}
+
+// resolveDirs returns dirs as resolved absolute paths, leaving out those
+// naming $REPO when cwd is in no repository.
+func resolveDirs(dirs []string, cwd string) []string {
+	var out []string
+	var root string
+	for _, d := range dirs {
+		if rest, ok := strings.CutPrefix(d, repoVar); ok {
+			if root == "" {
+				if root = repoRoot(cwd); root == "" {
+					continue
+				}
+			}
+			d = root + rest
+		}
+		out = append(out, resolvePath(filepath.Clean(expandHome(d))))
+	}
+	return out
+}
+
+// underAny reports whether path is one of dirs or lies beneath one.
+func underAny(path string, dirs []string) bool {
+	for _, d := range dirs {
+		if withinDir(path, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns path, which is absolute, with symbolic links
+// resolved as far as it exists, so that a link out of a directory does
+// not pass for a path within it.
+func resolvePath(path string) string {
+	var rest []string
+	for p := path; ; p = filepath.Dir(p) {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		if p == filepath.Dir(p) {
+			return path
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+	}
+}
+
+// repoRoot returns the root of the repository containing dir, the first
+// directory up from it holding .git, or "".
+func repoRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// checkPathDirs reports the first entry of dirs, under key, that is
+// neither absolute nor begins ~ or $REPO.
+func checkPathDirs(key string, dirs []string) error {
+	for _, d := range dirs {
+		if !filepath.IsAbs(d) && d != "~" && !strings.HasPrefix(d, "~/") && d != repoVar && !strings.HasPrefix(d, repoVar+"/") {
+			return fmt.Errorf("%s: %q is not absolute, and does not begin ~ or %s", key, d, repoVar)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRepo makes a repository with build and src directories, and a link
+// from build/out to a directory outside it, and returns its root.
+func testRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, d := range []string{".git", "build", "src"} {
+		if err := os.Mkdir(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(t.TempDir(), filepath.Join(root, "build", "out")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestMatchPaths(t *testing.T) {
+	root := testRepo(t)
+	src := filepath.Join(root, "src")
+	for _, tc := range []struct {
+		cwd             string
+		args            []string
+		within, outside []string
+		want            bool
+	}{
+		{root, []string{"build/a.o", "build/obj/b.o"}, []string{"$REPO/build"}, nil, true},
+		{src, []string{"../build/a.o"}, []string{"$REPO/build"}, nil, true},
+		{src, []string{filepath.Join(root, "build", "a.o")}, []string{"$REPO/build"}, nil, true},
+		{root, []string{"build"}, []string{"$REPO/build"}, nil, true},
+		{root, []string{"build/../src/main.go"}, []string{"$REPO/build"}, nil, false},
+		{root, []string{"build/a.o", "src/main.go"}, []string{"$REPO/build"}, nil, false},
+		{root, []string{"build/out/x"}, []string{"$REPO/build"}, nil, false}, // link out of build
+		{root, []string{"buildx/a"}, []string{"$REPO/build"}, nil, false},
+		{root, []string{"src/main.go"}, nil, []string{"$REPO/build"}, true},
+		{root, []string{"build/a.o"}, nil, []string{"$REPO/build"}, false},
+		{root, []string{"/etc/passwd"}, []string{"$REPO"}, nil, false},
+		{root, []string{"src/x", "/tmp/y"}, []string{"$REPO", "/tmp"}, nil, true},
+		{t.TempDir(), []string{"a"}, []string{"$REPO"}, nil, false}, // no repository
+		{root, []string{"$PWD/build/a"}, []string{"$REPO"}, nil, false},
+		{root, []string{"build/*"}, []string{"$REPO"}, nil, false},
+		{root, []string{"'build/a'"}, []string{"$REPO"}, nil, false},
+		{root, []string{"$HOME/x"}, nil, []string{"$REPO"}, false}, // not outside either
+	} {
+		if got := matchPaths(tc.args, tc.cwd, tc.within, tc.outside); got != tc.want {
+			t.Errorf("matchPaths(%q, %s, %q, %q) = %v, want %v", tc.args, tc.cwd, tc.within, tc.outside, got, tc.want)
+		}
+	}
+}
+
+func TestLevel2PathsWithin(t *testing.T) {
+	root := testRepo(t)
+	l2 := NewLevel2([]PolicyEntry{{
+		ID:        "allow-rm-build",
+		Match:     MatchCriteria{Cap: "rm", PathsWithin: []string{"$REPO/build"}},
+		Decision:  "allow",
+		Reasoning: "build artifacts are regenerated",
+		Approved:  true,
+	}})
+	for _, tc := range []struct {
+		command, cwd string
+		want         Decision
+	}{
+		{"rm -rf build/obj", root, Allow},
+		{"rm -rf ../build/obj", filepath.Join(root, "src"), Allow},
+		{"rm -rf " + filepath.Join(root, "build", "obj"), root, Allow},
+		{"rm -rf src", root, Escalate},
+		{"rm -rf build/obj", "", Escalate}, // relative to nothing
+		{"rm -rf", root, Escalate},         // nothing to be within
+		// Words the shell expands or unquotes are not the paths they look
+		// like, and a compound command does more than its first segment.
+		{"rm $PWD/../other.txt", root, Escalate},
+		{"rm ${PWD}/../other.txt", root, Escalate},
+		{"rm $OLDPWD/other.txt", root, Escalate},
+		{"rm -f `dirname $PWD`/other.txt", root, Escalate},
+		{"rm -f $(dirname $PWD)/other.txt", root, Escalate},
+		{"rm build/x; cd ..; rm other.txt", root, Escalate},
+		{"rm build/x && rm ../other.txt", root, Escalate},
+		{"rm build/*", root, Escalate},
+		{"rm build/?", root, Escalate},
+		{"rm build/[ab]", root, Escalate},
+		{"rm build/{a,../../x}", root, Escalate},
+		{"rm build/~x", root, Escalate},
+		{"rm 'build/x'", root, Escalate},
+		{`rm "build/x"`, root, Escalate},
+		{`rm build\/x`, root, Escalate},
+	} {
+		if got := l2.Evaluate(&Request{Command: tc.command, Cwd: tc.cwd}).Decision; got != tc.want {
+			t.Errorf("%q in %q: got %v, want %v", tc.command, tc.cwd, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPathDirs(t *testing.T) {
+	if err := checkPathDirs("paths_within", []string{"/tmp", "~", "~/src", "$REPO", "$REPO/build"}); err != nil {
+		t.Errorf("valid dirs: %v", err)
+	}
+	for _, bad := range []string{"build", "./build", "$REPOS", "~user/x"} {
+		if err := checkPathDirs("paths_within", []string{bad}); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package proc runs commands in their own process group, so that
+// signalling or cancelling a command reaches everything it spawned
+// (make → cc, npm → node), not just its first process.
+package proc
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultGrace is how long a cancelled command has between SIGTERM and
+// SIGKILL unless configured otherwise.
+const DefaultGrace = 5 * time.Second
+
+// Isolate configures cmd, which must come from exec.CommandContext and not
+// yet be started, to lead a new process group.
+// Cancelling its context sends SIGTERM to the group and, if the command
+// has not exited after grace, SIGKILL.
+func Isolate(cmd *exec.Cmd, grace time.Duration) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		err := Signal(cmd, syscall.SIGTERM)
+		time.AfterFunc(grace, func() { Signal(cmd, syscall.SIGKILL) })
+		return err
+	}
+	cmd.WaitDelay = grace
+}
+
+// Signal sends s to the process group of a command started after
+// Isolate, or to the command alone if it does not lead a group.
+func Signal(cmd *exec.Cmd, s os.Signal) error {
+	sig, ok := s.(syscall.Signal)
+	if !ok || cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		return cmd.Process.Signal(s)
+	}
+	err := syscall.Kill(-cmd.Process.Pid, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// ExitStatus returns the exit code of a finished command in shell
+// convention, 128+n if signal n killed it, together with the signal's
+// name (SIGKILL, SIGINT) or "" if it exited normally.
+func ExitStatus(state *os.ProcessState) (code int, signal string) {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal()), unix.SignalName(ws.Signal())
+	}
+	return state.ExitCode(), ""
+}
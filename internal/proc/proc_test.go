@@ -0,0 +1,119 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package proc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// grandchild starts sh running a background sleep, waits for the sleep's
+// PID, and returns it.
+func grandchild(t *testing.T, cmd *exec.Cmd, pidFile string) int {
+	t.Helper()
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return pid
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("grandchild never started")
+	return 0
+}
+
+// waitGone polls until pid no longer runs. A zombie counts as gone: it
+// is dead, just not yet reaped by whoever inherited it.
+func waitGone(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if errors.Is(syscall.Kill(pid, 0), syscall.ESRCH) {
+			return
+		}
+		if stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat"); err == nil {
+			if f := strings.Fields(string(stat)); len(f) > 2 && f[2] == "Z" {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	syscall.Kill(pid, syscall.SIGKILL)
+	t.Errorf("grandchild %d outlived its cancelled parent", pid)
+}
+
+func TestIsolate_CancelKillsGrandchildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", "sleep 30 & echo $! > "+pidFile+"; wait")
+	Isolate(cmd, 200*time.Millisecond)
+
+	pid := grandchild(t, cmd, pidFile)
+	cancel()
+	cmd.Wait()
+	waitGone(t, pid)
+}
+
+func TestIsolate_KillsGroupIgnoringTerm(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", "trap '' TERM; sleep 30 & echo $! > "+pidFile+"; wait")
+	Isolate(cmd, 200*time.Millisecond)
+
+	pid := grandchild(t, cmd, pidFile)
+	cancel()
+	cmd.Wait()
+	waitGone(t, pid)
+}
+
+func TestSignal_ReachesGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	cmd := exec.CommandContext(context.Background(), "sh", "-c", "sleep 30 & echo $! > "+pidFile+"; wait")
+	Isolate(cmd, time.Second)
+
+	pid := grandchild(t, cmd, pidFile)
+	if err := Signal(cmd, syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Wait()
+	waitGone(t, pid)
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		script string
+		code   int
+		signal string
+	}{
+		{"exit 0", 0, ""},
+		{"exit 3", 3, ""},
+		{"kill -KILL $$", 137, "SIGKILL"},
+		{"kill -INT $$", 130, "SIGINT"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command("sh", "-c", tt.script)
+		cmd.Run()
+		code, signal := ExitStatus(cmd.ProcessState)
+		if code != tt.code || signal != tt.signal {
+			t.Errorf("%q: got %d %q, want %d %q", tt.script, code, signal, tt.code, tt.signal)
+		}
+	}
+}
@@ -6,30 +6,71 @@ package rules
 import "fmt"
 
 // CapRuleConfig represents one capability's rules from YAML config.
+// Rules are bypassable with a retry unless Bypassable is set to false;
+// subcommand rules inherit the capability's setting unless they set their
+// own.
 type CapRuleConfig struct {
-	RejectFlags []string                `yaml:"reject_flags"`
+	RejectFlags []string                 `yaml:"reject_flags"`
+	Bypassable  *bool                    `yaml:"bypassable,omitempty"`
 	Subcommands map[string]SubRuleConfig `yaml:"subcommands"`
 }
 
 // SubRuleConfig represents rules for a specific subcommand.
 type SubRuleConfig struct {
 	RejectFlags []string `yaml:"reject_flags"`
+	Bypassable  *bool    `yaml:"bypassable,omitempty"`
+}
+
+// IsBypassable reports whether the capability-level rule is bypassable.
+func (c CapRuleConfig) IsBypassable() bool {
+	return c.Bypassable == nil || *c.Bypassable
+}
+
+// SubBypassable reports whether the rule for subcommand sub is bypassable.
+func (c CapRuleConfig) SubBypassable(sub string) bool {
+	if b := c.Subcommands[sub].Bypassable; b != nil {
+		return *b
+	}
+	return c.IsBypassable()
+}
+
+// BypassNote is the parenthetical that denial messages use to say whether
+// a config rule can be bypassed.
+func BypassNote(bypassable bool) string {
+	if bypassable {
+		return "config rule, bypassable"
+	}
+	return "config rule, not bypassable"
 }
 
 // CompileCapRule turns a single capability's config into CheckFuncs.
 func CompileCapRule(capName string, cfg CapRuleConfig) []CheckFunc {
-	var fns []CheckFunc
+	bypassable, permanent := CompileCapRuleByBypass(capName, cfg)
+	return append(bypassable, permanent...)
+}
+
+// CompileCapRuleByBypass is like CompileCapRule but separates the
+// bypassable rules from those configured with bypassable: false.
+func CompileCapRuleByBypass(capName string, cfg CapRuleConfig) (bypassable, permanent []CheckFunc) {
+	add := func(b bool, fn CheckFunc) {
+		if b {
+			bypassable = append(bypassable, fn)
+		} else {
+			permanent = append(permanent, fn)
+		}
+	}
 
 	// Top-level reject_flags for the whole capability.
 	if len(cfg.RejectFlags) > 0 {
 		flags := cfg.RejectFlags
 		name := capName
-		fns = append(fns, func(cn string, args []string) error {
+		note := BypassNote(cfg.IsBypassable())
+		add(cfg.IsBypassable(), func(cn string, args []string) error {
 			if cn != name {
 				return nil
 			}
-			if HasAnyFlag(args, flags...) {
-				return fmt.Errorf("rejected flag for %s (config rule, bypassable)", name)
+			if HasFlag(name, args, flags...) {
+				return fmt.Errorf("rejected flag for %s (%s)", name, note)
 			}
 			return nil
 		})
@@ -41,17 +82,23 @@ func CompileCapRule(capName string, cfg CapRuleConfig) []CheckFunc {
 			flags := subRule.RejectFlags
 			name := capName
 			sub := subcmd
-			fns = append(fns, func(cn string, args []string) error {
-				if cn != name || len(args) == 0 || args[0] != sub {
+			b := cfg.SubBypassable(sub)
+			note := BypassNote(b)
+			add(b, func(cn string, args []string) error {
+				if cn != name {
+					return nil
+				}
+				args = normalizeArgs(cn, args)
+				if len(args) == 0 || args[0] != sub {
 					return nil
 				}
-				if HasAnyFlag(args[1:], flags...) {
-					return fmt.Errorf("%s: rejected flag for %s (config rule, bypassable)", sub, name)
+				if HasFlag(name+" "+sub, args[1:], flags...) {
+					return fmt.Errorf("%s: rejected flag for %s (%s)", sub, name, note)
 				}
 				return nil
 			})
 		}
 	}
 
-	return fns
+	return bypassable, permanent
 }
@@ -3,7 +3,10 @@
 
 package rules
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestCompileCapRuleRejectFlags(t *testing.T) {
 	cfg := CapRuleConfig{
@@ -108,3 +111,54 @@ func TestCompileCapRuleMixed(t *testing.T) {
 		t.Errorf("expected pull --force to pass, got %v", err)
 	}
 }
+
+func TestDescribeCapRules(t *testing.T) {
+	infos := DescribeCapRules(map[string]CapRuleConfig{
+		"make": {RejectFlags: []string{"-j"}},
+		"git": {Subcommands: map[string]SubRuleConfig{
+			"reset": {RejectFlags: []string{"--hard"}},
+			"push":  {RejectFlags: []string{"--force"}},
+			"log":   {},
+		}},
+	})
+	want := []string{"config:git.push", "config:git.reset", "config:make"}
+	if len(infos) != len(want) {
+		t.Fatalf("expected %d infos, got %+v", len(want), infos)
+	}
+	for i, id := range want {
+		if infos[i].ID != id {
+			t.Errorf("infos[%d].ID = %q, want %q", i, infos[i].ID, id)
+		}
+		if !infos[i].Bypassable {
+			t.Errorf("infos[%d] should be bypassable", i)
+		}
+	}
+	if infos[1].Subcommand != "reset" || infos[1].RejectFlags[0] != "--hard" {
+		t.Errorf("unexpected git reset info: %+v", infos[1])
+	}
+}
+
+func TestCompileCapRuleNotBypassable(t *testing.T) {
+	no := false
+	cfg := CapRuleConfig{
+		RejectFlags: []string{"-v"},
+		Subcommands: map[string]SubRuleConfig{
+			"push": {RejectFlags: []string{"--force"}, Bypassable: &no},
+		},
+	}
+	bypassable, permanent := CompileCapRuleByBypass("git", cfg)
+	if len(bypassable) != 1 || len(permanent) != 1 {
+		t.Fatalf("got %d bypassable and %d permanent rules, want 1 and 1", len(bypassable), len(permanent))
+	}
+
+	rs := NewRuleSet()
+	rs.AddConfig(bypassable[0])
+	rs.AddPermanent(permanent[0])
+	if err := rs.Check("git", []string{"status", "-v"}, true); err != nil {
+		t.Errorf("expected retry to skip the bypassable rule, got %v", err)
+	}
+	err := rs.Check("git", []string{"push", "--force"}, true)
+	if err == nil || !strings.Contains(err.Error(), "not bypassable") {
+		t.Errorf("expected the non-bypassable rule to hold on retry, got %v", err)
+	}
+}
@@ -45,7 +45,7 @@ func checkRmCatastrophic(capName string, args []string) error {
 	if capName != "rm" {
 		return nil
 	}
-	if !HasAnyFlag(args, "-r", "-R") {
+	if !HasFlag("rm", args, "-r", "-R") {
 		return nil
 	}
 	for _, arg := range args {
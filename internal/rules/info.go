@@ -0,0 +1,82 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package rules
+
+import "sort"
+
+// Info describes an active rule for manifests and generated documentation.
+// CheckFuncs are opaque, so each source of rules reports its own Info.
+type Info struct {
+	ID          string   `json:"id"`
+	Capability  string   `json:"capability,omitempty"`
+	Subcommand  string   `json:"subcommand,omitempty"`
+	RejectFlags []string `json:"reject_flags,omitempty"`
+	Description string   `json:"description"`
+	Bypassable  bool     `json:"bypassable"`
+}
+
+// HardcodedInfo describes the rules returned by Hardcoded.
+func HardcodedInfo() []Info {
+	return []Info{{
+		ID:          "rm-catastrophic",
+		Capability:  "rm",
+		Description: "recursive removal of /, ., .., or ~",
+	}}
+}
+
+// DefaultProgrammaticInfo describes the programmatic default config rules
+// (those that cannot be expressed as reject_flags), such as
+// CheckGitCheckoutAll.
+func DefaultProgrammaticInfo() []Info {
+	return []Info{{
+		ID:          "git-checkout-all",
+		Capability:  "git",
+		Subcommand:  "checkout",
+		Description: "git checkout . discards all uncommitted changes",
+		Bypassable:  true,
+	}}
+}
+
+// DescribeCapRules describes the rules CompileCapRule produces for each
+// capability in cfg, sorted by capability then subcommand.
+func DescribeCapRules(cfg map[string]CapRuleConfig) []Info {
+	var infos []Info
+	names := make([]string, 0, len(cfg))
+	for name := range cfg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := cfg[name]
+		if len(c.RejectFlags) > 0 {
+			infos = append(infos, Info{
+				ID:          "config:" + name,
+				Capability:  name,
+				RejectFlags: c.RejectFlags,
+				Description: "rejected flags for " + name,
+				Bypassable:  c.IsBypassable(),
+			})
+		}
+		subs := make([]string, 0, len(c.Subcommands))
+		for sub := range c.Subcommands {
+			subs = append(subs, sub)
+		}
+		sort.Strings(subs)
+		for _, sub := range subs {
+			flags := c.Subcommands[sub].RejectFlags
+			if len(flags) == 0 {
+				continue
+			}
+			infos = append(infos, Info{
+				ID:          "config:" + name + "." + sub,
+				Capability:  name,
+				Subcommand:  sub,
+				RejectFlags: flags,
+				Description: "rejected flags for " + name + " " + sub,
+				Bypassable:  c.SubBypassable(sub),
+			})
+		}
+	}
+	return infos
+}
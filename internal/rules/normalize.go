@@ -0,0 +1,430 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package rules
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FlagSpec describes the flags of a command, or of one of a command's
+// subcommands, so that every way of writing a flag is matched as that
+// flag.
+type FlagSpec struct {
+	// Short lists the short flags getopt-style: each letter, followed by
+	// ":" if it takes a value, as in "fC:".
+	Short string
+
+	// Long lists the long flags without their dashes, followed by "=" if
+	// they take a value, or "=?" if they may take one (attached only).
+	Long []string
+
+	// Alias maps short flags to the long flag each is another name for,
+	// as in 'f' → "force".
+	Alias map[byte]string
+
+	// Same maps long flags to the long flag each is another name for, as
+	// in "just-print" → "dry-run".
+	Same map[string]string
+
+	// Subcommands have their own specs, under "cmd sub". Flags before the
+	// subcommand, which this spec describes, are moved after it.
+	Subcommands bool
+}
+
+// flagSpecs are the FlagSpecs of commands whose flags policy rules
+// commonly name, by program or "program subcommand".
+var flagSpecs = map[string]FlagSpec{
+	"rm": {
+		Short: "fiIrRdv",
+		Long:  []string{"force", "interactive=?", "recursive", "dir", "verbose", "one-file-system", "no-preserve-root", "preserve-root=?"},
+		Alias: map[byte]string{'f': "force", 'r': "recursive", 'R': "recursive", 'd': "dir", 'v': "verbose"},
+	},
+	"cp": {
+		Short: "afinrRvpt:T",
+		Long:  []string{"archive", "force", "interactive", "no-clobber", "recursive", "verbose", "preserve=?", "target-directory=", "no-target-directory"},
+		Alias: map[byte]string{'a': "archive", 'f': "force", 'i': "interactive", 'n': "no-clobber", 'r': "recursive", 'R': "recursive", 'v': "verbose", 't': "target-directory", 'T': "no-target-directory"},
+	},
+	"mv": {
+		Short: "finvt:T",
+		Long:  []string{"force", "interactive", "no-clobber", "verbose", "target-directory=", "no-target-directory"},
+		Alias: map[byte]string{'f': "force", 'i': "interactive", 'n': "no-clobber", 'v': "verbose", 't': "target-directory", 'T': "no-target-directory"},
+	},
+	"chmod": {
+		Short: "cfvR",
+		Long:  []string{"changes", "silent", "quiet", "verbose", "recursive", "reference="},
+		Alias: map[byte]string{'c': "changes", 'f': "silent", 'v': "verbose", 'R': "recursive"},
+		Same:  map[string]string{"quiet": "silent"},
+	},
+	"chown": {
+		Short: "cfvhR",
+		Long:  []string{"changes", "silent", "quiet", "verbose", "no-dereference", "recursive", "reference=", "from="},
+		Alias: map[byte]string{'c': "changes", 'f': "silent", 'v': "verbose", 'h': "no-dereference", 'R': "recursive"},
+		Same:  map[string]string{"quiet": "silent"},
+	},
+	"chgrp": {
+		Short: "cfvhR",
+		Long:  []string{"changes", "silent", "quiet", "verbose", "no-dereference", "recursive", "reference="},
+		Alias: map[byte]string{'c': "changes", 'f': "silent", 'v': "verbose", 'h': "no-dereference", 'R': "recursive"},
+		Same:  map[string]string{"quiet": "silent"},
+	},
+	"make": {
+		Short: "BC:f:ij::knqsS",
+		Long:  []string{"always-make", "directory=", "file=", "makefile=", "ignore-errors", "jobs=?", "keep-going", "load-average=?", "dry-run", "just-print", "recon", "question", "silent", "quiet", "no-keep-going", "stop"},
+		Alias: map[byte]string{'B': "always-make", 'C': "directory", 'f': "file", 'i': "ignore-errors", 'j': "jobs", 'k': "keep-going", 'n': "dry-run", 'q': "question", 's': "silent", 'S': "no-keep-going"},
+		Same:  map[string]string{"makefile": "file", "just-print": "dry-run", "recon": "dry-run", "quiet": "silent", "stop": "no-keep-going"},
+	},
+	"git": {
+		Short:       "C:c:pP",
+		Long:        []string{"git-dir=", "work-tree=", "namespace=", "paginate", "no-pager", "bare", "no-replace-objects", "exec-path=?", "config-env="},
+		Alias:       map[byte]string{'p': "paginate", 'P': "no-pager"},
+		Subcommands: true,
+	},
+	"git push": {
+		Short: "fnquvdo:",
+		Long:  []string{"force", "force-with-lease=?", "force-if-includes", "dry-run", "quiet", "verbose", "set-upstream", "all", "mirror", "tags", "delete", "no-verify", "verify", "repo=", "push-option=", "atomic", "prune"},
+		Alias: map[byte]string{'f': "force", 'n': "dry-run", 'q': "quiet", 'u': "set-upstream", 'v': "verbose", 'd': "delete", 'o': "push-option"},
+	},
+	"git reset": {
+		Short: "qp",
+		Long:  []string{"hard", "soft", "mixed", "merge", "keep", "quiet", "patch", "recurse-submodules=?"},
+		Alias: map[byte]string{'q': "quiet", 'p': "patch"},
+	},
+	"git clean": {
+		Short: "dfinqxXe:",
+		Long:  []string{"force", "interactive", "dry-run", "quiet", "exclude="},
+		Alias: map[byte]string{'f': "force", 'i': "interactive", 'n': "dry-run", 'q': "quiet", 'e': "exclude"},
+	},
+	"git checkout": {
+		Short: "fqmpb:B:",
+		Long:  []string{"force", "quiet", "merge", "patch", "detach", "orphan=", "ours", "theirs", "track=?"},
+		Alias: map[byte]string{'f': "force", 'q': "quiet", 'm': "merge", 'p': "patch"},
+	},
+	"git branch": {
+		Short: "dDfmMcCravlu:",
+		Long:  []string{"delete", "force", "move", "copy", "remotes", "all", "verbose", "list", "set-upstream-to="},
+		Alias: map[byte]string{'d': "delete", 'f': "force", 'm': "move", 'c': "copy", 'r': "remotes", 'a': "all", 'v': "verbose", 'l': "list", 'u': "set-upstream-to"},
+	},
+	"git commit": {
+		Short: "anqvm:F:C:",
+		Long:  []string{"all", "no-verify", "verify", "quiet", "verbose", "message=", "file=", "reuse-message=", "amend", "allow-empty", "no-edit"},
+		Alias: map[byte]string{'a': "all", 'n': "no-verify", 'q': "quiet", 'v': "verbose", 'm': "message", 'F': "file", 'C': "reuse-message"},
+	},
+}
+
+// shortValue reports whether short flag c of s is known, and whether it
+// takes a value: 1 if it must, 2 if it may (attached only).
+func (s *FlagSpec) shortValue(c byte) (known bool, value int) {
+	i := strings.IndexByte(s.Short, c)
+	if i < 0 || c == ':' {
+		return false, 0
+	}
+	rest := s.Short[i+1:]
+	switch {
+	case strings.HasPrefix(rest, "::"):
+		return true, 2
+	case strings.HasPrefix(rest, ":"):
+		return true, 1
+	}
+	return true, 0
+}
+
+// long resolves a long flag name of s, which may be an unambiguous
+// abbreviation as getopt_long allows, to its canonical name, reporting
+// whether it takes a value as shortValue does.
+func (s *FlagSpec) long(name string) (canonical string, value int, ok bool) {
+	var matches []string
+	for _, l := range s.Long {
+		n := strings.TrimRight(l, "=?")
+		if n == name {
+			matches = []string{l}
+			break
+		}
+		if strings.HasPrefix(n, name) {
+			matches = append(matches, l)
+		}
+	}
+	if len(matches) != 1 {
+		return "", 0, false
+	}
+	l := matches[0]
+	canonical = strings.TrimRight(l, "=?")
+	switch {
+	case strings.HasSuffix(l, "=?"):
+		value = 2
+	case strings.HasSuffix(l, "="):
+		value = 1
+	}
+	if same, ok := s.Same[canonical]; ok {
+		canonical = same
+	}
+	return canonical, value, true
+}
+
+// shortName returns the canonical spelling of short flag c: its long
+// alias, or itself.
+func (s *FlagSpec) shortName(c byte) string {
+	if l, ok := s.Alias[c]; ok {
+		if same, ok := s.Same[l]; ok {
+			l = same
+		}
+		return "--" + l
+	}
+	return "-" + string(c)
+}
+
+// specFor returns the FlagSpec of program prog (by base name), or of
+// its subcommand sub if sub is not empty; nil if there is none.
+func specFor(prog, sub string) *FlagSpec {
+	key := filepath.Base(prog)
+	if sub != "" {
+		key += " " + sub
+	}
+	if s, ok := flagSpecs[key]; ok {
+		return &s
+	}
+	return nil
+}
+
+// Normalize returns the words of a simple command, program first, with
+// their flags written one way, so that rules naming a flag match however
+// it was written:
+//
+//   - a short flag with a long alias becomes the long flag (-f → --force);
+//   - combined short flags are separated (-rf → --recursive --force);
+//   - an abbreviated long flag is completed (--recur → --recursive), and one
+//     with another name takes the first (--just-print → --dry-run);
+//   - a flag's value is attached (-C dir → --directory=dir for make,
+//     -Cdir for git, whose -C has no long name; --repo origin →
+//     --repo=origin);
+//   - flags before a subcommand move after it, so that git -C dir push
+//     reads as git push -Cdir.
+//
+// Flags a command's spec does not know, and the words of commands
+// without one, are left as they are.
+func Normalize(words []string) []string {
+	if len(words) == 0 {
+		return words
+	}
+	spec := specFor(words[0], "")
+	if spec == nil {
+		return words
+	}
+	if !spec.Subcommands {
+		return append([]string{words[0]}, normalizeFlags(spec, words[1:])...)
+	}
+	// Find the subcommand: the first word that is not a global flag or
+	// its value.
+	var global []string
+	i := 1
+	for i < len(words) {
+		n := flagWords(spec, words[i:])
+		if n == 0 {
+			break
+		}
+		global = append(global, words[i:i+n]...)
+		i += n
+	}
+	if i == len(words) {
+		return append([]string{words[0]}, normalizeFlags(spec, global)...)
+	}
+	sub := words[i]
+	out := []string{words[0], sub}
+	out = append(out, normalizeFlags(spec, global)...)
+	if subSpec := specFor(words[0], sub); subSpec != nil {
+		return append(out, normalizeFlags(subSpec, words[i+1:])...)
+	}
+	return append(out, words[i+1:]...)
+}
+
+// normalizeArgs returns args, the arguments of program prog, as
+// Normalize writes them.
+func normalizeArgs(prog string, args []string) []string {
+	return Normalize(append([]string{prog}, args...))[1:]
+}
+
+// flagWords returns how many of args the flag args[0] spans under spec,
+// counting a separate value, or 0 if args[0] is not a flag.
+func flagWords(spec *FlagSpec, args []string) int {
+	a := args[0]
+	switch {
+	case a == "--" || len(a) < 2 || a[0] != '-':
+		return 0
+	case strings.HasPrefix(a, "--"):
+		name, _, attached := strings.Cut(a[2:], "=")
+		if _, value, ok := spec.long(name); ok && value == 1 && !attached && len(args) > 1 {
+			return 2
+		}
+		return 1
+	}
+	for j := 1; j < len(a); j++ {
+		known, value := spec.shortValue(a[j])
+		if !known {
+			return 1
+		}
+		if value > 0 {
+			if value == 1 && j == len(a)-1 && len(args) > 1 {
+				return 2
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// normalizeFlags writes the flags among args one way, as Normalize
+// describes; everything from "--" on is left as it is.
+func normalizeFlags(spec *FlagSpec, args []string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(out, args[i:]...)
+		case len(a) < 2 || a[0] != '-':
+			out = append(out, a)
+		case strings.HasPrefix(a, "--"):
+			name, val, attached := strings.Cut(a[2:], "=")
+			canonical, value, ok := spec.long(name)
+			switch {
+			case !ok:
+				out = append(out, a)
+			case attached:
+				out = append(out, "--"+canonical+"="+val)
+			case value == 1 && i+1 < len(args):
+				i++
+				out = append(out, "--"+canonical+"="+args[i])
+			default:
+				out = append(out, "--"+canonical)
+			}
+		default:
+			flags, used, ok := normalizeShort(spec, a, args[i+1:])
+			if !ok {
+				out = append(out, a)
+				continue
+			}
+			out = append(out, flags...)
+			i += used
+		}
+	}
+	return out
+}
+
+// normalizeShort separates a, one or more combined short flags, and
+// attaches any value, taken from next if not from a; used is how many
+// words of next that took. ok is false if a holds a flag spec does not
+// know.
+func normalizeShort(spec *FlagSpec, a string, next []string) (flags []string, used int, ok bool) {
+	for j := 1; j < len(a); j++ {
+		known, value := spec.shortValue(a[j])
+		if !known {
+			return nil, 0, false
+		}
+		name := spec.shortName(a[j])
+		if value == 0 {
+			flags = append(flags, name)
+			continue
+		}
+		val := a[j+1:]
+		if val == "" && value == 1 && len(next) > 0 {
+			val, used = next[0], 1
+		}
+		switch {
+		case val == "" && value == 2:
+			flags = append(flags, name)
+		case strings.HasPrefix(name, "--"):
+			flags = append(flags, name+"="+val)
+		default:
+			flags = append(flags, name+val)
+		}
+		return flags, used, true
+	}
+	return flags, 0, true
+}
+
+// FlagSpellings returns flags, as a rule names them for command (a
+// program, or "program subcommand"), together with the spellings
+// Normalize gives them, so that a rule matches a flag in normalized
+// arguments however either wrote it.
+func FlagSpellings(command string, flags []string) []string {
+	prog, sub, _ := strings.Cut(command, " ")
+	spec := specFor(prog, sub)
+	if spec == nil {
+		return flags
+	}
+	out := slices.Clone(flags)
+	for _, f := range flags {
+		switch {
+		case strings.HasPrefix(f, "--"):
+			if canonical, _, ok := spec.long(strings.TrimPrefix(f, "--")); ok {
+				out = append(out, "--"+canonical)
+			}
+		case len(f) == 2 && f[0] == '-':
+			if known, _ := spec.shortValue(f[1]); known {
+				out = append(out, spec.shortName(f[1]))
+			}
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// HasFlag reports whether args, the arguments of command (a program, or
+// "program subcommand"), use any of flags, however either writes them.
+// Where the command has a FlagSpec, it reads args as getopt would: the
+// value of a short flag, attached or not, is not taken for more flags,
+// so -bfix for git checkout (branch "fix") does not use -f. Otherwise,
+// and for short flags the spec does not know, it falls back on
+// HasAnyFlag.
+func HasFlag(command string, args []string, flags ...string) bool {
+	prog, sub, _ := strings.Cut(command, " ")
+	spec := specFor(prog, sub)
+	flags = FlagSpellings(command, flags)
+	if spec == nil {
+		return HasAnyFlag(args, flags...)
+	}
+	// Normalize moves the program's own flags after its subcommand.
+	var parent *FlagSpec
+	if sub != "" {
+		parent = specFor(prog, "")
+	}
+	args = normalizeFlags(spec, args)
+	for _, a := range args {
+		switch {
+		case a == "--":
+			return false
+		case len(a) < 2 || a[0] != '-':
+			continue
+		case strings.HasPrefix(a, "--"):
+			if HasAnyFlag([]string{a}, flags...) {
+				return true
+			}
+			continue
+		}
+		// Normalization left a short flag: one without a long alias,
+		// with its value attached, or a cluster holding an unknown flag.
+		for j := 1; j < len(a); j++ {
+			known, value := spec.shortValue(a[j])
+			if !known && j == 1 && parent != nil {
+				if known, value := parent.shortValue(a[j]); known && value > 0 {
+					break // -Cdir, git's own
+				}
+			}
+			if !known {
+				if HasAnyFlag([]string{a}, flags...) {
+					return true
+				}
+				break
+			}
+			if slices.Contains(flags, "-"+string(a[j])) || slices.Contains(flags, spec.shortName(a[j])) {
+				return true
+			}
+			if value > 0 {
+				break // the rest is its value
+			}
+		}
+	}
+	return false
+}
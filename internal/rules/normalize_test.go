@@ -0,0 +1,77 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"rm -rf build", "rm --recursive --force build"},
+		{"rm --recur -- -f", "rm --recursive -- -f"},
+		{"rm -rfx build", "rm -rfx build"}, // -x is unknown: left alone
+		{"git push -f origin main", "git push --force origin main"},
+		{"git push --force-with origin", "git push --force-with-lease origin"},
+		{"git push --forc origin", "git push --forc origin"}, // ambiguous
+		{"git push --repo origin main", "git push --repo=origin main"},
+		{"git push -uo ci.skip origin", "git push --set-upstream --push-option=ci.skip origin"},
+		{"git -C dir --no-pager push -f", "git push -Cdir --no-pager --force"},
+		{"git -c user.name=x commit -am msg", "git commit -cuser.name=x --all --message=msg"},
+		{"git status -s", "git status -s"},
+		{"make -j4 -C src all", "make --jobs=4 --directory=src all"},
+		{"make -j all", "make --jobs all"},
+		{"make --just-print", "make --dry-run"},
+		{"/usr/bin/chmod -R 755 .", "/usr/bin/chmod --recursive 755 ."},
+		{"ls -la", "ls -la"},
+	} {
+		if got := strings.Join(Normalize(strings.Fields(tc.in)), " "); got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFlagSpellings(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		flags   []string
+		want    string
+	}{
+		{"git push", []string{"-f", "--force-with-lease"}, "--force --force-with-lease -f"},
+		{"rm", []string{"-r", "-R"}, "--recursive -R -r"},
+		{"make", []string{"-n"}, "--dry-run -n"},
+		{"ls", []string{"-a"}, "-a"},
+	} {
+		if got := strings.Join(FlagSpellings(tc.command, tc.flags), " "); got != tc.want {
+			t.Errorf("FlagSpellings(%q, %q) = %q, want %q", tc.command, tc.flags, got, tc.want)
+		}
+	}
+}
+
+func TestHasFlag(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		args    string
+		flag    string
+		want    bool
+	}{
+		{"git checkout", "-bfix", "-f", false}, // branch "fix"
+		{"git checkout", "-b fix", "-f", false},
+		{"git checkout", "-fb fix", "-f", true},
+		{"git checkout", "--force", "-f", true},
+		{"git push", "-Cfoo --tags", "-f", false}, // git -Cfoo push, normalized
+		{"git push", "origin -uf", "--force", true},
+		{"make", "-j4", "-j", true},
+		{"make", "-Cjobs", "-j", false},
+		{"make", "-kj", "--jobs", true},
+		{"rm", "-- -rf", "-r", false},
+		{"rm", "--recur", "-R", true},
+		{"ls", "-la", "-a", true}, // no spec: HasAnyFlag
+	} {
+		if got := HasFlag(tc.command, strings.Fields(tc.args), tc.flag); got != tc.want {
+			t.Errorf("HasFlag(%q, %q, %q) = %v, want %v", tc.command, tc.args, tc.flag, got, tc.want)
+		}
+	}
+}
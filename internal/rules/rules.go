@@ -26,6 +26,11 @@ func (rs *RuleSet) AddConfig(fn CheckFunc) {
 	rs.config = append(rs.config, fn)
 }
 
+// AddPermanent appends a config-driven rule that a retry cannot skip.
+func (rs *RuleSet) AddPermanent(fn CheckFunc) {
+	rs.hardcoded = append(rs.hardcoded, fn)
+}
+
 // Check runs all rules against the given capability name and args.
 // Hardcoded rules always run first. When retry is true, config rules are
 // skipped (the user has explicitly approved the operation).
@@ -52,6 +57,10 @@ func (rs *RuleSet) Check(capName string, args []string, retry bool) error {
 //   - Combined short flags: "-rf" matches "-r" and "-f"
 //   - Short flag with value: "-j4" matches "-j"
 //   - Long flag with =: "--flag=value" matches "--flag"
+//
+// Not knowing which short flags take values, it errs towards matching:
+// "-xj" matches "-j" even where j is -x's value. HasFlag knows better
+// for commands with a FlagSpec.
 func HasAnyFlag(args []string, flags ...string) bool {
 	for _, arg := range args {
 		if arg == "" || arg[0] != '-' {
@@ -19,8 +19,14 @@ func NewEvaluator(rules []*Rule) *Evaluator {
 // If bypassable rules should be skipped (retry=true), they are skipped.
 // Returns nil if no rule has an opinion.
 func (e *Evaluator) EvaluateCommand(command string, args []string, retry bool) (result *CheckResult, ruleID string, bypassable bool) {
+	return e.EvaluateCommandBypassing(command, args, func(string) bool { return retry })
+}
+
+// EvaluateCommandBypassing is like EvaluateCommand but skips only the
+// bypassable rules for which bypass returns true.
+func (e *Evaluator) EvaluateCommandBypassing(command string, args []string, bypass func(ruleID string) bool) (result *CheckResult, ruleID string, bypassable bool) {
 	for _, rule := range e.rules {
-		if rule.Bypassable && retry {
+		if rule.Bypassable && bypass(rule.ID) {
 			continue
 		}
 		r, err := rule.Evaluate(command, args)
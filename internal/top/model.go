@@ -0,0 +1,249 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package top implements `doit --top`, a terminal dashboard over the
+// event sockets of every running doit: active requests, recent decisions,
+// decision counters, and pending escalations that can be approved or
+// denied inline.
+package top
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+// maxRecent bounds the recent-decisions list.
+const maxRecent = 50
+
+type reqKey struct {
+	pid     int
+	request uint64
+}
+
+// Model is the dashboard state, built from events and pending-escalation
+// replies.
+type Model struct {
+	active   map[reqKey]events.Event // started, not yet exited
+	recent   []events.Event          // decisions, resolutions, and violations, newest last
+	pending  map[reqKey]events.Pending
+	procs    map[int]bool
+	selected int
+
+	Requests, Allowed, Denied, Escalated int
+	Status                               string // last action result
+}
+
+// NewModel returns an empty Model.
+func NewModel() *Model {
+	return &Model{
+		active:  make(map[reqKey]events.Event),
+		pending: make(map[reqKey]events.Pending),
+		procs:   make(map[int]bool),
+	}
+}
+
+// Apply folds one event into the model.
+func (m *Model) Apply(e events.Event) {
+	k := reqKey{e.PID, e.Request}
+	m.procs[e.PID] = true
+	switch e.Type {
+	case events.RequestStart:
+		m.Requests++
+		m.active[k] = e
+	case events.Decision:
+		switch e.Decision {
+		case "allow":
+			m.Allowed++
+		case "deny":
+			m.Denied++
+		case "escalate":
+			m.Escalated++
+		}
+		if a, ok := m.active[k]; ok {
+			a.Decision, a.Level = e.Decision, e.Level
+			m.active[k] = a
+		}
+		m.addRecent(e)
+	case events.Escalation:
+		if _, ok := m.pending[k]; !ok {
+			m.pending[k] = events.Pending{
+				PID: e.PID, Request: e.Request, Command: e.Command, Cwd: e.Cwd,
+				Session: e.Session, Level: e.Level, Reason: e.Reason, IssuedAt: e.Time,
+			}
+		}
+	case events.Exit:
+		delete(m.active, k)
+	case events.Resolution:
+		delete(m.pending, k)
+		m.clampSelection()
+		m.addRecent(e)
+	case events.Violation, events.Tripwire:
+		m.addRecent(e)
+	}
+}
+
+func (m *Model) addRecent(e events.Event) {
+	m.recent = append(m.recent, e)
+	if len(m.recent) > maxRecent {
+		m.recent = m.recent[len(m.recent)-maxRecent:]
+	}
+}
+
+// SetPending replaces the pending escalations of process pid with the
+// server's authoritative list.
+func (m *Model) SetPending(pid int, pending []events.Pending) {
+	m.procs[pid] = true
+	for k := range m.pending {
+		if k.pid == pid {
+			delete(m.pending, k)
+		}
+	}
+	for _, p := range pending {
+		m.pending[reqKey{p.PID, p.Request}] = p
+	}
+	m.clampSelection()
+}
+
+// Disconnect forgets the live state of a process that went away.
+func (m *Model) Disconnect(pid int) {
+	delete(m.procs, pid)
+	for k := range m.active {
+		if k.pid == pid {
+			delete(m.active, k)
+		}
+	}
+	for k := range m.pending {
+		if k.pid == pid {
+			delete(m.pending, k)
+		}
+	}
+	m.clampSelection()
+}
+
+// Pending returns the pending escalations, oldest first.
+func (m *Model) Pending() []events.Pending {
+	out := make([]events.Pending, 0, len(m.pending))
+	for _, p := range m.pending {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].IssuedAt.Equal(out[j].IssuedAt) {
+			return out[i].IssuedAt.Before(out[j].IssuedAt)
+		}
+		if out[i].PID != out[j].PID {
+			return out[i].PID < out[j].PID
+		}
+		return out[i].Request < out[j].Request
+	})
+	return out
+}
+
+// Move moves the pending-escalation selection by delta.
+func (m *Model) Move(delta int) {
+	m.selected += delta
+	m.clampSelection()
+}
+
+// Selected returns the selected pending escalation, if any.
+func (m *Model) Selected() (events.Pending, bool) {
+	p := m.Pending()
+	if len(p) == 0 {
+		return events.Pending{}, false
+	}
+	return p[m.selected], true
+}
+
+func (m *Model) clampSelection() {
+	n := len(m.pending)
+	if m.selected >= n {
+		m.selected = n - 1
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+}
+
+// Render draws the dashboard, clipped to width columns and height rows.
+func (m *Model) Render(w io.Writer, width, height int, now time.Time) {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	add("doit top — %d process(es)   requests %d   allow %d   deny %d   escalate %d",
+		len(m.procs), m.Requests, m.Allowed, m.Denied, m.Escalated)
+	add("keys: j/k select  a approve  d deny  q quit")
+	if m.Status != "" {
+		add("%s", m.Status)
+	}
+
+	pending := m.Pending()
+	add("")
+	add("PENDING APPROVALS (%d)", len(pending))
+	for i, p := range pending {
+		cursor := " "
+		if i == m.selected {
+			cursor = ">"
+		}
+		expires := "-"
+		if !p.ExpiresAt.IsZero() {
+			expires = p.ExpiresAt.Sub(now).Round(time.Second).String()
+		}
+		add("%s %6d/%-4d  expires %-6s  %s — %s", cursor, p.PID, p.Request, expires, oneLine(p.Command), oneLine(p.Reason))
+	}
+
+	active := make([]events.Event, 0, len(m.active))
+	for _, e := range m.active {
+		active = append(active, e)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Time.Before(active[j].Time) })
+	add("")
+	add("ACTIVE (%d)", len(active))
+	for _, e := range active {
+		state := "evaluating"
+		if e.Decision != "" {
+			state = fmt.Sprintf("L%d/%s", e.Level, e.Decision)
+		}
+		add("  %6d/%-4d  %7s  %-12s  %s", e.PID, e.Request, now.Sub(e.Time).Round(time.Second), state, oneLine(e.Command))
+	}
+
+	add("")
+	add("RECENT DECISIONS")
+	for i := len(m.recent) - 1; i >= 0; i-- {
+		e := m.recent[i]
+		what, command := fmt.Sprintf("L%d/%s", e.Level, e.Decision), e.Command
+		switch e.Type {
+		case events.Resolution:
+			what = "human/" + e.Decision
+		case events.Violation:
+			what, command = "violation", e.Command+": "+e.Reason
+		case events.Tripwire:
+			what = "TRIPWIRE"
+		}
+		add("  %s  %6d/%-4d  %-14s  %s", e.Time.Local().Format("15:04:05"), e.PID, e.Request, what, oneLine(command))
+	}
+
+	if height > 0 && len(lines) > height {
+		lines = lines[:height]
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for _, l := range lines {
+		if width > 0 && len([]rune(l)) > width {
+			l = string([]rune(l)[:width])
+		}
+		b.WriteString(l)
+		b.WriteString("\r\n")
+	}
+	io.WriteString(w, b.String())
+}
+
+// oneLine collapses newlines so commands fit a row.
+func oneLine(s string) string {
+	return strings.ReplaceAll(s, "\n", " ⏎ ")
+}
@@ -0,0 +1,211 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package top
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+// defaultRefresh is how often the dashboard rescans for doit processes,
+// refreshes pending escalations, and redraws.
+const defaultRefresh = time.Second
+
+// Options configures Run.
+type Options struct {
+	Dir     string            // event socket directory
+	In      io.Reader         // keystrokes (terminal in cbreak mode)
+	Out     io.Writer         // terminal output
+	Size    func() (w, h int) // terminal size; nil means unbounded
+	Refresh time.Duration     // rescan/redraw period; zero means 1s
+	Now     func() time.Time  // clock; nil means time.Now
+}
+
+type frameMsg struct {
+	pid   int
+	frame events.Frame
+	err   error // non-nil when the connection ended
+}
+
+// Run shows the dashboard until ctx is done or the user presses q.
+func Run(ctx context.Context, opts Options) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	refresh := opts.Refresh
+	if refresh <= 0 {
+		refresh = defaultRefresh
+	}
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	frames := make(chan frameMsg, 64)
+	clients := map[int]*events.Client{}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	connect := func() {
+		socks, _ := events.Sockets(opts.Dir)
+		for _, path := range socks {
+			pid, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(path), ".sock"))
+			if err != nil || clients[pid] != nil {
+				continue
+			}
+			c, err := events.Dial(ctx, path, nil)
+			if err != nil {
+				continue // stale socket from a process that has exited
+			}
+			clients[pid] = c
+			go func() {
+				for {
+					f, err := c.Recv()
+					select {
+					case frames <- frameMsg{pid: pid, frame: f, err: err}:
+					case <-ctx.Done():
+						return
+					}
+					if err != nil {
+						return
+					}
+				}
+			}()
+		}
+		for _, c := range clients {
+			c.RequestPending()
+		}
+	}
+
+	keys := make(chan byte, 16)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := opts.In.Read(buf)
+			for _, b := range buf[:n] {
+				select {
+				case keys <- b:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				close(keys)
+				return
+			}
+		}
+	}()
+
+	m := NewModel()
+	draw := func() {
+		w, h := 0, 0
+		if opts.Size != nil {
+			w, h = opts.Size()
+		}
+		m.Render(opts.Out, w, h, now())
+	}
+	resolve := func(approve bool) {
+		p, ok := m.Selected()
+		if !ok {
+			return
+		}
+		c := clients[p.PID]
+		if c == nil {
+			m.Status = fmt.Sprintf("doit %d is gone", p.PID)
+			return
+		}
+		verb := "deny"
+		if approve {
+			verb = "approve"
+		}
+		if err := c.Resolve(p.Request, approve, false); err != nil {
+			m.Status = fmt.Sprintf("%s %d/%d: %v", verb, p.PID, p.Request, err)
+			return
+		}
+		m.Status = fmt.Sprintf("%s %d/%d: sent", verb, p.PID, p.Request)
+	}
+
+	connect()
+	draw()
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	var esc []byte // pending escape sequence
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			connect()
+			draw()
+
+		case msg := <-frames:
+			switch f := msg.frame; {
+			case msg.err != nil:
+				if c := clients[msg.pid]; c != nil {
+					c.Close()
+					delete(clients, msg.pid)
+				}
+				m.Disconnect(msg.pid)
+			case f.Event != nil:
+				m.Apply(*f.Event)
+			case f.Reply == "pending":
+				m.SetPending(msg.pid, f.Pending)
+			case f.Reply == "resolve":
+				m.Status = fmt.Sprintf("resolved %d/%d", msg.pid, f.Resolved)
+				if c := clients[msg.pid]; c != nil {
+					c.RequestPending()
+				}
+			case f.Error != "":
+				m.Status = fmt.Sprintf("doit %d: %s", msg.pid, f.Error)
+			}
+			draw()
+
+		case b, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			// Arrow keys arrive as ESC [ A / ESC [ B.
+			if len(esc) > 0 || b == 0x1b {
+				esc = append(esc, b)
+				if len(esc) < 3 {
+					continue
+				}
+				switch string(esc) {
+				case "\x1b[A":
+					m.Move(-1)
+				case "\x1b[B":
+					m.Move(1)
+				}
+				esc = nil
+				draw()
+				continue
+			}
+			switch b {
+			case 'q', 3: // q or ctrl-C
+				return nil
+			case 'k':
+				m.Move(-1)
+			case 'j':
+				m.Move(1)
+			case 'a':
+				resolve(true)
+			case 'd':
+				resolve(false)
+			}
+			draw()
+		}
+	}
+}
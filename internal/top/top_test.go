@@ -0,0 +1,175 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package top
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcelocantos/doit/internal/events"
+)
+
+func TestModel(t *testing.T) {
+	m := NewModel()
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ev := func(typ events.Type, req uint64, decision string) events.Event {
+		return events.Event{Type: typ, PID: 10, Request: req, Command: fmt.Sprintf("cmd%d", req), Decision: decision, Level: 3, Time: t0}
+	}
+
+	m.Apply(ev(events.RequestStart, 1, ""))
+	m.Apply(ev(events.Decision, 1, "allow"))
+	m.Apply(ev(events.RequestStart, 2, ""))
+	m.Apply(ev(events.Decision, 2, "escalate"))
+	m.Apply(ev(events.Escalation, 2, ""))
+	m.Apply(ev(events.Exit, 2, "escalate"))
+	m.Apply(ev(events.RequestStart, 3, ""))
+	m.Apply(ev(events.Decision, 3, "deny"))
+	m.Apply(ev(events.Exit, 3, "deny"))
+	violation := ev(events.Violation, 1, "")
+	violation.Reason = "keep-go-mod: go.mod no longer exists"
+	m.Apply(violation)
+	m.Apply(ev(events.Tripwire, 3, "deny"))
+
+	if m.Requests != 3 || m.Allowed != 1 || m.Escalated != 1 || m.Denied != 1 {
+		t.Errorf("counters = %d/%d/%d/%d", m.Requests, m.Allowed, m.Escalated, m.Denied)
+	}
+	if p, ok := m.Selected(); !ok || p.Request != 2 {
+		t.Fatalf("selected = %+v, %v; want request 2", p, ok)
+	}
+
+	var out bytes.Buffer
+	m.Render(&out, 0, 0, t0.Add(5*time.Second))
+	screen := out.String()
+	for _, want := range []string{
+		"requests 3   allow 1   deny 1   escalate 1",
+		"PENDING APPROVALS (1)",
+		">     10/2     expires -       cmd2",
+		"ACTIVE (1)",
+		"5s  L3/allow      cmd1",
+		"violation       cmd1: keep-go-mod: go.mod no longer exists",
+		"TRIPWIRE        cmd3",
+	} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+
+	// The server's list is authoritative; a resolution clears the entry.
+	m.SetPending(10, []events.Pending{{PID: 10, Request: 2, Command: "cmd2"}, {PID: 10, Request: 4, Command: "cmd4", IssuedAt: t0.Add(time.Second)}})
+	m.Move(5)
+	if p, _ := m.Selected(); p.Request != 4 {
+		t.Errorf("selection after Move(5) = %d, want clamped to 4", p.Request)
+	}
+	m.Apply(ev(events.Resolution, 4, "deny"))
+	if p, _ := m.Selected(); p.Request != 2 {
+		t.Errorf("selection after resolution = %d, want 2", p.Request)
+	}
+
+	m.Disconnect(10)
+	if _, ok := m.Selected(); ok {
+		t.Error("pending survived disconnect")
+	}
+
+	// Rendering clips to the terminal.
+	out.Reset()
+	m.Render(&out, 10, 2, t0)
+	if lines := strings.Split(strings.TrimPrefix(out.String(), "\x1b[H\x1b[2J"), "\r\n"); len(lines) != 3 || len([]rune(lines[0])) != 10 {
+		t.Errorf("clipped render = %q", out.String())
+	}
+}
+
+type fakeController struct {
+	mu       sync.Mutex
+	pending  []events.Pending
+	resolved []string
+}
+
+func (f *fakeController) Pending() []events.Pending {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]events.Pending(nil), f.pending...)
+}
+
+func (f *fakeController) Resolve(request uint64, approve, always bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resolved = append(f.resolved, fmt.Sprintf("%d:%v", request, approve))
+	f.pending = nil
+	return nil
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestRun_ApproveInline(t *testing.T) {
+	dir, err := os.MkdirTemp("", "top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := events.Listen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctrl := &fakeController{pending: []events.Pending{{PID: os.Getpid(), Request: 9, Command: "git push --force"}}}
+	go events.Serve(ctx, l, events.NewBus(), ctrl)
+
+	keysR, keysW := io.Pipe()
+	out := &syncBuffer{}
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, Options{Dir: dir, In: keysR, Out: out, Refresh: 10 * time.Millisecond})
+	}()
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		for !cond() {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("timed out waiting for %s; screen:\n%s", what, out.String())
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}
+	waitFor("pending escalation", func() bool { return strings.Contains(out.String(), "git push --force") })
+
+	keysW.Write([]byte("a"))
+	waitFor("resolution", func() bool {
+		ctrl.mu.Lock()
+		defer ctrl.mu.Unlock()
+		return len(ctrl.resolved) == 1
+	})
+	if ctrl.resolved[0] != "9:true" {
+		t.Errorf("resolved = %v, want 9:true", ctrl.resolved)
+	}
+
+	keysW.Write([]byte("q"))
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+}
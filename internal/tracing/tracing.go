@@ -0,0 +1,410 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing records OpenTelemetry spans of doit's request lifecycle
+// and exports them to an OTLP/HTTP collector in its JSON encoding. It
+// implements just what doit needs — spans with attributes and a status,
+// parented through a context — rather than pulling in the OpenTelemetry
+// SDK.
+//
+// A nil *Tracer, and the nil *Span it starts, do nothing, so code can
+// trace unconditionally.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Standard OpenTelemetry environment variables, used when the config
+// names no endpoint or service.
+const (
+	envTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	envEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envServiceName    = "OTEL_SERVICE_NAME"
+)
+
+// DefaultService is the service.name of doit's spans.
+const DefaultService = "doit"
+
+// Export tuning: spans are sent in batches of up to batchSize, at least
+// every flushInterval, and a collector gets exportTimeout to accept one.
+const (
+	batchSize     = 256
+	maxQueued     = 4096
+	flushInterval = 5 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// Options configures a Tracer.
+type Options struct {
+	Endpoint string            // OTLP/HTTP traces URL; "" to use $OTEL_EXPORTER_OTLP_*
+	Service  string            // service.name; "" for $OTEL_SERVICE_NAME, else "doit"
+	Headers  map[string]string // sent with every export, e.g. a collector's API key
+}
+
+// endpoint returns the traces URL opts configures: opts.Endpoint, else
+// $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, else $OTEL_EXPORTER_OTLP_ENDPOINT
+// with /v1/traces appended. It is "" if tracing is not configured.
+func (opts Options) endpoint() string {
+	if opts.Endpoint != "" {
+		return opts.Endpoint
+	}
+	if ep := os.Getenv(envTracesEndpoint); ep != "" {
+		return ep
+	}
+	if ep := os.Getenv(envEndpoint); ep != "" {
+		return strings.TrimSuffix(ep, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// Tracer records spans and exports them in the background.
+type Tracer struct {
+	endpoint string
+	headers  map[string]string
+	resource resource
+	scope    scope
+	client   *http.Client
+
+	mu     sync.Mutex
+	queue  []*Span
+	kick   chan struct{} // a full batch is waiting
+	quit   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// New returns a Tracer exporting to the endpoint opts configures, or nil
+// if tracing is not configured.
+func New(opts Options) *Tracer {
+	endpoint := opts.endpoint()
+	if endpoint == "" {
+		return nil
+	}
+	service := opts.Service
+	if service == "" {
+		service = os.Getenv(envServiceName)
+	}
+	if service == "" {
+		service = DefaultService
+	}
+	t := &Tracer{
+		endpoint: endpoint,
+		headers:  opts.Headers,
+		resource: resource{Attributes: []keyValue{String("service.name", service).kv()}},
+		scope:    scope{Name: "github.com/marcelocantos/doit"},
+		client:   &http.Client{Timeout: exportTimeout},
+		kick:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start begins a span named name, a child of the span in ctx if there is
+// one, and returns a context carrying it.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		tracer: t,
+		name:   name,
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	s.spanID = randomHex(8)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+type spanKey struct{}
+
+// Close exports the spans that have ended and stops the exporter. Spans
+// ending afterwards are dropped.
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	t.mu.Unlock()
+	close(t.quit)
+	<-t.done
+}
+
+// enqueue queues an ended span for export, dropping it if the collector
+// has fallen too far behind.
+func (t *Tracer) enqueue(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed || len(t.queue) >= maxQueued {
+		return
+	}
+	t.queue = append(t.queue, s)
+	if len(t.queue) >= batchSize {
+		select {
+		case t.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports queued spans until Close, then exports what is left.
+func (t *Tracer) run() {
+	defer close(t.done)
+	tick := time.NewTicker(flushInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-t.quit:
+			t.flush()
+			return
+		case <-tick.C:
+		case <-t.kick:
+		}
+		t.flush()
+	}
+}
+
+// flush exports every queued span, a batch at a time.
+func (t *Tracer) flush() {
+	for {
+		t.mu.Lock()
+		n := min(len(t.queue), batchSize)
+		batch := t.queue[:n:n]
+		t.queue = t.queue[n:]
+		t.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil {
+			log.Printf("doit: tracing: dropped %d spans: %v", n, err)
+		}
+	}
+}
+
+// export sends spans to the collector as one OTLP/HTTP JSON request.
+func (t *Tracer) export(spans []*Span) error {
+	payload := exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   t.resource,
+		ScopeSpans: []scopeSpans{{Scope: t.scope}},
+	}}}
+	ss := &payload.ResourceSpans[0].ScopeSpans[0]
+	for _, s := range spans {
+		ss.Spans = append(ss.Spans, s.otlp())
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Span is one timed operation. Its methods may be called on nil.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []Attr
+	errMsg string
+	failed bool
+	ended  bool
+}
+
+// SetAttrs adds attributes to s.
+func (s *Span) SetAttrs(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// Fail marks s as failed, for the reason msg.
+func (s *Span) Fail(msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed, s.errMsg = true, msg
+	s.mu.Unlock()
+}
+
+// End ends s and queues it for export. Only the first End counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+func (s *Span) otlp() span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := span{
+		TraceID:      s.traceID,
+		SpanID:       s.spanID,
+		ParentSpanID: s.parentID,
+		Name:         s.name,
+		Kind:         spanKindInternal,
+		Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+		End:          strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	for _, a := range s.attrs {
+		out.Attributes = append(out.Attributes, a.kv())
+	}
+	if s.failed {
+		out.Status = &status{Code: statusError, Message: s.errMsg}
+	}
+	return out
+}
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string
+	value anyValue
+}
+
+// String returns a string attribute.
+func String(key, v string) Attr { return Attr{key, anyValue{String: &v}} }
+
+// Int returns an integer attribute.
+func Int(key string, v int) Attr {
+	s := strconv.Itoa(v)
+	return Attr{key, anyValue{Int: &s}}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, v bool) Attr { return Attr{key, anyValue{Bool: &v}} }
+
+// Strings returns a string array attribute.
+func Strings(key string, v []string) Attr {
+	arr := &arrayValue{Values: []anyValue{}}
+	for _, s := range v {
+		arr.Values = append(arr.Values, anyValue{String: &s})
+	}
+	return Attr{key, anyValue{Array: arr}}
+}
+
+func (a Attr) kv() keyValue { return keyValue{Key: a.Key, Value: a.value} }
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// The OTLP/HTTP JSON encoding of an export request. IDs are hex, and
+// 64-bit integers are decimal strings.
+
+const (
+	spanKindInternal = 1
+	statusError      = 2
+)
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type span struct {
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	Start        string     `json:"startTimeUnixNano"`
+	End          string     `json:"endTimeUnixNano"`
+	Attributes   []keyValue `json:"attributes,omitempty"`
+	Status       *status    `json:"status,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	String *string     `json:"stringValue,omitempty"`
+	Int    *string     `json:"intValue,omitempty"`
+	Bool   *bool       `json:"boolValue,omitempty"`
+	Array  *arrayValue `json:"arrayValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// collector is a fake OTLP/HTTP collector.
+type collector struct {
+	mu       sync.Mutex
+	requests []exportRequest
+	headers  []http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad export", http.StatusBadRequest)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header)
+	c.mu.Unlock()
+}
+
+func (c *collector) spans() []span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []span
+	for _, r := range c.requests {
+		for _, rs := range r.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				out = append(out, ss.Spans...)
+			}
+		}
+	}
+	return out
+}
+
+func TestTracer(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	tr := New(Options{Endpoint: srv.URL + "/v1/traces", Headers: map[string]string{"X-Key": "k"}})
+	ctx, parent := tr.Start(context.Background(), "parent", String("cmd", "ls"))
+	_, child := tr.Start(ctx, "child")
+	child.SetAttrs(Int("n", 3), Strings("segs", []string{"a", "b"}), Bool("ok", false))
+	child.Fail("boom")
+	child.End()
+	child.End()
+	parent.End()
+	tr.Close()
+	parent.End() // after Close: dropped
+
+	spans := c.spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	cs, ps := spans[0], spans[1]
+	if cs.Name != "child" || ps.Name != "parent" {
+		t.Fatalf("span names %q, %q", cs.Name, ps.Name)
+	}
+	if len(ps.TraceID) != 32 || len(ps.SpanID) != 16 || ps.ParentSpanID != "" {
+		t.Errorf("parent IDs: %+v", ps)
+	}
+	if cs.TraceID != ps.TraceID || cs.ParentSpanID != ps.SpanID || cs.SpanID == ps.SpanID {
+		t.Errorf("child not parented: %+v", cs)
+	}
+	if cs.Status == nil || cs.Status.Code != statusError || cs.Status.Message != "boom" || ps.Status != nil {
+		t.Errorf("status: child %+v, parent %+v", cs.Status, ps.Status)
+	}
+	if len(cs.Attributes) != 3 || *cs.Attributes[0].Value.Int != "3" || len(cs.Attributes[1].Value.Array.Values) != 2 || *cs.Attributes[2].Value.Bool {
+		t.Errorf("child attributes: %+v", cs.Attributes)
+	}
+	if cs.Start == "" || cs.End < cs.Start {
+		t.Errorf("child times %s..%s", cs.Start, cs.End)
+	}
+	res := c.requests[0].ResourceSpans[0].Resource.Attributes
+	if len(res) != 1 || res[0].Key != "service.name" || *res[0].Value.String != DefaultService {
+		t.Errorf("resource: %+v", res)
+	}
+	if c.headers[0].Get("X-Key") != "k" {
+		t.Errorf("headers: %v", c.headers[0])
+	}
+}
+
+func TestNew_Unconfigured(t *testing.T) {
+	t.Setenv(envTracesEndpoint, "")
+	t.Setenv(envEndpoint, "")
+	tr := New(Options{})
+	if tr != nil {
+		t.Fatalf("New without an endpoint = %+v, want nil", tr)
+	}
+	ctx, span := tr.Start(context.Background(), "x")
+	span.SetAttrs(String("k", "v"))
+	span.Fail("no")
+	span.End()
+	tr.Close()
+	if ctx.Value(spanKey{}) != nil {
+		t.Error("nil tracer put a span in the context")
+	}
+}
+
+func TestEndpointFromEnv(t *testing.T) {
+	t.Setenv(envTracesEndpoint, "")
+	t.Setenv(envEndpoint, "http://collector:4318/")
+	if got := (Options{}).endpoint(); got != "http://collector:4318/v1/traces" {
+		t.Errorf("endpoint = %q", got)
+	}
+	t.Setenv(envTracesEndpoint, "http://traces:4318/x")
+	if got := (Options{}).endpoint(); got != "http://traces:4318/x" {
+		t.Errorf("endpoint = %q", got)
+	}
+	if got := (Options{Endpoint: "http://cfg/v1/traces"}).endpoint(); got != "http://cfg/v1/traces" {
+		t.Errorf("endpoint = %q", got)
+	}
+}
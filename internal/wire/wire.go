@@ -0,0 +1,60 @@
+// Copyright 2026 Marcelo Cantos
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wire converts between the engine's requests and results and
+// their JSON form in the doitclient package, which doit --repl and doit
+// --serve-http speak.
+package wire
+
+import (
+	"github.com/marcelocantos/doit/doitclient"
+	"github.com/marcelocantos/doit/engine"
+)
+
+// Request returns the engine request r describes.
+func Request(r doitclient.Request) engine.Request {
+	return engine.Request{
+		Command:       r.Command,
+		Cwd:           r.Cwd,
+		Env:           r.Env,
+		Stdin:         r.Stdin,
+		Justification: r.Justification,
+		SafetyArg:     r.SafetyArg,
+		Approved:      r.Approved,
+		Retry:         r.Retry,
+		RetryRef:      r.RetryRef,
+		Agent:         r.Agent,
+		AgentSecret:   r.AgentSecret,
+	}
+}
+
+// Result returns res in its JSON form.
+func Result(res *engine.Result) doitclient.Result {
+	out := doitclient.Result{
+		ExitCode:      res.ExitCode,
+		Signal:        res.Signal,
+		Stdout:        res.Stdout,
+		Stderr:        res.Stderr,
+		EscalateToken: res.EscalateToken,
+	}
+	if res.PolicyDecision != "" {
+		out.Policy = &doitclient.Policy{
+			Level:    res.PolicyLevel,
+			Decision: res.PolicyDecision,
+			Reason:   res.PolicyReason,
+			RuleID:   res.PolicyRuleID,
+		}
+	}
+	if e := res.Error; e != nil {
+		out.Error = &doitclient.Error{
+			Kind:       e.Kind,
+			Message:    e.Message,
+			Segment:    e.Segment,
+			RuleID:     e.RuleID,
+			Suggestion: e.Suggestion,
+			Retryable:  e.Retryable,
+			Approval:   e.Approval,
+		}
+	}
+	return out
+}
@@ -14,6 +14,8 @@ import (
 	mcpclient "github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/marcelocantos/doit/engine"
 )
 
 // newMCPClient creates an in-process MCP client connected to a doit MCP server.
@@ -172,6 +174,34 @@ func TestIntegration_Execute_PolicyDeny(t *testing.T) {
 	}
 }
 
+func TestIntegration_Execute_ErrorPayload(t *testing.T) {
+	c := newMCPClient(t)
+	ctx := context.Background()
+
+	result, err := c.CallTool(ctx, mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      "doit_execute",
+			Arguments: map[string]any{"command": "echo hi | doit-no-such-program"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected error for a missing program")
+	}
+	var resp struct {
+		ExitCode int               `json:"exit_code"`
+		Error    *engine.ErrorInfo `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(extractText(t, result)), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.ExitCode != engine.ExitUnavailable || resp.Error == nil || resp.Error.Kind != engine.ErrorExec || resp.Error.Segment != 2 {
+		t.Errorf("response = %+v, error %+v; want an exec error in segment 2", resp, resp.Error)
+	}
+}
+
 func TestIntegration_Execute_ConfigRuleDeny(t *testing.T) {
 	c := newMCPClient(t)
 	ctx := context.Background()
@@ -14,6 +14,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"time"
 
@@ -23,6 +24,7 @@ import (
 	"github.com/marcelocantos/doit/engine"
 	"github.com/marcelocantos/doit/internal/audit"
 	doitctx "github.com/marcelocantos/doit/internal/context"
+	"github.com/marcelocantos/doit/internal/messages"
 	"github.com/marcelocantos/doit/internal/policy"
 )
 
@@ -38,8 +40,9 @@ func Register(srv *server.MCPServer, eng *engine.Engine) {
 			mcp.WithString("command", mcp.Required(), mcp.Description("The command to execute (e.g. 'git status', 'make test')")),
 			mcp.WithString("justification", mcp.Description("Why the agent needs this command")),
 			mcp.WithString("safety_arg", mcp.Description("Why the agent believes the command is safe")),
-			mcp.WithString("cwd", mcp.Description("Working directory for the command")),
+			mcp.WithString("cwd", mcp.Description("Working directory for the command (absolute path)")),
 			mcp.WithString("approved", mcp.Description("Approval token for previously escalated commands")),
+			mcp.WithString("stdin", mcp.Description("Standard input for the command (for write: the new file content, or a unified diff with --patch)")),
 		),
 		handleExecute(srv, eng),
 	)
@@ -70,6 +73,7 @@ func Register(srv *server.MCPServer, eng *engine.Engine) {
 				"Tokens are single-use and time-limited."),
 			mcp.WithString("token", mcp.Required(), mcp.Description("The approval token")),
 			mcp.WithString("command", mcp.Required(), mcp.Description("The original command (must match exactly)")),
+			mcp.WithString("cwd", mcp.Description("Working directory of the original command (must match where it escalated)")),
 		),
 		handleApprove(eng),
 	)
@@ -78,8 +82,10 @@ func Register(srv *server.MCPServer, eng *engine.Engine) {
 	srv.AddTool(
 		mcp.NewTool("doit_list_capabilities",
 			mcp.WithDescription("List all registered capabilities with their safety tiers. "+
-				"Optionally filter by tier (read, build, write, dangerous)."),
+				"Optionally filter by tier (read, build, write, dangerous), or pass capability "+
+				"for that capability's examples, flags, and tier rationale."),
 			mcp.WithString("tier", mcp.Description("Filter by tier: read, build, write, or dangerous")),
+			mcp.WithString("capability", mcp.Description("Show detailed help for one capability (e.g. git)")),
 		),
 		handleListCapabilities(eng),
 	)
@@ -87,7 +93,9 @@ func Register(srv *server.MCPServer, eng *engine.Engine) {
 	srv.AddTool(
 		mcp.NewTool("doit_audit_verify",
 			mcp.WithDescription("Verify the audit log hash chain integrity. "+
-				"Returns OK if the chain is valid, or describes the first violation found."),
+				"Returns OK if the chain is valid, or describes the first violation found. "+
+				"By default only entries appended since the last successful verification are checked."),
+			mcp.WithBoolean("full", mcp.Description("Re-verify the whole chain from genesis, ignoring the last verified watermark")),
 		),
 		handleAuditVerify(eng),
 	)
@@ -204,6 +212,7 @@ func handleExecute(srv *server.MCPServer, eng *engine.Engine) server.ToolHandler
 			SafetyArg:     argString(args, "safety_arg"),
 			Cwd:           argString(args, "cwd"),
 			Approved:      argString(args, "approved"),
+			Stdin:         argString(args, "stdin"),
 		}
 
 		// Phase 1: Evaluate policy before executing.
@@ -211,7 +220,7 @@ func handleExecute(srv *server.MCPServer, eng *engine.Engine) server.ToolHandler
 
 		if evalResult.Decision == "escalate" || evalResult.Decision == "deny" {
 			if evalResult.Bypassable || evalResult.Decision == "escalate" {
-				decision, err := elicitPolicyDecision(ctx, srv, command, evalResult)
+				decision, err := elicitPolicyDecision(ctx, srv, eng, command, evalResult)
 				if err != nil {
 					// Elicitation not supported or failed — fall through to
 					// normal execution which will return the denial.
@@ -220,27 +229,26 @@ func handleExecute(srv *server.MCPServer, eng *engine.Engine) server.ToolHandler
 
 				switch decision {
 				case "allow_once":
-					r.Retry = true
+					r.ApproveByUser()
 					return executeAndRespond(ctx, eng, r)
 				case "allow_always":
-					r.Retry = true
+					r.ApproveByUser()
 					result := eng.Execute(ctx, r)
 					_ = eng.RecordDecision(command, "allow")
 					elicitRulePromotion(ctx, srv, eng, command, "allow")
 					return buildResult(result), nil
 				case "deny":
-					return mcp.NewToolResultError(fmt.Sprintf("Denied by user: %s", command)), nil
+					return mcp.NewToolResultError(eng.Messages().Render(messages.UserDeny, messages.Data{Command: command})), nil
 				case "deny_always":
 					_ = eng.RecordDecision(command, "deny")
 					elicitRulePromotion(ctx, srv, eng, command, "deny")
-					return mcp.NewToolResultError(fmt.Sprintf("Denied by user (permanent): %s", command)), nil
+					return mcp.NewToolResultError(eng.Messages().Render(messages.UserDenyAlways, messages.Data{Command: command})), nil
 				}
 			}
 
 			// Non-bypassable denial (hardcoded rule) — no elicitation.
 			if evalResult.Decision == "deny" {
-				return mcp.NewToolResultError(fmt.Sprintf("Denied by policy (L%d): %s — %s",
-					evalResult.Level, evalResult.RuleID, evalResult.Reason)), nil
+				return mcp.NewToolResultError(eng.Messages().Render(messages.HardDeny, evalMessageData(command, evalResult))), nil
 			}
 		}
 
@@ -250,12 +258,8 @@ func handleExecute(srv *server.MCPServer, eng *engine.Engine) server.ToolHandler
 
 // elicitPolicyDecision presents a policy escalation to the user via MCP
 // elicitation and returns their choice.
-func elicitPolicyDecision(ctx context.Context, srv *server.MCPServer, command string, eval *engine.EvalResult) (string, error) {
-	message := fmt.Sprintf("Policy %s for command: %s\n\nLevel: L%d\nReason: %s",
-		eval.Decision, command, eval.Level, eval.Reason)
-	if eval.RuleID != "" {
-		message += fmt.Sprintf("\nRule: %s", eval.RuleID)
-	}
+func elicitPolicyDecision(ctx context.Context, srv *server.MCPServer, eng *engine.Engine, command string, eval *engine.EvalResult) (string, error) {
+	message := eng.Messages().Render(messages.ElicitDecision, evalMessageData(command, eval))
 
 	result, err := srv.RequestElicitation(ctx, mcp.ElicitationRequest{
 		Params: mcp.ElicitationParams{
@@ -293,6 +297,17 @@ func elicitPolicyDecision(ctx context.Context, srv *server.MCPServer, command st
 	return decision, nil
 }
 
+// evalMessageData builds template data for messages about an evaluation.
+func evalMessageData(command string, eval *engine.EvalResult) messages.Data {
+	return messages.Data{
+		Command:  command,
+		Decision: eval.Decision,
+		Level:    eval.Level,
+		Reason:   eval.Reason,
+		RuleID:   eval.RuleID,
+	}
+}
+
 // elicitRulePromotion fires phase 2 elicitation: propose Starlark rules at
 // varying generality levels for the user to choose from. Non-blocking — errors
 // are silently ignored (the command decision has already been recorded in L2).
@@ -311,7 +326,7 @@ func elicitRulePromotion(ctx context.Context, srv *server.MCPServer, eng *engine
 
 	result, err := srv.RequestElicitation(ctx, mcp.ElicitationRequest{
 		Params: mcp.ElicitationParams{
-			Message: fmt.Sprintf("Would you like to create a permanent rule for `%s`?", command),
+			Message: eng.Messages().Render(messages.ElicitPromotion, messages.Data{Command: command, Decision: decision}),
 			RequestedSchema: map[string]any{
 				"type": "object",
 				"properties": map[string]any{
@@ -338,7 +353,7 @@ func elicitRulePromotion(ctx context.Context, srv *server.MCPServer, eng *engine
 	// Find the matching proposal and write the rule.
 	for _, p := range proposals {
 		if p.Description == chosen {
-			ruleID := fmt.Sprintf("user-%s-%d", decision, time.Now().UnixMilli())
+			ruleID := eng.NewID("user-" + decision)
 			if err := eng.WriteStarlarkRule(ruleID, p.Source); err != nil {
 				log.Printf("doit: write starlark rule: %v", err)
 			}
@@ -347,6 +362,22 @@ func elicitRulePromotion(ctx context.Context, srv *server.MCPServer, eng *engine
 	}
 }
 
+// Recover is tool handler middleware that turns a panicking handler into
+// an error result, so one failed request does not take down the server and
+// the other agents it serves. Install it with
+// server.WithToolHandlerMiddleware(mcptools.Recover).
+func Recover(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (res *mcp.CallToolResult, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("doit: panic in %s: %v\n%s", req.Params.Name, p, debug.Stack())
+				res, err = mcp.NewToolResultError(fmt.Sprintf("doit: internal error: panic: %v", p)), nil
+			}
+		}()
+		return next(ctx, req)
+	}
+}
+
 func executeAndRespond(ctx context.Context, eng *engine.Engine, r engine.Request) (*mcp.CallToolResult, error) {
 	result := eng.Execute(ctx, r)
 	return buildResult(result), nil
@@ -356,6 +387,9 @@ func buildResult(result *engine.Result) *mcp.CallToolResult {
 	resp := map[string]any{
 		"exit_code": result.ExitCode,
 	}
+	if result.Signal != "" {
+		resp["signal"] = result.Signal
+	}
 	if result.Stdout != "" {
 		resp["stdout"] = result.Stdout
 	}
@@ -373,6 +407,9 @@ func buildResult(result *engine.Result) *mcp.CallToolResult {
 	if result.EscalateToken != "" {
 		resp["escalate_token"] = result.EscalateToken
 	}
+	if result.Error != nil {
+		resp["error"] = result.Error
+	}
 
 	data, _ := json.MarshalIndent(resp, "", "  ")
 	isError := result.ExitCode != 0
@@ -402,6 +439,7 @@ func handleDryRun(eng *engine.Engine) server.ToolHandlerFunc {
 		var b strings.Builder
 		fmt.Fprintf(&b, "Command: %s\n", command)
 		fmt.Fprintf(&b, "Decision: %s (Level %d)\n", result.Decision, result.Level)
+		fmt.Fprintf(&b, "Tier: %s\n", result.Tier)
 		fmt.Fprintf(&b, "Reason: %s\n", result.Reason)
 		if result.RuleID != "" {
 			fmt.Fprintf(&b, "Rule: %s\n", result.RuleID)
@@ -431,8 +469,7 @@ func handleApprove(eng *engine.Engine) server.ToolHandlerFunc {
 			return mcp.NewToolResultError("missing required parameter: command"), nil
 		}
 
-		cmdArgs := strings.Fields(command)
-		if err := eng.ValidateApproval(token, cmdArgs); err != nil {
+		if err := eng.ValidateApproval(token, command, argString(args, "cwd")); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("approval failed: %v", err)), nil
 		}
 
@@ -442,6 +479,14 @@ func handleApprove(eng *engine.Engine) server.ToolHandlerFunc {
 
 func handleListCapabilities(eng *engine.Engine) server.ToolHandlerFunc {
 	return func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if name := argString(req.GetArguments(), "capability"); name != "" {
+			help, err := eng.CapabilityHelp(name)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(help), nil
+		}
+
 		tierFilter := argString(req.GetArguments(), "tier")
 		caps := eng.ListCapabilities()
 
@@ -450,7 +495,11 @@ func handleListCapabilities(eng *engine.Engine) server.ToolHandlerFunc {
 			if tierFilter != "" && c.Tier != tierFilter {
 				continue
 			}
-			fmt.Fprintf(&b, "%-12s %-10s %s\n", c.Name, c.Tier, c.Description)
+			status := ""
+			if c.Unavailable != "" {
+				status = " (unavailable: " + c.Unavailable + ")"
+			}
+			fmt.Fprintf(&b, "%-12s %-10s %s%s\n", c.Name, c.Tier, c.Description, status)
 		}
 		if b.Len() == 0 {
 			if tierFilter != "" {
@@ -463,11 +512,19 @@ func handleListCapabilities(eng *engine.Engine) server.ToolHandlerFunc {
 }
 
 func handleAuditVerify(eng *engine.Engine) server.ToolHandlerFunc {
-	return func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		if err := audit.Verify(eng.AuditPath()); err != nil {
+	return func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		full, _ := req.GetArguments()["full"].(bool)
+		report, err := eng.VerifyAudit(full)
+		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Audit chain violation: %v", err)), nil
 		}
-		return mcp.NewToolResultText("Audit log integrity verified — hash chain is valid."), nil
+		scope := "full chain"
+		if report.Incremental {
+			scope = "since last verified watermark"
+		}
+		return mcp.NewToolResultText(fmt.Sprintf(
+			"Audit log integrity verified — hash chain is valid (%d entries checked, %s; last seq %d).",
+			report.Checked, scope, report.LastSeq)), nil
 	}
 }
 
@@ -477,6 +534,9 @@ func handleAuditTail(eng *engine.Engine) server.ToolHandlerFunc {
 		if n, ok := req.GetArguments()["count"].(float64); ok && n > 0 {
 			count = int(n)
 		}
+		if err := eng.FlushAudit(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to flush audit log: %v", err)), nil
+		}
 		entries, err := audit.Tail(eng.AuditPath(), count)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Failed to read audit log: %v", err)), nil
@@ -509,7 +569,7 @@ func handlePolicyDelete(eng *engine.Engine) server.ToolHandlerFunc {
 		if id == "" {
 			return mcp.NewToolResultError("missing required parameter: id"), nil
 		}
-		if err := policy.DeleteEntry(eng.StorePath(), id); err != nil {
+		if err := eng.DeleteLearned(id); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("Delete failed: %v", err)), nil
 		}
 		return mcp.NewToolResultText(fmt.Sprintf("Deleted policy entry %q.", id)), nil
@@ -566,7 +626,7 @@ func handleSessionStatus(eng *engine.Engine) server.ToolHandlerFunc {
 		if ws == nil {
 			return mcp.NewToolResultText("No active session."), nil
 		}
-		remaining := ws.Timeout - time.Since(ws.StartedAt)
+		remaining := ws.Remaining()
 		resp := map[string]any{
 			"session_id":        ws.ID,
 			"scope":             ws.Scope,
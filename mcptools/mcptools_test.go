@@ -58,6 +58,9 @@ func TestDryRun_ReadOnly(t *testing.T) {
 	if !strings.Contains(text, "escalate") && !strings.Contains(text, "allow") {
 		t.Errorf("expected 'escalate' or 'allow' in result, got: %s", text)
 	}
+	if !strings.Contains(text, "Tier: read\n") {
+		t.Errorf("expected the command's tier in result, got: %s", text)
+	}
 }
 
 func TestDryRun_DangerousCommand(t *testing.T) {
@@ -147,6 +150,32 @@ func TestApprove_NoTokenStore(t *testing.T) {
 	}
 }
 
+func TestListCapabilities_Help(t *testing.T) {
+	eng := newTestEngine(t)
+	handler := handleListCapabilities(eng)
+
+	result, err := handler(context.Background(), newCallReq("doit_list_capabilities", map[string]any{
+		"capability": "find",
+	}))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	text := textContent(t, result)
+	if !strings.Contains(text, "Examples:") || !strings.Contains(text, "-delete") {
+		t.Errorf("expected find help with examples and flags, got:\n%s", text)
+	}
+
+	result, err = handler(context.Background(), newCallReq("doit_list_capabilities", map[string]any{
+		"capability": "nope",
+	}))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !result.IsError {
+		t.Error("expected error result for unknown capability")
+	}
+}
+
 // --- helpers ---
 
 func newCallReq(name string, args map[string]any) mcp.CallToolRequest {
@@ -187,3 +216,16 @@ func newTestEngine(t *testing.T) *engine.Engine {
 	}
 	return eng
 }
+
+func TestRecover_PanickingHandler(t *testing.T) {
+	handler := Recover(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		panic("boom")
+	})
+	result, err := handler(context.Background(), newCallReq("doit_execute", nil))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(textContent(t, result), "panic: boom") {
+		t.Errorf("expected an internal-error result mentioning the panic, got %+v", result)
+	}
+}